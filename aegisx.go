@@ -0,0 +1,114 @@
+// Package aegisx embeds the generator-executor in another Go program. New builds a node from a
+// config, Start starts it and Handler serves its API and runtimes from the program's own HTTP
+// server:
+//
+//	cfg, err := aegisx.LoadConfig("aegisx.yaml")
+//	...
+//	app, err := aegisx.New(cfg)
+//	...
+//	if err := app.Start(ctx); err != nil {
+//		...
+//	}
+//	defer app.Stop(context.Background())
+//	mux.Handle("/", app.Handler())
+//
+// The node keeps its stores in the directories cfg names, as the standalone binary does.
+package aegisx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/server"
+	"github.com/gcottom/aegisx/services/executer"
+)
+
+// Config is the configuration of a node; config/config.yaml documents the settings.
+type Config = config.Config
+
+// Module is an optional part of a node, such as the metrics or the admin API.
+type Module = server.Module
+
+// LoadConfig reads the config file at path with the AEGISX_* environment variables applied,
+// and validates it.
+func LoadConfig(path string) (*Config, error) {
+	return config.Load(nil, path)
+}
+
+// Option changes how New builds a node.
+type Option func(*options)
+
+type options struct {
+	modules []Module
+	custom  bool
+}
+
+// WithModules installs modules instead of the ones the config turns on.
+func WithModules(modules ...Module) Option {
+	return func(o *options) {
+		o.modules, o.custom = modules, true
+	}
+}
+
+// App is an embedded node.
+type App struct {
+	app *server.App
+}
+
+// New builds a node from cfg, with the modules cfg turns on unless WithModules says otherwise.
+// The node listens on no port of its own: the program serves Handler. Custom domain
+// certificates and handoffs between processes need the node's own listeners, so autocert and
+// handoff_socket cannot be set.
+func New(cfg *Config, opts ...Option) (*App, error) {
+	if cfg.Autocert {
+		return nil, errors.New("autocert is not supported by an embedded node; serve TLS from the program")
+	}
+	if cfg.HandoffSocket != "" {
+		return nil, errors.New("handoff_socket is not supported by an embedded node")
+	}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.custom {
+		o.modules = server.DefaultModules(cfg)
+	}
+	app, err := server.New(cfg, o.modules...)
+	if err != nil {
+		return nil, err
+	}
+	app.Embedded = true
+	return &App{app: app}, nil
+}
+
+// Start runs the startup self-test, restores the runtimes' routes and starts the node's
+// background work. ctx bounds the startup only; the node runs until Stop.
+func (a *App) Start(ctx context.Context) error {
+	return a.app.Start(ctx)
+}
+
+// Stop shuts down the node's runtimes, waiting for them until ctx is done, ends its background
+// work and releases its stores and registry connections, so that New can build a node from the
+// same config again. The runtime records are left as they were, for the next start to restore
+// their routes.
+func (a *App) Stop(ctx context.Context) error {
+	if err := a.app.Executer.ShutdownRuntimes(ctx); err != nil {
+		log.Printf("Failed to shut down runtimes: %v", err)
+	}
+	return a.app.Stop(ctx)
+}
+
+// Handler serves the node's API and its runtimes' prefixes. Mount it at the root of a server,
+// since runtimes are served under /runtime/ and the API's paths are absolute.
+func (a *App) Handler() http.Handler {
+	return a.app.FrontDoor
+}
+
+// Executer generates and manages the node's runtimes, for programs that drive it directly
+// rather than through the API.
+func (a *App) Executer() *executer.ExecuterService {
+	return a.app.Executer
+}
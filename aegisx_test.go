@@ -0,0 +1,165 @@
+package aegisx
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+)
+
+// fakeRedis answers the commands of the registries with empty replies and counts its open
+// connections.
+type fakeRedis struct {
+	listener net.Listener
+	open     atomic.Int32
+	wg       sync.WaitGroup
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeRedis{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r.open.Add(1)
+			r.wg.Add(1)
+			go r.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		r.wg.Wait()
+	})
+	return r
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer r.wg.Done()
+	defer r.open.Add(-1)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		reply := "$-1\r\n"
+		switch strings.ToUpper(args[0]) {
+		case "SET":
+			reply = "+OK\r\n"
+		case "SCAN":
+			reply = "*2\r\n$1\r\n0\r\n*0\r\n"
+		case "HGETALL":
+			reply = "*0\r\n"
+		case "HSET", "HDEL":
+			reply = ":1\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("malformed command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+// testConfig returns a config keeping its stores in a temporary directory, with the runtime
+// and node registries in redis.
+func testConfig(t *testing.T, redisURL string) *Config {
+	t.Helper()
+	cfg := config.Defaults()
+	dir := t.TempDir()
+	for _, store := range []*string{
+		&cfg.ExecuterStore, &cfg.ProxyStore, &cfg.AccessLogStore, &cfg.DomainStore, &cfg.ShareStore,
+		&cfg.StaticStore, &cfg.SQLiteStore, &cfg.SnapshotStore, &cfg.VersionStore, &cfg.GenerationCacheStore,
+		&cfg.GenerationHistoryStore, &cfg.PromptStore, &cfg.SecretsStore, &cfg.ScreenshotStore,
+		&cfg.ModuleStore, &cfg.RegistryStore, &cfg.AuditLog,
+	} {
+		if *store != "" {
+			*store = filepath.Join(dir, filepath.Base(*store))
+		}
+	}
+	cfg.GptApiKey = "test"
+	cfg.DemoMode = true
+	cfg.BrowserSmokeTest = false
+	cfg.Thumbnails = false
+	cfg.Registry = "redis"
+	cfg.RuntimeRegistry = "redis"
+	cfg.RegistryURL = redisURL
+	cfg.NodeURL = "http://127.0.0.1:" + strconv.Itoa(cfg.Port)
+	return cfg
+}
+
+// TestRestart checks that a node can be built, started and stopped again from the same config
+// without keeping the connections of the previous one.
+func TestRestart(t *testing.T) {
+	redis := newFakeRedis(t)
+	cfg := testConfig(t, "redis://"+redis.listener.Addr().String())
+	for i := 0; i < 2; i++ {
+		app, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New #%d: %v", i+1, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := app.Start(ctx); err != nil {
+			cancel()
+			t.Fatalf("Start #%d: %v", i+1, err)
+		}
+		// Starting a node sends a heartbeat, so the node registry is connected.
+		deadline := time.Now().Add(5 * time.Second)
+		for redis.open.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if redis.open.Load() == 0 {
+			t.Errorf("node #%d never connected to redis", i+1)
+		}
+		if err := app.Stop(ctx); err != nil {
+			t.Errorf("Stop #%d: %v", i+1, err)
+		}
+		cancel()
+		deadline = time.Now().Add(5 * time.Second)
+		for redis.open.Load() != 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := redis.open.Load(); n != 0 {
+			t.Errorf("%d redis connections still open after Stop #%d", n, i+1)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/smoke"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+)
+
+// Stages measured by the harness besides the pipeline stages of the metrics package.
+const (
+	StageInterpreter = "interpreter"
+	StageExecute     = "execute"
+)
+
+// program is a minimal generated program following the generation rules, so the pipeline can
+// be measured without an LLM. {{port}} is replaced with the port it listens on.
+const program = `package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const AegisxPort = {{port}}
+
+var server *http.Server
+
+func main() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<!DOCTYPE html><html><body><h1>aegisx bench</h1></body></html>")
+	})
+	server = &http.Server{Addr: ":" + strconv.Itoa(AegisxPort), Handler: mux}
+	fmt.Println("AEGISX:PORT=" + strconv.Itoa(AegisxPort))
+	fmt.Println("AEGISX:READY")
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Println(err)
+	}
+}
+
+func Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+}
+`
+
+// startTimeout bounds how long the program may take to listen on its port.
+const startTimeout = 45 * time.Second
+
+// Result summarizes the runs of one stage. Budget is the stage's performance budget, which
+// its 95th percentile must not exceed.
+type Result struct {
+	Stage      string
+	Runs       int
+	Errors     int
+	Err        error // The first error, if any
+	P50        time.Duration
+	P95        time.Duration
+	Max        time.Duration
+	Throughput float64 // Runs per second, for stages run concurrently
+	Budget     time.Duration
+}
+
+// OverBudget reports whether the stage failed or was slower than its budget.
+func (r Result) OverBudget() bool {
+	return r.Errors > 0 || r.Budget > 0 && r.P95 > r.Budget
+}
+
+// Runner measures the execution pipeline in process and, when Target is set, the execute and
+// proxy paths of a live aegisx deployment.
+type Runner struct {
+	Config      *config.Config
+	Iterations  int
+	Requests    int
+	Concurrency int
+	Target      string
+	Prompt      string
+	Client      *http.Client
+}
+
+// Run parses the bench subcommand arguments, runs the benchmarks and fails when a stage is
+// over its performance budget. Arguments after -- are config flags as described by config.Load.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	iterations := fs.Int("iterations", 10, "runs of each in-process stage")
+	requests := fs.Int("requests", 1000, "requests sent through the proxy of the live deployment")
+	concurrency := fs.Int("concurrency", 16, "concurrent proxy requests")
+	target := fs.String("target", "", "base URL of a live aegisx deployment to also measure execute and proxy throughput")
+	prompt := fs.String("prompt", smoke.DefaultPrompt, "prompt used for the live execution")
+	timeout := fs.Duration("timeout", 10*time.Minute, "timeout for each API call")
+	metricsOut := fs.String("metrics-out", "", "file to write the results to in the Prometheus text format")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *iterations < 1 || *requests < 1 || *concurrency < 1 {
+		return errors.New("--iterations, --requests and --concurrency must be positive")
+	}
+	cfg, err := config.Load(fs.Args(), filepath.Join(util.GetAppRoot(), "config", "config.yaml"))
+	if err != nil {
+		return err
+	}
+	metrics.SetStageBudgets(cfg.PerformanceBudget)
+	runner := &Runner{
+		Config:      cfg,
+		Iterations:  *iterations,
+		Requests:    *requests,
+		Concurrency: *concurrency,
+		Target:      strings.TrimSuffix(*target, "/"),
+		Prompt:      *prompt,
+		Client:      &http.Client{Timeout: *timeout},
+	}
+	results := runner.RunAll()
+	failed := 0
+	for _, result := range results {
+		status := "PASS"
+		if result.OverBudget() {
+			status = "FAIL"
+			failed++
+		}
+		line := fmt.Sprintf("%s %-12s runs=%d errors=%d p50=%s p95=%s max=%s", status, result.Stage, result.Runs, result.Errors,
+			result.P50.Round(time.Microsecond), result.P95.Round(time.Microsecond), result.Max.Round(time.Microsecond))
+		if result.Throughput > 0 {
+			line += fmt.Sprintf(" throughput=%.1f/s", result.Throughput)
+		}
+		if result.Budget > 0 {
+			line += " budget=" + result.Budget.String()
+		}
+		if result.Err != nil {
+			line += fmt.Sprintf(": %v", result.Err)
+		}
+		log.Print(line)
+	}
+	if *metricsOut != "" {
+		if err := writeMetrics(*metricsOut); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("benchmark failed: %d of %d stages over budget", failed, len(results))
+	}
+	log.Printf("benchmark passed: %d stages within budget", len(results))
+	return nil
+}
+
+// RunAll measures every stage, the live ones only when Target is set.
+func (r *Runner) RunAll() []Result {
+	results := []Result{
+		r.measure(StageInterpreter, r.Iterations, 1, r.interpreter),
+		r.measure(metrics.StageValidation, r.Iterations, 1, r.validation),
+		r.measure(metrics.StageEvalToPort, r.Iterations, 1, r.evalToPort),
+	}
+	if r.Target == "" {
+		return results
+	}
+	var runtimeID string
+	results = append(results, r.measure(StageExecute, 1, 1, func() (time.Duration, error) {
+		start := time.Now()
+		id, err := r.execute()
+		runtimeID = id
+		return time.Since(start), err
+	}))
+	if runtimeID == "" {
+		return results
+	}
+	results = append(results, r.measure(metrics.StageProxy, r.Requests, r.Concurrency, func() (time.Duration, error) {
+		return r.proxy(runtimeID)
+	}))
+	if err := r.do(http.MethodDelete, "/runtime/"+runtimeID, nil, nil); err != nil {
+		log.Printf("failed to delete benchmark runtime %s: %v", runtimeID, err)
+	}
+	return results
+}
+
+// measure runs fn runs times on concurrency goroutines and summarizes the durations it reports,
+// recording each in the stage metrics.
+func (r *Runner) measure(stage string, runs int, concurrency int, fn func() (time.Duration, error)) Result {
+	result := Result{Stage: stage, Runs: runs, Budget: metrics.StageBudget(stage)}
+	var mu sync.Mutex
+	var durations []time.Duration
+	work := make(chan struct{}, runs)
+	for range runs {
+		work <- struct{}{}
+	}
+	close(work)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range work {
+				d, err := fn()
+				mu.Lock()
+				if err != nil {
+					result.Errors++
+					if result.Err == nil {
+						result.Err = err
+					}
+				} else {
+					durations = append(durations, d)
+					metrics.ObserveStage(stage, d)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if concurrency > 1 {
+		result.Throughput = float64(len(durations)) / time.Since(start).Seconds()
+	}
+	if len(durations) > 0 {
+		slices.Sort(durations)
+		result.P50 = percentile(durations, 50)
+		result.P95 = percentile(durations, 95)
+		result.Max = durations[len(durations)-1]
+	}
+	return result
+}
+
+// percentile returns the p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// interpreter measures creating a runtime's interpreter without the pool.
+func (r *Runner) interpreter() (time.Duration, error) {
+	start := time.Now()
+	util.NewYaegiInterpreter(r.Config.YaegiGoPath)
+	return time.Since(start), nil
+}
+
+func (r *Runner) validation() (time.Duration, error) {
+	start := time.Now()
+	validator, err := code.NewValidator(r.Config, "/runtime/bench", 8080)
+	if err != nil {
+		return 0, err
+	}
+	if err := validator.Validate(programFor(8080)); err != nil {
+		return 0, fmt.Errorf("the benchmark program failed validation: %w", err)
+	}
+	return time.Since(start), nil
+}
+
+// evalToPort measures evaluating the program until it listens on its port, then shuts it down.
+func (r *Runner) evalToPort() (time.Duration, error) {
+	port, err := freePort()
+	if err != nil {
+		return 0, err
+	}
+	interpreter, output := util.NewYaegiInterpreter(r.Config.YaegiGoPath)
+	captured := output.Capture(0)
+	defer captured()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	evalErr := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		_, err := interpreter.EvalWithContext(ctx, programFor(port))
+		evalErr <- err
+	}()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(startTimeout)
+	for !util.IsPortListening(port) {
+		select {
+		case err := <-evalErr:
+			out, _ := captured()
+			return 0, fmt.Errorf("the benchmark program exited before listening: %v: %s", err, out)
+		case <-deadline:
+			return 0, fmt.Errorf("the benchmark program did not listen on port %d within %s", port, startTimeout)
+		case <-ticker.C:
+		}
+	}
+	elapsed := time.Since(start)
+	// Evaluating Shutdown() would run main again, so the function is called directly.
+	shutdown := interpreter.Symbols("main")["main"]["Shutdown"]
+	if !shutdown.IsValid() {
+		return 0, errors.New("the benchmark program has no Shutdown function")
+	}
+	shutdown.Call(nil)
+	<-evalErr
+	return elapsed, nil
+}
+
+// execute generates a runtime on the live deployment, returning once it is healthy.
+func (r *Runner) execute() (string, error) {
+	body, err := json.Marshal(map[string]string{"prompt": r.Prompt})
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		ExecuterID string `json:"executerID"`
+	}
+	if err := r.do(http.MethodPost, "/execute", body, &res); err != nil {
+		return "", err
+	}
+	if res.ExecuterID == "" {
+		return "", errors.New("execute response did not include an executerID")
+	}
+	return res.ExecuterID, nil
+}
+
+// proxy measures one request through the live deployment's proxy to the runtime.
+func (r *Runner) proxy(runtimeID string) (time.Duration, error) {
+	start := time.Now()
+	res, err := r.Client.Get(r.Target + "/runtime/" + runtimeID + "/")
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach proxied app: %w", err)
+	}
+	defer res.Body.Close()
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return 0, fmt.Errorf("failed to read proxied response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("proxied app returned %d", res.StatusCode)
+	}
+	return time.Since(start), nil
+}
+
+// do sends a request to the control API and decodes a JSON response into out when set.
+func (r *Runner) do(method string, path string, body []byte, out any) error {
+	req, err := http.NewRequest(method, r.Target+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %d: %s", method, path, res.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
+
+func programFor(port int) string {
+	return strings.ReplaceAll(program, "{{port}}", strconv.Itoa(port))
+}
+
+// freePort returns a port nothing is listening on.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// writeMetrics writes the stage metrics recorded by the run to path.
+func writeMetrics(path string) error {
+	var b bytes.Buffer
+	if err := metrics.Default.WritePrometheus(&b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/handlers"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/qgin/qgin"
+	"github.com/gin-gonic/gin"
+)
+
+// The benchmarks measure the in-process stages of the pipeline like the bench subcommand,
+// so go test -bench can compare them across commits.
+
+// newRunner returns a runner for the bundled config, in demo mode so no API key is needed.
+func newRunner(b *testing.B) *Runner {
+	b.Helper()
+	cfg, err := config.Load([]string{"--demo-mode", "true"}, "../config/config.yaml")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &Runner{Config: cfg}
+}
+
+func BenchmarkInterpreter(b *testing.B) {
+	runner := newRunner(b)
+	b.ResetTimer()
+	for range b.N {
+		util.NewYaegiInterpreter(runner.Config.YaegiGoPath)
+	}
+}
+
+func BenchmarkInterpreterPool(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := util.NewInterpreterPool(ctx, 4)
+	goPath := b.TempDir()
+	b.ResetTimer()
+	for range b.N {
+		pool.Get(goPath)
+	}
+}
+
+func BenchmarkExtractGoCode(b *testing.B) {
+	response := "Here is the app:\n\n```go\n" + programFor(8080) + "```\n\n```css static/app.css\nbody { margin: 0; }\n```\n\nRun it with go run."
+	b.SetBytes(int64(len(response)))
+	b.ResetTimer()
+	for range b.N {
+		util.ExtractGoCode(response)
+	}
+}
+
+func BenchmarkValidation(b *testing.B) {
+	runner := newRunner(b)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := runner.validation(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEvalToPort(b *testing.B) {
+	runner := newRunner(b)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := runner.evalToPort(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProxy measures requests through a runtime's proxy route to a program answering
+// like the benchmark program.
+func BenchmarkProxy(b *testing.B) {
+	gin.SetMode(gin.ReleaseMode)
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<!DOCTYPE html><html><body><h1>aegisx bench</h1></body></html>")
+	}))
+	defer app.Close()
+	target, err := url.Parse(app.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	port, err := strconv.Atoi(target.Port())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	runner := newRunner(b)
+	ctx := context.Background()
+	router := qgin.NewGinEngine(&ctx, &qgin.Config{ProdMode: true})
+	switcher := routes.NewRouterSwitcher(router)
+	routeService := routes.NewDynamicRouteService(runner.Config, &handlers.MainHandler{}, router, switcher)
+	routeService.Store = nil
+	routeService.RegisterReverseProxy("bench", "", port)
+	server := httptest.NewServer(switcher)
+	defer server.Close()
+
+	client := server.Client()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			res, err := client.Get(server.URL + "/runtime/bench/")
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				b.Errorf("proxied app returned %d", res.StatusCode)
+				return
+			}
+		}
+	})
+}
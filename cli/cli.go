@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gcottom/aegisx/client"
+)
+
+const usage = `usage: aegisx-cli [--target URL] <command> [arguments]
+
+commands:
+  run "<prompt>"        generate and start a runtime
+  run --saved <name> [--param key=value ...]
+                        generate and start a runtime from a saved prompt
+  list [-a]             list runtimes, including archived ones with -a
+  logs <id> [-f]        print a runtime's logs, following new lines with -f
+  stop <id>             stop a runtime
+  delete <id>           stop a runtime and delete it with all of its data
+  export <id> [-o dir]  write a runtime's code and static assets to a directory
+`
+
+// CLI drives the control API of an aegisx deployment from the terminal.
+type CLI struct {
+	Client *client.Client
+	Out    io.Writer
+}
+
+// Run parses the global flags and dispatches to a subcommand.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("aegisx-cli", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	defaultTarget := os.Getenv("AEGISX_URL")
+	if defaultTarget == "" {
+		defaultTarget = "http://localhost:8080"
+	}
+	target := fs.String("target", defaultTarget, "base URL of the aegisx deployment (env AEGISX_URL)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "timeout for API calls other than log streaming")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing command")
+	}
+	c := &CLI{Client: client.New(*target), Out: os.Stdout}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	command, rest := fs.Arg(0), fs.Args()[1:]
+	if command == "logs" {
+		return c.Logs(ctx, rest)
+	}
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	switch command {
+	case "run":
+		return c.RunPrompt(ctx, rest)
+	case "list":
+		return c.List(ctx, rest)
+	case "stop":
+		return c.Stop(ctx, rest)
+	case "delete":
+		return c.Delete(ctx, rest)
+	case "export":
+		return c.Export(ctx, rest)
+	}
+	fs.Usage()
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func (c *CLI) RunPrompt(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fresh := fs.Bool("fresh", false, "skip the generation cache")
+	force := fs.Bool("force", false, "generate a new runtime even if one is running from a near-identical prompt")
+	key := fs.String("idempotency-key", "", "key that makes a retried run return the runtime of the first")
+	strategy := fs.String("strategy", "", "failure strategy: repair, regenerate or hybrid")
+	model := fs.String("model", "", "generation model, e.g. gpt-4o")
+	maxTokens := fs.Int("max-tokens", 0, "completion token limit of each generation request")
+	var temperature, topP *float64
+	fs.Func("temperature", "sampling temperature, 0 to 2", floatFlag(&temperature))
+	fs.Func("top-p", "nucleus sampling probability mass, above 0 and at most 1", floatFlag(&topP))
+	effort := fs.String("reasoning-effort", "", "reasoning effort of reasoning models: minimal, low, medium or high")
+	saved := fs.String("saved", "", "name of a saved prompt to run instead of a prompt")
+	params := map[string]string{}
+	fs.Func("param", "key=value for a placeholder of the saved prompt (repeatable)", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("invalid parameter %q, expected key=value", value)
+		}
+		params[key] = val
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	req := &client.ExecuteRequest{PromptName: *saved, Parameters: params, Strategy: *strategy, Model: *model, MaxTokens: *maxTokens, Temperature: temperature, TopP: topP, ReasoningEffort: *effort}
+	switch {
+	case *saved == "" && fs.NArg() == 1:
+		req.Prompt = fs.Arg(0)
+	case *saved == "" || fs.NArg() != 0:
+		return errors.New(`usage: run [--fresh] [--force] [--idempotency-key k] [--strategy s] [--model m] [--max-tokens n] [--temperature t] [--top-p p] [--reasoning-effort e] ("<prompt>" | --saved name [--param key=value ...])`)
+	}
+	res, err := c.Client.Execute(ctx, *fresh, *force, *key, req)
+	if err != nil {
+		return err
+	}
+	if res.Duplicate {
+		fmt.Fprintln(c.Out, "a runtime from a near-identical prompt is running; use --force to generate a new one")
+	}
+	fmt.Fprintf(c.Out, "%s\t%s\t%s\n", res.ExecuterID, res.Title, res.URL)
+	return nil
+}
+
+// floatFlag parses a flag into a float that stays nil when the flag is not given.
+func floatFlag(target **float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*target = &f
+		return nil
+	}
+}
+
+func (c *CLI) List(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	archived := fs.Bool("a", false, "include archived runtimes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	res, err := c.Client.ListRuntimes(ctx, *archived)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATE\tHEALTHY\tCREATED\tTITLE")
+	for _, runtime := range res.Runtimes {
+		title := runtime.Title
+		if runtime.Pinned {
+			title = "* " + title
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", runtime.ID, runtime.State, runtime.PassedHealthCheck, runtime.CreatedAt.Local().Format(time.DateTime), title)
+	}
+	return w.Flush()
+}
+
+func (c *CLI) Logs(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "follow new log lines")
+	id, err := parseID(fs, args, "logs <id> [-f]")
+	if err != nil {
+		return err
+	}
+	if *follow {
+		return c.Client.FollowLogs(ctx, id, func(line string) { fmt.Fprintln(c.Out, line) })
+	}
+	res, err := c.Client.Logs(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, line := range res.Lines {
+		fmt.Fprintln(c.Out, line)
+	}
+	return nil
+}
+
+func (c *CLI) Stop(ctx context.Context, args []string) error {
+	id, err := parseID(flag.NewFlagSet("stop", flag.ContinueOnError), args, "stop <id>")
+	if err != nil {
+		return err
+	}
+	res, err := c.Client.Stop(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Out, "%s %s\n", id, res.Status)
+	return nil
+}
+
+func (c *CLI) Delete(ctx context.Context, args []string) error {
+	id, err := parseID(flag.NewFlagSet("delete", flag.ContinueOnError), args, "delete <id>")
+	if err != nil {
+		return err
+	}
+	res, err := c.Client.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Out, "%s %s\n", id, res.Status)
+	return nil
+}
+
+// Export writes the runtime's Go program to main.go and its static assets to static/.
+func (c *CLI) Export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	dir := fs.String("o", "", "output directory (default: the runtime ID)")
+	id, err := parseID(fs, args, "export <id> [-o dir]")
+	if err != nil {
+		return err
+	}
+	if *dir == "" {
+		*dir = id
+	}
+	runtime, err := c.Client.Status(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, "main.go"), []byte(runtime.Code), 0o644); err != nil {
+		return fmt.Errorf("failed to write main.go: %w", err)
+	}
+	for _, name := range runtime.Assets {
+		data, err := c.Client.Asset(ctx, id, name)
+		if err != nil {
+			return fmt.Errorf("failed to download asset %s: %w", name, err)
+		}
+		path := filepath.Join(*dir, "static", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write asset %s: %w", name, err)
+		}
+	}
+	fmt.Fprintf(c.Out, "exported %s to %s (%d assets)\n", id, *dir, len(runtime.Assets))
+	return nil
+}
+
+// parseID parses a subcommand's flags around a single runtime ID argument, allowing flags
+// before or after the ID.
+func parseID(fs *flag.FlagSet, args []string, usage string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", errors.New("usage: " + usage)
+	}
+	id := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 0 {
+		return "", errors.New("usage: " + usage)
+	}
+	return id, nil
+}
@@ -1,9 +1,22 @@
 package main
 
-import "github.com/gcottom/aegisx/server"
+import (
+	"os"
+
+	"github.com/gcottom/aegisx/bench"
+	"github.com/gcottom/aegisx/server"
+	"github.com/gcottom/aegisx/smoke"
+)
 
 func main() {
-	err := server.Run()
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "smoke" {
+		err = smoke.Run(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "bench" {
+		err = bench.Run(os.Args[2:])
+	} else {
+		err = server.Run(os.Args[1:])
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,194 @@
+// Command mock-gen generates mocks of the interfaces declared in a Go file, so the code
+// consuming them can be tested without the services behind them. Every method of an interface
+// I becomes a field of IMock holding the function the method calls, and the mock records the
+// arguments of each call:
+//
+//	executorService := &handlers.ExecuterServiceMock{
+//		GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+//			return nil, executer.ErrRuntimeNotFound
+//		},
+//	}
+//
+// A method called while its function is nil panics, naming the method.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// versionSuffix matches the major version of an import path, which is not part of the
+// package name.
+var versionSuffix = regexp.MustCompile(`[./]v\d+$`)
+
+func main() {
+	sourcePath := flag.String("source", "", "Go file declaring the interfaces")
+	outPath := flag.String("out", "mocks.gen.go", "output path of the generated mocks")
+	flag.Parse()
+
+	source, err := generate(*sourcePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outPath, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the mocks of the exported interfaces declared in the file at sourcePath.
+func generate(sourcePath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, sourcePath, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	used := map[string]bool{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			iface, ok := typeSpec.Type.(*ast.InterfaceType)
+			if !ok || !typeSpec.Name.IsExported() {
+				continue
+			}
+			if err := writeMock(&body, fset, typeSpec.Name.Name, iface, used); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if body.Len() == 0 {
+		return nil, fmt.Errorf("%s declares no exported interfaces", sourcePath)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by mock-gen from %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n\t\"sync\"\n", path.Base(sourcePath), file.Name.Name)
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := versionSuffix.ReplaceAllString(path.Base(importPath), "")
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if used[name] && importPath != "sync" {
+			if imp.Name != nil {
+				fmt.Fprintf(&out, "\t%s %q\n", name, importPath)
+			} else {
+				fmt.Fprintf(&out, "\t%q\n", importPath)
+			}
+		}
+	}
+	out.WriteString(")\n\n")
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// writeMock writes the mock of the interface name, adding the packages its methods refer to
+// to used.
+func writeMock(w *bytes.Buffer, fset *token.FileSet, name string, iface *ast.InterfaceType, used map[string]bool) error {
+	type method struct {
+		name            string
+		params, results string
+		args            string // The parameters as passed on to the function
+	}
+	var methods []method
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return fmt.Errorf("%s embeds %s; embedded interfaces are not supported", name, expr(fset, field.Type))
+		}
+		ast.Inspect(fn, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				if pkg, ok := sel.X.(*ast.Ident); ok {
+					used[pkg.Name] = true
+				}
+			}
+			return true
+		})
+		m := method{name: field.Names[0].Name}
+		var params, args []string
+		for i, param := range fn.Params.List {
+			names := param.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{{Name: "_"}}
+			}
+			for j, ident := range names {
+				paramName := ident.Name
+				if paramName == "_" {
+					paramName = fmt.Sprintf("arg%d", i+j)
+				}
+				params = append(params, paramName+" "+expr(fset, param.Type))
+				if _, variadic := param.Type.(*ast.Ellipsis); variadic {
+					paramName += "..."
+				}
+				args = append(args, paramName)
+			}
+		}
+		m.params, m.args = strings.Join(params, ", "), strings.Join(args, ", ")
+		if fn.Results != nil {
+			var results []string
+			for _, result := range fn.Results.List {
+				for range max(len(result.Names), 1) {
+					results = append(results, expr(fset, result.Type))
+				}
+			}
+			m.results = strings.Join(results, ", ")
+			if len(results) > 1 {
+				m.results = "(" + m.results + ")"
+			}
+		}
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].name < methods[j].name })
+
+	mock := name + "Mock"
+	fmt.Fprintf(w, "// %s is a mock of %s. Set the function of every method the code under test calls.\n", mock, name)
+	fmt.Fprintf(w, "type %s struct {\n", mock)
+	for _, m := range methods {
+		fmt.Fprintf(w, "\t%sFunc func(%s) %s\n", m.name, m.params, m.results)
+	}
+	w.WriteString("\n\tmu    sync.Mutex\n\tcalls map[string][][]any\n}\n\n")
+	fmt.Fprintf(w, "var _ %s = (*%s)(nil)\n\n", name, mock)
+	fmt.Fprintf(w, "// Calls returns the arguments of every call of method, in order.\n")
+	fmt.Fprintf(w, "func (m *%s) Calls(method string) [][]any {\n\tm.mu.Lock()\n\tdefer m.mu.Unlock()\n\treturn append([][]any(nil), m.calls[method]...)\n}\n\n", mock)
+	fmt.Fprintf(w, "func (m *%s) record(method string, args ...any) {\n\tm.mu.Lock()\n\tdefer m.mu.Unlock()\n\tif m.calls == nil {\n\t\tm.calls = map[string][][]any{}\n\t}\n\tm.calls[method] = append(m.calls[method], args)\n}\n\n", mock)
+	for _, m := range methods {
+		fmt.Fprintf(w, "// %s calls %sFunc.\n", m.name, m.name)
+		fmt.Fprintf(w, "func (m *%s) %s(%s) %s {\n", mock, m.name, m.params, m.results)
+		fmt.Fprintf(w, "\tm.record(%q", m.name)
+		if m.args != "" {
+			fmt.Fprintf(w, ", %s", strings.ReplaceAll(m.args, "...", ""))
+		}
+		w.WriteString(")\n")
+		fmt.Fprintf(w, "\tif m.%sFunc == nil {\n\t\tpanic(%q)\n\t}\n", m.name, mock+"."+m.name+" called without "+m.name+"Func")
+		call := fmt.Sprintf("m.%sFunc(%s)", m.name, m.args)
+		if m.results != "" {
+			call = "return " + call
+		}
+		fmt.Fprintf(w, "\t%s\n}\n\n", call)
+	}
+	return nil
+}
+
+// expr returns the source of a type expression.
+func expr(fset *token.FileSet, node ast.Expr) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
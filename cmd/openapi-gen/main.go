@@ -0,0 +1,491 @@
+// Command openapi-gen builds the OpenAPI document of the control API from the annotations on
+// the gin handlers and generates the typed Go client from it.
+//
+// Handlers are annotated in their doc comments:
+//
+//	// @operation Execute
+//	// @summary Generate and start a runtime from a prompt
+//	// @router POST /execute
+//	// @param fresh query bool false "Skip the generation cache"
+//	// @param Idempotency-Key header string false "Key identifying retries of the request"
+//	// @body ExecuteRequest
+//	// @success 200 ExecuteResponse
+//	// @failure 500 ErrorResponse
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/handlers"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/analytics"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/health"
+	"github.com/gcottom/aegisx/services/moderation"
+	"github.com/gcottom/aegisx/services/prompts"
+	"github.com/gcottom/aegisx/services/secrets"
+)
+
+// apiTypes are the types annotations may refer to by name.
+var apiTypes = map[string]reflect.Type{
+	"ExecuteRequest":       reflect.TypeOf(handlers.ExecuteRequest{}),
+	"ExecuteResponse":      reflect.TypeOf(handlers.ExecuteResponse{}),
+	"ComposeRequest":       reflect.TypeOf(handlers.ComposeRequest{}),
+	"Group":                reflect.TypeOf(executer.Group{}),
+	"StopResponse":         reflect.TypeOf(handlers.StopResponse{}),
+	"DeleteResponse":       reflect.TypeOf(handlers.DeleteResponse{}),
+	"ErrorResponse":        reflect.TypeOf(handlers.ErrorResponse{}),
+	"RejectionResponse":    reflect.TypeOf(handlers.RejectionResponse{}),
+	"Reason":               reflect.TypeOf(moderation.Reason{}),
+	"ListResponse":         reflect.TypeOf(handlers.ListResponse{}),
+	"RuntimeSummary":       reflect.TypeOf(handlers.RuntimeSummary{}),
+	"DomainRequest":        reflect.TypeOf(handlers.DomainRequest{}),
+	"ShareResponse":        reflect.TypeOf(handlers.ShareResponse{}),
+	"Schedule":             reflect.TypeOf(models.Schedule{}),
+	"Prompt":               reflect.TypeOf(prompts.Prompt{}),
+	"ScaffoldListResponse": reflect.TypeOf(handlers.ScaffoldListResponse{}),
+	"PromptListResponse":   reflect.TypeOf(handlers.PromptListResponse{}),
+	"SecretRequest":        reflect.TypeOf(handlers.SecretRequest{}),
+	"SecretListResponse":   reflect.TypeOf(handlers.SecretListResponse{}),
+	"Secret":               reflect.TypeOf(secrets.Secret{}),
+	"LogsResponse":         reflect.TypeOf(handlers.LogsResponse{}),
+	"ResultResponse":       reflect.TypeOf(handlers.ResultResponse{}),
+	"AccessLogResponse":    reflect.TypeOf(handlers.AccessLogResponse{}),
+	"RuntimeInfo":          reflect.TypeOf(models.RuntimeInfo{}),
+	"GenerationReport":     reflect.TypeOf(analytics.GenerationReport{}),
+	"DiagnoseResponse":     reflect.TypeOf(handlers.DiagnoseResponse{}),
+	"UpdateCodeRequest":    reflect.TypeOf(handlers.UpdateCodeRequest{}),
+	"UpdateCodeResponse":   reflect.TypeOf(handlers.UpdateCodeResponse{}),
+	"AuditLogResponse":     reflect.TypeOf(handlers.AuditLogResponse{}),
+	"AdminUsageResponse":   reflect.TypeOf(handlers.AdminUsageResponse{}),
+	"StopAllResponse":      reflect.TypeOf(handlers.StopAllResponse{}),
+	"AdminStateResponse":   reflect.TypeOf(handlers.AdminStateResponse{}),
+	"HealthResponse":       reflect.TypeOf(handlers.HealthResponse{}),
+	"Readiness":            reflect.TypeOf(health.Readiness{}),
+}
+
+type param struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+type response struct {
+	Code int
+	Type string
+}
+
+type operation struct {
+	ID        string
+	Summary   string
+	Method    string
+	Path      string
+	Params    []param
+	Body      string
+	Responses []response
+}
+
+var paramRegex = regexp.MustCompile(`^([\w-]+)\s+(path|query|header)\s+(string|bool|int)\s+(true|false)\s+"(.*)"$`)
+
+func main() {
+	handlersDir := flag.String("handlers", "handlers", "directory of the annotated handlers")
+	specPath := flag.String("spec", "openapi/openapi.json", "output path of the OpenAPI document")
+	clientPath := flag.String("client", "client/client.gen.go", "output path of the generated client")
+	flag.Parse()
+
+	operations, err := parseOperations(*handlersDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := &generator{schemas: map[string]reflect.Type{}}
+	spec, err := json.MarshalIndent(g.spec(operations), "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*specPath, append(spec, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	source, err := g.client(operations)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*clientPath, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseOperations reads the annotations of every handler method in dir.
+func parseOperations(dir string) ([]operation, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse handlers: %w", err)
+	}
+	var operations []operation
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Doc == nil {
+					continue
+				}
+				op, ok, err := parseAnnotations(fn.Doc.List)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", fn.Name.Name, err)
+				}
+				if ok {
+					operations = append(operations, op)
+				}
+			}
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].ID < operations[j].ID })
+	return operations, nil
+}
+
+func parseAnnotations(comments []*ast.Comment) (operation, bool, error) {
+	var op operation
+	found := false
+	for _, comment := range comments {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		found = true
+		tag, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		switch tag {
+		case "@operation":
+			op.ID = value
+		case "@summary":
+			op.Summary = value
+		case "@router":
+			method, path, ok := strings.Cut(value, " ")
+			if !ok {
+				return op, false, fmt.Errorf("invalid @router %q", value)
+			}
+			op.Method, op.Path = strings.ToUpper(method), path
+		case "@param":
+			m := paramRegex.FindStringSubmatch(value)
+			if m == nil {
+				return op, false, fmt.Errorf("invalid @param %q", value)
+			}
+			// Path and query parameters become client arguments of the same name; header values
+			// are strings.
+			if m[2] != "header" && strings.Contains(m[1], "-") || m[2] == "header" && m[3] != "string" {
+				return op, false, fmt.Errorf("invalid @param %q", value)
+			}
+			op.Params = append(op.Params, param{Name: m[1], In: m[2], Type: m[3], Required: m[4] == "true", Description: m[5]})
+		case "@body":
+			op.Body = value
+		case "@success", "@failure":
+			code, typ, _ := strings.Cut(value, " ")
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				return op, false, fmt.Errorf("invalid %s %q", tag, value)
+			}
+			op.Responses = append(op.Responses, response{Code: status, Type: strings.TrimSpace(typ)})
+		default:
+			return op, false, fmt.Errorf("unknown annotation %s", tag)
+		}
+	}
+	if found && (op.ID == "" || op.Method == "") {
+		return op, false, fmt.Errorf("annotations need @operation and @router")
+	}
+	for _, name := range append([]string{op.Body}, responseTypes(op)...) {
+		if _, ok := apiTypes[name]; name != "" && !ok {
+			return op, false, fmt.Errorf("unknown type %s", name)
+		}
+	}
+	return op, found, nil
+}
+
+func responseTypes(op operation) []string {
+	var types []string
+	for _, res := range op.Responses {
+		types = append(types, res.Type)
+	}
+	return types
+}
+
+// generator collects the named struct types reachable from the annotations.
+type generator struct {
+	schemas map[string]reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isOpaque reports whether a struct has no exported fields, e.g. bytes.Buffer.
+func isOpaque(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonField(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+func (g *generator) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && isOpaque(t):
+		return map[string]any{"type": "object"}
+	case t.Kind() == reflect.Struct:
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = t
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	}
+	return map[string]any{}
+}
+
+func (g *generator) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonField(t.Field(i)); ok {
+			properties[name] = g.schema(t.Field(i).Type)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (g *generator) spec(operations []operation) map[string]any {
+	paths := map[string]map[string]any{}
+	for _, op := range operations {
+		item := map[string]any{"operationId": op.ID, "summary": op.Summary}
+		var params []map[string]any
+		for _, p := range op.Params {
+			typ := map[string]string{"string": "string", "bool": "boolean", "int": "integer"}[p.Type]
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]any{"type": typ},
+			})
+		}
+		if len(params) > 0 {
+			item["parameters"] = params
+		}
+		if op.Body != "" {
+			item["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": g.schema(apiTypes[op.Body])}},
+			}
+		}
+		responses := map[string]any{}
+		for _, res := range op.Responses {
+			responses[strconv.Itoa(res.Code)] = map[string]any{
+				"description": http.StatusText(res.Code),
+				"content":     map[string]any{"application/json": map[string]any{"schema": g.schema(apiTypes[res.Type])}},
+			}
+		}
+		item["responses"] = responses
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]any{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = item
+	}
+	// Resolving a schema can discover more named types, so repeat until none are added.
+	schemas := map[string]any{}
+	for len(schemas) < len(g.schemas) {
+		for name, t := range g.schemas {
+			if _, ok := schemas[name]; !ok {
+				schemas[name] = g.structSchema(t)
+			}
+		}
+	}
+	return map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": "aegisx control API", "version": "1.0.0"},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// goType returns the client's Go type expression for t.
+func (g *generator) goType(t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct && isOpaque(t.Elem()):
+		return "json.RawMessage"
+	case t.Kind() == reflect.Pointer:
+		return "*" + g.goType(t.Elem())
+	case t == timeType:
+		return "time.Time"
+	case t.Kind() == reflect.Struct && isOpaque(t):
+		return "json.RawMessage"
+	case t.Kind() == reflect.Struct:
+		return t.Name()
+	case t.Kind() == reflect.Slice:
+		return "[]" + g.goType(t.Elem())
+	case t.Kind() == reflect.Map:
+		return "map[string]" + g.goType(t.Elem())
+	case t.Kind() == reflect.Interface:
+		return "any"
+	}
+	return t.Kind().String()
+}
+
+func (g *generator) client(operations []operation) ([]byte, error) {
+	var body bytes.Buffer
+	names := make([]string, 0, len(g.schemas))
+	for name := range g.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := g.schemas[name]
+		fmt.Fprintf(&body, "type %s struct {\n", name)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if _, ok := jsonField(f); !ok {
+				continue
+			}
+			fmt.Fprintf(&body, "\t%s %s `json:%q`\n", f.Name, g.goType(f.Type), f.Tag.Get("json"))
+		}
+		body.WriteString("}\n\n")
+	}
+	for _, op := range operations {
+		writeMethod(&body, op)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by openapi-gen from the handler annotations. DO NOT EDIT.\n\npackage client\n\nimport (\n\t\"context\"\n")
+	src := body.String()
+	for _, imp := range []struct{ pkg, use string }{{"encoding/json", "json."}, {"net/http", "http."}, {"net/url", "url."}, {"strconv", "strconv."}, {"time", "time."}} {
+		if strings.Contains(src, imp.use) {
+			fmt.Fprintf(&out, "\t%q\n", imp.pkg)
+		}
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(src)
+	return format.Source(out.Bytes())
+}
+
+func writeMethod(w *bytes.Buffer, op operation) {
+	var success string
+	for _, res := range op.Responses {
+		if res.Code < 300 {
+			success = res.Type
+			break
+		}
+	}
+	args := []string{"ctx context.Context"}
+	path := strconv.Quote(op.Path)
+	var query, header []param
+	for _, p := range op.Params {
+		goType := p.Type
+		args = append(args, argName(p)+" "+goType)
+		switch p.In {
+		case "path":
+			path = strings.Replace(path, "{"+p.Name+"}", `" + url.PathEscape(`+p.Name+`) + "`, 1)
+		case "header":
+			header = append(header, p)
+		default:
+			query = append(query, p)
+		}
+	}
+	path = strings.TrimSuffix(path, ` + ""`)
+	if op.Body != "" {
+		args = append(args, "body *"+op.Body)
+	}
+	fmt.Fprintf(w, "// %s calls %s %s: %s.\n", op.ID, op.Method, op.Path, strings.ToLower(op.Summary[:1])+op.Summary[1:])
+	fmt.Fprintf(w, "func (c *Client) %s(%s) (*%s, error) {\n", op.ID, strings.Join(args, ", "), success)
+	queryArg := "nil"
+	if len(query) > 0 {
+		queryArg = "query"
+		w.WriteString("\tquery := url.Values{}\n")
+		for _, p := range query {
+			switch p.Type {
+			case "bool":
+				fmt.Fprintf(w, "\tif %s {\n\t\tquery.Set(%q, strconv.FormatBool(%s))\n\t}\n", p.Name, p.Name, p.Name)
+			case "int":
+				fmt.Fprintf(w, "\tif %s != 0 {\n\t\tquery.Set(%q, strconv.Itoa(%s))\n\t}\n", p.Name, p.Name, p.Name)
+			default:
+				fmt.Fprintf(w, "\tif %s != \"\" {\n\t\tquery.Set(%q, %s)\n\t}\n", p.Name, p.Name, p.Name)
+			}
+		}
+	}
+	headerArg := "nil"
+	if len(header) > 0 {
+		headerArg = "header"
+		w.WriteString("\theader := http.Header{}\n")
+		for _, p := range header {
+			fmt.Fprintf(w, "\tif %s != \"\" {\n\t\theader.Set(%q, %s)\n\t}\n", argName(p), p.Name, argName(p))
+		}
+	}
+	bodyArg := "nil"
+	if op.Body != "" {
+		bodyArg = "body"
+	}
+	fmt.Fprintf(w, "\tout := new(%s)\n", success)
+	fmt.Fprintf(w, "\tif err := c.do(ctx, %q, %s, %s, %s, %s, out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n\n", op.Method, path, queryArg, headerArg, bodyArg)
+}
+
+// argName returns the client argument of a parameter: its name, lower camel cased for headers
+// such as Idempotency-Key.
+func argName(p param) string {
+	if p.In != "header" {
+		return p.Name
+	}
+	parts := strings.Split(p.Name, "-")
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(part)
+		} else if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		}
+	}
+	return strings.Join(parts, "")
+}
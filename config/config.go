@@ -1,28 +1,256 @@
 package config
 
 import (
+	_ "embed"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	GptApiKey     string `yaml:"gpt_api_key"`
-	Port          int    `yaml:"port"`
-	ExecuterStore string `yaml:"executer_store"`
+	GptApiKey               string                     `yaml:"gpt_api_key"`
+	Port                    int                        `yaml:"port"`
+	GRPCPort                int                        `yaml:"grpc_port"`
+	RuntimePortMin          int                        `yaml:"runtime_port_min"`
+	RuntimePortMax          int                        `yaml:"runtime_port_max"`
+	PublicURL               string                     `yaml:"public_url"`
+	ExecuterStore           string                     `yaml:"executer_store"`
+	ProxyStore              string                     `yaml:"proxy_store"`
+	ProxyMaxRequestBytes    int64                      `yaml:"proxy_max_request_bytes"`
+	ProxyMaxResponseBytes   int64                      `yaml:"proxy_max_response_bytes"`
+	ProxyTimeout            time.Duration              `yaml:"proxy_timeout"`
+	ProxyClientTimeout      time.Duration              `yaml:"proxy_client_timeout"`
+	ProxyHoldTimeout        time.Duration              `yaml:"proxy_hold_timeout"`
+	SecurityHeaders         bool                       `yaml:"security_headers"`
+	ContentSecurityPolicy   string                     `yaml:"content_security_policy"`
+	FrameOptions            string                     `yaml:"frame_options"`
+	ReferrerPolicy          string                     `yaml:"referrer_policy"`
+	Compression             bool                       `yaml:"compression"`
+	CompressionMinBytes     int64                      `yaml:"compression_min_bytes"`
+	AccessLogStore          string                     `yaml:"access_log_store"`
+	AccessLogMaxBytes       int64                      `yaml:"access_log_max_bytes"`
+	DomainStore             string                     `yaml:"domain_store"`
+	ShareStore              string                     `yaml:"share_store"`
+	Autocert                bool                       `yaml:"autocert"`
+	AutocertCache           string                     `yaml:"autocert_cache"`
+	AutocertEmail           string                     `yaml:"autocert_email"`
+	TLSPort                 int                        `yaml:"tls_port"`
+	StaticStore             string                     `yaml:"static_store"`
+	SQLiteEnabled           bool                       `yaml:"sqlite_enabled"`
+	SQLiteStore             string                     `yaml:"sqlite_store"`
+	SnapshotStore           string                     `yaml:"snapshot_store"`
+	VersionStore            string                     `yaml:"version_store"`
+	GenerationCache         bool                       `yaml:"generation_cache"`
+	GenerationCacheStore    string                     `yaml:"generation_cache_store"`
+	DuplicateSimilarity     float64                    `yaml:"duplicate_similarity"`
+	IdempotencyKeyTTL       time.Duration              `yaml:"idempotency_key_ttl"`
+	GenerationHistoryStore  string                     `yaml:"generation_history_store"`
+	GenerationHistoryTTL    time.Duration              `yaml:"generation_history_ttl"`
+	AuditLog                string                     `yaml:"audit_log"`
+	AuditLogTTL             time.Duration              `yaml:"audit_log_ttl"`
+	AuditMaxContent         int                        `yaml:"audit_max_content"`
+	AuditRedact             []string                   `yaml:"audit_redact"`
+	PromptStore             string                     `yaml:"prompt_store"`
+	SecretsStore            string                     `yaml:"secrets_store"`
+	SecretsMasterKey        string                     `yaml:"secrets_master_key"`
+	Model                   string                     `yaml:"model"`
+	FallbackModels          []string                   `yaml:"fallback_models"`
+	MaxTokens               int                        `yaml:"max_tokens"`
+	Temperature             *float64                   `yaml:"temperature"`
+	TopP                    *float64                   `yaml:"top_p"`
+	ReasoningEffort         string                     `yaml:"reasoning_effort"`
+	ExecutionTargets        []ExecutionTarget          `yaml:"execution_targets"`
+	PromptVariants          []PromptVariant            `yaml:"prompt_variants"`
+	StructuredOutput        bool                       `yaml:"structured_output"`
+	SystemPrompt            string                     `yaml:"system_prompt"`
+	SystemRole              string                     `yaml:"system_role"`
+	FewShotStore            string                     `yaml:"few_shot_store"`
+	MaxFewShotExamples      int                        `yaml:"max_few_shot_examples"`
+	ScaffoldStore           string                     `yaml:"scaffold_store"`
+	JobTimeout              time.Duration              `yaml:"job_timeout"`
+	WorkerHeartbeatTimeout  time.Duration              `yaml:"worker_heartbeat_timeout"`
+	Languages               map[string]LanguageConfig  `yaml:"languages"`
+	TitleProvider           string                     `yaml:"title_provider"`
+	TitleModel              string                     `yaml:"title_model"`
+	HedgeAfter              time.Duration              `yaml:"hedge_after"`
+	HedgeModel              string                     `yaml:"hedge_model"`
+	HedgeApiUrl             string                     `yaml:"hedge_api_url"`
+	HedgeApiKey             string                     `yaml:"hedge_api_key"`
+	BreakerThreshold        int                        `yaml:"breaker_threshold"`
+	BreakerCooldown         time.Duration              `yaml:"breaker_cooldown"`
+	ReadinessLLMCheck       bool                       `yaml:"readiness_llm_check"`
+	GeneratedTests          bool                       `yaml:"generated_tests"`
+	VerifyRuntimes          bool                       `yaml:"verify_runtimes"`
+	VerificationModel       string                     `yaml:"verification_model"`
+	MaxVerificationChecks   int                        `yaml:"max_verification_checks"`
+	PostMortems             bool                       `yaml:"post_mortems"`
+	PostMortemModel         string                     `yaml:"post_mortem_model"`
+	BrowserSmokeTest        bool                       `yaml:"browser_smoke_test"`
+	ChromePath              string                     `yaml:"chrome_path"`
+	ScreenshotStore         string                     `yaml:"screenshot_store"`
+	Thumbnails              bool                       `yaml:"thumbnails"`
+	ThumbnailWidth          int                        `yaml:"thumbnail_width"`
+	MaxConcurrentExecutions int                        `yaml:"max_concurrent_executions"`
+	Validator               *ValidatorConfig           `yaml:"validator"`
+	Retry                   map[string]RetryRuleConfig `yaml:"retry"`
+	FailureStrategy         string                     `yaml:"failure_strategy"`
+	MaxRegenerations        int                        `yaml:"max_regenerations"`
+	DiffRebuildMinLines     int                        `yaml:"diff_rebuild_min_lines"`
+	ChunkedGenerationLines  int                        `yaml:"chunked_generation_lines"`
+	MaxContinuations        int                        `yaml:"max_continuations"`
+	OfflineMode             bool                       `yaml:"offline_mode"`
+	OfflinePackageCache     string                     `yaml:"offline_package_cache"`
+	DemoMode                bool                       `yaml:"demo_mode"`
+	RateLimitPerMinute      int                        `yaml:"rate_limit_per_minute"`
+	MaxRuntimes             int                        `yaml:"max_runtimes"`
+	TokenQuota              int                        `yaml:"token_quota"`
+	Notifiers               []NotifierConfig           `yaml:"notifiers"`
+	NotifyCooldown          time.Duration              `yaml:"notify_cooldown"`
+	RetryStormThreshold     int                        `yaml:"retry_storm_threshold"`
+	RetryStormWindow        time.Duration              `yaml:"retry_storm_window"`
+	IDStrategy              string                     `yaml:"id_strategy"`
+	IDPrefix                string                     `yaml:"id_prefix"`
+	YaegiGoPath             string                     `yaml:"yaegi_gopath"`
+	InterpreterPoolSize     int                        `yaml:"interpreter_pool_size"`
+	PerformanceBudget       map[string]time.Duration   `yaml:"performance_budget"`
+	ModuleStore             string                     `yaml:"module_store"`
+	NodeID                  string                     `yaml:"node_id"`
+	NodeURL                 string                     `yaml:"node_url"`
+	NodeRole                string                     `yaml:"node_role"`
+	Registry                string                     `yaml:"registry"`
+	RegistryURL             string                     `yaml:"registry_url"`
+	RegistryStore           string                     `yaml:"registry_store"`
+	RegistryTTL             time.Duration              `yaml:"registry_ttl"`
+	RuntimeRegistry         string                     `yaml:"runtime_registry"`
+	HandoffSocket           string                     `yaml:"handoff_socket"`
+	HandoffTimeout          time.Duration              `yaml:"handoff_timeout"`
+	Dependencies            DependencyPolicyConfig     `yaml:"dependencies"`
+	Egress                  EgressPolicyConfig         `yaml:"egress"`
+	OutboundProxy           OutboundProxyConfig        `yaml:"outbound_proxy"`
+	RuntimeResources        RuntimeResourcesConfig     `yaml:"runtime_resources"`
+	Watchdog                WatchdogConfig             `yaml:"watchdog"`
+	Moderation              ModerationConfig           `yaml:"moderation"`
+	Tenants                 []TenantConfig             `yaml:"tenants"`
+	AdminAPIKeys            []string                   `yaml:"admin_api_keys"`
 }
 
+// ExecutionTarget is a model, optionally on another OpenAI compatible provider, that
+// concurrent generation attempts can target. Models must be unique across targets.
+type ExecutionTarget struct {
+	Model  string `yaml:"model"`
+	APIURL string `yaml:"api_url"`
+	APIKey string `yaml:"api_key"`
+}
+
+// PromptVariant is an arm of the prompt template experiment. Each execution picks a variant
+// with probability proportional to its Weight and adds its Instructions to the generation
+// rules; a variant without instructions is the control. A zero Weight pauses the variant.
+type PromptVariant struct {
+	Name         string `yaml:"name"`
+	Weight       int    `yaml:"weight"`
+	Instructions string `yaml:"instructions"`
+}
+
+// LanguageConfig runs the programs of a language other than Go in a subprocess: Command, with
+// the path of the program appended, is started in the runtime's static directory with only
+// PATH, HOME and Env in its environment, confined by Sandbox. Name is what prompts call the
+// language, Extension the file extension of its programs and Labels the fenced block labels
+// models return them under.
+type LanguageConfig struct {
+	Name      string            `yaml:"name"`
+	Command   []string          `yaml:"command"`
+	Extension string            `yaml:"extension"`
+	Labels    []string          `yaml:"labels"`
+	Env       map[string]string `yaml:"env"`
+	Sandbox   SandboxConfig     `yaml:"sandbox"`
+}
+
+// PerformanceStages are the execution pipeline stages performance_budget can set a budget
+// for: generating and validating the program, evaluating it until it listens on its port and
+// proxying a request to it.
+var PerformanceStages = []string{"prepare", "validation", "eval_to_port", "proxy"}
+
+// NotifierConfig is a destination of runtime lifecycle notifications. Type is slack or webhook,
+// which post to URL, or email, which sends through the SMTP server at SMTPAddr. Events limits
+// the notifier to runtime_healthy, runtime_failed, retry_storm or quota_exceeded events; it
+// receives all of them when empty.
+type NotifierConfig struct {
+	Type     string            `yaml:"type"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	SMTPAddr string            `yaml:"smtp_addr"`
+	Username string            `yaml:"username"`
+	Password string            `yaml:"password"`
+	From     string            `yaml:"from"`
+	To       []string          `yaml:"to"`
+	Events   []string          `yaml:"events"`
+}
+
+// defaultYAML is the config.yaml shipped with aegisx.
+//
+//go:embed config.yaml
+var defaultYAML []byte
+
+// Defaults returns the settings of the config.yaml shipped with aegisx, for a node started
+// without a config file. The few-shot examples ship beside that file, so none are loaded.
+func Defaults() *Config {
+	config, err := parseConfig(defaultYAML, "the default config")
+	if err != nil {
+		panic(err)
+	}
+	config.FewShotStore = ""
+	return config
+}
+
+// LoadConfig reads a config file without environment or flag overrides; see Load.
 func LoadConfig(filePath string) (*Config, error) {
-	config := new(Config)
 	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 	data, err := io.ReadAll(f)
 	if err != nil {
 		return nil, err
 	}
-	yaml.Unmarshal(data, config)
+	return parseConfig(data, filePath)
+}
+
+// parseConfig parses the YAML of a config named name, filling in the validator and retry
+// sections it omits.
+func parseConfig(data []byte, name string) (*Config, error) {
+	config := new(Config)
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	if config.Validator == nil {
+		config.Validator = DefaultValidatorConfig()
+	}
+	if config.Retry == nil {
+		config.Retry = map[string]RetryRuleConfig{}
+	}
+	for class, rule := range DefaultRetryConfig() {
+		configured, ok := config.Retry[class]
+		if !ok {
+			configured = rule
+		} else if configured.Guidance == "" {
+			configured.Guidance = rule.Guidance
+		}
+		config.Retry[class] = configured
+	}
 	return config, nil
 }
+
+// GetPublicURL returns the externally reachable base URL of the aegisx front door,
+// falling back to localhost on the configured port.
+func (c *Config) GetPublicURL() string {
+	if c.PublicURL != "" {
+		return strings.TrimSuffix(c.PublicURL, "/")
+	}
+	return "http://localhost:" + strconv.Itoa(c.Port)
+}
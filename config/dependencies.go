@@ -0,0 +1,19 @@
+package config
+
+// DependencyPolicyConfig restricts the third party packages generated programs may import.
+// Allow and Deny hold module or package path prefixes; an empty Allow permits everything
+// that is not denied.
+type DependencyPolicyConfig struct {
+	Allow           []string `yaml:"allow"`
+	Deny            []string `yaml:"deny"`
+	MaxDependencies int      `yaml:"max_dependencies"`
+	// VulnCheck looks up every resolved module version in the OSV database before it is
+	// vendored into the runtime. Only runtimes with their own module can be checked.
+	VulnCheck bool   `yaml:"vuln_check"`
+	OSVURL    string `yaml:"osv_url"`
+}
+
+// Restricted reports whether any import restriction is configured.
+func (c DependencyPolicyConfig) Restricted() bool {
+	return len(c.Allow) > 0 || len(c.Deny) > 0 || c.MaxDependencies > 0
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"net"
+	"strings"
+)
+
+// EgressPolicyConfig restricts the hosts generated programs may connect to. Allow and Deny hold
+// host names, "*.domain" wildcards, IP addresses or CIDR ranges; an empty Allow permits every
+// host that is not denied. NoOutbound blocks outbound connections altogether.
+type EgressPolicyConfig struct {
+	NoOutbound bool     `yaml:"no_outbound"`
+	Allow      []string `yaml:"allow"`
+	Deny       []string `yaml:"deny"`
+}
+
+// Restricted reports whether any outbound restriction is configured.
+func (c EgressPolicyConfig) Restricted() bool {
+	return c.NoOutbound || len(c.Allow) > 0 || len(c.Deny) > 0
+}
+
+// validEgressEntry reports whether entry is a host name, "*.domain" wildcard, IP address or
+// CIDR range.
+func validEgressEntry(entry string) bool {
+	if strings.Contains(entry, "/") {
+		_, _, err := net.ParseCIDR(entry)
+		return err == nil
+	}
+	if net.ParseIP(entry) != nil {
+		return true
+	}
+	host := strings.TrimPrefix(entry, "*.")
+	return host != "" && !strings.ContainsAny(host, " :*/")
+}
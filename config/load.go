@@ -0,0 +1,325 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g. AEGISX_GPT_API_KEY.
+const EnvPrefix = "AEGISX_"
+
+// tenantNameRegex matches names that are safe in URLs and directory names.
+var tenantNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// idPrefixRegex matches prefixes that keep runtime IDs safe in URL paths and directory names.
+var idPrefixRegex = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+
+// Load builds the config from, in increasing precedence, the config file, AEGISX_* environment
+// variables and command-line flags, then validates it. The file is read from --config,
+// AEGISX_CONFIG or defaultPath; a missing file is only an error when it was named explicitly,
+// and otherwise leaves the defaults of the shipped config.yaml.
+func Load(args []string, defaultPath string) (*Config, error) {
+	fs := flag.NewFlagSet("aegisx", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config.yaml (env "+EnvPrefix+"CONFIG)")
+	overrides := map[string]string{}
+	for _, setting := range settings(new(Config)) {
+		name := strings.ReplaceAll(setting.key, "_", "-")
+		key := setting.key
+		fs.Func(name, "overrides "+key+" (env "+setting.env+")", func(value string) error {
+			overrides[key] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path, explicit := *configPath, *configPath != ""
+	if !explicit {
+		path, explicit = os.LookupEnv(EnvPrefix + "CONFIG")
+	}
+	if !explicit {
+		path = defaultPath
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		cfg = Defaults()
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	for _, setting := range settings(cfg) {
+		if value, ok := overrides[setting.key]; ok {
+			if err := setting.set(value); err != nil {
+				return nil, fmt.Errorf("invalid flag --%s: %w", strings.ReplaceAll(setting.key, "_", "-"), err)
+			}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides settings with the AEGISX_* environment variables found by lookup.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, setting := range settings(c) {
+		if value, ok := lookup(setting.env); ok {
+			if err := setting.set(value); err != nil {
+				return fmt.Errorf("invalid %s: %w", setting.env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, key string, format string, args ...any) {
+		if !ok {
+			problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+		}
+	}
+	check(c.GptApiKey != "" || c.DemoMode, "gpt_api_key", "is required unless demo_mode is set (set %sGPT_API_KEY)", EnvPrefix)
+	check(c.Port > 0 && c.Port <= 65535, "port", "must be between 1 and 65535, got %d", c.Port)
+	check(c.GRPCPort >= 0 && c.GRPCPort <= 65535, "grpc_port", "must be between 0 (disabled) and 65535, got %d", c.GRPCPort)
+	check(c.GRPCPort == 0 || c.GRPCPort != c.Port, "grpc_port", "must differ from port")
+	check(c.RuntimePortMin >= 0 && c.RuntimePortMax <= 65535, "runtime_port_min/max", "must be within 0-65535")
+	check(c.RuntimePortMin == 0 || c.RuntimePortMax >= c.RuntimePortMin, "runtime_port_max", "must not be below runtime_port_min (%d)", c.RuntimePortMin)
+	check(c.Port < c.RuntimePortMin || c.Port > c.RuntimePortMax, "port", "must not fall inside the runtime port range")
+	if c.PublicURL != "" {
+		u, err := url.Parse(c.PublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "public_url", "must be an absolute http(s) URL, got %q", c.PublicURL)
+	}
+	check(c.ExecuterStore != "", "executer_store", "is required")
+	check(c.ProxyStore != "", "proxy_store", "is required")
+	check(c.ProxyMaxRequestBytes >= 0, "proxy_max_request_bytes", "must not be negative")
+	check(c.ProxyMaxResponseBytes >= 0, "proxy_max_response_bytes", "must not be negative")
+	check(c.ProxyTimeout >= 0, "proxy_timeout", "must not be negative")
+	check(c.ProxyClientTimeout >= 0, "proxy_client_timeout", "must not be negative")
+	check(c.ProxyHoldTimeout >= 0, "proxy_hold_timeout", "must not be negative")
+	check(c.FrameOptions == "" || c.FrameOptions == "DENY" || c.FrameOptions == "SAMEORIGIN", "frame_options", "must be DENY or SAMEORIGIN, got %q", c.FrameOptions)
+	check(c.CompressionMinBytes >= 0, "compression_min_bytes", "must not be negative")
+	check(c.AccessLogMaxBytes >= 0, "access_log_max_bytes", "must not be negative")
+	check(c.DomainStore != "", "domain_store", "is required")
+	check(c.ShareStore != "", "share_store", "is required")
+	check(!c.Autocert || c.AutocertCache != "", "autocert_cache", "is required when autocert is set")
+	check(!c.Autocert || c.TLSPort > 0 && c.TLSPort <= 65535 && c.TLSPort != c.Port, "tls_port", "must be a port other than port (%d) when autocert is set", c.Port)
+	check(c.StaticStore != "", "static_store", "is required")
+	check(!c.SQLiteEnabled || c.SQLiteStore != "", "sqlite_store", "is required when sqlite_enabled is set")
+	check(c.SnapshotStore != "", "snapshot_store", "is required")
+	check(c.VersionStore != "", "version_store", "is required")
+	check(c.PromptStore != "", "prompt_store", "is required")
+	check(idPrefixRegex.MatchString(c.IDPrefix), "id_prefix", "may only contain letters, digits, '-' and '_', got %q", c.IDPrefix)
+	check(c.SecretsMasterKey == "" || c.SecretsStore != "", "secrets_store", "is required when secrets_master_key is set")
+	check(c.SecretsMasterKey == "" || len(c.SecretsMasterKey) >= 16, "secrets_master_key", "must be at least 16 characters")
+	check(!c.GenerationCache || c.GenerationCacheStore != "", "generation_cache_store", "is required when generation_cache is set")
+	check(c.DuplicateSimilarity >= 0 && c.DuplicateSimilarity <= 1, "duplicate_similarity", "must be between 0 and 1, got %v", c.DuplicateSimilarity)
+	check(c.IdempotencyKeyTTL >= 0, "idempotency_key_ttl", "must not be negative")
+	check(c.GenerationHistoryStore != "", "generation_history_store", "is required")
+	check(c.GenerationHistoryTTL >= 0, "generation_history_ttl", "must not be negative")
+	check(c.AuditLogTTL >= 0, "audit_log_ttl", "must not be negative")
+	check(c.AuditMaxContent >= 0, "audit_max_content", "must not be negative")
+	for _, pattern := range c.AuditRedact {
+		_, err := regexp.Compile(pattern)
+		check(err == nil, "audit_redact", "invalid pattern %q: %v", pattern, err)
+	}
+	check(c.TitleProvider == "" || c.TitleProvider == "llm" || c.TitleProvider == "keyword", "title_provider", "must be llm or keyword, got %q", c.TitleProvider)
+	check(c.SystemRole == "" || c.SystemRole == "system" || c.SystemRole == "developer" || c.SystemRole == "user", "system_role", "must be system, developer or user, got %q", c.SystemRole)
+	check(c.MaxFewShotExamples >= 0, "max_few_shot_examples", "must not be negative")
+	check(c.HedgeAfter >= 0, "hedge_after", "must not be negative")
+	check(c.JobTimeout >= 0, "job_timeout", "must not be negative")
+	check(c.WorkerHeartbeatTimeout >= 0, "worker_heartbeat_timeout", "must not be negative")
+	for name, language := range c.Languages {
+		key := "languages." + name
+		check(name != "go", key, "go programs run in the interpreter and cannot be configured")
+		check(language.Name != "", key, "name is required")
+		check(len(language.Command) > 0, key, "command is required")
+		check(strings.HasPrefix(language.Extension, "."), key, "extension must start with a dot, got %q", language.Extension)
+		check(len(language.Labels) > 0, key, "labels are required")
+		sandbox := language.Sandbox
+		check(sandbox.UID >= 0 && sandbox.GID >= 0, key+".sandbox", "uid and gid must not be negative")
+		check(sandbox.MaxMemoryMB >= 0 && sandbox.MaxFileSizeMB >= 0 && sandbox.MaxOpenFiles >= 0 && sandbox.MaxProcesses >= 0 && sandbox.MaxCPUSeconds >= 0, key+".sandbox", "limits must not be negative")
+		for _, path := range sandbox.Paths {
+			check(filepath.IsAbs(path), key+".sandbox.paths", "must be absolute, got %q", path)
+		}
+	}
+	check(c.BreakerThreshold >= 0, "breaker_threshold", "must not be negative")
+	check(c.BreakerThreshold == 0 || c.BreakerCooldown > 0, "breaker_cooldown", "must be positive when breaker_threshold is set")
+	check(c.MaxTokens >= 0, "max_tokens", "must not be negative")
+	check(c.Temperature == nil || *c.Temperature >= 0 && *c.Temperature <= 2, "temperature", "must be between 0 and 2")
+	check(c.TopP == nil || *c.TopP > 0 && *c.TopP <= 1, "top_p", "must be greater than 0 and at most 1")
+	check(c.ReasoningEffort == "" || c.ReasoningEffort == "minimal" || c.ReasoningEffort == "low" || c.ReasoningEffort == "medium" || c.ReasoningEffort == "high", "reasoning_effort", "must be minimal, low, medium or high, got %q", c.ReasoningEffort)
+	check(c.MaxVerificationChecks >= 0, "max_verification_checks", "must not be negative")
+	check(!(c.BrowserSmokeTest || c.Thumbnails) || c.ScreenshotStore != "", "screenshot_store", "is required when browser_smoke_test or thumbnails is set")
+	check(c.ThumbnailWidth >= 0, "thumbnail_width", "must not be negative")
+	check(c.MaxConcurrentExecutions >= 0, "max_concurrent_executions", "must not be negative")
+	check(c.InterpreterPoolSize >= 0, "interpreter_pool_size", "must not be negative")
+	for stage, budget := range c.PerformanceBudget {
+		check(slices.Contains(PerformanceStages, stage), "performance_budget."+stage, "must be one of %s", strings.Join(PerformanceStages, ", "))
+		check(budget >= 0, "performance_budget."+stage, "must not be negative")
+	}
+	check(c.RateLimitPerMinute >= 0, "rate_limit_per_minute", "must not be negative")
+	check(c.MaxRuntimes >= 0, "max_runtimes", "must not be negative")
+	check(c.TokenQuota >= 0, "token_quota", "must not be negative")
+	for i, notifier := range c.Notifiers {
+		key := fmt.Sprintf("notifiers[%d]", i)
+		switch notifier.Type {
+		case "slack", "webhook":
+			check(notifier.URL != "", key, "url is required for %s notifiers", notifier.Type)
+		case "email":
+			check(notifier.SMTPAddr != "" && notifier.From != "" && len(notifier.To) > 0, key, "smtp_addr, from and to are required for email notifiers")
+		default:
+			check(false, key, "type must be slack, webhook or email, got %q", notifier.Type)
+		}
+		for _, event := range notifier.Events {
+			check(slices.Contains([]string{"runtime_healthy", "runtime_failed", "retry_storm", "quota_exceeded"}, event), key, "unknown event %q", event)
+		}
+	}
+	check(c.NotifyCooldown >= 0, "notify_cooldown", "must not be negative")
+	check(c.RetryStormThreshold >= 0, "retry_storm_threshold", "must not be negative")
+	check(c.RetryStormThreshold == 0 || c.RetryStormWindow > 0, "retry_storm_window", "must be positive when retry_storm_threshold is set")
+	seenTargets := map[string]bool{}
+	for i, target := range c.ExecutionTargets {
+		key := fmt.Sprintf("execution_targets[%d]", i)
+		check(target.Model != "", key, "model is required")
+		check(!seenTargets[target.Model], key, "model %q is already targeted", target.Model)
+		seenTargets[target.Model] = true
+	}
+	seenVariants := map[string]bool{}
+	totalWeight := 0
+	for i, variant := range c.PromptVariants {
+		key := fmt.Sprintf("prompt_variants[%d]", i)
+		check(variant.Name != "", key, "name is required")
+		check(!seenVariants[variant.Name], key, "variant %q is already defined", variant.Name)
+		check(variant.Weight >= 0, key, "weight must not be negative")
+		seenVariants[variant.Name] = true
+		totalWeight += variant.Weight
+	}
+	check(len(c.PromptVariants) == 0 || totalWeight > 0, "prompt_variants", "need a variant with a positive weight")
+	check(!c.OfflineMode || c.OfflinePackageCache != "", "offline_package_cache", "is required when offline_mode is set")
+	check(c.Dependencies.MaxDependencies >= 0, "dependencies.max_dependencies", "must not be negative")
+	for _, entry := range c.Egress.Allow {
+		check(validEgressEntry(entry), "egress.allow", "invalid host, IP address or CIDR range %q", entry)
+	}
+	for _, entry := range c.Egress.Deny {
+		check(validEgressEntry(entry), "egress.deny", "invalid host, IP address or CIDR range %q", entry)
+	}
+	check(c.OutboundProxy.CacheTTL >= 0, "outbound_proxy.cache_ttl", "must not be negative")
+	check(c.OutboundProxy.CacheMaxBytes >= 0, "outbound_proxy.cache_max_bytes", "must not be negative")
+	check(c.OutboundProxy.CacheMaxEntryBytes >= 0, "outbound_proxy.cache_max_entry_bytes", "must not be negative")
+	check(c.OutboundProxy.RateLimitPerMinute >= 0, "outbound_proxy.rate_limit_per_minute", "must not be negative")
+	if c.RuntimeResources.Enabled {
+		check(c.RuntimeResources.Window > 0, "runtime_resources.window", "must be positive")
+		check(c.RuntimeResources.Interval > c.RuntimeResources.Window, "runtime_resources.interval", "must be longer than the window")
+	}
+	if c.Watchdog.Enabled {
+		check(c.Watchdog.Interval > 0, "watchdog.interval", "must be positive")
+		check(c.Watchdog.MaxMemoryPercent > 0 && c.Watchdog.MaxMemoryPercent <= 100, "watchdog.max_memory_percent", "must be between 0 and 100")
+		check(c.Watchdog.MaxCPUPercent > 0 && c.Watchdog.MaxCPUPercent <= 100, "watchdog.max_cpu_percent", "must be between 0 and 100")
+		check(c.Watchdog.ResumeMarginPercent >= 0 && c.Watchdog.ResumeMarginPercent < min(c.Watchdog.MaxMemoryPercent, c.Watchdog.MaxCPUPercent), "watchdog.resume_margin_percent", "must not be negative or reach the maximums")
+		check(c.Watchdog.RetryAfter >= time.Second, "watchdog.retry_after", "must be at least 1s")
+	}
+	for i, rule := range c.Moderation.Rules {
+		key := fmt.Sprintf("moderation.rules[%d]", i)
+		_, err := regexp.Compile(rule.Pattern)
+		check(rule.Pattern != "" && err == nil, key, "pattern must be a valid regular expression")
+		check(rule.Message != "", key, "message is required")
+	}
+	seenTenants, seenKeys := map[string]bool{}, map[string]bool{}
+	for i, tenant := range c.Tenants {
+		key := fmt.Sprintf("tenants[%d]", i)
+		check(tenantNameRegex.MatchString(tenant.Name), key, "name must be lowercase letters, digits, '-' and '_', got %q", tenant.Name)
+		check(!seenTenants[tenant.Name], key, "tenant %q is already defined", tenant.Name)
+		seenTenants[tenant.Name] = true
+		check(len(tenant.APIKeys) > 0, key, "at least one api key is required")
+		for _, apiKey := range tenant.APIKeys {
+			check(apiKey != "" && !seenKeys[apiKey], key, "api keys must be non-empty and unique across tenants")
+			seenKeys[apiKey] = true
+		}
+		check(tenant.MaxRuntimes >= 0 && tenant.RateLimitPerMinute >= 0, key, "limits must not be negative")
+	}
+	check(c.NodeRole == "" || c.NodeRole == "all" || c.NodeRole == "control" || c.NodeRole == "worker", "node_role", "must be all, control or worker, got %q", c.NodeRole)
+	check(c.Registry == "" || c.Registry == "file" || c.Registry == "redis", "registry", "must be file or redis, got %q", c.Registry)
+	check(c.Registry == "" || c.NodeURL != "", "node_url", "is required when registry is set")
+	check(c.Registry != "file" || c.RegistryStore != "", "registry_store", "is required when registry is file")
+	check(c.Registry != "redis" || strings.HasPrefix(c.RegistryURL, "redis://"), "registry_url", "must be a redis:// URL when registry is redis")
+	check(c.Registry != "" || c.NodeRole == "" || c.NodeRole == "all", "node_role", "requires a registry")
+	check(c.RegistryTTL >= 0, "registry_ttl", "must not be negative")
+	check(c.RuntimeRegistry == "" || c.RuntimeRegistry == "memory" || c.RuntimeRegistry == "redis", "runtime_registry", "must be memory or redis, got %q", c.RuntimeRegistry)
+	check(c.RuntimeRegistry != "redis" || strings.HasPrefix(c.RegistryURL, "redis://"), "registry_url", "must be a redis:// URL when runtime_registry is redis")
+	check(c.HandoffSocket == "" || c.HandoffTimeout > 0, "handoff_timeout", "must be positive when handoff_socket is set")
+	check(c.FailureStrategy == "" || c.FailureStrategy == "repair" || c.FailureStrategy == "regenerate" || c.FailureStrategy == "hybrid", "failure_strategy", "must be repair, regenerate or hybrid, got %q", c.FailureStrategy)
+	check(c.MaxRegenerations >= 0, "max_regenerations", "must not be negative")
+	check(c.DiffRebuildMinLines >= 0, "diff_rebuild_min_lines", "must not be negative")
+	check(c.ChunkedGenerationLines >= 0, "chunked_generation_lines", "must not be negative")
+	check(c.MaxContinuations >= 0, "max_continuations", "must not be negative")
+	for class, rule := range c.Retry {
+		check(rule.MaxAttempts >= 0 && rule.Backoff >= 0 && rule.MaxBackoff >= 0, "retry."+class, "attempts and backoff must not be negative")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid config:\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// setting is a scalar Config field addressable by its yaml key.
+type setting struct {
+	key   string
+	env   string
+	value reflect.Value
+}
+
+// settings lists the scalar fields of c; nested sections such as validator stay file-only.
+func settings(c *Config) []setting {
+	v := reflect.ValueOf(c).Elem()
+	var out []setting
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		switch v.Field(i).Kind() {
+		case reflect.String, reflect.Int, reflect.Int64, reflect.Bool:
+			out = append(out, setting{key: key, env: EnvPrefix + strings.ToUpper(key), value: v.Field(i)})
+		}
+	}
+	return out
+}
+
+func (s setting) set(value string) error {
+	switch {
+	case s.value.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(value)
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	default:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(n)
+	}
+	return nil
+}
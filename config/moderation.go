@@ -0,0 +1,24 @@
+package config
+
+// ModerationConfig screens prompts before generation. Local rules always run when enabled;
+// Provider additionally sends every prompt to an OpenAI compatible moderation endpoint.
+type ModerationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DisableDefaultRules keeps only the rules listed here.
+	DisableDefaultRules bool                   `yaml:"disable_default_rules"`
+	Rules               []ModerationRuleConfig `yaml:"rules"`
+	Provider            bool                   `yaml:"provider"`
+	ProviderApiUrl      string                 `yaml:"provider_api_url"`
+	ProviderApiKey      string                 `yaml:"provider_api_key"`
+	ProviderModel       string                 `yaml:"provider_model"`
+	// FailClosed rejects prompts while the provider is unreachable.
+	FailClosed bool `yaml:"fail_closed"`
+}
+
+// ModerationRuleConfig rejects prompts matching a case-insensitive regular expression;
+// Message is returned to the caller as the reason.
+type ModerationRuleConfig struct {
+	Category string `yaml:"category"`
+	Pattern  string `yaml:"pattern"`
+	Message  string `yaml:"message"`
+}
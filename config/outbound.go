@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// OutboundProxyConfig configures the forward proxy the HTTP requests of generated programs go
+// through. CacheTTL is how long cacheable responses without a max-age are kept; RateLimitPerMinute
+// caps the outbound requests of each runtime, zero meaning no limit.
+type OutboundProxyConfig struct {
+	Enabled            bool          `yaml:"enabled"`
+	CacheTTL           time.Duration `yaml:"cache_ttl"`
+	CacheMaxBytes      int64         `yaml:"cache_max_bytes"`
+	CacheMaxEntryBytes int64         `yaml:"cache_max_entry_bytes"`
+	RateLimitPerMinute int           `yaml:"rate_limit_per_minute"`
+}
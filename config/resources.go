@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// RuntimeResourcesConfig configures the sampling of the CPU time, allocations and goroutines
+// of each runtime. Every Interval the process is profiled for Window, so the CPU profiler runs
+// Window/Interval of the time. Sampling is off by default.
+type RuntimeResourcesConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	Window   time.Duration `yaml:"window"`
+}
@@ -0,0 +1,66 @@
+package config
+
+import "time"
+
+// RetryRuleConfig controls how failures of one class are retried. Backoff doubles with every
+// attempt up to MaxBackoff; Guidance is added to the rebuild prompt.
+type RetryRuleConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	Guidance    string        `yaml:"guidance"`
+}
+
+// DefaultRetryConfig returns the retry rules keyed by failure class. Classes missing from
+// config.yaml fall back to these.
+func DefaultRetryConfig() map[string]RetryRuleConfig {
+	return map[string]RetryRuleConfig{
+		"validation": {
+			MaxAttempts: 3,
+			Guidance:    "Fix every listed validation problem; the program is rejected before it runs until they are all resolved.",
+		},
+		"compile": {
+			MaxAttempts: 3,
+			Guidance:    "The program did not compile. Fix the reported compile error and check the rest of the file for similar mistakes.",
+		},
+		"panic": {
+			MaxAttempts: 2,
+			Backoff:     time.Second,
+			MaxBackoff:  5 * time.Second,
+			Guidance:    "The program panicked at runtime. Guard against nil values, out of range indexes and unchecked type assertions.",
+		},
+		"timeout": {
+			MaxAttempts: 2,
+			Backoff:     2 * time.Second,
+			MaxBackoff:  10 * time.Second,
+			Guidance:    "The program never started listening on its port. Start the server from main without blocking on anything else first.",
+		},
+		"healthcheck": {
+			MaxAttempts: 2,
+			Backoff:     2 * time.Second,
+			MaxBackoff:  10 * time.Second,
+			Guidance:    "The server started but GET / did not return 200. Register a handler for / that serves the application.",
+		},
+		"test": {
+			MaxAttempts: 2,
+			Guidance:    "The program's own AegisxTest() failed. Fix the program logic it exercises; only change the test where it contradicts the original prompt.",
+		},
+		"verification": {
+			MaxAttempts: 2,
+			Guidance:    "The server started but failed functional checks of its endpoints. Make every failed check listed above behave as the original prompt describes.",
+		},
+		"browser": {
+			MaxAttempts: 2,
+			Guidance:    "Loading the page in a browser raised JavaScript errors or its form failed to submit. Fix the listed errors in the front end and make the form post to a handler that succeeds.",
+		},
+		"reported": {
+			MaxAttempts: 2,
+			Guidance:    "The program reported a fatal error through AEGISX:ERROR. Fix the cause of the reported error.",
+		},
+		"llm": {
+			MaxAttempts: 4,
+			Backoff:     2 * time.Second,
+			MaxBackoff:  30 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+// SandboxConfig confines the processes of a language. Each runs in user, mount, PID, IPC and
+// UTS namespaces of its own, as host user UID and group GID, in a root file system holding only
+// the read-only Paths and its program's directory, without capabilities and under the resource
+// limits below, where 0 keeps the default. The network is shared with aegisx, which proxies to
+// the port the program listens on.
+type SandboxConfig struct {
+	UID           int      `yaml:"uid"`
+	GID           int      `yaml:"gid"`
+	Paths         []string `yaml:"paths"`
+	MaxMemoryMB   int      `yaml:"max_memory_mb"`
+	MaxFileSizeMB int      `yaml:"max_file_size_mb"`
+	MaxOpenFiles  int      `yaml:"max_open_files"`
+	MaxProcesses  int      `yaml:"max_processes"`
+	MaxCPUSeconds int      `yaml:"max_cpu_seconds"`
+}
+
+// DefaultSandboxPaths are the read-only paths of a sandbox that sets none: the interpreters,
+// their libraries and what name resolution and TLS need from /etc.
+var DefaultSandboxPaths = []string{
+	"/bin", "/lib", "/lib64", "/usr",
+	"/etc/alternatives", "/etc/ssl", "/etc/hosts", "/etc/resolv.conf", "/etc/nsswitch.conf",
+}
+
+// WithDefaults returns c with its unset fields set to their defaults: the nobody user and
+// group, DefaultSandboxPaths, 512 MB of memory, 64 MB files and 256 open files. Processes and
+// CPU time are not limited by default.
+func (c SandboxConfig) WithDefaults() SandboxConfig {
+	if c.UID == 0 {
+		c.UID = 65534
+	}
+	if c.GID == 0 {
+		c.GID = 65534
+	}
+	if len(c.Paths) == 0 {
+		c.Paths = DefaultSandboxPaths
+	}
+	if c.MaxMemoryMB == 0 {
+		c.MaxMemoryMB = 512
+	}
+	if c.MaxFileSizeMB == 0 {
+		c.MaxFileSizeMB = 64
+	}
+	if c.MaxOpenFiles == 0 {
+		c.MaxOpenFiles = 256
+	}
+	return c
+}
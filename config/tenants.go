@@ -0,0 +1,41 @@
+package config
+
+import "crypto/subtle"
+
+// TenantConfig is a team sharing the deployment. Its runtimes live under /t/<name>/runtime/<id>,
+// its control API under /t/<name> requires one of APIKeys, and it has its own runtime and rate
+// limits. Zero limits are unlimited.
+type TenantConfig struct {
+	Name               string   `yaml:"name"`
+	APIKeys            []string `yaml:"api_keys"`
+	MaxRuntimes        int      `yaml:"max_runtimes"`
+	RateLimitPerMinute int      `yaml:"rate_limit_per_minute"`
+}
+
+// Tenant returns the tenant called name, or nil.
+func (c *Config) Tenant(name string) *TenantConfig {
+	for i := range c.Tenants {
+		if c.Tenants[i].Name == name {
+			return &c.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// Authorized reports whether key is one of the tenant's API keys.
+func (t *TenantConfig) Authorized(key string) bool {
+	return authorized(t.APIKeys, key)
+}
+
+// AdminAuthorized reports whether key is one of the admin API keys.
+func (c *Config) AdminAuthorized(key string) bool {
+	return authorized(c.AdminAPIKeys, key)
+}
+
+func authorized(keys []string, key string) bool {
+	matched := 0
+	for _, apiKey := range keys {
+		matched |= subtle.ConstantTimeCompare([]byte(apiKey), []byte(key))
+	}
+	return key != "" && matched == 1
+}
@@ -0,0 +1,73 @@
+package config
+
+// ValidatorConfig selects and parameterizes the rules run against generated code.
+// Every rule can be turned off independently.
+type ValidatorConfig struct {
+	RequiredFunctions ListRuleConfig   `yaml:"required_functions"`
+	BannedImports     ListRuleConfig   `yaml:"banned_imports"`
+	BannedCalls       ListRuleConfig   `yaml:"banned_calls"`
+	BannedMethods     MethodRuleConfig `yaml:"banned_methods"`
+	BannedIdentifiers ListRuleConfig   `yaml:"banned_identifiers"`
+	MaxFileSize       LimitRuleConfig  `yaml:"max_file_size"`
+	MaxGoroutines     LimitRuleConfig  `yaml:"max_goroutines"`
+	Regexp            RegexpRuleConfig `yaml:"regexp"`
+	PackageMain       ToggleRuleConfig `yaml:"package_main"`
+	FormActionPrefix  ToggleRuleConfig `yaml:"form_action_prefix"`
+	HandlerRoot       ToggleRuleConfig `yaml:"handler_root"`
+	PortConstant      ToggleRuleConfig `yaml:"port_constant"`
+	Frontend          ToggleRuleConfig `yaml:"frontend"`
+}
+
+type ToggleRuleConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type ListRuleConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Values  []string `yaml:"values"`
+}
+
+type MethodRuleConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Package string   `yaml:"package"`
+	Values  []string `yaml:"values"`
+}
+
+type LimitRuleConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Limit   int  `yaml:"limit"`
+}
+
+type RegexpRuleConfig struct {
+	Enabled  bool            `yaml:"enabled"`
+	Patterns []RegexpPattern `yaml:"patterns"`
+}
+
+type RegexpPattern struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	Message string `yaml:"message"`
+}
+
+// DefaultValidatorConfig is used when config.yaml has no validator section.
+func DefaultValidatorConfig() *ValidatorConfig {
+	return &ValidatorConfig{
+		RequiredFunctions: ListRuleConfig{Enabled: true, Values: []string{"main", "Shutdown"}},
+		BannedImports:     ListRuleConfig{Enabled: true, Values: []string{"syscall", "os/exec", "unsafe", "plugin"}},
+		BannedCalls: ListRuleConfig{Enabled: true, Values: []string{
+			"os.Exit", "os.StartProcess", "os.Setenv", "os.Unsetenv", "os.Chdir",
+			"log.Fatal", "log.Fatalf", "log.Fatalln",
+			"reflect.NewAt", "runtime.Goexit", "runtime.LockOSThread",
+		}},
+		BannedMethods:     MethodRuleConfig{Enabled: true, Package: "reflect", Values: []string{"UnsafeAddr", "UnsafePointer", "SetPointer"}},
+		BannedIdentifiers: ListRuleConfig{Enabled: false},
+		MaxFileSize:       LimitRuleConfig{Enabled: true, Limit: 256 * 1024},
+		MaxGoroutines:     LimitRuleConfig{Enabled: true, Limit: 8},
+		Regexp:            RegexpRuleConfig{Enabled: false},
+		PackageMain:       ToggleRuleConfig{Enabled: true},
+		FormActionPrefix:  ToggleRuleConfig{Enabled: true},
+		HandlerRoot:       ToggleRuleConfig{Enabled: true},
+		PortConstant:      ToggleRuleConfig{Enabled: true},
+		Frontend:          ToggleRuleConfig{Enabled: true},
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// WatchdogConfig configures the load shedding of the node. Every Interval the watchdog reads
+// the memory and CPU use of the host, or of the container's cgroup when it has a memory limit.
+// Above MaxMemoryPercent or MaxCPUPercent new executions are rejected with a Retry-After of
+// RetryAfter and queued ones wait; above MaxMemoryPercent runtimes are also stopped, least
+// recently used first. Shedding ends once both are ResumeMarginPercent below their maximum.
+type WatchdogConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	Interval            time.Duration `yaml:"interval"`
+	MaxMemoryPercent    float64       `yaml:"max_memory_percent"`
+	MaxCPUPercent       float64       `yaml:"max_cpu_percent"`
+	ResumeMarginPercent float64       `yaml:"resume_margin_percent"`
+	RetryAfter          time.Duration `yaml:"retry_after"`
+}
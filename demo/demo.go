@@ -0,0 +1,109 @@
+// Package demo bundles pre-generated programs that stand in for the LLM in demo mode, so the
+// whole pipeline of validation, interpretation and proxying runs offline and in CI without an
+// API key.
+package demo
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+	"gopkg.in/yaml.v3"
+)
+
+// ModelName is the model demo runtimes report they were generated with.
+const ModelName = "demo"
+
+// examplePrefix and examplePort are what the bundled programs are written for; Adapt replaces
+// them with the runtime's own.
+const (
+	examplePrefix = "/runtime/example"
+	examplePort   = "20000"
+)
+
+// ErrUnsupported is returned for prompts other than code generation, such as verification
+// plans, which demo mode cannot answer.
+var ErrUnsupported = errors.New("demo mode only answers code generation prompts")
+
+//go:embed programs.yaml
+var programsYAML []byte
+
+// Programs are the bundled programs. The first one is served for prompts matching none.
+var Programs = mustParse(programsYAML)
+
+var (
+	portRegex   = regexp.MustCompile(`const ` + code.PortConstName + ` = (\d+)`)
+	prefixRegex = regexp.MustCompile(`must use (/\S*?)/\.\.\.`)
+	wordRegex   = regexp.MustCompile(`[a-z]+`)
+)
+
+func mustParse(data []byte) []util.FewShotExample {
+	var programs []util.FewShotExample
+	if err := yaml.Unmarshal(data, &programs); err != nil {
+		panic(fmt.Sprintf("demo: failed to parse bundled programs: %v", err))
+	}
+	return programs
+}
+
+// Client answers generation prompts with the bundled program whose name appears in the
+// prompt, adapted to the port and prefix the prompt assigns. It never calls a provider.
+type Client struct{}
+
+// ModelName returns ModelName.
+func (c *Client) ModelName() string {
+	return ModelName
+}
+
+// WithModel returns the client itself: every model is the demo.
+func (c *Client) WithModel(model string) util.LLMClient {
+	return c
+}
+
+func (c *Client) SendMessage(ctx context.Context, prompt string) (string, error) {
+	if !portRegex.MatchString(prompt) {
+		return "", ErrUnsupported
+	}
+	return "```go\n" + Adapt(Match(prompt).Code, prompt) + "```\n", ctx.Err()
+}
+
+// Adapt moves a program written for the port and prefix of the bundled programs to those the
+// prompt assigns. Rebuild prompts quote the program before the port, so the last one counts.
+func Adapt(program string, prompt string) string {
+	if ports := portRegex.FindAllStringSubmatch(prompt, -1); ports != nil {
+		port := ports[len(ports)-1][1]
+		program = strings.ReplaceAll(program, code.PortConstName+" = "+examplePort, code.PortConstName+" = "+port)
+	}
+	if prefix := prefixRegex.FindStringSubmatch(prompt); prefix != nil {
+		program = strings.ReplaceAll(program, examplePrefix, strings.TrimSuffix(prefix[1], "/"))
+	}
+	return program
+}
+
+// Match returns the bundled program named by a word of the user's request, which is the end
+// of a generation prompt, or the first program.
+func Match(prompt string) util.FewShotExample {
+	words := map[string]bool{}
+	for _, word := range wordRegex.FindAllString(strings.ToLower(userRequest(prompt)), -1) {
+		words[word] = true
+	}
+	for _, program := range Programs {
+		if words[program.Name] {
+			return program
+		}
+	}
+	return Programs[0]
+}
+
+// userRequest returns the part of a generation prompt after its rules, or the whole prompt.
+func userRequest(prompt string) string {
+	const marker = "based on the user prompt:"
+	if i := strings.LastIndex(prompt, marker); i >= 0 {
+		return prompt[i+len(marker):]
+	}
+	return prompt
+}
@@ -0,0 +1,83 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gcottom/aegisx/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// caller is who made a call: an admin, who reaches every runtime and executes in the default
+// namespace, or a tenant, who only reaches its own runtimes.
+type caller struct {
+	Admin  bool
+	Tenant string
+}
+
+// mayAccess reports whether the caller may reach a runtime of tenant.
+func (c caller) mayAccess(tenant string) bool {
+	return c.Admin || c.Tenant == tenant
+}
+
+type callerKey struct{}
+
+// callerFrom returns the caller authUnary or authStream authenticated.
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// authenticate resolves the API key of a call, sent as x-api-key metadata or as a bearer token
+// in authorization, to one of admin_api_keys or a tenant's API keys. Calls without a valid key
+// fail with Unauthenticated, so the control plane refuses every call until admin keys or
+// tenants are configured.
+func authenticate(ctx context.Context, cfg *config.Config) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var key string
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		key = values[0]
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		key, _ = strings.CutPrefix(values[0], "Bearer ")
+	}
+	if cfg.AdminAuthorized(key) {
+		return context.WithValue(ctx, callerKey{}, caller{Admin: true}), nil
+	}
+	for i := range cfg.Tenants {
+		if cfg.Tenants[i].Authorized(key) {
+			return context.WithValue(ctx, callerKey{}, caller{Tenant: cfg.Tenants[i].Name}), nil
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing or invalid API key")
+}
+
+// authUnary authenticates unary calls.
+func (s *ControlService) authUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := authenticate(ctx, s.Config)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authStream authenticates streaming calls.
+func (s *ControlService) authStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticate(stream.Context(), s.Config)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream is a stream whose context carries its caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,240 @@
+package grpcapi
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/moderation"
+	"github.com/gcottom/aegisx/services/prompts"
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gcottom/aegisx/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ControlService implements the gRPC control plane on top of the executer service. Calls
+// carry an admin or tenant API key, and executions are admitted by the same rate limits and
+// quotas as those of the HTTP API.
+type ControlService struct {
+	UnimplementedControlServer
+	ExecutorService *executer.ExecuterService
+	Config          *config.Config
+	Prompts         *prompts.Library
+	Limits          Limits
+}
+
+// Limits admits executions, see handlers.MainHandler.AdmitExecution.
+type Limits interface {
+	AdmitExecution(tenant string, key string, runtimes int) error
+}
+
+func (s *ControlService) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	prompt, err := s.Prompts.Resolve(req.GetPrompt(), req.GetPromptName(), req.GetParameters())
+	if err != nil {
+		if errors.Is(err, prompts.ErrInvalid) || errors.Is(err, prompts.ErrMissingParameters) || errors.Is(err, prompts.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	tenant := callerFrom(ctx).Tenant
+	opts := executer.ExecutionOptions{Fresh: req.GetFresh(), Strategy: req.GetStrategy(), Model: req.GetModel(), Tenant: tenant}
+	if err := opts.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.Limits.AdmitExecution(tenant, peerHost(ctx), 1); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	id, err := s.ExecutorService.NewConcurrentExecution(ctx, prompt, opts)
+	if err != nil {
+		var rejected *moderation.RejectedError
+		if errors.As(err, &rejected) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, secrets.ErrNotFound) || errors.Is(err, executer.ErrSecretsDisabled) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, executer.ErrExecutionsPaused) || errors.Is(err, executer.ErrOverloaded) || errors.Is(err, util.ErrGenerationUnavailable) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	runtimeData, err := s.ExecutorService.GetRuntime(ctx, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	runtime := runtimeData.Snapshot()
+	return &ExecuteResponse{ExecuterId: id, Status: string(runtime.State), Title: runtime.Title, Url: s.runtimeURL(tenant, id)}, nil
+}
+
+func (s *ControlService) Stop(ctx context.Context, req *StopRequest) (*StopResponse, error) {
+	if _, err := s.runtime(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	if err := s.ExecutorService.StopRuntime(ctx, req.GetId()); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &StopResponse{Status: string(models.RSSTOPPING)}, nil
+}
+
+func (s *ControlService) Status(ctx context.Context, req *StatusRequest) (*Runtime, error) {
+	runtime, err := s.runtime(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return s.toRuntime(runtime), nil
+}
+
+func (s *ControlService) StreamLogs(req *StreamLogsRequest, stream Control_StreamLogsServer) error {
+	if _, err := s.runtime(stream.Context(), req.GetId()); err != nil {
+		return err
+	}
+	lines, ch, cancel, err := s.ExecutorService.SubscribeLogs(stream.Context(), req.GetId())
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	defer cancel()
+	for _, line := range lines {
+		if err := stream.Send(&LogLine{Line: line}); err != nil {
+			return err
+		}
+	}
+	if !req.GetFollow() {
+		return nil
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case line := <-ch:
+			if err := stream.Send(&LogLine{Line: line}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *ControlService) ListRuntimes(ctx context.Context, req *ListRuntimesRequest) (*ListRuntimesResponse, error) {
+	res := &ListRuntimesResponse{}
+	caller := callerFrom(ctx)
+	for _, runtime := range s.ExecutorService.ListRuntimes() {
+		if runtime.Archived || !caller.mayAccess(runtime.Tenant) {
+			continue
+		}
+		res.Runtimes = append(res.Runtimes, s.toRuntime(runtime))
+	}
+	return res, nil
+}
+
+// runtime returns the runtime id if the caller may reach it, and a NotFound error otherwise.
+func (s *ControlService) runtime(ctx context.Context, id string) (models.RuntimeInfo, error) {
+	runtime, err := s.ExecutorService.GetRuntime(ctx, id)
+	if err != nil {
+		return models.RuntimeInfo{}, status.Error(codes.NotFound, err.Error())
+	}
+	info := runtime.Snapshot()
+	if !callerFrom(ctx).mayAccess(info.Tenant) {
+		return models.RuntimeInfo{}, status.Error(codes.NotFound, "runtime not found: "+id)
+	}
+	return info, nil
+}
+
+func (s *ControlService) toRuntime(runtime models.RuntimeInfo) *Runtime {
+	return &Runtime{
+		Id:                runtime.ID,
+		Title:             runtime.Title,
+		State:             string(runtime.State),
+		Port:              int32(runtime.Port),
+		PassedHealthCheck: runtime.PassedHealthCheck,
+		LastErrorMsg:      runtime.LastErrorMsg,
+		RebuildCount:      int32(runtime.RebuildCount),
+		Version:           int32(runtime.Version),
+		CreatedAt:         timestamp(runtime.CreatedAt),
+		StartedAt:         timestamp(runtime.StartedAt),
+		FinishedAt:        timestamp(runtime.FinishedAt),
+		Url:               s.runtimeURL(runtime.Tenant, runtime.ID),
+	}
+}
+
+func (s *ControlService) runtimeURL(tenant string, id string) string {
+	return s.Config.GetPublicURL() + models.RuntimePrefix(tenant, id)
+}
+
+// peerHost is the IP address of the caller, which rate limits clients of the default namespace.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// Serve runs the gRPC control plane on port until the listener fails.
+func Serve(service *ControlService, port int) error {
+	lis, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	return ServeListener(service, lis)
+}
+
+// ServeListener runs the gRPC control plane on lis until it fails.
+func ServeListener(service *ControlService, lis net.Listener) error {
+	return NewServer(service).Serve(lis)
+}
+
+// NewServer returns a gRPC server of the control plane, which authenticates calls and turns
+// panics into errors.
+func NewServer(service *ControlService) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(recoverUnary, service.authUnary), grpc.ChainStreamInterceptor(recoverStream, service.authStream))
+	RegisterControlServer(server, service)
+	return server
+}
+
+// recoverUnary turns a panic in a unary handler into an Internal error and a panic report.
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = reportPanic(info.FullMethod, value)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoverStream turns a panic in a streaming handler into an Internal error and a panic report.
+func recoverStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = reportPanic(info.FullMethod, value)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+func reportPanic(method string, value any) error {
+	util.ReportPanic(util.PanicReport{Component: "grpc", Method: method, Value: fmt.Sprint(value), Stack: string(debug.Stack())})
+	return status.Error(codes.Internal, "internal error")
+}
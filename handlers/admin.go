@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Admin is middleware that guards the admin API: requests must carry one of admin_api_keys
+// in the X-API-Key header or as a bearer token. The admin API is disabled when no key is
+// configured.
+func (h *MainHandler) Admin(c *gin.Context) {
+	if len(h.Config.AdminAPIKeys) == 0 {
+		c.AbortWithStatusJSON(404, ErrorResponse{Error: "the admin API is disabled"})
+		return
+	}
+	if !h.Config.AdminAuthorized(apiKey(c)) {
+		c.AbortWithStatusJSON(401, ErrorResponse{Error: "missing or invalid admin API key"})
+	}
+}
+
+// AdminUsage reports the node's executions, runtimes, process resources and token usage.
+//
+// @operation AdminUsage
+// @summary Get the aggregate resource usage of the node
+// @router GET /admin/usage
+// @param X-API-Key header string true "Admin API key"
+// @success 200 AdminUsageResponse
+// @failure 401 ErrorResponse
+func (h *MainHandler) AdminUsage(c *gin.Context) {
+	c.JSON(200, AdminUsageResponse{Node: h.ExecutorService.ResourceUsage(), Tokens: h.Usage.Report()})
+}
+
+// AdminStopAll starts a graceful stop of every active runtime of the node.
+//
+// @operation AdminStopAll
+// @summary Stop all runtimes
+// @router POST /admin/stop-all
+// @param X-API-Key header string true "Admin API key"
+// @success 202 StopAllResponse
+// @failure 401 ErrorResponse
+func (h *MainHandler) AdminStopAll(c *gin.Context) {
+	c.JSON(202, StopAllResponse{Stopped: h.ExecutorService.StopAllRuntimes(c)})
+}
+
+// AdminPause rejects new executions with 503 until they are resumed; running runtimes and
+// executions in flight are not affected.
+//
+// @operation AdminPause
+// @summary Pause new executions
+// @router POST /admin/pause
+// @param X-API-Key header string true "Admin API key"
+// @success 200 AdminStateResponse
+// @failure 401 ErrorResponse
+func (h *MainHandler) AdminPause(c *gin.Context) {
+	h.ExecutorService.PauseExecutions()
+	h.respondAdminState(c, 200)
+}
+
+// AdminResume accepts new executions again, ending a pause or a drain.
+//
+// @operation AdminResume
+// @summary Resume new executions
+// @router POST /admin/resume
+// @param X-API-Key header string true "Admin API key"
+// @success 200 AdminStateResponse
+// @failure 401 ErrorResponse
+func (h *MainHandler) AdminResume(c *gin.Context) {
+	h.ExecutorService.ResumeExecutions()
+	h.respondAdminState(c, 200)
+}
+
+// AdminDrain pauses new executions, takes the node out of execution placement and stops its
+// runtimes once the executions in flight finish.
+//
+// @operation AdminDrain
+// @summary Drain the node
+// @router POST /admin/drain
+// @param X-API-Key header string true "Admin API key"
+// @success 202 AdminStateResponse
+// @failure 401 ErrorResponse
+func (h *MainHandler) AdminDrain(c *gin.Context) {
+	h.ExecutorService.Drain()
+	h.respondAdminState(c, 202)
+}
+
+func (h *MainHandler) respondAdminState(c *gin.Context, status int) {
+	c.JSON(status, AdminStateResponse{Paused: h.ExecutorService.ExecutionsPaused(), Draining: h.ExecutorService.Draining()})
+}
+
+// AdminEvict deletes a runtime of any namespace with all of its data, even if it is pinned.
+//
+// @operation AdminEvict
+// @summary Evict a runtime
+// @router POST /admin/evict/{id}
+// @param id path string true "Runtime ID"
+// @param X-API-Key header string true "Admin API key"
+// @success 200 DeleteResponse
+// @failure 401 ErrorResponse
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) AdminEvict(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.ExecutorService.EvictRuntime(c, id); err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.Links.Remove(id); err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, DeleteResponse{Status: "evicted"})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gcottom/aegisx/services/analytics"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAnalyticsDays is the period GenerationAnalytics covers unless days is given.
+const defaultAnalyticsDays = 30
+
+// GenerationAnalytics aggregates the generation attempts of the last days: their success rate,
+// duration, time to healthy, token spend and failure classes overall, by model, by prompt
+// template version, by prompt variant and per hour or day.
+//
+// @operation GenerationAnalytics
+// @summary Get success and failure analytics of generation attempts
+// @router GET /analytics/generations
+// @param days query int false "Number of days covered, 30 by default"
+// @param interval query string false "Breakdown interval, hour or day (the default)"
+// @success 200 GenerationReport
+// @failure 400 ErrorResponse
+func (h *MainHandler) GenerationAnalytics(c *gin.Context) {
+	days := defaultAnalyticsDays
+	if value := c.Query("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 {
+			c.JSON(400, ErrorResponse{Error: "days must be a positive integer"})
+			return
+		}
+	}
+	interval := c.DefaultQuery("interval", "day")
+	until := time.Now()
+	report, err := h.History.Report(until.AddDate(0, 0, -days), until, interval)
+	if err != nil {
+		status := 500
+		if errors.Is(err, analytics.ErrInvalidInterval) {
+			status = 400
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, report)
+}
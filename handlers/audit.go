@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAuditEntries and maxAuditEntries bound the entries returned by AuditLog.
+const (
+	defaultAuditEntries = 50
+	maxAuditEntries     = 500
+)
+
+// AuditLog returns the latest requests sent to the language model for a runtime, with their
+// prompt hash, model, latency and redacted, truncated content.
+//
+// @operation AuditLog
+// @summary Get the LLM requests and responses of a runtime
+// @router GET /runtime/{id}/audit-log
+// @param id path string true "Runtime ID"
+// @param limit query int false "Number of entries, 50 by default and at most 500"
+// @success 200 AuditLogResponse
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) AuditLog(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if h.Audit == nil {
+		c.JSON(404, ErrorResponse{Error: "the LLM audit log is disabled"})
+		return
+	}
+	limit := defaultAuditEntries
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(400, ErrorResponse{Error: "limit must be a positive integer"})
+			return
+		}
+		limit = min(limit, maxAuditEntries)
+	}
+	entries, err := h.Audit.Runtime(id, limit)
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, AuditLogResponse{Entries: entries})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/validators/code"
+	"github.com/gin-gonic/gin"
+)
+
+// Diagnose asks the model for patches of a runtime's current error without applying them; one
+// is applied by sending its code to UpdateCode.
+//
+// @operation Diagnose
+// @summary Propose fixes for a runtime's current error
+// @router POST /runtime/{id}/diagnose
+// @param id path string true "Runtime ID"
+// @success 200 DiagnoseResponse
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) Diagnose(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	options, err := h.ExecutorService.DiagnoseRuntime(c, id)
+	if errors.Is(err, executer.ErrNothingToDiagnose) {
+		c.JSON(409, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, DiagnoseResponse{Options: options})
+}
+
+// UpdateCode replaces a runtime's program and restarts it, recording the code as a new
+// version that can be rolled back.
+//
+// @operation UpdateCode
+// @summary Replace a runtime's code
+// @router PUT /runtime/{id}/code
+// @param id path string true "Runtime ID"
+// @body UpdateCodeRequest
+// @success 200 UpdateCodeResponse
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) UpdateCode(c *gin.Context) {
+	id := c.Param("id")
+	var req UpdateCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.Code == "" {
+		c.JSON(400, ErrorResponse{Error: "code is required"})
+		return
+	}
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	version, err := h.ExecutorService.UpdateRuntimeCode(c, id, req.Code, req.Reason)
+	var validationErr *code.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, UpdateCodeResponse{Status: "updated", Version: version.Version})
+}
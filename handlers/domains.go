@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gin-gonic/gin"
+)
+
+// SetDomain serves a runtime at a custom hostname, e.g. {"host": "todo.example.com"}, replacing
+// its previous one. The hostname's DNS must point at this instance; with autocert enabled a
+// certificate is obtained on its first HTTPS request. The hostnames of the nodes themselves
+// are refused with a 409.
+//
+// @operation SetDomain
+// @summary Map a custom domain to a runtime
+// @router PUT /runtime/{id}/domain
+// @param id path string true "Runtime ID"
+// @body DomainRequest
+// @success 200 RuntimeSummary
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) SetDomain(c *gin.Context) {
+	var req DomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	id := c.Param("id")
+	runtime, err := h.ExecutorService.GetRuntime(c, id)
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if _, err := h.Domains.Map(routes.Domain{Host: req.Host, RuntimeID: id, Tenant: runtime.Snapshot().Tenant}); err != nil {
+		switch {
+		case errors.Is(err, routes.ErrInvalidDomain):
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, routes.ErrDomainTaken), errors.Is(err, routes.ErrReservedDomain):
+			c.JSON(409, ErrorResponse{Error: err.Error()})
+		default:
+			c.JSON(500, ErrorResponse{Error: err.Error()})
+		}
+		return
+	}
+	h.respondSummary(c, id)
+}
+
+// DeleteDomain stops serving a runtime at its custom hostname.
+//
+// @operation DeleteDomain
+// @summary Remove a runtime's custom domain
+// @router DELETE /runtime/{id}/domain
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) DeleteDomain(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.Domains.Unmap(id); err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.respondSummary(c, id)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type galleryApp struct {
+	Title     string
+	URL       string
+	Image     string
+	CreatedAt time.Time
+}
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>aegisx gallery</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 56rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+ul { list-style: none; padding: 0; display: grid; grid-template-columns: repeat(auto-fill, minmax(16rem, 1fr)); gap: 1rem; }
+li { border: 1px solid #ddd; border-radius: .5rem; padding: 1rem; }
+a { color: #0b5cad; font-weight: 600; text-decoration: none; }
+img { display: block; width: 100%; border: 1px solid #eee; border-radius: .25rem; margin-bottom: .5rem; }
+small { display: block; color: #777; margin-top: .5rem; }
+</style>
+</head>
+<body>
+<h1>aegisx gallery</h1>
+{{if .}}<ul>
+{{range .}}<li>{{if .Image}}<a href="{{.URL}}"><img src="{{.Image}}" alt="" loading="lazy"></a>{{end}}<a href="{{.URL}}">{{.Title}}</a><small>created {{.CreatedAt.Format "Jan 2, 2006 15:04"}}</small></li>
+{{end}}</ul>
+{{else}}<p>No apps are running right now. Create one with <code>POST /execute</code>.</p>
+{{end}}</body>
+</html>
+`))
+
+// Gallery lists the healthy runtimes of the default namespace so users of a shared instance
+// can discover them; tenants' runtimes are not listed, nor are runtimes of other kinds than web
+// apps.
+func (h *MainHandler) Gallery(c *gin.Context) {
+	var apps []galleryApp
+	for _, runtime := range h.ExecutorService.HealthyRuntimes() {
+		if runtime.Tenant != "" || runtime.Kind != "" {
+			continue
+		}
+		title := runtime.Title
+		if title == "" {
+			title = runtime.ID
+		}
+		app := galleryApp{Title: title, URL: "/runtime/" + runtime.ID + "/", CreatedAt: runtime.CreatedAt}
+		switch {
+		case runtime.Thumbnail != nil:
+			app.Image = "/runtime/" + runtime.ID + "/thumbnail"
+		case runtime.Browser != nil && runtime.Browser.Screenshot:
+			app.Image = "/runtime/" + runtime.ID + "/screenshot"
+		}
+		apps = append(apps, app)
+	}
+	var buf bytes.Buffer
+	if err := galleryTemplate.Execute(&buf, apps); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(200, "text/html; charset=utf-8", buf.Bytes())
+}
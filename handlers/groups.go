@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Compose generates a composite app from one prompt: its backends as API runtimes, then a web
+// frontend that gets their URLs in its environment as <NAME>_API_URL. The runtimes are tracked
+// as a group, whose status and stop cover all of them. If a runtime cannot be generated, the
+// ones generated before it are deleted.
+//
+// @operation Compose
+// @summary Generate a composite app of a frontend and its backends
+// @router POST /compose
+// @body ComposeRequest
+// @success 200 Group
+// @failure 400 ErrorResponse
+// @failure 422 RejectionResponse
+// @failure 429 ErrorResponse
+// @failure 500 ErrorResponse
+// @failure 503 ErrorResponse
+func (h *MainHandler) Compose(c *gin.Context) {
+	var req ComposeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.Prompt == "" {
+		c.JSON(400, ErrorResponse{Error: "missing prompt"})
+		return
+	}
+	opts := executer.ExecutionOptions{Strategy: req.Strategy, Model: req.Model, Language: req.Language, Tenant: c.Param("tenant")}
+	if err := opts.Validate(); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	group, err := h.ExecutorService.ComposeApp(c.Request.Context(), req.Prompt, req.Backends, opts)
+	if err != nil {
+		if respondRejected(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, executer.ErrInvalidComposition), errors.Is(err, executer.ErrUnsupportedLanguage):
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, executer.ErrExecutionsPaused):
+			c.JSON(503, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, util.ErrGenerationUnavailable):
+			c.Header("Retry-After", strconv.Itoa(int(h.Breaker.RetryAfter().Seconds())+1))
+			c.JSON(503, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, executer.ErrOverloaded):
+			c.Header("Retry-After", strconv.Itoa(int(h.Config.Watchdog.RetryAfter.Seconds())))
+			c.JSON(503, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, group)
+}
+
+// GroupStatus returns the state of a composite app and of each of its runtimes.
+//
+// @operation GroupStatus
+// @summary Get a composite app's state
+// @router GET /groups/{id}
+// @param id path string true "Group ID"
+// @success 200 Group
+// @failure 404 ErrorResponse
+func (h *MainHandler) GroupStatus(c *gin.Context) {
+	group, err := h.ExecutorService.GetGroup(c.Param("tenant"), c.Param("id"))
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, group)
+}
+
+// StopGroup starts a graceful shutdown of every runtime of a composite app and returns without
+// waiting for them, as Stop does.
+//
+// @operation StopGroup
+// @summary Stop a composite app
+// @router POST /groups/{id}/stop
+// @param id path string true "Group ID"
+// @success 202 Group
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) StopGroup(c *gin.Context) {
+	group, err := h.ExecutorService.StopGroup(c, c.Param("tenant"), c.Param("id"))
+	if err != nil {
+		status := 500
+		if errors.Is(err, executer.ErrGroupNotFound) {
+			status = 404
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(202, group)
+}
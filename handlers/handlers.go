@@ -1,34 +1,206 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/analytics"
+	"github.com/gcottom/aegisx/services/audit"
 	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/health"
+	"github.com/gcottom/aegisx/services/moderation"
+	"github.com/gcottom/aegisx/services/notify"
+	"github.com/gcottom/aegisx/services/prompts"
+	"github.com/gcottom/aegisx/services/quota"
+	"github.com/gcottom/aegisx/services/scaffold"
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gcottom/aegisx/services/share"
+	"github.com/gcottom/aegisx/services/traffic"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
 	"github.com/gin-gonic/gin"
 )
 
 type MainHandler struct {
-	ExecutorService *executer.ExecuterService
+	ExecutorService ExecuterService
+	Routes          DynamicRouteService
+	Config          *config.Config
+	Usage           *util.UsageTracker
+	Quota           *quota.QuotaService
+	TenantQuotas    map[string]*quota.QuotaService
+	Prompts         *prompts.Library
+	Traffic         *traffic.Recorder
+	Domains         *routes.DomainRouter
+	Links           *share.Store
+	Secrets         *secrets.Store       // Nil when no secrets_master_key is set
+	Notifier        *notify.Dispatcher   // Nil when no notifiers are configured
+	History         *analytics.Store     // Generation attempts, for the analytics endpoint
+	Breaker         *util.CircuitBreaker // Nil when the LLM circuit breaker is disabled
+	Audit           *audit.Store         // Nil when the LLM audit log is disabled
+	HealthChecks    []health.Check
 }
 
+// NewMainHandler returns the handler of executorService with the stores and quotas cfg
+// configures. The route service, secrets, notifier, history, breaker, audit log and health
+// checks are set by the caller, as the node has them.
+func NewMainHandler(executorService ExecuterService, cfg *config.Config, usage *util.UsageTracker) *MainHandler {
+	h := &MainHandler{
+		ExecutorService: executorService,
+		Config:          cfg,
+		Usage:           usage,
+		Quota:           quota.NewQuotaService(cfg.RateLimitPerMinute, cfg.MaxRuntimes, cfg.TokenQuota),
+		TenantQuotas:    map[string]*quota.QuotaService{},
+		Prompts:         &prompts.Library{Dir: cfg.PromptStore},
+		Traffic:         &traffic.Recorder{Dir: cfg.AccessLogStore, MaxBytes: cfg.AccessLogMaxBytes},
+		Domains:         &routes.DomainRouter{File: cfg.DomainStore},
+		Links:           &share.Store{File: cfg.ShareStore},
+	}
+	h.Domains.Reserved = func() ([]string, error) { return []string{cfg.GetPublicURL()}, nil }
+	// The token quota is a budget for the whole deployment, so tenants share it.
+	for _, tenant := range cfg.Tenants {
+		h.TenantQuotas[tenant.Name] = quota.NewQuotaService(tenant.RateLimitPerMinute, tenant.MaxRuntimes, cfg.TokenQuota)
+	}
+	return h
+}
+
+// Execute generates and starts a new runtime from a prompt, or from a saved prompt rendered
+// with the given parameters. A prompt nearly identical to that of a running runtime returns
+// that runtime, flagged as a duplicate, unless force is set. A request retried with the same
+// Idempotency-Key gets the runtime of the first one, even if the first timed out. The prompt
+// may reference the tenant's secrets as {{secret:NAME}}; the model only sees their names. A
+// job runs to completion before the response, whose URL is then its result; a worker's URL is
+// its logs.
+// While the LLM provider keeps failing or the node is overloaded, requests fail fast with a 503
+// and a Retry-After.
+//
+// @operation Execute
+// @summary Generate and start a runtime from a prompt
+// @router POST /execute
+// @param fresh query bool false "Skip the generation cache"
+// @param force query bool false "Generate a new runtime even if one is running from a near-identical prompt"
+// @param Idempotency-Key header string false "Key identifying retries of the request"
+// @body ExecuteRequest
+// @success 200 ExecuteResponse
+// @failure 400 ErrorResponse
+// @failure 409 ErrorResponse
+// @failure 422 RejectionResponse
+// @failure 429 ErrorResponse
+// @failure 500 ErrorResponse
+// @failure 503 ErrorResponse
 func (h *MainHandler) Execute(c *gin.Context) {
 	var req ExecuteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		c.JSON(400, ErrorResponse{Error: err.Error()})
 		return
 	}
-	id, err := h.ExecutorService.NewConcurrentExecution(c, req.Prompt)
+	generated := req.Source == "" || req.Source == executer.SourceLLM
+	var prompt string
+	var err error
+	// Programs from other sources than the LLM may come without a prompt.
+	if generated || req.Prompt != "" || req.PromptName != "" {
+		prompt, err = h.Prompts.Resolve(req.Prompt, req.PromptName, req.Parameters)
+	}
+	if err != nil {
+		status := promptErrorStatus(err)
+		if status == 404 {
+			status = 400
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+	fresh, _ := strconv.ParseBool(c.Query("fresh"))
+	force, _ := strconv.ParseBool(c.Query("force"))
+	opts := executer.ExecutionOptions{Fresh: fresh, Force: force, Strategy: req.Strategy, Model: req.Model, Tenant: c.Param("tenant"), NoOutbound: req.NoOutbound}
+	opts.Source, opts.Scaffold, opts.ScaffoldParams, opts.Code, opts.Kind = req.Source, req.Scaffold, req.ScaffoldParams, req.Code, req.Kind
+	opts.Language = req.Language
+	opts.Params = util.GenerationParams{MaxTokens: req.MaxTokens, Temperature: req.Temperature, TopP: req.TopP, ReasoningEffort: req.ReasoningEffort}
+	if err := opts.Validate(); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	var id string
+	var duplicate bool
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		id, duplicate, err = h.ExecutorService.NewIdempotentExecution(c.Request.Context(), key, prompt, opts)
+	} else {
+		id, duplicate, err = h.ExecutorService.NewDeduplicatedExecution(c, prompt, opts)
+	}
 	if err != nil {
+		if respondRejected(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, executer.ErrInvalidIdempotencyKey):
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, secrets.ErrNotFound), errors.Is(err, executer.ErrSecretsDisabled):
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, scaffold.ErrNotFound), errors.Is(err, scaffold.ErrMissingParameters):
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, executer.ErrUnsupportedLanguage):
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		case !generated && errors.As(err, new(*code.ValidationError)):
+			// The program was the caller's to get right; generated ones are the model's.
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, executer.ErrIdempotencyKeyReused):
+			c.JSON(409, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, executer.ErrExecutionsPaused):
+			c.JSON(503, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, util.ErrGenerationUnavailable):
+			c.Header("Retry-After", strconv.Itoa(int(h.Breaker.RetryAfter().Seconds())+1))
+			c.JSON(503, ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, executer.ErrOverloaded):
+			c.Header("Retry-After", strconv.Itoa(int(h.Config.Watchdog.RetryAfter.Seconds())))
+			c.JSON(503, ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	runtime, err := h.ExecutorService.GetRuntime(c, id)
+	runtimeData, err := h.ExecutorService.GetRuntime(c, id)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"status": runtime.State, "executerID": id, "title": runtime.Title, "url": "http://localhost:8080/runtime/" + id})
+	runtime := runtimeData.Snapshot()
+	c.JSON(200, ExecuteResponse{Status: runtime.State, ExecuterID: id, Title: runtime.Title, URL: h.runtimeURL(runtime), Model: runtime.Model, Duplicate: duplicate})
+}
+
+// respondRejected writes a 422 explaining the moderation rejection if err is one.
+func respondRejected(c *gin.Context, err error) bool {
+	var rejected *moderation.RejectedError
+	if !errors.As(err, &rejected) {
+		return false
+	}
+	c.JSON(422, RejectionResponse{Error: err.Error(), Reasons: rejected.Reasons})
+	return true
 }
 
+// Stop starts a graceful shutdown of a runtime and returns without waiting for it. The
+// runtime's status reports "stopping" until its program has exited and then "stopped".
+//
+// @operation Stop
+// @summary Stop a runtime
+// @router POST /stop/{id}
+// @param id path string true "Runtime ID"
+// @success 202 StopResponse
+// @failure 400 ErrorResponse
+// @failure 500 ErrorResponse
 func (h *MainHandler) Stop(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -40,9 +212,65 @@ func (h *MainHandler) Stop(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"status": "stopped"})
+	c.JSON(202, StopResponse{Status: string(models.RSSTOPPING)})
+}
+
+// Delete stops a runtime and removes it together with its code versions, snapshots and data.
+//
+// @operation Delete
+// @summary Delete a runtime and all of its data
+// @router DELETE /runtime/{id}
+// @param id path string true "Runtime ID"
+// @success 200 DeleteResponse
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.ExecutorService.DeleteRuntime(c, id); err != nil {
+		if errors.Is(err, executer.ErrRuntimePinned) {
+			c.JSON(409, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.Links.Remove(id); err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, DeleteResponse{Status: "deleted"})
 }
 
+// Kill stops a runtime without the usual shutdown wait; ?force=true skips Shutdown() entirely.
+func (h *MainHandler) Kill(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(400, gin.H{"error": "missing ID"})
+		return
+	}
+	force, _ := strconv.ParseBool(c.Query("force"))
+	report, err := h.ExecutorService.KillRuntime(c, id, force)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"status": "killed", "kill": report})
+}
+
+// Status returns the full state of a runtime, including its latest sampled resource usage.
+//
+// @operation Status
+// @summary Get a runtime's state
+// @router GET /status/{id}
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeInfo
+// @failure 400 ErrorResponse
+// @failure 500 ErrorResponse
 func (h *MainHandler) Status(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -55,5 +283,467 @@ func (h *MainHandler) Status(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, *status)
+	info := status.Snapshot()
+	info.Resources = h.ExecutorService.RuntimeResources(id)
+	c.JSON(200, info)
+}
+
+// List returns a summary of every known runtime, pinned ones first and otherwise newest
+// first. Archived runtimes are only included with ?archived=true.
+//
+// @operation ListRuntimes
+// @summary List runtimes
+// @router GET /runtimes
+// @param archived query bool false "Include archived runtimes"
+// @success 200 ListResponse
+func (h *MainHandler) List(c *gin.Context) {
+	archived, _ := strconv.ParseBool(c.Query("archived"))
+	res := ListResponse{Runtimes: []RuntimeSummary{}}
+	for _, runtime := range h.ExecutorService.TenantRuntimes(c.Param("tenant")) {
+		if runtime.Archived && !archived {
+			continue
+		}
+		res.Runtimes = append(res.Runtimes, h.summary(runtime))
+	}
+	c.JSON(200, res)
+}
+
+func (h *MainHandler) summary(runtime models.RuntimeInfo) RuntimeSummary {
+	summary := RuntimeSummary{
+		ID:                runtime.ID,
+		Title:             runtime.Title,
+		State:             runtime.State,
+		PassedHealthCheck: runtime.PassedHealthCheck,
+		CreatedAt:         runtime.CreatedAt,
+		URL:               h.runtimeURL(runtime),
+		Model:             runtime.Model,
+		Kind:              runtime.Kind,
+		Language:          runtime.Language,
+		Pinned:            runtime.Pinned,
+		Archived:          runtime.Archived,
+		Schedule:          runtime.Schedule,
+		PromptVariant:     runtime.PromptVariant,
+	}
+	if runtime.PostMortem != nil {
+		summary.PostMortem = runtime.PostMortem.Summary
+	}
+	if h.Domains != nil {
+		if domain, ok := h.Domains.Lookup(runtime.ID); ok {
+			summary.Domain = domain.Host
+		}
+	}
+	return summary
+}
+
+// Pin marks a runtime as a favourite: it is listed first and protected from archiving and
+// deletion.
+//
+// @operation Pin
+// @summary Pin a runtime
+// @router POST /runtime/{id}/pin
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+func (h *MainHandler) Pin(c *gin.Context) {
+	h.setPinned(c, true)
+}
+
+// Unpin removes a runtime's pin.
+//
+// @operation Unpin
+// @summary Unpin a runtime
+// @router POST /runtime/{id}/unpin
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+func (h *MainHandler) Unpin(c *gin.Context) {
+	h.setPinned(c, false)
+}
+
+func (h *MainHandler) setPinned(c *gin.Context, pinned bool) {
+	id := c.Param("id")
+	if err := h.ExecutorService.SetPinned(c, id, pinned); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.respondSummary(c, id)
+}
+
+// Archive stops a runtime and hides it from the default listing, keeping its code and data.
+//
+// @operation Archive
+// @summary Archive a runtime
+// @router POST /runtime/{id}/archive
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+func (h *MainHandler) Archive(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.ExecutorService.ArchiveRuntime(c, id); err != nil {
+		if errors.Is(err, executer.ErrRuntimePinned) {
+			c.JSON(409, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.respondSummary(c, id)
+}
+
+// Unarchive lists an archived runtime again and restarts it.
+//
+// @operation Unarchive
+// @summary Restore an archived runtime
+// @router POST /runtime/{id}/unarchive
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) Unarchive(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.ExecutorService.UnarchiveRuntime(c, id); err != nil {
+		if errors.Is(err, executer.ErrRuntimeActive) {
+			c.JSON(409, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.respondSummary(c, id)
+}
+
+// Restart starts a stopped or failed runtime again on a fresh port, with a new retry budget.
+//
+// @operation Restart
+// @summary Restart a stopped or failed runtime
+// @router POST /runtime/{id}/restart
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) Restart(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.ExecutorService.RestartRuntime(c, id); err != nil {
+		if errors.Is(err, executer.ErrRuntimeActive) {
+			c.JSON(409, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.respondSummary(c, id)
+}
+
+// SetSchedule starts and stops a runtime on a cron schedule, e.g. {"start": "0 9 * * 1-5",
+// "stop": "0 17 * * 1-5"} for business hours.
+//
+// @operation SetSchedule
+// @summary Schedule a runtime's start and stop
+// @router PUT /runtime/{id}/schedule
+// @param id path string true "Runtime ID"
+// @body Schedule
+// @success 200 RuntimeSummary
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+func (h *MainHandler) SetSchedule(c *gin.Context) {
+	var schedule models.Schedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.setSchedule(c, &schedule)
+}
+
+// DeleteSchedule removes a runtime's schedule.
+//
+// @operation DeleteSchedule
+// @summary Remove a runtime's schedule
+// @router DELETE /runtime/{id}/schedule
+// @param id path string true "Runtime ID"
+// @success 200 RuntimeSummary
+// @failure 404 ErrorResponse
+func (h *MainHandler) DeleteSchedule(c *gin.Context) {
+	h.setSchedule(c, nil)
+}
+
+func (h *MainHandler) setSchedule(c *gin.Context, schedule *models.Schedule) {
+	id := c.Param("id")
+	if err := h.ExecutorService.SetSchedule(c, id, schedule); err != nil {
+		if errors.Is(err, executer.ErrInvalidSchedule) {
+			c.JSON(400, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.respondSummary(c, id)
+}
+
+func (h *MainHandler) respondSummary(c *gin.Context, id string) {
+	runtime, err := h.ExecutorService.GetRuntime(c, id)
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, h.summary(runtime.Snapshot()))
+}
+
+// Logs returns the most recent log lines of a runtime's program. With ?follow=true the
+// response is a plain text stream of the retained lines followed by new ones as they arrive.
+//
+// @operation Logs
+// @summary Get a runtime's recent logs
+// @router GET /runtime/{id}/logs
+// @param id path string true "Runtime ID"
+// @success 200 LogsResponse
+// @failure 404 ErrorResponse
+func (h *MainHandler) Logs(c *gin.Context) {
+	if follow, _ := strconv.ParseBool(c.Query("follow")); follow {
+		h.followLogs(c)
+		return
+	}
+	lines, err := h.ExecutorService.RuntimeLogs(c, c.Param("id"))
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, LogsResponse{Lines: lines})
+}
+
+// defaultAccessLogLines and maxAccessLogLines bound the lines returned by AccessLog.
+const (
+	defaultAccessLogLines = 100
+	maxAccessLogLines     = 1000
+)
+
+// AccessLog returns a summary of the requests proxied to a runtime, with their count by status
+// code and latency percentiles, and the latest lines of its access log.
+//
+// @operation AccessLog
+// @summary Get a runtime's proxied traffic and access log
+// @router GET /runtime/{id}/access-log
+// @param id path string true "Runtime ID"
+// @param lines query int false "Number of access log lines, 100 by default and at most 1000"
+// @success 200 AccessLogResponse
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+func (h *MainHandler) AccessLog(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	n := defaultAccessLogLines
+	if value := c.Query("lines"); value != "" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n < 0 {
+			c.JSON(400, ErrorResponse{Error: "lines must be a non-negative integer"})
+			return
+		}
+		n = min(n, maxAccessLogLines)
+	}
+	lines, err := h.Traffic.AccessLog(id, n)
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+	c.JSON(200, AccessLogResponse{Traffic: h.Traffic.Report(id), Lines: lines})
+}
+
+func (h *MainHandler) followLogs(c *gin.Context) {
+	lines, ch, cancel, err := h.ExecutorService.SubscribeLogs(c, c.Param("id"))
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	defer cancel()
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	for _, line := range lines {
+		fmt.Fprintln(c.Writer, line)
+	}
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case line := <-ch:
+			fmt.Fprintln(w, line)
+			return true
+		}
+	})
+}
+
+func (h *MainHandler) UsageReport(c *gin.Context) {
+	c.JSON(200, h.Usage.Report())
+}
+
+func (h *MainHandler) Seed(c *gin.Context) {
+	id := c.Param("id")
+	var req SeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.ExecutorService.SeedRuntime(c, id, req.Route, req.Records)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"results": results})
+}
+
+func (h *MainHandler) Static(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	c.FileFromFS(c.Param("filepath"), http.Dir(h.ExecutorService.StaticDir(id)))
+}
+
+// Screenshot serves the PNG of the runtime's landing page taken by the browser smoke test.
+func (h *MainHandler) Screenshot(c *gin.Context) {
+	h.serveImage(c, "screenshot", h.ExecutorService.ScreenshotPath)
+}
+
+// Thumbnail serves the scaled-down PNG of the runtime's landing page shown in the gallery.
+func (h *MainHandler) Thumbnail(c *gin.Context) {
+	h.serveImage(c, "thumbnail", h.ExecutorService.ThumbnailPath)
+}
+
+func (h *MainHandler) serveImage(c *gin.Context, kind string, pathFor func(runtimeID string) string) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	path := pathFor(id)
+	if _, err := os.Stat(path); h.Config.ScreenshotStore == "" || err != nil {
+		c.JSON(404, ErrorResponse{Error: "no " + kind + " of runtime " + id})
+		return
+	}
+	c.File(path)
+}
+
+func (h *MainHandler) Snapshot(c *gin.Context) {
+	id := c.Param("id")
+	snapshot, err := h.ExecutorService.SnapshotRuntime(c, id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, snapshot)
+}
+
+func (h *MainHandler) Snapshots(c *gin.Context) {
+	id := c.Param("id")
+	snapshots, err := h.ExecutorService.ListSnapshots(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"snapshots": snapshots})
+}
+
+func (h *MainHandler) Restore(c *gin.Context) {
+	id := c.Param("id")
+	var req RestoreRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	snapshot, err := h.ExecutorService.RestoreRuntime(c, id, req.Snapshot)
+	if err != nil {
+		if errors.Is(err, executer.ErrRuntimeActive) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"status": "restored", "snapshot": snapshot})
+}
+
+func (h *MainHandler) Clone(c *gin.Context) {
+	id := c.Param("id")
+	var req CloneRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	cloneID, err := h.ExecutorService.CloneRuntime(c, id, req.Prompt)
+	if err != nil {
+		if respondRejected(c, err) {
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	runtimeData, err := h.ExecutorService.GetRuntime(c, cloneID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	runtime := runtimeData.Snapshot()
+	c.JSON(200, gin.H{"status": runtime.State, "executerID": cloneID, "clonedFrom": id, "title": runtime.Title, "url": h.Config.GetPublicURL() + models.RuntimePrefix(runtime.Tenant, cloneID)})
+}
+
+func (h *MainHandler) Versions(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	versions, err := h.ExecutorService.ListVersions(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"versions": versions})
+}
+
+func (h *MainHandler) Rollback(c *gin.Context) {
+	id := c.Param("id")
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid version"})
+		return
+	}
+	if _, err := h.ExecutorService.GetRuntime(c, id); err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	rollback, err := h.ExecutorService.RollbackRuntime(c, id, version)
+	if errors.Is(err, executer.ErrVersionNotFound) {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"status": "rolled back", "version": rollback.Version, "rolledBackTo": version})
 }
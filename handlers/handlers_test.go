@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter returns a router serving the endpoints of a handler whose executer is mock,
+// under the paths CreateRoutes gives them.
+func newTestRouter(t *testing.T, mock *ExecuterServiceMock) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	h := NewMainHandler(mock, &config.Config{PublicURL: "http://aegisx.test", PromptStore: dir, ShareStore: dir + "/links.json"}, util.NewUsageTracker())
+	router := gin.New()
+	router.POST("/execute", h.Execute)
+	router.POST("/stop/:id", h.Stop)
+	router.DELETE("/runtime/:id", h.Delete)
+	router.POST("/runtime/:id/kill", h.Kill)
+	router.POST("/runtime/:id/rollback/:version", h.Rollback)
+	router.PUT("/runtime/:id/domain", h.SetDomain)
+	router.GET("/status/:id", h.Status)
+	return router
+}
+
+func serve(router *gin.Engine, method string, path string, body any, header http.Header) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&reader).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func runningRuntime(id string) *models.Runtime {
+	return models.NewRuntime(models.RuntimeInfo{ID: id, State: models.RSRUN, Title: "Notes", Model: "mock", Port: 20000})
+}
+
+func TestExecute(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		NewDeduplicatedExecutionFunc: func(ctx context.Context, prompt string, opts executer.ExecutionOptions) (string, bool, error) {
+			return "r1", false, nil
+		},
+		GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+			return runningRuntime(runtimeID), nil
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	w := serve(router, http.MethodPost, "/execute?force=true", ExecuteRequest{Prompt: "a notes app", Model: "mock"}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /execute = %d %s", w.Code, w.Body)
+	}
+	var res ExecuteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ExecuterID != "r1" || res.Title != "Notes" || res.URL != "http://aegisx.test/runtime/r1" {
+		t.Errorf("unexpected response %+v", res)
+	}
+	calls := mock.Calls("NewDeduplicatedExecution")
+	if len(calls) != 1 {
+		t.Fatalf("NewDeduplicatedExecution called %d times, want once", len(calls))
+	}
+	if prompt, opts := calls[0][1].(string), calls[0][2].(executer.ExecutionOptions); prompt != "a notes app" || !opts.Force || opts.Model != "mock" {
+		t.Errorf("NewDeduplicatedExecution got prompt %q and options %+v", prompt, opts)
+	}
+}
+
+func TestExecuteIdempotencyKey(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		NewIdempotentExecutionFunc: func(ctx context.Context, key string, prompt string, opts executer.ExecutionOptions) (string, bool, error) {
+			return "r1", true, nil
+		},
+		GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+			return runningRuntime(runtimeID), nil
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	w := serve(router, http.MethodPost, "/execute", ExecuteRequest{Prompt: "a notes app"}, http.Header{"Idempotency-Key": {"key-1"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /execute = %d %s", w.Code, w.Body)
+	}
+	calls := mock.Calls("NewIdempotentExecution")
+	if len(calls) != 1 || calls[0][1] != "key-1" {
+		t.Errorf("NewIdempotentExecution calls = %v, want one with key-1", calls)
+	}
+	if calls := mock.Calls("NewDeduplicatedExecution"); len(calls) != 0 {
+		t.Errorf("NewDeduplicatedExecution called %d times, want never", len(calls))
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    ExecuteRequest
+		err    error
+		status int
+	}{
+		{"missing prompt", ExecuteRequest{}, nil, http.StatusBadRequest},
+		{"invalid strategy", ExecuteRequest{Prompt: "app", Strategy: "guess"}, nil, http.StatusBadRequest},
+		{"paused", ExecuteRequest{Prompt: "app"}, executer.ErrExecutionsPaused, http.StatusServiceUnavailable},
+		{"reused key", ExecuteRequest{Prompt: "app"}, executer.ErrIdempotencyKeyReused, http.StatusConflict},
+		{"unsupported language", ExecuteRequest{Prompt: "app"}, executer.ErrUnsupportedLanguage, http.StatusBadRequest},
+		{"other", ExecuteRequest{Prompt: "app"}, errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &ExecuterServiceMock{
+				NewDeduplicatedExecutionFunc: func(ctx context.Context, prompt string, opts executer.ExecutionOptions) (string, bool, error) {
+					return "", false, tt.err
+				},
+			}
+			router := newTestRouter(t, mock)
+			if w := serve(router, http.MethodPost, "/execute", tt.req, nil); w.Code != tt.status {
+				t.Errorf("POST /execute = %d %s, want %d", w.Code, w.Body, tt.status)
+			}
+			// Requests rejected before execution never reach the executer.
+			if tt.err == nil && len(mock.Calls("NewDeduplicatedExecution")) != 0 {
+				t.Error("an invalid request reached the executer")
+			}
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		StopRuntimeFunc: func(ctx context.Context, runtimeID string) error {
+			if runtimeID != "r1" {
+				return errors.New("runtime not found")
+			}
+			return nil
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	if w := serve(router, http.MethodPost, "/stop/r1", nil, nil); w.Code != http.StatusAccepted {
+		t.Errorf("POST /stop/r1 = %d %s, want 202", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPost, "/stop/r2", nil, nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("POST /stop/r2 = %d %s, want 500", w.Code, w.Body)
+	}
+	if calls := mock.Calls("StopRuntime"); len(calls) != 2 {
+		t.Errorf("StopRuntime called %d times, want twice", len(calls))
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		getErr    error
+		deleteErr error
+		status    int
+	}{
+		{"deleted", nil, nil, http.StatusOK},
+		{"unknown", errors.New("runtime not found"), nil, http.StatusNotFound},
+		{"pinned", nil, executer.ErrRuntimePinned, http.StatusConflict},
+		{"failed", nil, errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &ExecuterServiceMock{
+				GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return runningRuntime(runtimeID), nil
+				},
+				DeleteRuntimeFunc: func(ctx context.Context, runtimeID string) error {
+					return tt.deleteErr
+				},
+			}
+			router := newTestRouter(t, mock)
+			if w := serve(router, http.MethodDelete, "/runtime/r1", nil, nil); w.Code != tt.status {
+				t.Errorf("DELETE /runtime/r1 = %d %s, want %d", w.Code, w.Body, tt.status)
+			}
+			if tt.getErr != nil && len(mock.Calls("DeleteRuntime")) != 0 {
+				t.Error("an unknown runtime was deleted")
+			}
+		})
+	}
+}
+
+func TestKill(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		KillRuntimeFunc: func(ctx context.Context, runtimeID string, force bool) (*models.KillReport, error) {
+			return &models.KillReport{Force: force}, nil
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	if w := serve(router, http.MethodPost, "/runtime/r1/kill?force=true", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("POST /runtime/r1/kill = %d %s", w.Code, w.Body)
+	}
+	calls := mock.Calls("KillRuntime")
+	if len(calls) != 1 || calls[0][1] != "r1" || calls[0][2] != true {
+		t.Errorf("KillRuntime calls = %v, want one forced kill of r1", calls)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+			if runtimeID != "r1" {
+				return nil, errors.New("runtime not found")
+			}
+			return runningRuntime(runtimeID), nil
+		},
+		RollbackRuntimeFunc: func(ctx context.Context, runtimeID string, version int) (*executer.CodeVersion, error) {
+			if version != 2 {
+				return nil, fmt.Errorf("%w: version %d of runtime %s", executer.ErrVersionNotFound, version, runtimeID)
+			}
+			return &executer.CodeVersion{Version: 4}, nil
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	if w := serve(router, http.MethodPost, "/runtime/r1/rollback/2", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("POST /runtime/r1/rollback/2 = %d %s", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPost, "/runtime/r1/rollback/9", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("POST /runtime/r1/rollback/9 = %d %s, want 404", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPost, "/runtime/r2/rollback/2", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("POST /runtime/r2/rollback/2 = %d %s, want 404", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPost, "/runtime/r1/rollback/latest", nil, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("POST /runtime/r1/rollback/latest = %d %s, want 400", w.Code, w.Body)
+	}
+}
+
+func TestSetDomain(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+			return runningRuntime(runtimeID), nil
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	if w := serve(router, http.MethodPut, "/runtime/r1/domain", DomainRequest{Host: "notes.example.com"}, nil); w.Code != http.StatusOK {
+		t.Fatalf("PUT /runtime/r1/domain = %d %s", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPut, "/runtime/r2/domain", DomainRequest{Host: "notes.example.com"}, nil); w.Code != http.StatusConflict {
+		t.Errorf("mapping a taken domain = %d %s, want 409", w.Code, w.Body)
+	}
+	// The node's own host would send the control API to the runtime.
+	for _, host := range []string{"aegisx.test", "AEGISX.test."} {
+		if w := serve(router, http.MethodPut, "/runtime/r2/domain", DomainRequest{Host: host}, nil); w.Code != http.StatusConflict {
+			t.Errorf("mapping the public host %s = %d %s, want 409", host, w.Code, w.Body)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	mock := &ExecuterServiceMock{
+		GetRuntimeFunc: func(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+			return runningRuntime(runtimeID), nil
+		},
+		RuntimeResourcesFunc: func(runtimeID string) *models.RuntimeResources {
+			return &models.RuntimeResources{Goroutines: 3}
+		},
+	}
+	router := newTestRouter(t, mock)
+
+	w := serve(router, http.MethodGet, "/status/r1", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /status/r1 = %d %s", w.Code, w.Body)
+	}
+	var info models.RuntimeInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != "r1" || info.State != models.RSRUN || info.Resources == nil || info.Resources.Goroutines != 3 {
+		t.Errorf("unexpected status %+v", info)
+	}
+}
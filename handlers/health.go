@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/gcottom/aegisx/services/health"
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz is the liveness probe: it answers as long as the server serves requests.
+//
+// @operation Healthz
+// @summary Check that the server is alive
+// @router GET /healthz
+// @success 200 HealthResponse
+func (h *MainHandler) Healthz(c *gin.Context) {
+	c.JSON(200, HealthResponse{Status: "ok"})
+}
+
+// Readyz is the readiness probe: it runs the self-tests and answers 503 when one fails, e.g.
+// while the node is draining or its store is not writable.
+//
+// @operation Readyz
+// @summary Check that the server is ready to serve executions
+// @router GET /readyz
+// @success 200 Readiness
+// @failure 503 Readiness
+func (h *MainHandler) Readyz(c *gin.Context) {
+	report := health.Run(c.Request.Context(), h.HealthChecks)
+	if !report.Ready {
+		c.JSON(503, report)
+		return
+	}
+	c.JSON(200, report)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gin-gonic/gin"
+)
+
+// Result returns the output of a job, the runtime kind that runs to completion instead of
+// serving its prefix. A job without a result, because it is still running or failed, answers
+// 409 with its state.
+//
+// @operation Result
+// @summary Get the result of a job
+// @router GET /runtime/{id}/result
+// @param id path string true "Runtime ID"
+// @success 200 ResultResponse
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+// @failure 409 ErrorResponse
+func (h *MainHandler) Result(c *gin.Context) {
+	id := c.Param("id")
+	runtimeData, err := h.ExecutorService.GetRuntime(c, id)
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	runtime := runtimeData.Snapshot()
+	if runtime.Kind != executer.KindJob {
+		c.JSON(400, ErrorResponse{Error: "runtime " + id + " is not a job"})
+		return
+	}
+	if runtime.Result == nil {
+		c.JSON(409, ErrorResponse{Error: "job " + id + " has no result: it is " + string(runtime.State)})
+		return
+	}
+	c.JSON(200, ResultResponse{State: runtime.State, Output: runtime.Result.Output, Truncated: runtime.Result.Truncated, FinishedAt: runtime.FinishedAt})
+}
+
+// RuntimeOpenAPI serves the OpenAPI document an API runtime was generated with, describing the
+// JSON API under its prefix.
+func (h *MainHandler) RuntimeOpenAPI(c *gin.Context) {
+	id := c.Param("id")
+	runtimeData, err := h.ExecutorService.GetRuntime(c, id)
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	path := filepath.Join(h.ExecutorService.StaticDir(id), executer.OpenAPIDocName)
+	if _, err := os.Stat(path); runtimeData.Snapshot().Kind != executer.KindAPI || err != nil {
+		c.JSON(404, ErrorResponse{Error: "runtime " + id + " has no OpenAPI document"})
+		return
+	}
+	c.File(path)
+}
+
+// runtimeURL is where a runtime is used: the prefix it is served under, a job's result or a
+// worker's logs.
+func (h *MainHandler) runtimeURL(runtime models.RuntimeInfo) string {
+	url := h.Config.GetPublicURL() + models.RuntimePrefix(runtime.Tenant, runtime.ID)
+	switch runtime.Kind {
+	case executer.KindJob:
+		url += "/result"
+	case executer.KindWorker:
+		url += "/logs"
+	}
+	return url
+}
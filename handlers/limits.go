@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/notify"
+	"github.com/gcottom/aegisx/services/quota"
+	"github.com/gin-gonic/gin"
+)
+
+// LimitError rejects an execution that would exceed a rate limit or quota. RetryAfter is when
+// the rate limit allows it again, or 0 when waiting does not help.
+type LimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+// Limits is middleware that reports rate limit and quota state in response headers, and the
+// queue position of execute and compose requests. Clients of the default namespace are counted
+// by IP; a tenant's requests share its own limits and runtime quota, and those that would
+// exceed them are rejected. A compose request must leave room in the runtime quota for the
+// largest composite app.
+func (h *MainHandler) Limits(c *gin.Context) {
+	path := strings.TrimPrefix(c.FullPath(), routes.TenantPrefix)
+	isExecute := c.Request.Method == "POST" && (path == "/execute" || path == "/compose")
+	tenant := c.Param("tenant")
+	var err error
+	if isExecute {
+		runtimes := 1
+		if path == "/compose" {
+			runtimes = executer.MaxGroupSize
+		}
+		err = h.AdmitExecution(tenant, c.ClientIP(), runtimes)
+	}
+	limits, key, active := h.namespaceLimits(tenant, c.ClientIP())
+	rate := limits.Peek(key)
+	if rate.Limit > 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rate.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(rate.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(rate.Reset.Unix(), 10))
+	}
+	runtimesLeft := limits.RemainingRuntimes(active)
+	if runtimesLeft >= 0 {
+		c.Header("X-Quota-Remaining-Runtimes", strconv.Itoa(runtimesLeft))
+	}
+	tokensLeft := limits.RemainingTokens(h.Usage.TotalTokens())
+	if tokensLeft >= 0 {
+		c.Header("X-Quota-Remaining-Tokens", strconv.Itoa(tokensLeft))
+	}
+	if isExecute {
+		c.Header("X-Queue-Position", strconv.Itoa(h.ExecutorService.QueuePosition()))
+	}
+
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(limitErr.RetryAfter.Seconds())+1))
+		}
+		c.AbortWithStatusJSON(429, gin.H{"error": limitErr.Message})
+		return
+	}
+	c.Next()
+}
+
+// AdmitExecution counts an execution of the given number of runtimes by the client key in
+// tenant's namespace against the rate limit and, for a tenant, returns a *LimitError when it
+// exceeds the rate limit, the runtime quota or the token budget. The default namespace only
+// reports its limits, counting each client by its own key.
+func (h *MainHandler) AdmitExecution(tenant string, key string, runtimes int) error {
+	limits, key, active := h.namespaceLimits(tenant, key)
+	rate := limits.Allow(key)
+	if tenant == "" {
+		return nil
+	}
+	runtimesLeft := limits.RemainingRuntimes(active)
+	switch {
+	case !rate.Allowed:
+		return &LimitError{Message: "rate limit exceeded", RetryAfter: time.Until(rate.Reset)}
+	case runtimesLeft >= 0 && runtimesLeft < runtimes:
+		h.Notifier.Notify(notify.Event{Type: notify.EventQuotaExceeded, Tenant: tenant, Message: "Execute request rejected: runtime quota exceeded"})
+		return &LimitError{Message: "runtime quota exceeded"}
+	case limits.RemainingTokens(h.Usage.TotalTokens()) == 0:
+		h.Notifier.Notify(notify.Event{Type: notify.EventQuotaExceeded, Tenant: tenant, Message: "Execute request rejected: token quota exhausted"})
+		return &LimitError{Message: "token quota exhausted"}
+	}
+	return nil
+}
+
+// namespaceLimits returns the limits of tenant's namespace, the key they count a client with
+// key under and the number of active runtimes they cover.
+func (h *MainHandler) namespaceLimits(tenant string, key string) (*quota.QuotaService, string, int) {
+	if tenant != "" {
+		return h.TenantQuotas[tenant], tenant, h.ExecutorService.ActiveTenantRuntimeCount(tenant)
+	}
+	return h.Quota, key, h.ExecutorService.ActiveRuntimeCount()
+}
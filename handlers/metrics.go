@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"bytes"
+
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+func (h *MainHandler) Metrics(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := metrics.Default.WritePrometheus(&buf); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(200, "text/plain; version=0.0.4", buf.Bytes())
+}
+
+func (h *MainHandler) GrafanaDashboard(c *gin.Context) {
+	c.JSON(200, metrics.Default.GrafanaDashboard())
+}
+
+func (h *MainHandler) AlertRules(c *gin.Context) {
+	data, err := yaml.Marshal(metrics.AlertRules())
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(200, "application/yaml", data)
+}
@@ -0,0 +1,501 @@
+// Code generated by mock-gen from services.go. DO NOT EDIT.
+
+package handlers
+
+import (
+	"context"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gin-gonic/gin"
+	"sync"
+)
+
+// ExecuterServiceMock is a mock of ExecuterService. Set the function of every method the code under test calls.
+type ExecuterServiceMock struct {
+	ActiveRuntimeCountFunc       func() int
+	ActiveTenantRuntimeCountFunc func(tenant string) int
+	ArchiveRuntimeFunc           func(ctx context.Context, runtimeID string) error
+	CloneRuntimeFunc             func(ctx context.Context, runtimeID string, modification string) (string, error)
+	ComposeAppFunc               func(ctx context.Context, prompt string, backends []executer.ComposedBackend, opts executer.ExecutionOptions) (*executer.Group, error)
+	DeleteRuntimeFunc            func(ctx context.Context, runtimeID string) error
+	DiagnoseRuntimeFunc          func(ctx context.Context, runtimeID string) ([]executer.FixOption, error)
+	DrainFunc                    func()
+	DrainingFunc                 func() bool
+	EvictRuntimeFunc             func(ctx context.Context, runtimeID string) error
+	ExecutionsPausedFunc         func() bool
+	GetGroupFunc                 func(tenant string, groupID string) (*executer.Group, error)
+	GetRuntimeFunc               func(ctx context.Context, runtimeID string) (*models.Runtime, error)
+	HealthyRuntimesFunc          func() []models.RuntimeInfo
+	KillRuntimeFunc              func(ctx context.Context, runtimeID string, force bool) (*models.KillReport, error)
+	ListSnapshotsFunc            func(runtimeID string) ([]*executer.Snapshot, error)
+	ListVersionsFunc             func(runtimeID string) ([]*executer.CodeVersion, error)
+	NewDeduplicatedExecutionFunc func(ctx context.Context, prompt string, opts executer.ExecutionOptions) (string, bool, error)
+	NewIdempotentExecutionFunc   func(ctx context.Context, key string, prompt string, opts executer.ExecutionOptions) (string, bool, error)
+	PauseExecutionsFunc          func()
+	QueuePositionFunc            func() int
+	ResourceUsageFunc            func() executer.ResourceUsage
+	RestartRuntimeFunc           func(ctx context.Context, runtimeID string) error
+	RestoreRuntimeFunc           func(ctx context.Context, runtimeID string, snapshotID string) (*executer.Snapshot, error)
+	ResumeExecutionsFunc         func()
+	RetryingFunc                 func(runtimeID string) bool
+	RollbackRuntimeFunc          func(ctx context.Context, runtimeID string, version int) (*executer.CodeVersion, error)
+	RuntimeLogsFunc              func(ctx context.Context, runtimeID string) ([]string, error)
+	RuntimeResourcesFunc         func(runtimeID string) *models.RuntimeResources
+	ScreenshotPathFunc           func(runtimeID string) string
+	SeedRuntimeFunc              func(ctx context.Context, runtimeID string, route string, records []map[string]any) ([]executer.SeedResult, error)
+	SetPinnedFunc                func(ctx context.Context, runtimeID string, pinned bool) error
+	SetScheduleFunc              func(ctx context.Context, runtimeID string, schedule *models.Schedule) error
+	SnapshotRuntimeFunc          func(ctx context.Context, runtimeID string) (*executer.Snapshot, error)
+	StaticDirFunc                func(runtimeID string) string
+	StopAllRuntimesFunc          func(ctx context.Context) []string
+	StopGroupFunc                func(ctx context.Context, tenant string, groupID string) (*executer.Group, error)
+	StopRuntimeFunc              func(ctx context.Context, runtimeID string) error
+	SubscribeLogsFunc            func(ctx context.Context, runtimeID string) ([]string, <-chan string, func(), error)
+	TenantRuntimesFunc           func(tenant string) []models.RuntimeInfo
+	ThumbnailPathFunc            func(runtimeID string) string
+	UnarchiveRuntimeFunc         func(ctx context.Context, runtimeID string) error
+	UpdateRuntimeCodeFunc        func(ctx context.Context, runtimeID string, program string, reason string) (*executer.CodeVersion, error)
+
+	mu    sync.Mutex
+	calls map[string][][]any
+}
+
+var _ ExecuterService = (*ExecuterServiceMock)(nil)
+
+// Calls returns the arguments of every call of method, in order.
+func (m *ExecuterServiceMock) Calls(method string) [][]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]any(nil), m.calls[method]...)
+}
+
+func (m *ExecuterServiceMock) record(method string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = map[string][][]any{}
+	}
+	m.calls[method] = append(m.calls[method], args)
+}
+
+// ActiveRuntimeCount calls ActiveRuntimeCountFunc.
+func (m *ExecuterServiceMock) ActiveRuntimeCount() int {
+	m.record("ActiveRuntimeCount")
+	if m.ActiveRuntimeCountFunc == nil {
+		panic("ExecuterServiceMock.ActiveRuntimeCount called without ActiveRuntimeCountFunc")
+	}
+	return m.ActiveRuntimeCountFunc()
+}
+
+// ActiveTenantRuntimeCount calls ActiveTenantRuntimeCountFunc.
+func (m *ExecuterServiceMock) ActiveTenantRuntimeCount(tenant string) int {
+	m.record("ActiveTenantRuntimeCount", tenant)
+	if m.ActiveTenantRuntimeCountFunc == nil {
+		panic("ExecuterServiceMock.ActiveTenantRuntimeCount called without ActiveTenantRuntimeCountFunc")
+	}
+	return m.ActiveTenantRuntimeCountFunc(tenant)
+}
+
+// ArchiveRuntime calls ArchiveRuntimeFunc.
+func (m *ExecuterServiceMock) ArchiveRuntime(ctx context.Context, runtimeID string) error {
+	m.record("ArchiveRuntime", ctx, runtimeID)
+	if m.ArchiveRuntimeFunc == nil {
+		panic("ExecuterServiceMock.ArchiveRuntime called without ArchiveRuntimeFunc")
+	}
+	return m.ArchiveRuntimeFunc(ctx, runtimeID)
+}
+
+// CloneRuntime calls CloneRuntimeFunc.
+func (m *ExecuterServiceMock) CloneRuntime(ctx context.Context, runtimeID string, modification string) (string, error) {
+	m.record("CloneRuntime", ctx, runtimeID, modification)
+	if m.CloneRuntimeFunc == nil {
+		panic("ExecuterServiceMock.CloneRuntime called without CloneRuntimeFunc")
+	}
+	return m.CloneRuntimeFunc(ctx, runtimeID, modification)
+}
+
+// ComposeApp calls ComposeAppFunc.
+func (m *ExecuterServiceMock) ComposeApp(ctx context.Context, prompt string, backends []executer.ComposedBackend, opts executer.ExecutionOptions) (*executer.Group, error) {
+	m.record("ComposeApp", ctx, prompt, backends, opts)
+	if m.ComposeAppFunc == nil {
+		panic("ExecuterServiceMock.ComposeApp called without ComposeAppFunc")
+	}
+	return m.ComposeAppFunc(ctx, prompt, backends, opts)
+}
+
+// DeleteRuntime calls DeleteRuntimeFunc.
+func (m *ExecuterServiceMock) DeleteRuntime(ctx context.Context, runtimeID string) error {
+	m.record("DeleteRuntime", ctx, runtimeID)
+	if m.DeleteRuntimeFunc == nil {
+		panic("ExecuterServiceMock.DeleteRuntime called without DeleteRuntimeFunc")
+	}
+	return m.DeleteRuntimeFunc(ctx, runtimeID)
+}
+
+// DiagnoseRuntime calls DiagnoseRuntimeFunc.
+func (m *ExecuterServiceMock) DiagnoseRuntime(ctx context.Context, runtimeID string) ([]executer.FixOption, error) {
+	m.record("DiagnoseRuntime", ctx, runtimeID)
+	if m.DiagnoseRuntimeFunc == nil {
+		panic("ExecuterServiceMock.DiagnoseRuntime called without DiagnoseRuntimeFunc")
+	}
+	return m.DiagnoseRuntimeFunc(ctx, runtimeID)
+}
+
+// Drain calls DrainFunc.
+func (m *ExecuterServiceMock) Drain() {
+	m.record("Drain")
+	if m.DrainFunc == nil {
+		panic("ExecuterServiceMock.Drain called without DrainFunc")
+	}
+	m.DrainFunc()
+}
+
+// Draining calls DrainingFunc.
+func (m *ExecuterServiceMock) Draining() bool {
+	m.record("Draining")
+	if m.DrainingFunc == nil {
+		panic("ExecuterServiceMock.Draining called without DrainingFunc")
+	}
+	return m.DrainingFunc()
+}
+
+// EvictRuntime calls EvictRuntimeFunc.
+func (m *ExecuterServiceMock) EvictRuntime(ctx context.Context, runtimeID string) error {
+	m.record("EvictRuntime", ctx, runtimeID)
+	if m.EvictRuntimeFunc == nil {
+		panic("ExecuterServiceMock.EvictRuntime called without EvictRuntimeFunc")
+	}
+	return m.EvictRuntimeFunc(ctx, runtimeID)
+}
+
+// ExecutionsPaused calls ExecutionsPausedFunc.
+func (m *ExecuterServiceMock) ExecutionsPaused() bool {
+	m.record("ExecutionsPaused")
+	if m.ExecutionsPausedFunc == nil {
+		panic("ExecuterServiceMock.ExecutionsPaused called without ExecutionsPausedFunc")
+	}
+	return m.ExecutionsPausedFunc()
+}
+
+// GetGroup calls GetGroupFunc.
+func (m *ExecuterServiceMock) GetGroup(tenant string, groupID string) (*executer.Group, error) {
+	m.record("GetGroup", tenant, groupID)
+	if m.GetGroupFunc == nil {
+		panic("ExecuterServiceMock.GetGroup called without GetGroupFunc")
+	}
+	return m.GetGroupFunc(tenant, groupID)
+}
+
+// GetRuntime calls GetRuntimeFunc.
+func (m *ExecuterServiceMock) GetRuntime(ctx context.Context, runtimeID string) (*models.Runtime, error) {
+	m.record("GetRuntime", ctx, runtimeID)
+	if m.GetRuntimeFunc == nil {
+		panic("ExecuterServiceMock.GetRuntime called without GetRuntimeFunc")
+	}
+	return m.GetRuntimeFunc(ctx, runtimeID)
+}
+
+// HealthyRuntimes calls HealthyRuntimesFunc.
+func (m *ExecuterServiceMock) HealthyRuntimes() []models.RuntimeInfo {
+	m.record("HealthyRuntimes")
+	if m.HealthyRuntimesFunc == nil {
+		panic("ExecuterServiceMock.HealthyRuntimes called without HealthyRuntimesFunc")
+	}
+	return m.HealthyRuntimesFunc()
+}
+
+// KillRuntime calls KillRuntimeFunc.
+func (m *ExecuterServiceMock) KillRuntime(ctx context.Context, runtimeID string, force bool) (*models.KillReport, error) {
+	m.record("KillRuntime", ctx, runtimeID, force)
+	if m.KillRuntimeFunc == nil {
+		panic("ExecuterServiceMock.KillRuntime called without KillRuntimeFunc")
+	}
+	return m.KillRuntimeFunc(ctx, runtimeID, force)
+}
+
+// ListSnapshots calls ListSnapshotsFunc.
+func (m *ExecuterServiceMock) ListSnapshots(runtimeID string) ([]*executer.Snapshot, error) {
+	m.record("ListSnapshots", runtimeID)
+	if m.ListSnapshotsFunc == nil {
+		panic("ExecuterServiceMock.ListSnapshots called without ListSnapshotsFunc")
+	}
+	return m.ListSnapshotsFunc(runtimeID)
+}
+
+// ListVersions calls ListVersionsFunc.
+func (m *ExecuterServiceMock) ListVersions(runtimeID string) ([]*executer.CodeVersion, error) {
+	m.record("ListVersions", runtimeID)
+	if m.ListVersionsFunc == nil {
+		panic("ExecuterServiceMock.ListVersions called without ListVersionsFunc")
+	}
+	return m.ListVersionsFunc(runtimeID)
+}
+
+// NewDeduplicatedExecution calls NewDeduplicatedExecutionFunc.
+func (m *ExecuterServiceMock) NewDeduplicatedExecution(ctx context.Context, prompt string, opts executer.ExecutionOptions) (string, bool, error) {
+	m.record("NewDeduplicatedExecution", ctx, prompt, opts)
+	if m.NewDeduplicatedExecutionFunc == nil {
+		panic("ExecuterServiceMock.NewDeduplicatedExecution called without NewDeduplicatedExecutionFunc")
+	}
+	return m.NewDeduplicatedExecutionFunc(ctx, prompt, opts)
+}
+
+// NewIdempotentExecution calls NewIdempotentExecutionFunc.
+func (m *ExecuterServiceMock) NewIdempotentExecution(ctx context.Context, key string, prompt string, opts executer.ExecutionOptions) (string, bool, error) {
+	m.record("NewIdempotentExecution", ctx, key, prompt, opts)
+	if m.NewIdempotentExecutionFunc == nil {
+		panic("ExecuterServiceMock.NewIdempotentExecution called without NewIdempotentExecutionFunc")
+	}
+	return m.NewIdempotentExecutionFunc(ctx, key, prompt, opts)
+}
+
+// PauseExecutions calls PauseExecutionsFunc.
+func (m *ExecuterServiceMock) PauseExecutions() {
+	m.record("PauseExecutions")
+	if m.PauseExecutionsFunc == nil {
+		panic("ExecuterServiceMock.PauseExecutions called without PauseExecutionsFunc")
+	}
+	m.PauseExecutionsFunc()
+}
+
+// QueuePosition calls QueuePositionFunc.
+func (m *ExecuterServiceMock) QueuePosition() int {
+	m.record("QueuePosition")
+	if m.QueuePositionFunc == nil {
+		panic("ExecuterServiceMock.QueuePosition called without QueuePositionFunc")
+	}
+	return m.QueuePositionFunc()
+}
+
+// ResourceUsage calls ResourceUsageFunc.
+func (m *ExecuterServiceMock) ResourceUsage() executer.ResourceUsage {
+	m.record("ResourceUsage")
+	if m.ResourceUsageFunc == nil {
+		panic("ExecuterServiceMock.ResourceUsage called without ResourceUsageFunc")
+	}
+	return m.ResourceUsageFunc()
+}
+
+// RestartRuntime calls RestartRuntimeFunc.
+func (m *ExecuterServiceMock) RestartRuntime(ctx context.Context, runtimeID string) error {
+	m.record("RestartRuntime", ctx, runtimeID)
+	if m.RestartRuntimeFunc == nil {
+		panic("ExecuterServiceMock.RestartRuntime called without RestartRuntimeFunc")
+	}
+	return m.RestartRuntimeFunc(ctx, runtimeID)
+}
+
+// RestoreRuntime calls RestoreRuntimeFunc.
+func (m *ExecuterServiceMock) RestoreRuntime(ctx context.Context, runtimeID string, snapshotID string) (*executer.Snapshot, error) {
+	m.record("RestoreRuntime", ctx, runtimeID, snapshotID)
+	if m.RestoreRuntimeFunc == nil {
+		panic("ExecuterServiceMock.RestoreRuntime called without RestoreRuntimeFunc")
+	}
+	return m.RestoreRuntimeFunc(ctx, runtimeID, snapshotID)
+}
+
+// ResumeExecutions calls ResumeExecutionsFunc.
+func (m *ExecuterServiceMock) ResumeExecutions() {
+	m.record("ResumeExecutions")
+	if m.ResumeExecutionsFunc == nil {
+		panic("ExecuterServiceMock.ResumeExecutions called without ResumeExecutionsFunc")
+	}
+	m.ResumeExecutionsFunc()
+}
+
+// Retrying calls RetryingFunc.
+func (m *ExecuterServiceMock) Retrying(runtimeID string) bool {
+	m.record("Retrying", runtimeID)
+	if m.RetryingFunc == nil {
+		panic("ExecuterServiceMock.Retrying called without RetryingFunc")
+	}
+	return m.RetryingFunc(runtimeID)
+}
+
+// RollbackRuntime calls RollbackRuntimeFunc.
+func (m *ExecuterServiceMock) RollbackRuntime(ctx context.Context, runtimeID string, version int) (*executer.CodeVersion, error) {
+	m.record("RollbackRuntime", ctx, runtimeID, version)
+	if m.RollbackRuntimeFunc == nil {
+		panic("ExecuterServiceMock.RollbackRuntime called without RollbackRuntimeFunc")
+	}
+	return m.RollbackRuntimeFunc(ctx, runtimeID, version)
+}
+
+// RuntimeLogs calls RuntimeLogsFunc.
+func (m *ExecuterServiceMock) RuntimeLogs(ctx context.Context, runtimeID string) ([]string, error) {
+	m.record("RuntimeLogs", ctx, runtimeID)
+	if m.RuntimeLogsFunc == nil {
+		panic("ExecuterServiceMock.RuntimeLogs called without RuntimeLogsFunc")
+	}
+	return m.RuntimeLogsFunc(ctx, runtimeID)
+}
+
+// RuntimeResources calls RuntimeResourcesFunc.
+func (m *ExecuterServiceMock) RuntimeResources(runtimeID string) *models.RuntimeResources {
+	m.record("RuntimeResources", runtimeID)
+	if m.RuntimeResourcesFunc == nil {
+		panic("ExecuterServiceMock.RuntimeResources called without RuntimeResourcesFunc")
+	}
+	return m.RuntimeResourcesFunc(runtimeID)
+}
+
+// ScreenshotPath calls ScreenshotPathFunc.
+func (m *ExecuterServiceMock) ScreenshotPath(runtimeID string) string {
+	m.record("ScreenshotPath", runtimeID)
+	if m.ScreenshotPathFunc == nil {
+		panic("ExecuterServiceMock.ScreenshotPath called without ScreenshotPathFunc")
+	}
+	return m.ScreenshotPathFunc(runtimeID)
+}
+
+// SeedRuntime calls SeedRuntimeFunc.
+func (m *ExecuterServiceMock) SeedRuntime(ctx context.Context, runtimeID string, route string, records []map[string]any) ([]executer.SeedResult, error) {
+	m.record("SeedRuntime", ctx, runtimeID, route, records)
+	if m.SeedRuntimeFunc == nil {
+		panic("ExecuterServiceMock.SeedRuntime called without SeedRuntimeFunc")
+	}
+	return m.SeedRuntimeFunc(ctx, runtimeID, route, records)
+}
+
+// SetPinned calls SetPinnedFunc.
+func (m *ExecuterServiceMock) SetPinned(ctx context.Context, runtimeID string, pinned bool) error {
+	m.record("SetPinned", ctx, runtimeID, pinned)
+	if m.SetPinnedFunc == nil {
+		panic("ExecuterServiceMock.SetPinned called without SetPinnedFunc")
+	}
+	return m.SetPinnedFunc(ctx, runtimeID, pinned)
+}
+
+// SetSchedule calls SetScheduleFunc.
+func (m *ExecuterServiceMock) SetSchedule(ctx context.Context, runtimeID string, schedule *models.Schedule) error {
+	m.record("SetSchedule", ctx, runtimeID, schedule)
+	if m.SetScheduleFunc == nil {
+		panic("ExecuterServiceMock.SetSchedule called without SetScheduleFunc")
+	}
+	return m.SetScheduleFunc(ctx, runtimeID, schedule)
+}
+
+// SnapshotRuntime calls SnapshotRuntimeFunc.
+func (m *ExecuterServiceMock) SnapshotRuntime(ctx context.Context, runtimeID string) (*executer.Snapshot, error) {
+	m.record("SnapshotRuntime", ctx, runtimeID)
+	if m.SnapshotRuntimeFunc == nil {
+		panic("ExecuterServiceMock.SnapshotRuntime called without SnapshotRuntimeFunc")
+	}
+	return m.SnapshotRuntimeFunc(ctx, runtimeID)
+}
+
+// StaticDir calls StaticDirFunc.
+func (m *ExecuterServiceMock) StaticDir(runtimeID string) string {
+	m.record("StaticDir", runtimeID)
+	if m.StaticDirFunc == nil {
+		panic("ExecuterServiceMock.StaticDir called without StaticDirFunc")
+	}
+	return m.StaticDirFunc(runtimeID)
+}
+
+// StopAllRuntimes calls StopAllRuntimesFunc.
+func (m *ExecuterServiceMock) StopAllRuntimes(ctx context.Context) []string {
+	m.record("StopAllRuntimes", ctx)
+	if m.StopAllRuntimesFunc == nil {
+		panic("ExecuterServiceMock.StopAllRuntimes called without StopAllRuntimesFunc")
+	}
+	return m.StopAllRuntimesFunc(ctx)
+}
+
+// StopGroup calls StopGroupFunc.
+func (m *ExecuterServiceMock) StopGroup(ctx context.Context, tenant string, groupID string) (*executer.Group, error) {
+	m.record("StopGroup", ctx, tenant, groupID)
+	if m.StopGroupFunc == nil {
+		panic("ExecuterServiceMock.StopGroup called without StopGroupFunc")
+	}
+	return m.StopGroupFunc(ctx, tenant, groupID)
+}
+
+// StopRuntime calls StopRuntimeFunc.
+func (m *ExecuterServiceMock) StopRuntime(ctx context.Context, runtimeID string) error {
+	m.record("StopRuntime", ctx, runtimeID)
+	if m.StopRuntimeFunc == nil {
+		panic("ExecuterServiceMock.StopRuntime called without StopRuntimeFunc")
+	}
+	return m.StopRuntimeFunc(ctx, runtimeID)
+}
+
+// SubscribeLogs calls SubscribeLogsFunc.
+func (m *ExecuterServiceMock) SubscribeLogs(ctx context.Context, runtimeID string) ([]string, <-chan string, func(), error) {
+	m.record("SubscribeLogs", ctx, runtimeID)
+	if m.SubscribeLogsFunc == nil {
+		panic("ExecuterServiceMock.SubscribeLogs called without SubscribeLogsFunc")
+	}
+	return m.SubscribeLogsFunc(ctx, runtimeID)
+}
+
+// TenantRuntimes calls TenantRuntimesFunc.
+func (m *ExecuterServiceMock) TenantRuntimes(tenant string) []models.RuntimeInfo {
+	m.record("TenantRuntimes", tenant)
+	if m.TenantRuntimesFunc == nil {
+		panic("ExecuterServiceMock.TenantRuntimes called without TenantRuntimesFunc")
+	}
+	return m.TenantRuntimesFunc(tenant)
+}
+
+// ThumbnailPath calls ThumbnailPathFunc.
+func (m *ExecuterServiceMock) ThumbnailPath(runtimeID string) string {
+	m.record("ThumbnailPath", runtimeID)
+	if m.ThumbnailPathFunc == nil {
+		panic("ExecuterServiceMock.ThumbnailPath called without ThumbnailPathFunc")
+	}
+	return m.ThumbnailPathFunc(runtimeID)
+}
+
+// UnarchiveRuntime calls UnarchiveRuntimeFunc.
+func (m *ExecuterServiceMock) UnarchiveRuntime(ctx context.Context, runtimeID string) error {
+	m.record("UnarchiveRuntime", ctx, runtimeID)
+	if m.UnarchiveRuntimeFunc == nil {
+		panic("ExecuterServiceMock.UnarchiveRuntime called without UnarchiveRuntimeFunc")
+	}
+	return m.UnarchiveRuntimeFunc(ctx, runtimeID)
+}
+
+// UpdateRuntimeCode calls UpdateRuntimeCodeFunc.
+func (m *ExecuterServiceMock) UpdateRuntimeCode(ctx context.Context, runtimeID string, program string, reason string) (*executer.CodeVersion, error) {
+	m.record("UpdateRuntimeCode", ctx, runtimeID, program, reason)
+	if m.UpdateRuntimeCodeFunc == nil {
+		panic("ExecuterServiceMock.UpdateRuntimeCode called without UpdateRuntimeCodeFunc")
+	}
+	return m.UpdateRuntimeCodeFunc(ctx, runtimeID, program, reason)
+}
+
+// DynamicRouteServiceMock is a mock of DynamicRouteService. Set the function of every method the code under test calls.
+type DynamicRouteServiceMock struct {
+	ForwardFunc func(c *gin.Context, runtimeID string) bool
+
+	mu    sync.Mutex
+	calls map[string][][]any
+}
+
+var _ DynamicRouteService = (*DynamicRouteServiceMock)(nil)
+
+// Calls returns the arguments of every call of method, in order.
+func (m *DynamicRouteServiceMock) Calls(method string) [][]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]any(nil), m.calls[method]...)
+}
+
+func (m *DynamicRouteServiceMock) record(method string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = map[string][][]any{}
+	}
+	m.calls[method] = append(m.calls[method], args)
+}
+
+// Forward calls ForwardFunc.
+func (m *DynamicRouteServiceMock) Forward(c *gin.Context, runtimeID string) bool {
+	m.record("Forward", c, runtimeID)
+	if m.ForwardFunc == nil {
+		panic("DynamicRouteServiceMock.Forward called without ForwardFunc")
+	}
+	return m.ForwardFunc(c, runtimeID)
+}
@@ -0,0 +1,10 @@
+package handlers
+
+import (
+	"github.com/gcottom/aegisx/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *MainHandler) OpenAPI(c *gin.Context) {
+	c.Data(200, "application/json", openapi.Spec)
+}
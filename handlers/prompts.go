@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gcottom/aegisx/services/prompts"
+	"github.com/gin-gonic/gin"
+)
+
+// promptErrorStatus maps prompt library errors to HTTP status codes.
+func promptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prompts.ErrNotFound):
+		return 404
+	case errors.Is(err, prompts.ErrExists):
+		return 409
+	case errors.Is(err, prompts.ErrInvalid), errors.Is(err, prompts.ErrMissingParameters):
+		return 400
+	}
+	return 500
+}
+
+// ListPrompts returns the saved prompts sorted by name.
+//
+// @operation ListPrompts
+// @summary List saved prompts
+// @router GET /prompts
+// @success 200 PromptListResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) ListPrompts(c *gin.Context) {
+	saved, err := h.Prompts.List()
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, PromptListResponse{Prompts: saved})
+}
+
+// CreatePrompt saves a new prompt template. Placeholders are written {{name}}.
+//
+// @operation CreatePrompt
+// @summary Save a prompt template
+// @router POST /prompts
+// @body Prompt
+// @success 201 Prompt
+// @failure 400 ErrorResponse
+// @failure 409 ErrorResponse
+func (h *MainHandler) CreatePrompt(c *gin.Context) {
+	var prompt prompts.Prompt
+	if err := c.ShouldBindJSON(&prompt); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := h.Prompts.Create(&prompt); err != nil {
+		c.JSON(promptErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(201, prompt)
+}
+
+// GetPrompt returns a saved prompt.
+//
+// @operation GetPrompt
+// @summary Get a saved prompt
+// @router GET /prompts/{name}
+// @param name path string true "Prompt name"
+// @success 200 Prompt
+// @failure 404 ErrorResponse
+func (h *MainHandler) GetPrompt(c *gin.Context) {
+	prompt, err := h.Prompts.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(promptErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, prompt)
+}
+
+// UpdatePrompt creates or replaces the saved prompt called name.
+//
+// @operation UpdatePrompt
+// @summary Create or replace a saved prompt
+// @router PUT /prompts/{name}
+// @param name path string true "Prompt name"
+// @body Prompt
+// @success 200 Prompt
+// @failure 400 ErrorResponse
+func (h *MainHandler) UpdatePrompt(c *gin.Context) {
+	var prompt prompts.Prompt
+	if err := c.ShouldBindJSON(&prompt); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	prompt.Name = c.Param("name")
+	if err := h.Prompts.Put(&prompt); err != nil {
+		c.JSON(promptErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, prompt)
+}
+
+// DeletePrompt removes a saved prompt.
+//
+// @operation DeletePrompt
+// @summary Delete a saved prompt
+// @router DELETE /prompts/{name}
+// @param name path string true "Prompt name"
+// @success 200 DeleteResponse
+// @failure 404 ErrorResponse
+func (h *MainHandler) DeletePrompt(c *gin.Context) {
+	if err := h.Prompts.Delete(c.Param("name")); err != nil {
+		c.JSON(promptErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, DeleteResponse{Status: "deleted"})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gcottom/aegisx/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Recover is middleware that turns a panic in a handler, including the proxy to a runtime,
+// into a 500 and a structured panic report instead of a dead connection and a bare stack
+// trace. A reverse proxy aborting a response it cannot finish is not a failure and is not
+// reported.
+func (h *MainHandler) Recover(c *gin.Context) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		if value == http.ErrAbortHandler {
+			c.Abort()
+			return
+		}
+		component := "api"
+		if strings.HasSuffix(c.FullPath(), "/*any") {
+			component = "proxy"
+		}
+		util.ReportPanic(util.PanicReport{
+			Component: component,
+			RuntimeID: c.Param("id"),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			RequestID: c.GetHeader("X-Request-ID"),
+			Value:     fmt.Sprint(value),
+			Stack:     string(debug.Stack()),
+		})
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		c.AbortWithStatusJSON(500, ErrorResponse{Error: "internal server error"})
+	}()
+	c.Next()
+}
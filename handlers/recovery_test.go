@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &MainHandler{}
+	router := gin.New()
+	router.Use(h.Recover)
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+	router.GET("/runtime/:id/*any", func(c *gin.Context) { panic("proxy boom") })
+	router.GET("/abort", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+	router.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "partial")
+		panic("late boom")
+	})
+	router.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/panic", http.StatusInternalServerError, `{"error":"internal server error"}`},
+		{"/runtime/r1/", http.StatusInternalServerError, `{"error":"internal server error"}`},
+		{"/abort", http.StatusOK, ""},
+		{"/written", http.StatusAccepted, "partial"},
+		// The server keeps serving after the panics.
+		{"/ok", http.StatusOK, "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status || w.Body.String() != tt.body {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.status, tt.body)
+			}
+		})
+	}
+}
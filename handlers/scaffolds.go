@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/gcottom/aegisx/services/scaffold"
+	"github.com/gin-gonic/gin"
+)
+
+// ListScaffolds returns the scaffolds runtimes can be created from with the scaffold source,
+// sorted by name. Placeholders are written [[name]].
+//
+// @operation ListScaffolds
+// @summary List the scaffolds runtimes can be created from
+// @router GET /scaffolds
+// @success 200 ScaffoldListResponse
+func (h *MainHandler) ListScaffolds(c *gin.Context) {
+	c.JSON(200, ScaffoldListResponse{Scaffolds: scaffold.List()})
+}
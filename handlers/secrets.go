@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gin-gonic/gin"
+)
+
+// secretErrorStatus maps secrets store errors to HTTP status codes.
+func secretErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, secrets.ErrNotFound):
+		return 404
+	case errors.Is(err, secrets.ErrInvalid):
+		return 400
+	}
+	return 500
+}
+
+// secretsEnabled responds with 404 and reports false when no secrets_master_key is configured.
+func (h *MainHandler) secretsEnabled(c *gin.Context) bool {
+	if h.Secrets == nil {
+		c.JSON(404, ErrorResponse{Error: "secrets are disabled"})
+		return false
+	}
+	return true
+}
+
+// ListSecrets returns the names of the tenant's secrets, never their values.
+//
+// @operation ListSecrets
+// @summary List secrets
+// @router GET /secrets
+// @success 200 SecretListResponse
+// @failure 404 ErrorResponse
+func (h *MainHandler) ListSecrets(c *gin.Context) {
+	if !h.secretsEnabled(c) {
+		return
+	}
+	c.JSON(200, SecretListResponse{Secrets: h.Secrets.List(c.Param("tenant"))})
+}
+
+// PutSecret registers or replaces a secret of the tenant. Prompts reference it as
+// {{secret:NAME}} and programs read it with secrets.Get from the aegisx/secrets package.
+//
+// @operation PutSecret
+// @summary Register or replace a secret
+// @router PUT /secrets/{name}
+// @param name path string true "Secret name"
+// @body SecretRequest
+// @success 200 Secret
+// @failure 400 ErrorResponse
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) PutSecret(c *gin.Context) {
+	if !h.secretsEnabled(c) {
+		return
+	}
+	var req SecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse{Error: err.Error()})
+		return
+	}
+	secret, err := h.Secrets.Put(c.Param("tenant"), c.Param("name"), req.Value)
+	if err != nil {
+		c.JSON(secretErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, secret)
+}
+
+// DeleteSecret removes a secret of the tenant. Running programs that read it get an error from
+// then on.
+//
+// @operation DeleteSecret
+// @summary Delete a secret
+// @router DELETE /secrets/{name}
+// @param name path string true "Secret name"
+// @success 200 DeleteResponse
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) DeleteSecret(c *gin.Context) {
+	if !h.secretsEnabled(c) {
+		return
+	}
+	if err := h.Secrets.Delete(c.Param("tenant"), c.Param("name")); err != nil {
+		c.JSON(secretErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, DeleteResponse{Status: "deleted"})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ../cmd/mock-gen -source services.go -out mocks.gen.go
+
+// ExecuterService generates, runs and manages the runtimes behind the API.
+// *executer.ExecuterService implements it; ExecuterServiceMock stands in for it in tests.
+type ExecuterService interface {
+	// Generation
+	NewDeduplicatedExecution(ctx context.Context, prompt string, opts executer.ExecutionOptions) (id string, duplicate bool, err error)
+	NewIdempotentExecution(ctx context.Context, key string, prompt string, opts executer.ExecutionOptions) (string, bool, error)
+	CloneRuntime(ctx context.Context, runtimeID string, modification string) (string, error)
+	ComposeApp(ctx context.Context, prompt string, backends []executer.ComposedBackend, opts executer.ExecutionOptions) (*executer.Group, error)
+	DiagnoseRuntime(ctx context.Context, runtimeID string) ([]executer.FixOption, error)
+	Retrying(runtimeID string) bool
+
+	// Lifecycle
+	GetRuntime(ctx context.Context, runtimeID string) (*models.Runtime, error)
+	StopRuntime(ctx context.Context, runtimeID string) error
+	StopGroup(ctx context.Context, tenant string, groupID string) (*executer.Group, error)
+	RestartRuntime(ctx context.Context, runtimeID string) error
+	KillRuntime(ctx context.Context, runtimeID string, force bool) (*models.KillReport, error)
+	DeleteRuntime(ctx context.Context, runtimeID string) error
+	EvictRuntime(ctx context.Context, runtimeID string) error
+	ArchiveRuntime(ctx context.Context, runtimeID string) error
+	UnarchiveRuntime(ctx context.Context, runtimeID string) error
+	SetPinned(ctx context.Context, runtimeID string, pinned bool) error
+	SetSchedule(ctx context.Context, runtimeID string, schedule *models.Schedule) error
+	SeedRuntime(ctx context.Context, runtimeID string, route string, records []map[string]any) ([]executer.SeedResult, error)
+
+	// Code versions and snapshots
+	UpdateRuntimeCode(ctx context.Context, runtimeID string, program string, reason string) (*executer.CodeVersion, error)
+	ListVersions(runtimeID string) ([]*executer.CodeVersion, error)
+	RollbackRuntime(ctx context.Context, runtimeID string, version int) (*executer.CodeVersion, error)
+	SnapshotRuntime(ctx context.Context, runtimeID string) (*executer.Snapshot, error)
+	ListSnapshots(runtimeID string) ([]*executer.Snapshot, error)
+	RestoreRuntime(ctx context.Context, runtimeID string, snapshotID string) (*executer.Snapshot, error)
+
+	// Observation
+	HealthyRuntimes() []models.RuntimeInfo
+	TenantRuntimes(tenant string) []models.RuntimeInfo
+	ActiveRuntimeCount() int
+	ActiveTenantRuntimeCount(tenant string) int
+	RuntimeLogs(ctx context.Context, runtimeID string) ([]string, error)
+	SubscribeLogs(ctx context.Context, runtimeID string) ([]string, <-chan string, func(), error)
+	RuntimeResources(runtimeID string) *models.RuntimeResources
+	GetGroup(tenant string, groupID string) (*executer.Group, error)
+	StaticDir(runtimeID string) string
+	ScreenshotPath(runtimeID string) string
+	ThumbnailPath(runtimeID string) string
+
+	// Node administration
+	ResourceUsage() executer.ResourceUsage
+	QueuePosition() int
+	StopAllRuntimes(ctx context.Context) []string
+	PauseExecutions()
+	ResumeExecutions()
+	ExecutionsPaused() bool
+	Drain()
+	Draining() bool
+}
+
+// DynamicRouteService proxies requests to the runtimes. *routes.DynamicRouteService implements
+// it; DynamicRouteServiceMock stands in for it in tests.
+type DynamicRouteService interface {
+	// Forward serves a request under the runtime's prefix through its proxy, reporting false
+	// when the runtime is not proxied.
+	Forward(c *gin.Context, runtimeID string) bool
+}
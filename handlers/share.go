@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/share"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gin-gonic/gin"
+)
+
+// qrScale is the default size in pixels of a QR code module.
+const qrScale = 8
+
+// Share returns a runtime's short link and the URL of its QR code, creating the link on the
+// first call.
+//
+// @operation Share
+// @summary Get a short link and QR code for a runtime
+// @router POST /runtime/{id}/share
+// @param id path string true "Runtime ID"
+// @success 200 ShareResponse
+// @failure 404 ErrorResponse
+// @failure 500 ErrorResponse
+func (h *MainHandler) Share(c *gin.Context) {
+	id := c.Param("id")
+	runtime, err := h.ExecutorService.GetRuntime(c, id)
+	if err != nil {
+		c.JSON(404, ErrorResponse{Error: err.Error()})
+		return
+	}
+	link, err := h.Links.Create(id, runtime.Snapshot().Tenant)
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	url := h.Config.GetPublicURL() + "/r/" + link.Slug
+	c.JSON(200, ShareResponse{Slug: link.Slug, URL: url, QRCodeURL: url + "/qr"})
+}
+
+// ShortLink redirects a short link to its runtime.
+func (h *MainHandler) ShortLink(c *gin.Context) {
+	link, ok := h.resolveLink(c)
+	if !ok {
+		return
+	}
+	c.Redirect(302, models.RuntimePrefix(link.Tenant, link.RuntimeID)+"/")
+}
+
+// ShareQRCode renders a short link as a QR code PNG; ?scale sets the pixels per module.
+func (h *MainHandler) ShareQRCode(c *gin.Context) {
+	link, ok := h.resolveLink(c)
+	if !ok {
+		return
+	}
+	scale := qrScale
+	if s, err := strconv.Atoi(c.Query("scale")); err == nil {
+		scale = max(1, min(s, 32))
+	}
+	png, err := util.QRCodePNG([]byte(h.Config.GetPublicURL()+"/r/"+link.Slug), scale)
+	if err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(200, "image/png", png)
+}
+
+// resolveLink finds the link of the slug parameter and checks its runtime still exists,
+// responding with a 404 otherwise.
+func (h *MainHandler) resolveLink(c *gin.Context) (share.Link, bool) {
+	link, ok := h.Links.Resolve(c.Param("slug"))
+	if !ok {
+		c.JSON(404, ErrorResponse{Error: "short link not found: " + c.Param("slug")})
+		return link, false
+	}
+	if _, err := h.ExecutorService.GetRuntime(c, link.RuntimeID); err != nil {
+		c.JSON(404, ErrorResponse{Error: "the runtime of short link " + link.Slug + " no longer exists"})
+		return link, false
+	}
+	return link, true
+}
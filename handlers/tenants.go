@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tenant is middleware that resolves the namespace of a request. Requests under /t/:tenant
+// must carry one of the tenant's API keys in the X-API-Key header or as a bearer token. A
+// runtime addressed by :id must belong to the request's namespace, so tenants and the default
+// namespace cannot reach each other's runtimes.
+func (h *MainHandler) Tenant(c *gin.Context) {
+	name := c.Param("tenant")
+	if name != "" {
+		tenant := h.Config.Tenant(name)
+		if tenant == nil || !tenant.Authorized(apiKey(c)) {
+			c.AbortWithStatusJSON(401, ErrorResponse{Error: "missing or invalid API key for tenant " + name})
+			return
+		}
+	}
+	if id := c.Param("id"); id != "" {
+		if runtime, err := h.ExecutorService.GetRuntime(c, id); err == nil && runtime.Snapshot().Tenant != name {
+			c.AbortWithStatusJSON(404, ErrorResponse{Error: "runtime not found: " + id})
+		}
+	}
+}
+
+func apiKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	key, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return key
+}
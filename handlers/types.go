@@ -1,5 +1,227 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/audit"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/moderation"
+	"github.com/gcottom/aegisx/services/prompts"
+	"github.com/gcottom/aegisx/services/scaffold"
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gcottom/aegisx/util"
+)
+
 type ExecuteRequest struct {
+	Prompt string `json:"prompt,omitempty"`
+	// PromptName executes a saved prompt instead of Prompt, rendered with Parameters.
+	PromptName string            `json:"promptName,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Strategy overrides the configured failure strategy: repair, regenerate or hybrid.
+	Strategy string `json:"strategy,omitempty"`
+	// Model overrides the configured generation model.
+	Model string `json:"model,omitempty"`
+	// MaxTokens, Temperature, TopP and ReasoningEffort override the configured generation
+	// parameters.
+	MaxTokens       int      `json:"maxTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	ReasoningEffort string   `json:"reasoningEffort,omitempty"`
+	// NoOutbound denies the program every outbound connection, whatever the configured egress
+	// policy allows.
+	NoOutbound bool `json:"noOutbound,omitempty"`
+	// Source picks where the program comes from: llm (the default) generates it from the
+	// prompt, scaffold renders Scaffold with ScaffoldParams and code runs Code. Programs from
+	// other sources than the LLM are written for the prefix /runtime/example and declare
+	// const AegisxPort; the prompt is optional for them.
+	Source         string            `json:"source,omitempty"`
+	Scaffold       string            `json:"scaffold,omitempty"`
+	ScaffoldParams map[string]string `json:"scaffoldParams,omitempty"`
+	Code           string            `json:"code,omitempty"`
+	// Kind is what the program is: web (the default), an app served under the runtime's
+	// prefix, api, a JSON API described at <prefix>/openapi.json, job, a program that runs to
+	// completion and whose output is its result, or worker, a background program that reports
+	// readiness and heartbeats instead of serving.
+	Kind string `json:"kind,omitempty"`
+	// Language is the program's language: go (the default), run by the embedded interpreter,
+	// or one configured under languages, e.g. python or javascript, run in a subprocess.
+	Language string `json:"language,omitempty"`
+}
+
+// ComposeRequest generates a composite app: a web frontend and the JSON API backends it calls.
+// Without Backends the model splits Prompt into the frontend and up to four backends; with
+// them, Prompt describes the frontend.
+type ComposeRequest struct {
+	Prompt   string                     `json:"prompt"`
+	Backends []executer.ComposedBackend `json:"backends,omitempty"`
+	// Strategy, Model and Language apply to every runtime of the app, as in ExecuteRequest.
+	Strategy string `json:"strategy,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+type ExecuteResponse struct {
+	Status     models.RuntimeState `json:"status"`
+	ExecuterID string              `json:"executerID"`
+	Title      string              `json:"title"`
+	URL        string              `json:"url"`
+	// Model is the model that generated the runtime.
+	Model string `json:"model,omitempty"`
+	// Duplicate is set when an existing runtime generated from a near-identical prompt was
+	// returned instead of generating a new one.
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+type StopResponse struct {
+	Status string `json:"status"`
+}
+
+type DeleteResponse struct {
+	Status string `json:"status"`
+}
+
+type PromptListResponse struct {
+	Prompts []*prompts.Prompt `json:"prompts"`
+}
+
+// ResultResponse is the output of a job that ran to completion. Truncated is set when the
+// program printed more than is kept.
+type ResultResponse struct {
+	State      models.RuntimeState `json:"state"`
+	Output     string              `json:"output"`
+	Truncated  bool                `json:"truncated,omitempty"`
+	FinishedAt time.Time           `json:"finishedAt"`
+}
+
+type ScaffoldListResponse struct {
+	Scaffolds []scaffold.Scaffold `json:"scaffolds"`
+}
+
+// SecretRequest sets the value of a secret.
+type SecretRequest struct {
+	Value string `json:"value"`
+}
+
+type SecretListResponse struct {
+	Secrets []secrets.Secret `json:"secrets"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// RejectionResponse explains why moderation refused a prompt.
+type RejectionResponse struct {
+	Error   string              `json:"error"`
+	Reasons []moderation.Reason `json:"reasons"`
+}
+
+// RuntimeSummary is the listing view of a runtime, without its code and logs.
+type RuntimeSummary struct {
+	ID                string              `json:"id"`
+	Title             string              `json:"title,omitempty"`
+	State             models.RuntimeState `json:"state"`
+	PassedHealthCheck bool                `json:"passedHealthCheck"`
+	CreatedAt         time.Time           `json:"createdAt"`
+	URL               string              `json:"url"`
+	Model             string              `json:"model,omitempty"`
+	Kind              string              `json:"kind,omitempty"`
+	Language          string              `json:"language,omitempty"`
+	Pinned            bool                `json:"pinned,omitempty"`
+	Archived          bool                `json:"archived,omitempty"`
+	Schedule          *models.Schedule    `json:"schedule,omitempty"`
+	// Domain is the custom hostname the runtime is also served at.
+	Domain string `json:"domain,omitempty"`
+	// PromptVariant is the prompt template variant the runtime was generated with.
+	PromptVariant string `json:"promptVariant,omitempty"`
+	// PostMortem explains why a failed runtime could not be repaired.
+	PostMortem string `json:"postMortem,omitempty"`
+}
+
+// ShareResponse is a runtime's short link and the URL of a QR code image of it.
+type ShareResponse struct {
+	Slug      string `json:"slug"`
+	URL       string `json:"url"`
+	QRCodeURL string `json:"qrCodeUrl"`
+}
+
+type DomainRequest struct {
+	Host string `json:"host"`
+}
+
+type ListResponse struct {
+	Runtimes []RuntimeSummary `json:"runtimes"`
+}
+
+type LogsResponse struct {
+	Lines []string `json:"lines"`
+}
+
+// AccessLogResponse is a runtime's proxied traffic: a summary, absent before its first request,
+// and the latest access log lines in the combined log format.
+type AccessLogResponse struct {
+	Traffic *models.TrafficReport `json:"traffic,omitempty"`
+	Lines   []string              `json:"lines"`
+}
+
+type SeedRequest struct {
+	// Route optionally forces every record to be posted to this app path.
+	Route   string           `json:"route"`
+	Records []map[string]any `json:"records"`
+}
+
+type RestoreRequest struct {
+	// Snapshot selects the snapshot to restore; the latest one is used when empty.
+	Snapshot string `json:"snapshot"`
+}
+
+// DiagnoseResponse is the fixes proposed for a runtime's current error.
+type DiagnoseResponse struct {
+	Options []executer.FixOption `json:"options"`
+}
+
+// UpdateCodeRequest replaces a runtime's program, e.g. with the code of a diagnosis option.
+type UpdateCodeRequest struct {
+	Code string `json:"code"`
+	// Reason is recorded on the new code version.
+	Reason string `json:"reason,omitempty"`
+}
+
+// UpdateCodeResponse is the code version created by a code update.
+type UpdateCodeResponse struct {
+	Status  string `json:"status"`
+	Version int    `json:"version"`
+}
+
+// AuditLogResponse is the latest LLM requests of a runtime, oldest first.
+type AuditLogResponse struct {
+	Entries []audit.Entry `json:"entries"`
+}
+
+// AdminUsageResponse is the aggregate resource usage of the node.
+type AdminUsageResponse struct {
+	Node   executer.ResourceUsage `json:"node"`
+	Tokens util.UsageReport       `json:"tokens"`
+}
+
+// StopAllResponse lists the runtimes being stopped.
+type StopAllResponse struct {
+	Stopped []string `json:"stopped"`
+}
+
+// HealthResponse is the answer of the liveness probe.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// AdminStateResponse is whether new executions are paused and the node is draining.
+type AdminStateResponse struct {
+	Paused   bool `json:"paused"`
+	Draining bool `json:"draining"`
+}
+
+type CloneRequest struct {
+	// Prompt optionally describes a change to apply to the clone.
 	Prompt string `json:"prompt"`
 }
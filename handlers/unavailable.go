@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gin-gonic/gin"
+)
+
+// rebuildRetryAfter is how many seconds clients are asked to wait for a runtime that is
+// starting or being rebuilt.
+const rebuildRetryAfter = 10
+
+// holdPollInterval is how often a held request checks whether its runtime became healthy.
+const holdPollInterval = 250 * time.Millisecond
+
+type unavailablePage struct {
+	Title   string
+	Message string
+	// Diagnosis is the post-mortem of a failed runtime.
+	Diagnosis  string
+	Rebuilding bool
+	RetryAfter int
+	// Action is the control endpoint the rebuild button posts to, if the runtime can be started.
+	Action string
+}
+
+var unavailableTemplate = template.Must(template.New("unavailable").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+{{if .Rebuilding}}<meta http-equiv="refresh" content="{{.RetryAfter}}">
+{{end}}<title>{{.Title}} is unavailable</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 36rem; margin: 4rem auto; padding: 0 1rem; color: #222; text-align: center; }
+button { font: inherit; font-weight: 600; color: #fff; background: #0b5cad; border: 0; border-radius: .5rem; padding: .5rem 1.5rem; cursor: pointer; }
+button:disabled { opacity: .6; cursor: default; }
+blockquote { margin: 1.5rem 0; padding: .75rem 1rem; background: #f4f4f4; border-left: 4px solid #c33; text-align: left; }
+small { display: block; color: #777; margin-top: 2rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Diagnosis}}<blockquote>{{.Diagnosis}}</blockquote>
+{{end}}{{if .Action}}<button id="rebuild">Rebuild</button>
+<p id="status"></p>
+<script>
+document.getElementById("rebuild").onclick = async function () {
+  this.disabled = true;
+  const res = await fetch({{.Action}}, { method: "POST" });
+  if (res.ok) {
+    location.reload();
+    return;
+  }
+  const body = await res.json().catch(() => ({}));
+  document.getElementById("status").textContent = body.error || res.statusText;
+  this.disabled = false;
+};
+</script>
+{{end}}<small>served by aegisx</small>
+</body>
+</html>
+`))
+
+// RuntimeUnavailable answers requests under the prefix of a runtime that is not proxied, or
+// whose program did not answer, with a page explaining its state instead of a bare error.
+// Requests for runtimes that are starting or being rebuilt are held for up to
+// ProxyHoldTimeout and forwarded once the new instance is healthy; if it is not by then they
+// get a 503 with Retry-After. Stopped and failed runtimes get a 503 with a button restarting
+// them. Other requests get a 404.
+func (h *MainHandler) RuntimeUnavailable(c *gin.Context) {
+	tenant, id := c.Param("tenant"), c.Param("id")
+	if id == "" {
+		tenant, id = runtimeFromPath(c.Request.URL.Path)
+	}
+	if id == "" {
+		c.JSON(404, ErrorResponse{Error: "page not found: " + c.Request.URL.Path})
+		return
+	}
+	runtime, err := h.ExecutorService.GetRuntime(c, id)
+	if err != nil || runtime.Snapshot().Tenant != tenant {
+		c.JSON(404, ErrorResponse{Error: "runtime not found: " + id})
+		return
+	}
+	page := unavailable(runtime.Snapshot(), h.ExecutorService.Retrying(id))
+	if page.Rebuilding && h.Config.ProxyHoldTimeout > 0 && !c.GetBool(routes.NoHoldKey) {
+		c.Set(routes.NoHoldKey, true)
+		if h.holdUntilHealthy(c, id) && h.Routes.Forward(c, id) {
+			return
+		}
+		if runtime, err = h.ExecutorService.GetRuntime(c, id); err != nil {
+			c.JSON(404, ErrorResponse{Error: err.Error()})
+			return
+		}
+		page = unavailable(runtime.Snapshot(), h.ExecutorService.Retrying(id))
+	}
+	if page.Rebuilding {
+		c.Header("Retry-After", strconv.Itoa(page.RetryAfter))
+	}
+	if !strings.Contains(c.GetHeader("Accept"), "text/html") {
+		message := page.Message
+		if page.Diagnosis != "" {
+			message += " " + page.Diagnosis
+		}
+		c.JSON(503, ErrorResponse{Error: "runtime " + id + " is unavailable: " + message})
+		return
+	}
+	var buf bytes.Buffer
+	if err := unavailableTemplate.Execute(&buf, page); err != nil {
+		c.JSON(500, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(503, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// unavailable describes the state of a runtime that cannot serve requests. A retrying runtime
+// is being rebuilt whatever its state.
+func unavailable(info models.RuntimeInfo, retrying bool) unavailablePage {
+	page := unavailablePage{Title: info.Title, RetryAfter: rebuildRetryAfter}
+	if page.Title == "" {
+		page.Title = info.ID
+	}
+	prefix := models.RuntimePrefix(info.Tenant, info.ID)
+	switch {
+	case info.Archived:
+		page.Message = "This app was archived."
+		page.Action = prefix + "/unarchive"
+	case info.State == models.RSINIT:
+		page.Message = "This app is starting. This page reloads until it is ready."
+		page.Rebuilding = true
+	case info.State.Active() || retrying:
+		page.Message = "This app is being rebuilt. This page reloads until it is back."
+		page.Rebuilding = true
+	case info.State == "failed":
+		page.Message = "This app failed and could not be repaired automatically."
+		if info.FailureClass != "" {
+			page.Message = "This app failed with a " + info.FailureClass + " error and could not be repaired automatically."
+		}
+		page.Action = prefix + "/restart"
+		if info.PostMortem != nil {
+			page.Diagnosis = info.PostMortem.Summary
+		}
+	case info.State == models.RSKILL:
+		page.Message = "This app was killed."
+		page.Action = prefix + "/restart"
+	case info.State == models.RSDONE || info.State == "finished":
+		page.Message = "This app finished running."
+		page.Action = prefix + "/restart"
+	default:
+		page.Message = "This app was stopped."
+		page.Action = prefix + "/restart"
+	}
+	return page
+}
+
+// holdUntilHealthy waits up to ProxyHoldTimeout for a starting or rebuilding runtime to pass
+// its health check, reporting whether it did. It gives up early once the runtime stops or
+// fails, or the client goes away.
+func (h *MainHandler) holdUntilHealthy(c *gin.Context, id string) bool {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.Config.ProxyHoldTimeout)
+	defer cancel()
+	ticker := time.NewTicker(holdPollInterval)
+	defer ticker.Stop()
+	for {
+		runtime, err := h.ExecutorService.GetRuntime(ctx, id)
+		if err != nil {
+			return false
+		}
+		info := runtime.Snapshot()
+		if info.PassedHealthCheck && info.State == models.RSRUN {
+			return true
+		}
+		if !info.State.Active() && !h.ExecutorService.Retrying(id) || info.Archived {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// runtimeFromPath returns the tenant and ID of the runtime whose prefix the path is under.
+func runtimeFromPath(path string) (string, string) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) >= 4 && parts[0] == "t" && parts[2] == "runtime" {
+		return parts[1], parts[3]
+	}
+	if len(parts) >= 2 && parts[0] == "runtime" {
+		return "", parts[1]
+	}
+	return "", ""
+}
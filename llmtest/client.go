@@ -0,0 +1,187 @@
+// Package llmtest provides a scripted LLM client with fault injection and a harness that runs
+// the generation pipeline of a node against it, so the executer, validator, interpreter and
+// dynamic routes can be tested end to end without network access.
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/demo"
+	"github.com/gcottom/aegisx/util"
+)
+
+// ModelName is the model the client reports unless told otherwise.
+const ModelName = "mock"
+
+// Refusal is the reply of a FaultRefusal response.
+const Refusal = "I'm sorry, but I can't help with that."
+
+// Fault is a failure a response injects instead of, or into, its content.
+type Fault int
+
+const (
+	FaultNone        Fault = iota
+	FaultTruncated         // The content is cut off halfway, as when the model runs out of tokens
+	FaultRefusal           // The model declines with Refusal
+	FaultTimeout           // The request times out after the response's Delay
+	FaultServerError       // The provider answers 500
+	FaultRateLimited       // The provider answers 429
+)
+
+// Response is a scripted reply.
+type Response struct {
+	// Content is the reply. Programs written for the port and prefix of the demo programs are
+	// moved to those the prompt assigns, so they pass the validator.
+	Content string
+	Err     error
+	Fault   Fault
+	Delay   time.Duration // Waited before replying, unless the request is canceled first
+}
+
+// Program returns a response holding code as a fenced Go block.
+func Program(code string) Response {
+	return Response{Content: "```go\n" + code + "```\n"}
+}
+
+// Failure returns a response that injects fault.
+func Failure(fault Fault) Response {
+	return Response{Fault: fault}
+}
+
+// Request is a prompt the client received.
+type Request struct {
+	RuntimeID string
+	Model     string
+	Prompt    string
+}
+
+// Client is an LLM client answering from a script. Every runtime gets Responses in order, its
+// first request the first response and so on, with the last one repeated; concurrent attempts
+// therefore each replay the whole script. A runtime keeps the script it started with, and
+// requests without a runtime share one of their own. Respond, when set, answers instead.
+type Client struct {
+	Model     string
+	Responses []Response
+	Respond   func(req Request, call int) Response
+
+	mu       sync.Mutex
+	scripts  map[string]*script
+	requests []Request
+}
+
+// script is the script a runtime follows and how far it got.
+type script struct {
+	responses []Response
+	calls     int
+}
+
+// ModelName returns Model, or ModelName when unset.
+func (c *Client) ModelName() string {
+	if c.Model != "" {
+		return c.Model
+	}
+	return ModelName
+}
+
+// WithModel returns a client on model that shares the script and the recorded requests.
+func (c *Client) WithModel(model string) util.LLMClient {
+	return &modelClient{client: c, model: model}
+}
+
+func (c *Client) SendMessage(ctx context.Context, prompt string) (string, error) {
+	return c.send(ctx, Request{RuntimeID: util.RuntimeIDFrom(ctx), Model: c.ModelName(), Prompt: prompt})
+}
+
+// Requests returns the requests received so far.
+func (c *Client) Requests() []Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Request(nil), c.requests...)
+}
+
+// Script replaces Responses for the runtimes that have not sent a request yet and forgets the
+// recorded requests. Runtimes already underway, such as rebuilds left over from an earlier
+// pipeline, finish their own script.
+func (c *Client) Script(responses ...Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Responses, c.requests = responses, nil
+	delete(c.scripts, "")
+}
+
+func (c *Client) send(ctx context.Context, req Request) (string, error) {
+	c.mu.Lock()
+	if c.scripts == nil {
+		c.scripts = map[string]*script{}
+	}
+	current, ok := c.scripts[req.RuntimeID]
+	if !ok {
+		current = &script{responses: c.Responses}
+		c.scripts[req.RuntimeID] = current
+	}
+	call := current.calls
+	current.calls++
+	c.requests = append(c.requests, req)
+	respond := c.Respond
+	c.mu.Unlock()
+
+	var resp Response
+	switch {
+	case respond != nil:
+		resp = respond(req, call)
+	case len(current.responses) > 0:
+		resp = current.responses[min(call, len(current.responses)-1)]
+	default:
+		return "", fmt.Errorf("llmtest: no response scripted for request %d of runtime %q", call+1, req.RuntimeID)
+	}
+	if resp.Delay > 0 {
+		timer := time.NewTimer(resp.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return resp.reply(req.Prompt)
+}
+
+// reply returns the content of the response for prompt, or the error it injects.
+func (r Response) reply(prompt string) (string, error) {
+	content := demo.Adapt(r.Content, prompt)
+	switch r.Fault {
+	case FaultTruncated:
+		return content[:len(content)/2], r.Err
+	case FaultRefusal:
+		return Refusal, r.Err
+	case FaultTimeout:
+		return "", fmt.Errorf("llmtest: request timed out: %w", context.DeadlineExceeded)
+	case FaultServerError:
+		return "", &util.GPTAPIError{StatusCode: http.StatusInternalServerError, Type: "server_error", Message: "injected fault"}
+	case FaultRateLimited:
+		return "", &util.GPTAPIError{StatusCode: http.StatusTooManyRequests, Type: "rate_limit_exceeded", Message: "injected fault", RetryAfter: time.Second}
+	}
+	return content, r.Err
+}
+
+// modelClient is a Client reporting another model.
+type modelClient struct {
+	client *Client
+	model  string
+}
+
+func (c *modelClient) ModelName() string {
+	return c.model
+}
+
+func (c *modelClient) WithModel(model string) util.LLMClient {
+	return c.client.WithModel(model)
+}
+
+func (c *modelClient) SendMessage(ctx context.Context, prompt string) (string, error) {
+	return c.client.send(ctx, Request{RuntimeID: util.RuntimeIDFrom(ctx), Model: c.model, Prompt: prompt})
+}
@@ -0,0 +1,103 @@
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/demo"
+)
+
+// Pipeline is a golden scenario: a prompt, the script the model follows and how generation
+// must end.
+type Pipeline struct {
+	Name      string
+	Prompt    string
+	Responses []Response
+	Healthy   bool   // Whether a runtime must come up; otherwise the execution must fail
+	Page      string // Text the runtime's page must contain when healthy
+}
+
+// Goldens are the pipelines the executer, validator and dynamic routes must keep passing. A
+// first generation that fails, or is rejected by the validator, fails its attempt; only the
+// other concurrent attempts can still bring a runtime up.
+var Goldens = []Pipeline{
+	{
+		Name:      "first-try",
+		Prompt:    "A notes app where I can add a note and see every note I have added.",
+		Responses: []Response{demoProgram("notes")},
+		Healthy:   true,
+		Page:      "<h1>Notes</h1>",
+	},
+	{
+		Name:      "slow-provider",
+		Prompt:    "A todo list where I can add tasks, mark them done and delete them.",
+		Responses: []Response{delayed(demoProgram("todo"), time.Second)},
+		Healthy:   true,
+		Page:      "<h1>Todo</h1>",
+	},
+	{
+		Name:      "truncated",
+		Prompt:    "A page with a counter and buttons to increment, decrement and reset it.",
+		Responses: []Response{truncated(demoProgram("counter"))},
+	},
+	{
+		Name:      "refusal",
+		Prompt:    "A notes app where I can add a note and see every note I have added.",
+		Responses: []Response{Failure(FaultRefusal)},
+	},
+	{
+		Name:      "timeout",
+		Prompt:    "A notes app where I can add a note and see every note I have added.",
+		Responses: []Response{Failure(FaultTimeout)},
+	},
+	{
+		Name:      "server-error",
+		Prompt:    "A notes app where I can add a note and see every note I have added.",
+		Responses: []Response{Failure(FaultServerError)},
+	},
+}
+
+// Run runs p and reports how the outcome differs from the expected one.
+func (h *Harness) Run(ctx context.Context, p Pipeline) error {
+	h.Client.Script(p.Responses...)
+	info, err := h.Execute(ctx, p.Prompt)
+	switch {
+	case !p.Healthy && err == nil:
+		return fmt.Errorf("%s: runtime %s came up, want the execution to fail", p.Name, info.ID)
+	case !p.Healthy:
+		return nil
+	case err != nil:
+		return fmt.Errorf("%s: %w", p.Name, err)
+	}
+	status, body, err := h.Get(ctx, info.ID, "/")
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.Name, err)
+	}
+	if status != http.StatusOK || !strings.Contains(body, p.Page) {
+		return fmt.Errorf("%s: runtime page returned %d without %q", p.Name, status, p.Page)
+	}
+	return nil
+}
+
+// demoProgram returns a response holding the bundled demo program called name.
+func demoProgram(name string) Response {
+	for _, program := range demo.Programs {
+		if program.Name == name {
+			return Program(program.Code)
+		}
+	}
+	panic("llmtest: no demo program called " + name)
+}
+
+func truncated(resp Response) Response {
+	resp.Fault = FaultTruncated
+	return resp
+}
+
+func delayed(resp Response, delay time.Duration) Response {
+	resp.Delay = delay
+	return resp
+}
@@ -0,0 +1,87 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+func TestGoldens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the goldens run the whole pipeline")
+	}
+	for _, p := range Goldens {
+		t.Run(p.Name, func(t *testing.T) {
+			h, err := NewHarness(&Client{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := h.Close(); err != nil {
+					t.Error(err)
+				}
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			if err := h.Run(ctx, p); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestClientScript(t *testing.T) {
+	client := &Client{}
+	client.Script(Program("package main\n"), Failure(FaultRefusal))
+	r1 := util.WithRuntimeID(context.Background(), "r1")
+
+	first, err := client.SendMessage(r1, "prompt")
+	if err != nil || !strings.Contains(first, "package main") {
+		t.Fatalf("first response = %q, %v, want the program", first, err)
+	}
+	if second, err := client.SendMessage(r1, "prompt"); err != nil || second != Refusal {
+		t.Fatalf("second response = %q, %v, want the refusal", second, err)
+	}
+	// The last response repeats, and another runtime replays the script from the start.
+	if again, _ := client.SendMessage(r1, "prompt"); again != Refusal {
+		t.Errorf("third response = %q, want the refusal again", again)
+	}
+	if other, _ := client.SendMessage(util.WithRuntimeID(context.Background(), "r2"), "prompt"); other != first {
+		t.Errorf("first response of another runtime = %q, want %q", other, first)
+	}
+	if got := len(client.Requests()); got != 4 {
+		t.Errorf("%d requests recorded, want 4", got)
+	}
+}
+
+func TestClientFaults(t *testing.T) {
+	tests := []struct {
+		fault Fault
+		check func(reply string, err error) bool
+	}{
+		{FaultTruncated, func(reply string, err error) bool { return err == nil && !strings.HasSuffix(reply, "```\n") }},
+		{FaultTimeout, func(reply string, err error) bool { return errors.Is(err, context.DeadlineExceeded) }},
+		{FaultServerError, func(reply string, err error) bool {
+			var apiErr *util.GPTAPIError
+			return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusInternalServerError
+		}},
+		{FaultRateLimited, func(reply string, err error) bool {
+			var apiErr *util.GPTAPIError
+			return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+		}},
+	}
+	for _, tt := range tests {
+		client := &Client{}
+		resp := Program("package main\n\nfunc main() {}\n")
+		resp.Fault = tt.fault
+		client.Script(resp)
+		if reply, err := client.SendMessage(context.Background(), "prompt"); !tt.check(reply, err) {
+			t.Errorf("fault %d: got %q, %v", tt.fault, reply, err)
+		}
+	}
+}
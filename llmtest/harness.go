@@ -0,0 +1,148 @@
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/handlers"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/ids"
+	"github.com/gcottom/aegisx/services/kv"
+	"github.com/gcottom/aegisx/services/title"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/qgin/qgin"
+)
+
+// Harness is a node generating with Client: the executer with its validator and interpreter,
+// and the API and dynamic routes serving the runtimes, with every store in a temporary
+// directory.
+type Harness struct {
+	Client   *Client
+	Config   *config.Config
+	Executer *executer.ExecuterService
+	Server   *httptest.Server // Serves the API and the runtimes' prefixes
+
+	dir string
+}
+
+// NewHarness starts a node on config/config.yaml with the optional features that call other
+// models or services turned off. configure, if not nil, may change the configuration first.
+func NewHarness(client *Client, configure func(cfg *config.Config)) (*Harness, error) {
+	cfg, err := config.LoadConfig(filepath.Join(util.GetAppRoot(), "config", "config.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "aegisx-llmtest-*")
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{Client: client, Config: cfg, dir: dir}
+	if err := h.start(configure); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) start(configure func(cfg *config.Config)) error {
+	cfg := h.Config
+	for _, store := range []*string{
+		&cfg.ExecuterStore, &cfg.ProxyStore, &cfg.AccessLogStore, &cfg.DomainStore, &cfg.ShareStore,
+		&cfg.StaticStore, &cfg.SQLiteStore, &cfg.SnapshotStore, &cfg.VersionStore, &cfg.GenerationCacheStore,
+		&cfg.GenerationHistoryStore, &cfg.PromptStore, &cfg.SecretsStore, &cfg.ScreenshotStore,
+		&cfg.ModuleStore, &cfg.RegistryStore, &cfg.AuditLog,
+	} {
+		if *store != "" {
+			*store = filepath.Join(h.dir, filepath.Base(*store))
+		}
+	}
+	// The scripted client stands in for every provider.
+	cfg.DemoMode = true
+	cfg.GenerationCache = false
+	cfg.VerifyRuntimes = false
+	cfg.PostMortems = false
+	cfg.BrowserSmokeTest = false
+	cfg.Thumbnails = false
+	cfg.TitleProvider = title.ProviderKeyword
+	if configure != nil {
+		configure(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	var err error
+	if cfg.YaegiGoPath, err = util.ResolveYaegiGoPath(cfg.YaegiGoPath); err != nil {
+		return err
+	}
+	idGenerator, err := ids.NewGenerator(cfg.IDStrategy, cfg.IDPrefix, cfg.ExecuterStore)
+	if err != nil {
+		return err
+	}
+	kvService, err := kv.NewKVService(filepath.Join(cfg.ExecuterStore, "kv.db"))
+	if err != nil {
+		return err
+	}
+	h.Executer = executer.NewExecuterService(cfg, h.Client, &title.KeywordProvider{MaxWords: 4}, kvService, idGenerator)
+	ctx := context.Background()
+	router := qgin.NewGinEngine(&ctx, &qgin.Config{LogRequestID: true, ProdMode: true})
+	mainHandler := handlers.NewMainHandler(h.Executer, cfg, util.NewUsageTracker())
+	mainHandler.History = h.Executer.History
+	routerSwitcher := routes.NewRouterSwitcher(router)
+	routes.CreateRoutes(router, mainHandler)
+	dynamicRouteService := routes.NewDynamicRouteService(cfg, mainHandler, router, routerSwitcher)
+	dynamicRouteService.Traffic, dynamicRouteService.Domains = mainHandler.Traffic, mainHandler.Domains
+	mainHandler.Routes, h.Executer.DynamicRouteService = dynamicRouteService, dynamicRouteService
+	h.Server = httptest.NewServer(routerSwitcher)
+	return nil
+}
+
+// Execute generates and starts a runtime from prompt like the execute endpoint, and returns
+// the runtime that won.
+func (h *Harness) Execute(ctx context.Context, prompt string) (models.RuntimeInfo, error) {
+	runtimeID, err := h.Executer.NewConcurrentExecution(ctx, prompt, executer.ExecutionOptions{})
+	if err != nil {
+		return models.RuntimeInfo{}, err
+	}
+	runtime, err := h.Executer.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return models.RuntimeInfo{}, err
+	}
+	return runtime.Snapshot(), nil
+}
+
+// Get requests path of a runtime through its prefix, as a browser would, and returns the
+// status and body.
+func (h *Harness) Get(ctx context.Context, runtimeID string, path string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.Server.URL+models.RuntimePrefix("", runtimeID)+path, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := h.Server.Client().Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read response of %s: %w", path, err)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// Close stops the runtimes and the server and removes the stores.
+func (h *Harness) Close() error {
+	h.Executer.StopAllRuntimes(context.Background())
+	h.Server.Close()
+	if err := h.Executer.KV.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(h.dir)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+// The metrics below are the single source of truth for the exported Grafana dashboard
+// and Prometheus alert rules, so renaming one here updates both.
+var (
+	RuntimesStarted = Default.NewCounter("aegisx_runtimes_started_total",
+		"Runtimes whose generated program started evaluation.")
+	RuntimesHealthy = Default.NewCounter("aegisx_runtimes_healthy_total",
+		"Runtimes that passed their health check.")
+	RuntimeFailures = Default.NewCounter("aegisx_runtime_failures_total",
+		"Runtime failures by the stage they failed in.", "stage")
+	ProviderRequests = Default.NewCounter("aegisx_provider_requests_total",
+		"LLM provider requests by model and outcome.", "model", "outcome")
+	ExecutionAttempts = Default.NewCounter("aegisx_execution_attempts_total",
+		"Concurrent generation attempts by the model they targeted.", "model")
+	ExecutionWins = Default.NewCounter("aegisx_execution_wins_total",
+		"Executions won by the model whose runtime passed its health check first.", "model")
+	ExecutionsInFlight = Default.NewGauge("aegisx_executions_in_flight",
+		"Execute requests currently waiting for a healthy runtime.")
+	ExecutionCapacity = Default.NewGauge("aegisx_execution_capacity",
+		"Maximum number of execute requests that should be in flight at once.")
+	ProxyRequests = Default.NewCounter("aegisx_proxy_requests_total",
+		"Requests proxied to runtimes by runtime and status code.", "runtime", "status")
+	ProxyRequestSeconds = Default.NewCounter("aegisx_proxy_request_seconds_total",
+		"Time spent serving proxied requests by runtime.", "runtime")
+	Panics = Default.NewCounter("aegisx_panics_total",
+		"Panics recovered by the component they happened in.", "component")
+	InterpreterPool = Default.NewCounter("aegisx_interpreter_pool_total",
+		"Interpreters handed to runtimes by whether the pool had one ready.", "result")
+	StageRuns = Default.NewCounter("aegisx_stage_runs_total",
+		"Runs of the execution pipeline stages by stage.", "stage")
+	StageSeconds = Default.NewCounter("aegisx_stage_seconds_total",
+		"Time spent in the execution pipeline stages by stage.", "stage")
+	StageOverBudget = Default.NewCounter("aegisx_stage_over_budget_total",
+		"Runs of the execution pipeline stages slower than their performance budget.", "stage")
+	EgressDenied = Default.NewCounter("aegisx_egress_denied_total",
+		"Outbound connections of generated programs denied by the egress policy by runtime.", "runtime")
+	OutboundRequests = Default.NewCounter("aegisx_outbound_requests_total",
+		"HTTP requests of generated programs through the outbound proxy by runtime and result.", "runtime", "result")
+	OutboundBytes = Default.NewCounter("aegisx_outbound_bytes_total",
+		"Response bytes received from upstream by the outbound proxy by runtime.", "runtime")
+	RuntimeGoroutines = Default.NewGauge("aegisx_runtime_goroutines",
+		"Goroutines started by the program of each runtime at the latest resource sample.", "runtime")
+	RuntimeCPUSeconds = Default.NewCounter("aegisx_runtime_cpu_seconds_total",
+		"Estimated CPU time used by the program of each runtime.", "runtime")
+	RuntimeAllocBytes = Default.NewCounter("aegisx_runtime_alloc_bytes_total",
+		"Estimated bytes allocated by the program of each runtime.", "runtime")
+	HostMemoryPercent = Default.NewGauge("aegisx_host_memory_percent",
+		"Memory in use on the host, or in the container's cgroup, as read by the watchdog.")
+	HostCPUPercent = Default.NewGauge("aegisx_host_cpu_percent",
+		"CPU in use on the host as read by the watchdog.")
+	LoadShedding = Default.NewGauge("aegisx_load_shedding",
+		"1 while the watchdog rejects new executions because the node is overloaded.")
+	LoadShedStops = Default.NewCounter("aegisx_load_shed_stops_total",
+		"Runtimes stopped by the watchdog to free memory.")
+	LLMBreakerOpen = Default.NewGauge("aegisx_llm_breaker_open",
+		"1 while the LLM circuit breaker fails generation fast because the provider is failing.")
+	LLMBreakerRejections = Default.NewCounter("aegisx_llm_breaker_rejections_total",
+		"LLM calls failed fast by the open circuit breaker.")
+)
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GrafanaDashboard builds a Grafana dashboard model with one panel per registered metric.
+func (r *Registry) GrafanaDashboard() map[string]any {
+	var panels []map[string]any
+	for i, desc := range r.Descs() {
+		expr := desc.Name
+		if desc.Kind == KindCounter {
+			expr = fmt.Sprintf("rate(%s[5m])", desc.Name)
+		}
+		if len(desc.Labels) > 0 {
+			expr = fmt.Sprintf("sum by (%s) (%s)", strings.Join(desc.Labels, ", "), expr)
+		}
+		panels = append(panels, map[string]any{
+			"id":          i + 1,
+			"type":        "timeseries",
+			"title":       desc.Help,
+			"description": desc.Name,
+			"datasource":  map[string]any{"type": "prometheus", "uid": "${datasource}"},
+			"gridPos":     map[string]any{"h": 8, "w": 12, "x": (i % 2) * 12, "y": (i / 2) * 8},
+			"targets": []map[string]any{{
+				"refId":        "A",
+				"expr":         expr,
+				"legendFormat": legendFormat(desc.Labels),
+			}},
+		})
+	}
+	return map[string]any{
+		"title":         "aegisx",
+		"uid":           "aegisx",
+		"schemaVersion": 39,
+		"time":          map[string]any{"from": "now-6h", "to": "now"},
+		"templating": map[string]any{"list": []map[string]any{{
+			"name":  "datasource",
+			"type":  "datasource",
+			"query": "prometheus",
+		}}},
+		"panels": panels,
+	}
+}
+
+func legendFormat(labels []string) string {
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = "{{" + label + "}}"
+	}
+	return strings.Join(parts, " ")
+}
+
+// AlertRule is a single Prometheus alerting rule.
+type AlertRule struct {
+	Alert       string            `yaml:"alert" json:"alert"`
+	Expr        string            `yaml:"expr" json:"expr"`
+	For         string            `yaml:"for" json:"for"`
+	Labels      map[string]string `yaml:"labels" json:"labels"`
+	Annotations map[string]string `yaml:"annotations" json:"annotations"`
+}
+
+// AlertRuleGroups is the top level of a Prometheus rule file.
+type AlertRuleGroups struct {
+	Groups []AlertRuleGroup `yaml:"groups" json:"groups"`
+}
+
+type AlertRuleGroup struct {
+	Name  string      `yaml:"name" json:"name"`
+	Rules []AlertRule `yaml:"rules" json:"rules"`
+}
+
+// AlertRules returns the aegisx alert rules expressed against the registered metric names.
+func AlertRules() AlertRuleGroups {
+	failures := RuntimeFailures.Desc().Name
+	provider := ProviderRequests.Desc().Name
+	inFlight := ExecutionsInFlight.Desc().Name
+	capacity := ExecutionCapacity.Desc().Name
+	panics := Panics.Desc().Name
+	stageRuns := StageRuns.Desc().Name
+	overBudget := StageOverBudget.Desc().Name
+	return AlertRuleGroups{Groups: []AlertRuleGroup{{
+		Name: "aegisx",
+		Rules: []AlertRule{
+			{
+				Alert:       "AegisxRuntimeFailureSpike",
+				Expr:        fmt.Sprintf("sum(rate(%s[5m])) > 3 * sum(rate(%s[1h] offset 5m))", failures, failures),
+				For:         "10m",
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": "Runtime failures are spiking above the hourly baseline."},
+			},
+			{
+				Alert: "AegisxProviderErrorRate",
+				Expr: fmt.Sprintf(`sum(rate(%s{outcome="error"}[5m])) / sum(rate(%s[5m])) > 0.2`,
+					provider, provider),
+				For:         "5m",
+				Labels:      map[string]string{"severity": "critical"},
+				Annotations: map[string]string{"summary": "More than 20% of LLM provider requests are failing."},
+			},
+			{
+				Alert:       "AegisxQueueSaturation",
+				Expr:        fmt.Sprintf("%s / %s > 0.9", inFlight, capacity),
+				For:         "5m",
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": "Execute requests are close to the configured capacity."},
+			},
+			{
+				Alert:       "AegisxPanics",
+				Expr:        fmt.Sprintf(`sum(increase(%s{component!="runtime"}[10m])) > 0`, panics),
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": "aegisx recovered from a panic in its own code; see the panic reports in the log."},
+			},
+			{
+				Alert: "AegisxStageOverBudget",
+				Expr: fmt.Sprintf("sum by (stage) (rate(%s[15m])) / sum by (stage) (rate(%s[15m])) > 0.1",
+					overBudget, stageRuns),
+				For:         "15m",
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": "More than 10% of the runs of a pipeline stage exceed its performance budget."},
+			},
+		},
+	}}}
+}
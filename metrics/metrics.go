@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Kind string
+
+const (
+	KindCounter Kind = "counter"
+	KindGauge   Kind = "gauge"
+)
+
+// Desc describes a registered metric.
+type Desc struct {
+	Name   string   `json:"name"`
+	Help   string   `json:"help"`
+	Kind   Kind     `json:"kind"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Registry holds every metric exposed by the process.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*series
+}
+
+// Default is the registry the aegisx metrics are registered in.
+var Default = &Registry{}
+
+// series stores the values of a metric keyed by its joined label values.
+type series struct {
+	desc   Desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (r *Registry) register(desc Desc) *series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.metrics {
+		if m.desc.Name == desc.Name {
+			panic("metrics: duplicate metric " + desc.Name)
+		}
+	}
+	s := &series{desc: desc, values: map[string]float64{}}
+	r.metrics = append(r.metrics, s)
+	return s
+}
+
+func (s *series) add(v float64, labelValues []string) {
+	if len(labelValues) != len(s.desc.Labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", s.desc.Name, len(s.desc.Labels), len(labelValues)))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[strings.Join(labelValues, "\xff")] += v
+}
+
+func (s *series) set(v float64, labelValues []string) {
+	if len(labelValues) != len(s.desc.Labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", s.desc.Name, len(s.desc.Labels), len(labelValues)))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[strings.Join(labelValues, "\xff")] = v
+}
+
+type Counter struct{ s *series }
+
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{s: r.register(Desc{Name: name, Help: help, Kind: KindCounter, Labels: labels})}
+}
+
+func (c *Counter) Inc(labelValues ...string) { c.s.add(1, labelValues) }
+
+func (c *Counter) Add(v float64, labelValues ...string) { c.s.add(v, labelValues) }
+
+func (c *Counter) Desc() Desc { return c.s.desc }
+
+type Gauge struct{ s *series }
+
+func (r *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{s: r.register(Desc{Name: name, Help: help, Kind: KindGauge, Labels: labels})}
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) { g.s.set(v, labelValues) }
+
+func (g *Gauge) Inc(labelValues ...string) { g.s.add(1, labelValues) }
+
+func (g *Gauge) Dec(labelValues ...string) { g.s.add(-1, labelValues) }
+
+func (g *Gauge) Desc() Desc { return g.s.desc }
+
+// Descs returns the descriptors of all registered metrics in registration order.
+func (r *Registry) Descs() []Desc {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	descs := make([]Desc, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		descs = append(descs, m.desc)
+	}
+	return descs
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]*series(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.desc.Name, m.desc.Help, m.desc.Name, m.desc.Kind); err != nil {
+			return err
+		}
+		m.mu.Lock()
+		keys := make([]string, 0, len(m.values))
+		for k := range m.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, err := fmt.Fprintf(w, "%s%s %s\n", m.desc.Name, formatLabels(m.desc.Labels, k), strconv.FormatFloat(m.values[k], 'f', -1, 64))
+			if err != nil {
+				m.mu.Unlock()
+				return err
+			}
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+func formatLabels(names []string, key string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	values := strings.Split(key, "\xff")
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(values[i])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stages of the execution pipeline timed by ObserveStage.
+const (
+	StagePrepare    = "prepare"
+	StageValidation = "validation"
+	StageEvalToPort = "eval_to_port"
+	StageProxy      = "proxy"
+)
+
+var stageBudgets atomic.Pointer[map[string]time.Duration]
+
+// SetStageBudgets sets the performance budget of the pipeline stages. Stages without one are
+// only timed.
+func SetStageBudgets(budgets map[string]time.Duration) {
+	stageBudgets.Store(&budgets)
+}
+
+// StageBudget returns the performance budget of stage, or 0 when it has none.
+func StageBudget(stage string) time.Duration {
+	if budgets := stageBudgets.Load(); budgets != nil {
+		return (*budgets)[stage]
+	}
+	return 0
+}
+
+// ObserveStage records a run of a pipeline stage that took d, counting it against the stage's
+// budget.
+func ObserveStage(stage string, d time.Duration) {
+	StageRuns.Inc(stage)
+	StageSeconds.Add(d.Seconds(), stage)
+	if budget := StageBudget(stage); budget > 0 && d > budget {
+		StageOverBudget.Inc(stage)
+	}
+}
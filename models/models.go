@@ -1,28 +1,310 @@
 package models
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
 	"time"
 
-	"github.com/traefik/yaegi/interp"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
 )
 
+// Runtime is a generated program and its execution state. It is shared by the goroutines
+// that evaluate, monitor and repair the program, so its fields are read with Snapshot or the
+// getters and written with Update or the setters, never directly.
 type Runtime struct {
+	mu sync.RWMutex
+	RuntimeInfo
+	onChange func(*Runtime)
+}
+
+// RuntimeInfo is the data of a runtime. A RuntimeInfo returned by Snapshot is a copy that is
+// safe to read and serialize without locking.
+type RuntimeInfo struct {
 	ID                string              `json:"id,omitempty"`
+	Tenant            string              `json:"tenant,omitempty"`
 	Title             string              `json:"title,omitempty"`
 	Prompt            string              `json:"prompt,omitempty"`
 	Code              string              `json:"code,omitempty"`
+	Assets            []string            `json:"assets,omitempty"`
 	State             RuntimeState        `json:"state,omitempty"`
 	LastErrorMsg      string              `json:"lastErrorMsg,omitempty"`
+	Diagnostics       []code.Violation    `json:"diagnostics,omitempty"`
 	RebuildCount      int                 `json:"rebuildCount,omitempty"`
-	Executer          *interp.Interpreter `json:"-"`
-	StopFunction      func()              `json:"-"`
+	FailureClass      string              `json:"failureClass,omitempty"`
+	FailureCounts     map[string]int      `json:"failureCounts,omitempty"`
+	FailureStrategy   string              `json:"failureStrategy,omitempty"`
+	Model             string              `json:"model,omitempty"`
+	Source            string              `json:"source,omitempty"`   // Code source of a program the LLM did not generate
+	Kind              string              `json:"kind,omitempty"`     // Runtime kind, empty for a web app
+	Language          string              `json:"language,omitempty"` // Language of the program, empty for Go
+	PromptVariant     string              `json:"promptVariant,omitempty"`
+	Regenerations     int                 `json:"regenerations,omitempty"`
+	Version           int                 `json:"version,omitempty"`
+	Executer          Program             `json:"-"`
 	Port              int                 `json:"port"`
 	CreatedAt         time.Time           `json:"createdAt,omitempty,omitzero"`
 	StartedAt         time.Time           `json:"startedAt,omitempty,omitzero"`
 	FinishedAt        time.Time           `json:"finishedAt,omitempty,omitzero"`
-	Logs              *bytes.Buffer       `json:"logs,omitempty"`
+	LastHeartbeat     time.Time           `json:"lastHeartbeat,omitempty,omitzero"`
+	Logs              *util.LogWriter     `json:"-"`
 	PassedHealthCheck bool                `json:"passedHealthCheck"`
+	Kill              *KillReport         `json:"kill,omitempty"`
+	Stop              *StopReport         `json:"stop,omitempty"`
+	Pinned            bool                `json:"pinned,omitempty"`
+	Archived          bool                `json:"archived,omitempty"`
+	ArchivedAt        time.Time           `json:"archivedAt,omitempty,omitzero"`
+	Schedule          *Schedule           `json:"schedule,omitempty"`
+	Verification      *VerificationReport `json:"verification,omitempty"`
+	PostMortem        *PostMortem         `json:"postMortem,omitempty"`
+	Tests             *TestReport         `json:"tests,omitempty"`
+	Browser           *BrowserReport      `json:"browser,omitempty"`
+	Thumbnail         *Thumbnail          `json:"thumbnail,omitempty"`
+	Result            *JobResult          `json:"result,omitempty"`
+	// GenerationParams override the configured generation parameters for the runtime.
+	GenerationParams util.GenerationParams `json:"generationParams,omitzero"`
+	// NoOutbound denies the runtime's program every outbound connection, whatever the
+	// configured egress policy allows.
+	NoOutbound bool `json:"noOutbound,omitempty"`
+	// Env is the environment the runtime's program reads, such as the URLs of the other
+	// runtimes of its group. It is set when the runtime is created and never changes.
+	Env map[string]string `json:"env,omitempty"`
+	// Group places the runtime in a composite app.
+	Group *GroupMember `json:"group,omitempty"`
+	// Resources is the runtime's latest resource usage. It is sampled live and not persisted.
+	Resources *RuntimeResources `json:"resources,omitempty"`
+}
+
+// Program runs the code of a runtime: the interpreter of a Go program, or the backend of its
+// language for others.
+type Program interface {
+	// Run runs code until it returns or ctx is done.
+	Run(ctx context.Context, code string) error
+	// Shutdown asks the running code to stop and release its port.
+	Shutdown(ctx context.Context) error
+}
+
+// GroupMember is the place of a runtime in a composite app: its frontend, or one of the
+// backends the frontend calls, whose URL it gets in the Env variable EnvVar.
+type GroupMember struct {
+	ID     string `json:"id"`
+	Role   string `json:"role"`
+	Name   string `json:"name,omitempty"`
+	EnvVar string `json:"envVar,omitempty"`
+}
+
+// Schedule starts and stops a runtime at the minutes matched by five field cron expressions.
+// Either expression may be empty. Timezone is an IANA name and defaults to the server's time.
+type Schedule struct {
+	Start    string `json:"start,omitempty"`
+	Stop     string `json:"stop,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// RuntimePrefix is the path a runtime is served under: /runtime/<id>, or
+// /t/<tenant>/runtime/<id> for a tenant's runtime.
+func RuntimePrefix(tenant string, id string) string {
+	if tenant == "" {
+		return "/runtime/" + id
+	}
+	return "/t/" + tenant + "/runtime/" + id
+}
+
+// NewRuntime wraps info in a Runtime.
+func NewRuntime(info RuntimeInfo) *Runtime {
+	return &Runtime{RuntimeInfo: info}
+}
+
+// Snapshot returns a copy of the runtime's data.
+func (r *Runtime) Snapshot() RuntimeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	info := r.RuntimeInfo
+	info.Assets = append([]string(nil), r.Assets...)
+	info.Diagnostics = append([]code.Violation(nil), r.Diagnostics...)
+	if r.FailureCounts != nil {
+		info.FailureCounts = make(map[string]int, len(r.FailureCounts))
+		for class, count := range r.FailureCounts {
+			info.FailureCounts[class] = count
+		}
+	}
+	return info
+}
+
+// Update changes the runtime's data under its lock. fn must not block or call other methods
+// of the runtime.
+func (r *Runtime) Update(fn func(info *RuntimeInfo)) {
+	r.mu.Lock()
+	fn(&r.RuntimeInfo)
+	onChange := r.onChange
+	r.mu.Unlock()
+	if onChange != nil {
+		onChange(r)
+	}
+}
+
+// OnChange registers fn to be called after every Update and SetState, outside the lock.
+func (r *Runtime) OnChange(fn func(*Runtime)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = fn
+}
+
+// MarshalJSON serializes a snapshot so saving a runtime never races its goroutines.
+func (r *Runtime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Snapshot())
+}
+
+func (r *Runtime) GetState() RuntimeState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.State
+}
+
+func (r *Runtime) SetState(state RuntimeState) {
+	r.Update(func(info *RuntimeInfo) { info.State = state })
+}
+
+func (r *Runtime) GetTenant() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Tenant
+}
+
+func (r *Runtime) GetPort() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Port
+}
+
+func (r *Runtime) GetExecuter() Program {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Executer
+}
+
+func (r *Runtime) GetLogs() *util.LogWriter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Logs
+}
+
+func (r *Runtime) HasPassedHealthCheck() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.PassedHealthCheck
+}
+
+// TestReport records a run of the tests a generated program exports for a version of its code.
+// Output is the tail of what the program printed during the run.
+type TestReport struct {
+	Version  int    `json:"version"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// BrowserReport records a headless browser load of a version of a runtime's landing page.
+// Screenshot reports whether a screenshot was stored; Skipped explains why nothing was checked.
+type BrowserReport struct {
+	Version       int       `json:"version"`
+	Passed        bool      `json:"passed"`
+	Skipped       string    `json:"skipped,omitempty"`
+	ConsoleErrors []string  `json:"consoleErrors,omitempty"`
+	FormFound     bool      `json:"formFound"`
+	FormSubmitted bool      `json:"formSubmitted"`
+	FormError     string    `json:"formError,omitempty"`
+	Screenshot    bool      `json:"screenshot"`
+	CheckedAt     time.Time `json:"checkedAt"`
+}
+
+// Thumbnail records the capture of a thumbnail of a version of a runtime's landing page.
+type Thumbnail struct {
+	Version    int       `json:"version"`
+	CapturedAt time.Time `json:"capturedAt"`
+}
+
+// JobResult is the output of a job's program once it ran to completion. Truncated is set when
+// the program printed more than is kept.
+type JobResult struct {
+	Output    string `json:"output"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// TrafficReport summarizes the requests proxied to a runtime since aegisx started: counts by
+// status code and latency percentiles over the most recent requests.
+type TrafficReport struct {
+	Requests      int64            `json:"requests"`
+	Statuses      map[string]int64 `json:"statuses"`
+	LatencyP50Ms  float64          `json:"latencyP50Ms"`
+	LatencyP90Ms  float64          `json:"latencyP90Ms"`
+	LatencyP99Ms  float64          `json:"latencyP99Ms"`
+	LastRequestAt time.Time        `json:"lastRequestAt"`
+}
+
+// VerificationReport records the functional checks run against a version of a runtime's code
+// after it passed its health check. Skipped explains why no checks were run.
+type VerificationReport struct {
+	Version    int                 `json:"version"`
+	Passed     bool                `json:"passed"`
+	Skipped    string              `json:"skipped,omitempty"`
+	Checks     []VerificationCheck `json:"checks,omitempty"`
+	VerifiedAt time.Time           `json:"verifiedAt"`
+}
+
+// PostMortem is the diagnosis written when a runtime failed for good, explaining in plain
+// words why its prompt could not be fulfilled.
+type PostMortem struct {
+	Summary   string    `json:"summary"`
+	Version   int       `json:"version"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// VerificationCheck is one HTTP call of a verification plan and its outcome. Paths are
+// relative to the runtime prefix.
+type VerificationCheck struct {
+	Description    string            `json:"description,omitempty"`
+	Method         string            `json:"method"`
+	Path           string            `json:"path"`
+	Form           map[string]string `json:"form,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	ExpectStatus   int               `json:"expectStatus,omitempty"`
+	ExpectContains string            `json:"expectContains,omitempty"`
+	Status         int               `json:"status,omitempty"`
+	Passed         bool              `json:"passed"`
+	Error          string            `json:"error,omitempty"`
+}
+
+// StopReport records the progress of a graceful stop. StoppedAt is zero while the runtime is
+// still stopping.
+type StopReport struct {
+	GracefulShutdown bool      `json:"gracefulShutdown"`
+	PortReleased     bool      `json:"portReleased"`
+	RequestedAt      time.Time `json:"requestedAt"`
+	StoppedAt        time.Time `json:"stoppedAt,omitzero"`
+}
+
+// RuntimeResources is the share of the process' resources attributed to a runtime's program by
+// the latest profiling window. The cumulative figures extrapolate each window to its whole
+// sampling interval, so they are estimates.
+type RuntimeResources struct {
+	Goroutines          int       `json:"goroutines"`
+	CPUCores            float64   `json:"cpuCores"`
+	CPUSeconds          float64   `json:"cpuSeconds"`
+	AllocBytesPerSecond float64   `json:"allocBytesPerSecond"`
+	AllocBytes          int64     `json:"allocBytes"`
+	SampledAt           time.Time `json:"sampledAt"`
+}
+
+// KillReport records how a runtime was killed through the kill switch.
+type KillReport struct {
+	Force            bool      `json:"force"`
+	GracefulShutdown bool      `json:"gracefulShutdown"`
+	PortListening    bool      `json:"portListening"`
+	KilledAt         time.Time `json:"killedAt"`
 }
 
 type RuntimeState string
@@ -34,4 +316,17 @@ const (
 	RSSTOP RuntimeState = "stopped"
 	RSERR  RuntimeState = "error"
 	RSDONE RuntimeState = "done"
+	RSKILL RuntimeState = "killed"
+
+	// RSSTOPPING is a runtime whose stop was requested but whose program has not exited yet.
+	RSSTOPPING RuntimeState = "stopping"
 )
+
+// Active reports whether a runtime in this state may still have a program running.
+func (s RuntimeState) Active() bool {
+	switch s {
+	case RSSTOP, RSDONE, RSKILL, "failed", "finished":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// The tests in this file are meant to run with -race.
+
+func TestRuntimeConcurrentAccess(t *testing.T) {
+	runtime := NewRuntime(RuntimeInfo{ID: "r1", State: RSINIT})
+	var changes sync.Map
+	runtime.OnChange(func(r *Runtime) {
+		changes.Store(r.GetState(), true)
+	})
+
+	const writers, updates = 8, 200
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				runtime.Update(func(info *RuntimeInfo) {
+					info.RebuildCount++
+					info.Assets = append(info.Assets, "app.css")
+					if info.FailureCounts == nil {
+						info.FailureCounts = map[string]int{}
+					}
+					info.FailureCounts["eval"]++
+				})
+				runtime.SetState(RSRUN)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				info := runtime.Snapshot()
+				_ = info.FailureCounts["eval"]
+				_ = len(info.Assets)
+				_ = runtime.GetState()
+				_ = runtime.GetPort()
+				_ = runtime.GetTenant()
+				_ = runtime.GetExecuter()
+				_ = runtime.GetLogs()
+				_ = runtime.HasPassedHealthCheck()
+				if _, err := json.Marshal(runtime); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	info := runtime.Snapshot()
+	if want := writers * updates; info.RebuildCount != want || info.FailureCounts["eval"] != want || len(info.Assets) != want {
+		t.Errorf("got %d rebuilds, %d failures and %d assets, want %d of each", info.RebuildCount, info.FailureCounts["eval"], len(info.Assets), want)
+	}
+	if _, ok := changes.Load(RSRUN); !ok {
+		t.Error("OnChange was not called")
+	}
+}
+
+func TestRuntimeSnapshotIsACopy(t *testing.T) {
+	runtime := NewRuntime(RuntimeInfo{
+		Assets:        []string{"app.css"},
+		FailureCounts: map[string]int{"eval": 1},
+	})
+	info := runtime.Snapshot()
+	info.Assets[0] = "changed.css"
+	info.FailureCounts["eval"] = 5
+
+	after := runtime.Snapshot()
+	if after.Assets[0] != "app.css" || after.FailureCounts["eval"] != 1 {
+		t.Errorf("changing a snapshot changed the runtime: %v, %v", after.Assets, after.FailureCounts)
+	}
+}
@@ -0,0 +1,10 @@
+// Package openapi embeds the OpenAPI document of the control API. The document is generated
+// from the annotations on the handlers by cmd/openapi-gen, which also generates the client package.
+package openapi
+
+import _ "embed"
+
+//go:generate go run ../cmd/openapi-gen -handlers ../handlers -spec openapi.json -client ../client/client.gen.go
+
+//go:embed openapi.json
+var Spec []byte
@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// compressibleTypes are the media types worth compressing; images, fonts and archives are
+// compressed already. Event streams are left alone so every event is flushed as it comes.
+var compressibleTypes = []string{"text/html", "text/css", "text/plain", "text/javascript", "application/javascript", "application/json", "application/xml", "text/xml", "image/svg+xml"}
+
+// Compression compresses proxied responses with gzip or deflate when the client accepts it and
+// the runtime did not encode them itself. Responses announcing fewer than MinBytes are sent as
+// they are.
+type Compression struct {
+	MinBytes int64
+}
+
+func (cp *Compression) apply(resp *http.Response) {
+	if resp.Request == nil || resp.Request.Method == http.MethodHead || resp.Header.Get("Content-Encoding") != "" {
+		return
+	}
+	if resp.StatusCode < 200 || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusNotModified {
+		return
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength < cp.MinBytes || !compressible(resp.Header.Get("Content-Type")) {
+		return
+	}
+	encoding := acceptedEncoding(resp.Request.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return
+	}
+	body := resp.Body
+	reader, writer := io.Pipe()
+	go func() {
+		defer body.Close()
+		defer util.Recover("proxy", "", func(value any) { writer.CloseWithError(fmt.Errorf("compression panicked: %v", value)) })
+		var encoder io.WriteCloser
+		if encoding == "gzip" {
+			encoder = gzip.NewWriter(writer)
+		} else {
+			encoder, _ = flate.NewWriter(writer, flate.DefaultCompression)
+		}
+		_, err := io.Copy(encoder, body)
+		if closeErr := encoder.Close(); err == nil {
+			err = closeErr
+		}
+		writer.CloseWithError(err)
+	}()
+	resp.Body = reader
+	resp.Header.Set("Content-Encoding", encoding)
+	resp.Header.Add("Vary", "Accept-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, t := range compressibleTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding picks gzip, or else deflate, if the Accept-Encoding header allows it.
+func acceptedEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := strings.ReplaceAll(params, " ", "")
+		accepted[strings.ToLower(name)] = q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
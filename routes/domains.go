@@ -0,0 +1,233 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+var (
+	// ErrInvalidDomain is returned for hostnames that cannot be mapped.
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrDomainTaken is returned when a hostname is mapped to another runtime already.
+	ErrDomainTaken = errors.New("domain is mapped to another runtime")
+	// ErrUnknownDomain is returned by HostPolicy for hostnames that are not mapped.
+	ErrUnknownDomain = errors.New("domain is not mapped to a runtime")
+	// ErrReservedDomain is returned for the hostnames of the nodes themselves.
+	ErrReservedDomain = errors.New("domain is reserved")
+)
+
+// Domain maps a custom hostname to the runtime served at it.
+type Domain struct {
+	Host      string    `json:"host"`
+	RuntimeID string    `json:"runtimeID"`
+	Tenant    string    `json:"tenant,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DomainRouter serves runtimes at custom domains, one per runtime. A request whose Host is
+// mapped is served by Next under the runtime's prefix, so the app's own prefixed links keep
+// working; other requests pass through unchanged. Mappings are persisted in File when it is
+// set. The hosts of the URLs returned by Reserved, such as the node's public URL, are never
+// mapped, since the router would then send the control API's requests to a runtime.
+type DomainRouter struct {
+	Next     http.Handler
+	File     string
+	Reserved func() ([]string, error)
+
+	mu      sync.RWMutex
+	domains map[string]Domain // by host
+}
+
+// NormalizeDomain lowercases a hostname and checks it is a fully qualified DNS name.
+func NormalizeDomain(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if len(host) > 253 || !strings.Contains(host, ".") || net.ParseIP(host) != nil {
+		return "", fmt.Errorf("%w: %q is not a fully qualified hostname", ErrInvalidDomain, host)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("%w: %q is not a valid hostname", ErrInvalidDomain, host)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return "", fmt.Errorf("%w: %q is not a valid hostname", ErrInvalidDomain, host)
+			}
+		}
+	}
+	return host, nil
+}
+
+// Load reads the persisted mappings.
+func (d *DomainRouter) Load() error {
+	if d.File == "" {
+		return nil
+	}
+	data, err := os.ReadFile(d.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read domains: %w", err)
+	}
+	var domains []Domain
+	if err := json.Unmarshal(data, &domains); err != nil {
+		return fmt.Errorf("failed to decode domains: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.domains = map[string]Domain{}
+	for _, domain := range domains {
+		d.domains[domain.Host] = domain
+	}
+	return nil
+}
+
+// save persists the mappings; the caller holds the lock.
+func (d *DomainRouter) save() error {
+	if d.File == "" {
+		return nil
+	}
+	domains := make([]Domain, 0, len(d.domains))
+	for _, domain := range d.domains {
+		domains = append(domains, domain)
+	}
+	data, err := json.Marshal(domains)
+	if err != nil {
+		return fmt.Errorf("failed to marshal domains: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(d.File), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(d.File, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write domains: %w", err)
+	}
+	return nil
+}
+
+// Map serves the runtime at the domain's host, replacing the runtime's previous domain.
+func (d *DomainRouter) Map(domain Domain) (Domain, error) {
+	host, err := NormalizeDomain(domain.Host)
+	if err != nil {
+		return Domain{}, err
+	}
+	domain.Host = host
+	if err := d.checkReserved(host); err != nil {
+		return Domain{}, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.domains[host]; ok && existing.RuntimeID != domain.RuntimeID {
+		return Domain{}, fmt.Errorf("%w: %s", ErrDomainTaken, host)
+	}
+	if d.domains == nil {
+		d.domains = map[string]Domain{}
+	}
+	for existingHost, existing := range d.domains {
+		if existing.RuntimeID == domain.RuntimeID {
+			delete(d.domains, existingHost)
+		}
+	}
+	if domain.CreatedAt.IsZero() {
+		domain.CreatedAt = time.Now()
+	}
+	d.domains[host] = domain
+	return domain, d.save()
+}
+
+// checkReserved returns ErrReservedDomain if host is the host of one of the reserved URLs.
+func (d *DomainRouter) checkReserved(host string) error {
+	if d.Reserved == nil {
+		return nil
+	}
+	reserved, err := d.Reserved()
+	if err != nil {
+		return fmt.Errorf("failed to list reserved domains: %w", err)
+	}
+	for _, rawURL := range reserved {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		if strings.TrimSuffix(strings.ToLower(u.Hostname()), ".") == host {
+			return fmt.Errorf("%w: %s serves aegisx itself", ErrReservedDomain, host)
+		}
+	}
+	return nil
+}
+
+// Unmap removes the runtime's domain, if it has one.
+func (d *DomainRouter) Unmap(runtimeID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	removed := false
+	for host, domain := range d.domains {
+		if domain.RuntimeID == runtimeID {
+			delete(d.domains, host)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return d.save()
+}
+
+// Lookup returns the runtime's domain.
+func (d *DomainRouter) Lookup(runtimeID string) (Domain, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, domain := range d.domains {
+		if domain.RuntimeID == runtimeID {
+			return domain, true
+		}
+	}
+	return Domain{}, false
+}
+
+// HostPolicy allows certificates only for mapped hosts, for use as an autocert host policy.
+func (d *DomainRouter) HostPolicy(_ context.Context, host string) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if _, ok := d.domains[strings.ToLower(host)]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDomain, host)
+	}
+	return nil
+}
+
+func (d *DomainRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	d.mu.RLock()
+	domain, ok := d.domains[strings.TrimSuffix(strings.ToLower(host), ".")]
+	d.mu.RUnlock()
+	if !ok {
+		d.Next.ServeHTTP(w, r)
+		return
+	}
+	prefix := models.RuntimePrefix(domain.Tenant, domain.RuntimeID)
+	if r.URL.Path != prefix && !strings.HasPrefix(r.URL.Path, prefix+"/") {
+		u := *r.URL
+		u.Path = prefix + u.Path
+		if u.RawPath != "" {
+			u.RawPath = prefix + u.RawPath
+		}
+		r = r.WithContext(r.Context())
+		r.URL = &u
+		r.RequestURI = u.RequestURI()
+	}
+	d.Next.ServeHTTP(w, r)
+}
@@ -2,13 +2,21 @@ package routes
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/registry"
+	"github.com/gcottom/aegisx/services/traffic"
 	"github.com/gcottom/qgin/qgin"
 	"github.com/gin-gonic/gin"
 )
@@ -18,44 +26,327 @@ type DynamicRouteService struct {
 	Router         *gin.Engine
 	RouterSwitcher *RouterSwitcher
 	ProxyMap       sync.Map
+	Store          *ProxyStore
+	Registry       registry.Registry
+	Node           registry.Node
+	Traffic        *traffic.Recorder
+	Limits         ProxyLimits
+	Headers        *SecurityHeaders
+	Compression    *Compression
+	Domains        *DomainRouter
+	// ExtraRoutes add the routes of optional modules to the API routes whenever the router is
+	// rebuilt.
+	ExtraRoutes []func(router *gin.Engine, handler Handlers)
+}
+
+// NewDynamicRouteService returns the route service adding the runtimes' proxies to router,
+// which switcher serves, with the route store, limits, security headers and compression cfg
+// configures. Traffic, Domains and the node registry are set by the caller.
+func NewDynamicRouteService(cfg *config.Config, handler Handlers, router *gin.Engine, switcher *RouterSwitcher) *DynamicRouteService {
+	s := &DynamicRouteService{
+		Handler:        handler,
+		Router:         router,
+		RouterSwitcher: switcher,
+		Store:          &ProxyStore{Dir: cfg.ProxyStore},
+		Limits: ProxyLimits{
+			MaxRequestBytes:  cfg.ProxyMaxRequestBytes,
+			MaxResponseBytes: cfg.ProxyMaxResponseBytes,
+			Timeout:          cfg.ProxyTimeout,
+			ClientTimeout:    cfg.ProxyClientTimeout,
+		},
+	}
+	if cfg.Compression {
+		s.Compression = &Compression{MinBytes: cfg.CompressionMinBytes}
+	}
+	if cfg.SecurityHeaders {
+		s.Headers = &SecurityHeaders{
+			ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+			FrameOptions:          cfg.FrameOptions,
+			ReferrerPolicy:        cfg.ReferrerPolicy,
+		}
+	}
+	return s
 }
 
 type Handlers interface {
+	Tenant(c *gin.Context)
 	Execute(c *gin.Context)
+	Compose(c *gin.Context)
+	GroupStatus(c *gin.Context)
+	StopGroup(c *gin.Context)
 	Stop(c *gin.Context)
+	Delete(c *gin.Context)
 	Status(c *gin.Context)
+	UsageReport(c *gin.Context)
+	GenerationAnalytics(c *gin.Context)
+	Metrics(c *gin.Context)
+	GrafanaDashboard(c *gin.Context)
+	AlertRules(c *gin.Context)
+	Limits(c *gin.Context)
+	Seed(c *gin.Context)
+	Static(c *gin.Context)
+	Screenshot(c *gin.Context)
+	Thumbnail(c *gin.Context)
+	Kill(c *gin.Context)
+	Snapshot(c *gin.Context)
+	Snapshots(c *gin.Context)
+	Restore(c *gin.Context)
+	Clone(c *gin.Context)
+	Gallery(c *gin.Context)
+	List(c *gin.Context)
+	Logs(c *gin.Context)
+	Result(c *gin.Context)
+	RuntimeOpenAPI(c *gin.Context)
+	AccessLog(c *gin.Context)
+	AuditLog(c *gin.Context)
+	OpenAPI(c *gin.Context)
+	Versions(c *gin.Context)
+	Rollback(c *gin.Context)
+	Diagnose(c *gin.Context)
+	UpdateCode(c *gin.Context)
+	Pin(c *gin.Context)
+	Unpin(c *gin.Context)
+	Archive(c *gin.Context)
+	Unarchive(c *gin.Context)
+	Restart(c *gin.Context)
+	RuntimeUnavailable(c *gin.Context)
+	SetSchedule(c *gin.Context)
+	DeleteSchedule(c *gin.Context)
+	SetDomain(c *gin.Context)
+	DeleteDomain(c *gin.Context)
+	Share(c *gin.Context)
+	ShortLink(c *gin.Context)
+	ShareQRCode(c *gin.Context)
+	ListPrompts(c *gin.Context)
+	ListScaffolds(c *gin.Context)
+	CreatePrompt(c *gin.Context)
+	GetPrompt(c *gin.Context)
+	UpdatePrompt(c *gin.Context)
+	DeletePrompt(c *gin.Context)
+	ListSecrets(c *gin.Context)
+	PutSecret(c *gin.Context)
+	DeleteSecret(c *gin.Context)
+	Recover(c *gin.Context)
+	Admin(c *gin.Context)
+	AdminUsage(c *gin.Context)
+	AdminStopAll(c *gin.Context)
+	AdminPause(c *gin.Context)
+	AdminResume(c *gin.Context)
+	AdminDrain(c *gin.Context)
+	AdminEvict(c *gin.Context)
+	Healthz(c *gin.Context)
+	Readyz(c *gin.Context)
+}
+
+// RuntimeRoute is a control API endpoint living under a runtime's /runtime/:id prefix.
+// Path is relative to that prefix and may contain :param segments and a trailing *param.
+type RuntimeRoute struct {
+	Method  string
+	Path    string
+	Handler gin.HandlerFunc
+}
+
+// RuntimeRoutes lists the control endpoints under /runtime/:id. Once a runtime is proxied its
+// static route shadows these in the router, so the proxy handler dispatches them itself.
+func RuntimeRoutes(handler Handlers) []RuntimeRoute {
+	return []RuntimeRoute{
+		{Method: http.MethodPost, Path: "/seed", Handler: handler.Seed},
+		{Method: http.MethodGet, Path: "/logs", Handler: handler.Logs},
+		{Method: http.MethodGet, Path: "/result", Handler: handler.Result},
+		{Method: http.MethodGet, Path: "/openapi.json", Handler: handler.RuntimeOpenAPI},
+		{Method: http.MethodGet, Path: "/access-log", Handler: handler.AccessLog},
+		{Method: http.MethodGet, Path: "/audit-log", Handler: handler.AuditLog},
+		{Method: http.MethodPost, Path: "/kill", Handler: handler.Kill},
+		{Method: http.MethodPost, Path: "/snapshot", Handler: handler.Snapshot},
+		{Method: http.MethodGet, Path: "/snapshots", Handler: handler.Snapshots},
+		{Method: http.MethodPost, Path: "/restore", Handler: handler.Restore},
+		{Method: http.MethodPost, Path: "/clone", Handler: handler.Clone},
+		{Method: http.MethodGet, Path: "/versions", Handler: handler.Versions},
+		{Method: http.MethodPost, Path: "/rollback/:version", Handler: handler.Rollback},
+		{Method: http.MethodPost, Path: "/diagnose", Handler: handler.Diagnose},
+		{Method: http.MethodPut, Path: "/code", Handler: handler.UpdateCode},
+		{Method: http.MethodPost, Path: "/pin", Handler: handler.Pin},
+		{Method: http.MethodPost, Path: "/unpin", Handler: handler.Unpin},
+		{Method: http.MethodPost, Path: "/archive", Handler: handler.Archive},
+		{Method: http.MethodPost, Path: "/unarchive", Handler: handler.Unarchive},
+		{Method: http.MethodPost, Path: "/restart", Handler: handler.Restart},
+		{Method: http.MethodPut, Path: "/schedule", Handler: handler.SetSchedule},
+		{Method: http.MethodDelete, Path: "/schedule", Handler: handler.DeleteSchedule},
+		{Method: http.MethodPut, Path: "/domain", Handler: handler.SetDomain},
+		{Method: http.MethodDelete, Path: "/domain", Handler: handler.DeleteDomain},
+		{Method: http.MethodPost, Path: "/share", Handler: handler.Share},
+		{Method: http.MethodGet, Path: "/screenshot", Handler: handler.Screenshot},
+		{Method: http.MethodGet, Path: "/thumbnail", Handler: handler.Thumbnail},
+		{Method: http.MethodGet, Path: "/static/*filepath", Handler: handler.Static},
+	}
 }
 
+// TenantPrefix is the namespace of a tenant's control API and runtimes.
+const TenantPrefix = "/t/:tenant"
+
 func CreateRoutes(router *gin.Engine, handler Handlers) {
-	router.POST("/execute", handler.Execute)
-	router.POST("/stop/:id", handler.Stop)
-	router.GET("/status/:id", handler.Status)
+	router.Use(handler.Recover)
+	api := router.Group("", handler.Tenant, handler.Limits)
+	api.POST("/execute", handler.Execute)
+	api.POST("/compose", handler.Compose)
+	api.GET("/groups/:id", handler.GroupStatus)
+	api.POST("/groups/:id/stop", handler.StopGroup)
+	api.POST("/stop/:id", handler.Stop)
+	api.GET("/status/:id", handler.Status)
+	api.DELETE("/runtime/:id", handler.Delete)
+	api.GET("/usage", handler.UsageReport)
+	api.GET("/analytics/generations", handler.GenerationAnalytics)
+	api.GET("/runtimes", handler.List)
+	api.GET("/prompts", handler.ListPrompts)
+	api.POST("/prompts", handler.CreatePrompt)
+	api.GET("/prompts/:name", handler.GetPrompt)
+	api.PUT("/prompts/:name", handler.UpdatePrompt)
+	api.DELETE("/prompts/:name", handler.DeletePrompt)
+	api.GET("/scaffolds", handler.ListScaffolds)
+	api.GET("/secrets", handler.ListSecrets)
+	api.PUT("/secrets/:name", handler.PutSecret)
+	api.DELETE("/secrets/:name", handler.DeleteSecret)
+	for _, route := range RuntimeRoutes(handler) {
+		api.Handle(route.Method, "/runtime/:id"+route.Path, route.Handler)
+	}
+	tenant := router.Group(TenantPrefix, handler.Tenant, handler.Limits)
+	tenant.POST("/execute", handler.Execute)
+	tenant.POST("/compose", handler.Compose)
+	tenant.GET("/groups/:id", handler.GroupStatus)
+	tenant.POST("/groups/:id/stop", handler.StopGroup)
+	tenant.POST("/stop/:id", handler.Stop)
+	tenant.GET("/status/:id", handler.Status)
+	tenant.DELETE("/runtime/:id", handler.Delete)
+	tenant.GET("/usage", handler.UsageReport)
+	tenant.GET("/analytics/generations", handler.GenerationAnalytics)
+	tenant.GET("/runtimes", handler.List)
+	tenant.GET("/prompts", handler.ListPrompts)
+	tenant.POST("/prompts", handler.CreatePrompt)
+	tenant.GET("/prompts/:name", handler.GetPrompt)
+	tenant.PUT("/prompts/:name", handler.UpdatePrompt)
+	tenant.DELETE("/prompts/:name", handler.DeletePrompt)
+	tenant.GET("/scaffolds", handler.ListScaffolds)
+	tenant.GET("/secrets", handler.ListSecrets)
+	tenant.PUT("/secrets/:name", handler.PutSecret)
+	tenant.DELETE("/secrets/:name", handler.DeleteSecret)
+	for _, route := range RuntimeRoutes(handler) {
+		tenant.Handle(route.Method, "/runtime/:id"+route.Path, route.Handler)
+	}
+	router.GET("/", handler.Gallery)
+	router.GET("/openapi.json", handler.OpenAPI)
+	router.GET("/r/:slug", handler.ShortLink)
+	router.GET("/r/:slug/qr", handler.ShareQRCode)
+	router.GET("/healthz", handler.Healthz)
+	router.GET("/readyz", handler.Readyz)
+	// Prefixes of runtimes that are not proxied explain why instead of a bare 404. Their roots
+	// are routed explicitly since the router would redirect them to the DELETE path otherwise.
+	router.GET("/runtime/:id/", handler.RuntimeUnavailable)
+	router.GET(TenantPrefix+"/runtime/:id/", handler.RuntimeUnavailable)
+	router.NoRoute(handler.RuntimeUnavailable)
+}
+
+// CreateAdminRoutes serves the admin API, which is guarded by admin_api_keys.
+func CreateAdminRoutes(router *gin.Engine, handler Handlers) {
+	admin := router.Group("/admin", handler.Admin)
+	admin.GET("/usage", handler.AdminUsage)
+	admin.POST("/stop-all", handler.AdminStopAll)
+	admin.POST("/pause", handler.AdminPause)
+	admin.POST("/resume", handler.AdminResume)
+	admin.POST("/drain", handler.AdminDrain)
+	admin.POST("/evict/:id", handler.AdminEvict)
+}
+
+// CreateMetricsRoutes serves the Prometheus metrics and the dashboard and alert rules built on
+// them.
+func CreateMetricsRoutes(router *gin.Engine, handler Handlers) {
+	router.GET("/metrics", handler.Metrics)
+	router.GET("/metrics/grafana", handler.GrafanaDashboard)
+	router.GET("/metrics/alerts", handler.AlertRules)
+}
+
+// registeredProxy is the route of a proxied runtime and the reverse proxy serving it.
+type registeredProxy struct {
+	route *ProxyRoute
+	proxy *httputil.ReverseProxy
 }
 
-func (s *DynamicRouteService) RegisterReverseProxy(runtimeID string, port int) {
+// RegisterReverseProxy routes the runtime's prefix, which depends on its tenant, to its port.
+func (s *DynamicRouteService) RegisterReverseProxy(runtimeID string, tenant string, port int) {
+	version := 1
+	if s.Store != nil {
+		if prev, err := s.Store.Load(runtimeID); err == nil {
+			version = prev.Version + 1
+		}
+	}
 	s.DeregisterReverseProxy(runtimeID) // Deregister if already exists
-	targetURL, _ := url.Parse("http://localhost:" + strconv.Itoa(port))
+	route := &ProxyRoute{
+		RuntimeID:    runtimeID,
+		Tenant:       tenant,
+		Port:         port,
+		Version:      version,
+		Prefix:       models.RuntimePrefix(tenant, runtimeID),
+		Active:       true,
+		RegisteredAt: time.Now(),
+	}
+	s.addRoute(route)
+	s.place(route)
+	if s.Store != nil {
+		if err := s.Store.Save(route); err != nil {
+			log.Printf("⚠️ Failed to persist proxy route for runtime %s: %v", runtimeID, err)
+		}
+	}
+}
+
+// RestoreRoutes rebuilds the routing table from the persisted proxy routes exactly as
+// they were last registered.
+func (s *DynamicRouteService) RestoreRoutes() error {
+	if s.Store == nil {
+		return nil
+	}
+	routes, err := s.Store.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load proxy routes: %w", err)
+	}
+	for _, route := range routes {
+		if route.Active {
+			s.addRoute(route)
+			s.place(route)
+		}
+	}
+	return nil
+}
+
+func (s *DynamicRouteService) addRoute(route *ProxyRoute) {
+	targetURL, _ := url.Parse("http://localhost:" + strconv.Itoa(route.Port))
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 
 	proxy.ModifyResponse = func(resp *http.Response) error {
-		resp.Header.Set("X-Application-Base", targetURL.RawPath+"/runtime/"+runtimeID)
+		resp.Header.Set("X-Application-Base", targetURL.RawPath+route.Prefix)
+		if s.Headers != nil {
+			s.Headers.apply(resp.Header)
+		}
+		if err := s.Limits.limitResponse(resp); err != nil {
+			return err
+		}
+		if s.Compression != nil {
+			s.Compression.apply(resp)
+		}
 		return nil
 	}
+	proxy.ErrorHandler = proxyError(route.RuntimeID)
 
 	// Store the proxy in sync.Map
-	s.ProxyMap.Store(runtimeID, proxy)
+	s.ProxyMap.Store(route.RuntimeID, &registeredProxy{route: route, proxy: proxy})
 
 	// Register endpoint in Gin router
-	s.Router.Any("/runtime/"+runtimeID+"/*any", func(c *gin.Context) {
-		proxy.ServeHTTP(c.Writer, c.Request)
-	})
+	s.Router.Any(route.Prefix+"/*any", s.proxyHandler(route, proxy))
 
-	log.Printf("✅ Proxy registered: /runtime/%s → localhost:%d", runtimeID, port)
+	log.Printf("✅ Proxy registered: %s → localhost:%d (v%d)", route.Prefix, route.Port, route.Version)
 }
 
 func (s *DynamicRouteService) DeregisterReverseProxy(runtimeID string) {
 	// Check if proxy exists
-	_, exists := s.ProxyMap.Load(runtimeID)
+	value, exists := s.ProxyMap.Load(runtimeID)
 	if !exists {
 		log.Printf("⚠️ Proxy not found for runtime: %s", runtimeID)
 		return
@@ -63,16 +354,25 @@ func (s *DynamicRouteService) DeregisterReverseProxy(runtimeID string) {
 
 	// Delete from sync.Map
 	s.ProxyMap.Delete(runtimeID)
+	if s.Store != nil {
+		if route, err := s.Store.Load(runtimeID); err == nil {
+			route.Active = false
+			if err := s.Store.Save(route); err != nil {
+				log.Printf("⚠️ Failed to persist proxy route for runtime %s: %v", runtimeID, err)
+			}
+		}
+	}
 	ctx := context.Background()
 
 	// Remove the dynamic route by replacing the router
 	newRouter := qgin.NewGinEngine(&ctx, &qgin.Config{LogRequestID: true, ProdMode: true})
 	CreateRoutes(newRouter, s.Handler)
-	s.ProxyMap.Range(func(id, value interface{}) bool {
-		proxy := value.(*httputil.ReverseProxy)
-		newRouter.Any("/runtime/"+id.(string)+"/*any", func(c *gin.Context) {
-			proxy.ServeHTTP(c.Writer, c.Request)
-		})
+	for _, createRoutes := range s.ExtraRoutes {
+		createRoutes(newRouter, s.Handler)
+	}
+	s.ProxyMap.Range(func(_, value interface{}) bool {
+		registered := value.(*registeredProxy)
+		newRouter.Any(registered.route.Prefix+"/*any", s.proxyHandler(registered.route, registered.proxy))
 		return true
 	})
 
@@ -80,5 +380,167 @@ func (s *DynamicRouteService) DeregisterReverseProxy(runtimeID string) {
 	s.Router = newRouter
 	s.RouterSwitcher.UpdateRouter(newRouter)
 
-	log.Printf("❌ Proxy deregistered: /runtime/%s", runtimeID)
+	log.Printf("❌ Proxy deregistered: %s", value.(*registeredProxy).route.Prefix)
+}
+
+func (s *DynamicRouteService) proxyHandler(route *ProxyRoute, proxy *httputil.ReverseProxy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.dispatchRuntimeRoute(c, route) {
+			return
+		}
+		start := time.Now()
+		s.serveProxy(c, route, proxy)
+		metrics.ObserveStage(metrics.StageProxy, time.Since(start))
+		s.record(c, route, start)
+	}
+}
+
+// recordedKey is set in the gin context of a request whose traffic was recorded, so a request
+// held and forwarded from within the proxy handler is counted once.
+const recordedKey = "aegisx.recorded"
+
+func (s *DynamicRouteService) record(c *gin.Context, route *ProxyRoute, start time.Time) {
+	if s.Traffic == nil || c.GetBool(recordedKey) {
+		return
+	}
+	c.Set(recordedKey, true)
+	s.Traffic.Record(route.RuntimeID, traffic.Entry{
+		RemoteAddr: c.ClientIP(),
+		Method:     c.Request.Method,
+		URI:        c.Request.URL.RequestURI(),
+		Proto:      c.Request.Proto,
+		Status:     c.Writer.Status(),
+		Size:       c.Writer.Size(),
+		Referer:    c.Request.Referer(),
+		UserAgent:  c.Request.UserAgent(),
+		Start:      start,
+		Latency:    time.Since(start),
+	})
+}
+
+// NoHoldKey is set in the gin context of a request that must not be held until its runtime is
+// healthy again, because it was held already or a failed attempt may have consumed its body.
+const NoHoldKey = "aegisx.noHold"
+
+// serveProxy forwards the request to the runtime within the limits, explaining the runtime's
+// state when it cannot be reached.
+func (s *DynamicRouteService) serveProxy(c *gin.Context, route *ProxyRoute, proxy *httputil.ReverseProxy) {
+	original := c.Request
+	release, ok := s.Limits.limitRequest(c)
+	if !ok {
+		return
+	}
+	var unreachable bool
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), unreachableKey{}, &unreachable))
+	proxy.ServeHTTP(c.Writer, c.Request)
+	release()
+	if !unreachable {
+		return
+	}
+	c.Request = original
+	if original.Body != nil && original.Body != http.NoBody {
+		c.Set(NoHoldKey, true)
+	}
+	c.Params = runtimeParams(route)
+	s.Handler.RuntimeUnavailable(c)
+}
+
+// Forward serves a request under the runtime's prefix through its proxy, reporting false when
+// the runtime is not proxied. It lets a request held while the runtime was being rebuilt reach
+// the new instance.
+func (s *DynamicRouteService) Forward(c *gin.Context, runtimeID string) bool {
+	value, ok := s.ProxyMap.Load(runtimeID)
+	if !ok {
+		return false
+	}
+	registered := value.(*registeredProxy)
+	start := time.Now()
+	s.serveProxy(c, registered.route, registered.proxy)
+	s.record(c, registered.route, start)
+	return true
+}
+
+// place records this node as the host of the route's runtime in the shared registry. The
+// placement outlives the proxy so a stopped runtime is still managed through its node.
+func (s *DynamicRouteService) place(route *ProxyRoute) {
+	if s.Registry == nil {
+		return
+	}
+	placement := registry.Placement{RuntimeID: route.RuntimeID, Tenant: route.Tenant, NodeID: s.Node.ID, NodeURL: s.Node.URL, UpdatedAt: time.Now()}
+	if err := s.Registry.Place(context.Background(), placement); err != nil {
+		log.Printf("⚠️ Failed to place runtime %s in the registry: %v", route.RuntimeID, err)
+	}
+}
+
+// RemoveReverseProxy deregisters the runtime's proxy and deletes its persisted route,
+// placement, access logs and domain, for runtimes that will never be proxied again.
+func (s *DynamicRouteService) RemoveReverseProxy(runtimeID string) error {
+	s.DeregisterReverseProxy(runtimeID)
+	if s.Traffic != nil {
+		if err := s.Traffic.Remove(runtimeID); err != nil {
+			return err
+		}
+	}
+	if s.Domains != nil {
+		if err := s.Domains.Unmap(runtimeID); err != nil {
+			return err
+		}
+	}
+	if s.Registry != nil {
+		if err := s.Registry.Remove(context.Background(), runtimeID, s.Node.ID); err != nil {
+			log.Printf("⚠️ Failed to remove runtime %s from the registry: %v", runtimeID, err)
+		}
+	}
+	if s.Store == nil {
+		return nil
+	}
+	return s.Store.Delete(runtimeID)
+}
+
+// dispatchRuntimeRoute serves a control endpoint shadowed by a runtime's proxy route,
+// reporting whether the request was handled. The endpoint runs behind the Tenant middleware
+// like its unshadowed route.
+func (s *DynamicRouteService) dispatchRuntimeRoute(c *gin.Context, proxied *ProxyRoute) bool {
+	path := strings.Split(strings.Trim(c.Param("any"), "/"), "/")
+	for _, route := range RuntimeRoutes(s.Handler) {
+		if route.Method != c.Request.Method {
+			continue
+		}
+		pattern := strings.Split(strings.Trim(route.Path, "/"), "/")
+		catchAll := strings.HasPrefix(pattern[len(pattern)-1], "*")
+		if len(pattern) != len(path) && !(catchAll && len(path) >= len(pattern)-1) {
+			continue
+		}
+		params := runtimeParams(proxied)
+		matched := true
+		for i, segment := range pattern {
+			if strings.HasPrefix(segment, "*") {
+				params = append(params, gin.Param{Key: segment[1:], Value: "/" + strings.Join(path[i:], "/")})
+				break
+			}
+			if strings.HasPrefix(segment, ":") {
+				params = append(params, gin.Param{Key: segment[1:], Value: path[i]})
+			} else if segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			c.Params = params
+			if s.Handler.Tenant(c); !c.IsAborted() {
+				route.Handler(c)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeParams are the path parameters of the runtime's control endpoints.
+func runtimeParams(route *ProxyRoute) gin.Params {
+	params := gin.Params{{Key: "id", Value: route.RuntimeID}}
+	if route.Tenant != "" {
+		params = append(params, gin.Param{Key: "tenant", Value: route.Tenant})
+	}
+	return params
 }
@@ -0,0 +1,148 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/services/registry"
+)
+
+// ForwardedHeader names the node that forwarded a request; forwarded requests are always
+// served locally so a stale placement cannot loop between nodes.
+const ForwardedHeader = "X-Aegisx-Forwarded-By"
+
+// lookupTimeout bounds the registry lookups done for each request.
+const lookupTimeout = 2 * time.Second
+
+// NodeRouter is the front door of a node in a multi-node deployment. Requests for a runtime
+// hosted by another node, found through the registry, are forwarded to that node; control
+// nodes also forward execute requests to the least loaded worker. Everything else is served by
+// Local.
+type NodeRouter struct {
+	Local    http.Handler
+	Registry registry.Registry
+	Node     registry.Node
+	// IsLocal reports whether this node knows the runtime.
+	IsLocal func(runtimeID string) bool
+	// Runtimes, if not nil, publishes the runtimes of every node, which locates a runtime that
+	// has no placement yet, such as one still being generated.
+	Runtimes registry.RuntimeRegistry
+
+	proxies sync.Map // *httputil.ReverseProxy by node URL
+}
+
+func (n *NodeRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(ForwardedHeader) != "" {
+		n.Local.ServeHTTP(w, r)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), lookupTimeout)
+	defer cancel()
+	if runtimeID := runtimeIDFromPath(r.URL.Path); runtimeID != "" && !n.IsLocal(runtimeID) {
+		placement, err := n.Registry.Lookup(ctx, runtimeID)
+		if errors.Is(err, registry.ErrNotFound) {
+			placement, err = n.recordPlacement(ctx, runtimeID)
+		}
+		if err == nil && placement.NodeID != n.Node.ID {
+			n.forward(w, r, placement.NodeID, placement.NodeURL)
+			return
+		}
+	}
+	if !n.Node.Hosts() && r.Method == http.MethodPost && isExecutePath(r.URL.Path) {
+		nodes, err := n.Registry.Nodes(ctx)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, "failed to list nodes: "+err.Error())
+			return
+		}
+		worker := registry.LeastLoaded(nodes)
+		if worker == nil {
+			writeError(w, http.StatusServiceUnavailable, "no worker node is available")
+			return
+		}
+		n.forward(w, r, worker.ID, worker.URL)
+		return
+	}
+	n.Local.ServeHTTP(w, r)
+}
+
+// recordPlacement places a runtime on the node that published its record, or returns
+// registry.ErrNotFound when no live node did.
+func (n *NodeRouter) recordPlacement(ctx context.Context, runtimeID string) (*registry.Placement, error) {
+	if n.Runtimes == nil {
+		return nil, registry.ErrNotFound
+	}
+	records, err := n.Runtimes.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.ID != runtimeID || record.Node == "" {
+			continue
+		}
+		nodes, err := n.Registry.Nodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if node.ID == record.Node {
+				return &registry.Placement{RuntimeID: record.ID, Tenant: record.Tenant, NodeID: node.ID, NodeURL: node.URL, UpdatedAt: record.UpdatedAt}, nil
+			}
+		}
+	}
+	return nil, registry.ErrNotFound
+}
+
+func (n *NodeRouter) forward(w http.ResponseWriter, r *http.Request, nodeID string, nodeURL string) {
+	proxy, ok := n.proxies.Load(nodeURL)
+	if !ok {
+		target, err := url.Parse(nodeURL)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "invalid URL of node "+nodeID+": "+nodeURL)
+			return
+		}
+		reverseProxy := httputil.NewSingleHostReverseProxy(target)
+		reverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("⚠️ Failed to forward %s to node %s: %v", r.URL.Path, nodeID, err)
+			writeError(w, http.StatusBadGateway, "node "+nodeID+" is unreachable")
+		}
+		proxy, _ = n.proxies.LoadOrStore(nodeURL, reverseProxy)
+	}
+	r.Header.Set(ForwardedHeader, n.Node.ID)
+	proxy.(*httputil.ReverseProxy).ServeHTTP(w, r)
+}
+
+// runtimeIDFromPath returns the runtime addressed by a runtime, stop or status path, in the
+// default namespace or a tenant's.
+func runtimeIDFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 2 && segments[0] == "t" {
+		segments = segments[2:]
+	}
+	if len(segments) < 2 {
+		return ""
+	}
+	switch segments[0] {
+	case "runtime", "stop", "status":
+		return segments[1]
+	}
+	return ""
+}
+
+func isExecutePath(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return path == "/execute" || len(segments) == 3 && segments[0] == "t" && segments[2] == "execute"
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyLimits protects the front door and the runtimes behind it, whose generated programs have
+// no safeguards of their own, from oversized and slow requests. Zero values disable a limit.
+type ProxyLimits struct {
+	MaxRequestBytes  int64
+	MaxResponseBytes int64
+	// Timeout bounds a proxied request from its arrival until its response is written.
+	Timeout time.Duration
+	// ClientTimeout bounds how long a client may take to send its request body.
+	ClientTimeout time.Duration
+}
+
+// timeoutGrace leaves time to write the timeout error after Timeout.
+const timeoutGrace = 5 * time.Second
+
+// errResponseTooLarge fails a proxied response longer than MaxResponseBytes.
+var errResponseTooLarge = errors.New("runtime response is too large")
+
+// limitRequest applies the request limits, responding itself and returning false when the
+// request is rejected. The returned function releases the request's timeout. Upgraded
+// connections such as websockets are long-lived, so they are only size limited.
+func (l ProxyLimits) limitRequest(c *gin.Context) (func(), bool) {
+	hasBody := c.Request.Body != nil && c.Request.Body != http.NoBody
+	if l.MaxRequestBytes > 0 && hasBody {
+		if c.Request.ContentLength > l.MaxRequestBytes {
+			writeError(c.Writer, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", l.MaxRequestBytes))
+			return nil, false
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, l.MaxRequestBytes)
+	}
+	if isUpgrade(c.Request.Header) {
+		return func() {}, true
+	}
+	controller := http.NewResponseController(c.Writer)
+	// Deadlines are best effort: writers that do not support them are left alone.
+	if l.ClientTimeout > 0 && hasBody {
+		_ = controller.SetReadDeadline(time.Now().Add(l.ClientTimeout))
+		c.Request.Body = &deadlineBody{ReadCloser: c.Request.Body, controller: controller}
+	}
+	if l.Timeout <= 0 {
+		return func() {}, true
+	}
+	_ = controller.SetWriteDeadline(time.Now().Add(l.Timeout + timeoutGrace))
+	ctx, cancel := context.WithTimeout(c.Request.Context(), l.Timeout)
+	c.Request = c.Request.WithContext(ctx)
+	return cancel, true
+}
+
+// isUpgrade reports whether a request asks to upgrade its connection. Connection is a list of
+// comma-separated tokens, e.g. "keep-alive, Upgrade", and may be sent more than once.
+func isUpgrade(header http.Header) bool {
+	for _, value := range header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// limitResponse fails responses that announce more than MaxResponseBytes and cuts off the
+// ones that stream past it.
+func (l ProxyLimits) limitResponse(resp *http.Response) error {
+	if l.MaxResponseBytes <= 0 {
+		return nil
+	}
+	if resp.ContentLength > l.MaxResponseBytes {
+		return errResponseTooLarge
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: l.MaxResponseBytes}
+	return nil
+}
+
+// limitedBody returns errResponseTooLarge once more than remaining bytes were read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n - int(-b.remaining), errResponseTooLarge
+	}
+	return n, err
+}
+
+// deadlineBody clears the read deadline once the request body was read, since the server keeps
+// reading the connection afterwards to notice a client going away. It remembers a read that
+// timed out because the server cancels the request then, which the proxy may report instead.
+type deadlineBody struct {
+	io.ReadCloser
+	controller *http.ResponseController
+	timedOut   atomic.Bool
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch {
+	case err == io.EOF:
+		_ = b.controller.SetReadDeadline(time.Time{})
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		b.timedOut.Store(true)
+	}
+	return n, err
+}
+
+// unreachableKey marks a proxied request's context with a flag proxyError sets when the
+// runtime could not be reached, leaving the response to the proxy handler.
+type unreachableKey struct{}
+
+// proxyError answers a request the runtime could not serve within the limits, or at all.
+func proxyError(runtimeID string) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		var maxBytes *http.MaxBytesError
+		body, _ := r.Body.(*deadlineBody)
+		switch {
+		case errors.As(err, &maxBytes):
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytes.Limit))
+		case errors.Is(err, os.ErrDeadlineExceeded) || body != nil && body.timedOut.Load():
+			writeError(w, http.StatusRequestTimeout, "the request body was not received in time")
+		case errors.Is(err, context.DeadlineExceeded):
+			writeError(w, http.StatusGatewayTimeout, "runtime "+runtimeID+" did not respond in time")
+		case errors.Is(err, errResponseTooLarge):
+			writeError(w, http.StatusBadGateway, "runtime "+runtimeID+" sent a response that is too large")
+		case errors.Is(err, context.Canceled):
+			// The client went away, so only the access log sees the status.
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			log.Printf("⚠️ Proxy error for runtime %s: %v", runtimeID, err)
+			if unreachable, ok := r.Context().Value(unreachableKey{}).(*bool); ok {
+				// The proxy handler explains the runtime's state instead.
+				*unreachable = true
+				return
+			}
+			writeError(w, http.StatusBadGateway, "runtime "+runtimeID+" is unreachable")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyRoute is the persisted record of what was actually registered in the router for a runtime.
+type ProxyRoute struct {
+	RuntimeID    string    `json:"runtimeID"`
+	Tenant       string    `json:"tenant,omitempty"`
+	Port         int       `json:"port"`
+	Version      int       `json:"version"`
+	Prefix       string    `json:"prefix"`
+	Active       bool      `json:"active"`
+	RegisteredAt time.Time `json:"registeredAt,omitzero"`
+}
+
+// ProxyStore persists proxy routes as one JSON file per runtime so the routing table
+// can be rebuilt after a crash.
+type ProxyStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func (s *ProxyStore) path(runtimeID string) string {
+	return filepath.Join(s.Dir, runtimeID+".json")
+}
+
+func (s *ProxyStore) Save(route *ProxyRoute) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(route)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proxy route: %w", err)
+	}
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(s.path(route.RuntimeID), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write proxy route: %w", err)
+	}
+	return nil
+}
+
+func (s *ProxyStore) Load(runtimeID string) (*ProxyRoute, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path(runtimeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proxy route: %w", err)
+	}
+	route := new(ProxyRoute)
+	if err := json.Unmarshal(data, route); err != nil {
+		return nil, fmt.Errorf("failed to decode proxy route: %w", err)
+	}
+	return route, nil
+}
+
+func (s *ProxyStore) Delete(runtimeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(runtimeID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete proxy route: %w", err)
+	}
+	return nil
+}
+
+func (s *ProxyStore) LoadAll() ([]*ProxyRoute, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var routes []*ProxyRoute
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		route, err := s.Load(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
@@ -0,0 +1,30 @@
+package routes
+
+import "net/http"
+
+// strippedHeaders reveal the software behind a runtime and are removed from its responses.
+var strippedHeaders = []string{"Server", "X-Powered-By"}
+
+// SecurityHeaders are added to proxied responses, since generated programs never set them. A
+// header the runtime sets itself is kept, and empty values are not sent.
+type SecurityHeaders struct {
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+func (h *SecurityHeaders) apply(header http.Header) {
+	for _, name := range strippedHeaders {
+		header.Del(name)
+	}
+	for name, value := range map[string]string{
+		"Content-Security-Policy": h.ContentSecurityPolicy,
+		"X-Frame-Options":         h.FrameOptions,
+		"Referrer-Policy":         h.ReferrerPolicy,
+		"X-Content-Type-Options":  "nosniff",
+	} {
+		if value != "" && header.Get(name) == "" {
+			header.Set(name, value)
+		}
+	}
+}
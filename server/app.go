@@ -0,0 +1,523 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/demo"
+	"github.com/gcottom/aegisx/grpcapi"
+	"github.com/gcottom/aegisx/handlers"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/audit"
+	"github.com/gcottom/aegisx/services/browser"
+	"github.com/gcottom/aegisx/services/cache"
+	"github.com/gcottom/aegisx/services/database"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/handoff"
+	"github.com/gcottom/aegisx/services/health"
+	"github.com/gcottom/aegisx/services/ids"
+	"github.com/gcottom/aegisx/services/kv"
+	"github.com/gcottom/aegisx/services/moderation"
+	"github.com/gcottom/aegisx/services/notify"
+	"github.com/gcottom/aegisx/services/outbound"
+	"github.com/gcottom/aegisx/services/registry"
+	"github.com/gcottom/aegisx/services/scaffold"
+	"github.com/gcottom/aegisx/services/scheduler"
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gcottom/aegisx/services/title"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/qgin/qgin"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"gopkg.in/tylerb/graceful.v1"
+)
+
+// App is a node with every component built and wired: the LLM clients, the executer, the API
+// handler and router and the dynamic routes. New builds it, Start serves it and Stop shuts it
+// down. The components are exported for modules, which wire themselves in once the core is
+// built.
+type App struct {
+	Config    *config.Config
+	Usage     *util.UsageTracker
+	GPTClient *util.GPTClient // Plain-text client for titles, plans, diagnoses and post-mortems
+	Breaker   *util.CircuitBreaker
+	Audit     *audit.Store
+	Executer  *executer.ExecuterService
+	Handler   *handlers.MainHandler
+	Switcher  *routes.RouterSwitcher
+	Routes    *routes.DynamicRouteService
+	Registry  registry.Registry // Nil unless the node is part of a cluster
+	FrontDoor http.Handler      // Serves every request: custom domains, other nodes' runtimes, then Switcher
+	// Embedded apps are served through FrontDoor by the host program's HTTP server instead of
+	// listening on port and tls_port.
+	Embedded bool
+
+	modules   []Module
+	successor *handoff.Successor
+	ctx       context.Context // Canceled by Stop, ending the background work
+	cancel    context.CancelFunc
+	listeners map[string]net.Listener
+	grpc      *grpc.Server
+	server    *graceful.Server
+	tlsServer *graceful.Server
+	done      chan error
+}
+
+// New builds a node from cfg and installs modules in order. It takes over from a running
+// process first when cfg sets a handoff socket, and opens the stores, but serves nothing until
+// Start.
+func New(cfg *config.Config, modules ...Module) (*App, error) {
+	if err := resolvePaths(cfg); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &App{Config: cfg, modules: modules, ctx: ctx, cancel: cancel, listeners: map[string]net.Listener{}}
+	if err := a.build(); err != nil {
+		a.close()
+		return nil, err
+	}
+	for _, module := range modules {
+		if module.Install == nil {
+			continue
+		}
+		if err := module.Install(a); err != nil {
+			a.close()
+			return nil, fmt.Errorf("failed to install module %s: %w", module.Name, err)
+		}
+	}
+	return a, nil
+}
+
+// resolvePaths makes the paths the interpreter is given absolute and fills in the node ID.
+func resolvePaths(cfg *config.Config) error {
+	var err error
+	if cfg.YaegiGoPath, err = util.ResolveYaegiGoPath(cfg.YaegiGoPath); err != nil {
+		return fmt.Errorf("failed to resolve Yaegi GOPATH: %w", err)
+	}
+	if cfg.ModuleStore != "" {
+		// Runtime modules double as interpreter GOPATHs, which must be absolute.
+		if cfg.ModuleStore, err = filepath.Abs(cfg.ModuleStore); err != nil {
+			return fmt.Errorf("failed to resolve module store: %w", err)
+		}
+	}
+	if cfg.OfflineMode {
+		if cfg.OfflinePackageCache, err = filepath.Abs(cfg.OfflinePackageCache); err != nil {
+			return fmt.Errorf("failed to resolve offline package cache: %w", err)
+		}
+		log.Printf("Offline mode: third party packages are only resolved from %s", cfg.OfflinePackageCache)
+	}
+	if cfg.NodeID == "" {
+		if cfg.NodeID, err = os.Hostname(); err != nil {
+			return fmt.Errorf("failed to resolve node ID: %w", err)
+		}
+	}
+	return nil
+}
+
+// build constructs the core components in dependency order.
+func (a *App) build() error {
+	cfg := a.Config
+	var err error
+	// Taking over from a running process comes first, so it saves its runtimes before anything
+	// here reads the store.
+	if cfg.HandoffSocket != "" {
+		a.successor, err = handoff.Dial(cfg.HandoffSocket)
+		if err != nil && !errors.Is(err, handoff.ErrNoPredecessor) {
+			return fmt.Errorf("failed to take over from the running process: %w", err)
+		}
+		if a.successor != nil {
+			log.Printf("Taking over from the running process on %s", cfg.HandoffSocket)
+		}
+	}
+	generationClient, generation, err := a.buildClients()
+	if err != nil {
+		return err
+	}
+	if err := a.buildExecuter(generationClient, generation); err != nil {
+		return err
+	}
+	return a.buildRoutes()
+}
+
+// buildClients creates the plain-text GPT client and returns the client that generates code,
+// along with the GPT client behind it that execution targets are copied from.
+func (a *App) buildClients() (util.LLMClient, *util.GPTClient, error) {
+	cfg := a.Config
+	log.Println("Creating GPT client")
+	gptClient := util.NewGPTClient(cfg.GptApiKey)
+	if gptClient == nil {
+		return nil, nil, errors.New("failed to create GPT client")
+	}
+	if cfg.Model != "" {
+		gptClient.Model = cfg.Model
+	}
+	a.Usage = util.NewUsageTracker()
+	gptClient.Usage = a.Usage
+	gptClient.Params.MaxTokens = cfg.MaxTokens
+	a.GPTClient = gptClient
+	if cfg.AuditLog != "" {
+		var err error
+		if a.Audit, err = audit.NewStore(cfg.AuditLog, cfg.AuditLogTTL, cfg.AuditMaxContent, cfg.AuditRedact); err != nil {
+			return nil, nil, fmt.Errorf("failed to create LLM audit log: %w", err)
+		}
+		if err := a.Audit.Load(); err != nil {
+			return nil, nil, fmt.Errorf("failed to load LLM audit log: %w", err)
+		}
+		// Set before the other clients are copied from gptClient, so every request is logged.
+		gptClient.Audit = a.Audit
+	}
+	// Only code generation gets the system prompt, examples and structured output; titles and
+	// summaries stay plain text.
+	generation := *gptClient
+	generation.SystemPrompt = cfg.SystemPrompt
+	generation.SystemRole = cfg.SystemRole
+	generation.MaxContinuations = cfg.MaxContinuations
+	generation.Params = util.GenerationParams{MaxTokens: cfg.MaxTokens, Temperature: cfg.Temperature, TopP: cfg.TopP, ReasoningEffort: cfg.ReasoningEffort}
+	if cfg.StructuredOutput {
+		generation.ResponseFormat = util.FilesResponseFormat
+		if !util.SupportsResponseFormat(generation.Model) {
+			log.Printf("⚠️ %s does not support structured output; its prompts ask for the JSON files instead", generation.Model)
+		}
+	}
+	if cfg.FewShotStore != "" {
+		examples, err := util.LoadFewShotExamples(cfg.FewShotStore, cfg.MaxFewShotExamples)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load few-shot examples: %w", err)
+		}
+		if generation.Examples, err = util.FewShotMessages(examples, cfg.StructuredOutput); err != nil {
+			return nil, nil, fmt.Errorf("failed to load few-shot examples: %w", err)
+		}
+		log.Printf("Loaded %d few-shot examples from %s", len(examples), cfg.FewShotStore)
+	}
+	if cfg.BreakerThreshold > 0 {
+		a.Breaker = &util.CircuitBreaker{Threshold: cfg.BreakerThreshold, Cooldown: cfg.BreakerCooldown}
+	}
+	if cfg.DemoMode {
+		// Bundled programs stand in for the provider, and titles are built locally, so nothing
+		// calls the LLM.
+		log.Printf("Demo mode: serving %d bundled programs instead of calling the LLM", len(demo.Programs))
+		cfg.TitleProvider = title.ProviderKeyword
+		return &demo.Client{}, &generation, nil
+	}
+	return withBreaker(newGenerationClient(cfg, &generation, a.Usage), a.Breaker), &generation, nil
+}
+
+// buildExecuter creates the executer and the services it generates, validates and runs with.
+func (a *App) buildExecuter(generationClient util.LLMClient, generation *util.GPTClient) error {
+	cfg, gptClient := a.Config, a.GPTClient
+	titleProvider, err := title.NewProvider(cfg, gptClient)
+	if err != nil {
+		return fmt.Errorf("failed to create title provider: %w", err)
+	}
+	idGenerator, err := ids.NewGenerator(cfg.IDStrategy, cfg.IDPrefix, cfg.ExecuterStore)
+	if err != nil {
+		return fmt.Errorf("failed to create ID generator: %w", err)
+	}
+	kvService, err := kv.NewKVService(filepath.Join(cfg.ExecuterStore, "kv.db"))
+	if err != nil {
+		return fmt.Errorf("failed to open kv store: %w", err)
+	}
+	log.Println("Creating executor service")
+	executorService := executer.NewExecuterService(cfg, generationClient, titleProvider, kvService, idGenerator)
+	executorService.Breaker = a.Breaker
+	executorService.Examples = generation.Examples
+	a.Executer = executorService
+	if cfg.RuntimeRegistry == "redis" {
+		if executorService.Runtimes, err = registry.NewRedisRuntimes(a.ctx, cfg.RegistryURL, cfg.NodeID); err != nil {
+			return fmt.Errorf("failed to create runtime registry: %w", err)
+		}
+	}
+	if !cfg.DemoMode {
+		if cfg.VerifyRuntimes {
+			// Plans are plain JSON, so they skip the generation system prompt and structured output.
+			executorService.VerificationClient = gptClient
+			if cfg.VerificationModel != "" {
+				executorService.VerificationClient = gptClient.WithModel(cfg.VerificationModel)
+			}
+		}
+		// Fixes come back as several programs with prose, so they skip structured output too.
+		executorService.DiagnosisClient = gptClient
+		if cfg.PostMortems {
+			executorService.PostMortemClient = gptClient
+			if cfg.PostMortemModel != "" {
+				executorService.PostMortemClient = gptClient.WithModel(cfg.PostMortemModel)
+			}
+		}
+		for _, target := range cfg.ExecutionTargets {
+			executorService.Targets = append(executorService.Targets, withBreaker(newTargetClient(cfg, generation, target), a.Breaker))
+		}
+	}
+	if cfg.BrowserSmokeTest || cfg.Thumbnails {
+		if executorService.Browser, err = browser.NewChecker(cfg.ChromePath); err != nil {
+			return fmt.Errorf("failed to start headless browser: %w", err)
+		}
+	}
+	if executorService.Moderation, err = moderation.NewScreener(cfg); err != nil {
+		return fmt.Errorf("failed to create prompt screener: %w", err)
+	}
+	if executorService.Notifier, err = notify.NewDispatcher(cfg.Notifiers, cfg.NotifyCooldown); err != nil {
+		return fmt.Errorf("failed to create notifiers: %w", err)
+	}
+	if cfg.GenerationCache {
+		executorService.Cache = &cache.GenerationCache{Dir: cfg.GenerationCacheStore}
+	}
+	if err := executorService.History.Load(); err != nil {
+		return fmt.Errorf("failed to load generation history: %w", err)
+	}
+	if cfg.SQLiteEnabled {
+		executorService.SQLite = &database.SQLiteService{Dir: cfg.SQLiteStore}
+	}
+	if cfg.SecretsMasterKey != "" {
+		if executorService.Secrets, err = secrets.NewStore(cfg.SecretsStore, cfg.SecretsMasterKey); err != nil {
+			return fmt.Errorf("failed to open secrets: %w", err)
+		}
+	}
+	if cfg.OutboundProxy.Enabled {
+		executorService.Outbound = outbound.NewProxy(cfg.OutboundProxy)
+	}
+	if cfg.ScaffoldStore != "" {
+		loaded, err := scaffold.LoadDir(cfg.ScaffoldStore)
+		if err != nil {
+			return fmt.Errorf("failed to load scaffolds: %w", err)
+		}
+		log.Printf("Loaded %d scaffolds from %s", loaded, cfg.ScaffoldStore)
+	}
+	for name, language := range cfg.Languages {
+		executer.RegisterBackend(name, executer.NewCommandBackend(language))
+	}
+	if cfg.InterpreterPoolSize > 0 {
+		executorService.Interpreters = util.NewInterpreterPool(a.ctx, cfg.InterpreterPoolSize)
+	}
+	return nil
+}
+
+// buildRoutes creates the API handler, the router and the dynamic routes, and closes the loop
+// between them and the executer: the handler forwards to the dynamic routes, which serve the
+// handler's API, and the executer registers the runtimes it starts with them.
+func (a *App) buildRoutes() error {
+	cfg, executorService := a.Config, a.Executer
+	router := qgin.NewGinEngine(&a.ctx, &qgin.Config{LogRequestID: true, ProdMode: true})
+	mainHandler := handlers.NewMainHandler(executorService, cfg, a.Usage)
+	mainHandler.Secrets = executorService.Secrets
+	mainHandler.Notifier = executorService.Notifier
+	mainHandler.History = executorService.History
+	mainHandler.Breaker = a.Breaker
+	mainHandler.Audit = a.Audit
+	mainHandler.HealthChecks = selfTests(cfg, a.GPTClient, executorService)
+	a.Handler = mainHandler
+	if err := mainHandler.Domains.Load(); err != nil {
+		return fmt.Errorf("failed to load custom domains: %w", err)
+	}
+	if err := mainHandler.Links.Load(); err != nil {
+		return fmt.Errorf("failed to load short links: %w", err)
+	}
+	log.Println("Creating routes")
+	a.Switcher = routes.NewRouterSwitcher(router)
+	routes.CreateRoutes(router, mainHandler)
+	a.Routes = routes.NewDynamicRouteService(cfg, mainHandler, router, a.Switcher)
+	a.Routes.Traffic, a.Routes.Domains = mainHandler.Traffic, mainHandler.Domains
+	mainHandler.Routes = a.Routes
+	executorService.DynamicRouteService = a.Routes
+	a.FrontDoor = a.Switcher
+	var err error
+	if a.Registry, err = registry.New(cfg); err != nil {
+		return fmt.Errorf("failed to create runtime registry: %w", err)
+	}
+	if a.Registry != nil {
+		node := registry.Node{ID: cfg.NodeID, URL: cfg.NodeURL, Role: cfg.NodeRole}
+		a.Routes.Registry, a.Routes.Node = a.Registry, node
+		a.FrontDoor = &routes.NodeRouter{
+			Local:    a.Switcher,
+			Registry: a.Registry,
+			Node:     node,
+			IsLocal: func(runtimeID string) bool {
+				_, ok := executorService.Runtimes.Load(runtimeID)
+				return ok
+			},
+			Runtimes: executorService.Runtimes,
+		}
+		// A peer's host would send its control API to a runtime, like the node's own.
+		mainHandler.Domains.Reserved = func() ([]string, error) {
+			nodes, err := a.Registry.Nodes(a.ctx)
+			if err != nil {
+				return nil, err
+			}
+			reserved := []string{cfg.GetPublicURL(), cfg.NodeURL}
+			for _, node := range nodes {
+				reserved = append(reserved, node.URL)
+			}
+			return reserved, nil
+		}
+	}
+	// Custom domains are rewritten to their runtime's prefix before the runtime is located.
+	mainHandler.Domains.Next = a.FrontDoor
+	a.FrontDoor = mainHandler.Domains
+	return nil
+}
+
+// AddRoutes adds routes to the API. Modules add theirs here rather than on the router itself,
+// which the dynamic routes replace as runtimes come and go.
+func (a *App) AddRoutes(createRoutes func(router *gin.Engine, handler routes.Handlers)) {
+	createRoutes(a.Routes.Router, a.Handler)
+	a.Routes.ExtraRoutes = append(a.Routes.ExtraRoutes, createRoutes)
+}
+
+// Start runs the startup self-test, restores the proxy routes, starts the background work of
+// the node and its modules and, unless Embedded, starts serving. ctx bounds the startup only;
+// the node serves until Stop, or until a successor takes over.
+func (a *App) Start(ctx context.Context) error {
+	cfg := a.Config
+	if report := health.Run(ctx, a.Handler.HealthChecks); !report.Ready {
+		for _, result := range report.Checks {
+			if !result.OK {
+				log.Printf("Self-test %s failed: %s", result.Name, result.Error)
+			}
+		}
+		return errors.New("startup self-test failed")
+	}
+	if err := a.Routes.RestoreRoutes(); err != nil {
+		log.Printf("Failed to restore proxy routes: %v", err)
+	}
+	util.Go("scheduler", "", func() { (&scheduler.Scheduler{ExecutorService: a.Executer}).Run(a.ctx) })
+	if a.Registry != nil {
+		ttl := cfg.RegistryTTL
+		if ttl <= 0 {
+			ttl = registry.DefaultTTL
+		}
+		node := a.Routes.Node
+		util.Go("heartbeat", "", func() {
+			registry.Heartbeat(a.ctx, a.Registry, node, ttl, a.Executer.ActiveRuntimeCount, a.Executer.Draining)
+		})
+		log.Printf("Node %s (%s) joined the %s registry", node.ID, node.Role, cfg.Registry)
+	}
+	if a.Audit != nil {
+		util.Go("audit", "", func() { a.Audit.Run(a.ctx) })
+	}
+	for _, module := range a.modules {
+		if module.Start != nil {
+			util.Go("module "+module.Name, "", func() { module.Start(a.ctx, a) })
+		}
+	}
+	if cfg.GRPCPort > 0 {
+		grpcListener, err := listen(a.successor, "grpc", cfg.GRPCPort)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		a.listeners["grpc"] = grpcListener
+		if len(cfg.AdminAPIKeys) == 0 && len(cfg.Tenants) == 0 {
+			log.Printf("⚠️ The gRPC control plane refuses every call: no admin_api_keys or tenants are configured")
+		}
+		a.grpc = grpcapi.NewServer(&grpcapi.ControlService{ExecutorService: a.Executer, Config: cfg, Prompts: a.Handler.Prompts, Limits: a.Handler})
+		go func() {
+			log.Printf("gRPC control plane listening on port %d\n", cfg.GRPCPort)
+			if err := a.grpc.Serve(grpcListener); err != nil {
+				log.Printf("gRPC control plane stopped: %v", err)
+			}
+		}()
+	}
+	if a.Embedded {
+		return nil
+	}
+	log.Println("Starting server")
+	log.Printf("Server listening on port %d\n", cfg.Port)
+	httpListener, err := listen(a.successor, "http", cfg.Port)
+	if err != nil {
+		a.closeListeners()
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	a.listeners["http"] = httpListener
+	frontDoor := a.FrontDoor
+	if cfg.Autocert {
+		if a.tlsServer, frontDoor, err = serveAutocert(cfg, a.successor, a.Handler.Domains, frontDoor, a.listeners); err != nil {
+			a.closeListeners()
+			return fmt.Errorf("failed to listen for HTTPS: %w", err)
+		}
+	}
+	a.server = CreateGracefulServer(frontDoor, cfg.Port)
+	if cfg.HandoffSocket != "" {
+		go handOver(a.ctx, cfg, a.server, a.Executer, a.successor, a.listeners)
+	}
+	a.done = make(chan error, 1)
+	go func() { a.done <- a.server.Serve(httpListener) }()
+	return nil
+}
+
+// Wait blocks until the node stops serving, on Stop, a signal or a handoff, and returns why.
+// Embedded apps have nothing to wait for.
+func (a *App) Wait() error {
+	if a.done == nil {
+		return errors.New("the app is not serving")
+	}
+	return <-a.done
+}
+
+// Stop stops accepting requests, waits for the ones in flight until ctx is done, and ends the
+// background work. Runtimes are left as they are, for the next start to restore.
+func (a *App) Stop(ctx context.Context) error {
+	timeout := CreateGracefulServer(nil, 0).Timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = max(time.Until(deadline), 0)
+	}
+	for _, server := range []*graceful.Server{a.tlsServer, a.server} {
+		if server == nil {
+			continue
+		}
+		server.Stop(timeout)
+		select {
+		case <-server.StopChan():
+		case <-ctx.Done():
+			a.close()
+			return ctx.Err()
+		}
+	}
+	a.close()
+	return nil
+}
+
+// close ends the background work and releases what New and Start opened.
+func (a *App) close() {
+	a.cancel()
+	a.closeListeners()
+	if a.Registry != nil {
+		if err := a.Registry.Close(); err != nil {
+			log.Printf("Failed to close registry: %v", err)
+		}
+	}
+	if a.Executer == nil {
+		return
+	}
+	if a.Executer.Browser != nil {
+		a.Executer.Browser.Close()
+	}
+	if a.Executer.KV != nil {
+		if err := a.Executer.KV.Close(); err != nil {
+			log.Printf("Failed to close kv store: %v", err)
+		}
+	}
+	if runtimes, ok := a.Executer.Runtimes.(io.Closer); ok {
+		if err := runtimes.Close(); err != nil {
+			log.Printf("Failed to close runtime registry: %v", err)
+		}
+	}
+}
+
+// closeListeners stops the gRPC control plane and closes every listener Start opened.
+func (a *App) closeListeners() {
+	if a.grpc != nil {
+		a.grpc.Stop()
+		a.grpc = nil
+	}
+	for name, listener := range a.listeners {
+		listener.Close()
+		delete(a.listeners, name)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/handoff"
+	"golang.org/x/crypto/acme/autocert"
+	"gopkg.in/tylerb/graceful.v1"
+)
+
+// serveAutocert serves the front door over HTTPS on the TLS port, with certificates for the
+// mapped custom domains obtained from Let's Encrypt on their first request. It returns the
+// HTTPS server and the handler for plain HTTP, which answers the ACME challenges.
+func serveAutocert(cfg *config.Config, successor *handoff.Successor, domains *routes.DomainRouter, frontDoor http.Handler, listeners map[string]net.Listener) (*graceful.Server, http.Handler, error) {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: domains.HostPolicy,
+		Cache:      autocert.DirCache(cfg.AutocertCache),
+		Email:      cfg.AutocertEmail,
+	}
+	tlsListener, err := listen(successor, "https", cfg.TLSPort)
+	if err != nil {
+		return nil, nil, err
+	}
+	listeners["https"] = tlsListener
+	tlsServer := CreateGracefulServer(frontDoor, cfg.TLSPort)
+	go func() {
+		log.Printf("Serving custom domains over HTTPS on port %d\n", cfg.TLSPort)
+		if err := tlsServer.Serve(tls.NewListener(tlsListener, manager.TLSConfig())); err != nil {
+			log.Printf("HTTPS server stopped: %v", err)
+		}
+	}()
+	return tlsServer, manager.HTTPHandler(frontDoor), nil
+}
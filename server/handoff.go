@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/handoff"
+	"gopkg.in/tylerb/graceful.v1"
+)
+
+// listen returns the listener the predecessor handed over under name, or a new one on port.
+func listen(successor *handoff.Successor, name string, port int) (net.Listener, error) {
+	if successor != nil {
+		if listener, ok := successor.Listeners[name]; ok {
+			return listener, nil
+		}
+	}
+	return net.Listen("tcp", ":"+strconv.Itoa(port))
+}
+
+// handOver finishes taking over from the predecessor, if there is one, by restarting its
+// runtimes and releasing it. It then waits on the handoff socket for a successor of its own;
+// once released by one, the server stops accepting and drains so the process exits.
+func handOver(ctx context.Context, cfg *config.Config, server *graceful.Server, executorService *executer.ExecuterService, successor *handoff.Successor, listeners map[string]net.Listener) {
+	if successor != nil {
+		reattachCtx, cancel := context.WithTimeout(ctx, cfg.HandoffTimeout)
+		reattached, err := executorService.ReattachRuntimes(reattachCtx)
+		cancel()
+		if err != nil {
+			// The predecessor keeps serving its runtimes once the connection is dropped.
+			log.Printf("Failed to reattach runtimes, leaving them to the previous process: %v", err)
+			// The predecessor reopens the kv store when abandoned.
+			if executorService.KV != nil {
+				executorService.KV.Release()
+			}
+			successor.Abandon()
+			server.Stop(server.Timeout)
+			return
+		}
+		log.Printf("Reattached %d runtimes, releasing the previous process", reattached)
+		if err := successor.Release(); err != nil {
+			log.Printf("Failed to release the previous process: %v", err)
+		}
+	}
+	err := handoff.Serve(ctx, cfg.HandoffSocket, listeners, handoff.Hooks{
+		Prepare: func() error { return executorService.Detach(ctx) },
+		Abort:   executorService.Attach,
+	})
+	if err != nil {
+		log.Printf("Handoff socket closed: %v", err)
+		return
+	}
+	log.Println("Released by the new process, draining connections")
+	server.Stop(server.Timeout)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/resources"
+	"github.com/gcottom/aegisx/services/watchdog"
+)
+
+// Module is an optional part of an App. Install wires it into the components once New has
+// built them, and Start, if set, runs its background work from Start until the App stops.
+type Module struct {
+	Name    string
+	Install func(app *App) error
+	Start   func(ctx context.Context, app *App)
+}
+
+// DefaultModules returns the modules cfg turns on: metrics and the admin API always, the
+// execution queue when max_concurrent_executions is set and the watchdog when enabled.
+func DefaultModules(cfg *config.Config) []Module {
+	modules := []Module{MetricsModule(), AdminModule()}
+	if cfg.MaxConcurrentExecutions > 0 {
+		modules = append(modules, QueueModule())
+	}
+	if cfg.Watchdog.Enabled {
+		modules = append(modules, WatchdogModule())
+	}
+	return modules
+}
+
+// MetricsModule serves the Prometheus metrics, with the stage budgets of performance_budget,
+// and samples the runtimes' resources when runtime_resources is enabled.
+func MetricsModule() Module {
+	return Module{
+		Name: "metrics",
+		Install: func(app *App) error {
+			metrics.SetStageBudgets(app.Config.PerformanceBudget)
+			if app.Config.RuntimeResources.Enabled {
+				app.Executer.Resources = resources.NewSampler(app.Config.RuntimeResources)
+			}
+			app.AddRoutes(routes.CreateMetricsRoutes)
+			return nil
+		},
+		Start: func(ctx context.Context, app *App) {
+			if app.Executer.Resources != nil {
+				app.Executer.Resources.Run(ctx)
+			}
+		},
+	}
+}
+
+// AdminModule serves the admin API, which only answers requests carrying one of
+// admin_api_keys.
+func AdminModule() Module {
+	return Module{
+		Name: "admin",
+		Install: func(app *App) error {
+			app.AddRoutes(routes.CreateAdminRoutes)
+			return nil
+		},
+	}
+}
+
+// QueueModule queues executions beyond max_concurrent_executions instead of running them all
+// at once.
+func QueueModule() Module {
+	return Module{
+		Name: "queue",
+		Install: func(app *App) error {
+			if app.Config.MaxConcurrentExecutions <= 0 {
+				return errors.New("max_concurrent_executions must be positive")
+			}
+			app.Executer.ExecutionSlots = make(chan struct{}, app.Config.MaxConcurrentExecutions)
+			metrics.ExecutionCapacity.Set(float64(app.Config.MaxConcurrentExecutions))
+			return nil
+		},
+	}
+}
+
+// WatchdogModule sheds load while the host runs short of memory or CPU.
+func WatchdogModule() Module {
+	return Module{
+		Name: "watchdog",
+		Start: func(ctx context.Context, app *App) {
+			(&watchdog.Watchdog{ExecutorService: app.Executer, Traffic: app.Handler.Traffic, Config: app.Config.Watchdog}).Run(ctx)
+		},
+	}
+}
@@ -10,65 +10,135 @@ import (
 	"time"
 
 	"github.com/gcottom/aegisx/config"
-	"github.com/gcottom/aegisx/handlers"
-	"github.com/gcottom/aegisx/routes"
 	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/health"
 	"github.com/gcottom/aegisx/util"
-	"github.com/gcottom/qgin/qgin"
 	"gopkg.in/tylerb/graceful.v1"
 )
 
-func Run() error {
+// Run starts aegisx; args are the command-line flags described by config.Load.
+func Run(args []string) error {
 	log.Println("Starting server")
-	ctx := context.Background()
 	log.Println("Loading config")
-	cfg, err := config.LoadConfig(filepath.Join(util.GetAppRoot(), "config", "config.yaml"))
+	cfg, err := config.Load(args, filepath.Join(util.GetAppRoot(), "config", "config.yaml"))
 	if err != nil {
 		log.Fatal("Failed to load config: ", err)
 		return err
 	}
 	log.Println("Config loaded successfully")
-	log.Println("Creating GPT client")
-	gptClient := util.NewGPTClient(cfg.GptApiKey)
-	if gptClient == nil {
-		log.Fatal("Failed to create GPT client")
-		return errors.New("failed to create GPT client")
-	}
-	log.Println("GPT client created successfully")
-	executorService := &executer.ExecuterService{
-		GPTClient:  gptClient,
-		RetryLimit: 3,
-		Config:     cfg,
-	}
-	log.Println("Creating executor service")
-	router := qgin.NewGinEngine(&ctx, &qgin.Config{LogRequestID: true, ProdMode: true})
-	mainHandler := &handlers.MainHandler{
-		ExecutorService: executorService,
-	}
-	routerSwitcher := routes.NewRouterSwitcher(router)
-	routes.CreateRoutes(router, mainHandler)
-	log.Println("Creating routes")
-	dynamicRouteService := &routes.DynamicRouteService{
-		Handler:        mainHandler,
-		Router:         router,
-		RouterSwitcher: routerSwitcher,
-	}
-	executorService.DynamicRouteService = dynamicRouteService
-	log.Println("Starting server")
-	log.Printf("Server listening on port %d\n", cfg.Port)
-	server := CreateGracefulServer(routerSwitcher, cfg.Port)
-	return server.ListenAndServe()
+	app, err := New(cfg, DefaultModules(cfg)...)
+	if err != nil {
+		log.Fatal(err)
+		return err
+	}
+	if err := app.Start(context.Background()); err != nil {
+		log.Fatal(err)
+		return err
+	}
+	err = app.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), CreateGracefulServer(nil, 0).Timeout)
+	defer cancel()
+	if stopErr := app.Stop(ctx); stopErr != nil {
+		log.Printf("Failed to stop: %v", stopErr)
+	}
+	return err
+}
+
+// newGenerationClient wraps the GPT client in a HedgedClient when hedging is configured and in
+// a FallbackClient when fallback models are. The hedge target defaults to the primary provider
+// and model.
+func newGenerationClient(cfg *config.Config, gptClient *util.GPTClient, usage *util.UsageTracker) util.LLMClient {
+	client := newHedgedClient(cfg, gptClient, usage)
+	if len(cfg.FallbackModels) > 0 {
+		return &util.FallbackClient{Client: client, Models: cfg.FallbackModels}
+	}
+	return client
+}
+
+// newTargetClient returns the client for an execution target: the GPT client pointed at the
+// target's model and provider, with the configured fallback chain.
+func newTargetClient(cfg *config.Config, gptClient *util.GPTClient, target config.ExecutionTarget) util.LLMClient {
+	client := *gptClient
+	client.Model = target.Model
+	if target.APIURL != "" {
+		client.APIURL = target.APIURL
+	}
+	if target.APIKey != "" {
+		client.APIKey = target.APIKey
+	}
+	if len(cfg.FallbackModels) > 0 {
+		return &util.FallbackClient{Client: &client, Models: cfg.FallbackModels}
+	}
+	return &client
+}
+
+// selfTests are the checks run at startup and by the readiness probe. The LLM provider is
+// only checked when readiness_llm_check is set, since a node without it still serves its
+// runtimes.
+func selfTests(cfg *config.Config, gptClient *util.GPTClient, executorService *executer.ExecuterService) []health.Check {
+	checks := []health.Check{
+		health.Func("config", cfg.Validate),
+		health.Writable(cfg.ExecuterStore, cfg.ProxyStore, cfg.StaticStore, cfg.VersionStore, cfg.ModuleStore),
+		health.PortBindable(executorService.PortAllocator),
+		health.Func("draining", func() error {
+			if executorService.Draining() {
+				return errors.New("the node is draining")
+			}
+			return nil
+		}),
+	}
+	if cfg.ReadinessLLMCheck && !cfg.DemoMode {
+		reachable := health.Reachable("llm", gptClient.APIURL)
+		checks = append(checks, health.Check{Name: "llm", Run: func(ctx context.Context) error {
+			if executorService.Breaker != nil && executorService.Breaker.RetryAfter() > 0 {
+				return util.ErrGenerationUnavailable
+			}
+			return reachable.Run(ctx)
+		}})
+	}
+	return checks
+}
+
+// withBreaker sends the client's prompts through breaker, if there is one. The generation
+// client and the execution targets share it, so it counts the failures of generation as a whole.
+func withBreaker(client util.LLMClient, breaker *util.CircuitBreaker) util.LLMClient {
+	if breaker == nil {
+		return client
+	}
+	return &util.BreakerClient{Client: client, Breaker: breaker}
+}
 
+func newHedgedClient(cfg *config.Config, gptClient *util.GPTClient, usage *util.UsageTracker) util.LLMClient {
+	if cfg.HedgeAfter <= 0 {
+		return gptClient
+	}
+	secondary := *gptClient
+	if cfg.HedgeApiUrl != "" {
+		secondary.APIURL = cfg.HedgeApiUrl
+	}
+	if cfg.HedgeApiKey != "" {
+		secondary.APIKey = cfg.HedgeApiKey
+	}
+	if cfg.HedgeModel != "" {
+		secondary.Model = cfg.HedgeModel
+	}
+	return &util.HedgedClient{
+		Primary:   gptClient,
+		Secondary: &secondary,
+		After:     cfg.HedgeAfter,
+		Usage:     usage,
+	}
 }
 
-func CreateGracefulServer(router *routes.RouterSwitcher, port int) *graceful.Server {
+func CreateGracefulServer(router http.Handler, port int) *graceful.Server {
 	return &graceful.Server{
 		Server: &http.Server{
-			Addr:         ":" + strconv.Itoa(port),
-			Handler:      router,
-			ReadTimeout:  1 * time.Minute,
-			WriteTimeout: 5 * time.Minute,
-			IdleTimeout:  3 * time.Minute,
+			Addr:              ":" + strconv.Itoa(port),
+			Handler:           router,
+			ReadTimeout:       1 * time.Minute,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       3 * time.Minute,
 		},
 		Timeout: 30 * time.Second,
 	}
@@ -0,0 +1,292 @@
+// Package analytics records every generation attempt and aggregates them, so operators can
+// follow the success rate over time and compare models and prompt template versions.
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrInvalidInterval is returned for report intervals other than hour and day.
+var ErrInvalidInterval = errors.New("interval must be hour or day")
+
+// Outcome is how a generation attempt ended.
+type Outcome string
+
+const (
+	// OutcomeSuccess is an attempt whose runtime passed its health check.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeFailure is an attempt that failed to generate or start a healthy runtime.
+	OutcomeFailure Outcome = "failure"
+	// OutcomeCancelled is an attempt abandoned because another one won or the request ended.
+	OutcomeCancelled Outcome = "cancelled"
+)
+
+// Attempt is one generation attempt of an execution.
+type Attempt struct {
+	RuntimeID        string    `json:"runtimeID,omitempty"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Prompt           string    `json:"prompt"`
+	TemplateVersion  string    `json:"templateVersion"`
+	Variant          string    `json:"variant,omitempty"`
+	Model            string    `json:"model"`
+	Attempt          int       `json:"attempt"`
+	Cached           bool      `json:"cached,omitempty"`
+	Outcome          Outcome   `json:"outcome"`
+	FailureClass     string    `json:"failureClass,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	StartedAt        time.Time `json:"startedAt"`
+	DurationMs       float64   `json:"durationMs"`
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	TotalTokens      int       `json:"totalTokens"`
+}
+
+// GenerationStats aggregates attempts. SuccessRate leaves out cancelled attempts.
+type GenerationStats struct {
+	Attempts      int     `json:"attempts"`
+	Successes     int     `json:"successes"`
+	Failures      int     `json:"failures"`
+	Cancelled     int     `json:"cancelled"`
+	SuccessRate   float64 `json:"successRate"`
+	AvgDurationMs float64 `json:"avgDurationMs"`
+	// AvgTimeToHealthyMs is the average duration of the successful attempts.
+	AvgTimeToHealthyMs float64        `json:"avgTimeToHealthyMs"`
+	TotalTokens        int            `json:"totalTokens"`
+	FailureClasses     map[string]int `json:"failureClasses,omitempty"`
+}
+
+// GenerationBucket is the stats of the attempts started in an interval.
+type GenerationBucket struct {
+	Start time.Time       `json:"start"`
+	Stats GenerationStats `json:"stats"`
+}
+
+// GenerationReport aggregates the attempts started in [Since, Until).
+type GenerationReport struct {
+	Since             time.Time                  `json:"since"`
+	Until             time.Time                  `json:"until"`
+	Interval          string                     `json:"interval"`
+	Total             GenerationStats            `json:"total"`
+	ByModel           map[string]GenerationStats `json:"byModel"`
+	ByTemplateVersion map[string]GenerationStats `json:"byTemplateVersion"`
+	// ByVariant covers the attempts generated with a prompt variant.
+	ByVariant map[string]GenerationStats `json:"byVariant"`
+	Buckets   []GenerationBucket         `json:"buckets"`
+}
+
+// Intervals are the bucket sizes a report can be broken down by.
+var Intervals = map[string]time.Duration{"hour": time.Hour, "day": 24 * time.Hour}
+
+// Store keeps the attempts of the last Retention, appended to File when it is set.
+type Store struct {
+	File      string
+	Retention time.Duration
+
+	mu       sync.Mutex
+	attempts []Attempt
+}
+
+// Load reads the recorded attempts, compacting the file if some are past the retention.
+func (s *Store) Load() error {
+	if s.File == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read generation history: %w", err)
+	}
+	var attempts []Attempt
+	expired := false
+	cutoff := s.cutoff(time.Now())
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var attempt Attempt
+		if err := json.Unmarshal(scanner.Bytes(), &attempt); err != nil {
+			// A line cut short by a crash is skipped rather than losing the history.
+			continue
+		}
+		if attempt.StartedAt.Before(cutoff) {
+			expired = true
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read generation history: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = attempts
+	if expired {
+		return s.rewrite()
+	}
+	return nil
+}
+
+// rewrite replaces the file with the attempts in memory; the caller holds the lock.
+func (s *Store) rewrite() error {
+	var buf bytes.Buffer
+	for _, attempt := range s.attempts {
+		line, err := json.Marshal(attempt)
+		if err != nil {
+			return fmt.Errorf("failed to marshal generation attempt: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(s.File, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write generation history: %w", err)
+	}
+	return nil
+}
+
+// Record adds an attempt to the history.
+func (s *Store) Record(attempt Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, attempt)
+	cutoff := s.cutoff(time.Now())
+	expired := 0
+	for expired < len(s.attempts) && s.attempts[expired].StartedAt.Before(cutoff) {
+		expired++
+	}
+	s.attempts = s.attempts[expired:]
+	if s.File == "" {
+		return nil
+	}
+	line, err := json.Marshal(attempt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal generation attempt: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.File), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(s.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open generation history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write generation history: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) cutoff(now time.Time) time.Time {
+	if s.Retention <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-s.Retention)
+}
+
+// Report aggregates the attempts started in [since, until), in buckets of interval, which is
+// one of Intervals.
+func (s *Store) Report(since time.Time, until time.Time, interval string) (GenerationReport, error) {
+	size, ok := Intervals[interval]
+	if !ok {
+		return GenerationReport{}, fmt.Errorf("%w, got %q", ErrInvalidInterval, interval)
+	}
+	since = since.UTC().Truncate(size)
+	report := GenerationReport{Since: since, Until: until.UTC(), Interval: interval, ByModel: map[string]GenerationStats{}, ByTemplateVersion: map[string]GenerationStats{}, ByVariant: map[string]GenerationStats{}}
+	total := &aggregate{}
+	byModel := map[string]*aggregate{}
+	byVersion := map[string]*aggregate{}
+	byVariant := map[string]*aggregate{}
+	buckets := map[time.Time]*aggregate{}
+	group := func(groups map[string]*aggregate, key string) *aggregate {
+		if groups[key] == nil {
+			groups[key] = &aggregate{}
+		}
+		return groups[key]
+	}
+
+	s.mu.Lock()
+	for _, attempt := range s.attempts {
+		if attempt.StartedAt.Before(since) || !attempt.StartedAt.Before(until) {
+			continue
+		}
+		total.add(attempt)
+		group(byModel, attempt.Model).add(attempt)
+		group(byVersion, attempt.TemplateVersion).add(attempt)
+		if attempt.Variant != "" {
+			group(byVariant, attempt.Variant).add(attempt)
+		}
+		start := attempt.StartedAt.UTC().Truncate(size)
+		if buckets[start] == nil {
+			buckets[start] = &aggregate{}
+		}
+		buckets[start].add(attempt)
+	}
+	s.mu.Unlock()
+
+	report.Total = total.stats()
+	for model, a := range byModel {
+		report.ByModel[model] = a.stats()
+	}
+	for version, a := range byVersion {
+		report.ByTemplateVersion[version] = a.stats()
+	}
+	for variant, a := range byVariant {
+		report.ByVariant[variant] = a.stats()
+	}
+	for start, a := range buckets {
+		report.Buckets = append(report.Buckets, GenerationBucket{Start: start, Stats: a.stats()})
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool { return report.Buckets[i].Start.Before(report.Buckets[j].Start) })
+	return report, nil
+}
+
+// aggregate adds up attempts into GenerationStats.
+type aggregate struct {
+	GenerationStats
+	durationMs float64
+	healthyMs  float64
+}
+
+func (a *aggregate) add(attempt Attempt) {
+	a.Attempts++
+	switch attempt.Outcome {
+	case OutcomeSuccess:
+		a.Successes++
+		a.healthyMs += attempt.DurationMs
+	case OutcomeFailure:
+		a.Failures++
+		if attempt.FailureClass != "" {
+			if a.FailureClasses == nil {
+				a.FailureClasses = map[string]int{}
+			}
+			a.FailureClasses[attempt.FailureClass]++
+		}
+	default:
+		a.Cancelled++
+	}
+	a.TotalTokens += attempt.TotalTokens
+	a.durationMs += attempt.DurationMs
+}
+
+func (a *aggregate) stats() GenerationStats {
+	stats := a.GenerationStats
+	if decided := stats.Successes + stats.Failures; decided > 0 {
+		stats.SuccessRate = float64(stats.Successes) / float64(decided)
+	}
+	if stats.Attempts > 0 {
+		stats.AvgDurationMs = a.durationMs / float64(stats.Attempts)
+	}
+	if stats.Successes > 0 {
+		stats.AvgTimeToHealthyMs = a.healthyMs / float64(stats.Successes)
+	}
+	return stats
+}
@@ -0,0 +1,172 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	SourceHandler = "handler"
+	SourceForm    = "form"
+)
+
+// Route is an endpoint exposed by a generated program, relative to its runtime prefix.
+type Route struct {
+	// Method is empty when the handler accepts any method.
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path"`
+	// Fields are the names of the form inputs submitted to this route.
+	Fields []string `json:"fields,omitempty"`
+	Source string   `json:"source"`
+}
+
+// AnalyzeRoutes discovers the routes of a generated program from its handler registrations
+// and the HTML forms embedded in its string literals. prefix is stripped from form actions.
+func AnalyzeRoutes(code string, prefix string) ([]Route, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.AllErrors)
+	if err != nil {
+		return nil, err
+	}
+	var routes []Route
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			if route, ok := handlerRoute(node); ok {
+				routes = append(routes, route)
+			}
+		case *ast.BasicLit:
+			if node.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(node.Value)
+			if err == nil && strings.Contains(value, "<form") {
+				routes = append(routes, formRoutes(value, prefix)...)
+			}
+		}
+		return true
+	})
+	return mergeRoutes(routes), nil
+}
+
+// handlerRoute extracts the route of a HandleFunc/Handle call with a literal pattern.
+func handlerRoute(call *ast.CallExpr) (Route, bool) {
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+	if !isSel || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") || len(call.Args) == 0 {
+		return Route{}, false
+	}
+	lit, isLit := call.Args[0].(*ast.BasicLit)
+	if !isLit || lit.Kind != token.STRING {
+		return Route{}, false
+	}
+	pattern, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return Route{}, false
+	}
+	route := Route{Path: pattern, Source: SourceHandler}
+	// Go 1.22 patterns may be prefixed with a method, e.g. "POST /items".
+	if fields := strings.Fields(pattern); len(fields) == 2 {
+		route.Method = strings.ToUpper(fields[0])
+		route.Path = fields[1]
+	}
+	return route, true
+}
+
+// formRoutes returns a route for every form in an HTML fragment.
+func formRoutes(fragment string, prefix string) []Route {
+	var routes []Route
+	var current *Route
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			if current != nil {
+				routes = append(routes, *current)
+			}
+			return routes
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := tokenizer.Token()
+			switch tok.Data {
+			case "form":
+				route := Route{Method: "GET", Path: "/", Source: SourceForm}
+				for _, attr := range tok.Attr {
+					switch attr.Key {
+					case "action":
+						route.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(attr.Val, prefix), "/")
+					case "method":
+						route.Method = strings.ToUpper(attr.Val)
+					}
+				}
+				current = &route
+			case "input", "select", "textarea":
+				if current == nil {
+					continue
+				}
+				for _, attr := range tok.Attr {
+					if attr.Key == "name" && attr.Val != "" {
+						current.Fields = append(current.Fields, attr.Val)
+					}
+				}
+			}
+		case html.EndTagToken:
+			if tok := tokenizer.Token(); tok.Data == "form" && current != nil {
+				routes = append(routes, *current)
+				current = nil
+			}
+		}
+	}
+}
+
+// mergeRoutes folds form fields into the matching handler routes and removes duplicates.
+func mergeRoutes(routes []Route) []Route {
+	byKey := map[string]*Route{}
+	var order []string
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if existing, ok := byKey[key]; ok {
+			existing.Fields = appendUnique(existing.Fields, route.Fields...)
+			continue
+		}
+		r := route
+		byKey[key] = &r
+		order = append(order, key)
+	}
+	for _, key := range order {
+		route := byKey[key]
+		if route.Source == SourceForm {
+			// a form posting to a method-less handler is served by that handler
+			if handler, ok := byKey[" "+route.Path]; ok {
+				handler.Fields = appendUnique(handler.Fields, route.Fields...)
+			}
+		}
+	}
+	var merged []Route
+	for _, key := range order {
+		merged = append(merged, *byKey[key])
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Path < merged[j].Path })
+	return merged
+}
+
+func appendUnique(values []string, more ...string) []string {
+	for _, v := range more {
+		found := false
+		for _, existing := range values {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,234 @@
+// Package audit keeps a log of every request sent to a language model and its response, for
+// debugging generations and for compliance. Content is redacted and truncated before it is
+// written.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// redacted replaces the text matched by a redaction pattern.
+const redacted = "[REDACTED]"
+
+// Entry is one request to a language model. PromptHash covers every message of the request,
+// while Prompt is only its last message.
+type Entry struct {
+	Time             time.Time `json:"time"`
+	RuntimeID        string    `json:"runtimeID,omitempty"`
+	Model            string    `json:"model"`
+	PromptHash       string    `json:"promptHash"`
+	LatencyMs        float64   `json:"latencyMs"`
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	FinishReason     string    `json:"finishReason,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	Prompt           string    `json:"prompt,omitempty"`
+	Response         string    `json:"response,omitempty"`
+}
+
+// Store appends entries to File and keeps them for Retention. Content longer than MaxContent
+// bytes is truncated; a zero MaxContent leaves content out of the log.
+type Store struct {
+	File       string
+	Retention  time.Duration
+	MaxContent int
+
+	redact []*regexp.Regexp
+	mu     sync.Mutex
+}
+
+// NewStore returns a store that replaces the matches of the redact patterns in the content it
+// logs.
+func NewStore(file string, retention time.Duration, maxContent int, redact []string) (*Store, error) {
+	s := &Store{File: file, Retention: retention, MaxContent: maxContent}
+	for _, pattern := range redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile redaction pattern %q: %w", pattern, err)
+		}
+		s.redact = append(s.redact, re)
+	}
+	return s, nil
+}
+
+// pruneInterval is how often Run drops expired entries, at most.
+const pruneInterval = time.Hour
+
+// Run drops the entries past the retention from the file until ctx is done, so the log does
+// not grow without bound on a node that is never restarted.
+func (s *Store) Run(ctx context.Context) {
+	if s.Retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(s.Retention, pruneInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Load(); err != nil {
+			log.Printf("failed to prune LLM audit log: %v", err)
+		}
+	}
+}
+
+// Load drops the entries past the retention from the file.
+func (s *Store) Load() error {
+	if s.Retention <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := time.Now().Add(-s.Retention)
+	var kept bytes.Buffer
+	expired := false
+	err := s.scan(func(line []byte, entry Entry) {
+		if entry.Time.Before(cutoff) {
+			expired = true
+			return
+		}
+		kept.Write(line)
+		kept.WriteByte('\n')
+	})
+	if err != nil || !expired {
+		return err
+	}
+	if err := os.WriteFile(s.File, kept.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// RecordExchange appends an exchange to the log. Failures are logged rather than failing the
+// request.
+func (s *Store) RecordExchange(exchange util.LLMExchange) {
+	hash := sha256.New()
+	for _, message := range exchange.Messages {
+		hash.Write([]byte(message.Role + "\x00" + message.Content + "\x00"))
+	}
+	entry := Entry{
+		Time:             exchange.Start,
+		RuntimeID:        exchange.RuntimeID,
+		Model:            exchange.Model,
+		PromptHash:       hex.EncodeToString(hash.Sum(nil)),
+		LatencyMs:        float64(exchange.Duration) / float64(time.Millisecond),
+		PromptTokens:     exchange.Usage.PromptTokens,
+		CompletionTokens: exchange.Usage.CompletionTokens,
+		FinishReason:     exchange.FinishReason,
+		Response:         s.content(exchange.Response),
+	}
+	if len(exchange.Messages) > 0 {
+		entry.Prompt = s.content(exchange.Messages[len(exchange.Messages)-1].Content)
+	}
+	if exchange.Err != nil {
+		entry.Error = s.redactText(exchange.Err.Error())
+	}
+	if err := s.append(entry); err != nil {
+		log.Printf("failed to record LLM exchange: %v", err)
+	}
+}
+
+func (s *Store) append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.File), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(s.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// content redacts text and truncates it to MaxContent bytes.
+func (s *Store) content(text string) string {
+	if s.MaxContent <= 0 {
+		return ""
+	}
+	text = s.redactText(text)
+	if len(text) > s.MaxContent {
+		cut := s.MaxContent
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "…[truncated " + strconv.Itoa(len(text)-cut) + " bytes]"
+	}
+	return text
+}
+
+func (s *Store) redactText(text string) string {
+	for _, re := range s.redact {
+		text = re.ReplaceAllString(text, redacted)
+	}
+	return text
+}
+
+// Runtime returns the latest limit entries of the runtime, oldest first.
+func (s *Store) Runtime(runtimeID string, limit int) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := []Entry{}
+	err := s.scan(func(_ []byte, entry Entry) {
+		if entry.RuntimeID != runtimeID {
+			return
+		}
+		entries = append(entries, entry)
+		if len(entries) > limit {
+			entries = entries[1:]
+		}
+	})
+	return entries, err
+}
+
+// scan calls fn with every entry of the file; the caller holds the lock.
+func (s *Store) scan(fn func(line []byte, entry Entry)) error {
+	f, err := os.Open(s.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash is skipped rather than failing the whole log.
+			continue
+		}
+		fn(scanner.Bytes(), entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
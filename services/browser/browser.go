@@ -0,0 +1,34 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// CheckTimeout bounds a whole browser check: loading the page, the screenshot and the form.
+const CheckTimeout = 30 * time.Second
+
+// ErrUnavailable is returned by NewChecker in builds without headless browser support.
+var ErrUnavailable = errors.New("aegisx was built without headless browser support; rebuild with -tags chromedp")
+
+// Result is what a headless browser saw on a runtime's landing page.
+type Result struct {
+	// ConsoleErrors holds uncaught exceptions and console.error calls.
+	ConsoleErrors []string
+	// FormFound reports whether the page has a form; FormSubmitted whether filling it with
+	// sample values and submitting it got a response below 400. FormError explains why not.
+	FormFound     bool
+	FormSubmitted bool
+	FormError     string
+	// Screenshot is a PNG of the page as first rendered.
+	Screenshot []byte
+}
+
+// Checker loads pages in a headless browser. Check runs the smoke test; Capture only takes a
+// PNG screenshot of the page.
+type Checker interface {
+	Check(ctx context.Context, url string) (*Result, error)
+	Capture(ctx context.Context, url string) ([]byte, error)
+	Close()
+}
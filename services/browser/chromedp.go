@@ -0,0 +1,188 @@
+//go:build chromedp
+
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// settleDelay gives the page's scripts time to run before the screenshot.
+const settleDelay = time.Second
+
+// submitWait is how long the responses to a form submission are collected.
+const submitWait = 3 * time.Second
+
+// fillFormScript fills the first form of the page with sample values and reports whether
+// there is one.
+const fillFormScript = `(() => {
+	const form = document.querySelector('form');
+	if (!form) return false;
+	const samples = {
+		email: 'smoke@example.com', url: 'https://example.com', tel: '5555555555',
+		number: '1', range: '1', date: '2024-01-01', time: '12:00',
+		'datetime-local': '2024-01-01T12:00', month: '2024-01', week: '2024-W01',
+		color: '#336699', password: 'Smoke-test-1',
+	};
+	for (const el of form.querySelectorAll('input, textarea, select')) {
+		if (el.disabled || ['hidden', 'submit', 'button', 'reset', 'file', 'image'].includes(el.type)) continue;
+		if (el.tagName === 'SELECT') {
+			if (el.options.length > 1 && !el.value) el.selectedIndex = 1;
+			continue;
+		}
+		if (el.type === 'checkbox' || el.type === 'radio') {
+			el.checked = true;
+			continue;
+		}
+		if (el.value) continue;
+		el.value = samples[el.type] || (el.min ? el.min : 'aegisx smoke test');
+		el.dispatchEvent(new Event('input', { bubbles: true }));
+	}
+	return true;
+})()`
+
+// submitFormScript submits the first form the way a click on its submit button would.
+const submitFormScript = `(() => {
+	const form = document.querySelector('form');
+	if (form.requestSubmit) form.requestSubmit(); else form.submit();
+	return true;
+})()`
+
+type chromeChecker struct {
+	allocator context.Context
+	cancel    context.CancelFunc
+}
+
+// NewChecker starts a headless Chrome allocator; execPath selects the browser binary and is
+// looked up on PATH when empty.
+func NewChecker(execPath string) (Checker, error) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.WindowSize(1280, 800))
+	if execPath != "" {
+		opts = append(opts, chromedp.ExecPath(execPath))
+	}
+	allocator, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	return &chromeChecker{allocator: allocator, cancel: cancel}, nil
+}
+
+func (c *chromeChecker) Close() {
+	c.cancel()
+}
+
+// Capture loads url in a new tab and takes a screenshot once its scripts have run.
+func (c *chromeChecker) Capture(ctx context.Context, url string) ([]byte, error) {
+	tab, cancelTab := chromedp.NewContext(c.allocator)
+	defer cancelTab()
+	tab, cancelTimeout := context.WithTimeout(tab, CheckTimeout)
+	defer cancelTimeout()
+	defer context.AfterFunc(ctx, cancelTab)()
+
+	var screenshot []byte
+	if err := chromedp.Run(tab,
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(settleDelay),
+		chromedp.CaptureScreenshot(&screenshot),
+	); err != nil {
+		return nil, fmt.Errorf("failed to capture %s: %w", url, err)
+	}
+	return screenshot, nil
+}
+
+// Check loads url in a new tab, records console errors, takes a screenshot and submits the
+// page's first form.
+func (c *chromeChecker) Check(ctx context.Context, url string) (*Result, error) {
+	tab, cancelTab := chromedp.NewContext(c.allocator)
+	defer cancelTab()
+	tab, cancelTimeout := context.WithTimeout(tab, CheckTimeout)
+	defer cancelTimeout()
+	defer context.AfterFunc(ctx, cancelTab)()
+
+	result := &Result{}
+	var mu sync.Mutex
+	var consoleErrors []string
+	submitting := false
+	submitRequests := map[network.RequestID]bool{}
+	var statuses []int64
+	chromedp.ListenTarget(tab, func(ev any) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev := ev.(type) {
+		case *runtime.EventExceptionThrown:
+			text := ev.ExceptionDetails.Text
+			if ev.ExceptionDetails.Exception != nil && ev.ExceptionDetails.Exception.Description != "" {
+				text = ev.ExceptionDetails.Exception.Description
+			}
+			consoleErrors = append(consoleErrors, text)
+		case *runtime.EventConsoleAPICalled:
+			if ev.Type != runtime.APITypeError {
+				return
+			}
+			var args []string
+			for _, arg := range ev.Args {
+				if len(arg.Value) > 0 {
+					args = append(args, strings.Trim(string(arg.Value), `"`))
+				} else {
+					args = append(args, arg.Description)
+				}
+			}
+			consoleErrors = append(consoleErrors, strings.Join(args, " "))
+		case *network.EventRequestWillBeSent:
+			if submitting && (ev.Type == network.ResourceTypeDocument || ev.Type == network.ResourceTypeXHR || ev.Type == network.ResourceTypeFetch) {
+				submitRequests[ev.RequestID] = true
+			}
+		case *network.EventResponseReceived:
+			if submitRequests[ev.RequestID] {
+				statuses = append(statuses, ev.Response.Status)
+			}
+		}
+	})
+
+	if err := chromedp.Run(tab,
+		network.Enable(),
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(settleDelay),
+		chromedp.CaptureScreenshot(&result.Screenshot),
+		chromedp.Evaluate(fillFormScript, &result.FormFound),
+	); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", url, err)
+	}
+	if result.FormFound {
+		mu.Lock()
+		submitting = true
+		mu.Unlock()
+		if err := chromedp.Run(tab, chromedp.Evaluate(submitFormScript, nil), chromedp.Sleep(submitWait)); err != nil {
+			// Submitting a form navigates away, which may interrupt the evaluation.
+			if tab.Err() != nil {
+				return nil, fmt.Errorf("failed to submit form: %w", err)
+			}
+		}
+	}
+
+	// The listener keeps running until the tab is closed, so copy what it collected.
+	mu.Lock()
+	defer mu.Unlock()
+	result.ConsoleErrors = append([]string(nil), consoleErrors...)
+	if !result.FormFound {
+		return result, nil
+	}
+	if len(statuses) == 0 {
+		result.FormError = "submitting the form sent no request"
+		return result, nil
+	}
+	for _, status := range statuses {
+		if status >= 400 {
+			result.FormError = fmt.Sprintf("submitting the form returned %d", status)
+			return result, nil
+		}
+	}
+	result.FormSubmitted = true
+	return result, nil
+}
@@ -0,0 +1,8 @@
+//go:build !chromedp
+
+package browser
+
+// NewChecker returns ErrUnavailable; headless browser support needs the chromedp build tag.
+func NewChecker(execPath string) (Checker, error) {
+	return nil, ErrUnavailable
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a previously successful generation that can be reused for an identical prompt.
+type Entry struct {
+	Key       string            `json:"key"`
+	Prompt    string            `json:"prompt"`
+	Model     string            `json:"model"`
+	RuntimeID string            `json:"runtimeID"`
+	Title     string            `json:"title,omitempty"`
+	Code      string            `json:"code"`
+	Assets    map[string]string `json:"assets,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Hits      int               `json:"hits"`
+}
+
+// GenerationCache stores successful generations as one JSON file per key, content-addressed
+// by the normalized prompt, the prompt template version and the model.
+type GenerationCache struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NormalizePrompt folds case, whitespace and trailing punctuation so trivially different
+// prompts share a cache entry.
+func NormalizePrompt(prompt string) string {
+	prompt = strings.Join(strings.Fields(strings.ToLower(prompt)), " ")
+	return strings.TrimRight(prompt, ".!?")
+}
+
+// Key returns the cache key of a prompt. Generations are only shared within a tenant.
+// generation holds the model's other inputs, such as the system prompt and the requirements
+// added to the prompt, so changing one of them misses programs generated without it.
+func Key(prompt string, templateVersion string, model string, tenant string, generation ...string) string {
+	input := NormalizePrompt(prompt) + "\x00" + templateVersion + "\x00" + model
+	if tenant != "" {
+		input += "\x00" + tenant
+	}
+	for _, part := range generation {
+		input += "\x00" + part
+	}
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *GenerationCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Get returns the entry for key and counts the hit.
+func (c *GenerationCache) Get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	entry := new(Entry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, false
+	}
+	entry.Hits++
+	if data, err := json.Marshal(entry); err == nil {
+		_ = os.WriteFile(c.path(key), data, 0o644)
+	}
+	return entry, true
+}
+
+func (c *GenerationCache) Put(entry *Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(c.path(entry.Key), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete drops an entry, e.g. when its code no longer starts.
+func (c *GenerationCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/traefik/yaegi/interp"
+	// Registers the pure Go "sqlite" driver with database/sql so interpreted programs can use it.
+	_ "modernc.org/sqlite"
+)
+
+// ImportPath is the package generated programs import to reach their database.
+const ImportPath = "aegisx/db"
+
+// Driver is the database/sql driver name of the provisioned databases.
+const Driver = "sqlite"
+
+// SQLiteService provisions one SQLite database file per runtime under Dir.
+type SQLiteService struct {
+	Dir string
+}
+
+// Path returns the database file of a runtime.
+func (s *SQLiteService) Path(runtimeID string) string {
+	return filepath.Join(s.Dir, runtimeID+".db")
+}
+
+// Open opens the runtime's database, creating the file on first use.
+func (s *SQLiteService) Open(runtimeID string) (*sql.DB, error) {
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	db, err := sql.Open(Driver, s.Path(runtimeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// Exists reports whether the runtime's database has been created.
+func (s *SQLiteService) Exists(runtimeID string) bool {
+	_, err := os.Stat(s.Path(runtimeID))
+	return err == nil
+}
+
+// Backup writes a consistent copy of the runtime's database to dest. It is safe to call while
+// the runtime holds the database open.
+func (s *SQLiteService) Backup(runtimeID string, dest string) error {
+	db, err := s.Open(runtimeID)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec("VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the runtime's database with the file at src. The runtime must not have the
+// database open.
+func (s *SQLiteService) Restore(runtimeID string, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read database backup: %w", err)
+	}
+	if err := s.Remove(runtimeID); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(s.Path(runtimeID), data, 0o644); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the runtime's database file.
+func (s *SQLiteService) Remove(runtimeID string) error {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if err := os.Remove(s.Path(runtimeID) + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove database: %w", err)
+		}
+	}
+	return nil
+}
+
+// Exports returns the interpreter symbols for the aegisx/db package, bound to runtimeID.
+func (s *SQLiteService) Exports(runtimeID string) interp.Exports {
+	return interp.Exports{
+		ImportPath + "/db": {
+			"Open": reflect.ValueOf(func() (*sql.DB, error) {
+				return s.Open(runtimeID)
+			}),
+			"Path": reflect.ValueOf(func() string {
+				return s.Path(runtimeID)
+			}),
+		},
+	}
+}
@@ -0,0 +1,177 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// drainPollInterval is how often a drain checks whether the executions in flight finished.
+const drainPollInterval = time.Second
+
+// ErrExecutionsPaused is returned for execute requests while an operator paused new executions.
+var ErrExecutionsPaused = errors.New("new executions are paused")
+
+// ErrOverloaded is returned for execute requests while the watchdog sheds load.
+var ErrOverloaded = errors.New("the node is overloaded, retry later")
+
+// overloadPollInterval is how often executions queued during load shedding check whether it
+// ended.
+const overloadPollInterval = time.Second
+
+// ResourceUsage is the aggregate state of the node reported by the admin API.
+type ResourceUsage struct {
+	Paused             bool           `json:"paused"`
+	Draining           bool           `json:"draining"`
+	Overloaded         bool           `json:"overloaded"`
+	ExecutionsInFlight int            `json:"executionsInFlight"`
+	QueuedExecutions   int            `json:"queuedExecutions"`
+	ActiveRuntimes     int            `json:"activeRuntimes"`
+	RuntimesByState    map[string]int `json:"runtimesByState"`
+	AllocatedPorts     int            `json:"allocatedPorts"`
+	Goroutines         int            `json:"goroutines"`
+	HeapBytes          uint64         `json:"heapBytes"`
+	SysBytes           uint64         `json:"sysBytes"`
+}
+
+// PauseExecutions rejects new executions until ResumeExecutions. Executions in flight and the
+// running runtimes are not affected.
+func (s *ExecuterService) PauseExecutions() {
+	log.Println("New executions paused")
+	s.paused.Store(true)
+}
+
+// ResumeExecutions accepts new executions again, ending a pause or a drain.
+func (s *ExecuterService) ResumeExecutions() {
+	log.Println("New executions resumed")
+	s.draining.Store(false)
+	s.paused.Store(false)
+}
+
+// ExecutionsPaused reports whether new executions are rejected.
+func (s *ExecuterService) ExecutionsPaused() bool {
+	return s.paused.Load()
+}
+
+// SetOverloaded starts or ends load shedding: while the node is overloaded new executions are
+// rejected and queued ones wait.
+func (s *ExecuterService) SetOverloaded(overloaded bool) {
+	if s.overloaded.Swap(overloaded) == overloaded {
+		return
+	}
+	if overloaded {
+		log.Println("Node overloaded, shedding load")
+	} else {
+		log.Println("Node load recovered, accepting executions")
+	}
+}
+
+// Overloaded reports whether the watchdog is shedding load.
+func (s *ExecuterService) Overloaded() bool {
+	return s.overloaded.Load()
+}
+
+// awaitCapacity blocks while the node is overloaded, pausing the executions already queued.
+func (s *ExecuterService) awaitCapacity(ctx context.Context) error {
+	if !s.Overloaded() {
+		return nil
+	}
+	ticker := time.NewTicker(overloadPollInterval)
+	defer ticker.Stop()
+	for s.Overloaded() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the node's load to recover: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Draining reports whether the node is being drained; the registry heartbeat advertises it so
+// control nodes send executions elsewhere.
+func (s *ExecuterService) Draining() bool {
+	return s.draining.Load()
+}
+
+// Drain pauses new executions and, once the executions in flight have finished, stops every
+// runtime of the node so it can be taken down. It returns without waiting; ResumeExecutions
+// cancels a drain that has not stopped the runtimes yet.
+func (s *ExecuterService) Drain() {
+	s.PauseExecutions()
+	if !s.draining.CompareAndSwap(false, true) {
+		return
+	}
+	log.Println("Draining node")
+	util.Go("drain", "", func() {
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+		for s.inFlight.Load() > 0 {
+			<-ticker.C
+		}
+		if !s.draining.Load() {
+			log.Println("Drain canceled")
+			return
+		}
+		stopped := s.StopAllRuntimes(context.Background())
+		log.Printf("Drained node: stopped %d runtimes", len(stopped))
+	})
+}
+
+// StopAllRuntimes starts a graceful stop of every active runtime and returns their IDs.
+func (s *ExecuterService) StopAllRuntimes(ctx context.Context) []string {
+	var active []string
+	s.Runtimes.Range(func(runtime *models.Runtime) bool {
+		if runtime.GetState().Active() {
+			active = append(active, runtime.ID)
+		}
+		return true
+	})
+	stopped := []string{}
+	for _, runtimeID := range active {
+		if err := s.StopRuntime(ctx, runtimeID); err != nil {
+			log.Printf("Failed to stop runtime %s: %v", runtimeID, err)
+			continue
+		}
+		stopped = append(stopped, runtimeID)
+	}
+	return stopped
+}
+
+// EvictRuntime deletes a runtime like DeleteRuntime, even if it is pinned.
+func (s *ExecuterService) EvictRuntime(ctx context.Context, runtimeID string) error {
+	log.Printf("Evicting runtime %s", runtimeID)
+	return s.deleteRuntime(ctx, runtimeID, true)
+}
+
+// ResourceUsage returns the node's executions, runtimes and process resources.
+func (s *ExecuterService) ResourceUsage() ResourceUsage {
+	usage := ResourceUsage{
+		Paused:             s.ExecutionsPaused(),
+		Draining:           s.Draining(),
+		Overloaded:         s.Overloaded(),
+		ExecutionsInFlight: int(s.inFlight.Load()),
+		QueuedExecutions:   s.QueueLength(),
+		RuntimesByState:    map[string]int{},
+		AllocatedPorts:     s.PortAllocator.Allocated(),
+		Goroutines:         runtime.NumGoroutine(),
+	}
+	s.Runtimes.Range(func(runtime *models.Runtime) bool {
+		state := runtime.GetState()
+		usage.RuntimesByState[string(state)]++
+		if state.Active() {
+			usage.ActiveRuntimes++
+		}
+		return true
+	})
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	usage.HeapBytes, usage.SysBytes = mem.HeapAlloc, mem.Sys
+	return usage
+}
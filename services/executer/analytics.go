@@ -0,0 +1,39 @@
+package executer
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/services/analytics"
+	"github.com/gcottom/aegisx/util"
+)
+
+// recordAttempt adds a generation attempt that started at start and ended with err to the
+// history. ctx is the attempt's context: an attempt failing after it was cancelled, because
+// another attempt won or the request ended, is recorded as cancelled rather than failed.
+func (s *ExecuterService) recordAttempt(ctx context.Context, attempt analytics.Attempt, start time.Time, counter *util.UsageCounter, err error) {
+	if s.History == nil {
+		return
+	}
+	attempt.TemplateVersion = PromptTemplateVersion
+	attempt.StartedAt = start
+	attempt.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	usage := counter.Usage()
+	attempt.PromptTokens, attempt.CompletionTokens, attempt.TotalTokens = usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens
+	switch {
+	case err == nil:
+		attempt.Outcome = analytics.OutcomeSuccess
+	case ctx.Err() != nil:
+		attempt.Outcome = analytics.OutcomeCancelled
+	default:
+		attempt.Outcome = analytics.OutcomeFailure
+		attempt.Error = err.Error()
+		if runtime, ok := s.Runtimes.Load(attempt.RuntimeID); ok && attempt.RuntimeID != "" {
+			attempt.FailureClass = runtime.Snapshot().FailureClass
+		}
+	}
+	if err := s.History.Record(attempt); err != nil {
+		log.Printf("failed to record generation attempt: %v", err)
+	}
+}
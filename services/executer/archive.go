@@ -0,0 +1,79 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// ErrRuntimePinned is returned when an operation would remove a pinned runtime.
+var ErrRuntimePinned = errors.New("runtime is pinned")
+
+// SetPinned pins or unpins a runtime. Pinned runtimes are listed first and cannot be archived
+// or deleted until they are unpinned.
+func (s *ExecuterService) SetPinned(ctx context.Context, runtimeID string, pinned bool) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) { info.Pinned = pinned })
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+	return nil
+}
+
+// ArchiveRuntime stops a runtime and hides it from default listings. Its code, versions and
+// data are kept so UnarchiveRuntime can bring it back.
+func (s *ExecuterService) ArchiveRuntime(ctx context.Context, runtimeID string) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	info := runtimeData.Snapshot()
+	if info.Pinned {
+		return fmt.Errorf("%w: unpin runtime %s before archiving it", ErrRuntimePinned, runtimeID)
+	}
+	if info.Archived {
+		return nil
+	}
+	if info.State.Active() && info.State != models.RSSTOPPING {
+		if err := s.StopRuntime(ctx, runtimeID); err != nil {
+			return err
+		}
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.Archived = true
+		info.ArchivedAt = time.Now()
+	})
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+	log.Printf("Archived runtime %s", runtimeID)
+	return nil
+}
+
+// UnarchiveRuntime lists an archived runtime again and restarts its program.
+func (s *ExecuterService) UnarchiveRuntime(ctx context.Context, runtimeID string) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	info := runtimeData.Snapshot()
+	if !info.Archived {
+		return nil
+	}
+	if info.State.Active() {
+		return fmt.Errorf("%w: runtime %s is still %s", ErrRuntimeActive, runtimeID, info.State)
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.Archived = false
+		info.ArchivedAt = time.Time{}
+	})
+	log.Printf("Unarchived runtime %s", runtimeID)
+	return s.StartRuntime(ctx, runtimeID)
+}
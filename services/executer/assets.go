@@ -0,0 +1,65 @@
+package executer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// StaticDir returns the directory the runtime's static assets are served from.
+func (s *ExecuterService) StaticDir(runtimeID string) string {
+	return filepath.Join(s.Config.StaticStore, runtimeID)
+}
+
+// WriteAssets replaces the runtime's static directory with the assets found in a model
+// response and returns their names.
+func (s *ExecuterService) WriteAssets(runtimeID string, response string) ([]string, error) {
+	_, assets := util.ExtractGeneration(response)
+	return s.replaceAssets(runtimeID, assets)
+}
+
+// replaceAssets replaces the runtime's static directory with the given files.
+func (s *ExecuterService) replaceAssets(runtimeID string, assets map[string]string) ([]string, error) {
+	if err := os.RemoveAll(s.StaticDir(runtimeID)); err != nil {
+		return nil, fmt.Errorf("failed to clear static directory: %w", err)
+	}
+	return s.updateAssets(runtimeID, nil, assets)
+}
+
+// updateAssets writes the given files over the runtime's named static assets, keeping those
+// the files do not replace, and returns the names of them all.
+func (s *ExecuterService) updateAssets(runtimeID string, names []string, assets map[string]string) ([]string, error) {
+	dir := s.StaticDir(runtimeID)
+	names = slices.Clone(names)
+	for name, content := range assets {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create static directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write asset %s: %w", name, err)
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readAssets returns the contents of the runtime's named static assets.
+func (s *ExecuterService) readAssets(runtimeID string, names []string) (map[string]string, error) {
+	assets := make(map[string]string, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.StaticDir(runtimeID), filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset %s: %w", name, err)
+		}
+		assets[name] = string(data)
+	}
+	return assets, nil
+}
@@ -0,0 +1,200 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+	"github.com/traefik/yaegi/interp"
+)
+
+// LanguageGo is the language of programs run by the embedded interpreter, the default. Other
+// languages run in the Backend registered for them.
+const LanguageGo = "go"
+
+var (
+	ErrUnknownLanguage = errors.New("unknown language")
+	// ErrUnsupportedLanguage is returned for options that programs in another language than Go
+	// cannot honour.
+	ErrUnsupportedLanguage = errors.New("unsupported by the language")
+)
+
+// Backend generates, checks and runs the programs of a language other than Go. Runtimes of
+// every language share the rest of the executer: the proxy, health checks, kinds and the
+// retry and rebuild logic.
+type Backend interface {
+	// Name is what prompts call the language, e.g. Python.
+	Name() string
+	// Prompt wraps the user prompt in the generation rules of the language for spec, followed
+	// by extra requirements.
+	Prompt(prompt string, spec ProgramSpec, extraRequirements ...string) string
+	// RebuildRequirements are the REQUIREMENTS of the rebuild prompt for spec.
+	RebuildRequirements(spec ProgramSpec) string
+	// Extract returns the program and static assets of a model response.
+	Extract(response string) (program string, files map[string]string)
+	// Rules are the checks of the language for spec, run on the program's text after the
+	// validator's rules that apply to every language.
+	Rules(spec ProgramSpec) []code.Rule
+	// Program returns what runs a runtime's code with dir as its working directory and the
+	// variables of env, writing what it prints to output.
+	Program(dir string, env map[string]string, output *util.LogWriter) models.Program
+}
+
+// ProgramSpec describes the program of a runtime to its backend.
+type ProgramSpec struct {
+	Kind   string
+	Prefix string
+	// Port is the port of a program that listens, 0 otherwise.
+	Port int
+	// Heartbeat is how often a worker prints its heartbeat.
+	Heartbeat time.Duration
+	// Structured is set when the model replies with a list of files (structured_output).
+	Structured bool
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{}
+)
+
+// RegisterBackend makes backend run the programs of language, selected through
+// ExecutionOptions.Language.
+func RegisterBackend(language string, backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[language] = backend
+}
+
+func lookupBackend(language string) (Backend, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	backend, ok := backends[language]
+	return backend, ok
+}
+
+// validateLanguage rejects languages without a backend. Scaffolds are Go programs.
+func (o ExecutionOptions) validateLanguage() error {
+	language := o.runtimeLanguage()
+	if language == "" {
+		return nil
+	}
+	if _, ok := lookupBackend(language); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLanguage, language)
+	}
+	if o.Source == SourceScaffold {
+		return fmt.Errorf("%w: scaffolds are Go programs", ErrUnsupportedLanguage)
+	}
+	return nil
+}
+
+// runtimeLanguage is the language recorded on a runtime of opts, empty for Go.
+func (o ExecutionOptions) runtimeLanguage() string {
+	if o.Language == LanguageGo {
+		return ""
+	}
+	return o.Language
+}
+
+// checkLanguage rejects the options of opts that the interpreter enforces for Go programs but
+// a backend cannot: outbound connections are only policed inside the interpreter.
+func (s *ExecuterService) checkLanguage(opts ExecutionOptions) error {
+	if opts.runtimeLanguage() == "" {
+		return nil
+	}
+	if opts.NoOutbound || s.Config.Egress.Restricted() {
+		return fmt.Errorf("%w: %s programs cannot be held to an egress policy", ErrUnsupportedLanguage, opts.runtimeLanguage())
+	}
+	return nil
+}
+
+// programSpec describes the program of runtime id of kind in its tenant, listening on port.
+func (s *ExecuterService) programSpec(tenant string, id string, kind string, port int) ProgramSpec {
+	return ProgramSpec{
+		Kind:       kind,
+		Prefix:     models.RuntimePrefix(tenant, id),
+		Port:       port,
+		Heartbeat:  s.heartbeatInterval(),
+		Structured: s.Config.StructuredOutput,
+	}
+}
+
+// extractProgram returns the program and static assets of a model response for a program in
+// language.
+func extractProgram(language string, response string) (string, map[string]string) {
+	if backend, ok := lookupBackend(language); ok {
+		return backend.Extract(response)
+	}
+	return util.ExtractGeneration(response)
+}
+
+// programDir is the working directory of a runtime's program in another language than Go.
+func (s *ExecuterService) programDir(runtimeID string) string {
+	return filepath.Join(s.Config.ExecuterStore, "programs", runtimeID)
+}
+
+// newProgram creates what runs the runtime's code: its backend's program, or an interpreter
+// for a Go program. A runtime whose language is no longer configured fails when it runs.
+func (s *ExecuterService) newProgram(info models.RuntimeInfo) (models.Program, *util.LogWriter) {
+	if info.Language == "" {
+		interpreter, output := s.newInterpreter(info)
+		return interpreterProgram{interpreter}, output
+	}
+	output := new(util.LogWriter)
+	backend, ok := lookupBackend(info.Language)
+	if !ok {
+		return missingBackend(info.Language), output
+	}
+	return backend.Program(s.programDir(info.ID), info.Env, output), output
+}
+
+// interpreterProgram runs a Go program in its interpreter.
+type interpreterProgram struct {
+	interpreter *interp.Interpreter
+}
+
+func (p interpreterProgram) Run(ctx context.Context, code string) error {
+	_, err := p.interpreter.EvalWithContext(ctx, util.GuardGoroutines(code))
+	return err
+}
+
+// Shutdown calls the program's Shutdown function. Evaluating "Shutdown()" instead would run
+// main again first, as the interpreter runs main after every evaluation in package main.
+func (p interpreterProgram) Shutdown(ctx context.Context) error {
+	shutdown, ok := p.interpreter.Symbols("main")["main"]["Shutdown"].Interface().(func())
+	if !ok {
+		return errors.New("the program has no Shutdown() function")
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic during Shutdown(): %v", r)
+			}
+		}()
+		shutdown()
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// missingBackend is the program of a runtime whose language has no backend.
+type missingBackend string
+
+func (m missingBackend) Run(ctx context.Context, code string) error {
+	return fmt.Errorf("%w: %s", ErrUnknownLanguage, string(m))
+}
+
+func (m missingBackend) Shutdown(ctx context.Context) error {
+	return nil
+}
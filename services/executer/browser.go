@@ -0,0 +1,109 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/browser"
+)
+
+// ScreenshotPath is where the screenshot of the runtime's landing page is stored.
+func (s *ExecuterService) ScreenshotPath(runtimeID string) string {
+	return filepath.Join(s.Config.ScreenshotStore, runtimeID+".png")
+}
+
+// ThumbnailPath is where the thumbnail of the runtime's landing page is stored.
+func (s *ExecuterService) ThumbnailPath(runtimeID string) string {
+	return filepath.Join(s.Config.ScreenshotStore, runtimeID+".thumb.png")
+}
+
+// browserSmokeTest loads the runtime's landing page through the proxy in a headless browser,
+// unless its current code version was already checked. It stores the screenshot and returns
+// the report and whether the page is free of JavaScript errors and its form submits. A
+// browser that cannot be driven skips the check rather than failing the runtime.
+func (s *ExecuterService) browserSmokeTest(ctx context.Context, runtimeData *models.Runtime) (*models.BrowserReport, bool) {
+	info := runtimeData.Snapshot()
+	if previous := info.Browser; previous != nil && previous.Version == info.Version {
+		return previous, previous.Passed
+	}
+	report := &models.BrowserReport{Version: info.Version}
+	defer func() { report.CheckedAt = time.Now() }()
+
+	url := fmt.Sprintf("http://localhost:%d%s/", s.Config.Port, models.RuntimePrefix(info.Tenant, info.ID))
+	result, err := s.Browser.Check(ctx, url)
+	if err != nil {
+		log.Printf("Skipping browser smoke test of runtime %s: %v", info.ID, err)
+		report.Passed, report.Skipped = true, err.Error()
+		return report, true
+	}
+	report.ConsoleErrors = result.ConsoleErrors
+	report.FormFound = result.FormFound
+	report.FormSubmitted = result.FormSubmitted
+	report.FormError = result.FormError
+	report.Passed = len(result.ConsoleErrors) == 0 && (!result.FormFound || result.FormSubmitted)
+	if len(result.Screenshot) > 0 {
+		if err := s.saveImage(s.ScreenshotPath(info.ID), result.Screenshot); err != nil {
+			log.Printf("failed to save screenshot of runtime %s: %v", info.ID, err)
+		} else {
+			report.Screenshot = true
+		}
+	}
+	return report, report.Passed
+}
+
+func (s *ExecuterService) saveImage(path string, png []byte) error {
+	if err := os.MkdirAll(s.Config.ScreenshotStore, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, png, 0o644)
+}
+
+// captureThumbnail stores a thumbnail of the runtime's landing page, unless one was already
+// captured for its current code version. It scales down the smoke test's screenshot of the
+// version when there is one and otherwise loads the page through the proxy. A thumbnail that
+// cannot be captured is logged and leaves the previous one, if any, in place.
+func (s *ExecuterService) captureThumbnail(ctx context.Context, runtimeData *models.Runtime) *models.Thumbnail {
+	info := runtimeData.Snapshot()
+	if previous := info.Thumbnail; previous != nil && previous.Version == info.Version {
+		return previous
+	}
+	var screenshot []byte
+	if report := info.Browser; report != nil && report.Version == info.Version && report.Screenshot {
+		screenshot, _ = os.ReadFile(s.ScreenshotPath(info.ID))
+	}
+	if len(screenshot) == 0 {
+		var err error
+		url := fmt.Sprintf("http://localhost:%d%s/", s.Config.Port, models.RuntimePrefix(info.Tenant, info.ID))
+		if screenshot, err = s.Browser.Capture(ctx, url); err != nil {
+			log.Printf("failed to capture thumbnail of runtime %s: %v", info.ID, err)
+			return info.Thumbnail
+		}
+	}
+	thumbnail, err := browser.Thumbnail(screenshot, s.Config.ThumbnailWidth)
+	if err == nil {
+		err = s.saveImage(s.ThumbnailPath(info.ID), thumbnail)
+	}
+	if err != nil {
+		log.Printf("failed to save thumbnail of runtime %s: %v", info.ID, err)
+		return info.Thumbnail
+	}
+	return &models.Thumbnail{Version: info.Version, CapturedAt: time.Now()}
+}
+
+// browserFailure describes a failed browser smoke test for the rebuild prompt.
+func browserFailure(report *models.BrowserReport) string {
+	var problems []string
+	for _, consoleError := range report.ConsoleErrors {
+		problems = append(problems, "- JavaScript error: "+consoleError)
+	}
+	if report.FormError != "" {
+		problems = append(problems, "- "+report.FormError)
+	}
+	return "browser smoke test failed:\n" + strings.Join(problems, "\n")
+}
@@ -0,0 +1,206 @@
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// maxOutlineSections caps the sections of a chunked generation, and so its number of calls.
+const maxOutlineSections = 8
+
+// programOutline is the reply the outline prompt asks for.
+type programOutline struct {
+	Sections []outlineSection `json:"sections"`
+}
+
+type outlineSection struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	EstimatedLines int    `json:"estimatedLines"`
+}
+
+func (o programOutline) estimatedLines() int {
+	lines := 0
+	for _, section := range o.Sections {
+		lines += section.EstimatedLines
+	}
+	return lines
+}
+
+// CreateOutlinePrompt asks for the plan of the program a generation prompt describes, split
+// into sections that can be written one call at a time.
+func CreateOutlinePrompt(prompt string, maxSections int) string {
+	log.Println("Creating outline prompt")
+	return prompt + `
+
+🧭 Do NOT write the program yet. First plan it as at most ` + strconv.Itoa(maxSections) + ` sections that will each be written separately, in order, and concatenated into one file.
+- The first section holds the shared types, constants and global state; the last one holds main().
+- Give each section a short name, a description of the functions it contains and an estimate of its number of lines.
+
+Reply with JSON only, in this shape:
+{"sections":[{"name":"...","description":"...","estimatedLines":0}]}
+`
+}
+
+// CreateSectionPrompt asks for one section of an outlined program, given the code of the
+// sections written before it.
+func CreateSectionPrompt(prompt string, outline programOutline, index int, previous string) string {
+	log.Printf("Creating prompt for section %d of %d", index+1, len(outline.Sections))
+	var plan strings.Builder
+	for i, section := range outline.Sections {
+		fmt.Fprintf(&plan, "%d. %s: %s\n", i+1, section.Name, section.Description)
+	}
+	if previous == "" {
+		previous = "(none yet)"
+	}
+	section := outline.Sections[index]
+	return prompt + `
+
+🧩 The program is too large to write in one reply, so it is written in sections that are concatenated into one file.
+
+📝 OUTLINE:
+` + plan.String() + `
+📝 CODE OF THE EARLIER SECTIONS:
+` + previous + `
+
+Write ONLY section ` + strconv.Itoa(index+1) + `, "` + section.Name + `": ` + section.Description + `
+- Start with "package main" and an import block listing only the packages this section uses.
+- Do NOT repeat any declaration from the earlier sections; use them as they are.
+- Later sections will provide what the outline assigns to them.
+`
+}
+
+// generateCode asks client for the program of a generation prompt and returns its code and
+// static assets. When chunked_generation_lines is set, the model first outlines the program
+// and one estimated at that many lines or more is generated section by section and
+// assembled, so it does not exceed the completion token limit. A failed outline falls back to
+// generating the program in one call.
+func (s *ExecuterService) generateCode(ctx context.Context, client util.LLMClient, prompt string, language string) (string, map[string]string, error) {
+	// Sections are stitched together as Go declarations.
+	if minLines := s.Config.ChunkedGenerationLines; minLines > 0 && language == "" {
+		outline, err := s.outlineProgram(ctx, client, prompt)
+		switch {
+		case err != nil:
+			log.Printf("Generating program in one call: %v", err)
+		case outline.estimatedLines() >= minLines:
+			log.Printf("Generating program of about %d lines in %d sections", outline.estimatedLines(), len(outline.Sections))
+			return s.generateSections(ctx, client, prompt, outline)
+		}
+	}
+	response, err := client.SendMessage(ctx, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	program, files := extractProgram(language, response)
+	return program, files, nil
+}
+
+func (s *ExecuterService) outlineProgram(ctx context.Context, client util.LLMClient, prompt string) (programOutline, error) {
+	response, err := client.SendMessage(ctx, CreateOutlinePrompt(prompt, maxOutlineSections))
+	if err != nil {
+		return programOutline{}, fmt.Errorf("failed to get outline: %w", err)
+	}
+	var outline programOutline
+	if err := json.Unmarshal([]byte(jsonReply(response)), &outline); err != nil {
+		return programOutline{}, fmt.Errorf("failed to parse outline: %w", err)
+	}
+	if len(outline.Sections) == 0 {
+		return programOutline{}, errors.New("outline has no sections")
+	}
+	if len(outline.Sections) > maxOutlineSections {
+		outline.Sections = outline.Sections[:maxOutlineSections]
+	}
+	return outline, nil
+}
+
+// jsonReply returns the JSON object of a model reply that was asked for JSON only, without the
+// prose or fences the model may have put around it.
+func jsonReply(response string) string {
+	// Clients with structured output wrap the reply in a file.
+	if files, err := util.ParseGeneratedFiles(response); err == nil {
+		response = files.Files[0].Content
+	}
+	response = strings.TrimSpace(response)
+	if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+	return response
+}
+
+func (s *ExecuterService) generateSections(ctx context.Context, client util.LLMClient, prompt string, outline programOutline) (string, map[string]string, error) {
+	var sections []string
+	assets := map[string]string{}
+	program := ""
+	for i := range outline.Sections {
+		response, err := client.SendMessage(ctx, CreateSectionPrompt(prompt, outline, i, program))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate section %d: %w", i+1, err)
+		}
+		section, files := util.ExtractGeneration(response)
+		for name, content := range files {
+			assets[name] = content
+		}
+		sections = append(sections, section)
+		if program, err = assembleSections(sections); err != nil {
+			return "", nil, fmt.Errorf("failed to assemble section %d: %w", i+1, err)
+		}
+	}
+	return program, assets, nil
+}
+
+// assembleSections joins separately generated parts of a program into one file, merging their
+// package clauses and import blocks.
+func assembleSections(sections []string) (string, error) {
+	var imports []string
+	seen := map[string]bool{}
+	var bodies []string
+	for i, section := range sections {
+		if !strings.HasPrefix(strings.TrimSpace(section), "package ") {
+			section = "package main\n\n" + section
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", section, parser.ImportsOnly)
+		if err != nil {
+			return "", fmt.Errorf("section %d: %w", i+1, err)
+		}
+		bodyStart := fset.Position(file.Name.End()).Offset
+		for _, decl := range file.Decls {
+			bodyStart = fset.Position(decl.End()).Offset
+		}
+		for _, spec := range file.Imports {
+			line := spec.Path.Value
+			if spec.Name != nil {
+				line = spec.Name.Name + " " + line
+			}
+			if !seen[line] {
+				seen[line] = true
+				imports = append(imports, line)
+			}
+		}
+		bodies = append(bodies, strings.TrimSpace(section[bodyStart:]))
+	}
+	var program strings.Builder
+	program.WriteString("package main\n")
+	if len(imports) > 0 {
+		program.WriteString("\nimport (\n\t" + strings.Join(imports, "\n\t") + "\n)\n")
+	}
+	for _, body := range bodies {
+		if body != "" {
+			program.WriteString("\n" + body + "\n")
+		}
+	}
+	if formatted, err := format.Source([]byte(program.String())); err == nil {
+		return string(formatted), nil
+	}
+	return program.String(), nil
+}
@@ -0,0 +1,173 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+)
+
+var (
+	portConstRegex = regexp.MustCompile(`(` + code.PortConstName + `\s*=\s*)\d+`)
+	portLogRegex   = regexp.MustCompile(`PORT=\d+`)
+)
+
+// rewriteRuntimeReferences points code generated for one runtime at another runtime's
+// prefix and port.
+func rewriteRuntimeReferences(source string, fromID string, toID string, port int) string {
+	source = strings.ReplaceAll(source, "/runtime/"+fromID, "/runtime/"+toID)
+	source = portConstRegex.ReplaceAllString(source, "${1}"+strconv.Itoa(port))
+	return portLogRegex.ReplaceAllString(source, "PORT="+strconv.Itoa(port))
+}
+
+func CreateModifyPrompt(prompt string, modification string, generatedCode string, port int) string {
+	return createModifyPrompt(prompt, modification, generatedCode, "Go", `- The program must compile and run as provided.
+- Use http.NewServeMux and listen on the assigned port: const `+code.PortConstName+` = `+strconv.Itoa(port)+`.
+- Ensure '`+util.ControlLine(util.ControlPort, strconv.Itoa(port))+`' and '`+util.ControlLine(util.ControlReady, "")+`' are printed before the server starts serving.
+- Return the complete modified Go program.
+`)
+}
+
+// createModifyPrompt is CreateModifyPrompt for a program in language with its requirements.
+func createModifyPrompt(prompt string, modification string, generatedCode string, language string, requirements string) string {
+	log.Println("Creating modify prompt for modification: ", modification)
+	return `You are a ` + language + ` expert.
+The following program was generated based on a user prompt and works. 
+Modify it according to the requested change while adhering to the original prompt and best practices. 
+
+🛠️ REQUESTED CHANGE:
+` + modification + `
+
+📝 ORIGINAL CODE:
+` + generatedCode + `
+
+📝 ORIGINAL PROMPT:
+` + prompt + `
+
+✅ REQUIREMENTS:
+` + requirements
+}
+
+// CloneRuntime forks a runtime into a new one with a fresh ID and port, copying its code,
+// static assets and application data. A non-empty modification is applied to the clone's
+// code before it starts; the source runtime keeps running untouched.
+func (s *ExecuterService) CloneRuntime(ctx context.Context, runtimeID string, modification string) (string, error) {
+	runtime, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return "", err
+	}
+	source := runtime.Snapshot()
+	if modification != "" {
+		if err := s.Moderation.Screen(ctx, modification); err != nil {
+			return "", err
+		}
+	}
+	id := s.IDGenerator.NewID()
+	port, err := s.allocatePort(id, source.Kind)
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate port: %w", err)
+	}
+	// Until the clone is created, a failure removes everything copied to it.
+	discard := func(err error) (string, error) {
+		s.PortAllocator.Release(id)
+		if cleanupErr := s.DeleteRuntimeData(id); cleanupErr != nil {
+			log.Printf("⚠️ Failed to remove the data of failed clone %s: %v", id, cleanupErr)
+		}
+		return "", err
+	}
+	prompt := rewriteRuntimeReferences(source.Prompt, runtimeID, id, port)
+	clonedCode := rewriteRuntimeReferences(source.Code, runtimeID, id, port)
+	assets, err := s.copyAssets(source.ID, id, source.Assets, port)
+	if err != nil {
+		return discard(err)
+	}
+	if err := s.copyRuntimeData(source.ID, id); err != nil {
+		return discard(err)
+	}
+
+	if modification != "" {
+		modifyPrompt := CreateModifyPrompt(prompt, modification, clonedCode, port)
+		if backend, ok := lookupBackend(source.Language); ok {
+			modifyPrompt = createModifyPrompt(prompt, modification, clonedCode, backend.Name(), backend.RebuildRequirements(s.programSpec(source.Tenant, id, source.Kind, port)))
+		}
+		response, err := s.llm(source.Model).SendMessage(util.WithRuntimeID(util.WithGenerationParams(ctx, source.GenerationParams), id), modifyPrompt)
+		if err != nil {
+			return discard(fmt.Errorf("failed to get code from GPT: %w", err))
+		}
+		var files map[string]string
+		clonedCode, files = extractProgram(source.Language, response)
+		// Keep the copied assets unless the model replaced them.
+		if len(files) > 0 {
+			if assets, err = s.replaceAssets(id, files); err != nil {
+				return discard(err)
+			}
+		}
+	}
+	if err := s.resolveDependencies(id, clonedCode); err != nil {
+		return discard(err)
+	}
+
+	log.Printf("Cloning runtime %s into %s", runtimeID, id)
+	reason := "cloned from " + runtimeID
+	if modification != "" {
+		reason += ": " + modification
+	}
+	if _, err := s.createRuntime(ctx, id, prompt, clonedCode, assets, port, VersionClone, reason, ExecutionOptions{Strategy: source.FailureStrategy, Model: source.Model, Tenant: source.Tenant, PromptVariant: source.PromptVariant, Params: source.GenerationParams, NoOutbound: source.NoOutbound, Kind: source.Kind, Language: source.Language, Env: source.Env}); err != nil {
+		return "", err
+	}
+	if clone, err := s.GetRuntime(ctx, id); err == nil && source.Title != "" {
+		clone.Update(func(info *models.RuntimeInfo) { info.Title = source.Title + " (clone)" })
+	}
+	if err := s.ExecuteRuntime(ctx, id); err != nil {
+		return "", fmt.Errorf("failed to execute runtime: %w", err)
+	}
+	return id, nil
+}
+
+// copyAssets copies a runtime's static assets to another runtime, rewriting runtime references.
+func (s *ExecuterService) copyAssets(fromID string, toID string, names []string, port int) ([]string, error) {
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.StaticDir(fromID), filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset %s: %w", name, err)
+		}
+		path := filepath.Join(s.StaticDir(toID), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create static directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(rewriteRuntimeReferences(string(data), fromID, toID, port)), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write asset %s: %w", name, err)
+		}
+	}
+	return append([]string(nil), names...), nil
+}
+
+// copyRuntimeData copies a runtime's key-value data and SQLite database to another runtime.
+func (s *ExecuterService) copyRuntimeData(fromID string, toID string) error {
+	if s.KV != nil {
+		data, err := s.KV.Export(fromID)
+		if err != nil {
+			return err
+		}
+		if err := s.KV.Import(toID, data); err != nil {
+			return err
+		}
+	}
+	if s.SQLite != nil && s.SQLite.Exists(fromID) {
+		if err := os.MkdirAll(s.SQLite.Dir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := s.SQLite.Backup(fromID, s.SQLite.Path(toID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// Roles of the runtimes of a composite app.
+const (
+	// RoleFrontend is the web app of a composite app, the one its users open.
+	RoleFrontend = "frontend"
+	// RoleBackend is a JSON API the frontend calls.
+	RoleBackend = "backend"
+)
+
+// maxComposedBackends caps the backends of a composite app.
+const maxComposedBackends = 4
+
+// MaxGroupSize is the most runtimes a composite app starts: its frontend and up to
+// maxComposedBackends backends.
+const MaxGroupSize = maxComposedBackends + 1
+
+// maxBackendNameLength caps the names of backends, which name their environment variables.
+const maxBackendNameLength = 32
+
+// maxComposedDocSize caps the OpenAPI document of a backend quoted in the frontend's prompt.
+const maxComposedDocSize = 8 << 10
+
+var (
+	// ErrInvalidComposition is returned for composition requests that cannot be built.
+	ErrInvalidComposition = errors.New("invalid composition")
+	ErrGroupNotFound      = errors.New("group not found")
+)
+
+var backendNameRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ComposedBackend is a backend of a composite app, an API runtime generated from Prompt. Its
+// URL reaches the frontend in the environment variable <NAME>_API_URL.
+type ComposedBackend struct {
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+}
+
+// appPlan is the reply the composition prompt asks for.
+type appPlan struct {
+	Frontend string            `json:"frontend"`
+	Backends []ComposedBackend `json:"backends"`
+}
+
+// Group is a composite app: a frontend runtime and the backend runtimes it calls.
+type Group struct {
+	ID string `json:"id"`
+	// State sums up the states of the members: failed when one failed for good, running when
+	// all run, stopped when none is active, and otherwise the state of the first member that
+	// is not running.
+	State   models.RuntimeState `json:"state"`
+	Members []GroupRuntime      `json:"members"`
+}
+
+// GroupRuntime is a member of a group.
+type GroupRuntime struct {
+	RuntimeID string              `json:"runtimeID"`
+	Role      string              `json:"role"`
+	Name      string              `json:"name,omitempty"`
+	EnvVar    string              `json:"envVar,omitempty"`
+	State     models.RuntimeState `json:"state"`
+	Prefix    string              `json:"prefix"`
+}
+
+// CreateCompositionPrompt asks for the split of an app prompt into a web frontend and the JSON
+// APIs it calls, each generated as a runtime of its own.
+func CreateCompositionPrompt(prompt string, maxBackends int) string {
+	log.Println("Creating composition prompt")
+	return prompt + `
+
+🧭 Do NOT write any code. The app above will be built as a web frontend and at most ` + strconv.Itoa(maxBackends) + ` backend JSON APIs, each a separate program that keeps its own data.
+- Give each backend a short lower case name, such as todos or users, and a prompt describing the API it serves: its resources, endpoints and the data it stores.
+- Give the frontend a prompt describing the pages and how they use the backends. It keeps no data of its own.
+- Use a single backend unless the app has clearly separate parts.
+
+Reply with JSON only, in this shape:
+{"frontend":"...","backends":[{"name":"...","prompt":"..."}]}
+`
+}
+
+// ComposeApp generates a composite app from prompt and returns its group. The model splits
+// the prompt into a frontend and up to maxComposedBackends backends, unless backends are
+// given, in which case prompt describes the frontend. The backends are generated first as API
+// runtimes; the web frontend then gets their URLs in its environment, as <NAME>_API_URL, and
+// their OpenAPI documents in its prompt. opts apply to every member. When a member cannot be
+// generated, the members generated before it are deleted.
+func (s *ExecuterService) ComposeApp(ctx context.Context, prompt string, backends []ComposedBackend, opts ExecutionOptions) (*Group, error) {
+	if !opts.generated() || opts.Kind != "" || len(opts.Env) > 0 {
+		return nil, fmt.Errorf("%w: members are generated by the model with their own kind and environment", ErrInvalidComposition)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if s.ExecutionsPaused() {
+		return nil, ErrExecutionsPaused
+	}
+	// The prompt reaches the model in the plan before any member is generated.
+	if err := s.Moderation.Screen(ctx, prompt); err != nil {
+		return nil, err
+	}
+	plan := appPlan{Frontend: prompt, Backends: backends}
+	if len(backends) == 0 {
+		var err error
+		if plan, err = s.planApp(ctx, prompt, opts); err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.Backends) == 0 || len(plan.Backends) > maxComposedBackends {
+		return nil, fmt.Errorf("%w: a composite app has 1 to %d backends, got %d", ErrInvalidComposition, maxComposedBackends, len(plan.Backends))
+	}
+	for i, backend := range plan.Backends {
+		if strings.TrimSpace(backend.Prompt) == "" {
+			return nil, fmt.Errorf("%w: backend %d has no prompt", ErrInvalidComposition, i+1)
+		}
+	}
+	groupID := s.IDGenerator.NewID()
+	members := composedMembers(groupID, plan.Backends)
+	log.Printf("Composing app %s from %d backends", groupID, len(members))
+
+	runtimeIDs := make([]string, len(members))
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, backend := range plan.Backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			backendOpts := opts
+			backendOpts.Kind = KindAPI
+			runtimeIDs[i], errs[i] = s.NewConcurrentExecution(ctx, backend.Prompt, backendOpts)
+		}()
+	}
+	wg.Wait()
+	for i, runtimeID := range runtimeIDs {
+		if runtimeID != "" {
+			s.joinGroup(ctx, runtimeID, members[i])
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		s.deleteMembers(ctx, runtimeIDs)
+		return nil, fmt.Errorf("failed to generate the backends of app %s: %w", groupID, err)
+	}
+
+	frontendOpts := opts
+	frontendOpts.Env = map[string]string{}
+	for i, member := range members {
+		frontendOpts.Env[member.EnvVar] = models.RuntimePrefix(opts.Tenant, runtimeIDs[i])
+	}
+	frontendID, err := s.NewConcurrentExecution(ctx, s.frontendPrompt(plan, members, runtimeIDs), frontendOpts)
+	if err != nil {
+		s.deleteMembers(ctx, runtimeIDs)
+		return nil, fmt.Errorf("failed to generate the frontend of app %s: %w", groupID, err)
+	}
+	s.joinGroup(ctx, frontendID, models.GroupMember{ID: groupID, Role: RoleFrontend})
+	return s.GetGroup(opts.Tenant, groupID)
+}
+
+// planApp asks the model to split an app prompt into a frontend and its backends.
+func (s *ExecuterService) planApp(ctx context.Context, prompt string, opts ExecutionOptions) (appPlan, error) {
+	response, err := s.llm(opts.Model).SendMessage(util.WithGenerationParams(ctx, opts.Params), CreateCompositionPrompt(prompt, maxComposedBackends))
+	if err != nil {
+		return appPlan{}, fmt.Errorf("failed to get composition plan: %w", err)
+	}
+	var plan appPlan
+	if err := json.Unmarshal([]byte(jsonReply(response)), &plan); err != nil {
+		return appPlan{}, fmt.Errorf("failed to parse composition plan: %w", err)
+	}
+	if strings.TrimSpace(plan.Frontend) == "" {
+		plan.Frontend = prompt
+	}
+	return plan, nil
+}
+
+// composedMembers returns the group memberships of the backends, named after a normalized,
+// unique form of their names.
+func composedMembers(groupID string, backends []ComposedBackend) []models.GroupMember {
+	members := make([]models.GroupMember, len(backends))
+	seen := map[string]bool{}
+	for i, backend := range backends {
+		name := strings.Trim(backendNameRegex.ReplaceAllString(strings.ToLower(backend.Name), "_"), "_")
+		name = strings.TrimRight(name[:min(len(name), maxBackendNameLength)], "_")
+		if name == "" || name[0] >= '0' && name[0] <= '9' {
+			name = "api" + strconv.Itoa(i+1)
+		}
+		for base, n := name, 2; seen[name]; n++ {
+			name = base + strconv.Itoa(n)
+		}
+		seen[name] = true
+		members[i] = models.GroupMember{ID: groupID, Role: RoleBackend, Name: name, EnvVar: strings.ToUpper(name) + "_API_URL"}
+	}
+	return members
+}
+
+// frontendPrompt is the prompt of a composite app's frontend, describing the backends it calls
+// with their OpenAPI documents.
+func (s *ExecuterService) frontendPrompt(plan appPlan, members []models.GroupMember, runtimeIDs []string) string {
+	var b strings.Builder
+	b.WriteString(plan.Frontend)
+	b.WriteString(`
+
+🔗 Backends:
+The app is the frontend of a composite app. It keeps no data of its own and calls these JSON APIs, served on the same host, from the browser with fetch(). The environment variable of each holds its base URL: pass it to the page and build request URLs as <base URL> + <path>, e.g. base + "/items". These calls are the only exception to the prefix rule.
+`)
+	for i, member := range members {
+		fmt.Fprintf(&b, "\n- %s: %s\n", member.EnvVar, plan.Backends[i].Prompt)
+		doc, err := os.ReadFile(filepath.Join(s.StaticDir(runtimeIDs[i]), OpenAPIDocName))
+		if err != nil || len(doc) > maxComposedDocSize {
+			continue
+		}
+		b.WriteString("OpenAPI document:\n```json\n" + strings.TrimSpace(string(doc)) + "\n```\n")
+	}
+	return b.String()
+}
+
+// joinGroup records a runtime's membership of a group.
+func (s *ExecuterService) joinGroup(ctx context.Context, runtimeID string, member models.GroupMember) {
+	runtime, ok := s.Runtimes.Load(runtimeID)
+	if !ok {
+		return
+	}
+	runtime.Update(func(info *models.RuntimeInfo) { info.Group = &member })
+	if err := s.SaveExecuter(ctx, runtime); err != nil {
+		log.Printf("⚠️ Failed to save group membership of runtime %s: %v", runtimeID, err)
+	}
+}
+
+// deleteMembers deletes the runtimes generated for a composite app that failed.
+func (s *ExecuterService) deleteMembers(ctx context.Context, runtimeIDs []string) {
+	for _, runtimeID := range runtimeIDs {
+		if runtimeID == "" {
+			continue
+		}
+		if err := s.DeleteRuntime(ctx, runtimeID); err != nil {
+			log.Printf("⚠️ Failed to delete runtime %s of a failed composition: %v", runtimeID, err)
+		}
+	}
+}
+
+// GetGroup returns the tenant's group with its members, the frontend first and then the
+// backends by name.
+func (s *ExecuterService) GetGroup(tenant string, groupID string) (*Group, error) {
+	group := &Group{ID: groupID, Members: []GroupRuntime{}}
+	for _, runtime := range s.TenantRuntimes(tenant) {
+		if runtime.Group == nil || runtime.Group.ID != groupID {
+			continue
+		}
+		group.Members = append(group.Members, GroupRuntime{
+			RuntimeID: runtime.ID,
+			Role:      runtime.Group.Role,
+			Name:      runtime.Group.Name,
+			EnvVar:    runtime.Group.EnvVar,
+			State:     runtime.State,
+			Prefix:    models.RuntimePrefix(runtime.Tenant, runtime.ID),
+		})
+	}
+	if len(group.Members) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, groupID)
+	}
+	sort.Slice(group.Members, func(i, j int) bool {
+		a, b := group.Members[i], group.Members[j]
+		if a.Role != b.Role {
+			return a.Role == RoleFrontend
+		}
+		return a.Name < b.Name
+	})
+	group.State = groupState(group.Members)
+	return group, nil
+}
+
+func groupState(members []GroupRuntime) models.RuntimeState {
+	state, active := models.RSRUN, false
+	for _, member := range members {
+		if member.State == "failed" {
+			return member.State
+		}
+		active = active || member.State.Active()
+		if state == models.RSRUN && member.State != models.RSRUN {
+			state = member.State
+		}
+	}
+	if !active {
+		return models.RSSTOP
+	}
+	return state
+}
+
+// StopGroup starts a graceful stop of every active member of the tenant's group, as
+// StopRuntime does, and returns the group.
+func (s *ExecuterService) StopGroup(ctx context.Context, tenant string, groupID string) (*Group, error) {
+	group, err := s.GetGroup(tenant, groupID)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, member := range group.Members {
+		if !member.State.Active() || member.State == models.RSSTOPPING {
+			continue
+		}
+		if err := s.StopRuntime(ctx, member.RuntimeID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop runtime %s: %w", member.RuntimeID, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(tenant, groupID)
+}
@@ -0,0 +1,65 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// DeleteRuntime stops a runtime and removes every trace of it: its proxy route, persisted
+// record, code versions, snapshots, screenshot, application data and logs. A running program is given
+// KillGracePeriod to shut down before its data is removed.
+func (s *ExecuterService) DeleteRuntime(ctx context.Context, runtimeID string) error {
+	return s.deleteRuntime(ctx, runtimeID, false)
+}
+
+func (s *ExecuterService) deleteRuntime(ctx context.Context, runtimeID string, evict bool) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	info := runtimeData.Snapshot()
+	if info.Pinned && !evict {
+		return fmt.Errorf("%w: unpin runtime %s before deleting it", ErrRuntimePinned, runtimeID)
+	}
+	log.Printf("Deleting runtime %s", runtimeID)
+	// Mark the runtime killed first so the eval goroutine and failure handler leave it alone.
+	runtimeData.SetState(models.RSKILL)
+	if info.State.Active() && info.Executer != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, KillGracePeriod)
+		if err := info.Executer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Graceful shutdown failed for runtime %s: %v", runtimeID, err)
+		}
+		cancel()
+	}
+	s.stopSupervisor(runtimeID)
+	s.PortAllocator.Release(runtimeID)
+	s.Runtimes.Delete(runtimeID)
+	s.logs.Delete(runtimeID)
+
+	if err := s.DynamicRouteService.RemoveReverseProxy(runtimeID); err != nil {
+		return err
+	}
+	if err := s.DeleteRuntimeData(runtimeID); err != nil {
+		return err
+	}
+	paths := []string{
+		s.recordPath(info.Tenant, runtimeID),
+		s.versionDir(runtimeID),
+		filepath.Join(s.Config.SnapshotStore, runtimeID),
+	}
+	if s.Config.ScreenshotStore != "" {
+		paths = append(paths, s.ScreenshotPath(runtimeID), s.ThumbnailPath(runtimeID))
+	}
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	log.Printf("Deleted runtime %s", runtimeID)
+	return nil
+}
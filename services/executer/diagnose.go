@@ -0,0 +1,115 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// maxFixOptions is the number of patch options a diagnosis asks for.
+const maxFixOptions = 3
+
+var (
+	// ErrNothingToDiagnose is returned when diagnosing a runtime that has no current error.
+	ErrNothingToDiagnose = errors.New("runtime has no error to diagnose")
+	// ErrNoFixOptions is returned when the model's reply contains no usable patch option.
+	ErrNoFixOptions = errors.New("diagnosis proposed no fix")
+)
+
+// fixOptionRegex matches the heading that starts each option of a diagnosis reply.
+var fixOptionRegex = regexp.MustCompile(`(?m)^#*\s*OPTION[^:\n]*:[ \t]*(.*)$`)
+
+// FixOption is a patch proposed for a runtime's current error. Diff is against the running
+// code; Code is the whole patched program, to send to the code update endpoint.
+type FixOption struct {
+	Description string `json:"description"`
+	Diff        string `json:"diff"`
+	Code        string `json:"code"`
+}
+
+// CreateDiagnosePrompt asks for up to maxOptions alternative fixes of a program's error, each
+// as a complete program under an OPTION heading.
+func CreateDiagnosePrompt(prompt string, runtimeError string, generatedCode string, maxOptions int) string {
+	log.Println("Creating diagnose prompt")
+	return `The Go web application below was generated from the user prompt and fails with the error shown.
+Propose up to ` + strconv.Itoa(maxOptions) + ` different ways to fix it, most likely first. Options should differ in approach, not only in wording.
+
+📝 RULES:
+- Start each option with a line "OPTION: <one sentence describing the fix>".
+- Follow it with the COMPLETE fixed program in a single ` + "```go" + ` block, not only the changed lines.
+- Keep everything that is not part of the fix as it is.
+
+📝 PROMPT:
+` + prompt + `
+
+💥 ERROR:
+` + runtimeError + `
+
+📝 CODE:
+` + generatedCode + `
+`
+}
+
+// DiagnoseRuntime asks the diagnosis model for patches of the runtime's current error without
+// applying them. Options that do not change the code are dropped.
+func (s *ExecuterService) DiagnoseRuntime(ctx context.Context, runtimeID string) ([]FixOption, error) {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	if s.DiagnosisClient == nil {
+		return nil, errors.New("no diagnosis model configured")
+	}
+	info := runtimeData.Snapshot()
+	runtimeError := info.LastErrorMsg
+	for _, violation := range info.Diagnostics {
+		runtimeError = strings.TrimSpace(runtimeError + "\n" + violation.String())
+	}
+	if runtimeError == "" {
+		return nil, ErrNothingToDiagnose
+	}
+	if info.FailureClass != "" {
+		runtimeError = info.FailureClass + ": " + runtimeError
+	}
+	runtimeError = s.scrubSecrets(info.Tenant, runtimeError)
+	response, err := s.DiagnosisClient.SendMessage(util.WithRuntimeID(ctx, runtimeID), CreateDiagnosePrompt(userPrompt(info.Prompt), runtimeError, info.Code, maxFixOptions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to diagnose runtime: %w", err)
+	}
+	options := parseFixOptions(response, info.Code)
+	if len(options) == 0 {
+		return nil, ErrNoFixOptions
+	}
+	return options, nil
+}
+
+// parseFixOptions splits a diagnosis reply into its options and diffs each against code.
+func parseFixOptions(response string, code string) []FixOption {
+	headings := fixOptionRegex.FindAllStringSubmatchIndex(response, -1)
+	var options []FixOption
+	for i, heading := range headings {
+		end := len(response)
+		if i+1 < len(headings) {
+			end = headings[i+1][0]
+		}
+		fixed := util.ExtractGoCode(response[heading[1]:end])
+		if fixed == "" || fixed == strings.TrimSpace(code) {
+			continue
+		}
+		options = append(options, FixOption{
+			Description: strings.TrimSpace(response[heading[2]:heading[3]]),
+			Diff:        util.UnifiedDiff(code, fixed, "a/main.go", "b/main.go"),
+			Code:        fixed,
+		})
+		if len(options) == maxFixOptions {
+			break
+		}
+	}
+	return options
+}
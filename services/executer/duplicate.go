@@ -0,0 +1,132 @@
+package executer
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// userPromptMarker separates the generation rules from the user prompt in a runtime's prompt.
+const userPromptMarker = "Implement the above based on the user prompt:\n"
+
+// pendingExecution is a generation in flight that duplicates of its prompt wait for.
+type pendingExecution struct {
+	tenant    string
+	prompt    string
+	done      chan struct{}
+	runtimeID string
+	err       error
+}
+
+// NewDeduplicatedExecution is NewConcurrentExecution guarded against double submissions: unless
+// opts.Force is set, a prompt at least DuplicateSimilarity similar to that of an active runtime
+// of the same tenant returns that runtime instead, and one similar to a generation in flight
+// waits for it. duplicate reports whether an existing runtime was returned. A zero
+// DuplicateSimilarity disables the guard, and jobs, which are submitted to run again, skip it,
+// as do programs in other languages than Go.
+func (s *ExecuterService) NewDeduplicatedExecution(ctx context.Context, prompt string, opts ExecutionOptions) (id string, duplicate bool, err error) {
+	threshold := s.Config.DuplicateSimilarity
+	if threshold <= 0 || opts.Force || !opts.generated() || opts.runtimeKind() != "" || opts.runtimeLanguage() != "" {
+		id, err := s.NewConcurrentExecution(ctx, prompt, opts)
+		return id, false, err
+	}
+	for {
+		s.pendingMu.Lock()
+		// Generations in flight come first: their attempts are active runtimes too, but only
+		// the winner survives. A finished generation is registered before it leaves pending,
+		// so checking both under the lock cannot miss it.
+		var inFlight *pendingExecution
+		for pending := range s.pending {
+			if pending.tenant == opts.Tenant && PromptSimilarity(pending.prompt, prompt) >= threshold {
+				inFlight = pending
+				break
+			}
+		}
+		if inFlight == nil {
+			if id, ok := s.findDuplicate(prompt, opts.Tenant, threshold); ok {
+				s.pendingMu.Unlock()
+				return id, true, nil
+			}
+			break
+		}
+		s.pendingMu.Unlock()
+		select {
+		case <-inFlight.done:
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		}
+		if inFlight.err == nil {
+			return inFlight.runtimeID, true, nil
+		}
+		// The generation we waited for failed; try again, generating the runtime ourselves
+		// unless another duplicate got there first.
+	}
+	pending := &pendingExecution{tenant: opts.Tenant, prompt: prompt, done: make(chan struct{})}
+	if s.pending == nil {
+		s.pending = map[*pendingExecution]struct{}{}
+	}
+	s.pending[pending] = struct{}{}
+	s.pendingMu.Unlock()
+
+	pending.runtimeID, pending.err = s.NewConcurrentExecution(ctx, prompt, opts)
+	s.pendingMu.Lock()
+	delete(s.pending, pending)
+	s.pendingMu.Unlock()
+	close(pending.done)
+	return pending.runtimeID, false, pending.err
+}
+
+// findDuplicate returns the running runtime of the tenant whose user prompt is most similar to
+// prompt, the newest one on a tie, if it is at least threshold similar. Archived and stopping
+// runtimes are not considered, nor are runtimes of other kinds than web apps or in other
+// languages than Go.
+func (s *ExecuterService) findDuplicate(prompt string, tenant string, threshold float64) (string, bool) {
+	var best models.RuntimeInfo
+	bestSimilarity := threshold
+	for _, info := range s.ListRuntimes() {
+		if info.Tenant != tenant || info.Kind != "" || info.Language != "" || info.Archived || !info.State.Active() || info.State == models.RSSTOPPING {
+			continue
+		}
+		similarity := PromptSimilarity(userPrompt(info.Prompt), prompt)
+		if similarity > bestSimilarity || similarity == bestSimilarity && (best.ID == "" || info.CreatedAt.After(best.CreatedAt)) {
+			best, bestSimilarity = info, similarity
+		}
+	}
+	return best.ID, best.ID != ""
+}
+
+// userPrompt returns the user prompt a runtime's prompt was created from.
+func userPrompt(prompt string) string {
+	if i := strings.LastIndex(prompt, userPromptMarker); i >= 0 {
+		return prompt[i+len(userPromptMarker):]
+	}
+	return prompt
+}
+
+// PromptSimilarity returns the Jaccard similarity of the words of two prompts, from 0 for no
+// words in common to 1 for the same words ignoring case, punctuation and order.
+func PromptSimilarity(a string, b string) float64 {
+	wordsA, wordsB := promptWords(a), promptWords(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+	common := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+func promptWords(prompt string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
@@ -0,0 +1,92 @@
+package executer
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+	"github.com/traefik/yaegi/interp"
+)
+
+// egressPolicy is the configured egress policy, denying everything for a runtime created with
+// noOutbound.
+func (s *ExecuterService) egressPolicy(noOutbound bool) util.EgressPolicy {
+	return util.EgressPolicy{
+		NoOutbound: s.Config.Egress.NoOutbound || noOutbound,
+		Allow:      s.Config.Egress.Allow,
+		Deny:       s.Config.Egress.Deny,
+	}
+}
+
+// egressExports binds the standard library's outbound entry points to policy, logging the
+// connections it denies to the runtime's logs, and sends the default HTTP client of the
+// program through the outbound proxy when there is one.
+func (s *ExecuterService) egressExports(policy util.EgressPolicy, runtimeID string) interp.Exports {
+	var wrap func(http.RoundTripper) http.RoundTripper
+	if s.Outbound != nil {
+		wrap = func(next http.RoundTripper) http.RoundTripper { return s.Outbound.Transport(runtimeID, next) }
+	}
+	return util.EgressExports(policy, runtimeID, func(address string) {
+		s.appendLog(runtimeID, "aegisx: outbound connection to "+address+" denied by the egress policy")
+	}, wrap)
+}
+
+// egressRequirement tells the model about the egress policy and the outbound proxy, or is ""
+// when there is neither.
+func (s *ExecuterService) egressRequirement(noOutbound bool) string {
+	policy := s.egressPolicy(noOutbound)
+	switch {
+	case !policy.Restricted() && s.Outbound != nil:
+		return "Make outbound HTTP requests with http.Get, http.Post or http.DefaultClient, so aegisx can cache and rate limit them."
+	case !policy.Restricted():
+		return ""
+	case policy.NoOutbound:
+		return "The app has no outbound network access: do NOT call external APIs or services, do NOT load anything from other hosts and do NOT create HTTP clients, dialers or transports."
+	}
+	requirement := "Outbound network access is restricted"
+	if len(policy.Allow) > 0 {
+		requirement += " to these hosts: " + strings.Join(policy.Allow, ", ")
+	}
+	return requirement + ". Make outbound HTTP requests only with http.Get, http.Post or http.DefaultClient, or an http.Client with Transport: http.DefaultTransport; do NOT create net.Dialer, http.Transport or reverse proxies."
+}
+
+// newValidator builds the code validator for the runtime, holding a runtime created with
+// noOutbound to the egress rule even when no egress policy is configured. Rules that do not
+// apply to the runtime's kind are skipped, and an API runtime's OpenAPI document is checked.
+// Programs in other languages than Go get the rules for any language and those of their
+// backend.
+func (s *ExecuterService) newValidator(info models.RuntimeInfo) (*code.CodeValidator, error) {
+	var validator *code.CodeValidator
+	var err error
+	if backend, ok := lookupBackend(info.Language); ok {
+		if validator, err = code.NewTextValidator(s.Config); err != nil {
+			return nil, err
+		}
+		validator.Rules = append(validator.Rules, backend.Rules(s.programSpec(info.Tenant, info.ID, info.Kind, info.Port))...)
+	} else {
+		if validator, err = code.NewValidator(s.Config, models.RuntimePrefix(info.Tenant, info.ID), info.Port); err != nil {
+			return nil, err
+		}
+		validator.Without(skippedRules(info.Kind)...)
+	}
+	// Only the LLM is asked for the document; other sources may ship one among their assets.
+	if info.Kind == KindAPI && info.Source == "" {
+		validator.Rules = append(validator.Rules, s.openAPIDocRule(info.ID))
+	}
+	if info.NoOutbound && !s.Config.Egress.Restricted() && info.Language == "" {
+		validator.Rules = append(validator.Rules, code.EgressRule())
+	}
+	return validator, nil
+}
+
+// validateEgress runs the egress rule alone, for rebuilt code that skips the full validation.
+// The rule reads Go, and runtimes in other languages are only created without an egress policy.
+func (s *ExecuterService) validateEgress(info models.RuntimeInfo, program string) error {
+	if !s.egressPolicy(info.NoOutbound).Restricted() || info.Language != "" {
+		return nil
+	}
+	validator := &code.CodeValidator{Rules: []code.Rule{code.EgressRule()}}
+	return validator.Validate(program)
+}
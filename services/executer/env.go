@@ -0,0 +1,54 @@
+package executer
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// EnvImportPath is the package Go programs import to read their runtime's environment.
+const EnvImportPath = "aegisx/env"
+
+var envNameRegex = regexp.MustCompile(`^[A-Z_][A-Z0-9_]{0,63}$`)
+
+// validateEnv rejects environment variables that are not upper case identifiers.
+func (o ExecutionOptions) validateEnv() error {
+	for name := range o.Env {
+		if !envNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid environment variable %q: name must be 1-64 upper case letters, digits or '_' and not start with a digit", name)
+		}
+	}
+	return nil
+}
+
+// envExports returns the interpreter symbols for the aegisx/env package, reading env.
+func envExports(env map[string]string) interp.Exports {
+	return interp.Exports{
+		EnvImportPath + "/env": {
+			"Get": reflect.ValueOf(func(name string) string {
+				return env[name]
+			}),
+		},
+	}
+}
+
+// envRequirement is the generation instruction telling the model how a program in language
+// reads the variables of env, or "" when it has none. Only the names reach the model.
+func envRequirement(language string, env map[string]string) string {
+	if len(env) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, `"`+name+`"`)
+	}
+	sort.Strings(names)
+	if language == "" {
+		return `The app's environment holds ` + strings.Join(names, ", ") + `. Read them at run time: import "` + EnvImportPath + `" and call env.Get(name string) string. Do NOT hard-code their values.`
+	}
+	return `The process environment holds the variables ` + strings.Join(names, ", ") + `. Read them at run time and do NOT hard-code their values.`
+}
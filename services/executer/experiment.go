@@ -0,0 +1,36 @@
+package executer
+
+import (
+	"math/rand/v2"
+)
+
+// pickPromptVariant picks the prompt variant of a new execution at random, weighted by the
+// configured weights. It returns "" when no experiment is configured.
+func (s *ExecuterService) pickPromptVariant() string {
+	total := 0
+	for _, variant := range s.Config.PromptVariants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return ""
+	}
+	n := rand.IntN(total)
+	for _, variant := range s.Config.PromptVariants {
+		if n < variant.Weight {
+			return variant.Name
+		}
+		n -= variant.Weight
+	}
+	return ""
+}
+
+// variantInstructions returns the instructions of the named prompt variant, or "" if there is
+// no such variant.
+func (s *ExecuterService) variantInstructions(name string) string {
+	for _, variant := range s.Config.PromptVariants {
+		if variant.Name == name {
+			return variant.Instructions
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/cache"
+)
+
+// PromptTemplateVersion identifies the generation rules in CreatePrompt. Bump it whenever the
+// template changes so cached generations made under older rules are not reused.
+const PromptTemplateVersion = "2"
+
+var errCacheMiss = errors.New("generation cache miss")
+
+// executeCached starts a runtime from a cached generation, rewritten for a fresh ID and port.
+// A cached generation that no longer passes its health check is evicted.
+func (s *ExecuterService) executeCached(ctx context.Context, prompt string, key string, opts ExecutionOptions) (string, error) {
+	entry, ok := s.Cache.Get(key)
+	if !ok {
+		return "", errCacheMiss
+	}
+	id := s.IDGenerator.NewID()
+	port, err := s.allocatePort(id, opts.runtimeKind())
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate port: %w", err)
+	}
+	log.Printf("Reusing cached generation of runtime %s for runtime %s", entry.RuntimeID, id)
+	assets := make(map[string]string, len(entry.Assets))
+	for name, content := range entry.Assets {
+		assets[name] = rewriteRuntimeReferences(content, entry.RuntimeID, id, port)
+	}
+	names, err := s.replaceAssets(id, assets)
+	if err != nil {
+		s.PortAllocator.Release(id)
+		return "", err
+	}
+	generatedCode := rewriteRuntimeReferences(entry.Code, entry.RuntimeID, id, port)
+	if err := s.resolveDependencies(id, generatedCode); err != nil {
+		s.PortAllocator.Release(id)
+		return "", err
+	}
+	fullPrompt := s.createPrompt(prompt, id, port, opts)
+	if _, err := s.createRuntime(ctx, id, fullPrompt, generatedCode, names, port, VersionCache, "cached generation of "+entry.RuntimeID, opts); err != nil {
+		s.evictCached(ctx, key, id)
+		return "", err
+	}
+	if err := s.ExecuteRuntime(ctx, id); err != nil {
+		s.evictCached(ctx, key, id)
+		return "", fmt.Errorf("failed to execute runtime: %w", err)
+	}
+	if err := waitForPassedHealthCheck(ctx, s, id); err != nil {
+		s.evictCached(ctx, key, id)
+		return "", err
+	}
+	runtime, err := s.GetRuntime(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	runtime.Update(func(info *models.RuntimeInfo) { info.Title = entry.Title })
+	return id, nil
+}
+
+// evictCached drops a cache entry whose code failed and kills the runtime started from it so
+// no rebuild runs in the background.
+func (s *ExecuterService) evictCached(ctx context.Context, key string, runtimeID string) {
+	if err := s.Cache.Delete(key); err != nil {
+		log.Printf("failed to evict cached generation: %v", err)
+	}
+	if _, err := s.KillRuntime(ctx, runtimeID, true); err != nil {
+		log.Printf("failed to kill runtime %s started from cache: %v", runtimeID, err)
+	}
+}
+
+// cacheGeneration stores the code of a healthy runtime for reuse by identical prompts.
+func (s *ExecuterService) cacheGeneration(key string, prompt string, runtimeData *models.Runtime) {
+	if s.Cache == nil {
+		return
+	}
+	runtime := runtimeData.Snapshot()
+	assets, err := s.readAssets(runtime.ID, runtime.Assets)
+	if err != nil {
+		log.Printf("failed to cache generation of runtime %s: %v", runtime.ID, err)
+		return
+	}
+	entry := &cache.Entry{
+		Key:       key,
+		Prompt:    prompt,
+		Model:     s.llm(runtime.Model).ModelName(),
+		RuntimeID: runtime.ID,
+		Title:     runtime.Title,
+		Code:      runtime.Code,
+		Assets:    assets,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Cache.Put(entry); err != nil {
+		log.Printf("failed to cache generation of runtime %s: %v", runtime.ID, err)
+	}
+}
@@ -0,0 +1,177 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/traefik/yaegi/interp"
+)
+
+// TestFuncName is the function generated programs export to test their own logic when
+// generated_tests is enabled.
+const TestFuncName = "AegisxTest"
+
+// generatedTestTimeout bounds a run of the generated tests.
+const generatedTestTimeout = 30 * time.Second
+
+// generatedTestScratch suffixes the runtime ID the test interpreter's host packages are bound
+// to, so tests never touch the runtime's own data.
+const generatedTestScratch = ".test"
+
+// maxTestOutput caps the program output kept in a test report.
+const maxTestOutput = 4096
+
+// generatedTestRequirement is added to the generation prompt when generated_tests is enabled.
+var generatedTestRequirement = `Also export func ` + TestFuncName + `() error that tests the program's logic without listening on a port: build the same mux the server uses (create it in a function shared by main and ` + TestFuncName + `) and exercise its handlers with net/http/httptest, or call the program's functions directly. Return an error describing the first failed expectation, or nil. ` + TestFuncName + ` must not call main, start a server or sleep.`
+
+// runGeneratedTests evaluates the runtime's code in a separate interpreter with main renamed
+// so the server does not start, then calls its test function. The outcome is recorded on the
+// runtime; a failing run returns the failure class and message for a rebuild.
+func (s *ExecuterService) runGeneratedTests(ctx context.Context, runtimeData *models.Runtime) (FailureClass, error) {
+	info := runtimeData.Snapshot()
+	report := &models.TestReport{Version: info.Version}
+	start := time.Now()
+	class, err := s.evalGeneratedTests(ctx, info, report)
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+	report.Passed = err == nil
+	if err != nil {
+		report.Error = err.Error()
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) { info.Tests = report })
+	if err != nil {
+		log.Printf("Generated tests failed for runtime %s: %v", info.ID, err)
+		return class, err
+	}
+	log.Printf("Generated tests passed for runtime %s in %s", info.ID, report.Duration)
+	return "", nil
+}
+
+func (s *ExecuterService) evalGeneratedTests(ctx context.Context, info models.RuntimeInfo, report *models.TestReport) (FailureClass, error) {
+	source, err := withoutMain(info.Code)
+	if err != nil {
+		return FailureTest, err
+	}
+	scratchID := info.ID + generatedTestScratch
+	defer s.removeTestData(scratchID)
+	interpreter, output := s.newTestInterpreter(info, scratchID)
+	captured := output.Capture(0)
+	defer func() {
+		report.Output, _ = captured()
+		if len(report.Output) > maxTestOutput {
+			report.Output = report.Output[len(report.Output)-maxTestOutput:]
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, generatedTestTimeout)
+	defer cancel()
+	var result any
+	err = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic during generated tests: %v", r)
+			}
+		}()
+		if _, err := interpreter.EvalWithContext(ctx, source); err != nil {
+			return err
+		}
+		value, err := interpreter.EvalWithContext(ctx, TestFuncName+"()")
+		if err != nil {
+			return fmt.Errorf("%s failed to run: %w", TestFuncName, err)
+		}
+		if value.IsValid() && value.CanInterface() {
+			result = value.Interface()
+		}
+		return nil
+	}()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return FailureTest, fmt.Errorf("%s did not return within %s", TestFuncName, generatedTestTimeout)
+	case err != nil && strings.HasPrefix(err.Error(), TestFuncName):
+		return FailureTest, err
+	case err != nil:
+		return classifyEvalError(err), err
+	}
+	if testErr, ok := result.(error); ok && testErr != nil {
+		return FailureTest, fmt.Errorf("%s reported a failure: %v", TestFuncName, testErr)
+	}
+	return "", nil
+}
+
+// newTestInterpreter creates an interpreter that resolves imports like the runtime's own but
+// binds the host packages to scratchID. Secrets are read-only, so the tests see the tenant's,
+// and the tests are held to the runtime's egress policy.
+func (s *ExecuterService) newTestInterpreter(info models.RuntimeInfo, scratchID string) (*interp.Interpreter, *util.LogWriter) {
+	var exports []interp.Exports
+	if s.KV != nil {
+		exports = append(exports, s.KV.Exports(scratchID))
+	}
+	if s.SQLite != nil {
+		exports = append(exports, s.SQLite.Exports(scratchID))
+	}
+	if s.Secrets != nil {
+		exports = append(exports, s.Secrets.Exports(info.Tenant))
+	}
+	if len(info.Env) > 0 {
+		exports = append(exports, envExports(info.Env))
+	}
+	if policy := s.egressPolicy(info.NoOutbound); policy.Restricted() || s.Outbound != nil {
+		exports = append(exports, s.egressExports(policy, info.ID))
+	}
+	return s.Interpreters.Get(s.goPath(info.ID), exports...)
+}
+
+// removeTestData drops whatever the generated tests stored.
+func (s *ExecuterService) removeTestData(scratchID string) {
+	if s.KV != nil {
+		if err := s.KV.DeleteRuntime(scratchID); err != nil {
+			log.Printf("failed to remove generated test data %s: %v", scratchID, err)
+		}
+	}
+	if s.SQLite != nil {
+		if err := s.SQLite.Remove(scratchID); err != nil {
+			log.Printf("failed to remove generated test database %s: %v", scratchID, err)
+		}
+	}
+}
+
+// withoutMain renames the program's main function so evaluating it does not start the server,
+// and checks that it exports the test function.
+func withoutMain(code string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse program: %w", err)
+	}
+	hasTest := false
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		switch fn.Name.Name {
+		case "main":
+			fn.Name.Name = "aegisxMain"
+		case TestFuncName:
+			hasTest = fn.Type.Params.NumFields() == 0 && fn.Type.Results.NumFields() == 1
+		}
+	}
+	if !hasTest {
+		return "", fmt.Errorf("the program does not export func %s() error", TestFuncName)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		return "", fmt.Errorf("failed to print program: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,126 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// Detach saves every runtime record and stops writing them, and releases the kv store, so a
+// successor process taking over finds the store up to date and owns it from then on. The
+// runtimes keep serving until the process exits, though their kv calls fail; Attach undoes a
+// detach when the handoff is abandoned.
+func (s *ExecuterService) Detach(ctx context.Context) error {
+	var err error
+	s.Runtimes.Range(func(runtimeData *models.Runtime) bool {
+		if saveErr := s.SaveExecuter(ctx, runtimeData); saveErr != nil {
+			err = fmt.Errorf("failed to save runtime %s: %w", runtimeData.Snapshot().ID, saveErr)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if s.KV != nil {
+		if err := s.KV.Release(); err != nil {
+			return fmt.Errorf("failed to release kv store: %w", err)
+		}
+	}
+	s.detached.Store(true)
+	return nil
+}
+
+// Attach resumes writing runtime records and reopens the kv store after Detach.
+func (s *ExecuterService) Attach() {
+	s.detached.Store(false)
+	if s.KV != nil {
+		if err := s.KV.Reopen(); err != nil {
+			log.Printf("⚠️ Failed to reopen kv store after an abandoned handoff: %v", err)
+		}
+	}
+}
+
+// ShutdownRuntimes shuts down the program of every active runtime and frees its port, for a
+// node that stops inside a process that keeps running. The runtime records are saved and
+// detached first, so they stay as they were for the next start. It waits until the programs
+// exited or ctx is done.
+func (s *ExecuterService) ShutdownRuntimes(ctx context.Context) error {
+	if err := s.Detach(ctx); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	s.Runtimes.Range(func(runtimeData *models.Runtime) bool {
+		info := runtimeData.Snapshot()
+		if !info.State.Active() || info.State == models.RSSTOPPING {
+			return true
+		}
+		// Stopping, so that the program exiting is not handled as a failure.
+		runtimeData.SetState(models.RSSTOPPING)
+		wg.Add(1)
+		util.Go("shutdown", info.ID, func() {
+			defer wg.Done()
+			// The supervisor is stopped only after Shutdown(), as cancelling the program's
+			// context first would abort the call.
+			if info.Executer != nil {
+				if err := info.Executer.Shutdown(ctx); err != nil {
+					log.Printf("Graceful shutdown failed for runtime %s: %v", info.ID, err)
+				}
+			}
+			s.stopSupervisor(info.ID)
+			s.PortAllocator.Release(info.ID)
+		})
+		return true
+	})
+	wg.Wait()
+	return nil
+}
+
+// ReattachRuntimes loads the runtime records a predecessor process left in the store and
+// restarts the ones that were running, each on a fresh port since the predecessor still holds
+// the old ones. Their proxy routes move to the new ports once they listen, until then they
+// keep pointing at the predecessor's programs. It waits until every restarted runtime passed
+// its health check or failed, or ctx ends, and returns how many were reattached.
+func (s *ExecuterService) ReattachRuntimes(ctx context.Context) (int, error) {
+	runtimes, err := s.LoadAllExecuters(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load runtimes: %w", err)
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reattached := 0
+	for _, runtimeData := range runtimes {
+		info := runtimeData.Snapshot()
+		if !info.State.Active() || info.Archived {
+			continue
+		}
+		// A runtime that was still being generated or stopped has no program to carry over.
+		if info.Code == "" || info.State == models.RSSTOPPING {
+			log.Printf("Runtime %s was %s during the handoff and is not restarted", info.ID, info.State)
+			runtimeData.SetState(models.RSSTOP)
+			continue
+		}
+		runtimeData.SetState(models.RSSTOP)
+		if err := s.StartRuntime(ctx, info.ID); err != nil {
+			log.Printf("Failed to reattach runtime %s: %v", info.ID, err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := waitForPassedHealthCheck(ctx, s, info.ID); err != nil {
+				log.Printf("Reattached runtime %s did not become healthy: %v", info.ID, err)
+				return
+			}
+			mu.Lock()
+			reattached++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return reattached, nil
+}
@@ -0,0 +1,110 @@
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+var (
+	// ErrInvalidIdempotencyKey is returned for idempotency keys that are empty, too long or not
+	// printable ASCII.
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a
+	// different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+)
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted.
+const maxIdempotencyKeyLength = 255
+
+// idempotentExecution is the generation started for an idempotency key.
+type idempotentExecution struct {
+	request   string // The request the key was first sent with
+	done      chan struct{}
+	runtimeID string
+	duplicate bool
+	err       error
+	expires   time.Time
+}
+
+// NewIdempotentExecution is NewDeduplicatedExecution for a request carrying an idempotency key.
+// The generation runs detached from ctx, so a client that timed out and retries with the same
+// key, within IdempotencyKeyTTL of the generation finishing, gets the same runtime instead of
+// starting another generation. Keys are scoped to the tenant. A failed generation forgets its
+// key so the request can be retried; a zero IdempotencyKeyTTL only joins retries sent while
+// the generation is running.
+func (s *ExecuterService) NewIdempotentExecution(ctx context.Context, key string, prompt string, opts ExecutionOptions) (string, bool, error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return "", false, err
+	}
+	params, _ := json.Marshal(opts.Params)
+	request := fmt.Sprintf("%q %t %t %q %q %s", prompt, opts.Fresh, opts.Force, opts.Strategy, opts.Model, params)
+	key = opts.Tenant + "\x00" + key
+
+	s.idempotencyMu.Lock()
+	now := time.Now()
+	for k, execution := range s.idempotent {
+		if !execution.expires.IsZero() && now.After(execution.expires) {
+			delete(s.idempotent, k)
+		}
+	}
+	execution, ok := s.idempotent[key]
+	if ok && execution.request != request {
+		s.idempotencyMu.Unlock()
+		return "", false, ErrIdempotencyKeyReused
+	}
+	if !ok {
+		execution = &idempotentExecution{request: request, done: make(chan struct{})}
+		if s.idempotent == nil {
+			s.idempotent = map[string]*idempotentExecution{}
+		}
+		s.idempotent[key] = execution
+		go s.runIdempotent(context.WithoutCancel(ctx), key, execution, prompt, opts)
+	}
+	s.idempotencyMu.Unlock()
+
+	select {
+	case <-execution.done:
+	case <-ctx.Done():
+		return "", false, fmt.Errorf("waiting for generation: %w", ctx.Err())
+	}
+	return execution.runtimeID, execution.duplicate, execution.err
+}
+
+func (s *ExecuterService) runIdempotent(ctx context.Context, key string, execution *idempotentExecution, prompt string, opts ExecutionOptions) {
+	defer util.Recover("execution", "", func(value any) {
+		execution.err = fmt.Errorf("execution panicked: %v", value)
+		s.finishIdempotent(key, execution)
+	})
+	execution.runtimeID, execution.duplicate, execution.err = s.NewDeduplicatedExecution(ctx, prompt, opts)
+	s.finishIdempotent(key, execution)
+}
+
+// finishIdempotent publishes the outcome of an execution to the requests waiting for it.
+func (s *ExecuterService) finishIdempotent(key string, execution *idempotentExecution) {
+	s.idempotencyMu.Lock()
+	if execution.err != nil {
+		delete(s.idempotent, key)
+	} else {
+		execution.expires = time.Now().Add(s.Config.IdempotencyKeyTTL)
+	}
+	s.idempotencyMu.Unlock()
+	close(execution.done)
+}
+
+func validateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("%w: must be 1 to %d characters", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return fmt.Errorf("%w: must be printable ASCII", ErrInvalidIdempotencyKey)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// KillGracePeriod bounds how long a non-forced kill waits for the program's Shutdown().
+const KillGracePeriod = 3 * time.Second
+
+// KillRuntime stops a runtime immediately. Unless force is set, Shutdown() is given
+// KillGracePeriod to complete; the interpreter context is then cancelled, the proxy route
+// removed and the port released without waiting for the program to wind down. A forced kill
+// of an interpreted program also closes its listeners, so a wedged program gives up its port.
+func (s *ExecuterService) KillRuntime(ctx context.Context, runtimeID string, force bool) (*models.KillReport, error) {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.KillReport{Force: force}
+	// Mark the runtime killed first so the eval goroutine and failure handler leave it alone.
+	runtimeData.SetState(models.RSKILL)
+	info := runtimeData.Snapshot()
+
+	if !force && info.Executer != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, KillGracePeriod)
+		err := info.Executer.Shutdown(shutdownCtx)
+		cancel()
+		report.GracefulShutdown = err == nil
+		if err != nil {
+			log.Printf("Graceful shutdown failed for runtime %s: %v", runtimeID, err)
+		}
+	}
+	s.stopSupervisor(runtimeID)
+	s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
+	// Programs in other languages run in a process of their own, which the supervisor kills.
+	if force && info.Language == "" && info.Port > 0 {
+		if closed, err := closeListeners(info.Port); err != nil {
+			log.Printf("failed to close the listeners of killed runtime %s: %v", runtimeID, err)
+		} else if closed > 0 {
+			log.Printf("Closed %d listeners on port %d of killed runtime %s", closed, info.Port, runtimeID)
+		}
+	}
+	report.PortListening = util.IsPortListening(info.Port)
+	if report.PortListening {
+		log.Printf("Port %d of killed runtime %s is still listening", info.Port, runtimeID)
+	}
+	s.PortAllocator.Release(runtimeID)
+
+	report.KilledAt = time.Now()
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.Kill = report
+		info.FinishedAt = report.KilledAt
+	})
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		return report, fmt.Errorf("failed to save runtime: %w", err)
+	}
+	log.Printf("Killed runtime %s (force=%t, graceful=%t)", runtimeID, force, report.GracefulShutdown)
+	return report, nil
+}
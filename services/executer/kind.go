@@ -0,0 +1,321 @@
+package executer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/kv"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+)
+
+// Runtime kinds decide what a runtime's program is asked to be and how it is supervised.
+const (
+	// KindWeb is a web app serving its prefix through the proxy, the default.
+	KindWeb = "web"
+	// KindAPI is a JSON API served like a web app, described by the OpenAPI document the model
+	// generates along with it.
+	KindAPI = "api"
+	// KindJob runs to completion without binding a port; its output is the runtime's result.
+	KindJob = "job"
+	// KindWorker runs in the background without binding a port, such as a scheduler, a poller
+	// or a bot. It reports readiness and heartbeats with control lines.
+	KindWorker = "worker"
+)
+
+var ErrUnknownKind = errors.New("runtime kind must be web, api, job or worker")
+
+// OpenAPIDocName is the static asset holding the OpenAPI document of an API runtime.
+const OpenAPIDocName = "openapi.json"
+
+// maxJobResultBytes caps the output of a job kept as its result.
+const maxJobResultBytes = 1 << 20
+
+// maxJobOutputBytes caps the output of a job captured to make its result, leaving room for the
+// control lines dropped from it.
+const maxJobOutputBytes = 2 * maxJobResultBytes
+
+// webRules are the validator rules about serving HTTP, which programs that do not are spared.
+var webRules = []string{"form_action_prefix", "handler_root", "frontend"}
+
+// skippedRules returns the validator rules that do not apply to programs of kind: the web rules
+// for a kind that does not listen, and the required functions, Shutdown among them, for jobs,
+// which stop on their own.
+func skippedRules(kind string) []string {
+	switch {
+	case kind == KindJob:
+		return append([]string{"required_functions"}, webRules...)
+	case !listens(kind):
+		return webRules
+	}
+	return nil
+}
+
+// validateKind rejects unknown kinds.
+func (o ExecutionOptions) validateKind() error {
+	switch o.Kind {
+	case "", KindWeb, KindAPI, KindJob, KindWorker:
+		return nil
+	}
+	return fmt.Errorf("%w, got %q", ErrUnknownKind, o.Kind)
+}
+
+// runtimeKind is the kind recorded on a runtime of opts, empty for a web app.
+func (o ExecutionOptions) runtimeKind() string {
+	if o.Kind == KindWeb {
+		return ""
+	}
+	return o.Kind
+}
+
+// listens reports whether the programs of a runtime kind listen on a port behind the proxy.
+func listens(kind string) bool {
+	return kind == "" || kind == KindWeb || kind == KindAPI
+}
+
+// allocatePort reserves the port of a runtime of kind, or returns 0 for a kind that does not
+// listen.
+func (s *ExecuterService) allocatePort(runtimeID string, kind string) (int, error) {
+	if !listens(kind) {
+		return 0, nil
+	}
+	return s.PortAllocator.Allocate(runtimeID)
+}
+
+// createPrompt wraps the user prompt in the generation rules of the runtime kind of opts, in
+// the language of opts. The host packages are only offered to Go programs.
+func (s *ExecuterService) createPrompt(prompt string, id string, port int, opts ExecutionOptions) string {
+	if backend, ok := lookupBackend(opts.runtimeLanguage()); ok {
+		var requirements []string
+		if requirement := envRequirement(opts.runtimeLanguage(), opts.Env); requirement != "" {
+			requirements = append(requirements, requirement)
+		}
+		if instructions := s.variantInstructions(opts.PromptVariant); instructions != "" {
+			requirements = append(requirements, instructions)
+		}
+		return backend.Prompt(prompt, s.programSpec(opts.Tenant, id, opts.runtimeKind(), port), requirements...)
+	}
+	requirements := s.promptRequirements(opts, prompt)
+	switch opts.runtimeKind() {
+	case KindAPI:
+		return CreateAPIPrompt(prompt, models.RuntimePrefix(opts.Tenant, id), port, requirements...)
+	case KindJob:
+		return CreateJobPrompt(prompt, requirements...)
+	case KindWorker:
+		return CreateWorkerPrompt(prompt, s.heartbeatInterval(), requirements...)
+	}
+	return CreatePrompt(prompt, models.RuntimePrefix(opts.Tenant, id), port, requirements...)
+}
+
+// CreateAPIPrompt wraps the user prompt in the generation rules for a JSON API served under
+// prefix, which the model describes in an OpenAPI document returned as the OpenAPIDocName asset.
+func CreateAPIPrompt(prompt string, prefix string, port int, extraRequirements ...string) string {
+	log.Println("Creating API prompt for base prompt:", prompt)
+	base := `You are a Go expert. Generate a Go program that meets the following requirements:
+🛡️ Core Requirements:
+✅ A JSON HTTP API with a web server. Do NOT serve HTML, CSS or JavaScript.
+✅ Persist state with the host package: import "` + kv.ImportPath + `" and use kv.Get(key string) (string, bool, error), kv.Put(key, value string) error, kv.Delete(key string) error and kv.Keys() ([]string, error). Store structured values as JSON. Do NOT write files for storage.
+✅ Export an Shutdown() function with no arguments and no return values.
+✅ Shutdown() must stop the server and release the port.
+🚫 Do NOT use any global variables.
+🚫 Do NOT use syscall.
+🚫 Do NOT use os/exec, unsafe, os.Exit, or log.Fatal.
+📊 Logging Rules:
+✅ Use fmt.Println() or fmt.Printf() for logs.
+✅ Report to aegisx by printing these control lines exactly, each on its own line with fmt.Println:
+   - \"` + util.ControlLine(util.ControlPort, strconv.Itoa(port)) + `\" before starting the server.
+   - \"` + util.ControlLine(util.ControlReady, "") + `\" once setup is done, right before the server starts serving.
+   - \"` + util.ControlLine(util.ControlError, "") + `<message>\" only for a fatal error the program cannot recover from.
+🌐 API Requirements:
+✅ Declare exactly: const ` + code.PortConstName + ` = ` + strconv.Itoa(port) + `
+✅ Listen only on that port, e.g. ":" + strconv.Itoa(` + code.PortConstName + `). Do NOT pick a random port.
+✅ Use http.NewServeMux for all routes. Requests keep the ` + prefix + ` prefix through the proxy, so register them under it, e.g. mux.HandleFunc("` + prefix + `/items", itemsHandler).
+✅ Read and write JSON with encoding/json and set Content-Type: application/json on every response.
+✅ Report errors as {"error": "<message>"} with a matching 4xx or 5xx status code.
+✅ GET / must return 200 with a JSON summary of the API.
+📘 OpenAPI Document:
+✅ Also return an OpenAPI 3.0 document describing every endpoint, with its parameters, request bodies and responses, as a fenced code block labelled with its file name: ` + "```json " + OpenAPIDocName + `
+✅ Its servers list holds exactly one entry with the url ` + prefix + `, and its paths are relative to it, e.g. /items.
+
+💡 Program Instructions:
+Third party packages are permitted, but they must be stable and well-known.
+Return only the source code and the OpenAPI document—no additional commentary.
+The program must compile and run as provided.
+The program must be a complete, runnable Go program.
+`
+	for _, requirement := range extraRequirements {
+		base += requirement + "\n"
+	}
+	base += userPromptMarker
+	if strings.Contains(prompt, base) {
+		return prompt
+	}
+	return base + prompt
+}
+
+// openAPIDocRule checks the OpenAPI document an API runtime's program came with, so a missing
+// or malformed document is rebuilt like the program's own violations.
+func (s *ExecuterService) openAPIDocRule(runtimeID string) code.Rule {
+	return code.Rule{Name: "openapi_document", Check: func(src *code.Source) []code.Violation {
+		data, err := os.ReadFile(filepath.Join(s.StaticDir(runtimeID), OpenAPIDocName))
+		if err != nil {
+			return []code.Violation{{Message: "the OpenAPI document is missing: return it as a fenced block labelled " + OpenAPIDocName}}
+		}
+		var doc struct {
+			OpenAPI string         `json:"openapi"`
+			Paths   map[string]any `json:"paths"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return []code.Violation{{Message: "the OpenAPI document is not valid JSON: " + err.Error()}}
+		}
+		if doc.OpenAPI == "" || len(doc.Paths) == 0 {
+			return []code.Violation{{Message: "the OpenAPI document needs an openapi version and the paths of the API"}}
+		}
+		return nil
+	}}
+}
+
+// CreateJobPrompt wraps the user prompt in the generation rules for a job: a program that does
+// its work, prints its result and returns from main.
+func CreateJobPrompt(prompt string, extraRequirements ...string) string {
+	log.Println("Creating job prompt for base prompt:", prompt)
+	base := `You are a Go expert. Generate a Go program that meets the following requirements:
+🛡️ Core Requirements:
+✅ A command line job that runs once: it does its work, prints its result and returns from main.
+✅ Do NOT start a web server or listen on any port.
+✅ Persist state between runs with the host package: import "` + kv.ImportPath + `" and use kv.Get(key string) (string, bool, error), kv.Put(key, value string) error, kv.Delete(key string) error and kv.Keys() ([]string, error). Store structured values as JSON. Do NOT write files for storage.
+🚫 Do NOT use any global variables.
+🚫 Do NOT use syscall.
+🚫 Do NOT use os/exec, unsafe, os.Exit, or log.Fatal.
+📊 Output Rules:
+✅ Everything the program prints to stdout with fmt.Println() or fmt.Printf() is its result, so print only the result.
+✅ Report a fatal error by printing the control line \"` + util.ControlLine(util.ControlError, "") + `<message>\" with fmt.Println and returning from main.
+✅ Do NOT read from stdin; the program gets no input.
+
+💡 Program Instructions:
+Third party packages are permitted, but they must be stable and well-known.
+Return only the source code—no additional commentary.
+The program must compile and run as provided.
+The program must be a complete, runnable Go program.
+The program must finish on its own; do NOT loop forever or wait for a signal.
+`
+	for _, requirement := range extraRequirements {
+		base += requirement + "\n"
+	}
+	base += userPromptMarker
+	if strings.Contains(prompt, base) {
+		return prompt
+	}
+	return base + prompt
+}
+
+// CreateWorkerPrompt wraps the user prompt in the generation rules for a worker: a program that
+// keeps working in the background, reports readiness and prints a heartbeat at least every
+// heartbeat.
+func CreateWorkerPrompt(prompt string, heartbeat time.Duration, extraRequirements ...string) string {
+	log.Println("Creating worker prompt for base prompt:", prompt)
+	base := `You are a Go expert. Generate a Go program that meets the following requirements:
+🛡️ Core Requirements:
+✅ A background worker, such as a scheduler, a poller or a bot, that keeps working until it is shut down.
+✅ Do NOT start a web server or listen on any port.
+✅ Persist state with the host package: import "` + kv.ImportPath + `" and use kv.Get(key string) (string, bool, error), kv.Put(key, value string) error, kv.Delete(key string) error and kv.Keys() ([]string, error). Store structured values as JSON. Do NOT write files for storage.
+✅ Export an Shutdown() function with no arguments and no return values.
+✅ Shutdown() must stop the work, and main must block until it does.
+🚫 Do NOT use any global variables.
+🚫 Do NOT use syscall.
+🚫 Do NOT use os/exec, unsafe, os.Exit, or log.Fatal.
+📊 Logging Rules:
+✅ Use fmt.Println() or fmt.Printf() for logs.
+✅ Report to aegisx by printing these control lines exactly, each on its own line with fmt.Println:
+   - \"` + util.ControlLine(util.ControlReady, "") + `\" once setup is done and the work starts.
+   - \"` + util.ControlLine(util.ControlHeartbeat, "") + `\" at least every ` + heartbeat.String() + ` while the worker is healthy, from the loop doing the work, so a stuck worker stops sending it.
+   - \"` + util.ControlLine(util.ControlError, "") + `<message>\" only for a fatal error the program cannot recover from.
+
+💡 Program Instructions:
+Third party packages are permitted, but they must be stable and well-known.
+Return only the source code—no additional commentary.
+The program must compile and run as provided.
+The program must be a complete, runnable Go program.
+`
+	for _, requirement := range extraRequirements {
+		base += requirement + "\n"
+	}
+	base += userPromptMarker
+	if strings.Contains(prompt, base) {
+		return prompt
+	}
+	return base + prompt
+}
+
+// heartbeatInterval is how often workers are asked to print a heartbeat, leaving room for two
+// late ones within worker_heartbeat_timeout.
+func (s *ExecuterService) heartbeatInterval() time.Duration {
+	if s.Config.WorkerHeartbeatTimeout <= 0 {
+		return time.Minute
+	}
+	return max(s.Config.WorkerHeartbeatTimeout/3, time.Second)
+}
+
+// rebuildRequirements are the REQUIREMENTS of the rebuild prompt for a program of kind.
+func (s *ExecuterService) rebuildRequirements(kind string, port int) string {
+	switch kind {
+	case KindJob:
+		return `- The program must compile and run as provided.
+- Do NOT start a web server; print the result to stdout and return from main when done.
+- Return only the corrected Go program.
+`
+	case KindAPI:
+		return serverRebuildRequirements(port) + `- Serve JSON only; do NOT serve HTML.
+- Return the corrected Go program and the complete OpenAPI document of the API as a fenced block labelled ` + "```json " + OpenAPIDocName + `.
+`
+	case KindWorker:
+		return `- The program must compile and run as provided.
+- Do NOT start a web server; export Shutdown() and block in main until it is called.
+- Ensure '` + util.ControlLine(util.ControlReady, "") + `' is printed once the work starts and '` + util.ControlLine(util.ControlHeartbeat, "") + `' at least every ` + s.heartbeatInterval().String() + ` while working.
+- Return only the corrected Go program.
+`
+	}
+	return webRebuildRequirements(port)
+}
+
+// jobRan reports whether a job that failed with class may have done part of its work, so that
+// running it again would repeat its side effects. The Go interpreter compiles a program before
+// running it; a program of another language may fail only once its interpreter runs it.
+func jobRan(info models.RuntimeInfo, class FailureClass) bool {
+	switch class {
+	case FailureValidation, FailureLLM:
+		return false
+	case FailureCompile:
+		return info.Language != ""
+	}
+	return true
+}
+
+// jobResult turns the output of a job into its result: the control lines are dropped, and so
+// are the secrets of the tenant, and it is cut to maxJobResultBytes. truncated tells whether the
+// output was already cut when it was captured. reported is the fatal error the program reported,
+// if any.
+func (s *ExecuterService) jobResult(tenant string, output string, truncated bool) (result *models.JobResult, reported string) {
+	var kept strings.Builder
+	for _, line := range strings.SplitAfter(output, "\n") {
+		message, ok := util.ParseControlLine(strings.TrimRight(line, "\r\n"))
+		if !ok {
+			kept.WriteString(line)
+		} else if message.Kind == util.ControlError && reported == "" {
+			reported = message.Error
+		}
+	}
+	result = &models.JobResult{Output: s.scrubSecrets(tenant, kept.String()), Truncated: truncated}
+	if len(result.Output) > maxJobResultBytes {
+		result.Output, result.Truncated = strings.ToValidUTF8(result.Output[:maxJobResultBytes], ""), true
+	}
+	return result, reported
+}
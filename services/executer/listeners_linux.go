@@ -0,0 +1,47 @@
+//go:build linux
+
+package executer
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// closeListeners shuts down the sockets of this process listening on port, which the port
+// allocator gave a single runtime. The interpreted program's accept loop then fails, its server
+// returns and the port is free again, even when the program never returns from Shutdown().
+// It returns how many listeners were shut down.
+func closeListeners(port int) (int, error) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, entry := range fds {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil || !listensOn(fd, port) {
+			continue
+		}
+		if err := unix.Shutdown(fd, unix.SHUT_RDWR); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// listensOn reports whether fd is a socket listening on port.
+func listensOn(fd int, port int) bool {
+	if accepting, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ACCEPTCONN); err != nil || accepting != 1 {
+		return false
+	}
+	switch addr, _ := unix.Getsockname(fd); addr := addr.(type) {
+	case *unix.SockaddrInet4:
+		return addr.Port == port
+	case *unix.SockaddrInet6:
+		return addr.Port == port
+	}
+	return false
+}
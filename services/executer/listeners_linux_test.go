@@ -0,0 +1,44 @@
+//go:build linux
+
+package executer
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCloseListeners(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	served := make(chan error, 1)
+	go func() { served <- http.Serve(listener, http.NotFoundHandler()) }()
+	// A connection accepted on the port is not a listener and is left open.
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if closed, err := closeListeners(port); err != nil || closed != 1 {
+		t.Fatalf("closeListeners() = %d, %v, want 1 listener closed", closed, err)
+	}
+	select {
+	case err := <-served:
+		if errors.Is(err, http.ErrServerClosed) || err == nil {
+			t.Errorf("Serve returned %v, want an accept error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server kept serving")
+	}
+	relisten, err := net.Listen("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("port %d was not released: %v", port, err)
+	}
+	relisten.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package executer
+
+import "errors"
+
+// closeListeners fails: the listeners of a program are only found through /proc on Linux.
+func closeListeners(port int) (int, error) {
+	return 0, errors.New("listeners can only be closed on linux")
+}
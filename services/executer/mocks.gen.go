@@ -0,0 +1,62 @@
+// Code generated by mock-gen from routes.go. DO NOT EDIT.
+
+package executer
+
+import (
+	"sync"
+)
+
+// RouteServiceMock is a mock of RouteService. Set the function of every method the code under test calls.
+type RouteServiceMock struct {
+	DeregisterReverseProxyFunc func(runtimeID string)
+	RegisterReverseProxyFunc   func(runtimeID string, tenant string, port int)
+	RemoveReverseProxyFunc     func(runtimeID string) error
+
+	mu    sync.Mutex
+	calls map[string][][]any
+}
+
+var _ RouteService = (*RouteServiceMock)(nil)
+
+// Calls returns the arguments of every call of method, in order.
+func (m *RouteServiceMock) Calls(method string) [][]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]any(nil), m.calls[method]...)
+}
+
+func (m *RouteServiceMock) record(method string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = map[string][][]any{}
+	}
+	m.calls[method] = append(m.calls[method], args)
+}
+
+// DeregisterReverseProxy calls DeregisterReverseProxyFunc.
+func (m *RouteServiceMock) DeregisterReverseProxy(runtimeID string) {
+	m.record("DeregisterReverseProxy", runtimeID)
+	if m.DeregisterReverseProxyFunc == nil {
+		panic("RouteServiceMock.DeregisterReverseProxy called without DeregisterReverseProxyFunc")
+	}
+	m.DeregisterReverseProxyFunc(runtimeID)
+}
+
+// RegisterReverseProxy calls RegisterReverseProxyFunc.
+func (m *RouteServiceMock) RegisterReverseProxy(runtimeID string, tenant string, port int) {
+	m.record("RegisterReverseProxy", runtimeID, tenant, port)
+	if m.RegisterReverseProxyFunc == nil {
+		panic("RouteServiceMock.RegisterReverseProxy called without RegisterReverseProxyFunc")
+	}
+	m.RegisterReverseProxyFunc(runtimeID, tenant, port)
+}
+
+// RemoveReverseProxy calls RemoveReverseProxyFunc.
+func (m *RouteServiceMock) RemoveReverseProxy(runtimeID string) error {
+	m.record("RemoveReverseProxy", runtimeID)
+	if m.RemoveReverseProxyFunc == nil {
+		panic("RouteServiceMock.RemoveReverseProxy called without RemoveReverseProxyFunc")
+	}
+	return m.RemoveReverseProxyFunc(runtimeID)
+}
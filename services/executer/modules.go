@@ -0,0 +1,87 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gcottom/aegisx/services/vuln"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+)
+
+// ModuleDir returns the root of the runtime's own module, used as its interpreter's GOPATH.
+// It is empty in GOPATH mode, when no module store is configured, and in offline mode.
+func (s *ExecuterService) ModuleDir(runtimeID string) string {
+	if s.Config.ModuleStore == "" || s.Config.OfflineMode {
+		return ""
+	}
+	return filepath.Join(s.Config.ModuleStore, runtimeID)
+}
+
+// preparedModulesDir is the directory of the module store holding the modules prepared for
+// each set of imports, which runtimes importing the same packages link instead of resolving.
+const preparedModulesDir = ".prepared"
+
+// goPath returns the GOPATH the runtime's interpreter resolves third party imports from.
+func (s *ExecuterService) goPath(runtimeID string) string {
+	if s.Config.OfflineMode {
+		return s.Config.OfflinePackageCache
+	}
+	if dir := s.ModuleDir(runtimeID); dir != "" {
+		return dir
+	}
+	return s.Config.YaegiGoPath
+}
+
+// resolveDependencies makes the third party imports of source available to the runtime, in
+// its own module or, in GOPATH mode, in the shared GOPATH. Imports the dependency policy
+// forbids are rejected before anything is downloaded. In offline mode nothing is downloaded
+// and every import must already be in the offline package cache.
+func (s *ExecuterService) resolveDependencies(runtimeID string, source string) error {
+	policy := s.Config.Dependencies
+	imports := util.ExtractImports(source)
+	if violations := code.CheckDependencies(policy, imports); len(violations) > 0 {
+		return fmt.Errorf("dependency policy rejected the program: %w", &code.ValidationError{Violations: violations})
+	}
+	if s.Config.OfflineMode {
+		if violations := code.CheckOfflinePackages(s.Config.OfflinePackageCache, imports); len(violations) > 0 {
+			return fmt.Errorf("offline mode rejected the program: %w", &code.ValidationError{Violations: violations})
+		}
+		return nil
+	}
+	var err error
+	if dir := s.ModuleDir(runtimeID); dir != "" {
+		var check func([]util.ModuleVersion) error
+		if policy.VulnCheck {
+			osv := vuln.NewOSVClient(policy.OSVURL)
+			check = func(modules []util.ModuleVersion) error {
+				return osv.Check(context.Background(), modules)
+			}
+		}
+		err = util.ResolveModuleDependencies(source, dir, filepath.Join(s.Config.ModuleStore, preparedModulesDir), check)
+	} else {
+		if policy.VulnCheck {
+			log.Printf("Skipping vulnerability check for runtime %s: module_store is not configured", runtimeID)
+		}
+		err = util.DownloadNonStandardPackages(source, s.Config.YaegiGoPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download non-standard packages: %w", err)
+	}
+	return nil
+}
+
+// removeModule deletes the runtime's module directory.
+func (s *ExecuterService) removeModule(runtimeID string) error {
+	dir := s.ModuleDir(runtimeID)
+	if dir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove module directory: %w", err)
+	}
+	return nil
+}
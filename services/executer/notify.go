@@ -0,0 +1,47 @@
+package executer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/notify"
+)
+
+// notifyRuntime sends a lifecycle event about a runtime to the operators' notifiers.
+func (s *ExecuterService) notifyRuntime(eventType notify.EventType, info models.RuntimeInfo, message string) {
+	s.Notifier.Notify(notify.Event{
+		Type:      eventType,
+		RuntimeID: info.ID,
+		Tenant:    info.Tenant,
+		Message:   message,
+		URL:       s.Config.GetPublicURL() + models.RuntimePrefix(info.Tenant, info.ID),
+	})
+}
+
+// noteRebuild counts a rebuild or regeneration and reports a retry storm once
+// retry_storm_threshold of them happened within retry_storm_window.
+func (s *ExecuterService) noteRebuild() {
+	threshold := s.Config.RetryStormThreshold
+	if threshold <= 0 {
+		return
+	}
+	now := time.Now()
+	s.rebuildsMu.Lock()
+	cutoff := now.Add(-s.Config.RetryStormWindow)
+	kept := s.rebuilds[:0]
+	for _, t := range s.rebuilds {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	s.rebuilds = append(kept, now)
+	count := len(s.rebuilds)
+	s.rebuildsMu.Unlock()
+	if count >= threshold {
+		s.Notifier.Notify(notify.Event{
+			Type:    notify.EventRetryStorm,
+			Message: fmt.Sprintf("%d runtime rebuilds in the last %s", count, s.Config.RetryStormWindow),
+		})
+	}
+}
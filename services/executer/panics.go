@@ -0,0 +1,55 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/traefik/yaegi/interp"
+)
+
+// guardExports provides the package the guarded goroutines of a runtime's program report
+// their panics to; see util.GuardGoroutines.
+func (s *ExecuterService) guardExports(runtimeID string) interp.Exports {
+	return interp.Exports{
+		util.GoroutineGuardPackage + "/runtime": {
+			"Panicked": reflect.ValueOf(func(value any) {
+				s.goroutinePanicked(runtimeID, value)
+			}),
+		},
+	}
+}
+
+// goroutinePanicked fails a runtime whose program panicked in a goroutine, as the panic would
+// have crashed the program had it run on its own. Only the first panic of an execution is
+// handled; the rest are reported.
+func (s *ExecuterService) goroutinePanicked(runtimeID string, value any) {
+	util.ReportPanic(util.PanicReport{Component: "runtime", RuntimeID: runtimeID, Value: fmt.Sprint(value)})
+	runtimeData, ok := s.Runtimes.Load(runtimeID)
+	if !ok {
+		return
+	}
+	failed := false
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		if !info.State.Active() || info.State == models.RSERR || info.State == models.RSSTOPPING {
+			return
+		}
+		info.LastErrorMsg = fmt.Sprintf("panic in goroutine: %v", value)
+		info.FailureClass = string(FailurePanic)
+		info.State = models.RSERR
+		failed = true
+	})
+	if !failed {
+		return
+	}
+	log.Printf("Runtime %s panicked in a goroutine: %v", runtimeID, value)
+	metrics.RuntimeFailures.Inc(string(FailurePanic))
+	if supervisor, ok := s.supervisors.Load(runtimeID); ok {
+		supervisor.(*RuntimeSupervisor).Cancel()
+	}
+	util.Go("failure-handler", runtimeID, func() { s.HandleRuntimeFailure(context.Background(), runtimeID) })
+}
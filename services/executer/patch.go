@@ -0,0 +1,76 @@
+package executer
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+)
+
+// CreatePatchPrompt asks for the fix of a program as a unified diff, so a large program is not
+// sent back whole on every rebuild. guidance is the advice of the retry rule matching the
+// failure class and may be empty.
+func CreatePatchPrompt(prompt string, errorString string, diagnostics []code.Violation, generatedCode string, guidance string) string {
+	log.Println("Creating patch prompt due to error: ", errorString)
+	if len(diagnostics) > 0 {
+		errorString = "The code failed validation with the following problems:\n" + code.FormatDiagnostics(diagnostics)
+	}
+	if guidance != "" {
+		guidance = "\n💡 HINT:\n" + guidance + "\n"
+	}
+	return `You are a Go expert.
+The following program was generated based on a user prompt but has an error.
+Please correct the error while adhering to the original prompt and best practices.
+
+💥 ERROR:
+` + errorString + `
+` + guidance + `
+📝 ORIGINAL CODE (main.go):
+` + generatedCode + `
+
+📝 ORIGINAL PROMPT:
+` + prompt + `
+
+✅ REQUIREMENTS:
+- The program must compile and run after the fix.
+- Do NOT change the port constant or the PORT log line.
+- Reply with the fix ONLY, as a unified diff of main.go in a single ` + "```diff" + ` block, with --- a/main.go and +++ b/main.go headers and at least 3 lines of unchanged context around each change.
+- If the reply must be a list of files, put the diff in a file named main.go.diff.
+`
+}
+
+// rebuildCode asks the runtime's model for corrected code. Go programs of at least
+// diff_rebuild_min_lines lines are patched with a unified diff first, falling back to a full
+// rebuild when the diff does not apply. API programs are always rebuilt in full, since the diff
+// cannot carry their OpenAPI document. files are the static assets of a full rebuild, nil when
+// the runtime's assets are kept.
+func (s *ExecuterService) rebuildCode(ctx context.Context, info models.RuntimeInfo, port int, guidance string) (string, map[string]string, error) {
+	ctx = util.WithGenerationParams(ctx, info.GenerationParams)
+	// The error may quote what the program printed, secrets included.
+	lastError := s.scrubSecrets(info.Tenant, info.LastErrorMsg)
+	if minLines := s.Config.DiffRebuildMinLines; minLines > 0 && info.Language == "" && info.Kind != KindAPI && strings.Count(info.Code, "\n")+1 >= minLines {
+		response, err := s.sendWithRetry(ctx, info.ID, info.Model, CreatePatchPrompt(info.Prompt, lastError, info.Diagnostics, info.Code, guidance))
+		if err != nil {
+			return "", nil, err
+		}
+		patched, err := util.ApplyPatch(info.Code, util.ExtractPatch(response))
+		if err == nil {
+			log.Printf("Patched runtime %s with a diff", info.ID)
+			return rewriteRuntimeReferences(patched, info.ID, info.ID, port), nil, nil
+		}
+		log.Printf("Diff for runtime %s did not apply, requesting the full program: %v", info.ID, err)
+	}
+	language, requirements := "Go", s.rebuildRequirements(info.Kind, port)
+	if backend, ok := lookupBackend(info.Language); ok {
+		language, requirements = backend.Name(), backend.RebuildRequirements(s.programSpec(info.Tenant, info.ID, info.Kind, port))
+	}
+	response, err := s.sendWithRetry(ctx, info.ID, info.Model, createRebuildPrompt(info.Prompt, lastError, info.Diagnostics, info.Code, guidance, language, requirements))
+	if err != nil {
+		return "", nil, err
+	}
+	program, files := extractProgram(info.Language, response)
+	return program, files, nil
+}
@@ -0,0 +1,96 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// postMortemTimeout bounds the diagnosis of a failed runtime.
+const postMortemTimeout = 2 * time.Minute
+
+// maxPostMortemErrors is the number of most recent errors of a runtime's history included in
+// the post-mortem prompt.
+const maxPostMortemErrors = 10
+
+// CreatePostMortemPrompt asks for a short diagnosis of a runtime that failed for good, from its
+// user prompt, the errors that triggered its rebuilds, oldest first, and its final code.
+func CreatePostMortemPrompt(prompt string, failures []string, finalError string, generatedCode string) string {
+	log.Println("Creating post-mortem prompt")
+	var history strings.Builder
+	for i, err := range failures {
+		fmt.Fprintf(&history, "%d. %s\n", i+1, strings.TrimSpace(err))
+	}
+	if history.Len() == 0 {
+		history.WriteString("(none recorded)\n")
+	}
+	return `A Go web application was generated from the user prompt below, but every attempt to repair or regenerate it failed, so it was given up on.
+Explain to the user in at most three short sentences why the prompt could not be fulfilled, e.g. "The model kept using gorilla/mux, which is not in the dependency allowlist."
+Point out a recurring cause if there is one and, if it helps, how the prompt could be changed. Do NOT include code, headings or lists.
+
+📝 PROMPT:
+` + prompt + `
+
+💥 ERRORS, OLDEST FIRST:
+` + history.String() + `
+💥 FINAL ERROR:
+` + finalError + `
+
+📝 FINAL CODE:
+` + generatedCode + `
+`
+}
+
+// writePostMortem diagnoses a runtime that failed for good and stores the diagnosis on it. It
+// is skipped when the runtime was restarted in the meantime.
+func (s *ExecuterService) writePostMortem(runtimeData *models.Runtime) {
+	ctx, cancel := context.WithTimeout(context.Background(), postMortemTimeout)
+	defer cancel()
+	info := runtimeData.Snapshot()
+	var failures []string
+	if versions, err := s.ListVersions(info.ID); err != nil {
+		log.Printf("failed to list versions of runtime %s for its post-mortem: %v", info.ID, err)
+	} else {
+		for _, version := range versions {
+			if version.Source == VersionRebuild && version.Reason != "" {
+				failures = append(failures, s.scrubSecrets(info.Tenant, version.Reason))
+			}
+		}
+	}
+	if len(failures) > maxPostMortemErrors {
+		failures = failures[len(failures)-maxPostMortemErrors:]
+	}
+	finalError := s.scrubSecrets(info.Tenant, info.LastErrorMsg)
+	if info.FailureClass != "" {
+		finalError = info.FailureClass + ": " + finalError
+	}
+	summary, err := s.PostMortemClient.SendMessage(util.WithRuntimeID(ctx, info.ID), CreatePostMortemPrompt(userPrompt(info.Prompt), failures, finalError, info.Code))
+	if err != nil {
+		log.Printf("failed to write post-mortem of runtime %s: %v", info.ID, err)
+		return
+	}
+	postMortem := &models.PostMortem{
+		Summary:   strings.TrimSpace(summary),
+		Version:   info.Version,
+		Model:     s.PostMortemClient.ModelName(),
+		CreatedAt: time.Now(),
+	}
+	stored := false
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		if info.State == "failed" {
+			info.PostMortem = postMortem
+			stored = true
+		}
+	})
+	if !stored {
+		return
+	}
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		log.Printf("failed to save post-mortem of runtime %s: %v", info.ID, err)
+	}
+}
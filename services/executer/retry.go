@@ -0,0 +1,107 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// FailureClass identifies why a runtime failed and selects the retry rule applied to it.
+type FailureClass string
+
+const (
+	FailureValidation  FailureClass = "validation"
+	FailureCompile     FailureClass = "compile"
+	FailurePanic       FailureClass = "panic"
+	FailureTimeout     FailureClass = "timeout"
+	FailureHealthCheck FailureClass = "healthcheck"
+	FailureLLM         FailureClass = "llm"
+
+	FailureVerification FailureClass = "verification"
+	FailureTest         FailureClass = "test"
+	FailureBrowser      FailureClass = "browser"
+	FailureReported     FailureClass = "reported" // The program printed AEGISX:ERROR
+)
+
+// classifyEvalError tells a program that could not be compiled from one that crashed while running.
+func classifyEvalError(err error) FailureClass {
+	if strings.Contains(err.Error(), "panic") {
+		return FailurePanic
+	}
+	return FailureCompile
+}
+
+// markFailed records a failure of the given class on the runtime and leaves it in the error state.
+func (s *ExecuterService) markFailed(runtime *models.Runtime, class FailureClass, msg string) {
+	runtime.Update(func(info *models.RuntimeInfo) {
+		info.LastErrorMsg = msg
+		info.FailureClass = string(class)
+		info.State = "error"
+	})
+}
+
+// retryRule returns the configured rule for class. Unknown classes get RetryLimit attempts
+// without backoff.
+func (s *ExecuterService) retryRule(class FailureClass) config.RetryRuleConfig {
+	if rule, ok := s.Config.Retry[string(class)]; ok {
+		return rule
+	}
+	return config.RetryRuleConfig{MaxAttempts: s.RetryLimit}
+}
+
+// retryBackoff returns the delay before the given attempt (starting at 1): Backoff doubled for
+// every earlier attempt and capped at MaxBackoff.
+func retryBackoff(rule config.RetryRuleConfig, attempt int) time.Duration {
+	delay := rule.Backoff
+	for i := 1; i < attempt && delay > 0; i++ {
+		delay *= 2
+		if rule.MaxBackoff > 0 && delay >= rule.MaxBackoff {
+			break
+		}
+	}
+	if rule.MaxBackoff > 0 && delay > rule.MaxBackoff {
+		delay = rule.MaxBackoff
+	}
+	return delay
+}
+
+// waitBackoff sleeps for the backoff of the given attempt, returning early if ctx is done.
+func waitBackoff(ctx context.Context, runtimeID string, rule config.RetryRuleConfig, attempt int) error {
+	delay := retryBackoff(rule, attempt)
+	if delay <= 0 {
+		return nil
+	}
+	log.Printf("Backing off %s before retrying runtime %s", delay, runtimeID)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// sendWithRetry asks model for code, retrying failed requests under the llm retry rule. API
+// errors are not retried here: the GPT client already retries those that are transient,
+// honoring Retry-After, and the others are permanent.
+func (s *ExecuterService) sendWithRetry(ctx context.Context, runtimeID string, model string, prompt string) (string, error) {
+	rule := s.retryRule(FailureLLM)
+	client := s.llm(model)
+	ctx = util.WithRuntimeID(ctx, runtimeID)
+	for attempt := 1; ; attempt++ {
+		response, err := client.SendMessage(ctx, prompt)
+		var apiErr *util.GPTAPIError
+		if err == nil || attempt >= rule.MaxAttempts || errors.As(err, &apiErr) || errors.Is(err, util.ErrGenerationUnavailable) {
+			return response, err
+		}
+		log.Printf("LLM request for runtime %s failed (attempt %d of %d): %v", runtimeID, attempt, rule.MaxAttempts, err)
+		if err := waitBackoff(ctx, runtimeID, rule, attempt); err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package executer
+
+//go:generate go run ../../cmd/mock-gen -source routes.go -out mocks.gen.go
+
+// RouteService publishes the runtimes under their prefixes. *routes.DynamicRouteService
+// implements it; RouteServiceMock stands in for it in tests.
+type RouteService interface {
+	// RegisterReverseProxy routes the runtime's prefix, which depends on its tenant, to port.
+	RegisterReverseProxy(runtimeID string, tenant string, port int)
+	// DeregisterReverseProxy stops routing the runtime's prefix, keeping its persisted route.
+	DeregisterReverseProxy(runtimeID string)
+	// RemoveReverseProxy deregisters the runtime and deletes everything persisted about its route.
+	RemoveReverseProxy(runtimeID string) error
+}
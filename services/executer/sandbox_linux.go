@@ -0,0 +1,299 @@
+//go:build linux
+
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/gcottom/aegisx/config"
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv carries the sandboxSpec of a language process to the copy of aegisx that sets the
+// sandbox up and then executes the process in its place.
+const sandboxEnv = "AEGISX_SANDBOX"
+
+// sandboxSpec is what the sandboxed copy of aegisx needs to confine a process. UID and GID are
+// the host user and group it switches to, or 0 to keep those of aegisx.
+type sandboxSpec struct {
+	Command []string `json:"command"`
+	Dir     string   `json:"dir"`
+	Paths   []string `json:"paths"`
+	Limits  []rlimit `json:"limits"`
+	UID     int      `json:"uid"`
+	GID     int      `json:"gid"`
+}
+
+type rlimit struct {
+	Resource int    `json:"resource"`
+	Value    uint64 `json:"value"`
+}
+
+func init() {
+	if encoded, ok := os.LookupEnv(sandboxEnv); ok {
+		enterSandbox(encoded)
+	}
+}
+
+// sandboxCommand returns the command running command in dir confined by sandbox: aegisx runs
+// itself in new mount, PID, IPC and UTS namespaces, where init builds the sandbox and executes
+// command as the sandbox user. Without root, aegisx cannot switch users and runs itself in a
+// new user namespace as well, which confines the command to the sandbox's files as its own user.
+// The command runs in a process group of its own, is killed with aegisx and sees dir at the same
+// path. Its caller appends the environment of the process to cmd.Env.
+func sandboxCommand(ctx context.Context, sandbox config.SandboxConfig, dir string, command []string) (*exec.Cmd, error) {
+	sandbox = sandbox.WithDefaults()
+	spec := sandboxSpec{Command: command, Dir: dir, Paths: sandbox.Paths, Limits: []rlimit{
+		{Resource: unix.RLIMIT_DATA, Value: uint64(sandbox.MaxMemoryMB) << 20},
+		{Resource: unix.RLIMIT_FSIZE, Value: uint64(sandbox.MaxFileSizeMB) << 20},
+		{Resource: unix.RLIMIT_NOFILE, Value: uint64(sandbox.MaxOpenFiles)},
+		{Resource: unix.RLIMIT_NPROC, Value: uint64(sandbox.MaxProcesses)},
+		{Resource: unix.RLIMIT_CPU, Value: uint64(sandbox.MaxCPUSeconds)},
+	}}
+	attr := &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		Setpgid:    true,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	if os.Geteuid() == 0 {
+		spec.UID, spec.GID = sandbox.UID, sandbox.GID
+		if err := os.Chown(dir, spec.UID, spec.GID); err != nil {
+			return nil, fmt.Errorf("failed to give the program directory to the sandbox user: %w", err)
+		}
+	} else {
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+	}
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = []string{"aegisx-sandbox"}
+	cmd.Dir = "/"
+	cmd.Env = []string{sandboxEnv + "=" + string(encoded)}
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		return signalGroup(cmd.Process, syscall.SIGKILL)
+	}
+	return cmd, nil
+}
+
+// signalGroup sends sig to the process group process leads.
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	return syscall.Kill(-process.Pid, sig)
+}
+
+// enterSandbox builds the sandbox described by encoded and executes its command, or exits with
+// status 126 when it cannot. It runs in the namespaces sandboxCommand created, as their root.
+func enterSandbox(encoded string) {
+	// Capabilities are dropped per thread, so the thread that drops them must execute the command.
+	runtime.LockOSThread()
+	var spec sandboxSpec
+	err := json.Unmarshal([]byte(encoded), &spec)
+	if err == nil {
+		err = spec.enter()
+	}
+	fmt.Fprintf(os.Stderr, "aegisx sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// enter confines the process and executes the command; it only returns on failure.
+func (s sandboxSpec) enter() error {
+	if len(s.Command) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	// The program's directory may be under /tmp, so it is opened before /tmp is covered.
+	dirFD, err := unix.Open(s.Dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the program directory: %w", err)
+	}
+	// The new root is a small tmpfs mounted over /tmp, which is hidden once it becomes the root.
+	root := "/tmp"
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755,size=16m"); err != nil {
+		return fmt.Errorf("failed to mount the sandbox root: %w", err)
+	}
+	for _, path := range s.Paths {
+		if err := bindReadOnly(path, filepath.Join(root, path)); err != nil {
+			return err
+		}
+	}
+	for _, device := range []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"} {
+		if err := bindDevice(device, filepath.Join(root, device)); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "tmp"), 0o755); err != nil {
+		return err
+	}
+	if err := os.Chmod(filepath.Join(root, "tmp"), os.ModeSticky|0o777); err != nil {
+		return err
+	}
+	dir := filepath.Join(root, s.Dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount(fmt.Sprintf("/proc/self/fd/%d", dirFD), dir, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("failed to mount the program directory: %w", err)
+	}
+	unix.Close(dirFD)
+	if err := os.MkdirAll(filepath.Join(root, "proc"), 0o555); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("failed to change the root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach the old root: %w", err)
+	}
+	if err := os.Chdir(s.Dir); err != nil {
+		return err
+	}
+	if err := unix.Sethostname([]byte("aegisx")); err != nil {
+		return err
+	}
+	path, err := exec.LookPath(s.Command[0])
+	if err != nil {
+		return err
+	}
+	for _, limit := range s.Limits {
+		if limit.Value == 0 {
+			continue
+		}
+		if err := unix.Setrlimit(limit.Resource, &unix.Rlimit{Cur: limit.Value, Max: limit.Value}); err != nil {
+			return fmt.Errorf("failed to set resource limit %d: %w", limit.Resource, err)
+		}
+	}
+	if err := s.dropPrivileges(); err != nil {
+		return err
+	}
+	// Changing credentials clears the parent death signal.
+	if err := unix.Prctl(unix.PR_SET_PDEATHSIG, uintptr(unix.SIGKILL), 0, 0, 0); err != nil {
+		return err
+	}
+	var env []string
+	for _, entry := range os.Environ() {
+		if !strings.HasPrefix(entry, sandboxEnv+"=") {
+			env = append(env, entry)
+		}
+	}
+	return unix.Exec(path, s.Command, env)
+}
+
+// bindReadOnly mounts path read-only at target, recreating it there when it is a symbolic link.
+// Missing paths are skipped.
+func bindReadOnly(path, target string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+	if err := mountPoint(target, info.IsDir()); err != nil {
+		return err
+	}
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to mount %s: %w", path, err)
+	}
+	// A remount must keep the flags the host locked on the mount.
+	var stat unix.Statfs_t
+	if err := unix.Statfs(target, &stat); err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+	for st, ms := range map[int64]uintptr{unix.ST_NOSUID: unix.MS_NOSUID, unix.ST_NODEV: unix.MS_NODEV, unix.ST_NOEXEC: unix.MS_NOEXEC, unix.ST_NOATIME: unix.MS_NOATIME, unix.ST_NODIRATIME: unix.MS_NODIRATIME, unix.ST_RELATIME: unix.MS_RELATIME} {
+		if int64(stat.Flags)&st != 0 {
+			flags |= ms
+		}
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make %s read-only: %w", path, err)
+	}
+	return nil
+}
+
+// bindDevice mounts the device file path at target.
+func bindDevice(path, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err := mountPoint(target, false); err != nil {
+		return err
+	}
+	if err := unix.Mount(path, target, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("failed to mount %s: %w", path, err)
+	}
+	return nil
+}
+
+// mountPoint creates the directory or empty file a bind mount is mounted on.
+func mountPoint(target string, dir bool) error {
+	if dir {
+		return os.MkdirAll(target, 0o755)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// dropPrivileges switches to the sandbox user, when one is set, and leaves the thread, and the
+// command it executes, without capabilities and unable to gain them.
+func (s sandboxSpec) dropPrivileges() error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	for capability := 0; capability <= unix.CAP_LAST_CAP; capability++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("failed to drop capability %d: %w", capability, err)
+		}
+	}
+	if s.UID != 0 {
+		if err := unix.Setgroups(nil); err != nil {
+			return fmt.Errorf("failed to drop groups: %w", err)
+		}
+		if err := unix.Setresgid(s.GID, s.GID, s.GID); err != nil {
+			return fmt.Errorf("failed to switch to the sandbox group: %w", err)
+		}
+		if err := unix.Setresuid(s.UID, s.UID, s.UID); err != nil {
+			return fmt.Errorf("failed to switch to the sandbox user: %w", err)
+		}
+	}
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capset(&header, &data[0]); err != nil {
+		return fmt.Errorf("failed to drop capabilities: %w", err)
+	}
+	return nil
+}
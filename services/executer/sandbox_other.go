@@ -0,0 +1,22 @@
+//go:build !linux
+
+package executer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/gcottom/aegisx/config"
+)
+
+// sandboxCommand fails: language programs are only run on Linux, whose namespaces sandbox them.
+func sandboxCommand(ctx context.Context, sandbox config.SandboxConfig, dir string, command []string) (*exec.Cmd, error) {
+	return nil, errors.New("language programs can only be sandboxed on linux")
+}
+
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	return process.Signal(sig)
+}
@@ -0,0 +1,51 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// ErrInvalidSchedule is returned for a schedule with a malformed cron expression or time zone.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ValidateSchedule checks the cron expressions and time zone of a schedule.
+func ValidateSchedule(schedule *models.Schedule) error {
+	if schedule.Start == "" && schedule.Stop == "" {
+		return fmt.Errorf("%w: set a start or stop expression", ErrInvalidSchedule)
+	}
+	for _, expr := range []string{schedule.Start, schedule.Stop} {
+		if expr == "" {
+			continue
+		}
+		if _, err := util.ParseCron(expr); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedule, schedule.Timezone)
+	}
+	return nil
+}
+
+// SetSchedule replaces the runtime's schedule. A nil schedule removes it.
+func (s *ExecuterService) SetSchedule(ctx context.Context, runtimeID string, schedule *models.Schedule) error {
+	if schedule != nil {
+		if err := ValidateSchedule(schedule); err != nil {
+			return err
+		}
+	}
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) { info.Schedule = schedule })
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+	return nil
+}
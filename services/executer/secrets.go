@@ -0,0 +1,31 @@
+package executer
+
+import (
+	"errors"
+
+	"github.com/gcottom/aegisx/services/secrets"
+)
+
+// ErrSecretsDisabled is returned for a prompt referencing secrets when no secrets_master_key is
+// configured.
+var ErrSecretsDisabled = errors.New("the prompt references secrets but secrets are disabled, set secrets_master_key")
+
+// checkSecrets fails when prompt references a secret the tenant has not registered.
+func (s *ExecuterService) checkSecrets(tenant string, prompt string) error {
+	if s.Secrets == nil {
+		if len(secrets.References(prompt)) > 0 {
+			return ErrSecretsDisabled
+		}
+		return nil
+	}
+	return s.Secrets.Check(tenant, prompt)
+}
+
+// scrubSecrets replaces the values of the tenant's secrets in text before it is logged or
+// persisted.
+func (s *ExecuterService) scrubSecrets(tenant string, text string) string {
+	if s.Secrets == nil {
+		return text
+	}
+	return s.Secrets.Scrub(tenant, text)
+}
@@ -0,0 +1,74 @@
+package executer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/registry"
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gcottom/aegisx/util"
+)
+
+// recordingClient is an LLM client that keeps the prompts it is sent.
+type recordingClient struct {
+	prompts []string
+}
+
+func (c *recordingClient) SendMessage(ctx context.Context, prompt string) (string, error) {
+	c.prompts = append(c.prompts, prompt)
+	return "", nil
+}
+
+func (c *recordingClient) ModelName() string { return "recording" }
+
+func (c *recordingClient) WithModel(model string) util.LLMClient { return c }
+
+// TestScrubSecretsFromErrors checks that the value of a secret quoted in a runtime's error
+// reaches neither its versions nor the diagnosis model.
+func TestScrubSecretsFromErrors(t *testing.T) {
+	const value = "s3cr3t-api-token"
+	dir := t.TempDir()
+	store, err := secrets.NewStore(dir+"/secrets.json", "master key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put("acme", "API_TOKEN", value); err != nil {
+		t.Fatal(err)
+	}
+	client := &recordingClient{}
+	s := &ExecuterService{
+		Config:          &config.Config{VersionStore: dir},
+		Runtimes:        &registry.MemoryRuntimes{},
+		Secrets:         store,
+		DiagnosisClient: client,
+	}
+	runtime := models.NewRuntime(models.RuntimeInfo{ID: "r1", Tenant: "acme", Code: "package main", LastErrorMsg: "401 Unauthorized for token " + value})
+	s.Runtimes.Store(runtime)
+
+	version, err := s.RecordVersion(runtime, VersionRebuild, runtime.Snapshot().LastErrorMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(version.Reason, value) {
+		t.Errorf("version reason %q contains the secret", version.Reason)
+	}
+	stored, err := s.GetVersion("r1", version.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored.Reason, value) {
+		t.Errorf("stored version reason %q contains the secret", stored.Reason)
+	}
+
+	// The client proposes no fix, so only the prompt it was sent matters.
+	_, _ = s.DiagnoseRuntime(context.Background(), "r1")
+	if len(client.prompts) != 1 {
+		t.Fatalf("the diagnosis model was sent %d prompts, want 1", len(client.prompts))
+	}
+	if strings.Contains(client.prompts[0], value) || !strings.Contains(client.prompts[0], "401 Unauthorized") {
+		t.Errorf("diagnosis prompt does not quote the scrubbed error:\n%s", client.prompts[0])
+	}
+}
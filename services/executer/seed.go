@@ -0,0 +1,143 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/analyzer"
+)
+
+// SeedResult reports how one record of a seed dataset was submitted.
+type SeedResult struct {
+	Index  int    `json:"index"`
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SeedRuntime populates a running runtime with sample data by submitting each record to the
+// app's own endpoints. When route is empty, each record goes to the form route whose fields
+// best match the record's keys.
+func (s *ExecuterService) SeedRuntime(ctx context.Context, runtimeID string, route string, records []map[string]any) ([]SeedResult, error) {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	runtime := runtimeData.Snapshot()
+	if !runtime.PassedHealthCheck {
+		return nil, fmt.Errorf("runtime %s is not healthy", runtimeID)
+	}
+	routes, err := analyzer.AnalyzeRoutes(runtime.Code, models.RuntimePrefix(runtime.Tenant, runtimeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze routes: %w", err)
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		// forms usually redirect after a successful post, which already means the record was stored
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	results := make([]SeedResult, 0, len(records))
+	for i, record := range records {
+		result := SeedResult{Index: i}
+		target, ok := pickSeedRoute(routes, route, record)
+		if !ok {
+			result.Error = "no matching route for record"
+			results = append(results, result)
+			continue
+		}
+		result.Method, result.Path = target.Method, target.Path
+		status, err := submitSeedRecord(ctx, client, runtime.Port, target, record)
+		result.Status = status
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	log.Printf("Seeded runtime %s with %d records", runtimeID, len(records))
+	return results, nil
+}
+
+func pickSeedRoute(routes []analyzer.Route, path string, record map[string]any) (analyzer.Route, bool) {
+	if path != "" {
+		for _, r := range routes {
+			if r.Path == path && (r.Method == "" || r.Method == http.MethodPost) {
+				if r.Method == "" {
+					r.Method = http.MethodPost
+				}
+				return r, true
+			}
+		}
+		return analyzer.Route{Method: http.MethodPost, Path: path}, true
+	}
+	var best analyzer.Route
+	bestScore := 0
+	for _, r := range routes {
+		if r.Method != http.MethodPost && r.Method != "" {
+			continue
+		}
+		score := 0
+		for _, field := range r.Fields {
+			if _, ok := record[field]; ok {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	if best.Method == "" {
+		best.Method = http.MethodPost
+	}
+	return best, bestScore > 0
+}
+
+// submitSeedRecord posts the record as a form when the route is backed by a form and as
+// JSON otherwise.
+func submitSeedRecord(ctx context.Context, client *http.Client, port int, route analyzer.Route, record map[string]any) (int, error) {
+	target := fmt.Sprintf("http://localhost:%d%s", port, route.Path)
+	var body *bytes.Buffer
+	contentType := "application/json"
+	if len(route.Fields) > 0 {
+		form := url.Values{}
+		for key, value := range record {
+			if values, ok := value.([]any); ok {
+				for _, v := range values {
+					form.Add(key, fmt.Sprint(v))
+				}
+				continue
+			}
+			form.Set(key, fmt.Sprint(value))
+		}
+		body = bytes.NewBufferString(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal record: %w", err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(route.Method), target, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return res.StatusCode, fmt.Errorf("app returned %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
@@ -3,80 +3,155 @@ package executer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
 	"github.com/gcottom/aegisx/models"
-	"github.com/gcottom/aegisx/routes"
+	"github.com/gcottom/aegisx/services/analytics"
+	"github.com/gcottom/aegisx/services/browser"
+	"github.com/gcottom/aegisx/services/cache"
+	"github.com/gcottom/aegisx/services/database"
+	"github.com/gcottom/aegisx/services/ids"
+	"github.com/gcottom/aegisx/services/kv"
+	"github.com/gcottom/aegisx/services/moderation"
+	"github.com/gcottom/aegisx/services/notify"
+	"github.com/gcottom/aegisx/services/outbound"
+	"github.com/gcottom/aegisx/services/ports"
+	"github.com/gcottom/aegisx/services/registry"
+	"github.com/gcottom/aegisx/services/resources"
+	"github.com/gcottom/aegisx/services/secrets"
+	"github.com/gcottom/aegisx/services/title"
 	"github.com/gcottom/aegisx/util"
 	"github.com/gcottom/aegisx/validators/code"
-	"github.com/google/uuid"
+	"github.com/traefik/yaegi/interp"
 )
 
 type ExecuterService struct {
-	GPTClient           *util.GPTClient
-	Runtimes            sync.Map
+	GPTClient           util.LLMClient
+	PortAllocator       *ports.PortAllocator
+	IDGenerator         ids.Generator
+	TitleProvider       title.Provider
+	VerificationClient  util.LLMClient // Plans the functional checks run after the health check
+	PostMortemClient    util.LLMClient // Diagnoses runtimes that failed for good
+	DiagnosisClient     util.LLMClient // Proposes fixes for the diagnose endpoint
+	Browser             browser.Checker
+	KV                  *kv.KVService
+	SQLite              *database.SQLiteService
+	Secrets             *secrets.Store     // Nil when no secrets_master_key is set
+	Outbound            *outbound.Proxy    // Forward proxy of the programs' HTTP requests, if enabled
+	Resources           *resources.Sampler // Per-runtime CPU, allocation and goroutine usage, if enabled
+	Interpreters        *util.InterpreterPool
+	Cache               *cache.GenerationCache
+	History             *analytics.Store // Generation attempts, for the analytics endpoint
+	Moderation          *moderation.Screener
+	Notifier            *notify.Dispatcher
+	Targets             []util.LLMClient     // Generation targets the concurrent attempts rotate through
+	Breaker             *util.CircuitBreaker // Shared by GPTClient and Targets, if enabled
+	Examples            []util.Message       // Few-shot turns sent ahead of generation prompts
+	Runtimes            registry.RuntimeRegistry
 	RetryLimit          int
-	DynamicRouteService *routes.DynamicRouteService
+	DynamicRouteService RouteService
 	Config              *config.Config
 	ActiveRetries       sync.Map // Track active retries by runtimeID
+	ExecutionSlots      chan struct{}
+	queued              atomic.Int64
+	inFlight            atomic.Int64
+	paused              atomic.Bool // Set while an operator paused new executions
+	draining            atomic.Bool
+	overloaded          atomic.Bool // Set while the watchdog sheds load
+	logs                sync.Map    // Recent log lines by runtimeID
+	supervisors         sync.Map    // RuntimeSupervisor of the current execution by runtimeID
+	detached            atomic.Bool // Set while a successor process owns the runtime records
+	pendingMu           sync.Mutex
+	pending             map[*pendingExecution]struct{} // Generations in flight, for the duplicate guard
+	idempotencyMu       sync.Mutex
+	idempotent          map[string]*idempotentExecution // Generations by tenant and idempotency key
+	rebuildsMu          sync.Mutex
+	rebuilds            []time.Time // Recent rebuilds, for retry storm notifications
 }
 
-func CreateTitlePrompt(prompt string) string {
-	log.Println("Creating title prompt for base prompt:", prompt)
-	return `You are a concise title generator for Go programs.  
-Your task is to generate a **short, clear title** based on a program prompt.  
+// NewExecuterService returns an executer generating with client, with the in-memory runtime
+// registry, the runtime port range and the generation history cfg configures. The optional
+// features and DynamicRouteService are set by the caller.
+func NewExecuterService(cfg *config.Config, client util.LLMClient, titleProvider title.Provider, kvService *kv.KVService, idGenerator ids.Generator) *ExecuterService {
+	return &ExecuterService{
+		GPTClient:     client,
+		TitleProvider: titleProvider,
+		KV:            kvService,
+		PortAllocator: ports.NewPortAllocator(cfg.RuntimePortMin, cfg.RuntimePortMax),
+		IDGenerator:   idGenerator,
+		Runtimes:      &registry.MemoryRuntimes{},
+		History:       &analytics.Store{File: cfg.GenerationHistoryStore, Retention: cfg.GenerationHistoryTTL},
+		RetryLimit:    3,
+		Config:        cfg,
+	}
+}
 
-**Title Rules:**  
-✅ Titles should be **2 to 5 words** maximum.  
-✅ Use **Title Case** (capitalize major words).  
-✅ **No punctuation**, unless it is a recognized part of a name (e.g., OAuth, JWT).  
-✅ Focus on the **core functionality** or **primary feature**.  
-✅ Use **nouns** or **noun phrases**.  
+// runtimeStartTimeout is how long a program has to start listening on its port.
+const runtimeStartTimeout = 45 * time.Second
 
-**Examples:**  
-- 🛡️ JWT Decoder  
-- 📦 Inventory Manager  
-- 📝 To-Do List  
-- 📊 Stock Tracker  
-- 🌐 Web Server Generator  
-- 📅 Appointment Scheduler  
+// portProbeInterval is how often the port of a starting program is probed until the program
+// logs it, and announcedPortProbeInterval how often afterwards.
+const (
+	portProbeInterval          = 250 * time.Millisecond
+	announcedPortProbeInterval = 10 * time.Millisecond
+)
 
-**Output Format:**  
-Return only the title—no extra commentary.  
-Prompt: ` + prompt
-}
+// maxLogLines is the number of log lines retained per runtime for the logs endpoint.
+const maxLogLines = 1000
 
-func CreatePrompt(prompt string, id string) string {
+// maxOfflinePackagesListed caps the offline packages named in the generation prompt.
+const maxOfflinePackagesListed = 50
+
+// CreatePrompt wraps the user prompt in the generation rules for a runtime served under prefix.
+// extraRequirements are appended to the program instructions for optional host features.
+func CreatePrompt(prompt string, prefix string, port int, extraRequirements ...string) string {
 	log.Println("Creating prompt for base prompt:", prompt)
 	base := `You are a Go expert. Generate a Go program that meets the following requirements:
 🛡️ Core Requirements:
 ✅ Single Page Application (SPA) with a web server.
 ✅ The application should have persistent state and storage management.
+✅ Persist state with the host package: import "` + kv.ImportPath + `" and use kv.Get(key string) (string, bool, error), kv.Put(key, value string) error, kv.Delete(key string) error and kv.Keys() ([]string, error). Store structured values as JSON. Do NOT write files for storage.
 ✅ Export an Shutdown() function with no arguments and no return values.
 ✅ Shutdown() must stop the server and release the port.
 🚫 Do NOT use any global variables.
 🚫 Do NOT use syscall.
+🚫 Do NOT use os/exec, unsafe, os.Exit, or log.Fatal.
 ✅ Use only fmt and net/http for logs and server operations.
 📊 Logging Rules:
 ✅ Use fmt.Println() or fmt.Printf() for logs.
-✅ Log the assigned port as: \"PORT=<selected_port>\"
+✅ Report to aegisx by printing these control lines exactly, each on its own line with fmt.Println:
+   - \"` + util.ControlLine(util.ControlPort, strconv.Itoa(port)) + `\" before starting the server.
+   - \"` + util.ControlLine(util.ControlReady, "") + `\" once setup is done, right before the server starts serving.
+   - \"` + util.ControlLine(util.ControlError, "") + `<message>\" only for a fatal error the program cannot recover from.
 🌐 Web Server Requirements:
-✅ Bind to a random available port.
+✅ Declare exactly: const ` + code.PortConstName + ` = ` + strconv.Itoa(port) + `
+✅ Listen only on that port, e.g. ":" + strconv.Itoa(` + code.PortConstName + `). Do NOT pick a random port.
 ✅ Use http.NewServeMux for all routes.
-✅ ****HTML Form Rule: All HTML form actions must use /runtime/` + id + `/.... ****
+✅ ****HTML Form Rule: All HTML form actions must use ` + prefix + `/.... ****
+✅ ****Frontend Request Rule: All fetch() and XMLHttpRequest URLs must use ` + prefix + `/.... ****
+🚫 Do NOT use eval() or new Function() in JavaScript.
+📁 Static Assets:
+✅ Large HTML/CSS/JS may be returned as separate fenced code blocks labelled with a file name, e.g. ` + "```css app.css" + `.
+✅ aegisx serves them at ` + prefix + `/static/<file name>; reference them with that path and do NOT serve them from Go.
 ✅ Correct Handler Example:
 mux := http.NewServeMux()
 mux.HandleFunc("/hello", helloHandler) // ✅ Correct
 
 🚫 Incorrect Handler Example:
-mux.HandleFunc("/runtime/` + id + `/hello", helloHandler) // ❌ Wrong
-*******Do NOT use the /runtime/` + id + `/ prefix in the handler registration.********
+mux.HandleFunc("` + prefix + `/hello", helloHandler) // ❌ Wrong
+*******Do NOT use the ` + prefix + `/ prefix in the handler registration.********
 		
 💡 Program Instructions:
 Third party packages are permitted, but they must be stable and well-known.
@@ -85,8 +160,11 @@ The program must compile and run as provided.
 The program must be a complete, runnable Go program.
 The front end must be able to fully interact with the backend.
 Animation and css/javascript are permitted
-Implement the above based on the user prompt:
 `
+	for _, requirement := range extraRequirements {
+		base += requirement + "\n"
+	}
+	base += userPromptMarker
 	if strings.Contains(prompt, base) {
 		return prompt
 	} else {
@@ -95,26 +173,50 @@ Implement the above based on the user prompt:
 
 }
 
-func CreateRebuildPrompt(prompt string, errorString string, code string) string {
+// CreateRebuildPrompt asks for a corrected program. guidance is the advice of the retry rule
+// matching the failure class and may be empty.
+func CreateRebuildPrompt(prompt string, errorString string, diagnostics []code.Violation, generatedCode string, port int, guidance string) string {
+	return createRebuildPrompt(prompt, errorString, diagnostics, generatedCode, guidance, "Go", webRebuildRequirements(port))
+}
+
+// createRebuildPrompt is CreateRebuildPrompt for a program in language with the requirements of
+// its kind.
+func createRebuildPrompt(prompt string, errorString string, diagnostics []code.Violation, generatedCode string, guidance string, language string, requirements string) string {
 	log.Println("Creating rebuild prompt due to error: ", errorString)
-	return `You are a Go expert. 
+	if len(diagnostics) > 0 {
+		errorString = "The code failed validation with the following problems:\n" + code.FormatDiagnostics(diagnostics)
+	}
+	if guidance != "" {
+		guidance = "\n💡 HINT:\n" + guidance + "\n"
+	}
+	return `You are a ` + language + ` expert. 
 The following program was generated based on a user prompt but has an error. 
 Please correct the error while adhering to the original prompt and best practices. 
 
 💥 ERROR:
 ` + errorString + `
-
+` + guidance + `
 📝 ORIGINAL CODE:
-` + code + `
+` + generatedCode + `
 
 📝 ORIGINAL PROMPT:
 ` + prompt + `
 
 ✅ REQUIREMENTS:
-- The program must compile and run as provided.
-- Use http.NewServeMux and bind to a random port.
-- Ensure 'PORT=<port>' is logged.
-- Return only the corrected Go program.
+` + requirements
+}
+
+// webRebuildRequirements are the rebuild requirements of a web app listening on port.
+func webRebuildRequirements(port int) string {
+	return serverRebuildRequirements(port) + `- Return only the corrected Go program.
+`
+}
+
+// serverRebuildRequirements are the rebuild requirements of a program serving HTTP on port.
+func serverRebuildRequirements(port int) string {
+	return `- The program must compile and run as provided.
+- Use http.NewServeMux and listen on the assigned port: const ` + code.PortConstName + ` = ` + strconv.Itoa(port) + `.
+- Ensure '` + util.ControlLine(util.ControlPort, strconv.Itoa(port)) + `' and '` + util.ControlLine(util.ControlReady, "") + `' are printed before the server starts serving.
 `
 }
 
@@ -132,49 +234,151 @@ func waitForPassedHealthCheck(ctx context.Context, s *ExecuterService, runtimeID
 			if err != nil {
 				return err
 			}
-			if runtime.PassedHealthCheck {
+			if runtime.HasPassedHealthCheck() {
 				return nil
 			}
-			if runtime.State == "error" || runtime.State == "failed" {
+			if state := runtime.GetState(); state == "error" || state == "failed" {
 				return fmt.Errorf("runtime %s entered error state", runtimeID)
 			}
 		}
 	}
 }
 
-// NewConcurrentExecution spawns 3 concurrent attempts, each with its own context.
+// NewConcurrentExecution spawns 5 concurrent attempts, each with its own context.
 // It returns the runtimeID of the first execution that passes its health check.
-func (s *ExecuterService) NewConcurrentExecution(ctx context.Context, prompt string) (string, error) {
+// Unless opts.Fresh is set, a cached generation for the same prompt is tried first.
+// Without a requested model the attempts rotate through Targets, and the winning
+// runtime records the model that produced it. When prompt variants are configured, the
+// execution picks one for all of its attempts. Prompts rejected by moderation fail with a
+// *moderation.RejectedError before anything is generated. Programs from other code sources
+// than the LLM make a single attempt, which may come without a prompt, and so do jobs and
+// workers, whose programs act on their own and should not run more than once.
+func (s *ExecuterService) NewConcurrentExecution(ctx context.Context, prompt string, opts ExecutionOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if err := s.checkLanguage(opts); err != nil {
+		return "", err
+	}
+	if prompt == "" && !opts.generated() {
+		prompt = describeSource(opts)
+	}
+	if s.ExecutionsPaused() {
+		return "", ErrExecutionsPaused
+	}
+	if s.Overloaded() {
+		return "", ErrOverloaded
+	}
+	if s.Breaker != nil && s.Breaker.RetryAfter() > 0 && opts.generated() {
+		return "", util.ErrGenerationUnavailable
+	}
+	if err := s.Moderation.Screen(ctx, prompt); err != nil {
+		return "", err
+	}
+	if err := s.checkSecrets(opts.Tenant, prompt); err != nil {
+		return "", err
+	}
+	metrics.ExecutionsInFlight.Inc()
+	defer metrics.ExecutionsInFlight.Dec()
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	if s.ExecutionSlots != nil {
+		s.queued.Add(1)
+		select {
+		case s.ExecutionSlots <- struct{}{}:
+			s.queued.Add(-1)
+			defer func() { <-s.ExecutionSlots }()
+		case <-ctx.Done():
+			s.queued.Add(-1)
+			return "", fmt.Errorf("waiting for an execution slot: %w", ctx.Err())
+		}
+	}
+	if err := s.awaitCapacity(ctx); err != nil {
+		return "", err
+	}
+
+	if opts.PromptVariant == "" {
+		opts.PromptVariant = s.pickPromptVariant()
+	}
+	templateVersion := PromptTemplateVersion
+	if opts.PromptVariant != "" {
+		templateVersion += "/" + opts.PromptVariant
+	}
+	if kind := opts.runtimeKind(); kind != "" {
+		templateVersion += "/" + kind
+	}
+	if language := opts.runtimeLanguage(); language != "" {
+		templateVersion += "/" + language
+	}
+	cacheKey := cache.Key(prompt, templateVersion, s.llm(opts.Model).ModelName(), opts.Tenant, s.generationInputs(opts, prompt)...)
+	if s.Cache != nil && !opts.Fresh && opts.generated() {
+		start := time.Now()
+		cachedCtx, counter := util.WithUsageCounter(ctx)
+		runtimeID, err := s.executeCached(cachedCtx, prompt, cacheKey, opts)
+		if !errors.Is(err, errCacheMiss) {
+			s.recordAttempt(cachedCtx, analytics.Attempt{RuntimeID: runtimeID, Tenant: opts.Tenant, Prompt: prompt, Variant: opts.PromptVariant, Model: s.llm(opts.Model).ModelName(), Cached: true}, start, counter, err)
+		}
+		if err == nil {
+			return runtimeID, nil
+		}
+		if !errors.Is(err, errCacheMiss) {
+			log.Printf("Cached generation failed for prompt, generating fresh code: %v", err)
+		}
+	}
+
 	type result struct {
 		runtimeID string
 		err       error
 	}
 	concurrency := 5
+	if !opts.generated() || !listens(opts.runtimeKind()) {
+		// Other sources return the same program every time, and jobs and workers have side
+		// effects.
+		concurrency = 1
+	}
 	results := make(chan result, concurrency)
 	// Keep a slice of cancel functions for each goroutine.
 	var cancels []context.CancelFunc
 	var runtimes []string
+	var runtimesMu sync.Mutex
 
 	for i := 0; i < concurrency; i++ {
 		// Create a new independent context for each execution.
 		newCtx, cancel := context.WithCancel(ctx)
 		cancels = append(cancels, cancel)
+		attemptOpts := opts
+		if attemptOpts.Model == "" && len(s.Targets) > 0 && opts.generated() {
+			attemptOpts.Model = s.Targets[i%len(s.Targets)].ModelName()
+		}
+		metrics.ExecutionAttempts.Inc(s.modelName(attemptOpts))
 
-		go func(ctx context.Context) {
+		go func(ctx context.Context, opts ExecutionOptions, index int) {
+			defer util.Recover("execution", "", func(value any) {
+				results <- result{"", fmt.Errorf("execution attempt panicked: %v", value)}
+			})
+			start := time.Now()
+			ctx, counter := util.WithUsageCounter(ctx)
+			attempt := analytics.Attempt{Tenant: opts.Tenant, Prompt: prompt, Variant: opts.PromptVariant, Model: s.modelName(opts), Attempt: index}
 			// Create a new runtime.
-			runtimeID, err := s.NewExecution(ctx, prompt)
+			runtimeID, err := s.NewExecution(ctx, prompt, opts)
 			if err != nil {
+				s.recordAttempt(ctx, attempt, start, counter, err)
 				results <- result{"", err}
 				return
 			}
+			attempt.RuntimeID = runtimeID
+			runtimesMu.Lock()
 			runtimes = append(runtimes, runtimeID)
+			runtimesMu.Unlock()
 			// Wait until the runtime reports that it passed the health check.
-			if err := waitForPassedHealthCheck(ctx, s, runtimeID); err != nil {
+			err = waitForPassedHealthCheck(ctx, s, runtimeID)
+			s.recordAttempt(ctx, attempt, start, counter, err)
+			if err != nil {
 				results <- result{"", err}
 				return
 			}
 			results <- result{runtimeID, nil}
-		}(newCtx)
+		}(newCtx, attemptOpts, i)
 	}
 
 	var finalErr error
@@ -186,32 +390,45 @@ func (s *ExecuterService) NewConcurrentExecution(ctx context.Context, prompt str
 			for _, cancel := range cancels {
 				cancel()
 			}
-			runtimes = util.RemoveItem(runtimes, res.runtimeID)
-			for _, runtimeID := range runtimes {
+			runtimesMu.Lock()
+			losers := util.RemoveItem(append([]string(nil), runtimes...), res.runtimeID)
+			runtimesMu.Unlock()
+			for _, runtimeID := range losers {
 				s.StopRuntime(ctx, runtimeID)
 				s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
 			}
-			runtimeData, ok := s.Runtimes.Load(res.runtimeID)
+			runtime, ok := s.Runtimes.Load(res.runtimeID)
 			if !ok {
 				return "", fmt.Errorf("runtime not found: %s", res.runtimeID)
 			}
-			runtime := runtimeData.(*models.Runtime)
-			title, err := s.GPTClient.SendMessage(ctx, CreateTitlePrompt(runtime.Prompt))
+			model := runtime.Snapshot().Model
+			log.Printf("Runtime %s generated by model %s won the execution", res.runtimeID, model)
+			metrics.ExecutionWins.Inc(model)
+			runtimeTitle, err := s.TitleProvider.Title(ctx, prompt)
 			if err != nil {
-				return "", fmt.Errorf("failed to get title from GPT: %w", err)
+				return "", err
+			}
+			runtime.Update(func(info *models.RuntimeInfo) { info.Title = runtimeTitle })
+			if opts.generated() {
+				s.cacheGeneration(cacheKey, prompt, runtime)
 			}
-			runtime.Title = title
+			s.notifyRuntime(notify.EventRuntimeHealthy, runtime.Snapshot(), fmt.Sprintf("Runtime %s (%s) is healthy", res.runtimeID, runtimeTitle))
 			return res.runtimeID, nil
 		}
 
 		finalErr = res.err
 	}
+	s.Notifier.Notify(notify.Event{
+		Type:    notify.EventRuntimeFailed,
+		Tenant:  opts.Tenant,
+		Message: fmt.Sprintf("All %d execution attempts failed, last error: %v", concurrency, finalErr),
+	})
 	return "", fmt.Errorf("all concurrent execution attempts failed, last error: %w", finalErr)
 }
 
-func (s *ExecuterService) NewExecution(ctx context.Context, prompt string) (string, error) {
+func (s *ExecuterService) NewExecution(ctx context.Context, prompt string, opts ExecutionOptions) (string, error) {
 	log.Printf("New execution request for prompt: %s", prompt)
-	runtimeID, err := s.PrepareRuntime(ctx, prompt, "")
+	runtimeID, err := s.PrepareRuntime(ctx, prompt, "", opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to prepare runtime: %w", err)
 	}
@@ -222,133 +439,365 @@ func (s *ExecuterService) NewExecution(ctx context.Context, prompt string) (stri
 	return runtimeID, nil
 }
 
-func (s *ExecuterService) PrepareRuntime(ctx context.Context, prompt string, id string) (string, error) {
+func (s *ExecuterService) PrepareRuntime(ctx context.Context, prompt string, id string, opts ExecutionOptions) (string, error) {
 	log.Printf("Preparing runtime for prompt: %s", prompt)
+	start := time.Now()
+	defer func() { metrics.ObserveStage(metrics.StagePrepare, time.Since(start)) }()
+	if err := s.checkSecrets(opts.Tenant, prompt); err != nil {
+		return "", err
+	}
 	if id == "" {
-		id = strings.ReplaceAll(uuid.New().String(), "-", "")
+		id = s.IDGenerator.NewID()
+	}
+	port, err := s.allocatePort(id, opts.runtimeKind())
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate port: %w", err)
 	}
-	prompt = CreatePrompt(prompt, id)
-	generatedCode, err := s.GPTClient.SendMessage(ctx, prompt)
+	var extractedCode string
+	var files map[string]string
+	if opts.generated() {
+		prompt = s.createPrompt(prompt, id, port, opts)
+		extractedCode, files, err = s.generateCode(util.WithRuntimeID(util.WithGenerationParams(ctx, opts.Params), id), s.llm(opts.Model), prompt, opts.runtimeLanguage())
+		if err != nil {
+			s.PortAllocator.Release(id)
+			return "", fmt.Errorf("failed to get code from GPT: %w", err)
+		}
+		log.Printf("Generated code for runtime ID: %s", id)
+	} else if extractedCode, files, err = s.sourceProgram(ctx, id, prompt, port, opts); err != nil {
+		s.PortAllocator.Release(id)
+		return "", err
+	}
+	assets, err := s.replaceAssets(id, files)
 	if err != nil {
-		return "", fmt.Errorf("failed to get code from GPT: %w", err)
+		s.PortAllocator.Release(id)
+		return "", err
 	}
-	log.Printf("Generated code for runtime ID: %s", id)
-	extractedCode := util.ExtractGoCode(generatedCode)
 
-	if err := util.DownloadNonStandardPackages(extractedCode, util.GetYaegiGoPath()); err != nil {
-		return "", fmt.Errorf("failed to download non-standard packages: %w", err)
+	if err := s.resolveDependencies(id, extractedCode); err != nil {
+		// Imports the dependency policy forbids are reported by the validator in
+		// createRuntime, so the rebuild prompt can steer the model away from them.
+		var validationErr *code.ValidationError
+		if !errors.As(err, &validationErr) {
+			s.PortAllocator.Release(id)
+			return "", err
+		}
 	}
 
-	interp, output := util.NewYaegiInterpreter()
+	return s.createRuntime(ctx, id, prompt, extractedCode, assets, port, VersionGenerate, "", opts)
+}
+
+// createRuntime records a new runtime for generated code, validates it and, when
+// generated_tests is set, runs its tests. Failures are handed to HandleRuntimeFailure for a
+// rebuild. source and reason describe the code version being recorded. A regenerated runtime
+// keeps its regeneration count.
+func (s *ExecuterService) createRuntime(ctx context.Context, id string, prompt string, extractedCode string, assets []string, port int, source string, reason string, opts ExecutionOptions) (string, error) {
+	regenerations := 0
+	var group *models.GroupMember
+	if previous, ok := s.Runtimes.Load(id); ok {
+		snapshot := previous.Snapshot()
+		regenerations, group = snapshot.Regenerations, snapshot.Group
+	}
 
-	runtime := &models.Runtime{
-		ID:           id,
-		Prompt:       prompt,
-		State:        models.RSINIT,
-		LastErrorMsg: "",
-		RebuildCount: 0,
-		Code:         extractedCode,
-		CreatedAt:    time.Now(),
-		Executer:     interp,
-		Logs:         output,
+	info := models.RuntimeInfo{
+		ID:              id,
+		Tenant:          opts.Tenant,
+		Prompt:          prompt,
+		State:           models.RSINIT,
+		LastErrorMsg:    "",
+		RebuildCount:    0,
+		Code:            extractedCode,
+		Assets:          assets,
+		Port:            port,
+		FailureStrategy: opts.Strategy,
+		Model:           s.modelName(opts),
+		PromptVariant:   opts.PromptVariant,
+		Regenerations:   regenerations,
+		CreatedAt:       time.Now(),
+		// Rebuilds and regenerations reuse the generation parameters of the request.
+		GenerationParams: opts.Params,
+		NoOutbound:       opts.NoOutbound,
+		Kind:             opts.runtimeKind(),
+		Language:         opts.runtimeLanguage(),
+		Env:              opts.Env,
+		Group:            group,
 	}
-	s.Runtimes.Store(runtime.ID, runtime)
+	if !opts.generated() {
+		info.Source = opts.Source
+	}
+	info.Executer, info.Logs = s.newProgram(info)
+	runtime := models.NewRuntime(info)
+	s.recordVersion(runtime, source, reason)
+	s.Runtimes.Store(runtime)
 	if err := s.SaveExecuter(ctx, runtime); err != nil {
 		return "", fmt.Errorf("failed to save runtime: %w", err)
 	}
 
-	if err := code.DefaultValidator(id).Validate(extractedCode); err != nil {
-		log.Printf("Code validation failed for runtime ID: %s, error: %v", runtime.ID, err)
-		runtime.LastErrorMsg = fmt.Sprintf("code validation failed: %v", err)
-		runtime.State = "error"
-		s.Runtimes.Store(runtime.ID, runtime)
-		go s.HandleRuntimeFailure(ctx, id)
-		return "", fmt.Errorf("code validation failed: %v", err)
+	validator, err := s.newValidator(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to build code validator: %w", err)
 	}
-	return runtime.ID, nil
+	validationStart := time.Now()
+	err = validator.Validate(extractedCode)
+	metrics.ObserveStage(metrics.StageValidation, time.Since(validationStart))
+	if err != nil {
+		log.Printf("Code validation failed for runtime ID: %s, error: %v", id, err)
+		metrics.RuntimeFailures.Inc("validation")
+		var validationErr *code.ValidationError
+		if errors.As(err, &validationErr) {
+			runtime.Update(func(info *models.RuntimeInfo) { info.Diagnostics = validationErr.Violations })
+		}
+		s.markFailed(runtime, FailureValidation, fmt.Sprintf("code validation failed: %v", err))
+		util.Go("failure-handler", id, func() { s.HandleRuntimeFailure(ctx, id) })
+		return "", fmt.Errorf("code validation failed: %w", err)
+	}
+	// Generated tests are Go functions the program exports.
+	if s.Config.GeneratedTests && info.Language == "" {
+		if class, err := s.runGeneratedTests(ctx, runtime); err != nil {
+			metrics.RuntimeFailures.Inc(string(class))
+			s.markFailed(runtime, class, err.Error())
+			util.Go("failure-handler", id, func() { s.HandleRuntimeFailure(ctx, id) })
+			return "", fmt.Errorf("generated tests failed: %v", err)
+		}
+	}
+	return id, nil
 }
 
+// ExecuteRuntime starts the runtime's program under a new RuntimeSupervisor. The program is
+// evaluated while a monitor forwards its logs, registers its route once the port is listening
+// and, when verify_runtimes or browser_smoke_test is set, runs functional and browser checks
+// after the health check; a watchdog fails it if the port never opens. A failure cancels all
+// three and hands the runtime to HandleRuntimeFailure. A job is not probed: it passes once its
+// program returns within job_timeout, if set, and its output becomes the runtime's result. A
+// worker passes once it reports readiness, and fails when its heartbeats stop for
+// worker_heartbeat_timeout.
 func (s *ExecuterService) ExecuteRuntime(ctx context.Context, runtimeID string) error {
 	log.Printf("Executing runtime: %s", runtimeID)
-	runtime, ok := s.Runtimes.Load(runtimeID)
+	runtimeData, ok := s.Runtimes.Load(runtimeID)
 	if !ok {
 		return fmt.Errorf("runtime not found: %s", runtimeID)
 	}
-	runtimeData := runtime.(*models.Runtime)
-	runtimeData.State = models.RSRUN
-	ctx2, cancel := context.WithCancel(context.Background())
-	runtimeData.StopFunction = cancel
-	runtimeData.StartedAt = time.Now()
-	s.Runtimes.Store(runtimeID, runtimeData)
-	go func() {
-		var err error
-		defer func() {
-			if r := recover(); r != nil {
-				err = fmt.Errorf("runtime panicked: %v", r)
-				log.Printf("Runtime panicked for executer with ID: %s err: %s", runtimeID, err)
-			}
-			if err != nil && err.Error() != "context canceled" {
-				runtimeData.LastErrorMsg = err.Error()
-				log.Printf("Runtime failed for executer with ID: %s err: %s", runtimeID, err)
-				runtimeData.State = "error"
-				s.Runtimes.Store(runtimeID, runtimeData)
-				s.HandleRuntimeFailure(ctx, runtimeID)
-			} else {
-				log.Printf("Runtime finished successfully for executer with ID: %s", runtimeID)
-				runtimeData.State = "finished"
-				runtimeData.FinishedAt = time.Now()
-				s.Runtimes.Store(runtimeID, runtimeData)
+	supervisor := s.supervise(runtimeID)
+	var code string
+	var port int
+	var executer models.Program
+	var output *util.LogWriter
+	var kind string
+	startedAt := time.Now()
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.State = models.RSRUN
+		info.StartedAt = startedAt
+		info.Result = nil
+		code, port, executer, output, kind = info.Code, info.Port, info.Executer, info.Logs, info.Kind
+	})
+	metrics.RuntimesStarted.Inc()
+
+	// fail records a failure and stops the execution. Failure handling stops the supervisor
+	// before rebuilding, so it has to run outside of the supervised goroutines.
+	fail := func(class FailureClass, msg string) {
+		s.markFailed(runtimeData, class, msg)
+		supervisor.Cancel()
+		util.Go("failure-handler", runtimeID, func() { s.HandleRuntimeFailure(ctx, runtimeID) })
+	}
+	// A panic in aegisx's own supervision is not the program's fault, so it is not rebuilt.
+	supervisor.OnPanic = func(value any) {
+		metrics.RuntimeFailures.Inc(string(FailurePanic))
+		s.markFailed(runtimeData, FailurePanic, fmt.Sprintf("aegisx panicked while supervising the runtime: %v", value))
+	}
+
+	// The log pump follows the control lines of the program: announced is signalled when it
+	// reports its port or readiness, ready when it reports readiness and heartbeat on every
+	// heartbeat.
+	lines, unsubscribe := output.Subscribe()
+	announced, ready, heartbeat := make(chan struct{}, 1), make(chan struct{}, 1), make(chan struct{}, 1)
+	supervisor.Go(func(runCtx context.Context) {
+		defer unsubscribe()
+		for {
+			select {
+			case <-runCtx.Done():
+				// Keep what the program wrote before it was stopped, such as the error it failed with.
+				for {
+					select {
+					case line := <-lines:
+						s.appendLog(runtimeID, line)
+					default:
+						return
+					}
+				}
+			case line := <-lines:
+				s.appendLog(runtimeID, line)
+				message, ok := util.ParseControlLine(line)
+				if !ok {
+					continue
+				}
+				switch message.Kind {
+				case util.ControlPort:
+					if message.Port != port {
+						log.Printf("Runtime %s announced port %d instead of its assigned port %d", runtimeID, message.Port, port)
+						continue
+					}
+					signal(announced)
+				case util.ControlReady:
+					signal(announced)
+					signal(ready)
+				case util.ControlHeartbeat:
+					runtimeData.Update(func(info *models.RuntimeInfo) { info.LastHeartbeat = time.Now() })
+					signal(heartbeat)
+				case util.ControlError:
+					if runCtx.Err() == nil {
+						log.Printf("Runtime %s reported a fatal error: %s", runtimeID, message.Error)
+						metrics.RuntimeFailures.Inc(string(FailureReported))
+						fail(FailureReported, "the program reported a fatal error: "+message.Error)
+					}
+				}
 			}
-			cancel()
-		}()
-		execDone := make(chan error, 1)
-		isRegistered := false
-		go func() {
+		}
+	})
+
+	var listening atomic.Bool
+	if listens(kind) {
+		supervisor.Go(func(runCtx context.Context) {
+			probe := time.NewTicker(portProbeInterval)
+			defer probe.Stop()
 			for {
 				select {
-				case <-execDone:
+				case <-runCtx.Done():
+					return
+				case <-announced:
+					// Programs announce their port just before they listen on it.
+					probe.Reset(announcedPortProbeInterval)
+					continue
+				case <-probe.C:
+				}
+				if !util.IsPortListening(port) {
+					continue
+				}
+				log.Printf("Runtime started successfully for executer with ID: %s on port: %d", runtimeID, port)
+				metrics.ObserveStage(metrics.StageEvalToPort, time.Since(startedAt))
+				runtimeData.SetState("running")
+				s.DynamicRouteService.RegisterReverseProxy(runtimeID, runtimeData.Snapshot().Tenant, port)
+				listening.Store(true)
+				// A program that reports readiness is checked at once, others get time to finish setup.
+				select {
+				case <-runCtx.Done():
 					return
-				case <-ctx2.Done():
+				case <-ready:
+				case <-time.After(10 * time.Second):
+				}
+				if !util.RuntimeHealthCheck(runtimeID, port) {
+					log.Printf("Runtime health check failed for executer with ID: %s", runtimeID)
+					metrics.RuntimeFailures.Inc("healthcheck")
+					fail(FailureHealthCheck, "runtime root endpoint was inaccessible")
 					return
-				default:
-					port := util.ExtractPort(runtimeData.Logs.String())
-					if port > 0 && !isRegistered {
-						runtimeData.Port = port
-						runtimeData.Logs.Reset()
-						log.Printf("Runtime started successfully for executer with ID: %s on port: %d", runtimeID, port)
-						runtimeData.State = "running"
-						s.Runtimes.Store(runtimeID, runtimeData)
-						s.DynamicRouteService.RegisterReverseProxy(runtimeID, port)
-						isRegistered = true
-						time.Sleep(10 * time.Second)
-						if !util.RuntimeHealthCheck(runtimeID) {
-							log.Printf("Runtime health check failed for executer with ID: %s", runtimeID)
-							runtimeData.LastErrorMsg = "runtime root endpoint was inaccessible"
-							runtimeData.State = "error"
-							s.Runtimes.Store(runtimeID, runtimeData)
-							go s.HandleRuntimeFailure(ctx, runtimeID)
-							cancel()
-						} else {
-							log.Printf("Runtime health check passed for executer with ID: %s", runtimeID)
-							runtimeData.PassedHealthCheck = true
-							s.Runtimes.Store(runtimeID, runtimeData)
-						}
-					} else {
-						logData := runtimeData.Logs.String()
-						logLines := strings.Split(logData, "\n")
-						for _, line := range logLines {
-							if line == "" {
-								continue
-							}
-							log.Printf("executer ID: %s log: %s", runtimeID, line)
-						}
-						runtimeData.Logs.Reset()
-						time.Sleep(10 * time.Millisecond)
-
+				}
+				log.Printf("Runtime health check passed for executer with ID: %s", runtimeID)
+				if s.Config.VerifyRuntimes {
+					report, ok := s.verifyRuntime(runCtx, runtimeData)
+					if runCtx.Err() != nil {
+						return
+					}
+					runtimeData.Update(func(info *models.RuntimeInfo) { info.Verification = report })
+					if !ok {
+						log.Printf("Runtime verification failed for executer with ID: %s", runtimeID)
+						metrics.RuntimeFailures.Inc("verification")
+						fail(FailureVerification, verificationFailure(report))
+						return
 					}
 				}
+				// APIs have no pages to load in a browser.
+				if s.Config.BrowserSmokeTest && s.Browser != nil && kind != KindAPI {
+					report, ok := s.browserSmokeTest(runCtx, runtimeData)
+					if runCtx.Err() != nil {
+						return
+					}
+					runtimeData.Update(func(info *models.RuntimeInfo) { info.Browser = report })
+					if !ok {
+						log.Printf("Browser smoke test failed for executer with ID: %s", runtimeID)
+						metrics.RuntimeFailures.Inc("browser")
+						fail(FailureBrowser, browserFailure(report))
+						return
+					}
+				}
+				if s.Config.Thumbnails && s.Browser != nil && kind != KindAPI {
+					thumbnail := s.captureThumbnail(runCtx, runtimeData)
+					if runCtx.Err() != nil {
+						return
+					}
+					runtimeData.Update(func(info *models.RuntimeInfo) { info.Thumbnail = thumbnail })
+				}
+				metrics.RuntimesHealthy.Inc()
+				runtimeData.Update(func(info *models.RuntimeInfo) { info.PassedHealthCheck = true })
+				return
 			}
-		}()
+		})
+
+		supervisor.Go(func(runCtx context.Context) {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-time.After(runtimeStartTimeout):
+			}
+			if listening.Load() {
+				return
+			}
+			log.Printf("Runtime execution timed out for executer ID: %s", runtimeID)
+			metrics.RuntimeFailures.Inc("timeout")
+			fail(FailureTimeout, fmt.Sprintf("runtime never started listening on port %d", port))
+		})
+	} else if kind == KindJob && s.Config.JobTimeout > 0 {
+		supervisor.Go(func(runCtx context.Context) {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-time.After(s.Config.JobTimeout):
+			}
+			log.Printf("Job timed out for executer ID: %s", runtimeID)
+			metrics.RuntimeFailures.Inc("timeout")
+			fail(FailureTimeout, fmt.Sprintf("the job did not finish within %s", s.Config.JobTimeout))
+		})
+	} else if kind == KindWorker {
+		supervisor.Go(func(runCtx context.Context) {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ready:
+			case <-time.After(runtimeStartTimeout):
+				log.Printf("Worker never reported readiness for executer ID: %s", runtimeID)
+				metrics.RuntimeFailures.Inc("timeout")
+				fail(FailureTimeout, "the worker never reported readiness")
+				return
+			}
+			log.Printf("Worker reported readiness for executer with ID: %s", runtimeID)
+			metrics.RuntimesHealthy.Inc()
+			runtimeData.Update(func(info *models.RuntimeInfo) { info.PassedHealthCheck = true })
+			timeout := s.Config.WorkerHeartbeatTimeout
+			if timeout <= 0 {
+				return
+			}
+			missed := time.NewTimer(timeout)
+			defer missed.Stop()
+			for {
+				select {
+				case <-runCtx.Done():
+					return
+				case <-heartbeat:
+					missed.Reset(timeout)
+				case <-missed.C:
+					log.Printf("Worker missed its heartbeat for executer with ID: %s", runtimeID)
+					metrics.RuntimeFailures.Inc("healthcheck")
+					fail(FailureHealthCheck, fmt.Sprintf("the worker sent no heartbeat for %s", timeout))
+					return
+				}
+			}
+		})
+	}
+
+	var captured func() (string, bool)
+	if kind == KindJob {
+		captured = output.Capture(maxJobOutputBytes)
+	}
+
+	supervisor.Go(func(runCtx context.Context) {
+		var err error
 		//die god panic!
 		func() {
 			defer func() {
@@ -358,45 +807,85 @@ func (s *ExecuterService) ExecuteRuntime(ctx context.Context, runtimeID string)
 				}
 			}()
 			log.Println("Executing code in runtime")
-			go func() {
-				time.Sleep(45 * time.Second)
-				if !isRegistered {
-					cancel()
-					s.StopRuntime(ctx, runtimeID)
-					log.Printf("Runtime execution timed out for executer ID: %s", runtimeID)
-					err = fmt.Errorf("runtime never logged a port")
-					runtimeData.LastErrorMsg = err.Error()
-					runtimeData.State = "error"
-					s.Runtimes.Store(runtimeID, runtimeData)
-					s.HandleRuntimeFailure(ctx, runtimeID)
-				}
-			}()
-			_, err = runtimeData.Executer.EvalWithContext(ctx2, runtimeData.Code)
+			resources.Do(runCtx, runtimeID, func(ctx context.Context) {
+				err = executer.Run(ctx, code)
+			})
 		}()
-
-		execDone <- err
-
-	}()
+		output.Flush()
+		// A cancelled, stopped or killed execution was ended elsewhere, which sets its state.
+		if state := runtimeData.GetState(); runCtx.Err() != nil || state == models.RSKILL || state == models.RSSTOPPING {
+			return
+		}
+		if err != nil {
+			log.Printf("Runtime failed for executer with ID: %s err: %s", runtimeID, err)
+			metrics.RuntimeFailures.Inc("eval")
+			fail(classifyEvalError(err), err.Error())
+			return
+		}
+		if kind == KindWorker {
+			// A worker runs until it is stopped, so returning on its own means it broke down.
+			log.Printf("Worker exited on its own for executer with ID: %s", runtimeID)
+			metrics.RuntimeFailures.Inc("healthcheck")
+			fail(FailureHealthCheck, "the worker returned from main without being stopped")
+			return
+		}
+		log.Printf("Runtime finished successfully for executer with ID: %s", runtimeID)
+		var result *models.JobResult
+		if captured != nil {
+			var reported string
+			out, truncated := captured()
+			if result, reported = s.jobResult(runtimeData.GetTenant(), out, truncated); reported != "" {
+				// The log pump may not have seen the error before the program returned.
+				metrics.RuntimeFailures.Inc(string(FailureReported))
+				fail(FailureReported, "the program reported a fatal error: "+reported)
+				return
+			}
+		}
+		runtimeData.Update(func(info *models.RuntimeInfo) {
+			info.State = "finished"
+			info.FinishedAt = time.Now()
+			if result != nil {
+				// A job passes by running to completion.
+				info.Result = result
+				info.PassedHealthCheck = true
+			}
+		})
+		supervisor.Cancel()
+		if result != nil {
+			if err := s.SaveExecuter(context.Background(), runtimeData); err != nil {
+				log.Printf("failed to save the result of job %s: %v", runtimeID, err)
+			}
+		}
+	})
 	return nil
 }
 
-func (s *ExecuterService) StopRuntime(ctx context.Context, runtimeID string) error {
-	runtime, ok := s.Runtimes.Load(runtimeID)
-	if !ok {
-		return fmt.Errorf("runtime not found: %s", runtimeID)
+// signal wakes the receiver of ch without blocking when a wakeup is already pending.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
 	}
-	runtimeData := runtime.(*models.Runtime)
-	if runtimeData.Executer != nil {
-		_, _ = runtimeData.Executer.Eval("Shutdown()")
+}
+
+// appendLog records a line of the program's output in the log ring, from which the logs
+// endpoint and live subscribers read it, and in the service log.
+func (s *ExecuterService) appendLog(runtimeID string, line string) {
+	if line == "" {
+		return
 	}
-	s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
-	time.Sleep(15 * time.Second)
-	if runtimeData.StopFunction != nil {
-		runtimeData.StopFunction()
+	if runtime, ok := s.Runtimes.Load(runtimeID); ok {
+		line = s.scrubSecrets(runtime.GetTenant(), line)
 	}
-	runtimeData.State = "stopped"
-	s.Runtimes.Store(runtimeID, runtimeData)
-	return nil
+	log.Printf("executer ID: %s log: %s", runtimeID, line)
+	s.logRing(runtimeID).Append(line)
+}
+
+// Retrying reports whether the runtime's failure is being handled, which may rebuild or
+// regenerate it.
+func (s *ExecuterService) Retrying(runtimeID string) bool {
+	_, ok := s.ActiveRetries.Load(runtimeID)
+	return ok
 }
 
 func (s *ExecuterService) HandleRuntimeFailure(ctx context.Context, runtimeID string) error {
@@ -406,7 +895,11 @@ func (s *ExecuterService) HandleRuntimeFailure(ctx context.Context, runtimeID st
 		return nil
 	}
 	defer s.ActiveRetries.Delete(runtimeID) // Remove lock after retry attempt.
+	return s.handleRuntimeFailure(ctx, runtimeID)
+}
 
+// handleRuntimeFailure is HandleRuntimeFailure for a caller holding the runtime's retry lock.
+func (s *ExecuterService) handleRuntimeFailure(ctx context.Context, runtimeID string) error {
 	// Check if the parent context is already canceled.
 	select {
 	case <-ctx.Done():
@@ -415,90 +908,389 @@ func (s *ExecuterService) HandleRuntimeFailure(ctx context.Context, runtimeID st
 	}
 
 	log.Printf("Handling failure for runtime: %s", runtimeID)
-	runtime, ok := s.Runtimes.Load(runtimeID)
+	runtimeData, ok := s.Runtimes.Load(runtimeID)
 	if !ok {
 		return fmt.Errorf("runtime not found: %s", runtimeID)
 	}
-	runtimeData := runtime.(*models.Runtime)
+	info := runtimeData.Snapshot()
+	if info.State == models.RSKILL {
+		log.Printf("Runtime %s was killed, skipping failure handling", runtimeID)
+		return nil
+	}
 
-	// Stop if retry limit is reached.
-	if runtimeData.RebuildCount >= s.RetryLimit {
-		log.Printf("Retry limit reached for runtime %s: %d attempts", runtimeID, s.RetryLimit)
-		runtimeData.State = "failed"
-		s.Runtimes.Store(runtimeID, runtimeData)
-		s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
-		if _, err := s.PrepareRuntime(ctx, runtimeData.Prompt, runtimeID); err != nil {
-			return fmt.Errorf("failed to prepare runtime after reaching retry limit: %w", err)
-		}
-		log.Printf("Rebuilding runtime %s after reaching retry limit", runtimeID)
-		return s.ExecuteRuntime(ctx, runtimeID)
+	// Rebuilds ask the LLM to repair the program, which only makes sense for one it generated.
+	if info.Source != "" {
+		log.Printf("Runtime %s comes from the %s source, skipping rebuilds", runtimeID, info.Source)
+		s.giveUp(runtimeData)
+		return nil
+	}
+
+	// Once the retry limit for this class of failure is reached, the strategy decides
+	// whether to give up or start over from the original prompt.
+	class := FailureClass(info.FailureClass)
+	if info.Kind == KindJob && jobRan(info, class) {
+		log.Printf("Job %s failed after it started running, skipping rebuilds", runtimeID)
+		s.giveUp(runtimeData)
+		return nil
+	}
+	rule := s.retryRule(class)
+	limitReached := info.FailureCounts[string(class)] >= rule.MaxAttempts
+	if limitReached {
+		log.Printf("Retry limit reached for runtime %s: %d %s attempts", runtimeID, rule.MaxAttempts, class)
+	}
+	switch strategy := s.failureStrategy(runtimeData); {
+	case strategy == StrategyRegenerate, strategy == StrategyHybrid && limitReached:
+		return s.regenerateRuntime(ctx, runtimeData)
+	case limitReached:
+		s.giveUp(runtimeData)
+		return nil
 	}
 
 	// Increment retry count.
-	runtimeData.RebuildCount++
-	log.Printf("Retrying runtime %s (attempt %d of %d)", runtimeID, runtimeData.RebuildCount, s.RetryLimit)
+	var attempt int
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		if info.FailureCounts == nil {
+			info.FailureCounts = map[string]int{}
+		}
+		info.FailureCounts[string(class)]++
+		info.RebuildCount++
+		attempt = info.FailureCounts[string(class)]
+	})
+	s.noteRebuild()
+	log.Printf("Retrying runtime %s after %s failure (attempt %d of %d)", runtimeID, class, attempt, rule.MaxAttempts)
+	if err := waitBackoff(ctx, runtimeID, rule, attempt); err != nil {
+		return fmt.Errorf("retry of runtime %s canceled: %w", runtimeID, err)
+	}
 
 	// Shutdown previous runtime before retrying.
-	if runtimeData.Executer != nil {
-		_, _ = runtimeData.Executer.Eval("Shutdown()")
+	s.stopSupervisor(runtimeID)
+	if info.Executer != nil {
+		_ = info.Executer.Shutdown(context.Background())
 	}
 	s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
 
+	// The port may have been released if the runtime was stopped after timing out.
+	port, err := s.allocatePort(runtimeID, info.Kind)
+	if err != nil {
+		return fmt.Errorf("failed to allocate port: %w", err)
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) { info.Port = port })
+
 	// Request corrected code from GPT using the provided context.
-	prompt := CreateRebuildPrompt(runtimeData.Prompt, runtimeData.LastErrorMsg, runtimeData.Code)
-	code, err := s.GPTClient.SendMessage(ctx, prompt)
+	extractedCode, files, err := s.rebuildCode(ctx, info, port, rule.Guidance)
 	if err != nil {
+		metrics.RuntimeFailures.Inc("llm")
+		s.markFailed(runtimeData, FailureLLM, fmt.Sprintf("failed to get code from GPT: %v", err))
 		return fmt.Errorf("failed to get code from GPT: %w", err)
 	}
 
 	// Rebuild runtime with corrected code.
-	interp, output := util.NewYaegiInterpreter()
-	extractedCode := util.ExtractGoCode(code)
-	runtimeData.Code = extractedCode
-	runtimeData.State = "rebuilding"
-	runtimeData.LastErrorMsg = ""
-	runtimeData.Executer = interp
-	runtimeData.Logs = output
-	s.Runtimes.Store(runtimeID, runtimeData)
+	program, output := s.newProgram(info)
+	// The model returns the assets it changed; the others are kept.
+	assets := info.Assets
+	if files != nil {
+		if assets, err = s.updateAssets(runtimeID, info.Assets, files); err != nil {
+			return err
+		}
+	}
+	err = s.resolveDependencies(runtimeID, extractedCode)
+	if err == nil {
+		err = s.validateEgress(info, extractedCode)
+	}
+	if err != nil {
+		var validationErr *code.ValidationError
+		if !errors.As(err, &validationErr) {
+			s.markFailed(runtimeData, FailureCompile, err.Error())
+			return err
+		}
+		// Retry right away, showing the model the imports or connections the policies forbid.
+		runtimeData.Update(func(info *models.RuntimeInfo) {
+			info.Code = extractedCode
+			info.Diagnostics = validationErr.Violations
+		})
+		s.markFailed(runtimeData, FailureValidation, err.Error())
+		return s.handleRuntimeFailure(ctx, runtimeID)
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.Code = extractedCode
+		info.Assets = assets
+	})
+	s.recordVersion(runtimeData, VersionRebuild, info.LastErrorMsg)
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.State = "rebuilding"
+		info.PassedHealthCheck = false
+		info.LastErrorMsg = ""
+		info.FailureClass = ""
+		info.Diagnostics = nil
+		info.Executer = program
+		info.Logs = output
+	})
+
+	if s.Config.GeneratedTests {
+		if class, err := s.runGeneratedTests(ctx, runtimeData); err != nil {
+			metrics.RuntimeFailures.Inc(string(class))
+			s.markFailed(runtimeData, class, err.Error())
+			return s.handleRuntimeFailure(ctx, runtimeID)
+		}
+	}
 
 	// Execute the rebuilt runtime using the parent's context.
 	return s.ExecuteRuntime(ctx, runtimeID)
 }
+
+// modelName returns the model generating the runtimes of opts, or the code source standing in
+// for it.
+func (s *ExecuterService) modelName(opts ExecutionOptions) string {
+	if !opts.generated() {
+		return opts.Source
+	}
+	return s.llm(opts.Model).ModelName()
+}
+
+// llm returns the generation client for model, or the configured one when model is empty.
+// A model listed in Targets uses that target's provider.
+func (s *ExecuterService) llm(model string) util.LLMClient {
+	if model == "" || model == s.GPTClient.ModelName() {
+		return s.GPTClient
+	}
+	for _, target := range s.Targets {
+		if target.ModelName() == model {
+			return target
+		}
+	}
+	return s.GPTClient.WithModel(model)
+}
+
+// newInterpreter creates an interpreter for the runtime with the host packages bound to it,
+// the secrets of its tenant and its egress policy.
+func (s *ExecuterService) newInterpreter(info models.RuntimeInfo) (*interp.Interpreter, *util.LogWriter) {
+	var exports []interp.Exports
+	if s.KV != nil {
+		exports = append(exports, s.KV.Exports(info.ID))
+	}
+	if s.SQLite != nil {
+		exports = append(exports, s.SQLite.Exports(info.ID))
+	}
+	if s.Secrets != nil {
+		exports = append(exports, s.Secrets.Exports(info.Tenant))
+	}
+	if len(info.Env) > 0 {
+		exports = append(exports, envExports(info.Env))
+	}
+	if policy := s.egressPolicy(info.NoOutbound); policy.Restricted() || s.Outbound != nil {
+		exports = append(exports, s.egressExports(policy, info.ID))
+	}
+	exports = append(exports, s.guardExports(info.ID))
+	return s.Interpreters.Get(s.goPath(info.ID), exports...)
+}
+
+// promptRequirements describes the optional host packages available to generated programs,
+// including the secrets prompt references and the egress policy, followed by the instructions
+// of the prompt variant.
+// generationInputs are what the model is sent besides the prompt and its template: the
+// system prompt, the few-shot examples and the requirements added for the host features.
+func (s *ExecuterService) generationInputs(opts ExecutionOptions, prompt string) []string {
+	examples, _ := json.Marshal(s.Examples)
+	return append([]string{s.Config.SystemPrompt, string(examples)}, s.promptRequirements(opts, prompt)...)
+}
+
+func (s *ExecuterService) promptRequirements(opts ExecutionOptions, prompt string) []string {
+	var requirements []string
+	if s.Config.OfflineMode {
+		requirement := "aegisx runs offline and cannot download packages: use only the Go standard library"
+		if packages := util.OfflinePackages(s.Config.OfflinePackageCache); len(packages) > 0 {
+			if len(packages) > maxOfflinePackagesListed {
+				packages = packages[:maxOfflinePackagesListed]
+			}
+			requirement += " and these pre-installed packages: " + strings.Join(packages, ", ")
+		}
+		requirements = append(requirements, requirement+". Do NOT load scripts, styles or fonts from CDNs.")
+	}
+	if s.Config.StructuredOutput {
+		requirements = append(requirements, `Respond with a JSON object {"files": [{"name": "...", "content": "..."}]}: the Go program as main.go and every static asset under its file name, instead of fenced code blocks.`)
+	}
+	if s.SQLite != nil {
+		requirements = append(requirements, `A dedicated SQLite database is provisioned for this app: import "`+database.ImportPath+`" and call db.Open() (*sql.DB, error) to use it with database/sql. Create your tables with CREATE TABLE IF NOT EXISTS on startup and do NOT import a SQLite driver yourself.`)
+	}
+	if requirement := secrets.Requirement(prompt); requirement != "" && s.Secrets != nil {
+		requirements = append(requirements, requirement)
+	}
+	if requirement := s.egressRequirement(opts.NoOutbound); requirement != "" {
+		requirements = append(requirements, requirement)
+	}
+	if requirement := envRequirement("", opts.Env); requirement != "" {
+		requirements = append(requirements, requirement)
+	}
+	if s.Config.GeneratedTests {
+		requirements = append(requirements, generatedTestRequirement)
+	}
+	if instructions := s.variantInstructions(opts.PromptVariant); instructions != "" {
+		requirements = append(requirements, instructions)
+	}
+	return requirements
+}
+
+// DeleteRuntimeData removes everything a runtime persisted outside its record: key-value
+// data, its SQLite database, its static assets and its module. Its cached outbound responses
+// are dropped too, as is its sampled resource usage.
+func (s *ExecuterService) DeleteRuntimeData(runtimeID string) error {
+	if s.Outbound != nil {
+		s.Outbound.DeleteRuntime(runtimeID)
+	}
+	if s.Resources != nil {
+		s.Resources.DeleteRuntime(runtimeID)
+	}
+	if s.KV != nil {
+		if err := s.KV.DeleteRuntime(runtimeID); err != nil {
+			return err
+		}
+	}
+	if s.SQLite != nil {
+		if err := s.SQLite.Remove(runtimeID); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(s.StaticDir(runtimeID)); err != nil {
+		return fmt.Errorf("failed to remove static assets: %w", err)
+	}
+	if err := os.RemoveAll(s.programDir(runtimeID)); err != nil {
+		return fmt.Errorf("failed to remove program directory: %w", err)
+	}
+	return s.removeModule(runtimeID)
+}
+
+// RuntimeResources returns the runtime's latest sampled resource usage, or nil when sampling
+// is disabled or has not seen the runtime yet.
+func (s *ExecuterService) RuntimeResources(runtimeID string) *models.RuntimeResources {
+	if s.Resources == nil {
+		return nil
+	}
+	usage, ok := s.Resources.Usage(runtimeID)
+	if !ok {
+		return nil
+	}
+	return &usage
+}
+
 func (s *ExecuterService) GetRuntime(ctx context.Context, runtimeID string) (*models.Runtime, error) {
-	runtime, ok := s.Runtimes.Load(runtimeID)
+	runtimeData, ok := s.Runtimes.Load(runtimeID)
 	if !ok {
 		return nil, fmt.Errorf("runtime not found: %s", runtimeID)
 	}
-	runtimeData := runtime.(*models.Runtime)
 	return runtimeData, nil
 }
 
+// QueueLength returns the number of execute requests waiting for an execution slot.
+func (s *ExecuterService) QueueLength() int {
+	return int(s.queued.Load())
+}
+
+// QueuePosition returns the place an execute request arriving now takes in the queue for an
+// execution slot: 0 when it gets a slot right away, otherwise one behind those waiting.
+func (s *ExecuterService) QueuePosition() int {
+	if s.ExecutionSlots == nil || len(s.ExecutionSlots) < cap(s.ExecutionSlots) {
+		return 0
+	}
+	return s.QueueLength() + 1
+}
+
+// ActiveRuntimeCount returns the number of runtimes that have not stopped, failed or finished.
+func (s *ExecuterService) ActiveRuntimeCount() int {
+	count := 0
+	s.Runtimes.Range(func(runtime *models.Runtime) bool {
+		if runtime.GetState().Active() {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+func (s *ExecuterService) logRing(runtimeID string) *util.LogRing {
+	ring, _ := s.logs.LoadOrStore(runtimeID, util.NewLogRing(maxLogLines))
+	return ring.(*util.LogRing)
+}
+
+// RuntimeLogs returns the most recent log lines written by the runtime's program.
+func (s *ExecuterService) RuntimeLogs(ctx context.Context, runtimeID string) ([]string, error) {
+	if _, err := s.GetRuntime(ctx, runtimeID); err != nil {
+		return nil, err
+	}
+	return s.logRing(runtimeID).Lines(), nil
+}
+
+// SubscribeLogs returns the runtime's recent log lines and a channel of the lines written after.
+func (s *ExecuterService) SubscribeLogs(ctx context.Context, runtimeID string) ([]string, <-chan string, func(), error) {
+	if _, err := s.GetRuntime(ctx, runtimeID); err != nil {
+		return nil, nil, nil, err
+	}
+	lines, ch, cancel := s.logRing(runtimeID).Subscribe()
+	return lines, ch, cancel, nil
+}
+
+// ListRuntimes returns a snapshot of all known runtimes, pinned ones first and otherwise
+// newest first.
+func (s *ExecuterService) ListRuntimes() []models.RuntimeInfo {
+	var runtimes []models.RuntimeInfo
+	s.Runtimes.Range(func(runtime *models.Runtime) bool {
+		runtimes = append(runtimes, runtime.Snapshot())
+		return true
+	})
+	sort.Slice(runtimes, func(i, j int) bool {
+		if runtimes[i].Pinned != runtimes[j].Pinned {
+			return runtimes[i].Pinned
+		}
+		return runtimes[i].CreatedAt.After(runtimes[j].CreatedAt)
+	})
+	return runtimes
+}
+
+// HealthyRuntimes returns a snapshot of the running runtimes that passed their health check,
+// newest first.
+func (s *ExecuterService) HealthyRuntimes() []models.RuntimeInfo {
+	var runtimes []models.RuntimeInfo
+	s.Runtimes.Range(func(runtimeData *models.Runtime) bool {
+		runtime := runtimeData.Snapshot()
+		if runtime.PassedHealthCheck && runtime.State == models.RSRUN {
+			runtimes = append(runtimes, runtime)
+		}
+		return true
+	})
+	sort.Slice(runtimes, func(i, j int) bool { return runtimes[i].CreatedAt.After(runtimes[j].CreatedAt) })
+	return runtimes
+}
+
 func (s *ExecuterService) UpdateRuntimeState(ctx context.Context, runtimeID string, state models.RuntimeState) error {
 	runtime, ok := s.Runtimes.Load(runtimeID)
 	if !ok {
 		return fmt.Errorf("runtime not found: %s", runtimeID)
 	}
-	runtimeData := runtime.(*models.Runtime)
-	runtimeData.State = state
-	s.Runtimes.Store(runtimeID, runtimeData)
+	runtime.SetState(state)
 	return nil
 }
 
-func (s *ExecuterService) SaveExecuter(ctx context.Context, runtime *models.Runtime) error {
+func (s *ExecuterService) SaveExecuter(ctx context.Context, runtimeData *models.Runtime) error {
+	if s.detached.Load() {
+		return nil
+	}
+	runtime := runtimeData.Snapshot()
 	log.Printf("Saving runtime data for ID: %s", runtime.ID)
+	runtime.Code = s.scrubSecrets(runtime.Tenant, runtime.Code)
+	runtime.LastErrorMsg = s.scrubSecrets(runtime.Tenant, runtime.LastErrorMsg)
 	data, err := json.Marshal(runtime)
 	if err != nil {
 		return fmt.Errorf("failed to marshal runtime data: %w", err)
 	}
-	if err := os.MkdirAll(s.Config.ExecuterStore, os.ModePerm); err != nil {
+	dir := s.recordDir(runtime.Tenant)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	f, err := os.Create(s.Config.ExecuterStore + "/" + runtime.ID + ".json")
+	f, err := os.Create(s.recordPath(runtime.Tenant, runtime.ID))
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	os.Remove(s.Config.ExecuterStore + "/._" + runtime.ID + ".json")
+	os.Remove(dir + "/._" + runtime.ID + ".json")
 	defer f.Close()
 	_, err = f.Write(data)
 	if err != nil {
@@ -507,34 +1299,49 @@ func (s *ExecuterService) SaveExecuter(ctx context.Context, runtime *models.Runt
 	return nil
 }
 
-func (s *ExecuterService) LoadExecuter(ctx context.Context, runtimeID string) (*models.Runtime, error) {
-	f, err := os.Open(s.Config.ExecuterStore + "/" + runtimeID + ".json")
+func (s *ExecuterService) LoadExecuter(ctx context.Context, tenant string, runtimeID string) (*models.Runtime, error) {
+	f, err := os.Open(s.recordPath(tenant, runtimeID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
-	var runtime models.Runtime
+	var runtime models.RuntimeInfo
 	err = json.NewDecoder(f).Decode(&runtime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode data from file: %w", err)
 	}
-	return &runtime, nil
+	return models.NewRuntime(runtime), nil
 }
 
+// LoadAllExecuters loads the runtime records of the default namespace and of every tenant
+// directory in the executer store.
 func (s *ExecuterService) LoadAllExecuters(ctx context.Context) ([]*models.Runtime, error) {
-	files, err := os.ReadDir(s.Config.ExecuterStore)
-	if err != nil {
+	tenants := []string{""}
+	tenantDirs, err := os.ReadDir(filepath.Join(s.Config.ExecuterStore, tenantStore))
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
+	for _, dir := range tenantDirs {
+		if dir.IsDir() {
+			tenants = append(tenants, dir.Name())
+		}
+	}
 	var runtimes []*models.Runtime
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".json") {
-			runtime, err := s.LoadExecuter(ctx, strings.TrimRight(file.Name(), ".json"))
-			if err != nil {
-				log.Printf("failed to load runtime %s: %v", file.Name(), err)
-				continue
+	for _, tenant := range tenants {
+		files, err := os.ReadDir(s.recordDir(tenant))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory: %w", err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file.Name(), ".json") {
+				runtime, err := s.LoadExecuter(ctx, tenant, strings.TrimSuffix(file.Name(), ".json"))
+				if err != nil {
+					log.Printf("failed to load runtime %s: %v", file.Name(), err)
+					continue
+				}
+				s.Runtimes.Store(runtime)
+				runtimes = append(runtimes, runtime)
 			}
-			s.Runtimes.Store(runtime.ID, runtime)
 		}
 	}
 	return runtimes, nil
@@ -0,0 +1,167 @@
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// ErrRuntimeActive is returned when an operation needs the runtime's program to be stopped.
+var ErrRuntimeActive = errors.New("runtime is active")
+
+// Snapshot describes a saved copy of a runtime's application data.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	RuntimeID string    `json:"runtimeID"`
+	CreatedAt time.Time `json:"createdAt"`
+	KVKeys    int       `json:"kvKeys"`
+	SQLite    bool      `json:"sqlite"`
+}
+
+const (
+	snapshotMeta   = "snapshot.json"
+	snapshotKV     = "kv.json"
+	snapshotSQLite = "sqlite.db"
+)
+
+func (s *ExecuterService) snapshotDir(runtimeID string, snapshotID string) string {
+	return filepath.Join(s.Config.SnapshotStore, runtimeID, snapshotID)
+}
+
+// SnapshotRuntime captures the runtime's key-value data and SQLite database.
+func (s *ExecuterService) SnapshotRuntime(ctx context.Context, runtimeID string) (*Snapshot, error) {
+	if _, err := s.GetRuntime(ctx, runtimeID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	snapshot := &Snapshot{ID: now.Format("20060102T150405.000000000"), RuntimeID: runtimeID, CreatedAt: now}
+	dir := s.snapshotDir(runtimeID, snapshot.ID)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if s.KV != nil {
+		data, err := s.KV.Export(runtimeID)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeJSON(filepath.Join(dir, snapshotKV), data); err != nil {
+			return nil, err
+		}
+		snapshot.KVKeys = len(data)
+	}
+	if s.SQLite != nil && s.SQLite.Exists(runtimeID) {
+		if err := s.SQLite.Backup(runtimeID, filepath.Join(dir, snapshotSQLite)); err != nil {
+			return nil, err
+		}
+		snapshot.SQLite = true
+	}
+	if err := writeJSON(filepath.Join(dir, snapshotMeta), snapshot); err != nil {
+		return nil, err
+	}
+	log.Printf("Created snapshot %s for runtime %s", snapshot.ID, runtimeID)
+	return snapshot, nil
+}
+
+// ListSnapshots returns a runtime's snapshots, oldest first.
+func (s *ExecuterService) ListSnapshots(runtimeID string) ([]*Snapshot, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Config.SnapshotStore, runtimeID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		snapshot := new(Snapshot)
+		if err := readJSON(filepath.Join(s.snapshotDir(runtimeID, entry.Name()), snapshotMeta), snapshot); err != nil {
+			log.Printf("failed to load snapshot %s of runtime %s: %v", entry.Name(), runtimeID, err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+// RestoreRuntime replaces the runtime's data with a snapshot, the latest one when snapshotID is
+// empty. The runtime's program must not be running because it may hold the database open.
+func (s *ExecuterService) RestoreRuntime(ctx context.Context, runtimeID string, snapshotID string) (*Snapshot, error) {
+	runtime, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	switch runtime.GetState() {
+	case models.RSINIT, models.RSRUN, "rebuilding":
+		return nil, fmt.Errorf("%w: stop runtime %s before restoring a snapshot", ErrRuntimeActive, runtimeID)
+	}
+	if snapshotID == "" {
+		snapshots, err := s.ListSnapshots(runtimeID)
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshots) == 0 {
+			return nil, fmt.Errorf("no snapshots found for runtime %s", runtimeID)
+		}
+		snapshotID = snapshots[len(snapshots)-1].ID
+	}
+	dir := s.snapshotDir(runtimeID, filepath.Base(snapshotID))
+	snapshot := new(Snapshot)
+	if err := readJSON(filepath.Join(dir, snapshotMeta), snapshot); err != nil {
+		return nil, fmt.Errorf("snapshot %s not found: %w", snapshotID, err)
+	}
+	if s.KV != nil {
+		data := map[string]string{}
+		if err := readJSON(filepath.Join(dir, snapshotKV), &data); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := s.KV.Import(runtimeID, data); err != nil {
+			return nil, err
+		}
+	}
+	if s.SQLite != nil {
+		if snapshot.SQLite {
+			err = s.SQLite.Restore(runtimeID, filepath.Join(dir, snapshotSQLite))
+		} else {
+			err = s.SQLite.Remove(runtimeID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("Restored snapshot %s for runtime %s", snapshot.ID, runtimeID)
+	return snapshot, nil
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/scaffold"
+)
+
+// Code sources produce the program of a new runtime. The LLM is the default; the others skip
+// generation, so their runtimes go straight to validation and execution.
+const (
+	// SourceLLM generates the program from the prompt.
+	SourceLLM = "llm"
+	// SourceScaffold renders the scaffold named by ExecutionOptions.Scaffold.
+	SourceScaffold = "scaffold"
+	// SourceCode runs ExecutionOptions.Code, a program supplied by the user.
+	SourceCode = "code"
+)
+
+var ErrUnknownSource = errors.New("unknown code source")
+
+// Source produces the program of a new runtime in place of the LLM, along with its static
+// assets. Programs are written for scaffold.ExamplePrefix and any value of the port constant,
+// and are moved to the runtime's own prefix and port before validation.
+type Source interface {
+	Program(ctx context.Context, req SourceRequest) (program string, files map[string]string, err error)
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func(ctx context.Context, req SourceRequest) (string, map[string]string, error)
+
+func (f SourceFunc) Program(ctx context.Context, req SourceRequest) (string, map[string]string, error) {
+	return f(ctx, req)
+}
+
+// SourceRequest is what a source builds a program for.
+type SourceRequest struct {
+	RuntimeID string
+	Prompt    string
+	Options   ExecutionOptions
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]Source{
+		SourceScaffold: SourceFunc(scaffoldProgram),
+		SourceCode:     SourceFunc(userProgram),
+	}
+)
+
+// RegisterSource makes a custom source selectable by name through ExecutionOptions.Source.
+func RegisterSource(name string, source Source) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[name] = source
+}
+
+func lookupSource(name string) (Source, bool) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	source, ok := sources[name]
+	return source, ok
+}
+
+func scaffoldProgram(ctx context.Context, req SourceRequest) (string, map[string]string, error) {
+	s, err := scaffold.Get(req.Options.Scaffold)
+	if err != nil {
+		return "", nil, err
+	}
+	program, err := s.Render(req.Options.ScaffoldParams)
+	return program, nil, err
+}
+
+func userProgram(ctx context.Context, req SourceRequest) (string, map[string]string, error) {
+	return req.Options.Code, nil, nil
+}
+
+// generated reports whether the runtime's program comes from the LLM.
+func (o ExecutionOptions) generated() bool {
+	return o.Source == "" || o.Source == SourceLLM
+}
+
+// validateSource rejects unknown sources and sources missing their input.
+func (o ExecutionOptions) validateSource() error {
+	switch {
+	case o.generated():
+		return nil
+	case o.Source == SourceScaffold && o.Scaffold == "":
+		return errors.New("the scaffold source needs a scaffold name")
+	case o.Source == SourceCode && strings.TrimSpace(o.Code) == "":
+		return errors.New("the code source needs a program")
+	}
+	if _, ok := lookupSource(o.Source); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, o.Source)
+	}
+	return nil
+}
+
+// describeSource stands in for the prompt of a runtime built without one, for its title and
+// the logs.
+func describeSource(opts ExecutionOptions) string {
+	if opts.Source == SourceScaffold {
+		if s, err := scaffold.Get(opts.Scaffold); err == nil && s.Description != "" {
+			return s.Description
+		}
+		return "Scaffold " + opts.Scaffold
+	}
+	return "Program from the " + opts.Source + " source"
+}
+
+// sourceProgram returns the program of opts.Source for runtime id, moved to its prefix and
+// port.
+func (s *ExecuterService) sourceProgram(ctx context.Context, id string, prompt string, port int, opts ExecutionOptions) (string, map[string]string, error) {
+	source, ok := lookupSource(opts.Source)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnknownSource, opts.Source)
+	}
+	program, files, err := source.Program(ctx, SourceRequest{RuntimeID: id, Prompt: prompt, Options: opts})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get code from the %s source: %w", opts.Source, err)
+	}
+	program = strings.ReplaceAll(program, scaffold.ExamplePrefix, models.RuntimePrefix(opts.Tenant, id))
+	return rewriteRuntimeReferences(program, id, id, port), files, nil
+}
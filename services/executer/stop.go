@@ -0,0 +1,163 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// StopTimeout bounds how long a stop waits for Shutdown() and for the port to be released
+// before the interpreter context is cancelled anyway.
+const StopTimeout = 15 * time.Second
+
+// stopPollInterval is how often a stopping runtime's port is checked.
+const stopPollInterval = 100 * time.Millisecond
+
+// StopRuntime starts a graceful stop and returns without waiting for it. The runtime leaves
+// the proxy and enters the stopping state at once; in the background Shutdown() is called and
+// the port is polled until it is released or StopTimeout passes, then the interpreter context
+// is cancelled and the runtime becomes stopped. Progress is recorded in its Stop report.
+func (s *ExecuterService) StopRuntime(ctx context.Context, runtimeID string) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	requestedAt := time.Now()
+	stopping := false
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		if info.State == models.RSSTOPPING {
+			stopping = true
+			return
+		}
+		info.State = models.RSSTOPPING
+		info.Stop = &models.StopReport{RequestedAt: requestedAt}
+	})
+	if stopping {
+		return nil
+	}
+	s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
+	util.Go("stop", runtimeID, func() { s.finishStop(runtimeData, requestedAt) })
+	return nil
+}
+
+// StartRuntime restarts the program of a runtime that is not running, on a fresh port.
+func (s *ExecuterService) StartRuntime(ctx context.Context, runtimeID string) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	info := runtimeData.Snapshot()
+	if info.State.Active() {
+		return fmt.Errorf("%w: runtime %s is %s", ErrRuntimeActive, runtimeID, info.State)
+	}
+	port, err := s.allocatePort(runtimeID, info.Kind)
+	if err != nil {
+		return fmt.Errorf("failed to allocate port: %w", err)
+	}
+	code := rewriteRuntimeReferences(info.Code, runtimeID, runtimeID, port)
+	if err := s.resolveDependencies(runtimeID, code); err != nil {
+		return err
+	}
+	program, output := s.newProgram(info)
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.Port = port
+		info.Code = code
+		info.State = "rebuilding"
+		info.PassedHealthCheck = false
+		info.PostMortem = nil
+		info.Executer = program
+		info.Logs = output
+	})
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+	log.Printf("Starting runtime %s on port %d", runtimeID, port)
+	if err := s.ExecuteRuntime(ctx, runtimeID); err != nil {
+		return fmt.Errorf("failed to execute runtime: %w", err)
+	}
+	return nil
+}
+
+// RestartRuntime starts a stopped or failed runtime again like StartRuntime, with its retry
+// budget reset so a program that failed for good is rebuilt if it fails once more.
+func (s *ExecuterService) RestartRuntime(ctx context.Context, runtimeID string) error {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return err
+	}
+	if info := runtimeData.Snapshot(); info.State.Active() {
+		return fmt.Errorf("%w: runtime %s is %s", ErrRuntimeActive, runtimeID, info.State)
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.FailureCounts = nil
+	})
+	return s.StartRuntime(ctx, runtimeID)
+}
+
+// finishStop shuts down a stopping runtime and marks it stopped, unless it left the stopping
+// state meanwhile, e.g. because it was killed or started again.
+func (s *ExecuterService) finishStop(runtimeData *models.Runtime, requestedAt time.Time) {
+	info := runtimeData.Snapshot()
+	ctx, cancel := context.WithTimeout(context.Background(), StopTimeout)
+	defer cancel()
+
+	report := &models.StopReport{RequestedAt: requestedAt}
+	if info.Executer != nil {
+		err := info.Executer.Shutdown(ctx)
+		report.GracefulShutdown = err == nil
+		if err != nil {
+			log.Printf("Graceful shutdown failed for runtime %s: %v", info.ID, err)
+		}
+	}
+	report.PortReleased = waitPortReleased(ctx, info.Port)
+	if !report.PortReleased {
+		log.Printf("Port %d of stopped runtime %s is still listening after %s", info.Port, info.ID, StopTimeout)
+	}
+	if state := runtimeData.GetState(); state != models.RSSTOPPING {
+		log.Printf("Runtime %s is %s, no longer stopping, leaving it as is", info.ID, state)
+		return
+	}
+	s.stopSupervisor(info.ID)
+	s.PortAllocator.Release(info.ID)
+
+	report.StoppedAt = time.Now()
+	stopped := false
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		if info.State != models.RSSTOPPING {
+			return
+		}
+		info.State = models.RSSTOP
+		info.Stop = report
+		info.FinishedAt = report.StoppedAt
+		stopped = true
+	})
+	if !stopped {
+		return
+	}
+	// A runtime deleted while it was stopping must not be written back to disk.
+	if _, ok := s.Runtimes.Load(info.ID); !ok {
+		return
+	}
+	if err := s.SaveExecuter(context.Background(), runtimeData); err != nil {
+		log.Printf("failed to save stopped runtime %s: %v", info.ID, err)
+	}
+	log.Printf("Stopped runtime %s in %s (graceful=%t, port released=%t)", info.ID, report.StoppedAt.Sub(requestedAt).Round(time.Millisecond), report.GracefulShutdown, report.PortReleased)
+}
+
+// waitPortReleased polls until nothing listens on port, returning false if ctx ends first.
+func waitPortReleased(ctx context.Context, port int) bool {
+	ticker := time.NewTicker(stopPollInterval)
+	defer ticker.Stop()
+	for util.IsPortListening(port) {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/notify"
+	"github.com/gcottom/aegisx/util"
+)
+
+// Failure strategies decide what happens to a runtime whose code keeps failing.
+const (
+	// StrategyRepair asks the model to fix the existing code until the retry limit is reached.
+	StrategyRepair = "repair"
+	// StrategyRegenerate discards the code and generates it again from the original prompt.
+	StrategyRegenerate = "regenerate"
+	// StrategyHybrid repairs until the retry limit is reached, then regenerates.
+	StrategyHybrid = "hybrid"
+)
+
+var ErrInvalidStrategy = errors.New("failure strategy must be repair, regenerate or hybrid")
+
+// ExecutionOptions are the per-request settings of an execution.
+type ExecutionOptions struct {
+	// Fresh skips the generation cache.
+	Fresh bool
+	// Force generates a new runtime even if one is running from a near-identical prompt.
+	Force bool
+	// Strategy overrides the configured failure strategy.
+	Strategy string
+	// Model overrides the configured generation model.
+	Model string
+	// Tenant is the namespace the runtime is created in; empty is the default namespace.
+	Tenant string
+	// PromptVariant is the prompt template variant the runtime is generated with.
+	PromptVariant string
+	// Params override the configured generation parameters for the runtime's generations and
+	// rebuilds.
+	Params util.GenerationParams
+	// NoOutbound denies the runtime's program every outbound connection.
+	NoOutbound bool
+	// Source is the code source of the runtime's program: SourceLLM, the default,
+	// SourceScaffold, SourceCode or a source added with RegisterSource.
+	Source string
+	// Scaffold and ScaffoldParams select and fill in the scaffold of SourceScaffold.
+	Scaffold       string
+	ScaffoldParams map[string]string
+	// Code is the program of SourceCode.
+	Code string
+	// Kind is the runtime kind: KindWeb, the default, KindAPI, KindJob or KindWorker.
+	Kind string
+	// Language is the language of the program: LanguageGo, the default, or a language added
+	// with RegisterBackend.
+	Language string
+	// Env is the runtime's environment: upper case variables that Go programs read with the
+	// aegisx/env host package and programs in other languages as environment variables.
+	Env map[string]string
+}
+
+// Validate rejects unknown options.
+func (o ExecutionOptions) Validate() error {
+	switch o.Strategy {
+	case "", StrategyRepair, StrategyRegenerate, StrategyHybrid:
+		if err := o.validateSource(); err != nil {
+			return err
+		}
+		if err := o.validateKind(); err != nil {
+			return err
+		}
+		if err := o.validateLanguage(); err != nil {
+			return err
+		}
+		if err := o.validateEnv(); err != nil {
+			return err
+		}
+		return o.Params.Validate()
+	}
+	return fmt.Errorf("%w, got %q", ErrInvalidStrategy, o.Strategy)
+}
+
+// failureStrategy returns the runtime's strategy, falling back to the configured default.
+func (s *ExecuterService) failureStrategy(runtime *models.Runtime) string {
+	if runtime.FailureStrategy != "" {
+		return runtime.FailureStrategy
+	}
+	if s.Config.FailureStrategy != "" {
+		return s.Config.FailureStrategy
+	}
+	return StrategyHybrid
+}
+
+// regenerateRuntime replaces a failing runtime's code with a fresh generation under the same ID,
+// up to MaxRegenerations times.
+func (s *ExecuterService) regenerateRuntime(ctx context.Context, runtimeData *models.Runtime) error {
+	info := runtimeData.Snapshot()
+	runtimeID := info.ID
+	if info.Regenerations >= s.Config.MaxRegenerations {
+		log.Printf("Regeneration limit reached for runtime %s: %d regenerations", runtimeID, s.Config.MaxRegenerations)
+		s.giveUp(runtimeData)
+		return nil
+	}
+	if info.Executer != nil {
+		_ = info.Executer.Shutdown(context.Background())
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) { info.Regenerations++ })
+	s.noteRebuild()
+	s.failRuntime(runtimeData)
+	log.Printf("Regenerating runtime %s (regeneration %d of %d)", runtimeID, info.Regenerations+1, s.Config.MaxRegenerations)
+	opts := ExecutionOptions{Strategy: info.FailureStrategy, Model: info.Model, Tenant: info.Tenant, PromptVariant: info.PromptVariant, Params: info.GenerationParams, NoOutbound: info.NoOutbound, Kind: info.Kind, Language: info.Language, Env: info.Env}
+	if _, err := s.PrepareRuntime(ctx, info.Prompt, runtimeID, opts); err != nil {
+		return fmt.Errorf("failed to prepare regenerated runtime: %w", err)
+	}
+	return s.ExecuteRuntime(ctx, runtimeID)
+}
+
+// failRuntime gives up on a runtime: it is marked failed and removed from the proxy.
+func (s *ExecuterService) failRuntime(runtimeData *models.Runtime) {
+	runtimeData.SetState("failed")
+	s.stopSupervisor(runtimeData.ID)
+	s.DynamicRouteService.DeregisterReverseProxy(runtimeData.ID)
+}
+
+// giveUp fails a runtime that exhausted its retries or regenerations and diagnoses why.
+func (s *ExecuterService) giveUp(runtimeData *models.Runtime) {
+	s.failRuntime(runtimeData)
+	info := runtimeData.Snapshot()
+	// Attempts of an execution in flight have no title yet; if they all fail, the execution
+	// reports it once.
+	if info.Title != "" {
+		s.notifyRuntime(notify.EventRuntimeFailed, info, fmt.Sprintf("Runtime %s (%s) failed after all retries: %s", info.ID, info.Title, info.LastErrorMsg))
+	}
+	if s.PostMortemClient != nil {
+		util.Go("post-mortem", info.ID, func() { s.writePostMortem(runtimeData) })
+	}
+}
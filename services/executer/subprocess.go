@@ -0,0 +1,282 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+	"github.com/gcottom/aegisx/validators/code"
+)
+
+// CommandBackend runs the programs of a language configured under languages with its
+// interpreter, e.g. python3 or node, in a sandboxed subprocess. The process gets a working
+// directory of its own and an environment holding only PATH, HOME and the configured variables.
+// No language is configured by default.
+type CommandBackend struct {
+	Language config.LanguageConfig
+}
+
+// NewCommandBackend returns the backend of a configured language.
+func NewCommandBackend(language config.LanguageConfig) *CommandBackend {
+	return &CommandBackend{Language: language}
+}
+
+func (b *CommandBackend) Name() string {
+	return b.Language.Name
+}
+
+// Prompt wraps the user prompt in the generation rules of spec's kind, written for the
+// language: the port constant, control lines and prefix rules are those of Go programs.
+func (b *CommandBackend) Prompt(prompt string, spec ProgramSpec, extraRequirements ...string) string {
+	log.Printf("Creating %s prompt for base prompt: %s", b.Language.Name, prompt)
+	name := b.Language.Name
+	base := `You are a ` + name + ` expert. Generate a ` + name + ` program that meets the following requirements:
+🛡️ Core Requirements:
+`
+	switch spec.Kind {
+	case KindAPI:
+		base += `✅ A JSON HTTP API with a web server. Do NOT serve HTML, CSS or JavaScript.
+`
+	case KindJob:
+		base += `✅ A command line job that runs once: it does its work, prints its result and exits.
+✅ Do NOT start a web server or listen on any port.
+`
+	case KindWorker:
+		base += `✅ A background worker, such as a scheduler, a poller or a bot, that keeps working until it is stopped.
+✅ Do NOT start a web server or listen on any port.
+✅ Exit cleanly on SIGINT or SIGTERM.
+`
+	default:
+		base += `✅ Single Page Application (SPA) with a web server.
+✅ The application should have persistent state and storage management.
+`
+	}
+	base += `✅ Use only the ` + name + ` standard library; no packages can be installed.
+✅ Keep persistent state in files of the current working directory, which is kept across restarts. Do NOT write files anywhere else.
+🚫 Do NOT start other processes or read environment variables, except those the requirements below name.
+🚫 Do NOT write Go.
+`
+	if spec.Kind == KindJob {
+		base += `📊 Output Rules:
+✅ Everything the program prints is its result, so print only the result.
+✅ Report a fatal error by printing the control line \"` + util.ControlLine(util.ControlError, "") + `<message>\" to stdout and exiting with a non-zero status.
+✅ Do NOT read from stdin; the program gets no input.
+`
+	} else {
+		base += `📊 Logging Rules:
+✅ Print logs to stdout.
+✅ Report to aegisx by printing these control lines exactly, each on its own line, flushing stdout after each:
+`
+		if spec.Kind == KindWorker {
+			base += `   - \"` + util.ControlLine(util.ControlReady, "") + `\" once setup is done and the work starts.
+   - \"` + util.ControlLine(util.ControlHeartbeat, "") + `\" at least every ` + spec.Heartbeat.String() + ` while the worker is healthy, from the loop doing the work, so a stuck worker stops sending it.
+`
+		} else {
+			base += `   - \"` + util.ControlLine(util.ControlPort, strconv.Itoa(spec.Port)) + `\" before starting the server.
+   - \"` + util.ControlLine(util.ControlReady, "") + `\" once setup is done, right before the server starts serving.
+`
+		}
+		base += `   - \"` + util.ControlLine(util.ControlError, "") + `<message>\" only for a fatal error the program cannot recover from.
+`
+	}
+	if listens(spec.Kind) {
+		base += `🌐 Web Server Requirements:
+✅ Declare the port in a top level constant assigned exactly: ` + code.PortConstName + ` = ` + strconv.Itoa(spec.Port) + `
+✅ Listen on 0.0.0.0 and only on that port. Do NOT pick a random port.
+✅ Requests keep the ` + spec.Prefix + ` prefix through the proxy, so serve every route under it, e.g. ` + spec.Prefix + `/items.
+✅ GET / must also answer with status 200; aegisx checks the program's health there.
+✅ Stop the server and exit on SIGINT or SIGTERM.
+`
+		if spec.Kind == KindAPI {
+			base += `✅ Read and write JSON and set Content-Type: application/json on every response.
+✅ Report errors as {"error": "<message>"} with a matching 4xx or 5xx status code.
+📘 OpenAPI Document:
+✅ Also return an OpenAPI 3.0 document describing every endpoint, with its parameters, request bodies and responses, as a fenced code block labelled with its file name: ` + "```json " + OpenAPIDocName + `
+✅ Its servers list holds exactly one entry with the url ` + spec.Prefix + `, and its paths are relative to it, e.g. /items.
+`
+		} else {
+			base += `✅ ****All HTML form actions, links, fetch() and XMLHttpRequest URLs must use ` + spec.Prefix + `/.... ****
+📁 Static Assets:
+✅ Large HTML/CSS/JS may be returned as separate fenced code blocks labelled with a file name, e.g. ` + "```css app.css" + `.
+✅ aegisx serves them at ` + spec.Prefix + `/static/<file name>; reference them with that path and do NOT serve them yourself.
+`
+		}
+	}
+	base += `
+💡 Program Instructions:
+` + b.returnRule(spec) + `
+The program must run as provided.
+The program must be a complete, runnable ` + name + ` program.
+`
+	for _, requirement := range extraRequirements {
+		base += requirement + "\n"
+	}
+	base += userPromptMarker
+	if strings.Contains(prompt, base) {
+		return prompt
+	}
+	return base + prompt
+}
+
+// returnRule tells the model how to return the program.
+func (b *CommandBackend) returnRule(spec ProgramSpec) string {
+	if spec.Structured {
+		return `Respond with a JSON object {"files": [{"name": "...", "content": "..."}]}: the program as main` + b.Language.Extension + ` and every other file under its name, with no additional commentary.`
+	}
+	return `Return the program as a single fenced code block labelled ` + b.Language.Labels[0] + `, with no additional commentary.`
+}
+
+func (b *CommandBackend) RebuildRequirements(spec ProgramSpec) string {
+	requirements := `- The program must run as provided with the ` + b.Language.Name + ` standard library.
+`
+	switch {
+	case spec.Kind == KindJob:
+		requirements += `- Do NOT start a web server; print the result to stdout and exit when done.
+`
+	case spec.Kind == KindWorker:
+		requirements += `- Do NOT start a web server; keep working until SIGINT or SIGTERM.
+- Ensure '` + util.ControlLine(util.ControlReady, "") + `' is printed once the work starts and '` + util.ControlLine(util.ControlHeartbeat, "") + `' at least every ` + spec.Heartbeat.String() + ` while working.
+`
+	default:
+		requirements += `- Listen on the assigned port: ` + code.PortConstName + ` = ` + strconv.Itoa(spec.Port) + `.
+- Ensure '` + util.ControlLine(util.ControlPort, strconv.Itoa(spec.Port)) + `' and '` + util.ControlLine(util.ControlReady, "") + `' are printed before the server starts serving.
+`
+	}
+	if spec.Kind == KindAPI {
+		requirements += `- Serve JSON only; do NOT serve HTML.
+- Also return the complete OpenAPI document of the API as a fenced block labelled ` + "```json " + OpenAPIDocName + `.
+`
+	}
+	return requirements + `- ` + b.returnRule(spec) + `
+`
+}
+
+func (b *CommandBackend) Extract(response string) (string, map[string]string) {
+	return util.ExtractProgram(response, b.Language.Labels, b.Language.Extension)
+}
+
+// Rules check that a program that listens declares its port constant, which aegisx rewrites
+// when the runtime moves to another port.
+func (b *CommandBackend) Rules(spec ProgramSpec) []code.Rule {
+	if !listens(spec.Kind) {
+		return nil
+	}
+	declared := regexp.MustCompile(`\b` + code.PortConstName + `\s*=\s*` + strconv.Itoa(spec.Port) + `\b`)
+	return []code.Rule{{Name: "port_constant", Check: func(src *code.Source) []code.Violation {
+		if !declared.MatchString(src.Code) {
+			return []code.Violation{{Message: fmt.Sprintf("missing constant: %s = %d", code.PortConstName, spec.Port)}}
+		}
+		return nil
+	}}}
+}
+
+func (b *CommandBackend) Program(dir string, env map[string]string, output *util.LogWriter) models.Program {
+	return &commandProgram{language: b.Language, dir: dir, env: env, output: output}
+}
+
+// commandProgram is a program run by its language's interpreter in a subprocess.
+type commandProgram struct {
+	language config.LanguageConfig
+	dir      string
+	env      map[string]string // The runtime's environment
+	output   *util.LogWriter
+
+	mu      sync.Mutex
+	process *os.Process
+	done    chan struct{}
+}
+
+// maxStderrTail caps what a failed program printed to stderr that is kept in its error.
+const maxStderrTail = 4 << 10
+
+// Run writes code to main<extension> in the program's directory and runs it in the language's
+// sandbox until it exits or ctx is done, which kills it. The error of a failed program ends with
+// the tail of its stderr, such as a traceback, for the rebuild prompt.
+func (p *commandProgram) Run(ctx context.Context, code string) error {
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create program directory: %w", err)
+	}
+	name := "main" + p.language.Extension
+	if err := os.WriteFile(filepath.Join(p.dir, name), []byte(code), 0o644); err != nil {
+		return fmt.Errorf("failed to write program: %w", err)
+	}
+	dir, err := filepath.Abs(p.dir)
+	if err != nil {
+		return err
+	}
+	// The interpreter runs in dir, so the program is named relative to it.
+	cmd, err := sandboxCommand(ctx, p.language.Sandbox, dir, append(slices.Clone(p.language.Command), name))
+	if err != nil {
+		return err
+	}
+	cmd.Env = append(cmd.Env, "PATH="+os.Getenv("PATH"), "HOME="+dir)
+	for key, value := range p.language.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	for key, value := range p.env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	stderr := &tailBuffer{limit: maxStderrTail}
+	cmd.Stdout, cmd.Stderr = p.output, io.MultiWriter(p.output, stderr)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", p.language.Command[0], err)
+	}
+	done := make(chan struct{})
+	p.mu.Lock()
+	p.process, p.done = cmd.Process, done
+	p.mu.Unlock()
+	defer close(done)
+	err = cmd.Wait()
+	if tail := strings.TrimSpace(string(stderr.data)); err != nil && tail != "" {
+		return fmt.Errorf("%w: %s", err, tail)
+	}
+	return err
+}
+
+// Shutdown interrupts the process group of the program and waits for it to exit, killing the
+// group when ctx is done first.
+func (p *commandProgram) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	process, done := p.process, p.done
+	p.mu.Unlock()
+	if process == nil {
+		return nil
+	}
+	if err := signalGroup(process, syscall.SIGINT); err != nil {
+		// The process already exited.
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		_ = signalGroup(process, syscall.SIGKILL)
+		return fmt.Errorf("the program did not exit after an interrupt: %w", ctx.Err())
+	}
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	data  []byte
+	limit int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = append([]byte(nil), b.data[len(b.data)-b.limit:]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,79 @@
+package executer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// RuntimeSupervisor owns the goroutines of one execution of a runtime: the eval, the log
+// monitor and the startup watchdog. They share a single context, so cancelling it winds all
+// of them down, and Stop waits until every one has returned. A panic in a supervised goroutine
+// is reported, passed to OnPanic and cancels the others.
+type RuntimeSupervisor struct {
+	OnPanic func(value any)
+
+	runtimeID string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewRuntimeSupervisor returns a supervisor with a fresh context. The context is deliberately
+// not derived from a request so the runtime outlives the call that started it.
+func NewRuntimeSupervisor() *RuntimeSupervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RuntimeSupervisor{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine owned by the supervisor. fn must return once ctx is done.
+func (s *RuntimeSupervisor) Go(fn func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer util.Recover("supervisor", s.runtimeID, func(value any) {
+			if s.OnPanic != nil {
+				s.OnPanic(value)
+			}
+			s.cancel()
+		})
+		fn(s.ctx)
+	}()
+}
+
+// Context returns the context shared by the supervised goroutines.
+func (s *RuntimeSupervisor) Context() context.Context {
+	return s.ctx
+}
+
+// Cancel tells the supervised goroutines to stop without waiting for them. Unlike Stop it is
+// safe to call from a supervised goroutine.
+func (s *RuntimeSupervisor) Cancel() {
+	s.cancel()
+}
+
+// Stop cancels the supervised goroutines and waits for them to return. It must not be called
+// from a supervised goroutine.
+func (s *RuntimeSupervisor) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// supervise starts a supervisor for a new execution of runtimeID, stopping the goroutines of
+// its previous execution first so they cannot touch the runtime again.
+func (s *ExecuterService) supervise(runtimeID string) *RuntimeSupervisor {
+	supervisor := NewRuntimeSupervisor()
+	supervisor.runtimeID = runtimeID
+	if previous, loaded := s.supervisors.Swap(runtimeID, supervisor); loaded {
+		previous.(*RuntimeSupervisor).Stop()
+	}
+	return supervisor
+}
+
+// stopSupervisor stops the goroutines of the runtime's current execution, if it has one.
+func (s *ExecuterService) stopSupervisor(runtimeID string) {
+	if supervisor, ok := s.supervisors.LoadAndDelete(runtimeID); ok {
+		supervisor.(*RuntimeSupervisor).Stop()
+	}
+}
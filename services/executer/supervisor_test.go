@@ -0,0 +1,158 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The tests in this file are meant to run with -race.
+
+func TestRuntimeSupervisorConcurrentStop(t *testing.T) {
+	supervisor := NewRuntimeSupervisor()
+	var returned atomic.Int32
+	const goroutines = 16
+	for i := 0; i < goroutines; i++ {
+		supervisor.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			returned.Add(1)
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			supervisor.Cancel()
+		}()
+		go func() {
+			defer wg.Done()
+			supervisor.Stop()
+		}()
+	}
+	wg.Wait()
+
+	if got := returned.Load(); got != goroutines {
+		t.Errorf("%d of %d supervised goroutines returned", got, goroutines)
+	}
+	if supervisor.Context().Err() == nil {
+		t.Error("the context was not cancelled")
+	}
+}
+
+func TestSuperviseConcurrently(t *testing.T) {
+	s := &ExecuterService{}
+	var running atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runtimeID := fmt.Sprintf("r%d", i)
+			// Each execution replaces the previous one of the runtime.
+			for j := 0; j < 20; j++ {
+				s.supervise(runtimeID).Go(func(ctx context.Context) {
+					running.Add(1)
+					defer running.Add(-1)
+					<-ctx.Done()
+				})
+			}
+			s.stopSupervisor(runtimeID)
+		}()
+	}
+	wg.Wait()
+
+	if got := running.Load(); got != 0 {
+		t.Errorf("%d goroutines of replaced or stopped executions are still running", got)
+	}
+}
+
+// checkNoLeak fails t unless the number of goroutines falls back to before within a second.
+func checkNoLeak(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		now := runtime.NumGoroutine()
+		if now <= before {
+			return
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines leaked:\n%s", now-before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRuntimeSupervisorStopLeaksNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	supervisor := NewRuntimeSupervisor()
+	// The eval, log monitor and startup watchdog of an execution.
+	supervisor.Go(func(ctx context.Context) { <-ctx.Done() })
+	supervisor.Go(func(ctx context.Context) {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+	supervisor.Go(func(ctx context.Context) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Hour):
+		}
+	})
+	supervisor.Stop()
+	checkNoLeak(t, before)
+}
+
+func TestRuntimeSupervisorPanicStopsTheOthers(t *testing.T) {
+	before := runtime.NumGoroutine()
+	supervisor := NewRuntimeSupervisor()
+	panicked := make(chan any, 1)
+	supervisor.OnPanic = func(value any) { panicked <- value }
+	supervisor.Go(func(ctx context.Context) { <-ctx.Done() })
+	supervisor.Go(func(ctx context.Context) { panic("boom") })
+
+	select {
+	case value := <-panicked:
+		if value != "boom" {
+			t.Errorf("OnPanic got %v, want boom", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnPanic was not called")
+	}
+	select {
+	case <-supervisor.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("a panic did not cancel the other goroutines")
+	}
+	supervisor.Stop()
+	checkNoLeak(t, before)
+}
+
+func TestSuperviseStopsThePreviousExecution(t *testing.T) {
+	before := runtime.NumGoroutine()
+	s := &ExecuterService{}
+	first := s.supervise("r1")
+	first.Go(func(ctx context.Context) { <-ctx.Done() })
+	second := s.supervise("r1")
+	if first.Context().Err() == nil {
+		t.Error("a new execution did not stop the previous one")
+	}
+	second.Go(func(ctx context.Context) { <-ctx.Done() })
+	s.stopSupervisor("r1")
+	if second.Context().Err() == nil {
+		t.Error("stopSupervisor did not stop the execution")
+	}
+	checkNoLeak(t, before)
+}
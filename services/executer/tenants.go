@@ -0,0 +1,49 @@
+package executer
+
+import (
+	"path/filepath"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// tenantStore is the subdirectory of the executer store holding one directory of runtime
+// records per tenant.
+const tenantStore = "tenants"
+
+// recordDir is the directory holding the records of the tenant's runtimes; the default
+// namespace keeps them at the top of the executer store.
+func (s *ExecuterService) recordDir(tenant string) string {
+	if tenant == "" {
+		return s.Config.ExecuterStore
+	}
+	return filepath.Join(s.Config.ExecuterStore, tenantStore, tenant)
+}
+
+func (s *ExecuterService) recordPath(tenant string, runtimeID string) string {
+	return filepath.Join(s.recordDir(tenant), runtimeID+".json")
+}
+
+// TenantRuntimes returns a snapshot of the tenant's runtimes in ListRuntimes order.
+func (s *ExecuterService) TenantRuntimes(tenant string) []models.RuntimeInfo {
+	var runtimes []models.RuntimeInfo
+	for _, runtime := range s.ListRuntimes() {
+		if runtime.Tenant == tenant {
+			runtimes = append(runtimes, runtime)
+		}
+	}
+	return runtimes
+}
+
+// ActiveTenantRuntimeCount returns the number of the tenant's runtimes that have not stopped,
+// failed or finished.
+func (s *ExecuterService) ActiveTenantRuntimeCount(tenant string) int {
+	count := 0
+	s.Runtimes.Range(func(runtime *models.Runtime) bool {
+		info := runtime.Snapshot()
+		if info.Tenant == tenant && info.State.Active() {
+			count++
+		}
+		return true
+	})
+	return count
+}
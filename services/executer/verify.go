@@ -0,0 +1,198 @@
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/analyzer"
+	"github.com/gcottom/aegisx/util"
+)
+
+// defaultVerificationChecks caps a verification plan when max_verification_checks is unset.
+const defaultVerificationChecks = 8
+
+// verificationPlan is the reply the verification prompt asks for.
+type verificationPlan struct {
+	Checks []models.VerificationCheck `json:"checks"`
+}
+
+// CreateVerificationPrompt asks for a short list of HTTP calls that exercise the program's
+// features. The calls reach the live runtime and its users' data, so only safe methods are
+// asked for. routes are the endpoints found by the analyzer and may be incomplete.
+func CreateVerificationPrompt(prompt string, generatedCode string, routes []analyzer.Route, maxChecks int) string {
+	log.Println("Creating verification prompt")
+	routeList, _ := json.Marshal(routes)
+	return `You are testing a Go web application generated from the prompt below.
+Write a short test plan of at most ` + strconv.Itoa(maxChecks) + ` HTTP calls that together show the application does what the prompt asks, not merely that it serves a page.
+
+📝 RULES:
+- Paths are relative to the application root, e.g. "/" or "/items". Do NOT include a host or a /runtime/ prefix.
+- Calls run against the live application and its users' data, so use only GET and HEAD requests, which must not change anything.
+- Set "expectStatus" only when a specific status is required; otherwise any status below 400, including redirects, passes.
+- Set "expectContains" to text the response body must contain, or leave it empty.
+- Only test behaviour the prompt or the code clearly defines.
+
+Reply with JSON only, in this shape:
+{"checks":[{"description":"...","method":"GET","path":"/","expectStatus":0,"expectContains":""}]}
+
+📝 PROMPT:
+` + prompt + `
+
+📝 ROUTES FOUND IN THE CODE:
+` + string(routeList) + `
+
+📝 CODE:
+` + generatedCode + `
+`
+}
+
+// verifyRuntime runs functional checks against a runtime that passed its health check, unless
+// its current code version was already verified. It returns the report and whether the
+// runtime may be declared healthy. Failing to produce a plan skips verification rather than
+// failing the runtime, since the fault is not the program's.
+func (s *ExecuterService) verifyRuntime(ctx context.Context, runtimeData *models.Runtime) (*models.VerificationReport, bool) {
+	info := runtimeData.Snapshot()
+	if previous := info.Verification; previous != nil && previous.Version == info.Version && (previous.Passed || previous.Skipped != "") {
+		return previous, true
+	}
+	report := &models.VerificationReport{Version: info.Version}
+	defer func() { report.VerifiedAt = time.Now() }()
+
+	plan, err := s.planVerification(ctx, info)
+	if err != nil {
+		log.Printf("Skipping verification of runtime %s: %v", info.ID, err)
+		report.Passed, report.Skipped = true, err.Error()
+		return report, true
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		// Redirects point at the runtime prefix, which only exists behind the proxy.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	report.Passed = true
+	for _, check := range plan {
+		if ctx.Err() != nil {
+			return report, false
+		}
+		runVerificationCheck(ctx, client, info.Port, &check)
+		report.Checks = append(report.Checks, check)
+		report.Passed = report.Passed && check.Passed
+	}
+	return report, report.Passed
+}
+
+// planVerification asks the verification model for the checks to run.
+func (s *ExecuterService) planVerification(ctx context.Context, info models.RuntimeInfo) ([]models.VerificationCheck, error) {
+	if s.VerificationClient == nil {
+		return nil, errors.New("no verification model configured")
+	}
+	maxChecks := s.Config.MaxVerificationChecks
+	if maxChecks <= 0 {
+		maxChecks = defaultVerificationChecks
+	}
+	routes, err := analyzer.AnalyzeRoutes(info.Code, models.RuntimePrefix(info.Tenant, info.ID))
+	if err != nil {
+		routes = nil
+	}
+	response, err := s.VerificationClient.SendMessage(util.WithRuntimeID(ctx, info.ID), CreateVerificationPrompt(info.Prompt, info.Code, routes, maxChecks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification plan: %w", err)
+	}
+	response = strings.TrimSpace(response)
+	if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+	var plan verificationPlan
+	if err := json.Unmarshal([]byte(response), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse verification plan: %w", err)
+	}
+	// Checks that could change the runtime's data are dropped.
+	plan.Checks = slices.DeleteFunc(plan.Checks, func(check models.VerificationCheck) bool {
+		method := strings.ToUpper(check.Method)
+		return method != "" && method != http.MethodGet && method != http.MethodHead
+	})
+	if len(plan.Checks) == 0 {
+		return nil, errors.New("verification plan has no safe checks")
+	}
+	if len(plan.Checks) > maxChecks {
+		plan.Checks = plan.Checks[:maxChecks]
+	}
+	return plan.Checks, nil
+}
+
+// runVerificationCheck performs one check against the program's port and records the outcome
+// on check.
+func runVerificationCheck(ctx context.Context, client *http.Client, port int, check *models.VerificationCheck) {
+	check.Method = strings.ToUpper(check.Method)
+	if check.Method == "" {
+		check.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(check.Path, "/") {
+		check.Path = "/" + check.Path
+	}
+	var body io.Reader
+	contentType := ""
+	switch {
+	case len(check.Form) > 0:
+		form := url.Values{}
+		for key, value := range check.Form {
+			form.Set(key, value)
+		}
+		body, contentType = strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"
+	case check.Body != "":
+		body, contentType = strings.NewReader(check.Body), "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, check.Method, fmt.Sprintf("http://localhost:%d%s", port, check.Path), body)
+	if err != nil {
+		check.Error = fmt.Sprintf("invalid request: %v", err)
+		return
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		check.Error = fmt.Sprintf("request failed: %v", err)
+		return
+	}
+	defer res.Body.Close()
+	check.Status = res.StatusCode
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	switch {
+	case check.ExpectStatus != 0 && res.StatusCode != check.ExpectStatus:
+		check.Error = fmt.Sprintf("expected status %d, got %d", check.ExpectStatus, res.StatusCode)
+	case check.ExpectStatus == 0 && res.StatusCode >= 400:
+		check.Error = fmt.Sprintf("got status %d", res.StatusCode)
+	case check.ExpectContains != "" && !strings.Contains(string(data), check.ExpectContains):
+		check.Error = fmt.Sprintf("response does not contain %q", check.ExpectContains)
+	default:
+		check.Passed = true
+	}
+}
+
+// verificationFailure describes the failed checks of a report for the rebuild prompt.
+func verificationFailure(report *models.VerificationReport) string {
+	var failed []string
+	for _, check := range report.Checks {
+		if check.Passed {
+			continue
+		}
+		line := fmt.Sprintf("- %s %s", check.Method, check.Path)
+		if check.Description != "" {
+			line += " (" + check.Description + ")"
+		}
+		failed = append(failed, line+": "+check.Error)
+	}
+	return "functional verification failed:\n" + strings.Join(failed, "\n")
+}
@@ -0,0 +1,220 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/util"
+)
+
+// CodeVersion is one revision of a runtime's generated code.
+type CodeVersion struct {
+	Version   int               `json:"version"`
+	Source    string            `json:"source"`
+	Reason    string            `json:"reason,omitempty"`
+	Diff      string            `json:"diff,omitempty"`
+	Code      string            `json:"code"`
+	Assets    map[string]string `json:"assets,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Sources of code versions.
+const (
+	VersionGenerate = "generate"
+	VersionRebuild  = "rebuild"
+	VersionClone    = "clone"
+	VersionCache    = "cache"
+	VersionRollback = "rollback"
+	VersionEdit     = "edit"
+)
+
+func (s *ExecuterService) versionDir(runtimeID string) string {
+	return filepath.Join(s.Config.VersionStore, runtimeID)
+}
+
+// RecordVersion stores the runtime's current code as a new version. reason is the error or
+// action that produced it.
+func (s *ExecuterService) RecordVersion(runtimeData *models.Runtime, source string, reason string) (*CodeVersion, error) {
+	runtime := runtimeData.Snapshot()
+	versions, err := s.ListVersions(runtime.ID)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := s.readAssets(runtime.ID, runtime.Assets)
+	if err != nil {
+		return nil, err
+	}
+	version := &CodeVersion{
+		Version:   1,
+		Source:    source,
+		Reason:    s.scrubSecrets(runtime.Tenant, reason),
+		Code:      s.scrubSecrets(runtime.Tenant, runtime.Code),
+		Assets:    assets,
+		CreatedAt: time.Now(),
+	}
+	if len(versions) > 0 {
+		previous := versions[len(versions)-1]
+		version.Version = previous.Version + 1
+		version.Diff = util.UnifiedDiff(previous.Code, version.Code, "v"+strconv.Itoa(previous.Version), "v"+strconv.Itoa(version.Version))
+	}
+	if err := os.MkdirAll(s.versionDir(runtime.ID), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := writeJSON(filepath.Join(s.versionDir(runtime.ID), strconv.Itoa(version.Version)+".json"), version); err != nil {
+		return nil, err
+	}
+	runtimeData.Update(func(info *models.RuntimeInfo) { info.Version = version.Version })
+	return version, nil
+}
+
+// recordVersion records a version and logs instead of failing the caller, since history is
+// secondary to keeping the runtime alive.
+func (s *ExecuterService) recordVersion(runtime *models.Runtime, source string, reason string) {
+	if _, err := s.RecordVersion(runtime, source, reason); err != nil {
+		log.Printf("failed to record code version for runtime %s: %v", runtime.ID, err)
+	}
+}
+
+// ListVersions returns the runtime's code versions, oldest first.
+func (s *ExecuterService) ListVersions(runtimeID string) ([]*CodeVersion, error) {
+	entries, err := os.ReadDir(s.versionDir(runtimeID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var versions []*CodeVersion
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		version := new(CodeVersion)
+		if err := readJSON(filepath.Join(s.versionDir(runtimeID), entry.Name()), version); err != nil {
+			log.Printf("failed to load version %s of runtime %s: %v", entry.Name(), runtimeID, err)
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+// ErrVersionNotFound is returned for a version a runtime never had.
+var ErrVersionNotFound = errors.New("version not found")
+
+// GetVersion returns a single code version of a runtime.
+func (s *ExecuterService) GetVersion(runtimeID string, version int) (*CodeVersion, error) {
+	codeVersion := new(CodeVersion)
+	if err := readJSON(filepath.Join(s.versionDir(runtimeID), strconv.Itoa(version)+".json"), codeVersion); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: version %d of runtime %s", ErrVersionNotFound, version, runtimeID)
+		}
+		return nil, fmt.Errorf("failed to load version %d of runtime %s: %w", version, runtimeID, err)
+	}
+	return codeVersion, nil
+}
+
+// RollbackRuntime restarts the runtime with the code of an earlier version, recorded as a new
+// version so the rollback itself can be undone.
+func (s *ExecuterService) RollbackRuntime(ctx context.Context, runtimeID string, version int) (*CodeVersion, error) {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.GetVersion(runtimeID, version)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Rolling back runtime %s to version %d", runtimeID, version)
+	if target.Assets == nil {
+		target.Assets = map[string]string{}
+	}
+	return s.deployCode(ctx, runtimeData, target.Code, target.Assets, VersionRollback, "rollback to version "+strconv.Itoa(version))
+}
+
+// UpdateRuntimeCode replaces the program of a runtime with code written or picked by the user,
+// e.g. a fix proposed by DiagnoseRuntime, keeping its static assets. The code goes through the
+// same validation as generated code; a *code.ValidationError leaves the runtime untouched.
+func (s *ExecuterService) UpdateRuntimeCode(ctx context.Context, runtimeID string, program string, reason string) (*CodeVersion, error) {
+	runtimeData, err := s.GetRuntime(ctx, runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	info := runtimeData.Snapshot()
+	validator, err := s.newValidator(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build code validator: %w", err)
+	}
+	if err := validator.Validate(rewriteRuntimeReferences(program, runtimeID, runtimeID, info.Port)); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = "code update"
+	}
+	log.Printf("Updating code of runtime %s", runtimeID)
+	return s.deployCode(ctx, runtimeData, program, nil, VersionEdit, reason)
+}
+
+// deployCode stops a runtime's program and starts program in its place as a new version. assets
+// replace the runtime's static assets unless nil.
+func (s *ExecuterService) deployCode(ctx context.Context, runtimeData *models.Runtime, program string, assets map[string]string, source string, reason string) (*CodeVersion, error) {
+	runtimeID := runtimeData.ID
+	// Shutdown the current program before starting the new code.
+	if executer := runtimeData.GetExecuter(); executer != nil {
+		_ = executer.Shutdown(context.Background())
+	}
+	s.DynamicRouteService.DeregisterReverseProxy(runtimeID)
+	port, err := s.allocatePort(runtimeID, runtimeData.Snapshot().Kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate port: %w", err)
+	}
+
+	names := runtimeData.Snapshot().Assets
+	if assets != nil {
+		if names, err = s.replaceAssets(runtimeID, assets); err != nil {
+			return nil, err
+		}
+	}
+
+	program = rewriteRuntimeReferences(program, runtimeID, runtimeID, port)
+	if err := s.resolveDependencies(runtimeID, program); err != nil {
+		return nil, err
+	}
+	executer, output := s.newProgram(runtimeData.Snapshot())
+	runtimeData.Update(func(info *models.RuntimeInfo) {
+		info.Port = port
+		info.Code = program
+		info.Assets = names
+		info.State = "rebuilding"
+		info.LastErrorMsg = ""
+		info.FailureClass = ""
+		info.FailureCounts = nil
+		info.Diagnostics = nil
+		info.PostMortem = nil
+		info.PassedHealthCheck = false
+		info.Executer = executer
+		info.Logs = output
+	})
+	version, err := s.RecordVersion(runtimeData, source, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SaveExecuter(ctx, runtimeData); err != nil {
+		return nil, fmt.Errorf("failed to save runtime: %w", err)
+	}
+	if err := s.ExecuteRuntime(ctx, runtimeID); err != nil {
+		return nil, fmt.Errorf("failed to execute runtime: %w", err)
+	}
+	return version, nil
+}
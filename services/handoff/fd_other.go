@@ -0,0 +1,16 @@
+//go:build !unix
+
+package handoff
+
+import (
+	"net"
+	"os"
+)
+
+func writeMessage(conn *net.UnixConn, data []byte, files []*os.File) error {
+	return ErrUnsupported
+}
+
+func readMessage(conn *net.UnixConn) ([]byte, []*os.File, error) {
+	return nil, nil, ErrUnsupported
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package handoff
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// writeMessage sends data with the descriptors of files attached.
+func writeMessage(conn *net.UnixConn, data []byte, files []*os.File) error {
+	var oob []byte
+	if len(files) > 0 {
+		fds := make([]int, len(files))
+		for i, file := range files {
+			fds[i] = int(file.Fd())
+		}
+		oob = syscall.UnixRights(fds...)
+	}
+	_, _, err := conn.WriteMsgUnix(data, oob, nil)
+	return err
+}
+
+// readMessage reads one message and the descriptors attached to it.
+func readMessage(conn *net.UnixConn) ([]byte, []*os.File, error) {
+	buf := make([]byte, maxMessage)
+	oob := make([]byte, syscall.CmsgSpace(16*4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []*os.File
+	if oobn > 0 {
+		messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse control message: %w", err)
+		}
+		for _, msg := range messages {
+			fds, err := syscall.ParseUnixRights(&msg)
+			if err != nil {
+				continue
+			}
+			for _, fd := range fds {
+				files = append(files, os.NewFile(uintptr(fd), "handoff"))
+			}
+		}
+	}
+	return buf[:n], files, nil
+}
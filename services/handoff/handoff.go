@@ -0,0 +1,244 @@
+// Package handoff lets a new aegisx process take over from a running one without refusing
+// connections. The running process (the predecessor) waits on a unix socket; its successor
+// connects, receives duplicates of the predecessor's listening sockets and serves on them
+// alongside it while it restarts the runtimes from the persistent store. Once they are
+// reattached the successor releases the predecessor, which stops accepting, drains its
+// in-flight requests and exits.
+//
+// The protocol is three JSON messages: hello from the successor, listeners from the
+// predecessor (carrying the descriptors) and release from the successor.
+package handoff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"syscall"
+)
+
+const (
+	msgHello     = "hello"
+	msgListeners = "listeners"
+	msgRelease   = "release"
+)
+
+// maxMessage bounds a protocol message.
+const maxMessage = 4096
+
+// ErrNoPredecessor is returned by Dial when no aegisx process waits on the socket.
+var ErrNoPredecessor = errors.New("no aegisx process is waiting to hand off")
+
+// ErrUnsupported is returned on platforms that cannot pass sockets between processes.
+var ErrUnsupported = errors.New("listener handoff is not supported on this platform")
+
+type message struct {
+	Type  string   `json:"type"`
+	Names []string `json:"names,omitempty"`
+}
+
+// Hooks are the predecessor's steps of a handoff.
+type Hooks struct {
+	// Prepare runs before the listeners are sent, so the successor finds the store up to date.
+	Prepare func() error
+	// Abort runs when a handoff fails after Prepare, before the successor released the
+	// predecessor, which keeps serving.
+	Abort func()
+}
+
+// Serve waits on the unix socket at path for successors and hands each the listeners, named
+// so the successor can tell them apart. A successor that fails before releasing is logged and
+// the next one is awaited. Serve returns nil once a successor released this process, which
+// should then stop accepting and exit, or an error when the socket fails or ctx ends.
+func Serve(ctx context.Context, path string, listeners map[string]net.Listener, hooks Hooks) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale handoff socket: %w", err)
+	}
+	unixListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return fmt.Errorf("failed to listen on handoff socket: %w", err)
+	}
+	// The successor listens on the same path once released, so the socket must not be unlinked
+	// after that; a stale one is removed by the next Serve and refuses Dial.
+	unixListener.SetUnlinkOnClose(false)
+	defer unixListener.Close()
+	stop := context.AfterFunc(ctx, func() { unixListener.Close() })
+	defer stop()
+	for {
+		conn, err := unixListener.AcceptUnix()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to accept successor: %w", err)
+		}
+		released, err := handOff(conn, listeners, hooks)
+		conn.Close()
+		if released {
+			return nil
+		}
+		log.Printf("Handoff to successor failed: %v", err)
+	}
+}
+
+// handOff runs the predecessor's side of the protocol on one connection.
+func handOff(conn *net.UnixConn, listeners map[string]net.Listener, hooks Hooks) (bool, error) {
+	msg, _, err := receive(conn)
+	if err != nil {
+		return false, err
+	}
+	if msg.Type != msgHello {
+		return false, fmt.Errorf("unexpected %q message from successor", msg.Type)
+	}
+	if hooks.Prepare != nil {
+		if err := hooks.Prepare(); err != nil {
+			return false, fmt.Errorf("failed to prepare handoff: %w", err)
+		}
+	}
+	released := false
+	defer func() {
+		if !released && hooks.Abort != nil {
+			hooks.Abort()
+		}
+	}()
+	names := make([]string, 0, len(listeners))
+	for name := range listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, name := range names {
+		file, err := listenerFile(listeners[name])
+		if err != nil {
+			return false, fmt.Errorf("failed to duplicate %s listener: %w", name, err)
+		}
+		files = append(files, file)
+	}
+	if err := send(conn, message{Type: msgListeners, Names: names}, files); err != nil {
+		return false, err
+	}
+	log.Printf("Handed listeners %v to successor, waiting for release", names)
+	if msg, _, err = receive(conn); err != nil {
+		return false, err
+	}
+	if msg.Type != msgRelease {
+		return false, fmt.Errorf("unexpected %q message from successor", msg.Type)
+	}
+	released = true
+	return true, nil
+}
+
+// Successor is the new process's end of a handoff.
+type Successor struct {
+	conn *net.UnixConn
+	// Listeners are the predecessor's listening sockets by name.
+	Listeners map[string]net.Listener
+}
+
+// Dial connects to the predecessor waiting on the unix socket at path and receives its
+// listeners. It returns ErrNoPredecessor when nothing is listening there.
+func Dial(path string) (*Successor, error) {
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, ErrNoPredecessor
+		}
+		return nil, fmt.Errorf("failed to connect to predecessor: %w", err)
+	}
+	successor, err := receiveListeners(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return successor, nil
+}
+
+func receiveListeners(conn *net.UnixConn) (*Successor, error) {
+	if err := send(conn, message{Type: msgHello}, nil); err != nil {
+		return nil, err
+	}
+	msg, files, err := receive(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	if msg.Type != msgListeners || len(msg.Names) != len(files) {
+		return nil, fmt.Errorf("unexpected %q message with %d descriptors from predecessor", msg.Type, len(files))
+	}
+	successor := &Successor{conn: conn, Listeners: map[string]net.Listener{}}
+	for i, name := range msg.Names {
+		listener, err := net.FileListener(files[i])
+		if err != nil {
+			successor.closeListeners()
+			return nil, fmt.Errorf("failed to restore %s listener: %w", name, err)
+		}
+		successor.Listeners[name] = listener
+	}
+	return successor, nil
+}
+
+// Release tells the predecessor to stop accepting and exit.
+func (s *Successor) Release() error {
+	defer s.conn.Close()
+	return send(s.conn, message{Type: msgRelease}, nil)
+}
+
+// Abandon gives up the takeover without releasing the predecessor, which keeps serving. The
+// handed listeners stay open for the caller to close.
+func (s *Successor) Abandon() {
+	s.conn.Close()
+}
+
+func (s *Successor) closeListeners() {
+	for _, listener := range s.Listeners {
+		listener.Close()
+	}
+}
+
+// listenerFile duplicates the descriptor of a TCP or unix listener.
+func listenerFile(listener net.Listener) (*os.File, error) {
+	filer, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("cannot pass a %T", listener)
+	}
+	return filer.File()
+}
+
+func send(conn *net.UnixConn, msg message, files []*os.File) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %w", msg.Type, err)
+	}
+	if err := writeMessage(conn, data, files); err != nil {
+		return fmt.Errorf("failed to send %s message: %w", msg.Type, err)
+	}
+	return nil
+}
+
+func receive(conn *net.UnixConn) (message, []*os.File, error) {
+	data, files, err := readMessage(conn)
+	if err != nil {
+		return message{}, nil, fmt.Errorf("failed to receive message: %w", err)
+	}
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		for _, file := range files {
+			file.Close()
+		}
+		return message{}, nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return msg, files, nil
+}
@@ -0,0 +1,134 @@
+// Package health runs the self-tests behind the readiness endpoint and the startup check:
+// whether the configuration is valid, the stores are writable, runtime ports can be bound and,
+// optionally, the LLM provider is reachable.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/services/ports"
+)
+
+// checkTimeout bounds every check, so a hung one cannot hang the probe.
+const checkTimeout = 5 * time.Second
+
+// portProbeID is the runtime the port check reserves a port for, which no runtime ID matches.
+const portProbeID = ".healthcheck"
+
+// Check is a named self-test.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a check.
+type CheckResult struct {
+	Name       string  `json:"name"`
+	OK         bool    `json:"ok"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Readiness is the outcome of all checks; the node is ready when every one passed.
+type Readiness struct {
+	Ready  bool          `json:"ready"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Run runs the checks concurrently and reports them in the order given.
+func Run(ctx context.Context, checks []Check) Readiness {
+	report := Readiness{Ready: true, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			err := check.Run(checkCtx)
+			result := CheckResult{Name: check.Name, OK: err == nil, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		report.Ready = report.Ready && result.OK
+	}
+	return report
+}
+
+// Writable checks that a file can be created in each of dirs, creating them if needed.
+func Writable(dirs ...string) Check {
+	return Check{Name: "store", Run: func(ctx context.Context) error {
+		for _, dir := range dirs {
+			if dir == "" {
+				continue
+			}
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+			file, err := os.CreateTemp(dir, ".healthcheck-*")
+			if err != nil {
+				return fmt.Errorf("%s is not writable: %w", dir, err)
+			}
+			file.Close()
+			if err := os.Remove(file.Name()); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", filepath.Base(file.Name()), err)
+			}
+		}
+		return nil
+	}}
+}
+
+// PortBindable checks that allocator can reserve a runtime port, which it only does for one it
+// can listen on, and releases it right away. Going through the allocator keeps the check off
+// the ports of runtimes and from handing a runtime a port while the check binds it.
+func PortBindable(allocator *ports.PortAllocator) Check {
+	return Check{Name: "ports", Run: func(ctx context.Context) error {
+		if _, err := allocator.Allocate(portProbeID); err != nil {
+			return err
+		}
+		allocator.Release(portProbeID)
+		return nil
+	}}
+}
+
+// Reachable checks that a TCP connection can be opened to the host of apiURL, without
+// spending tokens on a request.
+func Reachable(name string, apiURL string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		u, err := url.Parse(apiURL)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", apiURL, err)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+			if u.Scheme == "http" {
+				port = "80"
+			}
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+		if err != nil {
+			return fmt.Errorf("%s is unreachable: %w", u.Host, err)
+		}
+		return conn.Close()
+	}}
+}
+
+// Func wraps a plain function as a check.
+func Func(name string, fn func() error) Check {
+	return Check{Name: name, Run: func(context.Context) error { return fn() }}
+}
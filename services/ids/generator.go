@@ -0,0 +1,140 @@
+package ids
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StrategyUUID       = "uuid"
+	StrategyULID       = "ulid"
+	StrategySequential = "sequential"
+)
+
+// Generator produces runtime IDs. IDs end up in URLs, file names and generated code, so
+// implementations must only emit letters, digits, '-' and '_'.
+type Generator interface {
+	NewID() string
+}
+
+// GeneratorFunc adapts a function to the Generator interface.
+type GeneratorFunc func() string
+
+func (f GeneratorFunc) NewID() string { return f() }
+
+var (
+	customMu sync.RWMutex
+	custom   = map[string]Generator{}
+)
+
+// Register makes a custom generator selectable by name through the id_strategy config.
+func Register(name string, gen Generator) {
+	customMu.Lock()
+	defer customMu.Unlock()
+	custom[name] = gen
+}
+
+// NewGenerator returns the generator for strategy. store and its tenant subdirectories are
+// scanned so sequential IDs continue after the highest one already persisted there.
+func NewGenerator(strategy string, prefix string, store string) (Generator, error) {
+	switch strategy {
+	case "", StrategyUUID:
+		return &UUIDGenerator{Prefix: prefix}, nil
+	case StrategyULID:
+		return &ULIDGenerator{Prefix: prefix}, nil
+	case StrategySequential:
+		if prefix == "" {
+			prefix = "rt-"
+		}
+		return &SequentialGenerator{Prefix: prefix, next: highestSequence(store, prefix) + 1}, nil
+	}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	if gen, ok := custom[strategy]; ok {
+		return gen, nil
+	}
+	return nil, fmt.Errorf("unknown id strategy: %s", strategy)
+}
+
+// UUIDGenerator emits random UUIDs with the hyphens stripped.
+type UUIDGenerator struct {
+	Prefix string
+}
+
+func (g *UUIDGenerator) NewID() string {
+	return g.Prefix + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// ULIDGenerator emits lexicographically sortable ULIDs.
+type ULIDGenerator struct {
+	Prefix string
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g *ULIDGenerator) NewID() string {
+	var id [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+	rand.Read(id[6:])
+	// 128 bits encoded as 26 base32 characters, most significant first.
+	var out [26]byte
+	var hi, lo uint64
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(id[i])
+		lo = lo<<8 | uint64(id[i+8])
+	}
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return g.Prefix + string(out[:])
+}
+
+// SequentialGenerator emits Prefix followed by an increasing number.
+type SequentialGenerator struct {
+	Prefix string
+	mu     sync.Mutex
+	next   int
+}
+
+func (g *SequentialGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.next == 0 {
+		g.next = 1
+	}
+	id := g.Prefix + strconv.Itoa(g.next)
+	g.next++
+	return id
+}
+
+func highestSequence(store string, prefix string) int {
+	highest := 0
+	filepath.WalkDir(store, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(name, prefix)); err == nil && n > highest {
+			highest = n
+		}
+		return nil
+	})
+	return highest
+}
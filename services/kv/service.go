@@ -0,0 +1,234 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/traefik/yaegi/interp"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ImportPath is the package generated programs import to reach their key-value store.
+const ImportPath = "aegisx/kv"
+
+// ErrReleased is returned while the database is released to another process.
+var ErrReleased = errors.New("the kv store was handed over to another process")
+
+// KVService stores key-value data for generated programs in a single bbolt database,
+// with one bucket per runtime so no program can see another's data. The database is locked by
+// the process that opened it, so a process handing over to a successor releases it.
+type KVService struct {
+	path string
+	mu   sync.RWMutex
+	db   *bolt.DB // Nil while released
+}
+
+func NewKVService(path string) (*KVService, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	s := &KVService{path: path}
+	if err := s.Reopen(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Release closes the database so that another process can open it. Until Reopen, every
+// operation fails with ErrReleased.
+func (s *KVService) Release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// Reopen opens the database again after Release, waiting a few seconds for another process
+// to release it.
+func (s *KVService) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db != nil {
+		return nil
+	}
+	db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open kv database: %w", err)
+	}
+	s.db = db
+	return nil
+}
+
+func (s *KVService) view(fn func(tx *bolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return ErrReleased
+	}
+	return s.db.View(fn)
+}
+
+func (s *KVService) update(fn func(tx *bolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return ErrReleased
+	}
+	return s.db.Update(fn)
+}
+
+func (s *KVService) Get(runtimeID string, key string) (string, bool, error) {
+	var value []byte
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(runtimeID))
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(key)); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read key %s: %w", key, err)
+	}
+	return string(value), value != nil, nil
+}
+
+func (s *KVService) Put(runtimeID string, key string, value string) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(runtimeID))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), []byte(value))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write key %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *KVService) Delete(runtimeID string, key string) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(runtimeID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Keys returns the runtime's keys in sorted order.
+func (s *KVService) Keys(runtimeID string) ([]string, error) {
+	var keys []string
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(runtimeID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Export returns every key-value pair stored by a runtime.
+func (s *KVService) Export(runtimeID string) (map[string]string, error) {
+	data := map[string]string{}
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(runtimeID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			data[string(k)] = string(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export kv data: %w", err)
+	}
+	return data, nil
+}
+
+// Import atomically replaces a runtime's data with the given pairs.
+func (s *KVService) Import(runtimeID string, data map[string]string) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(runtimeID)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		bucket, err := tx.CreateBucket([]byte(runtimeID))
+		if err != nil {
+			return err
+		}
+		for k, v := range data {
+			if err := bucket.Put([]byte(k), []byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import kv data: %w", err)
+	}
+	return nil
+}
+
+// DeleteRuntime drops all data stored by a runtime.
+func (s *KVService) DeleteRuntime(runtimeID string) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(runtimeID)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete kv data for runtime %s: %w", runtimeID, err)
+	}
+	return nil
+}
+
+// Exports returns the interpreter symbols for the aegisx/kv package, bound to runtimeID.
+func (s *KVService) Exports(runtimeID string) interp.Exports {
+	return interp.Exports{
+		ImportPath + "/kv": {
+			"Get": reflect.ValueOf(func(key string) (string, bool, error) {
+				return s.Get(runtimeID, key)
+			}),
+			"Put": reflect.ValueOf(func(key string, value string) error {
+				return s.Put(runtimeID, key, value)
+			}),
+			"Delete": reflect.ValueOf(func(key string) error {
+				return s.Delete(runtimeID, key)
+			}),
+			"Keys": reflect.ValueOf(func() ([]string, error) {
+				return s.Keys(runtimeID)
+			}),
+		},
+	}
+}
+
+func (s *KVService) Close() error {
+	return s.Release()
+}
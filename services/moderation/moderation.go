@@ -0,0 +1,148 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/gcottom/aegisx/config"
+)
+
+// Categories of the built-in rules.
+const (
+	CategoryCredentialHarvesting = "credential_harvesting"
+	CategoryCryptoMining         = "crypto_mining"
+	CategorySandboxEscape        = "sandbox_escape"
+	CategoryPromptInjection      = "prompt_injection"
+)
+
+// Reason is one reason a prompt was rejected.
+type Reason struct {
+	// Source is "rules" for a local rule or "provider" for the moderation endpoint.
+	Source   string `json:"source"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// RejectedError is returned for a prompt that failed screening.
+type RejectedError struct {
+	Reasons []Reason
+}
+
+func (e *RejectedError) Error() string {
+	reasons := make([]string, len(e.Reasons))
+	for i, v := range e.Reasons {
+		reasons[i] = v.Message
+	}
+	return "prompt rejected by moderation: " + strings.Join(reasons, "; ")
+}
+
+// Rule rejects prompts matching Pattern, which is matched case-insensitively.
+type Rule struct {
+	Category string
+	Pattern  *regexp.Regexp
+	Message  string
+}
+
+func rule(category, pattern, message string) Rule {
+	return Rule{Category: category, Pattern: regexp.MustCompile(`(?is)` + pattern), Message: message}
+}
+
+// DefaultRules block the kinds of programs aegisx will not host.
+var DefaultRules = []Rule{
+	rule(CategoryCredentialHarvesting,
+		`\bphish\w*\b|\b(fake|spoof\w*|imitat\w*|lookalike|looks? (exactly )?like)\b.{0,40}\b(bank\w*|paypal|google|gmail|microsoft|office ?365|outlook|apple ?id|icloud|facebook|instagram|coinbase|metamask)\b.{0,30}\b(log ?in|sign ?in)\b`,
+		"imitating the login page of another service to collect credentials is not allowed"),
+	rule(CategoryCredentialHarvesting,
+		`\b(harvest\w*|steal\w*|captur\w*|exfiltrat\w*|grab\w*)\b.{0,40}\b(their|users'?|victims?'?|visitors'?|other people'?s?)\b.{0,20}\b(credentials?|passwords?|logins?|credit cards?|card numbers?|seed phrases?|private keys?|session cookies?)\b`,
+		"collecting other people's credentials or payment details is not allowed"),
+	rule(CategoryCryptoMining,
+		`\b(crypto ?currency|crypto|bitcoin|btc|monero|xmr|ethereum|litecoin|coin)\W{0,3}(miner|mining)\b`,
+		"cryptocurrency miners are not allowed"),
+	rule(CategoryCryptoMining,
+		`\b(mine|mines|mining)\b.{0,20}\b(bitcoin|monero|ethereum|litecoin|crypto\w*)\b|\b(xmrig|coinhive|cryptonight|randomx)\b|stratum\+(tcp|ssl)://`,
+		"cryptocurrency miners are not allowed"),
+	rule(CategorySandboxEscape,
+		`\b(escape|break(ing)? out of|bypass\w*|get out of|disable)\s+(from\s+)?(the\s+|this\s+|its\s+|your\s+)?(code\s+)?(sandbox|interpreter|yaegi|container|runtime isolation|validator)\b`,
+		"attempts to break out of the runtime sandbox are not allowed"),
+	rule(CategorySandboxEscape,
+		`\b(reverse|bind) shell\b|/etc/(passwd|shadow)|/proc/self|docker\.sock|\b(host|server)('s)? (file ?system|environment variables|shell)\b|\bexecute (arbitrary )?(shell|system) commands\b`,
+		"access to the host outside the runtime sandbox is not allowed"),
+	rule(CategoryPromptInjection,
+		`\b(ignore|disregard|forget|override)\b.{0,20}\b(all |any |the )?(previous|prior|above|earlier|system|original)\b.{0,20}\b(instructions|rules|prompt|requirements)\b`,
+		"instructions to override the generation rules are not allowed"),
+	rule(CategoryPromptInjection,
+		`\b(reveal|print|show|output|repeat|leak)\b.{0,20}\b(your|the)\b.{0,10}\b(system prompt|instructions|api keys?|gpt_api_key)\b`,
+		"requests for the generation instructions or API keys are not allowed"),
+}
+
+// Screener checks prompts before they are sent for generation.
+type Screener struct {
+	Rules []Rule
+	// Provider, when set, is also consulted for every prompt.
+	Provider *ProviderClient
+	// FailClosed rejects prompts when the provider cannot be reached instead of relying on the
+	// local rules alone.
+	FailClosed bool
+}
+
+// NewScreener returns the screener configured by cfg.Moderation, or nil when moderation is
+// disabled.
+func NewScreener(cfg *config.Config) (*Screener, error) {
+	policy := cfg.Moderation
+	if !policy.Enabled {
+		return nil, nil
+	}
+	screener := &Screener{FailClosed: policy.FailClosed}
+	if !policy.DisableDefaultRules {
+		screener.Rules = append(screener.Rules, DefaultRules...)
+	}
+	for _, custom := range policy.Rules {
+		pattern, err := regexp.Compile(`(?is)` + custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation rule %q: %w", custom.Category, err)
+		}
+		screener.Rules = append(screener.Rules, Rule{Category: custom.Category, Pattern: pattern, Message: custom.Message})
+	}
+	if policy.Provider {
+		apiKey := policy.ProviderApiKey
+		if apiKey == "" {
+			apiKey = cfg.GptApiKey
+		}
+		screener.Provider = NewProviderClient(policy.ProviderApiUrl, apiKey, policy.ProviderModel)
+	}
+	return screener, nil
+}
+
+// Screen returns a *RejectedError listing every reason to reject prompt, or nil if it may be
+// generated. A nil Screener allows everything.
+func (s *Screener) Screen(ctx context.Context, prompt string) error {
+	if s == nil {
+		return nil
+	}
+	var reasons []Reason
+	seen := map[string]bool{}
+	for _, r := range s.Rules {
+		if seen[r.Message] || !r.Pattern.MatchString(prompt) {
+			continue
+		}
+		seen[r.Message] = true
+		reasons = append(reasons, Reason{Source: "rules", Category: r.Category, Message: r.Message})
+	}
+	if s.Provider != nil {
+		flagged, err := s.Provider.Check(ctx, prompt)
+		if err != nil {
+			if s.FailClosed {
+				return fmt.Errorf("failed to screen prompt: %w", err)
+			}
+			log.Printf("Moderation provider unavailable, screening with local rules only: %v", err)
+		}
+		reasons = append(reasons, flagged...)
+	}
+	if len(reasons) > 0 {
+		return &RejectedError{Reasons: reasons}
+	}
+	return nil
+}
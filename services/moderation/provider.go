@@ -0,0 +1,93 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultProviderURL is OpenAI's moderation endpoint.
+const DefaultProviderURL = "https://api.openai.com/v1/moderations"
+
+// DefaultProviderModel is the moderation model used when none is configured.
+const DefaultProviderModel = "omni-moderation-latest"
+
+// ProviderClient asks an OpenAI compatible moderation endpoint whether a prompt is harmful.
+type ProviderClient struct {
+	URL        string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+func NewProviderClient(url, apiKey, model string) *ProviderClient {
+	if url == "" {
+		url = DefaultProviderURL
+	}
+	if model == "" {
+		model = DefaultProviderModel
+	}
+	return &ProviderClient{URL: url, APIKey: apiKey, Model: model, HTTPClient: &http.Client{Timeout: 15 * time.Second}}
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// Check returns a reason for every category the provider flagged the prompt for.
+func (c *ProviderClient) Check(ctx context.Context, prompt string) ([]Reason, error) {
+	body, err := json.Marshal(map[string]string{"model": c.Model, "input": prompt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation request failed: status %d", res.StatusCode)
+	}
+	var moderation moderationResponse
+	if err := json.NewDecoder(res.Body).Decode(&moderation); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	var reasons []Reason
+	for _, result := range moderation.Results {
+		if !result.Flagged {
+			continue
+		}
+		var categories []string
+		for category, flagged := range result.Categories {
+			if flagged {
+				categories = append(categories, category)
+			}
+		}
+		sort.Strings(categories)
+		if len(categories) == 0 {
+			categories = []string{"flagged"}
+		}
+		for _, category := range categories {
+			reasons = append(reasons, Reason{
+				Source:   "provider",
+				Category: category,
+				Message:  "the moderation provider flagged the prompt for " + strings.ReplaceAll(category, "/", " / "),
+			})
+		}
+	}
+	return reasons, nil
+}
@@ -0,0 +1,230 @@
+// Package notify tells operators about runtime lifecycle events through Slack, generic
+// webhooks or email, so they do not need to tail the logs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/util"
+)
+
+// sendTimeout bounds the delivery of a notification to one destination.
+const sendTimeout = 30 * time.Second
+
+// EventType is a kind of lifecycle event.
+type EventType string
+
+const (
+	// EventRuntimeHealthy fires when an execution produced a healthy runtime.
+	EventRuntimeHealthy EventType = "runtime_healthy"
+	// EventRuntimeFailed fires when a runtime is given up on after all retries.
+	EventRuntimeFailed EventType = "runtime_failed"
+	// EventRetryStorm fires when runtimes are rebuilt at an unusual rate.
+	EventRetryStorm EventType = "retry_storm"
+	// EventQuotaExceeded fires when an execute request is rejected by a runtime or token quota.
+	EventQuotaExceeded EventType = "quota_exceeded"
+)
+
+// Event is a notification. RuntimeID and URL are empty for events not about one runtime.
+type Event struct {
+	Type      EventType `json:"type"`
+	RuntimeID string    `json:"runtimeID,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Message   string    `json:"message"`
+	URL       string    `json:"url,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+func (e Event) text() string {
+	text := "[aegisx] " + e.Message
+	if e.URL != "" {
+		text += "\n" + e.URL
+	}
+	return text
+}
+
+// Notifier delivers events to one destination.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// SlackNotifier posts events to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, n.WebhookURL, nil, map[string]string{"text": event.text()})
+}
+
+// WebhookNotifier posts events as JSON to a URL, with optional extra headers.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, n.URL, n.Headers, event)
+}
+
+// EmailNotifier mails events through an SMTP server, authenticating when Username is set.
+type EmailNotifier struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, _ := net.SplitHostPort(n.Addr)
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	subject := "[aegisx] " + strings.ReplaceAll(string(event.Type), "_", " ")
+	if event.RuntimeID != "" {
+		subject += ": " + event.RuntimeID
+	}
+	msg := "From: " + n.From + "\r\nTo: " + strings.Join(n.To, ", ") + "\r\nSubject: " + subject +
+		"\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" + strings.ReplaceAll(event.text(), "\n", "\r\n") + "\r\n"
+	// net/smtp takes no context, so the send is abandoned rather than cancelled on timeout.
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(n.Addr, auth, n.From, n.To, []byte(msg)) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to send email: %w", ctx.Err())
+	}
+}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// subscription is a notifier and the events it receives, all of them when empty.
+type subscription struct {
+	notifier Notifier
+	events   []EventType
+}
+
+// Dispatcher sends events to the notifiers subscribed to them, in the background. Retry storm
+// and quota events of the same tenant are sent at most once per Cooldown. A nil Dispatcher
+// drops every event.
+type Dispatcher struct {
+	Cooldown time.Duration
+
+	subscriptions []subscription
+	mu            sync.Mutex
+	lastSent      map[string]time.Time
+}
+
+// NewDispatcher builds the notifiers of the config.
+func NewDispatcher(notifiers []config.NotifierConfig, cooldown time.Duration) (*Dispatcher, error) {
+	d := &Dispatcher{Cooldown: cooldown}
+	for i, cfg := range notifiers {
+		var notifier Notifier
+		switch cfg.Type {
+		case "slack":
+			notifier = &SlackNotifier{WebhookURL: cfg.URL}
+		case "webhook":
+			notifier = &WebhookNotifier{URL: cfg.URL, Headers: cfg.Headers}
+		case "email":
+			notifier = &EmailNotifier{Addr: cfg.SMTPAddr, Username: cfg.Username, Password: cfg.Password, From: cfg.From, To: cfg.To}
+		default:
+			return nil, fmt.Errorf("notifier %d: unknown type %q", i, cfg.Type)
+		}
+		var events []EventType
+		for _, event := range cfg.Events {
+			events = append(events, EventType(event))
+		}
+		d.Add(notifier, events...)
+	}
+	return d, nil
+}
+
+// Add subscribes a notifier to events, or to all events when none are given.
+func (d *Dispatcher) Add(notifier Notifier, events ...EventType) {
+	d.subscriptions = append(d.subscriptions, subscription{notifier: notifier, events: events})
+}
+
+// Notify sends an event to its subscribers without waiting for them. Delivery failures are
+// logged.
+func (d *Dispatcher) Notify(event Event) {
+	if d == nil || len(d.subscriptions) == 0 || d.coolingDown(event) {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, sub := range d.subscriptions {
+		if len(sub.events) > 0 && !slices.Contains(sub.events, event.Type) {
+			continue
+		}
+		go func(notifier Notifier) {
+			defer util.Recover("notify", event.RuntimeID, nil)
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := notifier.Notify(ctx, event); err != nil {
+				log.Printf("failed to send %s notification: %v", event.Type, err)
+			}
+		}(sub.notifier)
+	}
+}
+
+// coolingDown reports whether an event of the same kind was sent within the cooldown, and
+// otherwise records this one.
+func (d *Dispatcher) coolingDown(event Event) bool {
+	if event.Type != EventRetryStorm && event.Type != EventQuotaExceeded || d.Cooldown <= 0 {
+		return false
+	}
+	key := string(event.Type) + "\x00" + event.Tenant
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.lastSent[key]; ok && time.Since(last) < d.Cooldown {
+		return true
+	}
+	if d.lastSent == nil {
+		d.lastSent = map[string]time.Time{}
+	}
+	d.lastSent[key] = time.Now()
+	return false
+}
@@ -0,0 +1,253 @@
+// Package outbound is the forward proxy the HTTP requests of generated programs go through. It
+// caches cacheable responses, rate limits each runtime and attributes outbound traffic to the
+// runtime that made it in the metrics.
+package outbound
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/services/quota"
+)
+
+// CacheHeader is set to HIT on responses served from the cache.
+const CacheHeader = "X-Aegisx-Cache"
+
+// Results of outbound requests, the result label of metrics.OutboundRequests.
+const (
+	ResultHit     = "hit"
+	ResultMiss    = "miss"
+	ResultBypass  = "bypass"
+	ResultLimited = "limited"
+	ResultError   = "error"
+)
+
+type cacheEntry struct {
+	key       string
+	runtimeID string
+	status    int
+	header    http.Header
+	body      []byte
+	expires   time.Time
+}
+
+// Proxy caches responses per runtime, so no runtime is served what another one fetched with
+// its own credentials, and evicts the least recently used ones beyond CacheMaxBytes.
+type Proxy struct {
+	CacheTTL      time.Duration
+	CacheMaxBytes int64
+	MaxEntryBytes int64
+
+	limits  *quota.QuotaService
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used first
+	size    int64
+}
+
+func NewProxy(cfg config.OutboundProxyConfig) *Proxy {
+	return &Proxy{
+		CacheTTL:      cfg.CacheTTL,
+		CacheMaxBytes: cfg.CacheMaxBytes,
+		MaxEntryBytes: cfg.CacheMaxEntryBytes,
+		limits:        quota.NewQuotaService(cfg.RateLimitPerMinute, 0, 0),
+		entries:       map[string]*list.Element{},
+		lru:           list.New(),
+	}
+}
+
+// Transport returns the round tripper the runtime's requests go through, sending the ones it
+// does not answer itself to next.
+func (p *Proxy) Transport(runtimeID string, next http.RoundTripper) http.RoundTripper {
+	return &transport{proxy: p, runtimeID: runtimeID, next: next}
+}
+
+// DeleteRuntime drops the runtime's cached responses.
+func (p *Proxy) DeleteRuntime(runtimeID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, element := range p.entries {
+		if entry := element.Value.(*cacheEntry); entry.runtimeID == runtimeID {
+			p.remove(element)
+		}
+	}
+}
+
+// cacheKey returns the cache key of a request, or false when the request is not cacheable.
+// The credentials are part of the key, hashed with the rest so they are not kept in memory.
+func cacheKey(runtimeID string, req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || req.Header.Get("Cache-Control") == "no-cache" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{runtimeID, req.URL.String(), req.Header.Get("Authorization"), req.Header.Get("Cookie"), req.Header.Get("Accept")}, "\x00")))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// ttl is how long resp may be cached: its max-age, or CacheTTL when it has none.
+func (p *Proxy) ttl(resp *http.Response) time.Duration {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Set-Cookie") != "" || resp.Header.Get("Vary") == "*" {
+		return 0
+	}
+	ttl := p.CacheTTL
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err != nil {
+				return 0
+			}
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+	return ttl
+}
+
+// lookup returns a fresh cached response for the key.
+func (p *Proxy) lookup(key string, req *http.Request) (*http.Response, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	element, ok := p.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		p.remove(element)
+		return nil, false
+	}
+	p.lru.MoveToFront(element)
+	header := entry.header.Clone()
+	header.Set(CacheHeader, "HIT")
+	return &http.Response{
+		Status:        strconv.Itoa(entry.status) + " " + http.StatusText(entry.status),
+		StatusCode:    entry.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       req,
+	}, true
+}
+
+func (p *Proxy) store(entry *cacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if element, ok := p.entries[entry.key]; ok {
+		p.remove(element)
+	}
+	p.entries[entry.key] = p.lru.PushFront(entry)
+	p.size += int64(len(entry.body))
+	for p.size > p.CacheMaxBytes && p.lru.Len() > 0 {
+		p.remove(p.lru.Back())
+	}
+}
+
+// remove drops a cache entry; the caller holds the lock.
+func (p *Proxy) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	p.lru.Remove(element)
+	delete(p.entries, entry.key)
+	p.size -= int64(len(entry.body))
+}
+
+type transport struct {
+	proxy     *Proxy
+	runtimeID string
+	next      http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if status := t.proxy.limits.Allow(t.runtimeID); !status.Allowed {
+		metrics.OutboundRequests.Inc(t.runtimeID, ResultLimited)
+		return rateLimited(req, status.Reset), nil
+	}
+	key, cacheable := cacheKey(t.runtimeID, req)
+	cacheable = cacheable && t.proxy.CacheMaxBytes > 0
+	if cacheable {
+		if resp, ok := t.proxy.lookup(key, req); ok {
+			metrics.OutboundRequests.Inc(t.runtimeID, ResultHit)
+			return resp, nil
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		metrics.OutboundRequests.Inc(t.runtimeID, ResultError)
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, runtimeID: t.runtimeID}
+	ttl := time.Duration(0)
+	if cacheable {
+		ttl = t.proxy.ttl(resp)
+	}
+	if ttl <= 0 {
+		result := ResultBypass
+		if cacheable {
+			result = ResultMiss
+		}
+		metrics.OutboundRequests.Inc(t.runtimeID, result)
+		return resp, nil
+	}
+	metrics.OutboundRequests.Inc(t.runtimeID, ResultMiss)
+	// Read one byte past the limit to tell a body that fits from one that does not; a body
+	// that does not is passed on uncached.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.proxy.MaxEntryBytes+1))
+	if err != nil || int64(len(body)) > t.proxy.MaxEntryBytes {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	t.proxy.store(&cacheEntry{key: key, runtimeID: t.runtimeID, status: resp.StatusCode, header: resp.Header.Clone(), body: body, expires: time.Now().Add(ttl)})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// rateLimited is the response to a request over the runtime's outbound rate limit.
+func rateLimited(req *http.Request, reset time.Time) *http.Response {
+	body := "outbound rate limit exceeded"
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Retry-After", strconv.Itoa(max(int(time.Until(reset).Seconds()+0.5), 1)))
+	return &http.Response{
+		Status:        "429 " + http.StatusText(http.StatusTooManyRequests),
+		StatusCode:    http.StatusTooManyRequests,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// countingBody attributes the bytes read from an upstream response to the runtime.
+type countingBody struct {
+	io.ReadCloser
+	runtimeID string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		metrics.OutboundBytes.Add(float64(n), b.runtimeID)
+	}
+	return n, err
+}
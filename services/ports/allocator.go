@@ -0,0 +1,104 @@
+package ports
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// PortAllocator reserves a distinct listening port for every runtime before its code is generated.
+// When Min and Max are zero, ports are picked from the OS ephemeral range.
+type PortAllocator struct {
+	Min int
+	Max int
+
+	mu        sync.Mutex
+	byRuntime map[string]int
+	byPort    map[int]string
+}
+
+func NewPortAllocator(min int, max int) *PortAllocator {
+	return &PortAllocator{
+		Min:       min,
+		Max:       max,
+		byRuntime: map[string]int{},
+		byPort:    map[int]string{},
+	}
+}
+
+// Allocate returns the port reserved for runtimeID, reserving a new free one if needed.
+func (a *PortAllocator) Allocate(runtimeID string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if port, ok := a.byRuntime[runtimeID]; ok {
+		return port, nil
+	}
+	port, err := a.findFreePort()
+	if err != nil {
+		return 0, err
+	}
+	a.byRuntime[runtimeID] = port
+	a.byPort[port] = runtimeID
+	return port, nil
+}
+
+// Port returns the port currently reserved for runtimeID.
+func (a *PortAllocator) Port(runtimeID string) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	port, ok := a.byRuntime[runtimeID]
+	return port, ok
+}
+
+// Allocated returns the number of reserved ports.
+func (a *PortAllocator) Allocated() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.byRuntime)
+}
+
+// Release frees the port reserved for runtimeID.
+func (a *PortAllocator) Release(runtimeID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if port, ok := a.byRuntime[runtimeID]; ok {
+		delete(a.byPort, port)
+		delete(a.byRuntime, runtimeID)
+	}
+}
+
+func (a *PortAllocator) findFreePort() (int, error) {
+	if a.Min > 0 && a.Max >= a.Min {
+		for port := a.Min; port <= a.Max; port++ {
+			if _, taken := a.byPort[port]; taken {
+				continue
+			}
+			if isBindable(port) {
+				return port, nil
+			}
+		}
+		return 0, fmt.Errorf("no free port in range %d-%d", a.Min, a.Max)
+	}
+	for i := 0; i < 10; i++ {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return 0, fmt.Errorf("failed to find free port: %w", err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		if _, taken := a.byPort[port]; !taken {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to find a free port not already reserved")
+}
+
+func isBindable(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
@@ -0,0 +1,221 @@
+package prompts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for a prompt name that is not in the library.
+	ErrNotFound = errors.New("prompt not found")
+	// ErrExists is returned when creating a prompt whose name is taken.
+	ErrExists = errors.New("prompt already exists")
+	// ErrInvalid is returned for a prompt with a bad name or template.
+	ErrInvalid = errors.New("invalid prompt")
+	// ErrMissingParameters is returned when rendering without a value for every placeholder.
+	ErrMissingParameters = errors.New("missing prompt parameters")
+)
+
+var (
+	nameRegex        = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
+	placeholderRegex = regexp.MustCompile(`{{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*}}`)
+)
+
+// Prompt is a named prompt template. Placeholders are written {{name}} and listed in
+// Parameters; Defaults supplies values for the ones a caller may leave out.
+type Prompt struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Template    string            `json:"template"`
+	Parameters  []string          `json:"parameters"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// Library stores prompts as one JSON file per name.
+type Library struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func (l *Library) path(name string) string {
+	return filepath.Join(l.Dir, name+".json")
+}
+
+// Placeholders returns the distinct placeholder names of a template in order of appearance.
+func Placeholders(template string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, match := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Render fills the prompt's placeholders from params, falling back to its defaults.
+func (p *Prompt) Render(params map[string]string) (string, error) {
+	var missing []string
+	rendered := placeholderRegex.ReplaceAllStringFunc(p.Template, func(match string) string {
+		name := placeholderRegex.FindStringSubmatch(match)[1]
+		if value, ok := params[name]; ok {
+			return value
+		}
+		if value, ok := p.Defaults[name]; ok {
+			return value
+		}
+		if !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w for %q: %s", ErrMissingParameters, p.Name, strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
+
+func validate(p *Prompt) error {
+	if !nameRegex.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must be 1-64 letters, digits, '-' or '_'", ErrInvalid)
+	}
+	if strings.TrimSpace(p.Template) == "" {
+		return fmt.Errorf("%w: template is required", ErrInvalid)
+	}
+	return nil
+}
+
+// List returns every prompt sorted by name.
+func (l *Library) List() ([]*Prompt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := os.ReadDir(l.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Prompt{}, nil
+		}
+		return nil, fmt.Errorf("failed to read prompt library: %w", err)
+	}
+	prompts := []*Prompt{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		prompt, err := l.read(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+// Get returns the prompt called name.
+func (l *Library) Get(name string) (*Prompt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.read(name)
+}
+
+func (l *Library) read(name string) (*Prompt, error) {
+	if !nameRegex.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	data, err := os.ReadFile(l.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to read prompt: %w", err)
+	}
+	prompt := new(Prompt)
+	if err := json.Unmarshal(data, prompt); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt %s: %w", name, err)
+	}
+	return prompt, nil
+}
+
+// Create adds a new prompt, failing with ErrExists if the name is taken.
+func (l *Library) Create(p *Prompt) error {
+	return l.save(p, false)
+}
+
+// Put creates the prompt or replaces the one with the same name, keeping its creation time.
+func (l *Library) Put(p *Prompt) error {
+	return l.save(p, true)
+}
+
+func (l *Library) save(p *Prompt, replace bool) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	p.CreatedAt, p.UpdatedAt = now, now
+	if existing, err := l.read(p.Name); err == nil {
+		if !replace {
+			return fmt.Errorf("%w: %s", ErrExists, p.Name)
+		}
+		p.CreatedAt = existing.CreatedAt
+	}
+	p.Parameters = Placeholders(p.Template)
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prompt: %w", err)
+	}
+	if err := os.MkdirAll(l.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(l.path(p.Name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write prompt: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns the prompt to execute: prompt itself, or the saved prompt called name
+// rendered with params. Exactly one of prompt and name must be set.
+func (l *Library) Resolve(prompt string, name string, params map[string]string) (string, error) {
+	switch {
+	case name == "" && prompt == "":
+		return "", fmt.Errorf("%w: missing prompt", ErrInvalid)
+	case name == "":
+		return prompt, nil
+	case prompt != "":
+		return "", fmt.Errorf("%w: set either a prompt or a saved prompt name, not both", ErrInvalid)
+	}
+	saved, err := l.Get(name)
+	if err != nil {
+		return "", err
+	}
+	return saved.Render(params)
+}
+
+// Delete removes the prompt called name.
+func (l *Library) Delete(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err := os.Remove(l.path(name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return fmt.Errorf("failed to delete prompt: %w", err)
+	}
+	return nil
+}
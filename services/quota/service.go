@@ -0,0 +1,98 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// RateStatus is the rate limit state of one client after a request was counted or peeked.
+type RateStatus struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	Allowed   bool
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// QuotaService enforces a per-client fixed-window rate limit on execute requests.
+// A zero RatePerMinute disables rate limiting.
+type QuotaService struct {
+	RatePerMinute int
+	MaxRuntimes   int
+	TokenQuota    int
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+func NewQuotaService(ratePerMinute int, maxRuntimes int, tokenQuota int) *QuotaService {
+	return &QuotaService{
+		RatePerMinute: ratePerMinute,
+		MaxRuntimes:   maxRuntimes,
+		TokenQuota:    tokenQuota,
+		windows:       map[string]*window{},
+	}
+}
+
+// Allow counts a request for key and reports whether it is within the rate limit.
+func (q *QuotaService) Allow(key string) RateStatus {
+	return q.status(key, true)
+}
+
+// Peek reports the rate limit state for key without counting a request.
+func (q *QuotaService) Peek(key string) RateStatus {
+	return q.status(key, false)
+}
+
+func (q *QuotaService) status(key string, count bool) RateStatus {
+	if q.RatePerMinute <= 0 {
+		return RateStatus{Allowed: true}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	w, ok := q.windows[key]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &window{start: now}
+		q.windows[key] = w
+		q.evictExpired(now)
+	}
+	allowed := w.count < q.RatePerMinute
+	if count && allowed {
+		w.count++
+	}
+	return RateStatus{
+		Limit:     q.RatePerMinute,
+		Remaining: max(q.RatePerMinute-w.count, 0),
+		Reset:     w.start.Add(time.Minute),
+		Allowed:   allowed,
+	}
+}
+
+func (q *QuotaService) evictExpired(now time.Time) {
+	for key, w := range q.windows {
+		if now.Sub(w.start) >= time.Minute {
+			delete(q.windows, key)
+		}
+	}
+}
+
+// RemainingRuntimes returns how many more runtimes may be active, or -1 when unlimited.
+func (q *QuotaService) RemainingRuntimes(active int) int {
+	if q.MaxRuntimes <= 0 {
+		return -1
+	}
+	return max(q.MaxRuntimes-active, 0)
+}
+
+// RemainingTokens returns how many LLM tokens are left in the budget, or -1 when unlimited.
+func (q *QuotaService) RemainingTokens(used int) int {
+	if q.TokenQuota <= 0 {
+		return -1
+	}
+	return max(q.TokenQuota-used, 0)
+}
@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileRegistry keeps the registry as JSON files in a directory shared by all nodes, e.g. an
+// NFS mount. Files are replaced atomically, so readers never see partial writes.
+type FileRegistry struct {
+	Dir string
+	TTL time.Duration
+}
+
+func (r *FileRegistry) Heartbeat(ctx context.Context, node Node) error {
+	return r.write(filepath.Join(r.Dir, "nodes", node.ID+".json"), node)
+}
+
+func (r *FileRegistry) Nodes(ctx context.Context) ([]Node, error) {
+	dir := filepath.Join(r.Dir, "nodes")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var nodes []Node
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		var node Node
+		if err := r.read(filepath.Join(dir, file.Name()), &node); err != nil {
+			continue
+		}
+		if time.Since(node.HeartbeatAt) <= r.TTL {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func (r *FileRegistry) Place(ctx context.Context, placement Placement) error {
+	return r.write(r.placementPath(placement.RuntimeID), placement)
+}
+
+func (r *FileRegistry) Lookup(ctx context.Context, runtimeID string) (*Placement, error) {
+	placement := new(Placement)
+	if err := r.read(r.placementPath(runtimeID), placement); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return placement, nil
+}
+
+func (r *FileRegistry) Remove(ctx context.Context, runtimeID string, nodeID string) error {
+	placement, err := r.Lookup(ctx, runtimeID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil || placement.NodeID != nodeID {
+		return err
+	}
+	if err := os.Remove(r.placementPath(runtimeID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove placement: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing: the registry holds no connections.
+func (r *FileRegistry) Close() error {
+	return nil
+}
+
+func (r *FileRegistry) placementPath(runtimeID string) string {
+	return filepath.Join(r.Dir, "placements", runtimeID+".json")
+}
+
+func (r *FileRegistry) write(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write registry entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write registry entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write registry entry: %w", err)
+	}
+	return nil
+}
+
+func (r *FileRegistry) read(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode registry entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RedisRegistry keeps the registry in Redis: every node under Prefix+"node:<id>" with the TTL
+// as expiry, and the placements in the Prefix+"placements" hash.
+type RedisRegistry struct {
+	client *redisClient
+	Prefix string
+	TTL    time.Duration
+}
+
+func (r *RedisRegistry) Heartbeat(ctx context.Context, node Node) error {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node: %w", err)
+	}
+	_, err = r.client.Do(ctx, "SET", r.Prefix+"node:"+node.ID, string(data), "PX", strconv.FormatInt(r.TTL.Milliseconds(), 10))
+	return err
+}
+
+func (r *RedisRegistry) Nodes(ctx context.Context) ([]Node, error) {
+	var nodes []Node
+	cursor := "0"
+	for {
+		reply, err := r.client.Do(ctx, "SCAN", cursor, "MATCH", r.Prefix+"node:*", "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		keys, _ := page[1].([]any)
+		for _, key := range keys {
+			value, err := r.client.Do(ctx, "GET", fmt.Sprint(key))
+			if err != nil {
+				return nil, err
+			}
+			var node Node
+			if data, ok := value.(string); ok && json.Unmarshal([]byte(data), &node) == nil {
+				nodes = append(nodes, node)
+			}
+		}
+		if cursor = fmt.Sprint(page[0]); cursor == "0" {
+			return nodes, nil
+		}
+	}
+}
+
+func (r *RedisRegistry) Place(ctx context.Context, placement Placement) error {
+	data, err := json.Marshal(placement)
+	if err != nil {
+		return fmt.Errorf("failed to marshal placement: %w", err)
+	}
+	_, err = r.client.Do(ctx, "HSET", r.Prefix+"placements", placement.RuntimeID, string(data))
+	return err
+}
+
+func (r *RedisRegistry) Lookup(ctx context.Context, runtimeID string) (*Placement, error) {
+	reply, err := r.client.Do(ctx, "HGET", r.Prefix+"placements", runtimeID)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	placement := new(Placement)
+	if err := json.Unmarshal([]byte(data), placement); err != nil {
+		return nil, fmt.Errorf("failed to decode placement: %w", err)
+	}
+	return placement, nil
+}
+
+func (r *RedisRegistry) Remove(ctx context.Context, runtimeID string, nodeID string) error {
+	placement, err := r.Lookup(ctx, runtimeID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil || placement.NodeID != nodeID {
+		return err
+	}
+	_, err = r.client.Do(ctx, "HDEL", r.Prefix+"placements", runtimeID)
+	return err
+}
+
+func (r *RedisRegistry) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// runtimeFlushInterval batches the record writes of runtimes that change in quick succession.
+const runtimeFlushInterval = time.Second
+
+// RedisRuntimes keeps the runtimes in memory and mirrors their records to the
+// Prefix+"runtimes" hash. Changes are written by a background flush, so Update never waits
+// on Redis.
+type RedisRuntimes struct {
+	MemoryRuntimes
+	client *redisClient
+	Prefix string
+	Node   string
+
+	mu      sync.Mutex
+	dirty   map[string]*models.Runtime // nil marks a deleted runtime
+	wake    chan struct{}
+	flushed chan struct{} // closed when flushLoop returns
+}
+
+// NewRedisRuntimes connects to the Redis at rawURL and flushes records until ctx is done. Close
+// releases the connection once it is.
+func NewRedisRuntimes(ctx context.Context, rawURL string, node string) (*RedisRuntimes, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	r := &RedisRuntimes{
+		client:  client,
+		Prefix:  "aegisx:",
+		Node:    node,
+		dirty:   map[string]*models.Runtime{},
+		wake:    make(chan struct{}, 1),
+		flushed: make(chan struct{}),
+	}
+	go r.flushLoop(ctx)
+	return r, nil
+}
+
+func (r *RedisRuntimes) Store(runtime *models.Runtime) {
+	// A regenerated runtime replaces the previous one under the same ID.
+	if previous, ok := r.Load(runtime.Snapshot().ID); ok && previous != runtime {
+		previous.OnChange(nil)
+	}
+	r.MemoryRuntimes.Store(runtime)
+	runtime.OnChange(r.markDirty)
+	r.markDirty(runtime)
+}
+
+func (r *RedisRuntimes) Delete(runtimeID string) {
+	if runtime, ok := r.Load(runtimeID); ok {
+		runtime.OnChange(nil)
+	}
+	r.MemoryRuntimes.Delete(runtimeID)
+	r.mark(runtimeID, nil)
+}
+
+func (r *RedisRuntimes) Records(ctx context.Context) ([]RuntimeRecord, error) {
+	reply, err := r.client.Do(ctx, "HGETALL", r.Prefix+"runtimes")
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := reply.([]any)
+	var records []RuntimeRecord
+	for i := 1; i < len(fields); i += 2 {
+		var record RuntimeRecord
+		if data, ok := fields[i].(string); ok && json.Unmarshal([]byte(data), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (r *RedisRuntimes) markDirty(runtime *models.Runtime) {
+	r.mark(runtime.Snapshot().ID, runtime)
+}
+
+func (r *RedisRuntimes) mark(runtimeID string, runtime *models.Runtime) {
+	r.mu.Lock()
+	r.dirty[runtimeID] = runtime
+	r.mu.Unlock()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close waits for the flushes to end, which they do once the context of NewRedisRuntimes is
+// done, and closes the connection.
+func (r *RedisRuntimes) Close() error {
+	<-r.flushed
+	return r.client.Close()
+}
+
+func (r *RedisRuntimes) flushLoop(ctx context.Context) {
+	defer close(r.flushed)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(runtimeFlushInterval):
+		}
+		r.flush(context.Background())
+	}
+}
+
+// flush writes the records of the runtimes changed since the last flush. Failed writes stay
+// dirty for the next one.
+func (r *RedisRuntimes) flush(ctx context.Context) {
+	r.mu.Lock()
+	dirty := r.dirty
+	r.dirty = map[string]*models.Runtime{}
+	r.mu.Unlock()
+	for runtimeID, runtime := range dirty {
+		if err := r.write(ctx, runtimeID, runtime); err != nil {
+			log.Printf("failed to publish runtime %s to redis: %v", runtimeID, err)
+			r.mu.Lock()
+			if _, changed := r.dirty[runtimeID]; !changed {
+				r.dirty[runtimeID] = runtime
+			}
+			r.mu.Unlock()
+			r.mark(runtimeID, runtime)
+		}
+	}
+}
+
+func (r *RedisRuntimes) write(ctx context.Context, runtimeID string, runtime *models.Runtime) error {
+	if runtime == nil {
+		_, err := r.client.Do(ctx, "HDEL", r.Prefix+"runtimes", runtimeID)
+		return err
+	}
+	data, err := json.Marshal(recordOf(runtime, r.Node))
+	if err != nil {
+		return fmt.Errorf("failed to marshal runtime record: %w", err)
+	}
+	_, err = r.client.Do(ctx, "HSET", r.Prefix+"runtimes", runtimeID, string(data))
+	return err
+}
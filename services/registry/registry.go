@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+)
+
+// Node roles. Control nodes only route: they forward execute requests to workers and runtime
+// traffic to the owning node. Workers and standalone nodes host runtimes.
+const (
+	RoleAll     = "all"
+	RoleControl = "control"
+	RoleWorker  = "worker"
+)
+
+// DefaultTTL is how long a node counts as alive after its last heartbeat.
+const DefaultTTL = 30 * time.Second
+
+// ErrNotFound is returned by Lookup for runtimes no node has placed.
+var ErrNotFound = errors.New("runtime is not placed on any node")
+
+// Node is an aegisx process sharing the registry. URL is where the other nodes reach its HTTP
+// server.
+type Node struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Role        string    `json:"role"`
+	Runtimes    int       `json:"runtimes"`
+	HeartbeatAt time.Time `json:"heartbeatAt"`
+	// Draining nodes keep serving their runtimes but take no new executions.
+	Draining bool `json:"draining,omitempty"`
+}
+
+// Hosts reports whether the node runs runtimes.
+func (n Node) Hosts() bool {
+	return n.Role != RoleControl
+}
+
+// Placement records which node hosts a runtime.
+type Placement struct {
+	RuntimeID string    `json:"runtimeID"`
+	Tenant    string    `json:"tenant,omitempty"`
+	NodeID    string    `json:"nodeID"`
+	NodeURL   string    `json:"nodeURL"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Registry is the state shared by the nodes of a deployment: which nodes are alive and which
+// node hosts each runtime.
+type Registry interface {
+	// Heartbeat records that node is alive.
+	Heartbeat(ctx context.Context, node Node) error
+	// Nodes returns the nodes whose last heartbeat is within the TTL.
+	Nodes(ctx context.Context) ([]Node, error)
+	// Place records that placement.NodeID hosts the runtime, replacing any earlier placement.
+	Place(ctx context.Context, placement Placement) error
+	// Lookup returns the runtime's placement or ErrNotFound.
+	Lookup(ctx context.Context, runtimeID string) (*Placement, error)
+	// Remove drops the runtime's placement if nodeID still hosts it.
+	Remove(ctx context.Context, runtimeID string, nodeID string) error
+	// Close releases the registry's connections.
+	Close() error
+}
+
+// New returns the registry selected by cfg.Registry, or nil for a single node deployment.
+func New(cfg *config.Config) (Registry, error) {
+	ttl := cfg.RegistryTTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	switch cfg.Registry {
+	case "":
+		return nil, nil
+	case "file":
+		return &FileRegistry{Dir: cfg.RegistryStore, TTL: ttl}, nil
+	case "redis":
+		client, err := newRedisClient(cfg.RegistryURL)
+		if err != nil {
+			return nil, err
+		}
+		return &RedisRegistry{client: client, Prefix: "aegisx:", TTL: ttl}, nil
+	}
+	return nil, fmt.Errorf("unknown registry: %s", cfg.Registry)
+}
+
+// Heartbeat announces node every third of ttl until ctx is done; runtimes reports the node's
+// current number of active runtimes and draining whether it is being drained.
+func Heartbeat(ctx context.Context, registry Registry, node Node, ttl time.Duration, runtimes func() int, draining func() bool) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		node.Runtimes = runtimes()
+		node.Draining = draining()
+		node.HeartbeatAt = time.Now()
+		if err := registry.Heartbeat(ctx, node); err != nil {
+			log.Printf("failed to send heartbeat of node %s: %v", node.ID, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LeastLoaded returns the live node hosting the fewest runtimes, or nil when no node hosts
+// runtimes. Draining nodes are skipped.
+func LeastLoaded(nodes []Node) *Node {
+	var best *Node
+	for i, node := range nodes {
+		if node.Hosts() && !node.Draining && (best == nil || node.Runtimes < best.Runtimes) {
+			best = &nodes[i]
+		}
+	}
+	return best
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+)
+
+// RuntimeRegistry holds the runtimes hosted by this node. Runtimes carry interpreters and log
+// buffers, so the live objects always stay in process; shared implementations also publish a
+// record of each runtime for the other nodes and for a restarted process.
+type RuntimeRegistry interface {
+	Load(runtimeID string) (*models.Runtime, bool)
+	Store(runtime *models.Runtime)
+	Delete(runtimeID string)
+	// Range calls fn for each runtime until it returns false.
+	Range(fn func(runtime *models.Runtime) bool)
+	// Records returns the published records of the runtimes of every node.
+	Records(ctx context.Context) ([]RuntimeRecord, error)
+}
+
+// RuntimeRecord is the part of a runtime that is shared between nodes.
+type RuntimeRecord struct {
+	ID        string              `json:"id"`
+	Tenant    string              `json:"tenant,omitempty"`
+	Code      string              `json:"code"`
+	State     models.RuntimeState `json:"state"`
+	Port      int                 `json:"port"`
+	Node      string              `json:"node"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+func recordOf(runtime *models.Runtime, node string) RuntimeRecord {
+	info := runtime.Snapshot()
+	return RuntimeRecord{
+		ID:        info.ID,
+		Tenant:    info.Tenant,
+		Code:      info.Code,
+		State:     info.State,
+		Port:      info.Port,
+		Node:      node,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// MemoryRuntimes is the single node RuntimeRegistry. The zero value is ready to use.
+type MemoryRuntimes struct {
+	runtimes sync.Map
+}
+
+func (m *MemoryRuntimes) Load(runtimeID string) (*models.Runtime, bool) {
+	runtime, ok := m.runtimes.Load(runtimeID)
+	if !ok {
+		return nil, false
+	}
+	return runtime.(*models.Runtime), true
+}
+
+func (m *MemoryRuntimes) Store(runtime *models.Runtime) {
+	m.runtimes.Store(runtime.Snapshot().ID, runtime)
+}
+
+func (m *MemoryRuntimes) Delete(runtimeID string) {
+	m.runtimes.Delete(runtimeID)
+}
+
+func (m *MemoryRuntimes) Range(fn func(runtime *models.Runtime) bool) {
+	m.runtimes.Range(func(_, value any) bool {
+		return fn(value.(*models.Runtime))
+	})
+}
+
+func (m *MemoryRuntimes) Records(ctx context.Context) ([]RuntimeRecord, error) {
+	var records []RuntimeRecord
+	m.Range(func(runtime *models.Runtime) bool {
+		records = append(records, recordOf(runtime, ""))
+		return true
+	})
+	return records, nil
+}
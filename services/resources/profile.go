@@ -0,0 +1,226 @@
+package resources
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// profile holds the parts of a pprof profile the sampler reads: the values of each sample, the
+// runtime it is labelled with and the functions on its stack.
+type profile struct {
+	sampleTypes []string
+	samples     []sample
+}
+
+type sample struct {
+	values    []int64
+	runtimeID string
+	functions []string
+}
+
+// valueIndex returns the index of the sample values of type, or -1.
+func (p *profile) valueIndex(sampleType string) int {
+	for i, t := range p.sampleTypes {
+		if t == sampleType {
+			return i
+		}
+	}
+	return -1
+}
+
+// The raw messages of profile.proto, resolved into a profile once the string table is read.
+type rawProfile struct {
+	sampleTypes []int64
+	samples     []rawSample
+	locations   map[uint64][]uint64 // function IDs by location ID
+	functions   map[uint64]int64    // name string index by function ID
+	strings     []string
+}
+
+type rawSample struct {
+	locations []uint64
+	values    []int64
+	labels    [][2]int64 // key and value string indexes
+}
+
+// parseProfile decodes a gzipped profile as written by runtime/pprof.
+func parseProfile(r io.Reader) (*profile, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress profile: %w", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress profile: %w", err)
+	}
+	raw := &rawProfile{locations: map[uint64][]uint64{}, functions: map[uint64]int64{}}
+	err = walk(data, func(num protowire.Number, typ protowire.Type, field []byte, value uint64) error {
+		switch num {
+		case 1: // sample_type
+			return walk(field, func(num protowire.Number, _ protowire.Type, _ []byte, value uint64) error {
+				if num == 1 {
+					raw.sampleTypes = append(raw.sampleTypes, int64(value))
+				}
+				return nil
+			})
+		case 2: // sample
+			s, err := parseSample(field)
+			raw.samples = append(raw.samples, s)
+			return err
+		case 4: // location
+			return parseLocation(field, raw.locations)
+		case 5: // function
+			var id uint64
+			var name int64
+			err := walk(field, func(num protowire.Number, _ protowire.Type, _ []byte, value uint64) error {
+				switch num {
+				case 1:
+					id = value
+				case 2:
+					name = int64(value)
+				}
+				return nil
+			})
+			raw.functions[id] = name
+			return err
+		case 6: // string_table
+			raw.strings = append(raw.strings, string(field))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return raw.resolve(), nil
+}
+
+func parseSample(data []byte) (rawSample, error) {
+	var s rawSample
+	err := walk(data, func(num protowire.Number, typ protowire.Type, field []byte, value uint64) error {
+		switch num {
+		case 1: // location_id
+			ids, err := repeated(typ, field, value)
+			s.locations = append(s.locations, ids...)
+			return err
+		case 2: // value
+			values, err := repeated(typ, field, value)
+			for _, v := range values {
+				s.values = append(s.values, int64(v))
+			}
+			return err
+		case 3: // label
+			var label [2]int64
+			err := walk(field, func(num protowire.Number, _ protowire.Type, _ []byte, value uint64) error {
+				if num == 1 || num == 2 {
+					label[num-1] = int64(value)
+				}
+				return nil
+			})
+			s.labels = append(s.labels, label)
+			return err
+		}
+		return nil
+	})
+	return s, err
+}
+
+func parseLocation(data []byte, locations map[uint64][]uint64) error {
+	var id uint64
+	var functions []uint64
+	err := walk(data, func(num protowire.Number, _ protowire.Type, field []byte, value uint64) error {
+		switch num {
+		case 1:
+			id = value
+		case 4: // line, one per inlined function
+			return walk(field, func(num protowire.Number, _ protowire.Type, _ []byte, value uint64) error {
+				if num == 1 {
+					functions = append(functions, value)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	locations[id] = functions
+	return err
+}
+
+func (raw *rawProfile) str(index int64) string {
+	if index < 0 || index >= int64(len(raw.strings)) {
+		return ""
+	}
+	return raw.strings[index]
+}
+
+func (raw *rawProfile) resolve() *profile {
+	p := &profile{}
+	for _, t := range raw.sampleTypes {
+		p.sampleTypes = append(p.sampleTypes, raw.str(t))
+	}
+	for _, rs := range raw.samples {
+		s := sample{values: rs.values}
+		for _, label := range rs.labels {
+			if raw.str(label[0]) == Label {
+				s.runtimeID = raw.str(label[1])
+			}
+		}
+		for _, location := range rs.locations {
+			for _, function := range raw.locations[location] {
+				s.functions = append(s.functions, raw.str(raw.functions[function]))
+			}
+		}
+		p.samples = append(p.samples, s)
+	}
+	return p
+}
+
+// walk calls fn with every field of a message: the bytes of length delimited fields and the
+// value of the others.
+func walk(data []byte, fn func(num protowire.Number, typ protowire.Type, field []byte, value uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("malformed profile: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		var field []byte
+		var value uint64
+		switch typ {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			field, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return fmt.Errorf("malformed profile: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if err := fn(num, typ, field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repeated returns the values of a repeated integer field, which is either packed or one
+// value per field.
+func repeated(typ protowire.Type, field []byte, value uint64) ([]uint64, error) {
+	if typ != protowire.BytesType {
+		return []uint64{value}, nil
+	}
+	var values []uint64
+	for len(field) > 0 {
+		v, n := protowire.ConsumeVarint(field)
+		if n < 0 {
+			return nil, fmt.Errorf("malformed profile: %w", protowire.ParseError(n))
+		}
+		values = append(values, v)
+		field = field[n:]
+	}
+	return values, nil
+}
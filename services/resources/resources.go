@@ -0,0 +1,184 @@
+// Package resources attributes the CPU time, allocations and goroutines of the process to the
+// runtimes whose programs use them. The goroutine evaluating a program is labelled with its
+// runtime, a label every goroutine the program starts inherits, and the sampler periodically
+// profiles the process and sums the labelled samples.
+package resources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/models"
+)
+
+// Label is the profiler label holding the ID of the runtime a goroutine works for.
+const Label = "aegisx_runtime"
+
+// mallocFunction is on the stack of every CPU sample taken while allocating.
+const mallocFunction = "runtime.mallocgc"
+
+// Do calls fn with the goroutine, and every goroutine it starts, labelled with runtimeID.
+func Do(ctx context.Context, runtimeID string, fn func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels(Label, runtimeID), fn)
+}
+
+// Sampler profiles the process for Window every Interval. The CPU profiler slows the whole
+// process down, so CPU time is measured over the window only and extrapolated. Heap
+// profiles carry no labels at all, so the bytes allocated in the window are split between the
+// runtimes by their share of the CPU samples taken inside the allocator.
+type Sampler struct {
+	Interval time.Duration
+	Window   time.Duration
+
+	mu    sync.Mutex
+	usage map[string]*models.RuntimeResources
+}
+
+func NewSampler(cfg config.RuntimeResourcesConfig) *Sampler {
+	return &Sampler{Interval: cfg.Interval, Window: cfg.Window, usage: map[string]*models.RuntimeResources{}}
+}
+
+// Run samples every Interval until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Sample(ctx); err != nil {
+			log.Printf("Failed to sample runtime resources: %v", err)
+		}
+	}
+}
+
+// Usage returns the runtime's latest sampled usage.
+func (s *Sampler) Usage(runtimeID string) (models.RuntimeResources, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage, ok := s.usage[runtimeID]
+	if !ok {
+		return models.RuntimeResources{}, false
+	}
+	return *usage, true
+}
+
+// DeleteRuntime forgets the runtime's usage.
+func (s *Sampler) DeleteRuntime(runtimeID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.usage, runtimeID)
+	metrics.RuntimeGoroutines.Set(0, runtimeID)
+}
+
+// Sample profiles the process for Window and attributes what it used to the runtimes. It
+// fails when the CPU profiler is already running, e.g. for a profile requested by an operator.
+func (s *Sampler) Sample(ctx context.Context) error {
+	var before, after runtime.MemStats
+	var cpu bytes.Buffer
+	runtime.ReadMemStats(&before)
+	if err := pprof.StartCPUProfile(&cpu); err != nil {
+		return fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	start := time.Now()
+	timer := time.NewTimer(s.Window)
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	timer.Stop()
+	pprof.StopCPUProfile()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	cpuProfile, err := parseProfile(&cpu)
+	if err != nil {
+		return err
+	}
+	var goroutines bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&goroutines, 0); err != nil {
+		return fmt.Errorf("failed to write goroutine profile: %w", err)
+	}
+	goroutineProfile, err := parseProfile(&goroutines)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, sample := range goroutineProfile.samples {
+		if sample.runtimeID != "" && len(sample.values) > 0 {
+			counts[sample.runtimeID] += int(sample.values[0])
+		}
+	}
+	cpuNanos := map[string]int64{}
+	allocSamples := map[string]int64{}
+	totalAllocSamples := int64(0)
+	if index := cpuProfile.valueIndex("cpu"); index >= 0 {
+		for _, sample := range cpuProfile.samples {
+			if index >= len(sample.values) {
+				continue
+			}
+			allocating := false
+			for _, function := range sample.functions {
+				if function == mallocFunction {
+					allocating = true
+					break
+				}
+			}
+			if allocating {
+				totalAllocSamples++
+			}
+			if sample.runtimeID == "" {
+				continue
+			}
+			cpuNanos[sample.runtimeID] += sample.values[index]
+			if allocating {
+				allocSamples[sample.runtimeID]++
+			}
+		}
+	}
+	allocated := float64(after.TotalAlloc - before.TotalAlloc)
+	scale := s.Interval.Seconds() / elapsed.Seconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sampledAt := time.Now()
+	for runtimeID := range counts {
+		if _, ok := s.usage[runtimeID]; !ok {
+			s.usage[runtimeID] = &models.RuntimeResources{}
+		}
+	}
+	for runtimeID := range cpuNanos {
+		if _, ok := s.usage[runtimeID]; !ok {
+			s.usage[runtimeID] = &models.RuntimeResources{}
+		}
+	}
+	// Runtimes missing from this sample have finished; their totals are kept until deleted.
+	for runtimeID, usage := range s.usage {
+		cpuSeconds := float64(cpuNanos[runtimeID]) / float64(time.Second)
+		allocBytes := 0.0
+		if totalAllocSamples > 0 {
+			allocBytes = allocated * float64(allocSamples[runtimeID]) / float64(totalAllocSamples)
+		}
+		usage.Goroutines = counts[runtimeID]
+		usage.CPUCores = cpuSeconds / elapsed.Seconds()
+		usage.CPUSeconds += cpuSeconds * scale
+		usage.AllocBytesPerSecond = allocBytes / elapsed.Seconds()
+		usage.AllocBytes += int64(allocBytes * scale)
+		usage.SampledAt = sampledAt
+		metrics.RuntimeGoroutines.Set(float64(usage.Goroutines), runtimeID)
+		metrics.RuntimeCPUSeconds.Add(cpuSeconds*scale, runtimeID)
+		metrics.RuntimeAllocBytes.Add(allocBytes*scale, runtimeID)
+	}
+	return nil
+}
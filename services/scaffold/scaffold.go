@@ -0,0 +1,160 @@
+// Package scaffold holds pre-built programs with parameters, which runtimes can be created
+// from instead of generated code.
+package scaffold
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExamplePrefix is the runtime prefix scaffolds are written for, together with any value of
+// the port constant; the executer moves them to the runtime's own prefix and port.
+const ExamplePrefix = "/runtime/example"
+
+var (
+	// ErrNotFound is returned for a scaffold name that is not registered.
+	ErrNotFound = errors.New("scaffold not found")
+	// ErrInvalid is returned when registering a scaffold with a bad name or placeholders.
+	ErrInvalid = errors.New("invalid scaffold")
+	// ErrMissingParameters is returned when rendering without a value for every parameter.
+	ErrMissingParameters = errors.New("missing scaffold parameters")
+)
+
+var (
+	nameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
+	// Placeholders use square brackets, since Go programs serving HTML are full of the braces
+	// of html/template.
+	placeholderRegex = regexp.MustCompile(`\[\[\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\]\]`)
+)
+
+// Scaffold is a program with placeholders written [[name]] for each of its Parameters;
+// Defaults supplies values for the ones a caller may leave out.
+type Scaffold struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description,omitempty"`
+	Parameters  []string          `yaml:"parameters" json:"parameters"`
+	Defaults    map[string]string `yaml:"defaults" json:"defaults,omitempty"`
+	Code        string            `yaml:"code" json:"-"`
+}
+
+//go:embed scaffolds.yaml
+var builtinYAML []byte
+
+var (
+	mu        sync.RWMutex
+	scaffolds = map[string]Scaffold{}
+)
+
+func init() {
+	var builtin []Scaffold
+	if err := yaml.Unmarshal(builtinYAML, &builtin); err != nil {
+		panic(fmt.Sprintf("scaffold: failed to parse bundled scaffolds: %v", err))
+	}
+	for _, s := range builtin {
+		if err := Register(s); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register makes s available by name, replacing a scaffold of the same name. Every
+// placeholder of its code must be one of its parameters.
+func Register(s Scaffold) error {
+	if !nameRegex.MatchString(s.Name) {
+		return fmt.Errorf("%w: bad name %q", ErrInvalid, s.Name)
+	}
+	for _, match := range placeholderRegex.FindAllStringSubmatch(s.Code, -1) {
+		if !slices.Contains(s.Parameters, match[1]) {
+			return fmt.Errorf("%w: %s uses undeclared parameter %s", ErrInvalid, s.Name, match[1])
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	scaffolds[s.Name] = s
+	return nil
+}
+
+// Get returns the scaffold called name.
+func Get(name string) (Scaffold, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := scaffolds[name]
+	if !ok {
+		return Scaffold{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return s, nil
+}
+
+// List returns the registered scaffolds sorted by name.
+func List() []Scaffold {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Scaffold, 0, len(scaffolds))
+	for _, s := range scaffolds {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LoadDir registers the scaffolds of every .yaml file in dir, each holding a list of them.
+func LoadDir(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return 0, err
+	}
+	loaded := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return loaded, err
+		}
+		var list []Scaffold
+		if err := yaml.Unmarshal(data, &list); err != nil {
+			return loaded, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for _, s := range list {
+			if err := Register(s); err != nil {
+				return loaded, fmt.Errorf("%s: %w", path, err)
+			}
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// Render returns the program with params, falling back to Defaults, in place of the
+// placeholders. Values are HTML-escaped, backticks included, so they can only ever be text
+// inside the program's HTML and raw string literals, never code.
+func (s Scaffold) Render(params map[string]string) (string, error) {
+	values := map[string]string{}
+	var missing []string
+	for _, name := range s.Parameters {
+		value, ok := params[name]
+		if !ok {
+			value, ok = s.Defaults[name]
+		}
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		values[name] = strings.ReplaceAll(html.EscapeString(value), "`", "&#96;")
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingParameters, strings.Join(missing, ", "))
+	}
+	return placeholderRegex.ReplaceAllStringFunc(s.Code, func(placeholder string) string {
+		return values[placeholderRegex.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
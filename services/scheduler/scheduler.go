@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/util"
+)
+
+// Scheduler starts and stops runtimes according to their schedules. It wakes at the start of
+// every minute and acts on the runtimes whose start or stop expression matches that minute.
+type Scheduler struct {
+	ExecutorService *executer.ExecuterService
+}
+
+// Run checks the schedules every minute until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+		s.Tick(ctx, next)
+	}
+}
+
+// Tick starts and stops the scheduled runtimes due in the minute of now. A stop wins when
+// both expressions match. Archived runtimes are left alone.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	for _, runtime := range s.ExecutorService.ListRuntimes() {
+		if runtime.Schedule == nil || runtime.Archived {
+			continue
+		}
+		local := now.In(location(runtime.Schedule.Timezone))
+		switch {
+		case matches(runtime.Schedule.Stop, local) && runtime.State.Active() && runtime.State != models.RSSTOPPING:
+			log.Printf("Schedule stopping runtime %s", runtime.ID)
+			if err := s.ExecutorService.StopRuntime(ctx, runtime.ID); err != nil {
+				log.Printf("Scheduled stop of runtime %s failed: %v", runtime.ID, err)
+			}
+		case matches(runtime.Schedule.Start, local) && !runtime.State.Active():
+			log.Printf("Schedule starting runtime %s", runtime.ID)
+			if err := s.ExecutorService.StartRuntime(ctx, runtime.ID); err != nil {
+				log.Printf("Scheduled start of runtime %s failed: %v", runtime.ID, err)
+			}
+		}
+	}
+}
+
+func matches(expr string, t time.Time) bool {
+	if expr == "" {
+		return false
+	}
+	schedule, err := util.ParseCron(expr)
+	if err != nil {
+		return false
+	}
+	return schedule.Matches(t)
+}
+
+// location resolves a schedule's time zone, the server's own when none is set.
+func location(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,308 @@
+// Package secrets keeps the credentials generated programs need, such as API keys, out of
+// prompts and generated code. Secrets are registered by name per tenant, encrypted at rest with
+// a key derived from the configured master key, and read by programs at run time through the
+// aegisx/secrets host package.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// ImportPath is the package generated programs import to read their tenant's secrets.
+const ImportPath = "aegisx/secrets"
+
+var (
+	// ErrNotFound is returned for a secret name the tenant has not registered.
+	ErrNotFound = errors.New("secret not found")
+	// ErrInvalid is returned for a secret with a bad name or an empty value.
+	ErrInvalid = errors.New("invalid secret")
+)
+
+var (
+	nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+	// referenceRegex matches the references to secrets in prompts, written {{secret:NAME}}.
+	referenceRegex = regexp.MustCompile(`{{\s*secret:([A-Za-z_][A-Za-z0-9_]{0,63})\s*}}`)
+)
+
+// redacted replaces secret values scrubbed from text.
+const redacted = "[REDACTED]"
+
+// minScrubLength is the shortest value Scrub replaces; shorter values would match all over
+// ordinary output.
+const minScrubLength = 4
+
+// Secret describes a registered secret. Its value is never returned by the API.
+type Secret struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// record is a secret as persisted, its value sealed with the tenant and name as additional data
+// so a value cannot be moved to another secret by editing the file.
+type record struct {
+	Tenant    string    `json:"tenant,omitempty"`
+	Name      string    `json:"name"`
+	Value     []byte    `json:"value"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type entry struct {
+	Secret
+	value string
+}
+
+// Store keeps the secrets of every tenant in File, encrypted with AES-GCM.
+type Store struct {
+	File string
+
+	aead    cipher.AEAD
+	mu      sync.RWMutex
+	secrets map[string]map[string]entry // by tenant and name
+}
+
+// NewStore opens the secrets persisted in file, which are decrypted with masterKey.
+func NewStore(file string, masterKey string) (*Store, error) {
+	key := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secrets cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secrets cipher: %w", err)
+	}
+	s := &Store{File: file, aead: aead, secrets: map[string]map[string]entry{}}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func additionalData(tenant string, name string) []byte {
+	return []byte(tenant + "/" + name)
+}
+
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to decode secrets: %w", err)
+	}
+	nonceSize := s.aead.NonceSize()
+	for _, r := range records {
+		if len(r.Value) < nonceSize {
+			return fmt.Errorf("failed to decrypt secret %s: value is truncated", r.Name)
+		}
+		value, err := s.aead.Open(nil, r.Value[:nonceSize], r.Value[nonceSize:], additionalData(r.Tenant, r.Name))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret %s, is secrets_master_key the one it was stored with? %w", r.Name, err)
+		}
+		s.tenant(r.Tenant)[r.Name] = entry{Secret: Secret{Name: r.Name, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt}, value: string(value)}
+	}
+	return nil
+}
+
+// tenant returns the tenant's secrets, creating the map; the caller holds the lock.
+func (s *Store) tenant(tenant string) map[string]entry {
+	secrets, ok := s.secrets[tenant]
+	if !ok {
+		secrets = map[string]entry{}
+		s.secrets[tenant] = secrets
+	}
+	return secrets
+}
+
+// save encrypts and persists every secret; the caller holds the lock.
+func (s *Store) save() error {
+	records := []record{}
+	for tenant, secrets := range s.secrets {
+		for name, e := range secrets {
+			nonce := make([]byte, s.aead.NonceSize())
+			if _, err := rand.Read(nonce); err != nil {
+				return fmt.Errorf("failed to generate nonce: %w", err)
+			}
+			value := s.aead.Seal(nonce, nonce, []byte(e.value), additionalData(tenant, name))
+			records = append(records, record{Tenant: tenant, Name: name, Value: value, CreatedAt: e.CreatedAt, UpdatedAt: e.UpdatedAt})
+		}
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.File), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp := s.File + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	if err := os.Rename(tmp, s.File); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	return nil
+}
+
+// Put registers or replaces the tenant's secret called name.
+func (s *Store) Put(tenant string, name string, value string) (Secret, error) {
+	if !nameRegex.MatchString(name) {
+		return Secret{}, fmt.Errorf("%w: name must be 1-64 letters, digits or '_' and not start with a digit", ErrInvalid)
+	}
+	if value == "" {
+		return Secret{}, fmt.Errorf("%w: value is required", ErrInvalid)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secrets := s.tenant(tenant)
+	previous, existed := secrets[name]
+	now := time.Now()
+	e := entry{Secret: Secret{Name: name, CreatedAt: now, UpdatedAt: now}, value: value}
+	if existed {
+		e.CreatedAt = previous.CreatedAt
+	}
+	secrets[name] = e
+	if err := s.save(); err != nil {
+		if existed {
+			secrets[name] = previous
+		} else {
+			delete(secrets, name)
+		}
+		return Secret{}, err
+	}
+	return e.Secret, nil
+}
+
+// Delete removes the tenant's secret called name.
+func (s *Store) Delete(tenant string, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.secrets[tenant][name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.secrets[tenant], name)
+	if err := s.save(); err != nil {
+		s.secrets[tenant][name] = previous
+		return err
+	}
+	return nil
+}
+
+// List returns the tenant's secrets sorted by name.
+func (s *Store) List(tenant string) []Secret {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := []Secret{}
+	for _, e := range s.secrets[tenant] {
+		list = append(list, e.Secret)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns the value of the tenant's secret called name.
+func (s *Store) Get(tenant string, name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.secrets[tenant][name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return e.value, nil
+}
+
+// Check returns an error naming the secrets referenced by prompt that the tenant has not
+// registered.
+func (s *Store) Check(tenant string, prompt string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var missing []string
+	for _, name := range References(prompt) {
+		if _, ok := s.secrets[tenant][name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Scrub replaces the values of the tenant's secrets in text, longest first so a secret
+// containing another is replaced whole.
+func (s *Store) Scrub(tenant string, text string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var values []string
+	for _, e := range s.secrets[tenant] {
+		if len(e.value) >= minScrubLength && strings.Contains(text, e.value) {
+			values = append(values, e.value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, redacted)
+	}
+	return text
+}
+
+// Exports returns the interpreter symbols for the aegisx/secrets package, bound to tenant.
+func (s *Store) Exports(tenant string) interp.Exports {
+	return interp.Exports{
+		ImportPath + "/secrets": {
+			"Get": reflect.ValueOf(func(name string) (string, error) {
+				return s.Get(tenant, name)
+			}),
+		},
+	}
+}
+
+// References returns the distinct secret names referenced by prompt in order of appearance.
+func References(prompt string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, match := range referenceRegex.FindAllStringSubmatch(prompt, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Requirement is the generation instruction telling the model how to read the secrets
+// referenced by a prompt, or "" when it references none. Only the names reach the model.
+func Requirement(prompt string) string {
+	names := References(prompt)
+	if len(names) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = `"` + name + `"`
+	}
+	return `The requirements reference secrets as {{secret:NAME}}. Read them at run time: import "` + ImportPath + `" and call secrets.Get(name string) (string, error) with ` + strings.Join(quoted, ", ") + `. Do NOT hard-code, print, log or send secret values to the browser.`
+}
@@ -0,0 +1,148 @@
+// Package share gives runtimes short links, /r/<slug>, that are easy to type from a slide or
+// to scan as a QR code from a phone.
+package share
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// slugAlphabet leaves out characters that are easily confused when typed from a screen: 0, 1,
+// i, l and o.
+const slugAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// slugLength gives about 27 billion slugs.
+const slugLength = 7
+
+// Link is the short link of a runtime.
+type Link struct {
+	Slug      string    `json:"slug"`
+	RuntimeID string    `json:"runtimeID"`
+	Tenant    string    `json:"tenant,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store keeps one short link per runtime, persisted in File when it is set.
+type Store struct {
+	File string
+
+	mu    sync.RWMutex
+	links map[string]Link // by slug
+}
+
+// Load reads the persisted links.
+func (s *Store) Load() error {
+	if s.File == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read short links: %w", err)
+	}
+	var links []Link
+	if err := json.Unmarshal(data, &links); err != nil {
+		return fmt.Errorf("failed to decode short links: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links = map[string]Link{}
+	for _, link := range links {
+		s.links[link.Slug] = link
+	}
+	return nil
+}
+
+// save persists the links; the caller holds the lock.
+func (s *Store) save() error {
+	if s.File == "" {
+		return nil
+	}
+	links := make([]Link, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, link)
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return fmt.Errorf("failed to marshal short links: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.File), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(s.File, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write short links: %w", err)
+	}
+	return nil
+}
+
+// Create returns the runtime's short link, creating it on first use so sharing a runtime
+// twice gives the same link.
+func (s *Store) Create(runtimeID string, tenant string) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, link := range s.links {
+		if link.RuntimeID == runtimeID {
+			return link, nil
+		}
+	}
+	if s.links == nil {
+		s.links = map[string]Link{}
+	}
+	var slug string
+	for {
+		var err error
+		if slug, err = newSlug(); err != nil {
+			return Link{}, err
+		}
+		if _, taken := s.links[slug]; !taken {
+			break
+		}
+	}
+	link := Link{Slug: slug, RuntimeID: runtimeID, Tenant: tenant, CreatedAt: time.Now()}
+	s.links[slug] = link
+	if err := s.save(); err != nil {
+		delete(s.links, slug)
+		return Link{}, err
+	}
+	return link, nil
+}
+
+// Resolve returns the link with the slug.
+func (s *Store) Resolve(slug string) (Link, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	link, ok := s.links[slug]
+	return link, ok
+}
+
+// Remove deletes the runtime's short link, if it has one.
+func (s *Store) Remove(runtimeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for slug, link := range s.links {
+		if link.RuntimeID == runtimeID {
+			delete(s.links, slug)
+			return s.save()
+		}
+	}
+	return nil
+}
+
+func newSlug() (string, error) {
+	buf := make([]byte, slugLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate slug: %w", err)
+	}
+	// The slight bias of the modulo does not matter for link slugs.
+	for i, b := range buf {
+		buf[i] = slugAlphabet[int(b)%len(slugAlphabet)]
+	}
+	return string(buf), nil
+}
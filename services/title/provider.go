@@ -0,0 +1,133 @@
+package title
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/util"
+)
+
+const (
+	ProviderLLM     = "llm"
+	ProviderKeyword = "keyword"
+)
+
+// Provider generates the cosmetic title shown alongside a runtime.
+type Provider interface {
+	Title(ctx context.Context, prompt string) (string, error)
+}
+
+// NewProvider returns the provider selected by cfg.TitleProvider. The LLM provider
+// reuses the code generation client settings but talks to cfg.TitleModel when set.
+func NewProvider(cfg *config.Config, client *util.GPTClient) (Provider, error) {
+	switch cfg.TitleProvider {
+	case "", ProviderLLM:
+		titleClient := *client
+		if cfg.TitleModel != "" {
+			titleClient.Model = cfg.TitleModel
+		}
+		return &LLMProvider{GPTClient: &titleClient}, nil
+	case ProviderKeyword:
+		return &KeywordProvider{MaxWords: 4}, nil
+	default:
+		return nil, fmt.Errorf("unknown title provider: %s", cfg.TitleProvider)
+	}
+}
+
+func CreateTitlePrompt(prompt string) string {
+	log.Println("Creating title prompt for base prompt:", prompt)
+	return `You are a concise title generator for Go programs.
+Your task is to generate a **short, clear title** based on a program prompt.
+
+**Title Rules:**
+✅ Titles should be **2 to 5 words** maximum.
+✅ Use **Title Case** (capitalize major words).
+✅ **No punctuation**, unless it is a recognized part of a name (e.g., OAuth, JWT).
+✅ Focus on the **core functionality** or **primary feature**.
+✅ Use **nouns** or **noun phrases**.
+
+**Examples:**
+- 🛡️ JWT Decoder
+- 📦 Inventory Manager
+- 📝 To-Do List
+- 📊 Stock Tracker
+- 🌐 Web Server Generator
+- 📅 Appointment Scheduler
+
+**Output Format:**
+Return only the title—no extra commentary.
+Prompt: ` + prompt
+}
+
+// LLMProvider asks a (typically cheaper) chat model for titles.
+type LLMProvider struct {
+	GPTClient *util.GPTClient
+}
+
+func (p *LLMProvider) Title(ctx context.Context, prompt string) (string, error) {
+	title, err := p.GPTClient.SendMessage(ctx, CreateTitlePrompt(prompt))
+	if err != nil {
+		return "", fmt.Errorf("failed to get title from GPT: %w", err)
+	}
+	return strings.TrimSpace(title), nil
+}
+
+// KeywordProvider builds titles locally without calling an LLM.
+type KeywordProvider struct {
+	MaxWords int
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "app": true, "application": true, "are": true, "as": true,
+	"at": true, "be": true, "build": true, "by": true, "can": true, "create": true, "for": true,
+	"from": true, "generate": true, "go": true, "has": true, "i": true, "in": true, "into": true,
+	"is": true, "it": true, "let": true, "lets": true, "make": true, "me": true, "my": true,
+	"of": true, "on": true, "or": true, "program": true, "should": true, "simple": true,
+	"that": true, "the": true, "their": true, "them": true, "this": true, "to": true, "use": true,
+	"user": true, "users": true, "want": true, "web": true, "where": true, "which": true,
+	"while": true, "with": true, "write": true, "you": true,
+}
+
+// Title picks the most frequent non-stop words of the prompt, ties broken by first use.
+func (p *KeywordProvider) Title(ctx context.Context, prompt string) (string, error) {
+	words := strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	counts := map[string]int{}
+	var order []string
+	for _, w := range words {
+		w = strings.Trim(w, "-")
+		if len(w) < 3 || stopWords[w] {
+			continue
+		}
+		if counts[w] == 0 {
+			order = append(order, w)
+		}
+		counts[w]++
+	}
+	if len(order) == 0 {
+		return "Untitled App", nil
+	}
+	ranked := append([]string(nil), order...)
+	sort.SliceStable(ranked, func(i, j int) bool { return counts[ranked[i]] > counts[ranked[j]] })
+	if len(ranked) > p.MaxWords {
+		ranked = ranked[:p.MaxWords]
+	}
+	// keep the picked words in the order the user wrote them
+	picked := map[string]bool{}
+	for _, w := range ranked {
+		picked[w] = true
+	}
+	var title []string
+	for _, w := range order {
+		if picked[w] {
+			title = append(title, strings.ToUpper(w[:1])+w[1:])
+		}
+	}
+	return strings.Join(title, " "), nil
+}
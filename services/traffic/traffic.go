@@ -0,0 +1,216 @@
+// Package traffic records the requests the front door proxies to each runtime: counts by
+// status code, recent latencies for percentiles and an access log file per runtime.
+package traffic
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/models"
+)
+
+// latencySamples is the number of recent latencies the percentiles are computed over.
+const latencySamples = 1024
+
+// Entry is one proxied request.
+type Entry struct {
+	RemoteAddr string
+	Method     string
+	URI        string
+	Proto      string
+	Status     int
+	Size       int
+	Referer    string
+	UserAgent  string
+	Start      time.Time
+	Latency    time.Duration
+}
+
+// line formats the entry in the combined log format followed by the latency in milliseconds.
+func (e Entry) line() string {
+	size := "-"
+	if e.Size > 0 {
+		size = strconv.Itoa(e.Size)
+	}
+	return fmt.Sprintf("%s - - [%s] %q %d %s %q %q %.3fms", e.RemoteAddr, e.Start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method+" "+e.URI+" "+e.Proto, e.Status, size, e.Referer, e.UserAgent, float64(e.Latency.Microseconds())/1000)
+}
+
+// Recorder keeps the traffic of every proxied runtime. Access logs are written to Dir when it
+// is set; a log growing past MaxBytes is rotated to <id>.log.1, replacing the previous one.
+type Recorder struct {
+	Dir      string
+	MaxBytes int64
+
+	mu       sync.Mutex
+	runtimes map[string]*runtimeTraffic
+}
+
+type runtimeTraffic struct {
+	requests      int64
+	statuses      map[int]int64
+	latencies     []time.Duration
+	next          int
+	lastRequestAt time.Time
+	file          *os.File
+	written       int64
+}
+
+// Record counts a request proxied to the runtime and appends it to the runtime's access log.
+func (r *Recorder) Record(runtimeID string, entry Entry) {
+	metrics.ProxyRequests.Inc(runtimeID, strconv.Itoa(entry.Status))
+	metrics.ProxyRequestSeconds.Add(entry.Latency.Seconds(), runtimeID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runtimes == nil {
+		r.runtimes = map[string]*runtimeTraffic{}
+	}
+	t, ok := r.runtimes[runtimeID]
+	if !ok {
+		t = &runtimeTraffic{statuses: map[int]int64{}}
+		r.runtimes[runtimeID] = t
+	}
+	t.requests++
+	t.statuses[entry.Status]++
+	t.lastRequestAt = entry.Start
+	if len(t.latencies) < latencySamples {
+		t.latencies = append(t.latencies, entry.Latency)
+	} else {
+		t.latencies[t.next] = entry.Latency
+		t.next = (t.next + 1) % latencySamples
+	}
+	if r.Dir != "" {
+		if err := r.write(runtimeID, t, entry.line()+"\n"); err != nil {
+			log.Printf("failed to write access log of runtime %s: %v", runtimeID, err)
+		}
+	}
+}
+
+func (r *Recorder) write(runtimeID string, t *runtimeTraffic, line string) error {
+	if t.file != nil && r.MaxBytes > 0 && t.written+int64(len(line)) > r.MaxBytes {
+		t.file.Close()
+		t.file = nil
+		if err := os.Rename(r.path(runtimeID), r.path(runtimeID)+".1"); err != nil {
+			return fmt.Errorf("failed to rotate access log: %w", err)
+		}
+	}
+	if t.file == nil {
+		if err := os.MkdirAll(r.Dir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		file, err := os.OpenFile(r.path(runtimeID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open access log: %w", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to stat access log: %w", err)
+		}
+		t.file, t.written = file, info.Size()
+	}
+	n, err := io.WriteString(t.file, line)
+	t.written += int64(n)
+	return err
+}
+
+func (r *Recorder) path(runtimeID string) string {
+	return filepath.Join(r.Dir, runtimeID+".log")
+}
+
+// Report summarizes the requests proxied to the runtime since this process started, or
+// returns nil if there were none.
+func (r *Recorder) Report(runtimeID string) *models.TrafficReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.runtimes[runtimeID]
+	if !ok {
+		return nil
+	}
+	report := &models.TrafficReport{
+		Requests:      t.requests,
+		Statuses:      map[string]int64{},
+		LastRequestAt: t.lastRequestAt,
+	}
+	for status, count := range t.statuses {
+		report.Statuses[strconv.Itoa(status)] = count
+	}
+	latencies := append([]time.Duration(nil), t.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.LatencyP50Ms = percentile(latencies, 0.50)
+	report.LatencyP90Ms = percentile(latencies, 0.90)
+	report.LatencyP99Ms = percentile(latencies, 0.99)
+	return report
+}
+
+// percentile returns the q-th percentile of sorted latencies in milliseconds.
+func percentile(sorted []time.Duration, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+	return float64(sorted[i].Microseconds()) / 1000
+}
+
+// AccessLog returns the last n lines of the runtime's access log, oldest first.
+func (r *Recorder) AccessLog(runtimeID string, n int) ([]string, error) {
+	if r.Dir == "" {
+		return nil, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var lines []string
+	// The rotated log holds the lines before the current one.
+	for _, path := range []string{r.path(runtimeID), r.path(runtimeID) + ".1"} {
+		if len(lines) >= n {
+			break
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read access log: %w", err)
+		}
+		older := strings.Split(string(bytes.TrimRight(data, "\n")), "\n")
+		if len(data) == 0 {
+			older = nil
+		}
+		lines = append(older, lines...)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// Remove forgets the runtime's traffic and deletes its access logs.
+func (r *Recorder) Remove(runtimeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.runtimes[runtimeID]; ok && t.file != nil {
+		t.file.Close()
+	}
+	delete(r.runtimes, runtimeID)
+	if r.Dir == "" {
+		return nil
+	}
+	for _, path := range []string{r.path(runtimeID), r.path(runtimeID) + ".1"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove access log: %w", err)
+		}
+	}
+	return nil
+}
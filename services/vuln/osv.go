@@ -0,0 +1,112 @@
+package vuln
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/util"
+)
+
+// DefaultOSVURL is the batch query endpoint of the public OSV database.
+const DefaultOSVURL = "https://api.osv.dev/v1/querybatch"
+
+// OSVClient looks up known vulnerabilities of Go modules in the OSV database.
+type OSVClient struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func NewOSVClient(url string) *OSVClient {
+	if url == "" {
+		url = DefaultOSVURL
+	}
+	return &OSVClient{URL: url, HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type osvQuery struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Version string `json:"version"`
+}
+
+type osvBatchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	} `json:"results"`
+}
+
+// Vulnerability is an advisory affecting a resolved module version.
+type Vulnerability struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	ID      string `json:"id"`
+}
+
+// VulnerableModulesError lists the advisories that blocked a dependency resolution.
+type VulnerableModulesError struct {
+	Vulnerabilities []Vulnerability
+}
+
+func (e *VulnerableModulesError) Error() string {
+	found := make([]string, len(e.Vulnerabilities))
+	for i, v := range e.Vulnerabilities {
+		found[i] = fmt.Sprintf("%s@%s (%s)", v.Module, v.Version, v.ID)
+	}
+	return "dependencies have known vulnerabilities: " + strings.Join(found, ", ")
+}
+
+// Check queries every module and returns a *VulnerableModulesError if any is affected.
+func (c *OSVClient) Check(ctx context.Context, modules []util.ModuleVersion) error {
+	if len(modules) == 0 {
+		return nil
+	}
+	queries := make([]osvQuery, len(modules))
+	for i, module := range modules {
+		queries[i].Package.Name = module.Path
+		queries[i].Package.Ecosystem = "Go"
+		queries[i].Version = strings.TrimPrefix(module.Version, "v")
+	}
+	body, err := json.Marshal(map[string]any{"queries": queries})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OSV query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create OSV request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("OSV lookup failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("OSV lookup failed: status %d", res.StatusCode)
+	}
+	var batch osvBatchResponse
+	if err := json.NewDecoder(res.Body).Decode(&batch); err != nil {
+		return fmt.Errorf("failed to decode OSV response: %w", err)
+	}
+	var found []Vulnerability
+	for i, result := range batch.Results {
+		if i >= len(modules) {
+			break
+		}
+		for _, v := range result.Vulns {
+			found = append(found, Vulnerability{Module: modules[i].Path, Version: modules[i].Version, ID: v.ID})
+		}
+	}
+	if len(found) > 0 {
+		return &VulnerableModulesError{Vulnerabilities: found}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package watchdog
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupMemoryMax     = "/sys/fs/cgroup/memory.max"
+	cgroupMemoryCurrent = "/sys/fs/cgroup/memory.current"
+	procMeminfo         = "/proc/meminfo"
+	procStat            = "/proc/stat"
+)
+
+// memoryPercent returns the memory in use as a percentage of the container's cgroup limit,
+// which is what the OOM killer enforces, or of the host's memory when there is no limit.
+func memoryPercent() (float64, error) {
+	if limit, err := readUint(cgroupMemoryMax); err == nil && limit > 0 {
+		current, err := readUint(cgroupMemoryCurrent)
+		if err != nil {
+			return 0, err
+		}
+		return 100 * float64(current) / float64(limit), nil
+	}
+	file, err := os.Open(procMeminfo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read memory use: %w", err)
+	}
+	defer file.Close()
+	fields := map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err == nil {
+			fields[name] = kb
+		}
+	}
+	total, available := fields["MemTotal"], fields["MemAvailable"]
+	if total == 0 {
+		return 0, fmt.Errorf("failed to read memory use: no MemTotal in %s", procMeminfo)
+	}
+	return 100 * float64(total-min(available, total)) / float64(total), nil
+}
+
+// cpuTimes returns the busy and total CPU time of the host since boot, in clock ticks.
+func cpuTimes() (busy uint64, total uint64, err error) {
+	file, err := os.Open(procStat)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read CPU use: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return 0, 0, fmt.Errorf("failed to read CPU use: %s is empty", procStat)
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("failed to read CPU use: unexpected %s format", procStat)
+	}
+	for i, field := range fields[1:] {
+		ticks, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read CPU use: %w", err)
+		}
+		total += ticks
+		// idle and iowait are the fourth and fifth columns.
+		if i != 3 && i != 4 {
+			busy += ticks
+		}
+	}
+	return busy, total, nil
+}
+
+// readUint reads a file holding a single number; "max" reads as 0, i.e. no limit.
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
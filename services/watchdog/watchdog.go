@@ -0,0 +1,132 @@
+// Package watchdog sheds load before the node runs out of memory, so an overloaded node turns
+// away new executions instead of being OOM-killed together with all of its runtimes.
+package watchdog
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/gcottom/aegisx/models"
+	"github.com/gcottom/aegisx/services/executer"
+	"github.com/gcottom/aegisx/services/traffic"
+)
+
+// Watchdog reads the node's memory and CPU use every Interval and starts or ends load
+// shedding on the executer service.
+type Watchdog struct {
+	ExecutorService *executer.ExecuterService
+	Traffic         *traffic.Recorder // Tells when runtimes last served a request, if set
+	Config          config.WatchdogConfig
+
+	busy, total uint64 // CPU times of the previous reading
+}
+
+// Run checks the load every Interval until ctx is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		w.Tick(ctx)
+	}
+}
+
+// Tick reads the load and acts on it. A reading that fails counts as no load, so a host
+// without the files read never sheds.
+func (w *Watchdog) Tick(ctx context.Context) {
+	memory, err := memoryPercent()
+	if err != nil {
+		log.Printf("Watchdog: %v", err)
+	}
+	cpu, err := w.cpuPercent()
+	if err != nil {
+		log.Printf("Watchdog: %v", err)
+	}
+	metrics.HostMemoryPercent.Set(memory)
+	metrics.HostCPUPercent.Set(cpu)
+
+	memoryHigh := memory >= w.Config.MaxMemoryPercent
+	overloaded := memoryHigh || cpu >= w.Config.MaxCPUPercent
+	if !overloaded && w.ExecutorService.Overloaded() {
+		// Shedding ends with some margin so the node does not flap around the maximums.
+		margin := w.Config.ResumeMarginPercent
+		overloaded = memory >= w.Config.MaxMemoryPercent-margin || cpu >= w.Config.MaxCPUPercent-margin
+	}
+	if overloaded && !w.ExecutorService.Overloaded() {
+		log.Printf("Watchdog: memory at %.1f%%, CPU at %.1f%%", memory, cpu)
+	}
+	w.ExecutorService.SetOverloaded(overloaded)
+	if overloaded {
+		metrics.LoadShedding.Set(1)
+	} else {
+		metrics.LoadShedding.Set(0)
+	}
+	if memoryHigh {
+		w.stopLeastRecentlyUsed(ctx)
+	}
+}
+
+// cpuPercent returns the host's CPU use since the previous reading; the first reading covers
+// the time since boot.
+func (w *Watchdog) cpuPercent() (float64, error) {
+	busy, total, err := cpuTimes()
+	if err != nil {
+		return 0, err
+	}
+	previousBusy, previousTotal := w.busy, w.total
+	w.busy, w.total = busy, total
+	if total <= previousTotal || busy < previousBusy {
+		return 0, nil
+	}
+	return 100 * float64(busy-previousBusy) / float64(total-previousTotal), nil
+}
+
+// stopLeastRecentlyUsed stops the running runtime that served a request the longest ago, or
+// started the longest ago if it never served one. Pinned runtimes are left alone. Only one
+// runtime is stopped at a time, and none while another is still stopping, so the memory it
+// frees is seen before stopping more.
+func (w *Watchdog) stopLeastRecentlyUsed(ctx context.Context) {
+	var candidates []models.RuntimeInfo
+	for _, runtime := range w.ExecutorService.ListRuntimes() {
+		switch {
+		case runtime.State == models.RSSTOPPING:
+			return
+		case runtime.State == models.RSRUN && !runtime.Pinned:
+			candidates = append(candidates, runtime)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	lastUsed := map[string]time.Time{}
+	for _, runtime := range candidates {
+		lastUsed[runtime.ID] = w.lastUsed(runtime)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return lastUsed[candidates[i].ID].Before(lastUsed[candidates[j].ID])
+	})
+	runtime := candidates[0]
+	log.Printf("Watchdog: stopping runtime %s, last used %s, to free memory", runtime.ID, lastUsed[runtime.ID].Format(time.RFC3339))
+	if err := w.ExecutorService.StopRuntime(ctx, runtime.ID); err != nil {
+		log.Printf("Watchdog: failed to stop runtime %s: %v", runtime.ID, err)
+		return
+	}
+	metrics.LoadShedStops.Inc()
+}
+
+func (w *Watchdog) lastUsed(runtime models.RuntimeInfo) time.Time {
+	if w.Traffic != nil {
+		if report := w.Traffic.Report(runtime.ID); report != nil && report.LastRequestAt.After(runtime.StartedAt) {
+			return report.LastRequestAt
+		}
+	}
+	return runtime.StartedAt
+}
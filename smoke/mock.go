@@ -0,0 +1,21 @@
+//go:build mockprovider
+
+package smoke
+
+import (
+	"github.com/gcottom/aegisx/demo"
+	"github.com/gcottom/aegisx/llmtest"
+)
+
+// mockNode starts a node on config/config.yaml whose provider answers every prompt with the
+// bundled demo program it matches, and returns its URL and the function stopping it.
+func mockNode() (string, func() error, error) {
+	client := &llmtest.Client{Respond: func(req llmtest.Request, call int) llmtest.Response {
+		return llmtest.Program(demo.Match(req.Prompt).Code)
+	}}
+	harness, err := llmtest.NewHarness(client, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return harness.Server.URL, harness.Close, nil
+}
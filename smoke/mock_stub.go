@@ -0,0 +1,11 @@
+//go:build !mockprovider
+
+package smoke
+
+import "errors"
+
+// mockNode fails: the mock node runs on the llmtest harness, which release builds leave out.
+// It needs the mockprovider build tag.
+func mockNode() (string, func() error, error) {
+	return "", nil, errors.New("--mock-provider needs a build with the mockprovider tag")
+}
@@ -0,0 +1,194 @@
+package smoke
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultPrompt = "A counter page with a button that increments a number stored on the server."
+
+// Step is the outcome of a single stage of the smoke scenario.
+type Step struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Runner drives a scripted scenario against a live aegisx deployment.
+type Runner struct {
+	Target string
+	Prompt string
+	// Model, if set, generates the smoke runtime instead of the deployment's model.
+	Model  string
+	Client *http.Client
+
+	runtimeID string
+}
+
+// Run parses the smoke subcommand arguments, runs the scenario and reports pass/fail.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	target := fs.String("target", "", "base URL of the aegisx deployment, e.g. https://aegisx.example.com")
+	prompt := fs.String("prompt", DefaultPrompt, "prompt used for the smoke execution")
+	model := fs.String("model", "", "model generating the smoke runtime, e.g. a cheap one; the deployment's model by default")
+	mockProvider := fs.Bool("mock-provider", false, "run against an in-process node whose provider answers with the bundled demo programs, without network access; needs the mockprovider build tag")
+	timeout := fs.Duration("timeout", 10*time.Minute, "timeout for each API call")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *mockProvider && *target != "":
+		return errors.New("--target and --mock-provider are mutually exclusive")
+	case *mockProvider:
+		url, stop, err := mockNode()
+		if err != nil {
+			return fmt.Errorf("failed to start the mock node: %w", err)
+		}
+		defer stop()
+		*target = url
+	case *target == "":
+		return errors.New("missing required flag --target or --mock-provider")
+	}
+	runner := &Runner{
+		Target: strings.TrimSuffix(*target, "/"),
+		Prompt: *prompt,
+		Model:  *model,
+		Client: &http.Client{Timeout: *timeout},
+	}
+	steps := runner.RunScenario()
+	failed := 0
+	for _, step := range steps {
+		if step.Err != nil {
+			failed++
+			log.Printf("FAIL %-10s (%s): %v", step.Name, step.Duration.Round(time.Millisecond), step.Err)
+		} else {
+			log.Printf("PASS %-10s (%s)", step.Name, step.Duration.Round(time.Millisecond))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("smoke test failed: %d of %d steps failed", failed, len(steps))
+	}
+	log.Printf("smoke test passed: %d steps against %s", len(steps), runner.Target)
+	return nil
+}
+
+// RunScenario executes each step in order, stopping at the first failure but always
+// deleting the runtime once one was created.
+func (r *Runner) RunScenario() []Step {
+	stages := []struct {
+		name string
+		fn   func() error
+	}{
+		{"execute", r.execute},
+		{"status", r.status},
+		{"proxy", r.proxy},
+	}
+	var steps []Step
+	for _, stage := range stages {
+		step := r.timed(stage.name, stage.fn)
+		steps = append(steps, step)
+		if step.Err != nil {
+			break
+		}
+	}
+	if r.runtimeID != "" {
+		steps = append(steps, r.timed("delete", r.delete))
+	}
+	return steps
+}
+
+func (r *Runner) timed(name string, fn func() error) Step {
+	start := time.Now()
+	err := fn()
+	return Step{Name: name, Err: err, Duration: time.Since(start)}
+}
+
+func (r *Runner) execute() error {
+	request := map[string]string{"prompt": r.Prompt}
+	if r.Model != "" {
+		request["model"] = r.Model
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		ExecuterID string `json:"executerID"`
+	}
+	if err := r.do(http.MethodPost, "/execute", body, &res); err != nil {
+		return err
+	}
+	if res.ExecuterID == "" {
+		return errors.New("execute response did not include an executerID")
+	}
+	r.runtimeID = res.ExecuterID
+	return nil
+}
+
+func (r *Runner) status() error {
+	var res struct {
+		State             string `json:"state"`
+		PassedHealthCheck bool   `json:"passedHealthCheck"`
+	}
+	if err := r.do(http.MethodGet, "/status/"+r.runtimeID, nil, &res); err != nil {
+		return err
+	}
+	if !res.PassedHealthCheck {
+		return fmt.Errorf("runtime %s has not passed its health check (state %q)", r.runtimeID, res.State)
+	}
+	return nil
+}
+
+func (r *Runner) proxy() error {
+	url := r.Target + "/runtime/" + r.runtimeID + "/"
+	res, err := r.Client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to reach proxied app: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxied app returned %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (r *Runner) delete() error {
+	return r.do(http.MethodDelete, "/runtime/"+r.runtimeID, nil, nil)
+}
+
+// do sends a request to the control API and decodes a JSON response into out when set.
+func (r *Runner) do(method string, path string, body []byte, out any) error {
+	req, err := http.NewRequest(method, r.Target+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %d: %s", method, path, res.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"go/parser"
 	"go/token"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -12,54 +13,145 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/traefik/yaegi/stdlib"
 	"github.com/traefik/yaegi/stdlib/unsafe"
 )
 
+// ExtractGoCode returns the Go program in a model response. Fenced blocks labelled go or
+// golang, or unlabelled blocks that start with a package clause, are candidates: the block
+// declaring package main is the program, and blocks without a package clause that follow it
+// are appended to it; without one, the first candidate is used. Unterminated fences run to
+// the end of the response. Without any fence the code is taken from the first package
+// clause, dropping trailing prose.
 func ExtractGoCode(response string) string {
-	// Regular expression to match Go code blocks (```go ... ```)
-	codeBlockRegex := regexp.MustCompile("(?s)```go\\n(.*?)```")
-
-	// Try to find Go code inside markdown-style code blocks
-	matches := codeBlockRegex.FindStringSubmatch(response)
-	if len(matches) > 1 {
-		return strings.TrimSpace(matches[1]) // Extracted Go code
+	var program []string
+	var fallback string
+	for _, block := range fencedBlocks(response) {
+		if !isGoBlock(block) {
+			continue
+		}
+		switch pkg := packageName(block.body); {
+		case pkg == "main" && program == nil:
+			// Snippets shown before the program are illustrations, not part of it.
+			program = []string{block.body}
+		case pkg == "" && program != nil:
+			program = append(program, block.body)
+		case program == nil && fallback == "":
+			fallback = block.body
+		}
+	}
+	if program == nil && fallback != "" {
+		program = []string{fallback}
+	}
+	if program != nil {
+		return strings.TrimSpace(strings.Join(program, "\n\n"))
 	}
 
-	// If no markdown code block found, try to detect Go functions manually
-	altRegex := regexp.MustCompile(`(?s)(package\s+\w+.*?func\s+\w+$begin:math:text$.*?$end:math:text$\s*{.*})`)
-	altMatches := altRegex.FindStringSubmatch(response)
-	if len(altMatches) > 1 {
-		return strings.TrimSpace(altMatches[1])
+	// If no code block found, take everything from the package clause to the last closing brace
+	if loc := packageClauseRegex.FindStringIndex(response); loc != nil {
+		code := response[loc[0]:]
+		if end := strings.LastIndex(code, "\n}"); end >= 0 {
+			code = code[:end+2]
+		}
+		return strings.TrimSpace(code)
 	}
 
 	// Return full response if no Go code found (fallback case)
 	return response
 }
 
-func ExtractPort(logs string) int {
-	lines := strings.Split(logs, "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "PORT=") {
-			portStr := strings.Split(line, "=")[1]
-			portStr = strings.TrimPrefix(portStr, ":")
-			port, err := strconv.Atoi(portStr)
-			if err == nil {
-				return port
-			}
-		} else if strings.Contains(line, ":") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 1 {
-				portStr := strings.TrimSpace(parts[len(parts)-1])
-				port, err := strconv.Atoi(portStr)
-				if err == nil {
-					return port
-				}
-			}
+var packageClauseRegex = regexp.MustCompile(`(?m)^package\s+(\w+)`)
+
+// fencedBlock is a markdown code block; info is the text after the opening fence.
+type fencedBlock struct {
+	info string
+	body string
+}
+
+// fencedBlocks splits a response into its top level fenced code blocks. Fences opened inside
+// a block, e.g. in a Go raw string holding markdown, must be closed before the block ends.
+func fencedBlocks(response string) []fencedBlock {
+	var blocks []fencedBlock
+	var current *fencedBlock
+	var body []string
+	depth := 0
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		isFence := strings.HasPrefix(trimmed, "```")
+		switch {
+		case current == nil && isFence:
+			current = &fencedBlock{info: strings.TrimSpace(strings.TrimLeft(trimmed, "`"))}
+			body = nil
+		case current == nil:
+		case isFence && trimmed != "```":
+			depth++
+			body = append(body, line)
+		case isFence && depth > 0:
+			depth--
+			body = append(body, line)
+		case isFence:
+			current.body = strings.Join(body, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+		default:
+			body = append(body, line)
+		}
+	}
+	if current != nil {
+		current.body = strings.Join(body, "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// isGoBlock reports whether a fenced block holds Go source.
+func isGoBlock(block fencedBlock) bool {
+	lang, _, _ := strings.Cut(block.info, " ")
+	switch strings.ToLower(lang) {
+	case "go", "golang":
+		return true
+	case "":
+		return packageName(block.body) != ""
+	}
+	return false
+}
+
+// packageName returns the package declared by code, or "" if it has no package clause.
+func packageName(code string) string {
+	if match := packageClauseRegex.FindStringSubmatch(code); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// assetBlockRegex matches fenced blocks whose info string names a file, e.g. ```css app.css
+var assetBlockRegex = regexp.MustCompile("(?s)```([A-Za-z0-9]*)[ \t]+([^\\s`]+)[ \t]*\n(.*?)```")
+
+// ExtractAssets returns the non-Go files the model emitted as named fenced blocks, keyed by
+// their path relative to the runtime's static directory. Unsafe paths are dropped.
+func ExtractAssets(response string) map[string]string {
+	assets := map[string]string{}
+	for _, match := range assetBlockRegex.FindAllStringSubmatch(response, -1) {
+		if strings.EqualFold(match[1], "go") || strings.EqualFold(match[1], "golang") {
+			continue
+		}
+		if name, ok := cleanAssetPath(match[2]); ok {
+			assets[name] = match[3]
 		}
 	}
-	return 0
+	return assets
+}
+
+// cleanAssetPath returns name relative to the static directory, or false if it escapes it.
+func cleanAssetPath(name string) (string, bool) {
+	name = filepath.ToSlash(filepath.Clean(strings.TrimPrefix(name, "static/")))
+	if name == "." || filepath.IsAbs(name) || strings.HasPrefix(name, "../") || name == ".." {
+		return "", false
+	}
+	return name, true
 }
 
 func GetAppRoot() string {
@@ -90,7 +182,7 @@ func ExtractImports(code string) []string {
 	var imports []string
 	for _, imp := range node.Imports {
 		packageName := strings.Trim(imp.Path.Value, `"`)
-		if !IsStandardPackage(packageName) {
+		if !IsStandardPackage(packageName) && !IsHostPackage(packageName) {
 			imports = append(imports, packageName)
 		}
 	}
@@ -98,6 +190,11 @@ func ExtractImports(code string) []string {
 	return imports
 }
 
+// IsHostPackage reports whether pkg is provided by aegisx itself rather than downloaded.
+func IsHostPackage(pkg string) bool {
+	return strings.HasPrefix(pkg, "aegisx/")
+}
+
 // isStandardPackage checks if a package belongs to the Go standard library.
 func IsStandardPackage(pkg string) bool {
 	// List of Go standard library packages - shortened here for brevity.
@@ -122,6 +219,9 @@ func IsStandardPackage(pkg string) bool {
 	return false
 }
 
+// downloadLocks serializes downloads into the same target directory.
+var downloadLocks sync.Map
+
 // DownloadNonStandardPackages downloads all non-standard imports.
 func DownloadNonStandardPackages(code string, targetDir string) error {
 	packages := ExtractImports(code)
@@ -129,6 +229,9 @@ func DownloadNonStandardPackages(code string, targetDir string) error {
 		fmt.Println("No non-standard packages to download.")
 		return nil
 	}
+	lock, _ := downloadLocks.LoadOrStore(targetDir, new(sync.Mutex))
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
 
 	for _, pkg := range packages {
 		fmt.Printf("Downloading package: %s\n", pkg)
@@ -146,12 +249,9 @@ func DownloadPackage(pkg, targetDir string) error {
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
-	// Save the original GOPATH
-	originalGoPath := os.Getenv("GOPATH")
-
-	// Set the environment variables for the command
+	// GOPATH is only set on the child process, the process environment is never mutated
 	cmd := exec.Command("go", "get", pkg)
-	cmd.Env = append(os.Environ(), "GOPATH="+targetDir)
+	cmd.Env = EnvWith(os.Environ(), "GOPATH", targetDir)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -159,18 +259,26 @@ func DownloadPackage(pkg, targetDir string) error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to download package %s: %w", pkg, err)
 	}
+	return nil
+}
 
-	// Restore the original GOPATH
-	if err := os.Setenv("GOPATH", originalGoPath); err != nil {
-		return fmt.Errorf("failed to restore original GOPATH: %w", err)
+// EnvWith returns a copy of env with key set to value, replacing any existing entries.
+func EnvWith(env []string, key string, value string) []string {
+	out := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			out = append(out, kv)
+		}
 	}
-
-	return nil
+	return append(out, key+"="+value)
 }
 
-func RuntimeHealthCheck(runtimeID string) bool {
+// RuntimeHealthCheck probes the runtime's root endpoint directly on its own port,
+// bypassing the public reverse proxy.
+func RuntimeHealthCheck(runtimeID string, port int) bool {
 	log.Println("Performing health check for runtime:", runtimeID)
-	res, err := http.Get(fmt.Sprintf("http://localhost:8080/runtime/%s", runtimeID))
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(fmt.Sprintf("http://localhost:%d/", port))
 	if err != nil {
 		return false
 	}
@@ -178,6 +286,16 @@ func RuntimeHealthCheck(runtimeID string) bool {
 	return res.StatusCode == http.StatusOK
 }
 
+// IsPortListening reports whether something accepts TCP connections on the given local port.
+func IsPortListening(port int) bool {
+	conn, err := net.DialTimeout("tcp", "localhost:"+strconv.Itoa(port), 200*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
 // RemoveItem removes the first occurrence of an item from a slice of strings.
 func RemoveItem(slice []string, item string) []string {
 	for i, v := range slice {
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"context"
+	"time"
+)
+
+// LLMExchange is one request to a language model and its outcome
+type LLMExchange struct {
+	RuntimeID    string
+	Model        string
+	Messages     []Message
+	Response     string
+	FinishReason string
+	Usage        TokenUsage
+	Err          error
+	Start        time.Time
+	Duration     time.Duration
+}
+
+// ExchangeRecorder receives every request a client sends, e.g. to keep an audit log
+type ExchangeRecorder interface {
+	RecordExchange(exchange LLMExchange)
+}
+
+type runtimeIDKey struct{}
+
+// WithRuntimeID returns a context whose LLM requests are recorded as made for the runtime
+func WithRuntimeID(ctx context.Context, runtimeID string) context.Context {
+	return context.WithValue(ctx, runtimeIDKey{}, runtimeID)
+}
+
+// RuntimeIDFrom returns the runtime set with WithRuntimeID, or an empty string
+func RuntimeIDFrom(ctx context.Context) string {
+	runtimeID, _ := ctx.Value(runtimeIDKey{}).(string)
+	return runtimeID
+}
@@ -0,0 +1,139 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gcottom/aegisx/metrics"
+)
+
+// ErrGenerationUnavailable is returned without calling the provider while the circuit breaker
+// is open.
+var ErrGenerationUnavailable = errors.New("generation temporarily unavailable: the LLM provider is failing")
+
+// CircuitBreaker stops calls to an LLM provider that keeps failing. After Threshold
+// consecutive failures it opens and calls fail fast for Cooldown; the first call after that is
+// let through as a probe, closing the breaker if it succeeds and opening it again if not.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time // Zero while closed
+	probing  bool
+}
+
+// RetryAfter returns how long the breaker stays open, or 0 when calls are let through.
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return 0
+	}
+	if remaining := time.Until(b.openedAt.Add(b.Cooldown)); remaining > 0 {
+		return remaining
+	}
+	if b.probing {
+		// The probe is in flight; callers retry once it had time to finish.
+		return b.Cooldown
+	}
+	return 0
+}
+
+// allow reports whether a call may go to the provider and whether it is the probe, which it
+// claims once the cooldown is over.
+func (b *CircuitBreaker) allow() (allowed bool, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true, false
+	}
+	if b.probing || time.Since(b.openedAt) < b.Cooldown {
+		return false, false
+	}
+	log.Println("LLM circuit breaker probing the provider")
+	b.probing = true
+	return true, true
+}
+
+// record counts the outcome of a call that was let through.
+func (b *CircuitBreaker) record(failed bool, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		if !b.openedAt.IsZero() {
+			log.Println("LLM circuit breaker closed, the provider recovered")
+			metrics.LLMBreakerOpen.Set(0)
+		}
+		b.failures, b.openedAt, b.probing = 0, time.Time{}, false
+		return
+	}
+	b.failures++
+	if probe || b.openedAt.IsZero() && b.failures >= b.Threshold {
+		log.Printf("LLM circuit breaker opened after %d consecutive failures", b.failures)
+		metrics.LLMBreakerOpen.Set(1)
+		b.openedAt, b.probing = time.Now(), false
+	}
+}
+
+// release gives up a claimed probe whose call ended without telling whether the provider
+// works, e.g. because its caller went away.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// providerFailure reports whether err says the provider is down rather than that the call was
+// canceled or the request was bad. Refusals and 4xx responses prove the provider is up.
+func providerFailure(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var apiErr *GPTAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// BreakerClient sends prompts to Client through Breaker, which every client of the same
+// provider shares.
+type BreakerClient struct {
+	Client  LLMClient
+	Breaker *CircuitBreaker
+}
+
+// ModelName returns the model of the wrapped client.
+func (c *BreakerClient) ModelName() string {
+	return c.Client.ModelName()
+}
+
+// WithModel returns the wrapped client on model, sharing the breaker.
+func (c *BreakerClient) WithModel(model string) LLMClient {
+	return &BreakerClient{Client: c.Client.WithModel(model), Breaker: c.Breaker}
+}
+
+func (c *BreakerClient) SendMessage(ctx context.Context, prompt string) (string, error) {
+	allowed, probe := c.Breaker.allow()
+	if !allowed {
+		metrics.LLMBreakerRejections.Inc()
+		return "", ErrGenerationUnavailable
+	}
+	content, err := c.Client.SendMessage(ctx, prompt)
+	if err != nil && ctx.Err() != nil {
+		// Canceled by the caller, e.g. because another attempt won.
+		if probe {
+			c.Breaker.release()
+		}
+		return content, err
+	}
+	c.Breaker.record(err != nil && providerFailure(err), probe)
+	return content, err
+}
@@ -0,0 +1,261 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// UnifiedDiff returns a unified diff of two texts, or an empty string when they are equal.
+func UnifiedDiff(from string, to string, fromName string, toName string) string {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	changed := false
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Grow the hunk until the gap between changes exceeds twice the context.
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			gap := end
+			for gap < len(ops) && ops[gap].kind == ' ' {
+				gap++
+			}
+			if gap == len(ops) || gap-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = gap
+		}
+		if !changed {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+			changed = true
+		}
+		aStart, bStart, aLen, bLen := ops[start].a, ops[start].b, 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart+1, aLen, bStart+1, bLen)
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+// diffLines computes a line edit script from the longest common subsequence of a and b.
+func diffLines(a []string, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// ErrPatchFailed is returned when a patch is malformed or does not match the text it is
+// applied to.
+var ErrPatchFailed = errors.New("patch does not apply")
+
+// hunkHeaderRegex matches a hunk header. Models often get the line numbers wrong or leave them
+// out, so they are only a hint of where the hunk applies.
+var hunkHeaderRegex = regexp.MustCompile(`^@@\s*(?:-(\d+)(?:,\d+)?)?.*@@`)
+
+type hunk struct {
+	start int // 1-based line of the original text the hunk claims to apply at, 0 if unknown
+	old   []string
+	new   []string
+}
+
+// ExtractPatch returns the unified diff in a model response: a fenced diff or patch block, a
+// structured file named *.diff or *.patch, or the text from the first file or hunk header.
+// It returns an empty string if the response has no hunk.
+func ExtractPatch(response string) string {
+	patch := ""
+	for _, block := range fencedBlocks(response) {
+		lang, _, _ := strings.Cut(block.info, " ")
+		if lang = strings.ToLower(lang); lang == "diff" || lang == "patch" {
+			patch = block.body
+			break
+		}
+	}
+	if files, err := ParseGeneratedFiles(response); patch == "" && err == nil {
+		for _, file := range files.Files {
+			if strings.HasSuffix(file.Name, ".diff") || strings.HasSuffix(file.Name, ".patch") {
+				patch = file.Content
+				break
+			}
+		}
+	}
+	if patch == "" {
+		patch = response
+	}
+	lines := strings.Split(patch, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "@@") {
+			patch = strings.Join(lines[i:], "\n")
+			if !strings.Contains(patch, "\n@@") && !strings.HasPrefix(patch, "@@") {
+				return ""
+			}
+			return patch
+		}
+	}
+	return ""
+}
+
+// ApplyPatch applies a unified diff of a single file to text. Hunks are matched on their
+// content rather than their line numbers, first exactly and then ignoring indentation and
+// trailing spaces, searching outwards from the line the header names.
+func ApplyPatch(text string, patch string) (string, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(text, "\n")
+	var out []string
+	pos, offset := 0, 0
+	for i, h := range hunks {
+		hint := pos
+		if h.start > 0 {
+			hint = max(h.start-1+offset, pos)
+		}
+		at := findHunk(lines, h.old, pos, hint)
+		if at < 0 {
+			return "", fmt.Errorf("%w: hunk %d does not match the code", ErrPatchFailed, i+1)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, h.new...)
+		pos = at + len(h.old)
+		if h.start > 0 {
+			offset = at - (h.start - 1)
+		}
+	}
+	out = append(out, lines[pos:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+func parsePatch(patch string) ([]hunk, error) {
+	lines := strings.Split(strings.TrimRight(patch, "\n"), "\n")
+	var hunks []hunk
+	var current *hunk
+	files := 0
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if files++; files > 1 {
+				return nil, fmt.Errorf("%w: it changes more than one file", ErrPatchFailed)
+			}
+			current = nil
+		case strings.HasPrefix(line, "+++ ") && current == nil, strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+			current = nil
+		case strings.HasPrefix(line, "@@"):
+			match := hunkHeaderRegex.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("%w: malformed hunk header %q", ErrPatchFailed, line)
+			}
+			hunks = append(hunks, hunk{})
+			current = &hunks[len(hunks)-1]
+			current.start, _ = strconv.Atoi(match[1])
+		case current == nil:
+		case strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file"
+		case strings.HasPrefix(line, "-"):
+			current.old = append(current.old, line[1:])
+		case strings.HasPrefix(line, "+"):
+			current.new = append(current.new, line[1:])
+		default:
+			// Context lines; models sometimes drop the leading space of blank or context lines.
+			line = strings.TrimPrefix(line, " ")
+			current.old = append(current.old, line)
+			current.new = append(current.new, line)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("%w: it has no hunks", ErrPatchFailed)
+	}
+	return hunks, nil
+}
+
+// findHunk returns the line at or after pos where old appears, closest to hint, or -1.
+func findHunk(lines []string, old []string, pos int, hint int) int {
+	last := len(lines) - len(old)
+	if last < pos {
+		return -1
+	}
+	hint = min(max(hint, pos), last)
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+	} {
+		for d := 0; hint-d >= pos || hint+d <= last; d++ {
+			for _, at := range []int{hint - d, hint + d} {
+				if at >= pos && at <= last && matchLines(lines[at:at+len(old)], old, equal) {
+					return at
+				}
+			}
+		}
+	}
+	return -1
+}
+
+func matchLines(lines []string, old []string, equal func(a, b string) bool) bool {
+	for i := range old {
+		if !equal(lines[i], old[i]) {
+			return false
+		}
+	}
+	return true
+}
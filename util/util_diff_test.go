@@ -0,0 +1,119 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const patchSource = `package main
+
+import "fmt"
+
+func greet(name string) string {
+	return "hello " + name
+}
+
+func main() {
+	fmt.Println(greet("world"))
+}
+`
+
+func TestApplyPatchRoundTrip(t *testing.T) {
+	tests := map[string]string{
+		"change":    strings.Replace(patchSource, `"hello "`, `"hi "`, 1),
+		"insert":    strings.Replace(patchSource, "func main() {\n", "func main() {\n\tfmt.Println(\"start\")\n", 1),
+		"delete":    strings.Replace(patchSource, "import \"fmt\"\n\n", "", 1),
+		"first":     "// Command greet greets.\n" + patchSource,
+		"last":      patchSource + "\nfunc unused() {}\n",
+		"several":   strings.Replace(strings.Replace(patchSource, `"hello "`, `"hi "`, 1), `"world"`, `"gopher"`, 1),
+		"unchanged": patchSource,
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			patch := UnifiedDiff(patchSource, want, "a/main.go", "b/main.go")
+			if patch == "" {
+				if want != patchSource {
+					t.Fatal("UnifiedDiff returned no diff for different texts")
+				}
+				return
+			}
+			got, err := ApplyPatch(patchSource, patch)
+			if err != nil {
+				t.Fatalf("ApplyPatch: %v\n%s", err, patch)
+			}
+			if got != want {
+				t.Errorf("ApplyPatch = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchModelMistakes(t *testing.T) {
+	want := strings.Replace(patchSource, `"hello "`, `"hi "`, 1)
+	tests := map[string]string{
+		"wrong line numbers":        "@@ -40,3 +40,3 @@\n func greet(name string) string {\n-\treturn \"hello \" + name\n+\treturn \"hi \" + name\n }\n",
+		"no line numbers":           "@@ @@\n func greet(name string) string {\n-\treturn \"hello \" + name\n+\treturn \"hi \" + name\n }\n",
+		"reindented":                "@@ -5,3 +5,3 @@\n func greet(name string) string {\n-    return \"hello \" + name\n+\treturn \"hi \" + name\n }\n",
+		"context without its space": "@@ -5,3 +5,3 @@\nfunc greet(name string) string {\n-\treturn \"hello \" + name\n+\treturn \"hi \" + name\n}\n",
+		"file headers":              "diff --git a/main.go b/main.go\nindex 1234..5678 100644\n--- a/main.go\n+++ b/main.go\n@@ -6 +6 @@\n-\treturn \"hello \" + name\n+\treturn \"hi \" + name\n",
+	}
+	for name, patch := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ApplyPatch(patchSource, patch)
+			if err != nil {
+				t.Fatalf("ApplyPatch: %v", err)
+			}
+			if got != want {
+				t.Errorf("ApplyPatch = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchHunkNearItsLineNumber(t *testing.T) {
+	text := "a\nx\nb\nc\nx\nd\n"
+	// Both x lines match; the header points at the second.
+	got, err := ApplyPatch(text, "@@ -5,1 +5,1 @@\n-x\n+y\n")
+	if err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if want := "a\nx\nb\nc\ny\nd\n"; got != want {
+		t.Errorf("ApplyPatch = %q, want %q", got, want)
+	}
+}
+
+func TestApplyPatchFails(t *testing.T) {
+	tests := map[string]string{
+		"no hunks":         "--- a/main.go\n+++ b/main.go\n",
+		"malformed header": "@@ -x +y\n-a\n+b\n",
+		"no match":         "@@ -1,1 +1,1 @@\n-package other\n+package main\n",
+		"two files":        "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package main\n+package main\n--- a/other.go\n+++ b/other.go\n@@ -1 +1 @@\n-package main\n+package main\n",
+		// Hunks apply in order, so one that matches only before the previous one fails.
+		"out of order": "@@ -10,1 +10,1 @@\n-\tfmt.Println(greet(\"world\"))\n+\tfmt.Println(greet(\"gopher\"))\n@@ -6,1 +6,1 @@\n-\treturn \"hello \" + name\n+\treturn \"hi \" + name\n",
+	}
+	for name, patch := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ApplyPatch(patchSource, patch); !errors.Is(err, ErrPatchFailed) {
+				t.Errorf("ApplyPatch error = %v, want ErrPatchFailed", err)
+			}
+		})
+	}
+}
+
+func TestExtractPatch(t *testing.T) {
+	patch := "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n"
+	tests := map[string]string{
+		"fenced":     "Here is the fix:\n```diff\n" + patch + "```\n",
+		"structured": `{"files": [{"name": "fix.patch", "content": "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n"}]}`,
+		"bare":       "The fix:\n" + patch,
+	}
+	for name, response := range tests {
+		if got := ExtractPatch(response); strings.TrimRight(got, "\n") != strings.TrimRight(patch, "\n") {
+			t.Errorf("%s: ExtractPatch = %q, want %q", name, got, patch)
+		}
+	}
+	if got := ExtractPatch("```go\npackage main\n```"); got != "" {
+		t.Errorf("ExtractPatch of a response without a hunk = %q, want none", got)
+	}
+}
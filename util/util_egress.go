@@ -0,0 +1,429 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/smtp"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/traefik/yaegi/interp"
+)
+
+// ErrEgressDenied is returned to generated programs for outbound connections their egress
+// policy does not allow.
+var ErrEgressDenied = errors.New("outbound connection denied by egress policy")
+
+// EgressPolicy decides which hosts a generated program may connect to. Allow and Deny hold host
+// names, "*.domain" wildcards, IP addresses or CIDR ranges; Deny wins over Allow and an empty
+// Allow permits every host that is not denied. NoOutbound denies every connection.
+type EgressPolicy struct {
+	NoOutbound bool
+	Allow      []string
+	Deny       []string
+}
+
+// Restricted reports whether the policy denies anything, i.e. whether programs need the
+// policy's symbols at all.
+func (p EgressPolicy) Restricted() bool {
+	return p.NoOutbound || len(p.Allow) > 0 || len(p.Deny) > 0
+}
+
+// matchEgressEntry reports whether a policy entry matches host or one of its addresses.
+func matchEgressEntry(entry string, host string, ips []net.IP) bool {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return false
+		}
+		for _, ip := range ips {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		for _, addr := range ips {
+			if ip.Equal(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	entry = strings.ToLower(entry)
+	if domain, ok := strings.CutPrefix(entry, "*."); ok {
+		return strings.HasSuffix(host, "."+domain)
+	}
+	return host == entry
+}
+
+// allowed applies the policy to host resolved to ips. A host allowed by address rather than by
+// name needs every address allowed.
+func (p EgressPolicy) allowed(host string, ips []net.IP) bool {
+	if p.NoOutbound {
+		return false
+	}
+	for _, entry := range p.Deny {
+		if matchEgressEntry(entry, host, ips) {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, entry := range p.Allow {
+		if !strings.Contains(entry, "/") && net.ParseIP(entry) == nil && matchEgressEntry(entry, host, nil) {
+			return true
+		}
+	}
+	for _, ip := range ips {
+		matched := false
+		for _, entry := range p.Allow {
+			if matchEgressEntry(entry, host, []net.IP{ip}) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return len(ips) > 0
+}
+
+// resolve checks address against the policy and returns the addresses to dial instead, so a
+// name cannot resolve to a denied address between the check and the connection.
+func (p EgressPolicy) resolve(ctx context.Context, network string, address string) ([]string, error) {
+	if p.NoOutbound || strings.HasPrefix(network, "unix") {
+		return nil, fmt.Errorf("%w: %s", ErrEgressDenied, address)
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := p.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, len(ips))
+	for i, ip := range ips {
+		targets[i] = net.JoinHostPort(ip.String(), port)
+	}
+	return targets, nil
+}
+
+// lookup resolves host and returns its addresses if the policy allows them all.
+func (p EgressPolicy) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if p.NoOutbound {
+		return nil, fmt.Errorf("%w: %s", ErrEgressDenied, host)
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if !p.allowed(host, ips) {
+		return nil, fmt.Errorf("%w: %s", ErrEgressDenied, host)
+	}
+	return ips, nil
+}
+
+// egressDialer dials through a policy, reporting denied connections of a runtime.
+type egressDialer struct {
+	policy    EgressPolicy
+	runtimeID string
+	denied    func(address string)
+}
+
+func (d *egressDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	targets, err := d.policy.resolve(ctx, network, address)
+	if err != nil {
+		if errors.Is(err, ErrEgressDenied) {
+			d.report(address)
+		}
+		return nil, err
+	}
+	var dialer net.Dialer
+	for _, target := range targets {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, target); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+func (d *egressDialer) dialTimeout(network string, address string, timeout time.Duration) (net.Conn, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return d.DialContext(ctx, network, address)
+}
+
+// report counts a denied connection and tells the runtime about it.
+func (d *egressDialer) report(address string) {
+	metrics.EgressDenied.Inc(d.runtimeID)
+	if d.denied != nil {
+		d.denied(address)
+	}
+}
+
+// deny reports a connection denied without consulting the policy.
+func (d *egressDialer) deny(address string) error {
+	d.report(address)
+	return fmt.Errorf("%w: %s", ErrEgressDenied, address)
+}
+
+// lookup resolves host through the policy, reporting a denied host.
+func (d *egressDialer) lookup(host string) ([]net.IP, error) {
+	ips, err := d.policy.lookup(context.Background(), host)
+	if errors.Is(err, ErrEgressDenied) {
+		d.report(host)
+	}
+	return ips, err
+}
+
+func (d *egressDialer) dialTLS(dialer *net.Dialer, network string, address string, config *tls.Config) (*tls.Conn, error) {
+	var timeout time.Duration
+	if dialer != nil {
+		timeout = dialer.Timeout
+	}
+	conn, err := d.dialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// egressDenied replaces the standard library types that dial by themselves when the egress
+// policy is restricted. It has no fields or methods, so interpreted code, including the modules
+// a program imports, cannot build or use a dialer, resolver, transport or reverse proxy of its
+// own and fails to compile instead.
+type egressDenied struct{}
+
+// egressClient replaces http.Client when the egress policy is restricted. Without a
+// Transport, net/http's client dials through the host's DefaultTransport, and a client the
+// program builds has no policy of its own to fall back on, so one without a Transport is
+// denied every connection instead.
+type egressClient struct {
+	Transport     http.RoundTripper
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+	Jar           http.CookieJar
+	Timeout       time.Duration
+}
+
+func (c *egressClient) client() *http.Client {
+	transport := c.Transport
+	if transport == nil {
+		transport = noTransport{}
+	}
+	return &http.Client{Transport: transport, CheckRedirect: c.CheckRedirect, Jar: c.Jar, Timeout: c.Timeout}
+}
+
+func (c *egressClient) Do(req *http.Request) (*http.Response, error) { return c.client().Do(req) }
+
+func (c *egressClient) Get(url string) (*http.Response, error) { return c.client().Get(url) }
+
+func (c *egressClient) Head(url string) (*http.Response, error) { return c.client().Head(url) }
+
+func (c *egressClient) Post(url string, contentType string, body io.Reader) (*http.Response, error) {
+	return c.client().Post(url, contentType, body)
+}
+
+func (c *egressClient) PostForm(url string, data url.Values) (*http.Response, error) {
+	return c.client().PostForm(url, data)
+}
+
+func (c *egressClient) CloseIdleConnections() { c.client().CloseIdleConnections() }
+
+// noTransport is the transport of an egressClient built without one.
+type noTransport struct{}
+
+func (noTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	metrics.EgressDenied.Inc("")
+	return nil, fmt.Errorf("%w: %s, an http.Client needs Transport: http.DefaultTransport", ErrEgressDenied, req.URL.Host)
+}
+
+// egressDeniedTypes are the types replaced by egressDenied, by export path.
+var egressDeniedTypes = map[string][]string{
+	"net/net":                    {"Dialer", "Resolver"},
+	"net/http/http":              {"Transport"},
+	"crypto/tls/tls":             {"Dialer"},
+	"net/http/httputil/httputil": {"ReverseProxy"},
+}
+
+// EgressExports returns interpreter symbols that replace the standard library's entry points
+// for outbound connections in net, net/http, crypto/tls and net/smtp with ones dialing through
+// policy. denied, if not nil, is told about every denied connection of the runtime, and wrap,
+// if not nil, wraps the transport of the program's default client, e.g. in the outbound proxy.
+// Listeners that can send to arbitrary addresses, such as UDP sockets, are denied outright.
+// When the policy is restricted, the types that dial by themselves are replaced by
+// egressDenied, host lookups resolve through the policy and the other DNS queries are denied.
+// http.Client is then replaced by egressClient, whose connections are denied when it has no
+// Transport, since net/http would dial them directly.
+func EgressExports(policy EgressPolicy, runtimeID string, denied func(address string), wrap func(http.RoundTripper) http.RoundTripper) interp.Exports {
+	d := &egressDialer{policy: policy, runtimeID: runtimeID, denied: denied}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = d.DialContext
+	var defaultTransport http.RoundTripper = transport
+	if wrap != nil {
+		defaultTransport = wrap(transport)
+	}
+	defaultClient := &http.Client{Transport: defaultTransport}
+	exports := interp.Exports{
+		"net/net": {
+			"Dial": reflect.ValueOf(func(network string, address string) (net.Conn, error) {
+				return d.dialTimeout(network, address, 0)
+			}),
+			"DialTimeout": reflect.ValueOf(d.dialTimeout),
+			"DialTCP": reflect.ValueOf(func(network string, laddr *net.TCPAddr, raddr *net.TCPAddr) (*net.TCPConn, error) {
+				conn, err := d.dialTimeout(network, raddr.String(), 0)
+				if err != nil {
+					return nil, err
+				}
+				return conn.(*net.TCPConn), nil
+			}),
+			"DialUDP": reflect.ValueOf(func(network string, laddr *net.UDPAddr, raddr *net.UDPAddr) (*net.UDPConn, error) {
+				conn, err := d.dialTimeout(network, raddr.String(), 0)
+				if err != nil {
+					return nil, err
+				}
+				return conn.(*net.UDPConn), nil
+			}),
+			"DialIP": reflect.ValueOf(func(network string, laddr *net.IPAddr, raddr *net.IPAddr) (*net.IPConn, error) {
+				return nil, d.deny(raddr.String())
+			}),
+			"DialUnix": reflect.ValueOf(func(network string, laddr *net.UnixAddr, raddr *net.UnixAddr) (*net.UnixConn, error) {
+				return nil, d.deny(raddr.String())
+			}),
+			"ListenPacket": reflect.ValueOf(func(network string, address string) (net.PacketConn, error) {
+				return nil, d.deny(address)
+			}),
+			"ListenUDP": reflect.ValueOf(func(network string, laddr *net.UDPAddr) (*net.UDPConn, error) {
+				return nil, d.deny(laddr.String())
+			}),
+			"ListenIP": reflect.ValueOf(func(network string, laddr *net.IPAddr) (*net.IPConn, error) {
+				return nil, d.deny(laddr.String())
+			}),
+			"ListenMulticastUDP": reflect.ValueOf(func(network string, ifi *net.Interface, gaddr *net.UDPAddr) (*net.UDPConn, error) {
+				return nil, d.deny(gaddr.String())
+			}),
+		},
+		"net/http/http": {
+			"DefaultTransport": reflect.ValueOf(&defaultTransport).Elem(),
+			"DefaultClient":    reflect.ValueOf(&defaultClient).Elem(),
+			"Get":              reflect.ValueOf(defaultClient.Get),
+			"Head":             reflect.ValueOf(defaultClient.Head),
+			"Post":             reflect.ValueOf(defaultClient.Post),
+			"PostForm":         reflect.ValueOf(defaultClient.PostForm),
+		},
+		"crypto/tls/tls": {
+			"Dial": reflect.ValueOf(func(network string, address string, config *tls.Config) (*tls.Conn, error) {
+				return d.dialTLS(nil, network, address, config)
+			}),
+			"DialWithDialer": reflect.ValueOf(d.dialTLS),
+		},
+		"net/smtp/smtp": {
+			"Dial": reflect.ValueOf(func(address string) (*smtp.Client, error) {
+				conn, err := d.dialTimeout("tcp", address, 0)
+				if err != nil {
+					return nil, err
+				}
+				host, _, _ := net.SplitHostPort(address)
+				return smtp.NewClient(conn, host)
+			}),
+			"SendMail": reflect.ValueOf(func(address string, auth smtp.Auth, from string, to []string, msg []byte) error {
+				// SendMail dials by itself, so the address is checked first; unlike Dial, a name
+				// re-resolving to a denied address in between is not caught.
+				if _, err := d.policy.resolve(context.Background(), "tcp", address); err != nil {
+					if errors.Is(err, ErrEgressDenied) {
+						d.report(address)
+					}
+					return err
+				}
+				return smtp.SendMail(address, auth, from, to, msg)
+			}),
+		},
+	}
+	if !policy.Restricted() {
+		return exports
+	}
+	restrictedClient := &egressClient{Transport: defaultTransport}
+	httpSymbols := exports["net/http/http"]
+	httpSymbols["Client"] = reflect.ValueOf((*egressClient)(nil))
+	httpSymbols["DefaultClient"] = reflect.ValueOf(&restrictedClient).Elem()
+	httpSymbols["Get"] = reflect.ValueOf(restrictedClient.Get)
+	httpSymbols["Head"] = reflect.ValueOf(restrictedClient.Head)
+	httpSymbols["Post"] = reflect.ValueOf(restrictedClient.Post)
+	httpSymbols["PostForm"] = reflect.ValueOf(restrictedClient.PostForm)
+	for path, names := range egressDeniedTypes {
+		if exports[path] == nil {
+			exports[path] = map[string]reflect.Value{}
+		}
+		for _, name := range names {
+			exports[path][name] = reflect.ValueOf((*egressDenied)(nil))
+		}
+	}
+	// A proxy built by httputil goes through the program's default transport instead.
+	exports["net/http/httputil/httputil"]["NewSingleHostReverseProxy"] = reflect.ValueOf(func(target *url.URL) *httputil.ReverseProxy {
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = defaultTransport
+		return proxy
+	})
+	// The resolver of the program queries its DNS servers through the policy.
+	resolver := &net.Resolver{PreferGo: true, Dial: d.DialContext}
+	symbols := exports["net/net"]
+	symbols["DefaultResolver"] = reflect.ValueOf(&resolver).Elem()
+	symbols["LookupIP"] = reflect.ValueOf(d.lookup)
+	symbols["LookupHost"] = reflect.ValueOf(func(host string) ([]string, error) {
+		ips, err := d.lookup(host)
+		addrs := make([]string, len(ips))
+		for i, ip := range ips {
+			addrs[i] = ip.String()
+		}
+		return addrs, err
+	})
+	symbols["LookupAddr"] = reflect.ValueOf(func(addr string) ([]string, error) { return nil, d.deny(addr) })
+	symbols["LookupCNAME"] = reflect.ValueOf(func(host string) (string, error) { return "", d.deny(host) })
+	symbols["LookupMX"] = reflect.ValueOf(func(name string) ([]*net.MX, error) { return nil, d.deny(name) })
+	symbols["LookupNS"] = reflect.ValueOf(func(name string) ([]*net.NS, error) { return nil, d.deny(name) })
+	symbols["LookupSRV"] = reflect.ValueOf(func(service string, proto string, name string) (string, []*net.SRV, error) {
+		return "", nil, d.deny(name)
+	})
+	symbols["LookupTXT"] = reflect.ValueOf(func(name string) ([]string, error) { return nil, d.deny(name) })
+	return exports
+}
@@ -0,0 +1,196 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// evalEgress evaluates program in an interpreter bound to policy and returns the error of
+// main and the addresses the policy denied.
+func evalEgress(t *testing.T, policy EgressPolicy, program string) (error, []string) {
+	t.Helper()
+	var denied []string
+	interpreter, _ := NewYaegiInterpreter("", EgressExports(policy, "r1", func(address string) { denied = append(denied, address) }, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := interpreter.EvalWithContext(ctx, program)
+	return err, denied
+}
+
+// TestEgressExportsDenyDialingTypes checks that interpreted code cannot build the types that
+// dial by themselves, whichever package it is written in.
+func TestEgressExportsDenyDialingTypes(t *testing.T) {
+	programs := map[string]string{
+		"net.Dialer":            `d := &net.Dialer{Timeout: time.Second}; d.Dial("tcp", "example.com:80")`,
+		"zero net.Dialer":       `var d net.Dialer; d.Dial("tcp", "example.com:80")`,
+		"net.Resolver":          `r := &net.Resolver{PreferGo: true}; r.LookupHost(context.Background(), "example.com")`,
+		"http.Transport":        `c := &http.Client{Transport: &http.Transport{}}; c.Get("http://example.com")`,
+		"cloned http.Transport": `t := http.DefaultTransport.(*http.Transport).Clone(); _ = t`,
+		"tls.Dialer":            `d := &tls.Dialer{}; d.Dial("tcp", "example.com:443")`,
+		"httputil.ReverseProxy": `p := &httputil.ReverseProxy{}; p.ServeHTTP(nil, nil)`,
+	}
+	for name, body := range programs {
+		t.Run(name, func(t *testing.T) {
+			program := `package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+var (
+	_ = context.Background
+	_ = tls.Client
+	_ = net.Dial
+	_ = httputil.DumpRequest
+	_ = time.Second
+)
+
+func main() {
+	` + body + `
+}
+`
+			err, _ := evalEgress(t, EgressPolicy{Allow: []string{"example.com"}}, program)
+			if err == nil || strings.Contains(err.Error(), "panic") {
+				t.Errorf("a program building its own dialer compiled: %v", err)
+			}
+			// Without a restricted policy the standard library is left alone.
+			if err, _ := evalEgress(t, EgressPolicy{}, strings.Replace(program, "func main() {", "func main() {\n\treturn", 1)); err != nil {
+				t.Errorf("unrestricted program failed: %v", err)
+			}
+		})
+	}
+}
+
+// TestEgressExportsClientWithoutTransport checks that an http.Client built without a Transport
+// does not fall back to net/http's DefaultTransport, which dials directly.
+func TestEgressExportsClientWithoutTransport(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer server.Close()
+	programs := map[string]string{
+		"nil Transport":          `c := &http.Client{Transport: nil}`,
+		"nil RoundTripper":       `var rt http.RoundTripper; c := http.Client{Transport: rt}`,
+		"no Transport":           `c := &http.Client{Timeout: time.Second}`,
+		"zero Client":            `var c http.Client`,
+		"DefaultClient replaced": `c := &http.Client{}; http.DefaultClient = c`,
+	}
+	for name, body := range programs {
+		t.Run(name, func(t *testing.T) {
+			program := `package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+var _ = time.Second
+
+func main() {
+	` + body + `
+	if _, err := c.Get("` + server.URL + `"); err == nil {
+		panic(errors.New("the request was not denied"))
+	}
+}
+`
+			if err, _ := evalEgress(t, EgressPolicy{NoOutbound: true}, program); err != nil {
+				t.Error(err)
+			}
+			if err, _ := evalEgress(t, EgressPolicy{Allow: []string{"127.0.0.0/8"}}, program); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("the server was reached %d times", n)
+	}
+
+	// With the policy's transport, the client dials through the policy.
+	err, _ := evalEgress(t, EgressPolicy{Allow: []string{"127.0.0.0/8"}}, `package main
+
+import "net/http"
+
+func main() {
+	c := &http.Client{Transport: http.DefaultTransport}
+	resp, err := c.Get("`+server.URL+`")
+	if err != nil {
+		panic(err)
+	}
+	resp.Body.Close()
+	if _, err := http.Get("`+server.URL+`"); err != nil {
+		panic(err)
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("the server was reached %d times, want 2", n)
+	}
+}
+
+func TestEgressExportsLookups(t *testing.T) {
+	program := `package main
+
+import (
+	"errors"
+	"net"
+)
+
+func main() {
+	if _, err := net.LookupTXT("example.com"); err == nil {
+		panic(errors.New("LookupTXT was not denied"))
+	}
+	if _, err := net.LookupHost("localhost"); err == nil {
+		panic(errors.New("LookupHost was not denied"))
+	}
+}
+`
+	err, denied := evalEgress(t, EgressPolicy{NoOutbound: true}, program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denied) != 2 || denied[0] != "example.com" || denied[1] != "localhost" {
+		t.Errorf("denied = %v, want example.com and localhost", denied)
+	}
+
+	// A literal address is checked against the policy without a DNS query.
+	err, _ = evalEgress(t, EgressPolicy{Deny: []string{"10.0.0.0/8"}}, `package main
+
+import "net"
+
+func main() {
+	if ips, err := net.LookupIP("127.0.0.1"); err != nil || len(ips) != 1 {
+		panic(err)
+	}
+	if _, err := net.LookupIP("10.1.2.3"); err == nil {
+		panic("a denied address was resolved")
+	}
+}
+`)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEgressPolicyLookup(t *testing.T) {
+	policy := EgressPolicy{Allow: []string{"127.0.0.0/8"}}
+	if _, err := policy.lookup(context.Background(), "127.0.0.1"); err != nil {
+		t.Errorf("allowed address denied: %v", err)
+	}
+	if _, err := policy.lookup(context.Background(), "192.0.2.1"); !errors.Is(err, ErrEgressDenied) {
+		t.Errorf("lookup of a denied address = %v, want ErrEgressDenied", err)
+	}
+}
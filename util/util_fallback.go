@@ -0,0 +1,87 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// FallbackClient sends a prompt to Client's model and, when the model refuses, times out or is
+// unavailable, retries it on each of Models in order.
+type FallbackClient struct {
+	Client LLMClient
+	Models []string
+}
+
+// ModelName returns the first model of the chain.
+func (c *FallbackClient) ModelName() string {
+	return c.Client.ModelName()
+}
+
+// WithModel returns the chain starting at model instead.
+func (c *FallbackClient) WithModel(model string) LLMClient {
+	return &FallbackClient{Client: c.Client.WithModel(model), Models: c.Models}
+}
+
+func (c *FallbackClient) SendMessage(ctx context.Context, prompt string) (string, error) {
+	tried := map[string]bool{}
+	var lastErr error
+	for i, model := range append([]string{c.Client.ModelName()}, c.Models...) {
+		if tried[model] {
+			continue
+		}
+		tried[model] = true
+		client := c.Client
+		if i > 0 {
+			log.Printf("Falling back to model %s: %v", model, lastErr)
+			client = c.Client.WithModel(model)
+		}
+		content, err := client.SendMessage(ctx, prompt)
+		if err == nil && IsRefusal(content) {
+			err = ErrRefusal
+		}
+		if err == nil {
+			return content, nil
+		}
+		if ctx.Err() != nil || !shouldFallback(err) {
+			return "", err
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// shouldFallback reports whether another model might succeed where this one failed.
+func shouldFallback(err error) bool {
+	if errors.Is(err, ErrRefusal) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var apiErr *GPTAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable() || apiErr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+var refusalPrefixes = []string{"i'm sorry", "i am sorry", "i can't", "i cannot", "i can not", "sorry,", "i'm unable", "i am unable"}
+
+// IsRefusal reports whether a reply declines the request instead of answering it.
+func IsRefusal(content string) bool {
+	content = strings.ToLower(strings.TrimSpace(content))
+	if content == "" || strings.Contains(content, "```") || strings.Contains(content, "package ") {
+		return false
+	}
+	for _, prefix := range refusalPrefixes {
+		if strings.HasPrefix(content, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FewShotExample is a prompt and a program known to satisfy the generation contract
+type FewShotExample struct {
+	Name   string `yaml:"name"`
+	Prompt string `yaml:"prompt"`
+	Code   string `yaml:"code"`
+}
+
+// LoadFewShotExamples reads the example bank at path, keeping at most max examples (all when
+// max is 0)
+func LoadFewShotExamples(path string, max int) ([]FewShotExample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read few-shot examples: %w", err)
+	}
+	var examples []FewShotExample
+	if err := yaml.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("failed to parse few-shot examples %s: %w", path, err)
+	}
+	for i, example := range examples {
+		if strings.TrimSpace(example.Prompt) == "" || strings.TrimSpace(example.Code) == "" {
+			return nil, fmt.Errorf("few-shot example %d (%s) needs a prompt and code", i, example.Name)
+		}
+	}
+	if max > 0 && len(examples) > max {
+		examples = examples[:max]
+	}
+	return examples, nil
+}
+
+// FewShotMessages renders examples as user/assistant turns. The answers use the structured
+// files format when structured is set and a fenced go block otherwise, matching what the
+// model is asked to return
+func FewShotMessages(examples []FewShotExample, structured bool) ([]Message, error) {
+	var messages []Message
+	for _, example := range examples {
+		answer := "```go\n" + strings.TrimSpace(example.Code) + "\n```"
+		if structured {
+			data, err := json.Marshal(GeneratedFiles{Files: []GeneratedFile{{Name: "main.go", Content: example.Code}}})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal few-shot example %s: %w", example.Name, err)
+			}
+			answer = string(data)
+		}
+		messages = append(messages,
+			Message{Role: "user", Content: strings.TrimSpace(example.Prompt)},
+			Message{Role: "assistant", Content: answer},
+		)
+	}
+	return messages, nil
+}
+
+// SystemRoleFor returns the role a model accepts instructions in: o1-mini and o1-preview
+// reject both system and developer messages, other o-series reasoning models take developer
+// messages and everything else takes system messages
+func SystemRoleFor(model string) string {
+	switch {
+	case strings.HasPrefix(model, "o1-mini"), strings.HasPrefix(model, "o1-preview"):
+		return "user"
+	case len(model) > 1 && model[0] == 'o' && model[1] >= '0' && model[1] <= '9':
+		return "developer"
+	default:
+		return "system"
+	}
+}
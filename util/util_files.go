@@ -0,0 +1,226 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// GeneratedFile is one file of a structured generation
+type GeneratedFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// GeneratedFiles is the structured output requested with FilesResponseFormat
+type GeneratedFiles struct {
+	Files []GeneratedFile `json:"files"`
+}
+
+// ResponseFormat selects the provider's JSON mode or structured output
+type ResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *JSONSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaSpec names the schema a structured output must conform to
+type JSONSchemaSpec struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
+}
+
+// FilesResponseFormat asks for a GeneratedFiles object instead of markdown
+var FilesResponseFormat = &ResponseFormat{
+	Type: "json_schema",
+	JSONSchema: &JSONSchemaSpec{
+		Name:   "files",
+		Strict: true,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"files": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"name":    map[string]any{"type": "string"},
+							"content": map[string]any{"type": "string"},
+						},
+						"required":             []string{"name", "content"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			"required":             []string{"files"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+// SupportsResponseFormat reports whether a model accepts a response format: o1-mini and
+// o1-preview reject it, so they are only asked for the files in the prompt
+func SupportsResponseFormat(model string) bool {
+	return !strings.HasPrefix(model, "o1-mini") && !strings.HasPrefix(model, "o1-preview")
+}
+
+// ParseGeneratedFiles decodes a structured generation, tolerating a surrounding ```json fence
+func ParseGeneratedFiles(response string) (*GeneratedFiles, error) {
+	response = strings.TrimSpace(response)
+	if strings.HasPrefix(response, "```") {
+		response = strings.TrimSuffix(strings.TrimSpace(response[strings.Index(response, "\n")+1:]), "```")
+	}
+	var files GeneratedFiles
+	if err := json.Unmarshal([]byte(response), &files); err != nil {
+		return nil, err
+	}
+	if len(files.Files) == 0 {
+		return nil, errors.New("structured response contains no files")
+	}
+	return &files, nil
+}
+
+// ExtractGeneration returns the Go program and static assets of a model response. Structured
+// responses are read file by file, and their Go files are merged into one program, since the
+// interpreter runs a single file; anything else falls back to ExtractGoCode and ExtractAssets
+func ExtractGeneration(response string) (string, map[string]string) {
+	files, err := ParseGeneratedFiles(response)
+	if err != nil {
+		return ExtractGoCode(response), ExtractAssets(response)
+	}
+	var sources []GeneratedFile
+	assets := map[string]string{}
+	for _, file := range files.Files {
+		if strings.HasSuffix(file.Name, ".go") {
+			sources = append(sources, file)
+			continue
+		}
+		if name, ok := cleanAssetPath(file.Name); ok {
+			assets[name] = file.Content
+		}
+	}
+	// main.go comes first, then the files of package main.
+	slices.SortStableFunc(sources, func(a, b GeneratedFile) int {
+		return goFileRank(a) - goFileRank(b)
+	})
+	return strings.TrimSpace(mergeGoFiles(sources)), assets
+}
+
+func goFileRank(file GeneratedFile) int {
+	switch {
+	case filepath.Base(file.Name) == "main.go":
+		return 0
+	case strings.Contains(file.Content, "package main"):
+		return 1
+	}
+	return 2
+}
+
+// mergeGoFiles joins Go files into the first one: the imports of the others are added to its
+// own and their declarations appended to it. A file that does not parse is appended as it is,
+// so the program fails to compile with an error the rebuild prompt can report.
+func mergeGoFiles(files []GeneratedFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	merged := files[0].Content
+	if len(files) == 1 {
+		return merged
+	}
+	fset := token.NewFileSet()
+	first, err := parser.ParseFile(fset, files[0].Name, merged, parser.ImportsOnly)
+	if err != nil {
+		return strings.Join(goContents(files), "\n\n")
+	}
+	imported := map[string]bool{}
+	for _, spec := range first.Imports {
+		imported[importKey(spec.Name.String(), spec.Path.Value, spec.Name != nil)] = true
+	}
+	var imports, decls []string
+	for _, file := range files[1:] {
+		parsed, err := parser.ParseFile(fset, file.Name, file.Content, parser.ImportsOnly)
+		if err != nil {
+			decls = append(decls, file.Content)
+			continue
+		}
+		for _, spec := range parsed.Imports {
+			key := importKey(spec.Name.String(), spec.Path.Value, spec.Name != nil)
+			if !imported[key] {
+				imported[key] = true
+				imports = append(imports, key)
+			}
+		}
+		// Everything after the package clause and imports is declarations.
+		end := parsed.Name.End()
+		if len(parsed.Decls) > 0 {
+			end = parsed.Decls[len(parsed.Decls)-1].End()
+		}
+		decls = append(decls, file.Content[fset.Position(end).Offset:])
+	}
+	if len(imports) > 0 {
+		clause := fset.Position(first.Name.End()).Offset
+		merged = merged[:clause] + "\n\nimport (\n\t" + strings.Join(imports, "\n\t") + "\n)" + merged[clause:]
+	}
+	merged += "\n" + strings.Join(decls, "\n")
+	if formatted, err := format.Source([]byte(merged)); err == nil {
+		return string(formatted)
+	}
+	return merged
+}
+
+// importKey is an import spec as written in an import declaration.
+func importKey(name string, path string, named bool) string {
+	if !named {
+		return path
+	}
+	return name + " " + path
+}
+
+func goContents(files []GeneratedFile) []string {
+	contents := make([]string, len(files))
+	for i, file := range files {
+		contents[i] = file.Content
+	}
+	return contents
+}
+
+// ExtractProgram returns the program in another language than Go and the static assets of a
+// model response. The program is the first fenced block labelled with one of labels, either
+// unnamed or named with extension, or the first file with extension of a structured response.
+// The remaining named blocks and files are assets, as for ExtractGeneration.
+func ExtractProgram(response string, labels []string, extension string) (string, map[string]string) {
+	if files, err := ParseGeneratedFiles(response); err == nil {
+		var program string
+		assets := map[string]string{}
+		for _, file := range files.Files {
+			if program == "" && strings.HasSuffix(file.Name, extension) {
+				program = file.Content
+				continue
+			}
+			if name, ok := cleanAssetPath(file.Name); ok {
+				assets[name] = file.Content
+			}
+		}
+		return strings.TrimSpace(program), assets
+	}
+	assets := ExtractAssets(response)
+	for _, block := range fencedBlocks(response) {
+		label, name, _ := strings.Cut(block.info, " ")
+		name = strings.TrimSpace(name)
+		if !slices.ContainsFunc(labels, func(l string) bool { return strings.EqualFold(l, label) }) {
+			continue
+		}
+		if name == "" || strings.HasSuffix(name, extension) {
+			if cleaned, ok := cleanAssetPath(name); ok {
+				delete(assets, cleaned)
+			}
+			return strings.TrimSpace(block.body), assets
+		}
+	}
+	return strings.TrimSpace(response), assets
+}
@@ -0,0 +1,169 @@
+package util
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// GoroutineGuardPackage is the host package that guarded goroutines of a generated program
+// report their panics to, through its Panicked(value any) function.
+const GoroutineGuardPackage = "aegisx/runtime"
+
+// goroutineGuardAlias is the name the guard package is imported under, chosen not to clash
+// with the program's own identifiers.
+const goroutineGuardAlias = "aegisxruntime"
+
+// builtinFuncs cannot be bound to a variable, so goroutines calling them are not rewritten
+// to evaluate the function first.
+var builtinFuncs = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "max": true, "min": true,
+	"new": true, "panic": true, "print": true, "println": true, "real": true, "recover": true,
+}
+
+// GuardGoroutines rewrites the go statements of a program so a panic in a goroutine is
+// recovered and reported to GoroutineGuardPackage instead of crashing the process: the
+// interpreter re-panics in goroutines the program starts, where nothing else can recover.
+// The function and arguments are still evaluated by the starting goroutine, except constants,
+// named ones included, and a sole call argument, whose type the rewrite cannot know. Every rewrite stays on the
+// lines of the original statement so errors keep pointing at the right line. Code that does
+// not parse is returned as it is.
+func GuardGoroutines(code string) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, parser.SkipObjectResolution)
+	if err != nil {
+		return code
+	}
+	var stmts []*ast.GoStmt
+	ast.Inspect(file, func(n ast.Node) bool {
+		if stmt, ok := n.(*ast.GoStmt); ok {
+			stmts = append(stmts, stmt)
+		}
+		return true
+	})
+	if len(stmts) == 0 {
+		return code
+	}
+	g := &goroutineGuard{code: code, fset: fset, stmts: stmts, consts: constNames(file)}
+	guarded := g.render(0, len(code))
+	// The import shares the line of the package clause, which comes before any go statement.
+	nameEnd := g.offset(file.Name.End())
+	return guarded[:nameEnd] + "; import " + goroutineGuardAlias + " " + strconv.Quote(GoroutineGuardPackage) + guarded[nameEnd:]
+}
+
+type goroutineGuard struct {
+	code   string
+	fset   *token.FileSet
+	stmts  []*ast.GoStmt // In source order
+	consts map[string]bool
+}
+
+// constNames returns the names of the constants file declares, in any scope. A variable
+// shadowing one of them is taken for a constant too, which only leaves it unbound.
+func constNames(file *ast.File) map[string]bool {
+	consts := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if decl, ok := n.(*ast.GenDecl); ok && decl.Tok == token.CONST {
+			for _, spec := range decl.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					consts[name.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return consts
+}
+
+func (g *goroutineGuard) offset(pos token.Pos) int {
+	return g.fset.Position(pos).Offset
+}
+
+// render returns code[start:end] with the outermost go statements within it guarded.
+func (g *goroutineGuard) render(start int, end int) string {
+	var b strings.Builder
+	cursor := start
+	for _, stmt := range g.stmts {
+		stmtStart, stmtEnd := g.offset(stmt.Pos()), g.offset(stmt.End())
+		if stmtStart < cursor || stmtEnd > end {
+			continue
+		}
+		b.WriteString(g.code[cursor:stmtStart])
+		b.WriteString(g.guard(stmt))
+		cursor = stmtEnd
+	}
+	b.WriteString(g.code[cursor:end])
+	return b.String()
+}
+
+func (g *goroutineGuard) guard(stmt *ast.GoStmt) string {
+	call := stmt.Call
+	text := func(expr ast.Expr) string {
+		return g.render(g.offset(expr.Pos()), g.offset(expr.End()))
+	}
+	var b strings.Builder
+	b.WriteString("{ ")
+	// The interpreter mishandles method values bound together with other values, so the
+	// function gets a statement of its own.
+	fun := text(call.Fun)
+	if bindableFunc(call.Fun) {
+		b.WriteString("aegisxGoFn := " + fun + "; ")
+		fun = "aegisxGoFn"
+	}
+	var names, values, args []string
+	for i, arg := range call.Args {
+		_, isCall := arg.(*ast.CallExpr)
+		if g.isConstant(arg) || isCall && len(call.Args) == 1 {
+			args = append(args, text(arg))
+			continue
+		}
+		name := "aegisxGoArg" + strconv.Itoa(i)
+		names, values, args = append(names, name), append(values, text(arg)), append(args, name)
+	}
+	if len(names) > 0 {
+		b.WriteString(strings.Join(names, ", ") + " := " + strings.Join(values, ", ") + "; ")
+	}
+	b.WriteString("go func() { defer func() { if r := recover(); r != nil { " + goroutineGuardAlias + ".Panicked(r) } }(); ")
+	b.WriteString(fun + "(" + strings.Join(args, ", "))
+	if call.Ellipsis.IsValid() {
+		b.WriteString("...")
+	}
+	b.WriteString(") }() }")
+	return b.String()
+}
+
+// bindableFunc reports whether the function of a go statement can be evaluated into a
+// variable. Builtins cannot, function literals need not be, and the interpreter cannot
+// assign method expressions such as (*T).M.
+func bindableFunc(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return !builtinFuncs[fun.Name]
+	case *ast.SelectorExpr:
+		_, methodExpr := fun.X.(*ast.ParenExpr)
+		return !methodExpr
+	case *ast.FuncLit:
+		return false
+	}
+	return true
+}
+
+// isConstant reports whether expr is built only from literals and constants, so binding it to
+// a variable could give it the wrong type.
+func (g *goroutineGuard) isConstant(expr ast.Expr) bool {
+	constant := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case nil, *ast.BasicLit, *ast.BinaryExpr, *ast.UnaryExpr, *ast.ParenExpr:
+		case *ast.Ident:
+			constant = constant && (n.Name == "true" || n.Name == "false" || n.Name == "nil" || n.Name == "iota" || g.consts[n.Name])
+		default:
+			constant = false
+		}
+		return constant
+	})
+	return constant
+}
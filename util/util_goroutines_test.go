@@ -0,0 +1,222 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// guardedInterpreter returns an interpreter whose guard package records the panics of the
+// goroutines a program starts.
+func guardedInterpreter(t *testing.T) (*interp.Interpreter, func() []any) {
+	t.Helper()
+	var mu sync.Mutex
+	var panics []any
+	interpreter, _ := NewYaegiInterpreter("", interp.Exports{
+		GoroutineGuardPackage + "/runtime": {
+			"Panicked": reflect.ValueOf(func(value any) {
+				mu.Lock()
+				defer mu.Unlock()
+				panics = append(panics, value)
+			}),
+		},
+	})
+	return interpreter, func() []any {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]any(nil), panics...)
+	}
+}
+
+// runGuarded guards and evaluates program, which must return from main once its goroutines
+// are done.
+func runGuarded(t *testing.T, interpreter *interp.Interpreter, program string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := interpreter.EvalWithContext(ctx, GuardGoroutines(program)); err != nil {
+		t.Fatalf("guarded program failed: %v\n%s", err, GuardGoroutines(program))
+	}
+}
+
+func TestGuardGoroutinesLeavesCodeWithoutGoStatements(t *testing.T) {
+	program := "package main\n\nfunc main() {}\n"
+	if got := GuardGoroutines(program); got != program {
+		t.Errorf("GuardGoroutines() = %q, want the program unchanged", got)
+	}
+}
+
+func TestGuardGoroutinesKeepsLines(t *testing.T) {
+	program := `package main
+
+import "sync"
+
+func work(n int, wg *sync.WaitGroup) { wg.Done() }
+
+func main() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go work(1, &wg)
+	wg.Wait()
+}
+`
+	guarded := GuardGoroutines(program)
+	if got, want := strings.Count(guarded, "\n"), strings.Count(program, "\n"); got != want {
+		t.Errorf("guarded program has %d lines, want %d:\n%s", got, want, guarded)
+	}
+	if !strings.Contains(strings.Split(guarded, "\n")[9], "Panicked") {
+		t.Errorf("the go statement was not guarded on its own line:\n%s", guarded)
+	}
+}
+
+func TestGuardGoroutinesConstantArguments(t *testing.T) {
+	// Bound to variables, the untyped constants would be an int and a float64 and no longer
+	// convert to the parameter types.
+	program := `package main
+
+import (
+	"sync"
+	"time"
+)
+
+const Timeout = 5
+const (
+	Ratio = 0.5
+	Mask  = Timeout << 2
+)
+
+var wg sync.WaitGroup
+
+func wait(d time.Duration, ratio float32, mask uint8) { wg.Done() }
+
+func main() {
+	const local = 3
+	wg.Add(3)
+	go wait(Timeout, Ratio, Mask)
+	go wait(Timeout*time.Millisecond, Ratio*2, Mask|1)
+	go wait(local, local, local)
+	wg.Wait()
+}
+`
+	interpreter, panics := guardedInterpreter(t)
+	runGuarded(t, interpreter, program)
+	if got := panics(); len(got) != 0 {
+		t.Errorf("unexpected panics: %v", got)
+	}
+}
+
+// TestGuardGoroutinesRecoversPanics injects panics into every form of goroutine a program can
+// start and checks each is reported to the guard package instead of crashing the process.
+func TestGuardGoroutinesRecoversPanics(t *testing.T) {
+	program := `package main
+
+import (
+	"errors"
+	"sync"
+)
+
+var wg sync.WaitGroup
+
+type worker struct{ name string }
+
+func (w *worker) run(n int) {
+	defer wg.Done()
+	panic(w.name)
+}
+
+func crash(msg string) {
+	defer wg.Done()
+	panic(msg)
+}
+
+func crashAll(msgs ...string) {
+	defer wg.Done()
+	panic(msgs[len(msgs)-1])
+}
+
+func main() {
+	wg.Add(7)
+	go func() {
+		defer wg.Done()
+		panic("func literal")
+	}()
+	go crash("named function")
+	w := &worker{name: "method value"}
+	go w.run(1)
+	go crashAll([]string{"a", "variadic"}...)
+	go func() {
+		defer wg.Done()
+		var m map[string]int
+		m["nil map"] = 1
+	}()
+	go func() {
+		defer wg.Done()
+		panic(errors.New("error value"))
+	}()
+	go func() {
+		defer wg.Done()
+		wg.Add(1)
+		go crash("nested goroutine")
+	}()
+	wg.Wait()
+}
+`
+	interpreter, panics := guardedInterpreter(t)
+	runGuarded(t, interpreter, program)
+
+	got := panics()
+	if len(got) != 7 {
+		t.Fatalf("%d panics reported, want 7: %v", len(got), got)
+	}
+	reported := map[string]bool{}
+	for _, value := range got {
+		reported[fmt.Sprint(value)] = true
+	}
+	for _, want := range []string{"func literal", "named function", "method value", "variadic", "error value", "nested goroutine", "assignment to entry in nil map"} {
+		if !reported[want] {
+			t.Errorf("panic %q was not reported, got %v", want, got)
+		}
+	}
+}
+
+// TestGuardGoroutinesEvaluatesArgumentsFirst checks that arguments are still evaluated by the
+// starting goroutine, as without the guard.
+func TestGuardGoroutinesEvaluatesArgumentsFirst(t *testing.T) {
+	program := `package main
+
+import "sync"
+
+var wg sync.WaitGroup
+var got []int
+var mu sync.Mutex
+
+func record(n int) {
+	defer wg.Done()
+	mu.Lock()
+	defer mu.Unlock()
+	got = append(got, n)
+}
+
+func main() {
+	n := 1
+	wg.Add(1)
+	go record(n)
+	n = 2
+	wg.Wait()
+	if len(got) != 1 || got[0] != 1 {
+		panic("argument evaluated late")
+	}
+}
+`
+	interpreter, panics := guardedInterpreter(t)
+	runGuarded(t, interpreter, program)
+	if got := panics(); len(got) != 0 {
+		t.Errorf("unexpected panics: %v", got)
+	}
+}
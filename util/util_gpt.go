@@ -6,35 +6,133 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/gcottom/aegisx/metrics"
 )
 
 // GPTRequest represents the request payload for the GPT-4o API
 type GPTRequest struct {
-	Model     string    `json:"model"`
-	Messages  []Message `json:"messages"`
-	MaxTokens int       `json:"max_completion_tokens"`
+	Model           string          `json:"model"`
+	Messages        []Message       `json:"messages"`
+	MaxTokens       int             `json:"max_completion_tokens"`
+	Temperature     *float64        `json:"temperature,omitempty"`
+	TopP            *float64        `json:"top_p,omitempty"`
+	ReasoningEffort string          `json:"reasoning_effort,omitempty"`
+	ResponseFormat  *ResponseFormat `json:"response_format,omitempty"`
 }
 
 // Message represents a single message in the chat history
 type Message struct {
 	Role    string `json:"role"` // "system", "user", or "assistant"
 	Content string `json:"content"`
+	Refusal string `json:"refusal,omitempty"`
 }
 
+// ErrRefusal is returned when the model declines to answer
+var ErrRefusal = errors.New("model refused the request")
+
 // GPTResponse represents the response payload from GPT-4o API
 type GPTResponse struct {
 	Choices []struct {
-		Message Message `json:"message"`
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
 	} `json:"choices"`
+	Usage TokenUsage `json:"usage"`
+}
+
+// GPTErrorResponse is the error body returned by the API with a non-200 status
+type GPTErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
+}
+
+// GPTAPIError is returned for a non-200 response. Message holds the API's error message, or the
+// raw body when it could not be parsed
+type GPTAPIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *GPTAPIError) Error() string {
+	msg := fmt.Sprintf("GPT API returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Type != "" {
+		msg += " (" + e.Type + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Retryable reports whether the request may succeed if sent again. A 429 for an exhausted
+// quota is not a rate limit and lasts until the account is topped up
+func (e *GPTAPIError) Retryable() bool {
+	if e.Type == "insufficient_quota" || e.Code == "insufficient_quota" {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// TokenUsage is the token accounting returned with every completion
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
+// LLMClient sends a single prompt to a language model and returns its reply
+type LLMClient interface {
+	SendMessage(ctx context.Context, prompt string) (string, error)
+	ModelName() string
+	// WithModel returns a copy of the client that generates with model
+	WithModel(model string) LLMClient
+}
+
+// DefaultGPTModel is the model used for code generation when none is configured
+const DefaultGPTModel = "o1-mini"
+
 // GPTClient handles communication with OpenAI's API
 type GPTClient struct {
 	APIKey  string
 	APIURL  string
+	Model   string
 	Timeout time.Duration
+	Usage   *UsageTracker
+	// MaxRetries is the number of times a 429 or 5xx response is retried
+	MaxRetries int
+	// RetryBackoff is the first retry delay, doubled for every further retry. A Retry-After
+	// header takes precedence
+	RetryBackoff time.Duration
+	// MaxRetryWait caps the delay between retries, including Retry-After
+	MaxRetryWait time.Duration
+	// ResponseFormat, when set, asks for JSON or structured output instead of free text. It is
+	// not sent to models that reject it (see SupportsResponseFormat)
+	ResponseFormat *ResponseFormat
+	// SystemPrompt, when set, is sent ahead of every prompt in SystemRole, or in the role the
+	// model accepts (see SystemRoleFor) when SystemRole is empty
+	SystemPrompt string
+	SystemRole   string
+	// Examples are few-shot turns sent between the system prompt and the prompt
+	Examples []Message
+	// MaxContinuations is the number of continuation requests sent for a response cut off at
+	// the token limit, or that looks cut off when the API gives no finish reason. Zero
+	// returns truncated responses as they are
+	MaxContinuations int
+	// Params are the sampling settings of every request, overridden by WithGenerationParams
+	Params GenerationParams
+	// Audit, when set, is given every request and its response
+	Audit ExchangeRecorder
 }
 
 // NewGPTClient initializes a new GPTClient
@@ -42,31 +140,148 @@ func NewGPTClient(apiKey string) *GPTClient {
 	return &GPTClient{
 		APIKey:  apiKey,
 		APIURL:  "https://api.openai.com/v1/chat/completions",
+		Model:   DefaultGPTModel,
 		Timeout: 120 * time.Second,
+
+		MaxRetries:   3,
+		RetryBackoff: time.Second,
+		MaxRetryWait: time.Minute,
 	}
 }
 
 // SendMessage sends a message to GPT-4o and retrieves a response
 func (c *GPTClient) SendMessage(ctx context.Context, prompt string) (string, error) {
+	content, _, err := c.Send(ctx, prompt)
+	return content, err
+}
+
+// ModelName returns the model the client generates with
+func (c *GPTClient) ModelName() string {
+	return c.Model
+}
+
+// WithModel returns a copy of the client that generates with model
+func (c *GPTClient) WithModel(model string) LLMClient {
+	client := *c
+	client.Model = model
+	return &client
+}
+
+// Send is SendMessage that also returns the token usage reported for the call. 429 and 5xx
+// responses are retried with exponential backoff, honoring Retry-After. With
+// MaxContinuations set, a truncated response is continued and stitched together, failing with
+// ErrTruncated if it is still cut off after the last continuation
+func (c *GPTClient) Send(ctx context.Context, prompt string) (string, TokenUsage, error) {
+	messages := c.messages(prompt)
+	content, usage, finishReason, err := c.sendWithBackoff(ctx, messages)
+	if err != nil || c.MaxContinuations <= 0 {
+		return content, usage, err
+	}
+	for i := 0; truncated(finishReason, content); i++ {
+		if i == c.MaxContinuations {
+			return "", usage, fmt.Errorf("%w after %d continuations", ErrTruncated, i)
+		}
+		continued := append(messages[:len(messages):len(messages)], Message{Role: "assistant", Content: content}, Message{Role: "user", Content: ContinuationPrompt})
+		var more string
+		var moreUsage TokenUsage
+		more, moreUsage, finishReason, err = c.sendWithBackoff(ctx, continued)
+		usage.PromptTokens += moreUsage.PromptTokens
+		usage.CompletionTokens += moreUsage.CompletionTokens
+		usage.TotalTokens += moreUsage.TotalTokens
+		if err != nil {
+			return "", usage, err
+		}
+		if strings.TrimSpace(more) == "" {
+			break
+		}
+		content = StitchContinuation(content, more)
+	}
+	return content, usage, nil
+}
+
+// truncated reports whether a response was cut off. The finish reason is trusted when the API
+// gives one
+func truncated(finishReason string, content string) bool {
+	switch finishReason {
+	case "length":
+		return true
+	case "":
+		return LooksTruncated(content)
+	}
+	return false
+}
+
+func (c *GPTClient) sendWithBackoff(ctx context.Context, messages []Message) (string, TokenUsage, string, error) {
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		content, usage, finishReason, err := c.send(ctx, messages)
+		var apiErr *GPTAPIError
+		if err == nil || !errors.As(err, &apiErr) || !apiErr.Retryable() || attempt >= c.MaxRetries {
+			return content, usage, finishReason, err
+		}
+		wait := backoff
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		if c.MaxRetryWait > 0 && wait > c.MaxRetryWait {
+			wait = c.MaxRetryWait
+		}
+		metrics.ProviderRequests.Inc(c.Model, "retry")
+		select {
+		case <-ctx.Done():
+			return "", TokenUsage{}, "", fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *GPTClient) send(ctx context.Context, messages []Message) (string, TokenUsage, string, error) {
+	start := time.Now()
+	content, usage, finishReason, err := c.post(ctx, messages)
+	if c.Audit != nil {
+		c.Audit.RecordExchange(LLMExchange{
+			RuntimeID:    RuntimeIDFrom(ctx),
+			Model:        c.Model,
+			Messages:     messages,
+			Response:     content,
+			FinishReason: finishReason,
+			Usage:        usage,
+			Err:          err,
+			Start:        start,
+			Duration:     time.Since(start),
+		})
+	}
+	return content, usage, finishReason, err
+}
+
+func (c *GPTClient) post(ctx context.Context, messages []Message) (string, TokenUsage, string, error) {
+	params := c.Params.Merge(contextParams(ctx))
+	if params.MaxTokens <= 0 {
+		params.MaxTokens = DefaultMaxTokens
+	}
 	reqPayload := GPTRequest{
-		Model: "o1-mini", // Using GPT-4o Mini
-		Messages: []Message{
-			//{Role: "system", Content: "You are a helpful assistant that provides Go code execution."},
-			{Role: "user", Content: prompt},
-		},
-		MaxTokens: 25000,
+		Model:           c.Model,
+		Messages:        messages,
+		MaxTokens:       params.MaxTokens,
+		Temperature:     params.Temperature,
+		TopP:            params.TopP,
+		ReasoningEffort: params.ReasoningEffort,
+	}
+	if SupportsResponseFormat(c.Model) {
+		reqPayload.ResponseFormat = c.ResponseFormat
 	}
 
 	// Convert request to JSON
 	reqBody, err := json.Marshal(reqPayload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", TokenUsage{}, "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", c.APIURL, bytes.NewBuffer(reqBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", TokenUsage{}, "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
 	req.Header.Set("Content-Type", "application/json")
@@ -75,20 +290,88 @@ func (c *GPTClient) SendMessage(ctx context.Context, prompt string) (string, err
 	client := &http.Client{Timeout: c.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		metrics.ProviderRequests.Inc(c.Model, "error")
+		return "", TokenUsage{}, "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		metrics.ProviderRequests.Inc(c.Model, "error")
+		return "", TokenUsage{}, "", newGPTAPIError(resp)
+	}
+
 	// Decode response
 	var gptResp GPTResponse
 	if err := json.NewDecoder(resp.Body).Decode(&gptResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		metrics.ProviderRequests.Inc(c.Model, "error")
+		return "", TokenUsage{}, "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if c.Usage != nil {
+		c.Usage.Record(c.Model, gptResp.Usage)
 	}
+	countUsage(ctx, gptResp.Usage)
 	// Ensure we have a valid response
 	if len(gptResp.Choices) == 0 {
-		return "", errors.New("empty response from GPT")
+		metrics.ProviderRequests.Inc(c.Model, "error")
+		return "", TokenUsage{}, "", errors.New("empty response from GPT")
 	}
 
+	if refusal := gptResp.Choices[0].Message.Refusal; refusal != "" {
+		metrics.ProviderRequests.Inc(c.Model, "refusal")
+		return "", gptResp.Usage, "", fmt.Errorf("%w: %s", ErrRefusal, refusal)
+	}
+
+	finishReason := gptResp.Choices[0].FinishReason
+	if finishReason == "length" {
+		metrics.ProviderRequests.Inc(c.Model, "truncated")
+	} else {
+		metrics.ProviderRequests.Inc(c.Model, "success")
+	}
 	// Return the AI-generated content
-	return gptResp.Choices[0].Message.Content, nil
+	return gptResp.Choices[0].Message.Content, gptResp.Usage, finishReason, nil
+}
+
+// messages prepends the system prompt and few-shot examples to prompt
+func (c *GPTClient) messages(prompt string) []Message {
+	messages := make([]Message, 0, len(c.Examples)+2)
+	if c.SystemPrompt != "" {
+		role := c.SystemRole
+		if role == "" {
+			role = SystemRoleFor(c.Model)
+		}
+		messages = append(messages, Message{Role: role, Content: c.SystemPrompt})
+	}
+	messages = append(messages, c.Examples...)
+	return append(messages, Message{Role: "user", Content: prompt})
+}
+
+// newGPTAPIError reads the error body and Retry-After header of a non-200 response
+func newGPTAPIError(resp *http.Response) *GPTAPIError {
+	apiErr := &GPTAPIError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var errResp GPTErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		apiErr.Message = errResp.Error.Message
+		apiErr.Type = errResp.Error.Type
+		if errResp.Error.Code != nil {
+			apiErr.Code = fmt.Sprint(errResp.Error.Code)
+		}
+	} else {
+		apiErr.Message = string(bytes.TrimSpace(body))
+	}
+	return apiErr
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: delay seconds and an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
 }
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// HedgedClient sends a prompt to Primary and, if no response arrives within After,
+// issues the same prompt to Secondary and returns whichever completes first.
+type HedgedClient struct {
+	Primary   *GPTClient
+	Secondary *GPTClient
+	After     time.Duration
+	Usage     *UsageTracker
+}
+
+type hedgeResult struct {
+	content string
+	usage   TokenUsage
+	err     error
+}
+
+// ModelName returns the primary model; hedged responses come from an equivalent model.
+func (c *HedgedClient) ModelName() string {
+	return c.Primary.ModelName()
+}
+
+// WithModel returns a copy that generates with model. A secondary on the primary's model
+// follows it; an explicitly configured hedge model is kept.
+func (c *HedgedClient) WithModel(model string) LLMClient {
+	client := *c
+	primary := *c.Primary
+	primary.Model = model
+	client.Primary = &primary
+	if c.Secondary != nil && c.Secondary.Model == c.Primary.Model {
+		secondary := *c.Secondary
+		secondary.Model = model
+		client.Secondary = &secondary
+	}
+	return &client
+}
+
+func (c *HedgedClient) SendMessage(ctx context.Context, prompt string) (string, error) {
+	if c.After <= 0 || c.Secondary == nil {
+		return c.Primary.SendMessage(ctx, prompt)
+	}
+	results := make(chan hedgeResult, 2)
+	send := func(client *GPTClient) {
+		content, usage, err := client.Send(ctx, prompt)
+		results <- hedgeResult{content: content, usage: usage, err: err}
+	}
+	go send(c.Primary)
+
+	timer := time.NewTimer(c.After)
+	defer timer.Stop()
+	inFlight := 1
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			log.Printf("GPT call exceeded %s, sending hedged request to model %s", c.After, c.Secondary.Model)
+			if c.Usage != nil {
+				c.Usage.RecordHedge()
+			}
+			inFlight++
+			go send(c.Secondary)
+		case res := <-results:
+			inFlight--
+			if res.err == nil {
+				if inFlight > 0 {
+					// Let the loser finish so its token spend is still accounted for.
+					go c.drain(results, inFlight)
+				}
+				return res.content, nil
+			}
+			lastErr = res.err
+			if inFlight == 0 {
+				if !timer.Stop() {
+					return "", lastErr
+				}
+				// The primary failed before the hedge fired; retry on the secondary right away.
+				timer.Reset(0)
+			}
+		}
+	}
+}
+
+func (c *HedgedClient) drain(results chan hedgeResult, n int) {
+	for i := 0; i < n; i++ {
+		res := <-results
+		if res.err == nil && c.Usage != nil {
+			c.Usage.RecordDuplicate(res.usage)
+		}
+	}
+}
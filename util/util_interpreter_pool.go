@@ -0,0 +1,82 @@
+package util
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gcottom/aegisx/metrics"
+	"github.com/traefik/yaegi/interp"
+)
+
+// pooledGoPath is the GOPATH pooled interpreters are created with, before it is known which
+// runtime they will serve. Their source filesystem maps it onto the runtime's real GOPATH.
+const pooledGoPath = "/aegisx/gopath"
+
+// goPathFS reads interpreter sources below pooledGoPath from root instead.
+type goPathFS struct {
+	root string
+}
+
+func (f *goPathFS) Open(name string) (fs.File, error) {
+	if rel, ok := strings.CutPrefix(name, pooledGoPath); ok {
+		name = filepath.Join(f.root, rel)
+	}
+	return os.Open(name)
+}
+
+type pooledInterpreter struct {
+	interpreter *interp.Interpreter
+	output      *LogWriter
+	sources     *goPathFS
+}
+
+// InterpreterPool keeps interpreters with the standard library already loaded ready for new
+// runtimes, so the concurrent attempts of an execution do not each pay for creating one. The
+// pool is refilled in the background as interpreters are taken.
+type InterpreterPool struct {
+	ready chan pooledInterpreter
+}
+
+// NewInterpreterPool starts filling a pool of size interpreters until ctx is done.
+func NewInterpreterPool(ctx context.Context, size int) *InterpreterPool {
+	p := &InterpreterPool{ready: make(chan pooledInterpreter, size)}
+	Go("interpreter-pool", "", func() { p.fill(ctx) })
+	return p
+}
+
+func (p *InterpreterPool) fill(ctx context.Context) {
+	for {
+		sources := &goPathFS{}
+		interpreter, output := newInterpreter(interp.Options{GoPath: pooledGoPath, SourcecodeFilesystem: sources})
+		select {
+		case p.ready <- pooledInterpreter{interpreter: interpreter, output: output, sources: sources}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Get returns an interpreter resolving imports from goPath with exports loaded, like
+// NewYaegiInterpreter. It is taken from the pool when one is ready and created otherwise; a
+// nil pool always creates one.
+func (p *InterpreterPool) Get(goPath string, exports ...interp.Exports) (*interp.Interpreter, *LogWriter) {
+	// Without a GOPATH the interpreter reports missing imports differently, so it is not pooled.
+	if p == nil || goPath == "" {
+		return NewYaegiInterpreter(goPath, exports...)
+	}
+	select {
+	case pooled := <-p.ready:
+		metrics.InterpreterPool.Inc("hit")
+		pooled.sources.root = goPath
+		for _, e := range exports {
+			pooled.interpreter.Use(e)
+		}
+		return pooled.interpreter, pooled.output
+	default:
+		metrics.InterpreterPool.Inc("miss")
+		return NewYaegiInterpreter(goPath, exports...)
+	}
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const firstEvalProgram = `package main
+
+import "fmt"
+
+func main() { fmt.Sprint(1) }
+`
+
+// BenchmarkFirstEval measures the time from asking for an interpreter to having evaluated a
+// program, with a new interpreter and with one from a full pool.
+func BenchmarkFirstEval(b *testing.B) {
+	goPath := b.TempDir()
+	b.Run("new", func(b *testing.B) {
+		for range b.N {
+			interpreter, _ := NewYaegiInterpreter(goPath)
+			if _, err := interpreter.Eval(firstEvalProgram); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pool := NewInterpreterPool(ctx, 4)
+		for range b.N {
+			// Measure a ready pool, as the attempts of an execution find it.
+			b.StopTimer()
+			for len(pool.ready) < cap(pool.ready) {
+				time.Sleep(time.Millisecond)
+			}
+			b.StartTimer()
+			interpreter, _ := pool.Get(goPath)
+			if _, err := interpreter.Eval(firstEvalProgram); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// LogRing keeps the most recent Max log lines of a runtime and fans new lines out to subscribers.
+type LogRing struct {
+	Max         int
+	mu          sync.Mutex
+	lines       []string
+	subscribers map[chan string]struct{}
+}
+
+func NewLogRing(max int) *LogRing {
+	return &LogRing{Max: max}
+}
+
+func (r *LogRing) Append(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+	if over := len(r.lines) - r.Max; over > 0 {
+		r.lines = append(r.lines[:0], r.lines[over:]...)
+	}
+	for ch := range r.subscribers {
+		// Slow subscribers miss lines rather than blocking the runtime's log pump.
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the retained lines and a channel receiving every line appended afterwards.
+// The returned function must be called to unsubscribe.
+func (r *LogRing) Subscribe() ([]string, <-chan string, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := make(chan string, 256)
+	if r.subscribers == nil {
+		r.subscribers = map[chan string]struct{}{}
+	}
+	r.subscribers[ch] = struct{}{}
+	cancel := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subscribers, ch)
+	}
+	return append([]string(nil), r.lines...), ch, cancel
+}
+
+// Lines returns a copy of the retained lines, oldest first.
+func (r *LogRing) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+// logWriterBuffer is the number of lines a LogWriter subscriber may fall behind by.
+const logWriterBuffer = 1024
+
+// LogWriter is the output of an interpreted program. It splits what the program writes into
+// lines and sends each to every subscriber, so consumers read whole lines as they are written
+// instead of polling a shared buffer. Lines written without a subscriber or capture are
+// discarded, and a subscriber that falls behind misses lines rather than blocking the program.
+type LogWriter struct {
+	mu          sync.Mutex
+	partial     []byte // Output after the last newline
+	subscribers map[chan string]struct{}
+	captures    map[*capture]struct{}
+}
+
+// capture collects the lines of a LogWriter up to max bytes, or all of them when max is 0.
+type capture struct {
+	max       int
+	b         strings.Builder
+	truncated bool
+}
+
+func (c *capture) add(line string) {
+	if c.truncated {
+		return
+	}
+	if c.max > 0 && c.b.Len()+len(line)+1 > c.max {
+		c.b.WriteString(line[:min(len(line), max(c.max-c.b.Len(), 0))])
+		c.truncated = true
+		return
+	}
+	c.b.WriteString(line)
+	c.b.WriteByte('\n')
+}
+
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.send(strings.TrimSuffix(string(w.partial[:i]), "\r"))
+		w.partial = w.partial[i+1:]
+	}
+	// Keep the incomplete line in a buffer of its own instead of pinning what was sent.
+	w.partial = append([]byte(nil), w.partial...)
+	return len(p), nil
+}
+
+// Flush sends the output written after the last newline as a line of its own.
+func (w *LogWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.send(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *LogWriter) send(line string) {
+	for c := range w.captures {
+		c.add(line)
+	}
+	for ch := range w.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every line written from now on. The returned function
+// unsubscribes and closes the channel.
+func (w *LogWriter) Subscribe() (<-chan string, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch := make(chan string, logWriterBuffer)
+	if w.subscribers == nil {
+		w.subscribers = map[chan string]struct{}{}
+	}
+	w.subscribers[ch] = struct{}{}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			delete(w.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// Capture collects the lines written from now on, up to limit bytes or all of them when limit
+// is 0. Unlike a subscriber, a capture never misses lines. The returned function flushes the
+// output, stops collecting and returns the lines joined by newlines and whether they were cut
+// at limit bytes.
+func (w *LogWriter) Capture(limit int) func() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c := &capture{max: limit}
+	if w.captures == nil {
+		w.captures = map[*capture]struct{}{}
+	}
+	w.captures[c] = struct{}{}
+	return func() (string, bool) {
+		w.Flush()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.captures, c)
+		return c.b.String(), c.truncated
+	}
+}
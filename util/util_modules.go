@@ -0,0 +1,227 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RuntimeModulePath is the module path of the go.mod written for every runtime
+const RuntimeModulePath = "aegisx.local/runtime"
+
+// ModuleSourceDir returns the directory holding a runtime's go.mod inside its module root.
+// Yaegi evaluates the program as package main, so dependencies vendored under
+// <root>/src/main/vendor are found when the interpreter's GOPATH is root
+func ModuleSourceDir(root string) string {
+	return filepath.Join(root, "src", "main")
+}
+
+// ModuleVersion is a module resolved for a runtime
+type ModuleVersion struct {
+	Path    string
+	Version string
+}
+
+// ResolveModuleDependencies vendors the third party imports of code into the module root
+// dir. Each runtime has its own go.mod, so two runtimes can depend on different versions
+// of the same package. The module cache is shared, which is safe as it is content addressed.
+// check, if not nil, vets the resolved modules before their sources are downloaded.
+//
+// prepared, if not empty, keeps the module prepared for each set of imports for
+// PreparedModuleTTL. The concurrent attempts of an execution and its rebuilds usually import
+// the same packages, so they link the prepared module instead of resolving it again.
+func ResolveModuleDependencies(code string, root string, prepared string, check func([]ModuleVersion) error) error {
+	packages := ExtractImports(code)
+	dir := ModuleSourceDir(root)
+	defer lockPath(dir)()
+
+	// Start from a clean module so dependencies of earlier code do not linger.
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear module directory: %w", err)
+	}
+	if len(packages) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create module directory: %w", err)
+	}
+	// Only the third party imports are listed: host packages such as aegisx/kv do not exist
+	// for the go command.
+	var deps strings.Builder
+	deps.WriteString("package main\n\nimport (\n")
+	for _, pkg := range packages {
+		fmt.Fprintf(&deps, "\t_ %q\n", pkg)
+	}
+	deps.WriteString(")\n")
+
+	var cached string
+	if prepared != "" {
+		cached = filepath.Join(prepared, preparedKey(deps.String(), check != nil))
+		defer lockPath(cached)()
+		if info, err := os.Stat(filepath.Join(cached, "go.mod")); err == nil && time.Since(info.ModTime()) < PreparedModuleTTL {
+			return linkTree(cached, dir)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+RuntimeModulePath+"\n\ngo 1.22\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write go.mod: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deps.go"), []byte(deps.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write deps.go: %w", err)
+	}
+	if check != nil {
+		modules, err := moduleGraph(dir, packages)
+		if err != nil {
+			return err
+		}
+		if err := check(modules); err != nil {
+			return err
+		}
+	}
+	if _, err := runGo(dir, "mod", "tidy"); err != nil {
+		return err
+	}
+	if _, err := runGo(dir, "mod", "vendor"); err != nil {
+		return err
+	}
+	if cached != "" {
+		if err := storePrepared(dir, cached); err != nil {
+			log.Printf("⚠️ Failed to keep the prepared module of %s: %v", root, err)
+		}
+	}
+	return nil
+}
+
+// PreparedModuleTTL is how long a prepared module is reused before its imports are resolved
+// again, picking up new releases.
+const PreparedModuleTTL = 24 * time.Hour
+
+// preparedKey names the prepared module of deps. A module prepared without the vulnerability
+// check does not stand in for one with it.
+func preparedKey(deps string, checked bool) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%t", deps, checked)))
+	return hex.EncodeToString(sum[:12])
+}
+
+// lockPath locks the directory at path against concurrent preparation and returns its unlock.
+func lockPath(path string) func() {
+	lock, _ := downloadLocks.LoadOrStore(path, new(sync.Mutex))
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// storePrepared replaces the prepared module at cached with the module at dir.
+func storePrepared(dir string, cached string) error {
+	staging := cached + ".tmp"
+	if err := os.RemoveAll(staging); err != nil {
+		return err
+	}
+	if err := linkTree(dir, staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if err := os.RemoveAll(cached); err != nil {
+		return err
+	}
+	return os.Rename(staging, cached)
+}
+
+// linkTree recreates the tree at src in dst, hard linking its files, or copying them where
+// the filesystem does not support links. Modules are never written once prepared, so the
+// links are safe to share.
+func linkTree(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if os.Link(path, target) == nil {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	})
+}
+
+// moduleGraph requires the latest version of the module of each of packages in the module at
+// dir and returns every module of the resulting build list. Only the go.mod files of the
+// modules are downloaded, not their sources.
+func moduleGraph(dir string, packages []string) ([]ModuleVersion, error) {
+	var required []string
+	for _, pkg := range packages {
+		if slices.ContainsFunc(required, func(module string) bool { return pkg == module || strings.HasPrefix(pkg, module+"/") }) {
+			continue
+		}
+		module, err := latestModule(dir, pkg)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := runGo(dir, "mod", "edit", "-require="+module.Path+"@"+module.Version); err != nil {
+			return nil, err
+		}
+		required = append(required, module.Path)
+	}
+	out, err := runGo(dir, "list", "-mod=mod", "-m", "-f", "{{if not .Main}}{{.Path}} {{.Version}}{{end}}", "all")
+	if err != nil {
+		return nil, err
+	}
+	var modules []ModuleVersion
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if path, version, ok := strings.Cut(scanner.Text(), " "); ok {
+			modules = append(modules, ModuleVersion{Path: path, Version: version})
+		}
+	}
+	return modules, nil
+}
+
+// latestModule returns the latest version of the module providing pkg, the longest prefix of
+// its import path that is a module.
+func latestModule(dir string, pkg string) (ModuleVersion, error) {
+	for path := pkg; ; {
+		out, err := runGo(dir, "list", "-m", "-f", "{{.Path}} {{.Version}}", path+"@latest")
+		if err == nil {
+			module, version, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+			return ModuleVersion{Path: module, Version: version}, nil
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			return ModuleVersion{}, fmt.Errorf("no module provides package %s: %w", pkg, err)
+		}
+		path = path[:i]
+	}
+}
+
+func runGo(dir string, args ...string) ([]byte, error) {
+	fmt.Printf("Resolving dependencies in %s: go %s\n", dir, strings.Join(args, " "))
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = EnvWith(os.Environ(), "GOWORK", "off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveModuleDependenciesReusesPreparedModule(t *testing.T) {
+	prepared := t.TempDir()
+	program := "package main\n\nimport \"example.com/greet\"\n\nfunc main() { greet.Hello() }\n"
+
+	// Prepare the module of a first runtime by hand, as the go command would have.
+	first := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ModuleSourceDir(first), "vendor", "example.com", "greet"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":                            "module " + RuntimeModulePath + "\n\nrequire example.com/greet v1.0.0\n",
+		"vendor/example.com/greet/greet.go": "package greet\n\nfunc Hello() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(ModuleSourceDir(first), filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deps := "package main\n\nimport (\n\t_ \"example.com/greet\"\n)\n"
+	cached := filepath.Join(prepared, preparedKey(deps, false))
+	if err := storePrepared(ModuleSourceDir(first), cached); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second runtime importing the same package links it without running the go command,
+	// which would fail here since example.com/greet does not exist.
+	second := t.TempDir()
+	if err := ResolveModuleDependencies(program, second, prepared, nil); err != nil {
+		t.Fatalf("ResolveModuleDependencies: %v", err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(ModuleSourceDir(second), filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
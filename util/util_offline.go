@@ -0,0 +1,46 @@
+package util
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OfflinePackageAvailable reports whether pkg was pre-vendored into the GOPATH style offline
+// package cache, i.e. cacheDir/src/<pkg> holds Go files
+func OfflinePackageAvailable(cacheDir string, pkg string) bool {
+	entries, err := os.ReadDir(filepath.Join(cacheDir, "src", filepath.FromSlash(pkg)))
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// OfflinePackages lists the import paths available in the offline package cache, skipping
+// internal, testdata and nested vendor directories
+func OfflinePackages(cacheDir string) []string {
+	root := filepath.Join(cacheDir, "src")
+	var packages []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		switch d.Name() {
+		case "internal", "testdata", "vendor":
+			return filepath.SkipDir
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && OfflinePackageAvailable(cacheDir, filepath.ToSlash(rel)) {
+			packages = append(packages, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(packages)
+	return packages
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/gcottom/aegisx/metrics"
+)
+
+// PanicReport describes a recovered panic. Component is where it happened: "api" for the
+// control API, "runtime" for a goroutine of a generated program, or the background task.
+type PanicReport struct {
+	Time      time.Time `json:"time"`
+	Component string    `json:"component"`
+	RuntimeID string    `json:"runtimeID,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	RequestID string    `json:"requestID,omitempty"`
+	Value     string    `json:"value"`
+	Stack     string    `json:"stack,omitempty"`
+}
+
+// ReportPanic logs a recovered panic as one JSON line and counts it.
+func ReportPanic(report PanicReport) {
+	if report.Time.IsZero() {
+		report.Time = time.Now()
+	}
+	metrics.Panics.Inc(report.Component)
+	line, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("panic recovered in %s: %s\n%s", report.Component, report.Value, report.Stack)
+		return
+	}
+	log.Printf("panic recovered: %s", line)
+}
+
+// Recover reports a panic of the calling goroutine and then calls onPanic, if set, with the
+// panic value. It must be deferred directly: defer util.Recover("scheduler", "", nil).
+func Recover(component string, runtimeID string, onPanic func(value any)) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	ReportPanic(PanicReport{Component: component, RuntimeID: runtimeID, Value: fmt.Sprint(value), Stack: string(debug.Stack())})
+	if onPanic != nil {
+		onPanic(value)
+	}
+}
+
+// Go runs fn in a goroutine whose panic is reported instead of crashing the process.
+func Go(component string, runtimeID string, fn func()) {
+	go func() {
+		defer Recover(component, runtimeID, nil)
+		fn()
+	}()
+}
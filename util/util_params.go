@@ -0,0 +1,78 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxTokens is the completion token limit of requests that do not set one
+const DefaultMaxTokens = 25000
+
+// ErrInvalidGenerationParams is returned for generation parameters out of range
+var ErrInvalidGenerationParams = errors.New("invalid generation parameters")
+
+// GenerationParams are the sampling settings of a completion request. Unset fields leave the
+// provider's defaults, except MaxTokens which falls back to DefaultMaxTokens
+type GenerationParams struct {
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"topP,omitempty"`
+	// ReasoningEffort is minimal, low, medium or high, and only accepted by reasoning models
+	ReasoningEffort string `json:"reasoningEffort,omitempty"`
+}
+
+// Validate rejects parameters the API would refuse
+func (p GenerationParams) Validate() error {
+	switch {
+	case p.MaxTokens < 0:
+		return fmt.Errorf("%w: max tokens must not be negative", ErrInvalidGenerationParams)
+	case p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2):
+		return fmt.Errorf("%w: temperature must be between 0 and 2", ErrInvalidGenerationParams)
+	case p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1):
+		return fmt.Errorf("%w: top_p must be greater than 0 and at most 1", ErrInvalidGenerationParams)
+	}
+	switch p.ReasoningEffort {
+	case "", "minimal", "low", "medium", "high":
+		return nil
+	}
+	return fmt.Errorf("%w: reasoning effort must be minimal, low, medium or high, got %q", ErrInvalidGenerationParams, p.ReasoningEffort)
+}
+
+// IsZero reports whether no parameter is set
+func (p GenerationParams) IsZero() bool {
+	return p == GenerationParams{}
+}
+
+// Merge returns p with the parameters set in override replacing its own
+func (p GenerationParams) Merge(override GenerationParams) GenerationParams {
+	if override.MaxTokens > 0 {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.ReasoningEffort != "" {
+		p.ReasoningEffort = override.ReasoningEffort
+	}
+	return p
+}
+
+type generationParamsKey struct{}
+
+// WithGenerationParams returns a context whose LLM requests use params over the parameters of
+// the client and of any outer WithGenerationParams
+func WithGenerationParams(ctx context.Context, params GenerationParams) context.Context {
+	if params.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, generationParamsKey{}, contextParams(ctx).Merge(params))
+}
+
+func contextParams(ctx context.Context) GenerationParams {
+	params, _ := ctx.Value(generationParamsKey{}).(GenerationParams)
+	return params
+}
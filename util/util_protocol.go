@@ -0,0 +1,71 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ControlPrefix starts the control lines generated programs print on their own line to report
+// their progress to aegisx:
+//
+//	AEGISX:PORT=<port>     the port the program is about to listen on
+//	AEGISX:READY           setup is done and the program is about to serve requests
+//	AEGISX:ERROR=<message> the program is giving up after a fatal error
+//	AEGISX:HEARTBEAT       a worker is still doing its work
+const ControlPrefix = "AEGISX:"
+
+// ControlKind is the kind of a control line.
+type ControlKind string
+
+const (
+	ControlPort      ControlKind = "PORT"
+	ControlReady     ControlKind = "READY"
+	ControlError     ControlKind = "ERROR"
+	ControlHeartbeat ControlKind = "HEARTBEAT"
+)
+
+// ControlMessage is a parsed control line. Port is set for ControlPort and Error for
+// ControlError.
+type ControlMessage struct {
+	Kind  ControlKind
+	Port  int
+	Error string
+}
+
+// ParseControlLine parses a line of program output, reporting false for ordinary output and
+// malformed control lines. Only a line starting with ControlPrefix is a control line, so output
+// that merely mentions a port is never mistaken for one.
+func ParseControlLine(line string) (ControlMessage, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), ControlPrefix)
+	if !ok {
+		return ControlMessage{}, false
+	}
+	kind, value, hasValue := strings.Cut(rest, "=")
+	switch ControlKind(kind) {
+	case ControlPort:
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return ControlMessage{}, false
+		}
+		return ControlMessage{Kind: ControlPort, Port: port}, true
+	case ControlReady, ControlHeartbeat:
+		if hasValue {
+			return ControlMessage{}, false
+		}
+		return ControlMessage{Kind: ControlKind(kind)}, true
+	case ControlError:
+		if !hasValue {
+			return ControlMessage{}, false
+		}
+		return ControlMessage{Kind: ControlError, Error: value}, true
+	}
+	return ControlMessage{}, false
+}
+
+// ControlLine formats a control line; value is omitted for ControlReady and ControlHeartbeat.
+func ControlLine(kind ControlKind, value string) string {
+	if kind == ControlReady || kind == ControlHeartbeat {
+		return ControlPrefix + string(kind)
+	}
+	return ControlPrefix + string(kind) + "=" + value
+}
@@ -0,0 +1,224 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// qrFormatBits are the format information of level M for each mask, from ISO/IEC 18004
+// table C.1.
+var qrFormatBits = []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+
+// readFormat returns both copies of the format information of a code.
+func readFormat(modules [][]bool) (int, int) {
+	size := len(modules)
+	at := func(x, y int) int {
+		if modules[y][x] {
+			return 1
+		}
+		return 0
+	}
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= at(8, i) << i
+	}
+	first |= at(8, 7)<<6 | at(8, 8)<<7 | at(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		first |= at(14-i, 8) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= at(size-1-i, 8) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= at(8, size-15+i) << i
+	}
+	return first, second
+}
+
+func TestQRFormatBits(t *testing.T) {
+	for mask, want := range qrFormatBits {
+		q := newQR(1)
+		q.drawFormat(mask)
+		first, second := readFormat(q.modules)
+		if first != want || second != want {
+			t.Errorf("mask %d: format bits %015b and %015b, want %015b", mask, first, second, want)
+		}
+	}
+}
+
+func TestQRVersionBits(t *testing.T) {
+	// ISO/IEC 18004 table D.1.
+	tests := map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+	for version, want := range tests {
+		q := newQR(version)
+		var bottomLeft, topRight int
+		for i := 0; i < 18; i++ {
+			a, b := q.size-11+i%3, i/3
+			if q.modules[b][a] {
+				topRight |= 1 << i
+			}
+			if q.modules[a][b] {
+				bottomLeft |= 1 << i
+			}
+		}
+		if topRight != want || bottomLeft != want {
+			t.Errorf("version %d: version bits %018b and %018b, want %018b", version, topRight, bottomLeft, want)
+		}
+	}
+	q := newQR(6)
+	for y := 0; y < 6; y++ {
+		for x := q.size - 11; x < q.size-8; x++ {
+			if q.isFunction[y][x] {
+				t.Fatalf("version 6 reserves version information at (%d, %d)", x, y)
+			}
+		}
+	}
+}
+
+// TestQRBlocks checks the data capacity, the block split and the error correction of each
+// version against ISO/IEC 18004 tables 7 and 9 for level M.
+func TestQRBlocks(t *testing.T) {
+	tests := []struct {
+		version int
+		bytes   int   // byte mode capacity
+		blocks  []int // data codewords per block
+	}{
+		{1, 14, []int{16}},
+		{2, 26, []int{28}},
+		{3, 42, []int{44}},
+		{4, 62, []int{32, 32}},
+		{5, 84, []int{43, 43}},
+		{6, 106, []int{27, 27, 27, 27}},
+		{7, 122, []int{31, 31, 31, 31}},
+		{8, 152, []int{38, 38, 39, 39}},
+		{9, 180, []int{36, 36, 36, 37, 37}},
+		{10, 213, []int{43, 43, 43, 43, 44}},
+	}
+	for _, test := range tests {
+		v := qrVersions[test.version-1]
+		data := bytes.Repeat([]byte{'a'}, test.bytes)
+		codewords := newQR(test.version).codewords(data)
+		if len(codewords) != v.codewords {
+			t.Errorf("version %d: %d codewords, want %d", test.version, len(codewords), v.codewords)
+			continue
+		}
+		// Undo the interleaving: the data codewords are dealt to the blocks in turn, skipping
+		// the shorter blocks once they are full, then the error correction codewords are.
+		blocks := make([][]byte, len(test.blocks))
+		i := 0
+		for n := 0; n < test.blocks[len(test.blocks)-1]; n++ {
+			for b, size := range test.blocks {
+				if n < size {
+					blocks[b] = append(blocks[b], codewords[i])
+					i++
+				}
+			}
+		}
+		ec := make([][]byte, len(test.blocks))
+		for n := 0; n < v.ecPerBlock; n++ {
+			for b := range test.blocks {
+				ec[b] = append(ec[b], codewords[i])
+				i++
+			}
+		}
+		for b, block := range blocks {
+			if want := rsRemainder(block, rsDivisor(v.ecPerBlock)); !bytes.Equal(ec[b], want) {
+				t.Errorf("version %d: block %d has error correction %v, want %v", test.version, b, ec[b], want)
+			}
+		}
+
+		// The capacity is the largest payload of the version.
+		for _, n := range []int{test.bytes, test.bytes + 1} {
+			modules, err := QRCode(bytes.Repeat([]byte{'a'}, n))
+			if test.version == 10 && n > test.bytes {
+				if err == nil {
+					t.Errorf("%d bytes encoded, want an error", n)
+				}
+				continue
+			}
+			want := test.version
+			if n > test.bytes {
+				want++
+			}
+			if err != nil || len(modules) != 17+4*want {
+				t.Errorf("%d bytes: size %d, %v, want version %d", n, len(modules), err, want)
+			}
+		}
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// The data codewords of "HELLO WORLD" as a 1-M code in alphanumeric mode and their error
+	// correction, the worked example of the Thonky QR code tutorial.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// TestQRCodeReadBack reads the codewords back from finished codes, using the mask their format
+// information names.
+func TestQRCodeReadBack(t *testing.T) {
+	payloads := []string{"", "https://aegisx.example.com/s/abc123", strings.Repeat("x", 100), strings.Repeat("y", 213)}
+	for _, payload := range payloads {
+		modules, err := QRCode([]byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		version := (len(modules) - 17) / 4
+		first, second := readFormat(modules)
+		if first != second {
+			t.Fatalf("%d bytes: the format copies differ: %015b and %015b", len(payload), first, second)
+		}
+		format := first ^ 0x5412
+		if format>>13 != 0 {
+			t.Errorf("%d bytes: error correction level %02b, want M", len(payload), format>>13)
+		}
+		mask := format >> 10 & 7
+		if qrFormatBits[mask] != first {
+			t.Fatalf("%d bytes: format bits %015b are not those of a level M mask", len(payload), first)
+		}
+		if !modules[len(modules)-8][8] {
+			t.Errorf("%d bytes: the dark module is light", len(payload))
+		}
+
+		q := newQR(version)
+		want := q.codewords([]byte(payload))
+		for y := range modules {
+			copy(q.modules[y], modules[y])
+		}
+		q.applyMask(mask)
+		var got []byte
+		var bits int
+		for right := q.size - 1; right >= 1; right -= 2 {
+			if right == 6 {
+				right = 5
+			}
+			upward := (right+1)&2 == 0
+			for vert := 0; vert < q.size; vert++ {
+				y := vert
+				if upward {
+					y = q.size - 1 - vert
+				}
+				for j := 0; j < 2; j++ {
+					x := right - j
+					if q.isFunction[y][x] || len(got) == len(want) && bits == 0 {
+						continue
+					}
+					if bits == 0 {
+						got = append(got, 0)
+					}
+					if q.modules[y][x] {
+						got[len(got)-1] |= 0x80 >> bits
+					}
+					bits = (bits + 1) % 8
+				}
+			}
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%d bytes: read back %v, want %v", len(payload), got, want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// program is the code most responses in the corpus below carry.
+const program = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}`
+
+func TestExtractGoCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{
+			name:     "go fence",
+			response: "```go\n" + program + "\n```",
+			want:     program,
+		},
+		{
+			name:     "golang fence",
+			response: "```golang\n" + program + "\n```",
+			want:     program,
+		},
+		{
+			name:     "upper case label",
+			response: "```Go\n" + program + "\n```",
+			want:     program,
+		},
+		{
+			name:     "label with file name",
+			response: "```go main.go\n" + program + "\n```",
+			want:     program,
+		},
+		{
+			name:     "unlabelled fence with package clause",
+			response: "```\n" + program + "\n```",
+			want:     program,
+		},
+		{
+			name:     "indented fences",
+			response: "Here you go:\n  ```go\n" + program + "\n  ```\n",
+			want:     program,
+		},
+		{
+			name: "prose around the block",
+			response: "Sure! Below is a complete Go program that prints a greeting.\n\n```go\n" + program +
+				"\n```\n\n### How it works\n\n1. `main` prints hello.\n2. Run it with `go run main.go`.",
+			want: program,
+		},
+		{
+			name:     "unterminated fence",
+			response: "```go\n" + program + "\n",
+			want:     program,
+		},
+		{
+			name:     "unterminated fence cut mid-function",
+			response: "```go\npackage main\n\nfunc main() {\n\tprintln(1)",
+			want:     "package main\n\nfunc main() {\n\tprintln(1)",
+		},
+		{
+			name: "snippet before the program",
+			response: "First, the handler looks like this:\n\n```go\nfunc handler(w http.ResponseWriter, r *http.Request) {}\n```\n\n" +
+				"The full program:\n\n```go\n" + program + "\n```",
+			want: program,
+		},
+		{
+			name:     "helper package before the program",
+			response: "```go\npackage store\n\ntype Store struct{}\n```\n\n```go\n" + program + "\n```",
+			want:     program,
+		},
+		{
+			name:     "continuation blocks after the program",
+			response: "```go\n" + program + "\n```\n\nAnd the helpers:\n\n```go\nfunc add(a, b int) int {\n\treturn a + b\n}\n```",
+			want:     program + "\n\nfunc add(a, b int) int {\n\treturn a + b\n}",
+		},
+		{
+			name: "non-Go blocks are skipped",
+			response: "```bash\ngo run main.go\n```\n\n```html index.html\n<h1>hi</h1>\n```\n\n```go\n" + program +
+				"\n```\n\n```json\n{\"ok\": true}\n```",
+			want: program,
+		},
+		{
+			name:     "no package main falls back to the first Go block",
+			response: "```go\npackage app\n\nfunc Run() {}\n```\n\n```go\npackage other\n```",
+			want:     "package app\n\nfunc Run() {}",
+		},
+		{
+			name:     "nested fence in a raw string",
+			response: "```go\npackage main\n\nconst readme = `\n```markdown\n# Title\n```\n`\n\nfunc main() {}\n```\n\nDone.",
+			want:     "package main\n\nconst readme = `\n```markdown\n# Title\n```\n`\n\nfunc main() {}",
+		},
+		{
+			name:     "no fence drops trailing prose",
+			response: "Here is the code:\n\n" + program + "\n\nThis program prints hello.",
+			want:     program,
+		},
+		{
+			name:     "no fence and no trailing prose",
+			response: program,
+			want:     program,
+		},
+		{
+			name:     "surrounding whitespace is trimmed",
+			response: "```go\n\n\n" + program + "\n\n\n```",
+			want:     program,
+		},
+		{
+			name:     "windows line endings",
+			response: "```go\r\n" + strings.ReplaceAll(program, "\n", "\r\n") + "\r\n```\r\n",
+			want:     strings.ReplaceAll(program, "\n", "\r\n"),
+		},
+		{
+			name:     "no Go code returns the response",
+			response: "I'm sorry, I can't help with that.",
+			want:     "I'm sorry, I can't help with that.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractGoCode(tt.response); got != tt.want {
+				t.Errorf("ExtractGoCode() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogWriterCapture(t *testing.T) {
+	var w LogWriter
+	captured := w.Capture(0)
+	// More lines than a subscriber buffers, written without anyone reading them.
+	for i := range 3 * logWriterBuffer {
+		fmt.Fprintf(&w, "line %d\n", i)
+	}
+	fmt.Fprint(&w, "last")
+	output, truncated := captured()
+	if lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n"); len(lines) != 3*logWriterBuffer+1 || truncated {
+		t.Errorf("captured %d lines, truncated %v, want %d lines", len(lines), truncated, 3*logWriterBuffer+1)
+	}
+
+	bounded := w.Capture(10)
+	fmt.Fprint(&w, "12345\n67890\n")
+	if output, truncated := bounded(); output != "12345\n6789" || !truncated {
+		t.Errorf("bounded capture = %q, %v, want the first 10 bytes and truncated", output, truncated)
+	}
+}
+
+func TestGPTAPIErrorRetryable(t *testing.T) {
+	tests := []struct {
+		err  GPTAPIError
+		want bool
+	}{
+		{GPTAPIError{StatusCode: 429, Type: "requests"}, true},
+		{GPTAPIError{StatusCode: 503}, true},
+		{GPTAPIError{StatusCode: 429, Type: "insufficient_quota"}, false},
+		{GPTAPIError{StatusCode: 429, Code: "insufficient_quota"}, false},
+		{GPTAPIError{StatusCode: 400}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Retryable(); got != tt.want {
+			t.Errorf("Retryable() of %v = %v, want %v", &tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExtractGenerationMergesGoFiles(t *testing.T) {
+	response := `{"files": [
+		{"name": "store.go", "content": "package main\n\nimport (\n\t\"strings\"\n\t\"sync\"\n)\n\nvar mu sync.Mutex\n\nfunc upper(s string) string { return strings.ToUpper(s) }\n"},
+		{"name": "main.go", "content": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tmu.Lock()\n\tfmt.Println(upper(\"hi\"))\n}\n"},
+		{"name": "app.css", "content": "body {}"}
+	]}`
+	code, assets := ExtractGeneration(response)
+	for _, want := range []string{`"fmt"`, `"strings"`, `"sync"`, "func main()", "var mu sync.Mutex", "func upper("} {
+		if !strings.Contains(code, want) {
+			t.Errorf("merged program is missing %s:\n%s", want, code)
+		}
+	}
+	if !strings.HasPrefix(code, "package main") || strings.Count(code, "package ") != 1 {
+		t.Errorf("merged program should have one package clause:\n%s", code)
+	}
+	if assets["app.css"] != "body {}" {
+		t.Errorf("assets = %v, want app.css", assets)
+	}
+}
+
+func TestSupportsResponseFormat(t *testing.T) {
+	for model, want := range map[string]bool{"o1-mini": false, "o1-preview-2024-09-12": false, "gpt-4o": true, "o3-mini": true} {
+		if got := SupportsResponseFormat(model); got != want {
+			t.Errorf("SupportsResponseFormat(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
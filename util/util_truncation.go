@@ -0,0 +1,80 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// ErrTruncated is returned when a response is still cut off after the allowed continuations
+var ErrTruncated = errors.New("response was cut off at the token limit")
+
+// ContinuationPrompt asks the model to go on with a reply that was cut off
+const ContinuationPrompt = "Your reply was cut off. Continue exactly where it stopped, mid-line if needed, without repeating anything, restarting the code block or adding commentary."
+
+// maxContinuationOverlap bounds the text a continuation may repeat from the end of the reply
+const maxContinuationOverlap = 512
+
+// LooksTruncated reports whether a generation response appears cut off: a JSON object that
+// does not parse, a code fence that is never closed, or Go code with unclosed braces
+func LooksTruncated(response string) bool {
+	trimmed := strings.TrimSpace(response)
+	if trimmed == "" {
+		return false
+	}
+	if strings.HasPrefix(trimmed, "{") {
+		return !json.Valid([]byte(trimmed))
+	}
+	fenced := false
+	for _, line := range strings.Split(trimmed, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+	}
+	if fenced {
+		return true
+	}
+	return unbalancedBraces(ExtractGoCode(trimmed))
+}
+
+// unbalancedBraces reports whether code opens more braces, brackets or parentheses than it
+// closes. Strings and comments are skipped, and an unterminated one counts as unbalanced
+func unbalancedBraces(code string) bool {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(code))
+	broken := false
+	s.Init(file, []byte(code), func(token.Position, string) { broken = true }, 0)
+	depth := 0
+	for {
+		_, tok, _ := s.Scan()
+		switch tok {
+		case token.EOF:
+			return depth > 0 || broken
+		case token.LBRACE, token.LBRACK, token.LPAREN:
+			depth++
+		case token.RBRACE, token.RBRACK, token.RPAREN:
+			depth--
+		}
+	}
+}
+
+// StitchContinuation appends a continuation to the reply it continues, dropping a code fence
+// the model reopened and any text it repeated from the end of the reply, such as the line that
+// was cut off
+func StitchContinuation(previous string, continuation string) string {
+	if first, rest, ok := strings.Cut(continuation, "\n"); ok && strings.HasPrefix(strings.TrimSpace(first), "```") && strings.TrimSpace(first) != "```" {
+		continuation = rest
+	}
+	if cut := previous[strings.LastIndex(previous, "\n")+1:]; strings.TrimSpace(cut) != "" && strings.HasPrefix(continuation, cut) {
+		return previous[:len(previous)-len(cut)] + continuation
+	}
+	for n := min(len(previous), len(continuation), maxContinuationOverlap); n > 0; n-- {
+		if strings.HasSuffix(previous, continuation[:n]) && strings.Contains(continuation[:n], "\n") {
+			return previous + continuation[n:]
+		}
+	}
+	return previous + continuation
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// ModelUsage aggregates the token spend of a single model
+type ModelUsage struct {
+	Requests         int `json:"requests"`
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// UsageReport is a point in time snapshot of all recorded LLM usage
+type UsageReport struct {
+	Models map[string]ModelUsage `json:"models"`
+	// HedgedRequests counts calls where a second, duplicate request was issued
+	HedgedRequests int `json:"hedgedRequests"`
+	// DuplicateTokens are the tokens spent on hedged responses that were discarded
+	DuplicateTokens int `json:"duplicateTokens"`
+}
+
+// UsageTracker records LLM token usage across all clients sharing it
+type UsageTracker struct {
+	mu     sync.Mutex
+	report UsageReport
+}
+
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{report: UsageReport{Models: map[string]ModelUsage{}}}
+}
+
+// Record adds the usage of one completed request against model
+func (u *UsageTracker) Record(model string, usage TokenUsage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	m := u.report.Models[model]
+	m.Requests++
+	m.PromptTokens += usage.PromptTokens
+	m.CompletionTokens += usage.CompletionTokens
+	m.TotalTokens += usage.TotalTokens
+	u.report.Models[model] = m
+}
+
+// RecordHedge notes that a duplicate request was issued for a slow call
+func (u *UsageTracker) RecordHedge() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.report.HedgedRequests++
+}
+
+// RecordDuplicate adds the usage of a hedged response that lost the race
+func (u *UsageTracker) RecordDuplicate(usage TokenUsage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.report.DuplicateTokens += usage.TotalTokens
+}
+
+// TotalTokens returns the tokens spent across all models
+func (u *UsageTracker) TotalTokens() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	total := 0
+	for _, m := range u.report.Models {
+		total += m.TotalTokens
+	}
+	return total
+}
+
+// Report returns a copy of the current usage
+func (u *UsageTracker) Report() UsageReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	report := u.report
+	report.Models = make(map[string]ModelUsage, len(u.report.Models))
+	for k, v := range u.report.Models {
+		report.Models[k] = v
+	}
+	return report
+}
+
+type usageCounterKey struct{}
+
+// UsageCounter adds up the token usage of the LLM requests made with a context
+type UsageCounter struct {
+	mu    sync.Mutex
+	usage TokenUsage
+}
+
+// WithUsageCounter returns a context whose LLM requests, hedged duplicates included, add their
+// token usage to the returned counter
+func WithUsageCounter(ctx context.Context) (context.Context, *UsageCounter) {
+	counter := &UsageCounter{}
+	return context.WithValue(ctx, usageCounterKey{}, counter), counter
+}
+
+// Usage returns the usage counted so far
+func (c *UsageCounter) Usage() TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// countUsage adds usage to the counter of ctx, if it has one
+func countUsage(ctx context.Context, usage TokenUsage) {
+	counter, ok := ctx.Value(usageCounterKey{}).(*UsageCounter)
+	if !ok {
+		return
+	}
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	counter.usage.PromptTokens += usage.PromptTokens
+	counter.usage.CompletionTokens += usage.CompletionTokens
+	counter.usage.TotalTokens += usage.TotalTokens
+}
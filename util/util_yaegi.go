@@ -1,34 +1,52 @@
 package util
 
 import (
-	"bytes"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/traefik/yaegi/interp"
 	"github.com/traefik/yaegi/stdlib"
 	"github.com/traefik/yaegi/stdlib/unsafe"
 )
 
-func NewYaegiInterpreter() (*interp.Interpreter, *bytes.Buffer) {
-	outputBuffer := new(bytes.Buffer)
-	goPath, err := filepath.Abs(GetYaegiGoPath())
-	if err != nil {
-		panic(err)
+// NewYaegiInterpreter creates an interpreter resolving third party imports from goPath.
+// goPath is passed explicitly so interpreter creation never depends on process environment.
+// Extra exports provide host packages, such as aegisx/kv, to the generated program.
+func NewYaegiInterpreter(goPath string, exports ...interp.Exports) (*interp.Interpreter, *LogWriter) {
+	interpreter, output := newInterpreter(interp.Options{GoPath: goPath})
+	for _, e := range exports {
+		interpreter.Use(e)
 	}
-	interpreter := interp.New(interp.Options{Stdout: outputBuffer, Stderr: outputBuffer, GoPath: goPath})
+	return interpreter, output
+}
+
+// newInterpreter creates an interpreter with the standard library loaded and its output
+// captured.
+func newInterpreter(options interp.Options) (*interp.Interpreter, *LogWriter) {
+	output := new(LogWriter)
+	options.Stdout, options.Stderr = output, output
+	interpreter := interp.New(options)
 	interpreter.Use(stdlib.Symbols)
 	interpreter.Use(unsafe.Symbols)
-	return interpreter, outputBuffer
+	return interpreter, output
 }
 
-func GetYaegiGoPath() string {
-	/*goPath := filepath.Join("../", GetAppRoot())
-	goPath = filepath.Join(goPath, "yaegiGoPath")
-	goPath, err := filepath.Abs(goPath)
-	if err != nil {
-		panic(err)
-	}*/
-	goPath := os.Getenv("GOPATH")
-	return goPath
+// ResolveYaegiGoPath returns the absolute GOPATH used for downloads and interpreters. It is
+// resolved once at startup from the configured value, the GOPATH env var, or `go env GOPATH`.
+func ResolveYaegiGoPath(configured string) (string, error) {
+	goPath := configured
+	if goPath == "" {
+		goPath = os.Getenv("GOPATH")
+	}
+	if goPath == "" {
+		out, err := exec.Command("go", "env", "GOPATH").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve GOPATH: %w", err)
+		}
+		goPath = strings.TrimSpace(string(out))
+	}
+	return filepath.Abs(goPath)
 }
@@ -0,0 +1,108 @@
+package code
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/aegisx/config"
+	"github.com/gcottom/aegisx/util"
+)
+
+// CheckDependencies applies the dependency policy to the imports of a program. Standard
+// library and host packages are always permitted.
+func CheckDependencies(policy config.DependencyPolicyConfig, imports []string) []Violation {
+	var violations []Violation
+	for _, pkg := range imports {
+		if msg := dependencyProblem(policy, pkg); msg != "" {
+			violations = append(violations, Violation{Message: msg})
+		}
+	}
+	return append(violations, dependencyCountProblem(policy, imports)...)
+}
+
+// dependencyProblem explains why the policy forbids importing pkg, or returns "".
+func dependencyProblem(policy config.DependencyPolicyConfig, pkg string) string {
+	if util.IsStandardPackage(pkg) || util.IsHostPackage(pkg) {
+		return ""
+	}
+	if matchesPathPrefix(pkg, policy.Deny) {
+		return "dependency is denied: " + pkg
+	}
+	if len(policy.Allow) > 0 && !matchesPathPrefix(pkg, policy.Allow) {
+		return fmt.Sprintf("dependency is not on the allowlist: %s (allowed: %s)", pkg, strings.Join(policy.Allow, ", "))
+	}
+	return ""
+}
+
+func dependencyCountProblem(policy config.DependencyPolicyConfig, imports []string) []Violation {
+	count := 0
+	for _, pkg := range imports {
+		if !util.IsStandardPackage(pkg) && !util.IsHostPackage(pkg) {
+			count++
+		}
+	}
+	if policy.MaxDependencies > 0 && count > policy.MaxDependencies {
+		return []Violation{{Message: fmt.Sprintf("program imports %d third party packages, limit is %d", count, policy.MaxDependencies)}}
+	}
+	return nil
+}
+
+// matchesPathPrefix reports whether pkg is one of prefixes or a package below one.
+func matchesPathPrefix(pkg string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOfflinePackages reports the third party imports missing from the offline package cache.
+func CheckOfflinePackages(cacheDir string, imports []string) []Violation {
+	var violations []Violation
+	for _, pkg := range imports {
+		if msg := offlinePackageProblem(cacheDir, pkg); msg != "" {
+			violations = append(violations, Violation{Message: msg})
+		}
+	}
+	return violations
+}
+
+func offlinePackageProblem(cacheDir string, pkg string) string {
+	if util.IsStandardPackage(pkg) || util.IsHostPackage(pkg) || util.OfflinePackageAvailable(cacheDir, pkg) {
+		return ""
+	}
+	return "package " + pkg + " is not available offline; use the standard library instead"
+}
+
+// offlinePackagesRule rejects imports that cannot be resolved without downloading them.
+func offlinePackagesRule(cacheDir string) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		for _, imp := range src.File.Imports {
+			pkg, _ := strconv.Unquote(imp.Path.Value)
+			if msg := offlinePackageProblem(cacheDir, pkg); msg != "" {
+				violations = append(violations, Violation{Line: src.line(imp), Message: msg})
+			}
+		}
+		return violations
+	}
+}
+
+// dependenciesRule reports imports the dependency policy forbids.
+func dependenciesRule(policy config.DependencyPolicyConfig) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		var imports []string
+		for _, imp := range src.File.Imports {
+			pkg, _ := strconv.Unquote(imp.Path.Value)
+			imports = append(imports, pkg)
+			if msg := dependencyProblem(policy, pkg); msg != "" {
+				violations = append(violations, Violation{Line: src.line(imp), Message: msg})
+			}
+		}
+		return append(violations, dependencyCountProblem(policy, imports)...)
+	}
+}
@@ -0,0 +1,81 @@
+package code
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// egressBypasses are the standard library types and functions that open connections without
+// going through the dialer of the runtime's egress policy.
+var egressBypasses = map[string]bool{
+	"net.Dialer":                                  true,
+	"net.Resolver":                                true,
+	"net/http.Transport":                          true,
+	"crypto/tls.Dialer":                           true,
+	"net/http/httputil.ReverseProxy":              true,
+	"net/http/httputil.NewSingleHostReverseProxy": true,
+}
+
+// EgressRule rejects programs that build their own dialers, transports or clients, which
+// would bypass the egress policy. An http.Client is allowed when its Transport is set to
+// something other than nil, e.g. http.DefaultTransport. The interpreter denies the connections
+// of a client without one anyway; the rule reports the mistake before the program runs.
+func EgressRule() Rule {
+	return Rule{Name: "egress", Check: egressRule}
+}
+
+func egressRule(src *Source) []Violation {
+	imports := importNames(src.File)
+	qualified := func(expr ast.Expr) string {
+		sel, isSel := expr.(*ast.SelectorExpr)
+		if !isSel {
+			return ""
+		}
+		ident, isIdent := sel.X.(*ast.Ident)
+		if !isIdent || ident.Obj != nil {
+			return ""
+		}
+		if path, ok := imports[ident.Name]; ok {
+			return path + "." + sel.Sel.Name
+		}
+		return ""
+	}
+	var violations []Violation
+	report := func(node ast.Node, name string, hasTransport bool) {
+		switch {
+		case egressBypasses[name]:
+			violations = append(violations, Violation{Line: src.line(node), Message: fmt.Sprintf("%s bypasses the egress policy, use http.DefaultClient or http.Get instead", name)})
+		case name == "net/http.Client" && !hasTransport:
+			violations = append(violations, Violation{Line: src.line(node), Message: "an http.Client without a Transport bypasses the egress policy, set Transport: http.DefaultTransport"})
+		}
+	}
+	// Only values built by the program matter: a *http.Client parameter or field holds a
+	// client built elsewhere.
+	ast.Inspect(src.File, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			hasTransport := false
+			for _, elt := range n.Elts {
+				if kv, isKV := elt.(*ast.KeyValueExpr); isKV {
+					if key, isIdent := kv.Key.(*ast.Ident); isIdent && key.Name == "Transport" {
+						value, isIdent := kv.Value.(*ast.Ident)
+						hasTransport = !isIdent || value.Name != "nil" || value.Obj != nil
+					}
+				}
+			}
+			report(n, qualified(n.Type), hasTransport)
+		case *ast.CallExpr:
+			if ident, isIdent := n.Fun.(*ast.Ident); isIdent && ident.Name == "new" && ident.Obj == nil && len(n.Args) == 1 {
+				report(n, qualified(n.Args[0]), false)
+			} else {
+				report(n, qualified(n.Fun), false)
+			}
+		case *ast.ValueSpec:
+			if n.Type != nil && len(n.Values) == 0 {
+				report(n, qualified(n.Type), false)
+			}
+		}
+		return true
+	})
+	return violations
+}
@@ -0,0 +1,85 @@
+package code
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	fetchURLRegex = regexp.MustCompile("\\bfetch\\(\\s*(['\"`])([^'\"`]*)")
+	xhrURLRegex   = regexp.MustCompile("\\.open\\(\\s*['\"`]\\w+['\"`]\\s*,\\s*(['\"`])([^'\"`]*)")
+	inlineEvalRe  = regexp.MustCompile(`\beval\s*\(|\bnew\s+Function\s*\(|\bset(?:Timeout|Interval)\s*\(\s*['"]`)
+	externalURLRe = regexp.MustCompile(`^(?:https?:)?//`)
+)
+
+// frontendRule inspects the HTML and JavaScript embedded in string literals: fetch()/XHR
+// URLs must use the runtime prefix, inline eval is rejected and, in offline mode, assets
+// may not be loaded from external hosts. Form actions are covered by formActionPrefixRule.
+func frontendRule(prefix string, offline bool) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		ast.Inspect(src.File, func(n ast.Node) bool {
+			lit, isLit := n.(*ast.BasicLit)
+			if !isLit || lit.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			line := src.line(lit)
+			for _, msg := range checkFrontend(value, prefix, offline) {
+				violations = append(violations, Violation{Line: line, Message: msg})
+			}
+			return true
+		})
+		return violations
+	}
+}
+
+// checkFrontend returns a message for every problem found in one HTML or JS fragment.
+func checkFrontend(fragment string, prefix string, offline bool) []string {
+	var msgs []string
+	if strings.Contains(fragment, "<") {
+		tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+		for {
+			tt := tokenizer.Next()
+			if tt == html.ErrorToken {
+				break
+			}
+			if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+				continue
+			}
+			tok := tokenizer.Token()
+			for _, attr := range tok.Attr {
+				isAsset := (attr.Key == "src" && (tok.Data == "script" || tok.Data == "img" || tok.Data == "iframe")) ||
+					(attr.Key == "href" && tok.Data == "link")
+				if offline && isAsset && externalURLRe.MatchString(attr.Val) {
+					msgs = append(msgs, fmt.Sprintf("external %s %s=%q is not allowed in offline mode", tok.Data, attr.Key, attr.Val))
+				}
+			}
+		}
+	}
+
+	for _, re := range []*regexp.Regexp{fetchURLRegex, xhrURLRegex} {
+		for _, match := range re.FindAllStringSubmatch(fragment, -1) {
+			url := match[2]
+			if strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//") && !strings.HasPrefix(url, prefix) {
+				msgs = append(msgs, fmt.Sprintf("request URL %q must use prefix: %s", url, prefix))
+			}
+			if offline && externalURLRe.MatchString(url) {
+				msgs = append(msgs, fmt.Sprintf("request to external URL %q is not allowed in offline mode", url))
+			}
+		}
+	}
+	if inlineEvalRe.MatchString(fragment) {
+		msgs = append(msgs, "inline eval (eval, new Function or string timers) is not allowed")
+	}
+	return msgs
+}
@@ -0,0 +1,341 @@
+package code
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/aegisx/config"
+	"golang.org/x/net/html"
+)
+
+func (s *Source) line(n ast.Node) int {
+	return s.Fset.Position(n.Pos()).Line
+}
+
+// maxFileSizeRule rejects programs larger than limit bytes.
+func maxFileSizeRule(limit int) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		if limit > 0 && len(src.Code) > limit {
+			return []Violation{{Message: fmt.Sprintf("program is %d bytes, limit is %d", len(src.Code), limit)}}
+		}
+		return nil
+	}
+}
+
+// packageMainRule ensures that 'package main' is defined.
+func packageMainRule() func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		if src.File.Name.Name != "main" {
+			return []Violation{{Line: src.line(src.File.Name), Message: "missing 'package main'"}}
+		}
+		return nil
+	}
+}
+
+// requiredFunctionsRule ensures required top level functions exist.
+func requiredFunctionsRule(functions []string) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		found := map[string]bool{}
+		for _, decl := range src.File.Decls {
+			if fn, isFn := decl.(*ast.FuncDecl); isFn && fn.Recv == nil {
+				found[fn.Name.Name] = true
+			}
+		}
+		var violations []Violation
+		for _, req := range functions {
+			if !found[req] {
+				violations = append(violations, Violation{Message: "missing function: " + req})
+			}
+		}
+		return violations
+	}
+}
+
+// bannedImportsRule ensures no restricted packages are imported.
+func bannedImportsRule(packages []string) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		for _, imp := range src.File.Imports {
+			packageName, _ := strconv.Unquote(imp.Path.Value)
+			for _, banned := range packages {
+				if packageName == banned {
+					violations = append(violations, Violation{Line: src.line(imp), Message: "forbidden package used: " + packageName})
+				}
+			}
+		}
+		return violations
+	}
+}
+
+// importNames maps the local name of every import in file to its import path.
+func importNames(file *ast.File) map[string]string {
+	names := map[string]string{}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		names[name] = path
+	}
+	return names
+}
+
+// bannedCallsRule walks every call expression looking for package functions written as "import/path.Func".
+func bannedCallsRule(calls []string) func(src *Source) []Violation {
+	banned := map[string]bool{}
+	for _, call := range calls {
+		banned[call] = true
+	}
+	return func(src *Source) []Violation {
+		imports := importNames(src.File)
+		var violations []Violation
+		ast.Inspect(src.File, func(n ast.Node) bool {
+			call, isCall := n.(*ast.CallExpr)
+			if !isCall {
+				return true
+			}
+			sel, isSel := call.Fun.(*ast.SelectorExpr)
+			if !isSel {
+				return true
+			}
+			if ident, isIdent := sel.X.(*ast.Ident); isIdent && ident.Obj == nil {
+				if path, ok := imports[ident.Name]; ok && banned[path+"."+sel.Sel.Name] {
+					violations = append(violations, Violation{Line: src.line(call), Message: fmt.Sprintf("call to %s.%s is not allowed", path, sel.Sel.Name)})
+				}
+			}
+			return true
+		})
+		return violations
+	}
+}
+
+// bannedMethodsRule flags calls of the given method names when pkg is imported, since
+// without type information any such call may be on a value of that package.
+func bannedMethodsRule(pkg string, methods []string) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		imported := false
+		for _, path := range importNames(src.File) {
+			if path == pkg {
+				imported = true
+			}
+		}
+		if !imported {
+			return nil
+		}
+		var violations []Violation
+		ast.Inspect(src.File, func(n ast.Node) bool {
+			call, isCall := n.(*ast.CallExpr)
+			if !isCall {
+				return true
+			}
+			if sel, isSel := call.Fun.(*ast.SelectorExpr); isSel {
+				for _, method := range methods {
+					if sel.Sel.Name == method {
+						violations = append(violations, Violation{Line: src.line(call), Message: fmt.Sprintf("call to %s method %s is not allowed", pkg, method)})
+					}
+				}
+			}
+			return true
+		})
+		return violations
+	}
+}
+
+// bannedIdentifiersRule flags any use or declaration of the given identifiers.
+func bannedIdentifiersRule(identifiers []string) func(src *Source) []Violation {
+	banned := map[string]bool{}
+	for _, ident := range identifiers {
+		banned[ident] = true
+	}
+	return func(src *Source) []Violation {
+		var violations []Violation
+		ast.Inspect(src.File, func(n ast.Node) bool {
+			if ident, isIdent := n.(*ast.Ident); isIdent && banned[ident.Name] {
+				violations = append(violations, Violation{Line: src.line(ident), Message: "identifier not allowed: " + ident.Name})
+			}
+			return true
+		})
+		return violations
+	}
+}
+
+// maxGoroutinesRule limits the number of go statements. A go statement inside a loop can
+// spawn an unbounded number of goroutines and is always reported.
+func maxGoroutinesRule(limit int) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		count := 0
+		var walk func(n ast.Node, inLoop bool)
+		walk = func(n ast.Node, inLoop bool) {
+			ast.Inspect(n, func(child ast.Node) bool {
+				switch node := child.(type) {
+				case *ast.ForStmt:
+					if node != n {
+						walk(node.Body, true)
+						return false
+					}
+				case *ast.RangeStmt:
+					if node != n {
+						walk(node.Body, true)
+						return false
+					}
+				case *ast.FuncLit:
+					// a closure body runs wherever it is called, not per loop iteration
+					walk(node.Body, false)
+					return false
+				case *ast.GoStmt:
+					count++
+					if inLoop {
+						violations = append(violations, Violation{Line: src.line(node), Message: "goroutine spawned inside a loop"})
+					}
+				}
+				return true
+			})
+		}
+		walk(src.File, false)
+		if limit > 0 && count > limit {
+			violations = append(violations, Violation{Message: fmt.Sprintf("program spawns %d goroutines, limit is %d", count, limit)})
+		}
+		return violations
+	}
+}
+
+// formActionPrefixRule parses every string literal containing HTML and ensures absolute
+// form actions use the runtime prefix.
+func formActionPrefixRule(prefix string) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		ast.Inspect(src.File, func(n ast.Node) bool {
+			lit, isLit := n.(*ast.BasicLit)
+			if !isLit || lit.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil || !strings.Contains(value, "<form") {
+				return true
+			}
+			for _, action := range formActions(value) {
+				if strings.HasPrefix(action, "/") && !strings.HasPrefix(action, prefix) {
+					violations = append(violations, Violation{Line: src.line(lit), Message: fmt.Sprintf("form action %q must use prefix: %s", action, prefix)})
+				}
+			}
+			return true
+		})
+		return violations
+	}
+}
+
+// formActions returns the action attribute of every form element in an HTML fragment,
+// skipping template expressions that cannot be checked statically.
+func formActions(fragment string) []string {
+	var actions []string
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			// io.EOF or malformed markup, either way nothing more can be read
+			return actions
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := tokenizer.Token()
+		if tok.Data != "form" {
+			continue
+		}
+		for _, attr := range tok.Attr {
+			if attr.Key == "action" && !strings.Contains(attr.Val, "{{") {
+				actions = append(actions, attr.Val)
+			}
+		}
+	}
+}
+
+// handlerRootRule ensures every handler registered with a literal pattern is at the root.
+func handlerRootRule(prefix string) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		var violations []Violation
+		ast.Inspect(src.File, func(n ast.Node) bool {
+			call, isCall := n.(*ast.CallExpr)
+			if !isCall || len(call.Args) == 0 {
+				return true
+			}
+			sel, isSel := call.Fun.(*ast.SelectorExpr)
+			if !isSel || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") {
+				return true
+			}
+			lit, isLit := call.Args[0].(*ast.BasicLit)
+			if !isLit || lit.Kind != token.STRING {
+				return true
+			}
+			pattern, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			// Go 1.22 patterns may be prefixed with a method, e.g. "POST /items".
+			if fields := strings.Fields(pattern); len(fields) > 0 {
+				pattern = fields[len(fields)-1]
+			}
+			if strings.HasPrefix(pattern, strings.TrimSuffix(prefix, "/")) {
+				violations = append(violations, Violation{Line: src.line(call), Message: "handler must be at root, but found under runtime prefix: " + prefix})
+			}
+			return true
+		})
+		return violations
+	}
+}
+
+// portConstantRule ensures the program declares the port it was assigned.
+func portConstantRule(port int) func(src *Source) []Violation {
+	return func(src *Source) []Violation {
+		for _, decl := range src.File.Decls {
+			gen, isGen := decl.(*ast.GenDecl)
+			if !isGen || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				for i, name := range valueSpec.Names {
+					if name.Name != PortConstName {
+						continue
+					}
+					if i < len(valueSpec.Values) {
+						if lit, isLit := valueSpec.Values[i].(*ast.BasicLit); isLit && lit.Kind == token.INT && lit.Value == strconv.Itoa(port) {
+							return nil
+						}
+					}
+					return []Violation{{Line: src.line(name), Message: fmt.Sprintf("constant %s must equal %d", PortConstName, port)}}
+				}
+			}
+		}
+		return []Violation{{Message: fmt.Sprintf("missing constant: %s = %d", PortConstName, port)}}
+	}
+}
+
+// regexpRule reports every line of the program matching pattern.
+func regexpRule(pattern config.RegexpPattern) (func(src *Source) []Violation, error) {
+	re, err := regexp.Compile(pattern.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regexp rule %s: %w", pattern.Name, err)
+	}
+	message := pattern.Message
+	if message == "" {
+		message = "matches forbidden pattern " + pattern.Pattern
+	}
+	return func(src *Source) []Violation {
+		var violations []Violation
+		lineNo := 0
+		for line := range strings.Lines(src.Code) {
+			lineNo++
+			if re.MatchString(line) {
+				violations = append(violations, Violation{Line: lineNo, Message: message})
+			}
+		}
+		return violations
+	}, nil
+}
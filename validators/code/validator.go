@@ -5,125 +5,203 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"slices"
 	"strings"
+
+	"github.com/gcottom/aegisx/config"
 )
 
-// CodeValidator validates generated Go code before Yaegi execution.
-type CodeValidator struct {
-	RequiredFunctions []string
-	ForbiddenPackages []string
-	FormActionPrefix  string
+// PortConstName is the package level constant generated programs must declare with their assigned port.
+const PortConstName = "AegisxPort"
+
+// Source is the parsed program every rule inspects. The programs of text validators are not
+// parsed, so their Fset and File are nil.
+type Source struct {
+	Code string
+	Fset *token.FileSet
+	File *ast.File
 }
 
-// DefaultValidator returns a validator with default rules.
-func DefaultValidator(id string) *CodeValidator {
-	return &CodeValidator{
-		RequiredFunctions: []string{"main", "Shutdown"},
-		ForbiddenPackages: []string{"syscall"},
-		FormActionPrefix:  fmt.Sprintf("/runtime/%s/", id),
-	}
+// Violation is a single diagnostic reported by a rule. Snippet holds the numbered source
+// lines around Line so the rebuild prompt can show the model exactly what is wrong.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+	Snippet string `json:"snippet,omitempty"`
 }
 
-// Validate performs all checks on the provided Go code.
-func (v *CodeValidator) Validate(code string) error {
-	if err := v.checkSyntax(code); err != nil {
-		return fmt.Errorf("syntax error: %w", err)
-	}
-	if err := v.checkPackage(code); err != nil {
-		return fmt.Errorf("package error: %w", err)
-	}
-	if err := v.checkRequiredFunctions(code); err != nil {
-		return fmt.Errorf("missing required functions: %w", err)
-	}
-	if err := v.checkForbiddenPackages(code); err != nil {
-		return fmt.Errorf("forbidden packages used: %w", err)
-	}
-	if err := v.checkFormActionPrefix(code); err != nil {
-		return fmt.Errorf("form action routing error: %w", err)
+func (v Violation) String() string {
+	if v.Line > 0 {
+		return fmt.Sprintf("%s: line %d: %s", v.Rule, v.Line, v.Message)
 	}
-	if err := v.checkHandlerRoot(code); err != nil {
-		return fmt.Errorf("handler routing error: %w", err)
-	}
-	return nil
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
 }
 
-// checkSyntax validates the Go code for syntax errors.
-func (v *CodeValidator) checkSyntax(code string) error {
-	fset := token.NewFileSet()
-	_, err := parser.ParseFile(fset, "", code, parser.AllErrors)
-	return err
+// ValidationError collects the violations of every rule that failed.
+type ValidationError struct {
+	Violations []Violation
 }
 
-// checkPackage ensures that 'package main' is defined.
-func (v *CodeValidator) checkPackage(code string) error {
-	if !strings.Contains(code, "package main") {
-		return fmt.Errorf("missing 'package main'")
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
 	}
-	return nil
+	return strings.Join(msgs, "; ")
+}
+
+// Rule is a named, independently enabled check. Custom rules can be appended to CodeValidator.Rules.
+type Rule struct {
+	Name  string
+	Check func(src *Source) []Violation
+}
+
+// CodeValidator validates generated Go code before Yaegi execution by running its rule pipeline.
+// A text validator checks programs in other languages, with rules that only read Source.Code.
+type CodeValidator struct {
+	Rules []Rule
+	Text  bool
+}
+
+// Without removes the rules called names, such as the rules about serving HTTP for a program
+// that does not.
+func (v *CodeValidator) Without(names ...string) *CodeValidator {
+	v.Rules = slices.DeleteFunc(v.Rules, func(rule Rule) bool { return slices.Contains(names, rule.Name) })
+	return v
 }
 
-// checkRequiredFunctions ensures required functions exist.
-func (v *CodeValidator) checkRequiredFunctions(code string) error {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, "", code, parser.AllErrors)
+// DefaultValidator returns a validator with default rules.
+func DefaultValidator(id string, port int) *CodeValidator {
+	v, err := NewValidator(&config.Config{Validator: config.DefaultValidatorConfig()}, "/runtime/"+id, port)
 	if err != nil {
-		return err
+		panic(err)
 	}
+	return v
+}
 
-	found := map[string]bool{}
-	for _, decl := range node.Decls {
-		if fn, isFn := decl.(*ast.FuncDecl); isFn {
-			found[fn.Name.Name] = true
+// NewValidator builds the rule pipeline enabled in cfg.Validator for the runtime served under
+// the path prefix, e.g. /runtime/<id>, on the given port.
+func NewValidator(appCfg *config.Config, prefix string, port int) (*CodeValidator, error) {
+	cfg := appCfg.Validator
+	if cfg == nil {
+		cfg = config.DefaultValidatorConfig()
+	}
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	v := &CodeValidator{}
+	add := func(enabled bool, name string, check func(src *Source) []Violation) {
+		if enabled {
+			v.Rules = append(v.Rules, Rule{Name: name, Check: check})
 		}
 	}
+	add(cfg.MaxFileSize.Enabled, "max_file_size", maxFileSizeRule(cfg.MaxFileSize.Limit))
+	add(cfg.PackageMain.Enabled, "package_main", packageMainRule())
+	add(cfg.RequiredFunctions.Enabled, "required_functions", requiredFunctionsRule(cfg.RequiredFunctions.Values))
+	add(cfg.BannedImports.Enabled, "banned_imports", bannedImportsRule(cfg.BannedImports.Values))
+	add(cfg.BannedCalls.Enabled, "banned_calls", bannedCallsRule(cfg.BannedCalls.Values))
+	add(cfg.BannedMethods.Enabled, "banned_methods", bannedMethodsRule(cfg.BannedMethods.Package, cfg.BannedMethods.Values))
+	add(cfg.BannedIdentifiers.Enabled, "banned_identifiers", bannedIdentifiersRule(cfg.BannedIdentifiers.Values))
+	add(cfg.MaxGoroutines.Enabled, "max_goroutines", maxGoroutinesRule(cfg.MaxGoroutines.Limit))
+	add(cfg.FormActionPrefix.Enabled, "form_action_prefix", formActionPrefixRule(prefix))
+	add(cfg.HandlerRoot.Enabled, "handler_root", handlerRootRule(prefix))
+	add(cfg.PortConstant.Enabled && port > 0, "port_constant", portConstantRule(port))
+	add(cfg.Frontend.Enabled, "frontend", frontendRule(prefix, appCfg.OfflineMode))
+	add(appCfg.Dependencies.Restricted(), "dependencies", dependenciesRule(appCfg.Dependencies))
+	add(appCfg.OfflineMode, "offline_packages", offlinePackagesRule(appCfg.OfflinePackageCache))
+	add(appCfg.Egress.Restricted(), "egress", egressRule)
+	return v, v.addRegexpRules(cfg)
+}
+
+// NewTextValidator builds the rules enabled in cfg.Validator that apply to a program in any
+// language: its size limit and the regexp rules. Backends add the rules of their language.
+func NewTextValidator(appCfg *config.Config) (*CodeValidator, error) {
+	cfg := appCfg.Validator
+	if cfg == nil {
+		cfg = config.DefaultValidatorConfig()
+	}
+	v := &CodeValidator{Text: true}
+	if cfg.MaxFileSize.Enabled {
+		v.Rules = append(v.Rules, Rule{Name: "max_file_size", Check: maxFileSizeRule(cfg.MaxFileSize.Limit)})
+	}
+	return v, v.addRegexpRules(cfg)
+}
 
-	for _, req := range v.RequiredFunctions {
-		if !found[req] {
-			return fmt.Errorf("missing function: %s", req)
+func (v *CodeValidator) addRegexpRules(cfg *config.ValidatorConfig) error {
+	if !cfg.Regexp.Enabled {
+		return nil
+	}
+	for _, pattern := range cfg.Regexp.Patterns {
+		check, err := regexpRule(pattern)
+		if err != nil {
+			return err
 		}
+		v.Rules = append(v.Rules, Rule{Name: "regexp:" + pattern.Name, Check: check})
 	}
 	return nil
 }
 
-// checkForbiddenPackages ensures no restricted packages are imported.
-func (v *CodeValidator) checkForbiddenPackages(code string) error {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, "", code, parser.ImportsOnly)
-	if err != nil {
-		return err
+// Validate parses the code and runs every rule, returning a *ValidationError holding all
+// violations found. A syntax error stops the pipeline since the other rules need the AST. Text
+// validators run their rules on the code as is.
+func (v *CodeValidator) Validate(code string) error {
+	src := &Source{Code: code}
+	if !v.Text {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", code, parser.AllErrors)
+		if err != nil {
+			return &ValidationError{Violations: []Violation{{Rule: "syntax", Message: err.Error()}}}
+		}
+		src.Fset, src.File = fset, file
 	}
 
-	for _, imp := range node.Imports {
-		packageName := strings.Trim(imp.Path.Value, `"`)
-		for _, forbidden := range v.ForbiddenPackages {
-			if packageName == forbidden {
-				return fmt.Errorf("forbidden package used: %s", packageName)
-			}
+	lines := strings.Split(code, "\n")
+	var violations []Violation
+	for _, rule := range v.Rules {
+		for _, violation := range rule.Check(src) {
+			violation.Rule = rule.Name
+			violation.Snippet = snippet(lines, violation.Line)
+			violations = append(violations, violation)
 		}
 	}
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
 	return nil
 }
 
-// checkFormActionPrefix ensures all HTML form actions use the correct runtime prefix.
-func (v *CodeValidator) checkFormActionPrefix(code string) error {
-	for line := range strings.Lines(code) {
-		if strings.Contains(line, "<form") && strings.Contains(line, "action=") {
-			if !strings.Contains(line, v.FormActionPrefix) {
-				return fmt.Errorf("form action must use prefix: %s", v.FormActionPrefix)
-			}
+// snippetContext is the number of lines shown on each side of a violation.
+const snippetContext = 2
+
+// snippet renders the numbered lines around line, marking the offending one with '>'.
+func snippet(lines []string, line int) string {
+	if line <= 0 || line > len(lines) {
+		return ""
+	}
+	start := max(line-snippetContext, 1)
+	end := min(line+snippetContext, len(lines))
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
 		}
+		fmt.Fprintf(&b, "%s %4d | %s\n", marker, i, lines[i-1])
 	}
-	return nil
+	return b.String()
 }
 
-// checkHandlerRoot ensures all handlers are at the root.
-func (v *CodeValidator) checkHandlerRoot(code string) error {
-	for line := range strings.Lines(code) {
-		if strings.Contains(line, ".HandleFunc(") {
-			if strings.Contains(line, v.FormActionPrefix) {
-				return fmt.Errorf("handler must be at root, but found under runtime prefix: %s", v.FormActionPrefix)
-			}
+// FormatDiagnostics renders violations as a numbered list with their code context.
+func FormatDiagnostics(violations []Violation) string {
+	var b strings.Builder
+	for i, v := range violations {
+		if v.Line > 0 {
+			fmt.Fprintf(&b, "%d. [%s] line %d: %s\n", i+1, v.Rule, v.Line, v.Message)
+		} else {
+			fmt.Fprintf(&b, "%d. [%s] %s\n", i+1, v.Rule, v.Message)
+		}
+		if v.Snippet != "" {
+			b.WriteString("```go\n" + v.Snippet + "```\n")
 		}
 	}
-	return nil
+	return b.String()
 }